@@ -156,12 +156,16 @@ func startServer(app *fiber.App, config Config) {
 	server := handler.NewFiberServer(app, config.AppName, config.MonitorPath)
 
 	// Load TLS or mTLS certificates and keys from environment variables or command-line arguments ?
-	//
-	// TODO: Implement ACME?
 	tlsConfig, err := setupTLS.LoadConfig()
 	if err != nil {
 		log.LogFatal(err)
 	}
+	// Without certificate files, obtain the certificate from the ACME server (e.g., Let's Encrypt) when ACME_HOSTS is set.
+	if tlsConfig == nil {
+		if tlsConfig, err = server.LoadACME(); err != nil {
+			log.LogFatal(err)
+		}
+	}
 
 	// Start the server with graceful shutdown and monitor
 	if tlsConfig != nil {
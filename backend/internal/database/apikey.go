@@ -0,0 +1,419 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	// apiKeyPrefix is the prefix of every generated API key, so leaked keys are easy to spot (e.g., by secret scanners).
+	apiKeyPrefix = "sk-"
+
+	// apiKeyDisplayLength is the number of leading characters of a key stored in clear (including apiKeyPrefix),
+	// so the owner can tell the keys apart without the database holding the key itself.
+	apiKeyDisplayLength = 11
+
+	// apiKeyCachePrefix is the prefix of the cached API keys in the fiber storage, by keyed hash.
+	apiKeyCachePrefix = "apikey:"
+
+	// apiKeyCacheTTL is how long a validated API key is cached. Revoking a key deletes it from the cache,
+	// so this only bounds how stale the cached metadata (e.g., the last-used time) can be.
+	apiKeyCacheTTL = 5 * time.Minute
+
+	// apiKeyLastUsedInterval is the granularity of the last-used time, so a busy key doesn't write to the database on every request.
+	apiKeyLastUsedInterval = time.Minute
+
+	// minAPIKeyHashSecretLength is the minimum length of API_KEY_HASH_SECRET, the size of the SHA-256 output.
+	minAPIKeyHashSecretLength = 32
+)
+
+// APIKeysTable is the name of the table storing the API keys (see [CreateAPIKeysTable]).
+const APIKeysTable = "api_keys"
+
+// APIKey is the metadata of an API key. The key itself is never stored, only its keyed hash.
+//
+// Note: The zero time means "never" for ExpiresAt, LastUsedAt and RevokedAt.
+type APIKey struct {
+	ID         string    `json:"id"`
+	Owner      string    `json:"owner"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes,omitempty"`
+	Plan       string    `json:"plan,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	RevokedAt  time.Time `json:"revoked_at"`
+}
+
+// Expired reports whether the key has an expiration time that has passed.
+func (k APIKey) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// Revoked reports whether the key was revoked.
+func (k APIKey) Revoked() bool {
+	return !k.RevokedAt.IsZero()
+}
+
+// HasScope reports whether the key was granted the scope, either exactly or through a wildcard
+// ("health:*" grants every "health:" scope, and "*" grants every scope).
+func (k APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		switch {
+		case granted == scope, granted == "*":
+			return true
+		case strings.HasSuffix(granted, ":*") && strings.HasPrefix(scope, granted[:len(granted)-1]):
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyOption configures an API key created by [ServiceAuth.CreateAPIKey].
+type APIKeyOption func(*APIKey)
+
+// WithAPIKeyScopes grants scopes to the API key (e.g., "health:read"), which are checked by the RequireScopes middleware.
+// A key without scopes can only access the routes that don't require any.
+//
+// Note: Scopes are separated by spaces in the database (like OAuth 2.0), so they can't contain spaces themselves.
+func WithAPIKeyScopes(scopes ...string) APIKeyOption {
+	return func(k *APIKey) {
+		k.Scopes = append(k.Scopes, scopes...)
+	}
+}
+
+// WithAPIKeyPlan sets the quota plan of the API key, by name (see [QuotaConfig.Plans]).
+func WithAPIKeyPlan(plan string) APIKeyOption {
+	return func(k *APIKey) {
+		k.Plan = plan
+	}
+}
+
+// CreateAPIKeysTable creates the API keys table if it doesn't exist.
+//
+// Note: The times are stored as Unix seconds (0 meaning "never") instead of DATETIME, so the same schema works on MySQL
+// and SQLite, and doesn't depend on the "parseTime" DSN parameter.
+func CreateAPIKeysTable(ctx context.Context, db Service) error {
+	query := `CREATE TABLE IF NOT EXISTS api_keys (
+	id VARCHAR(32) NOT NULL PRIMARY KEY,
+	owner VARCHAR(255) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	prefix VARCHAR(32) NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	scopes VARCHAR(1024) NOT NULL DEFAULT '',
+	plan VARCHAR(64) NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL DEFAULT 0,
+	last_used_at BIGINT NOT NULL DEFAULT 0,
+	revoked_at BIGINT NOT NULL DEFAULT 0`
+
+	if db.Dialect().Name() == DriverMySQL {
+		// MySQL has no "CREATE INDEX IF NOT EXISTS", so the index is declared inline.
+		return db.ExecWithoutRow(ctx, query+",\n\tINDEX idx_api_keys_owner (owner)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+
+	if err := db.ExecWithoutRow(ctx, query+"\n)"); err != nil {
+		return err
+	}
+	return db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_api_keys_owner ON api_keys (owner)")
+}
+
+// hashAPIKey returns the keyed hash of an API key, which is what the database and the cache are indexed by.
+//
+// Note: The keys are 50 random bytes, so a fast hash is enough (unlike passwords, there is nothing to brute-force).
+// The HMAC secret only adds that a leaked table is useless without the application configuration as well.
+func (s *serviceAuth) hashAPIKey(key string) string {
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkAPIKeyHashSecret returns ErrAPIKeyHashSecret when the secret of the keyed hash is missing or too short,
+// since an empty HMAC key makes the stored hashes as easy to check as plain SHA-256.
+func checkAPIKeyHashSecret(secret string) error {
+	if len(secret) < minAPIKeyHashSecretLength {
+		return fmt.Errorf("%w (got %d bytes)", ErrAPIKeyHashSecret, len(secret))
+	}
+	return nil
+}
+
+// CreateAPIKey generates a new API key for the owner and stores its keyed hash.
+func (s *serviceAuth) CreateAPIKey(ctx context.Context, owner, name string, ttl time.Duration, opts ...APIKeyOption) (string, APIKey, error) {
+	id, err := newAPIKeyID()
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	key := helper.GenerateAPIKey(apiKeyPrefix)
+	now := time.Now().UTC().Truncate(time.Second)
+	info := APIKey{
+		ID:        id,
+		Owner:     owner,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		info.ExpiresAt = now.Add(ttl).Truncate(time.Second)
+	}
+	for _, opt := range opts {
+		opt(&info)
+	}
+	for _, scope := range info.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\r\n") {
+			return "", APIKey{}, fmt.Errorf("database: invalid API key scope %q", scope)
+		}
+	}
+
+	const query = "INSERT INTO api_keys (id, owner, name, prefix, key_hash, scopes, plan, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if _, err := s.db.ExecContext(ctx, query, info.ID, info.Owner, info.Name, info.Prefix, s.hashAPIKey(key),
+		strings.Join(info.Scopes, " "), info.Plan, unixOrZero(info.CreatedAt), unixOrZero(info.ExpiresAt)); err != nil {
+		return "", APIKey{}, fmt.Errorf("database: failed to create API key: %w", err)
+	}
+
+	return key, info, nil
+}
+
+// ValidateAPIKey returns the API key metadata if the key is valid.
+//
+// The key is looked up in the fiber storage (Redis) first, then in the database, and cached on success.
+// Invalid keys are not cached, so random keys can't fill the cache; the rate limiter covers them instead.
+func (s *serviceAuth) ValidateAPIKey(ctx context.Context, key string) (APIKey, error) {
+	if len(key) <= apiKeyDisplayLength {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	hash := s.hashAPIKey(key)
+	now := time.Now().UTC()
+
+	info, cached := s.cachedAPIKey(hash)
+	if !cached {
+		var err error
+		if info, err = s.queryAPIKey(ctx, "key_hash", hash); err != nil {
+			if errors.Is(err, ErrAPIKeyNotFound) {
+				return APIKey{}, ErrInvalidAPIKey
+			}
+			return APIKey{}, err
+		}
+	}
+
+	switch {
+	case info.Revoked():
+		return APIKey{}, ErrInvalidAPIKey
+	case info.Expired(now):
+		return APIKey{}, ErrExpiredAPIKey
+	}
+
+	if now.Sub(info.LastUsedAt) >= apiKeyLastUsedInterval {
+		info.LastUsedAt = now.Truncate(time.Second)
+		const query = "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+		if _, err := s.db.ExecContext(ctx, query, info.LastUsedAt.Unix(), info.ID); err != nil {
+			// The key is still valid, so don't fail the request over the last-used time.
+			log.LogErrorf("Failed to update the last-used time of API key %s: %v", info.ID, err)
+		}
+	} else if cached {
+		return info, nil
+	}
+
+	s.cacheAPIKey(hash, info, now)
+	return info, nil
+}
+
+// RevokeAPIKey revokes the API key with the given ID and removes it from the cache.
+func (s *serviceAuth) RevokeAPIKey(ctx context.Context, id string) error {
+	var hash string
+	if err := s.db.QueryRowContext(ctx, "SELECT key_hash FROM api_keys WHERE id = ?", id).Scan(&hash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+
+	const query = "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at = 0"
+	if _, err := s.db.ExecContext(ctx, query, time.Now().UTC().Unix(), id); err != nil {
+		return fmt.Errorf("database: failed to revoke API key: %w", err)
+	}
+	return s.uncacheAPIKey(hash)
+}
+
+// RotateAPIKey replaces the API key with the given ID by a new one with the same owner, name, lifetime, scopes and plan.
+func (s *serviceAuth) RotateAPIKey(ctx context.Context, id string, grace time.Duration) (string, APIKey, error) {
+	old, err := s.queryAPIKey(ctx, "id", id)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	if old.Revoked() {
+		return "", APIKey{}, ErrInvalidAPIKey
+	}
+
+	var ttl time.Duration
+	if !old.ExpiresAt.IsZero() {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	key, info, err := s.CreateAPIKey(ctx, old.Owner, old.Name, ttl, WithAPIKeyScopes(old.Scopes...), WithAPIKeyPlan(old.Plan))
+	if err != nil {
+		return "", APIKey{}, err
+	}
+
+	if grace <= 0 {
+		return key, info, s.RevokeAPIKey(ctx, id)
+	}
+
+	// Shorten the lifetime of the old key to the grace period, unless it expires sooner anyway.
+	expiresAt := time.Now().UTC().Add(grace).Unix()
+	const query = "UPDATE api_keys SET expires_at = ? WHERE id = ? AND (expires_at = 0 OR expires_at > ?)"
+	if _, err := s.db.ExecContext(ctx, query, expiresAt, id, expiresAt); err != nil {
+		return "", APIKey{}, fmt.Errorf("database: failed to shorten the old API key: %w", err)
+	}
+
+	var hash string
+	if err := s.db.QueryRowContext(ctx, "SELECT key_hash FROM api_keys WHERE id = ?", id).Scan(&hash); err != nil {
+		return "", APIKey{}, err
+	}
+	return key, info, s.uncacheAPIKey(hash)
+}
+
+// ListAPIKeys returns the metadata of the API keys of the owner, newest first.
+func (s *serviceAuth) ListAPIKeys(ctx context.Context, owner string) ([]APIKey, error) {
+	const query = "SELECT " + apiKeyColumns + " FROM api_keys WHERE owner = ? ORDER BY created_at DESC, id"
+	rows, err := s.db.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		info, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, info)
+	}
+	return keys, rows.Err()
+}
+
+// apiKeyColumns are the columns read by scanAPIKey.
+const apiKeyColumns = "id, owner, name, prefix, scopes, plan, created_at, expires_at, last_used_at, revoked_at"
+
+// queryAPIKey returns the API key whose column (either "id" or "key_hash") matches the value.
+func (s *serviceAuth) queryAPIKey(ctx context.Context, column, value string) (APIKey, error) {
+	query := "SELECT " + apiKeyColumns + " FROM api_keys WHERE " + column + " = ?"
+	info, err := scanAPIKey(s.db.QueryRowContext(ctx, query, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	return info, err
+}
+
+// scanAPIKey scans a row of apiKeyColumns.
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (APIKey, error) {
+	var (
+		info                                   APIKey
+		scopes                                 string
+		createdAt, expiresAt, lastUsed, revoke int64
+	)
+	if err := row.Scan(&info.ID, &info.Owner, &info.Name, &info.Prefix, &scopes, &info.Plan,
+		&createdAt, &expiresAt, &lastUsed, &revoke); err != nil {
+		return APIKey{}, err
+	}
+	info.Scopes = slices.DeleteFunc(strings.Split(scopes, " "), func(s string) bool { return s == "" })
+	info.CreatedAt = timeOrZero(createdAt)
+	info.ExpiresAt = timeOrZero(expiresAt)
+	info.LastUsedAt = timeOrZero(lastUsed)
+	info.RevokedAt = timeOrZero(revoke)
+	return info, nil
+}
+
+// cachedAPIKey returns the cached metadata of the key with the given hash.
+func (s *serviceAuth) cachedAPIKey(hash string) (APIKey, bool) {
+	if s.fiberStorage == nil {
+		return APIKey{}, false
+	}
+	data, err := s.fiberStorage.Get(apiKeyCachePrefix + hash)
+	if err != nil || len(data) == 0 {
+		if err != nil {
+			log.LogErrorf("Failed to get API key from cache: %v", err)
+		}
+		return APIKey{}, false
+	}
+
+	var info APIKey
+	if err := sonic.Unmarshal(data, &info); err != nil {
+		log.LogErrorf("Failed to unmarshal API key from cache: %v", err)
+		return APIKey{}, false
+	}
+	return info, true
+}
+
+// cacheAPIKey caches the metadata of a valid key, for no longer than it remains valid.
+func (s *serviceAuth) cacheAPIKey(hash string, info APIKey, now time.Time) {
+	if s.fiberStorage == nil {
+		return
+	}
+	ttl := apiKeyCacheTTL
+	if !info.ExpiresAt.IsZero() {
+		ttl = min(ttl, info.ExpiresAt.Sub(now))
+	}
+
+	data, err := sonic.Marshal(info)
+	if err != nil {
+		log.LogErrorf("Failed to marshal API key for cache: %v", err)
+		return
+	}
+	if err := s.fiberStorage.Set(apiKeyCachePrefix+hash, data, ttl); err != nil {
+		log.LogErrorf("Failed to cache API key: %v", err)
+	}
+}
+
+// uncacheAPIKey removes the key with the given hash from the cache.
+func (s *serviceAuth) uncacheAPIKey(hash string) error {
+	if s.fiberStorage == nil {
+		return nil
+	}
+	return s.fiberStorage.Delete(apiKeyCachePrefix + hash)
+}
+
+// newAPIKeyID returns a random public ID for an API key.
+func newAPIKeyID() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// unixOrZero returns the Unix seconds of t, or 0 for the zero time.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// timeOrZero is the inverse of unixOrZero.
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
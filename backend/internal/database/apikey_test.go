@@ -0,0 +1,207 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAPIKeyService(t *testing.T) database.Service {
+	t.Helper()
+	db := newInProcessService(t, ":memory:")
+	if err := database.CreateAPIKeysTable(context.Background(), db); err != nil {
+		t.Fatalf("CreateAPIKeysTable() error = %v", err)
+	}
+	// Creating it twice must be a no-op.
+	if err := database.CreateAPIKeysTable(context.Background(), db); err != nil {
+		t.Fatalf("CreateAPIKeysTable() second call error = %v", err)
+	}
+	return db
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	db := newAPIKeyService(t)
+	auth := db.Auth()
+	ctx := context.Background()
+
+	key, info, err := auth.CreateAPIKey(ctx, "gopher", "ci", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, "sk-") || !strings.HasPrefix(key, info.Prefix) || len(info.Prefix) >= len(key) {
+		t.Errorf("CreateAPIKey() = %q with prefix %q, want an sk- key starting with its prefix", key, info.Prefix)
+	}
+
+	// Only the keyed hash is stored, never the key.
+	var stored int
+	db.QueryRow(ctx, "SELECT COUNT(*) FROM api_keys WHERE key_hash = ? OR prefix = ?", key, key).Scan(&stored)
+	if stored != 0 {
+		t.Error("the plaintext key is stored in the database")
+	}
+
+	got, err := auth.ValidateAPIKey(ctx, key)
+	if err != nil || got.ID != info.ID || got.Owner != "gopher" {
+		t.Fatalf("ValidateAPIKey() = %+v, %v, want key %s of gopher", got, err, info.ID)
+	}
+	if got.LastUsedAt.IsZero() {
+		t.Error("LastUsedAt is zero after a validation")
+	}
+
+	// The second validation is served from the cache.
+	if _, err := auth.ValidateAPIKey(ctx, key); err != nil {
+		t.Fatalf("ValidateAPIKey() from cache error = %v", err)
+	}
+
+	for _, bad := range []string{"", "sk-", key + "x", strings.ToUpper(key)} {
+		if _, err := auth.ValidateAPIKey(ctx, bad); !errors.Is(err, database.ErrInvalidAPIKey) {
+			t.Errorf("ValidateAPIKey(%q) error = %v, want %v", bad, err, database.ErrInvalidAPIKey)
+		}
+	}
+
+	// Revocation takes effect immediately, even though the key is cached.
+	if err := auth.RevokeAPIKey(ctx, info.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if _, err := auth.ValidateAPIKey(ctx, key); !errors.Is(err, database.ErrInvalidAPIKey) {
+		t.Errorf("ValidateAPIKey() after revocation error = %v, want %v", err, database.ErrInvalidAPIKey)
+	}
+	if err := auth.RevokeAPIKey(ctx, "missing"); !errors.Is(err, database.ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey() of a missing key error = %v, want %v", err, database.ErrAPIKeyNotFound)
+	}
+}
+
+func TestAPIKeyExpired(t *testing.T) {
+	auth := newAPIKeyService(t).Auth()
+	ctx := context.Background()
+
+	key, _, err := auth.CreateAPIKey(ctx, "gopher", "short-lived", time.Second)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if _, err := auth.ValidateAPIKey(ctx, key); err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := auth.ValidateAPIKey(ctx, key); !errors.Is(err, database.ErrExpiredAPIKey) {
+		t.Errorf("ValidateAPIKey() after expiration error = %v, want %v", err, database.ErrExpiredAPIKey)
+	}
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		grace      time.Duration
+		wantOldErr error
+	}{
+		{"immediate", 0, database.ErrInvalidAPIKey},
+		{"grace period", time.Hour, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := newAPIKeyService(t).Auth()
+			ctx := context.Background()
+
+			oldKey, old, _ := auth.CreateAPIKey(ctx, "gopher", "deploy", 24*time.Hour)
+			auth.ValidateAPIKey(ctx, oldKey) // cache it
+
+			newKey, rotated, err := auth.RotateAPIKey(ctx, old.ID, tt.grace)
+			if err != nil {
+				t.Fatalf("RotateAPIKey() error = %v", err)
+			}
+			if rotated.ID == old.ID || rotated.Name != "deploy" || rotated.ExpiresAt.Sub(rotated.CreatedAt) != 24*time.Hour {
+				t.Errorf("RotateAPIKey() = %+v, want a new key with the same name and lifetime", rotated)
+			}
+			if _, err := auth.ValidateAPIKey(ctx, newKey); err != nil {
+				t.Errorf("ValidateAPIKey() of the new key error = %v", err)
+			}
+
+			info, err := auth.ValidateAPIKey(ctx, oldKey)
+			if !errors.Is(err, tt.wantOldErr) {
+				t.Errorf("ValidateAPIKey() of the old key error = %v, want %v", err, tt.wantOldErr)
+			}
+			if err == nil && info.ExpiresAt.After(time.Now().Add(tt.grace)) {
+				t.Errorf("old key expires at %v, want within the grace period", info.ExpiresAt)
+			}
+
+			keys, err := auth.ListAPIKeys(ctx, "gopher")
+			if err != nil || len(keys) != 2 {
+				t.Fatalf("ListAPIKeys() = %d keys, %v, want 2", len(keys), err)
+			}
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	auth := newAPIKeyService(t).Auth()
+	ctx := context.Background()
+
+	auth.CreateAPIKey(ctx, "gopher", "first", 0)
+	auth.CreateAPIKey(ctx, "ferris", "other owner", 0)
+
+	keys, err := auth.ListAPIKeys(ctx, "gopher")
+	if err != nil {
+		t.Fatalf("ListAPIKeys() error = %v", err)
+	}
+	if len(keys) != 1 || keys[0].Name != "first" || !keys[0].ExpiresAt.IsZero() {
+		t.Errorf("ListAPIKeys() = %+v, want the one non-expiring key of gopher", keys)
+	}
+
+	if keys, err := auth.ListAPIKeys(ctx, "nobody"); err != nil || len(keys) != 0 {
+		t.Errorf("ListAPIKeys() of an unknown owner = %+v, %v, want none", keys, err)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	auth := newAPIKeyService(t).Auth()
+	ctx := context.Background()
+
+	if _, _, err := auth.CreateAPIKey(ctx, "gopher", "bad", 0, database.WithAPIKeyScopes("health read")); err == nil {
+		t.Error("CreateAPIKey() with a scope containing a space error = nil, want an error")
+	}
+
+	key, info, err := auth.CreateAPIKey(ctx, "gopher", "scoped", 0,
+		database.WithAPIKeyScopes("health:read", "users:*"), database.WithAPIKeyPlan("pro"))
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	got, err := auth.ValidateAPIKey(ctx, key)
+	if err != nil || got.Plan != "pro" || len(got.Scopes) != 2 {
+		t.Fatalf("ValidateAPIKey() = %+v, %v, want the scopes and plan", got, err)
+	}
+
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"health:read", true},
+		{"health:write", false},
+		{"users:read", true},
+		{"users:delete", true},
+		{"users", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if has := got.HasScope(tt.scope); has != tt.want {
+			t.Errorf("HasScope(%q) = %v, want %v", tt.scope, has, tt.want)
+		}
+	}
+	if !(database.APIKey{Scopes: []string{"*"}}).HasScope("anything:at-all") {
+		t.Error(`HasScope() with "*" = false, want true`)
+	}
+
+	// Rotation keeps the scopes and the plan.
+	_, rotated, err := auth.RotateAPIKey(ctx, info.ID, 0)
+	if err != nil || rotated.Plan != "pro" || len(rotated.Scopes) != 2 {
+		t.Errorf("RotateAPIKey() = %+v, %v, want the same scopes and plan", rotated, err)
+	}
+}
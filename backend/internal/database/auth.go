@@ -9,8 +9,10 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/bcrypt"
 
@@ -22,6 +24,10 @@ var (
 	ErrInvalidAPIKey = errors.New("Invalid API key")
 	// ErrExpiredAPIKey is a custom error variable that represents an API key expired.
 	ErrExpiredAPIKey = errors.New("API Key Expired")
+	// ErrAPIKeyNotFound is returned when revoking or rotating an API key ID that doesn't exist.
+	ErrAPIKeyNotFound = errors.New("database: API key not found")
+	// ErrAPIKeyHashSecret is returned when API_KEY_HASH_SECRET is missing or shorter than minAPIKeyHashSecretLength.
+	ErrAPIKeyHashSecret = errors.New("database: API_KEY_HASH_SECRET must be set to at least 32 bytes")
 )
 
 // ServiceAuth is an interface that defines methods for user authentication and management.
@@ -29,6 +35,54 @@ var (
 type ServiceAuth interface {
 	// FiberStorage returns the [fiber.Storage] interface for fiber storage middleware.
 	FiberStorage() fiber.Storage
+
+	// CreateAPIKey generates a new API key for the owner and stores its keyed hash.
+	// The plaintext key is only returned here, so it must be shown to the owner right away.
+	// A ttl of zero creates a key that never expires. The options set its scopes and quota plan.
+	CreateAPIKey(ctx context.Context, owner, name string, ttl time.Duration, opts ...APIKeyOption) (string, APIKey, error)
+
+	// ValidateAPIKey returns the API key metadata if the key is valid. It returns ErrInvalidAPIKey
+	// when the key doesn't exist or was revoked, and ErrExpiredAPIKey when it expired.
+	ValidateAPIKey(ctx context.Context, key string) (APIKey, error)
+
+	// RevokeAPIKey revokes the API key with the given ID, effective immediately.
+	RevokeAPIKey(ctx context.Context, id string) error
+
+	// RotateAPIKey replaces the API key with the given ID by a new one with the same owner, name, lifetime, scopes and plan.
+	// The old key stays valid for the grace period (e.g., while the clients are updated), or is revoked right away when it's zero.
+	RotateAPIKey(ctx context.Context, id string, grace time.Duration) (string, APIKey, error)
+
+	// ListAPIKeys returns the metadata of the API keys of the owner, newest first, including the revoked and expired ones.
+	ListAPIKeys(ctx context.Context, owner string) ([]APIKey, error)
+
+	// CreateUser registers a new user, storing a bcrypt hash of the password.
+	// It returns ErrUserExists when the username or email is already taken.
+	CreateUser(ctx context.Context, username, email, password string) (User, error)
+
+	// AuthenticateUser returns the user whose username or email is login, if the password matches.
+	// It returns ErrInvalidCredentials otherwise, whether the user exists or not.
+	AuthenticateUser(ctx context.Context, login, password string) (User, error)
+
+	// GetUser returns the user with the given ID, or ErrUserNotFound.
+	GetUser(ctx context.Context, id string) (User, error)
+
+	// FindUser returns the user whose username or email is login, or ErrUserNotFound.
+	FindUser(ctx context.Context, login string) (User, error)
+
+	// ChangePassword replaces the password of the user, after checking the current one.
+	ChangePassword(ctx context.Context, id, currentPassword, newPassword string) error
+
+	// SignInWithIdentity returns the user linked to an external identity (e.g., an OAuth2 account).
+	// An identity seen for the first time is linked to the user with the same email when the provider verified it,
+	// or to a new user otherwise. It returns ErrUserExists when the email belongs to a user but isn't verified.
+	SignInWithIdentity(ctx context.Context, identity Identity) (User, error)
+
+	// LinkIdentity links an external identity to the user (e.g., a logged-in user connecting a GitHub account).
+	// It returns ErrIdentityLinked when the identity is already linked to another user.
+	LinkIdentity(ctx context.Context, userID string, identity Identity) error
+
+	// ListIdentities returns the external identities linked to the user, oldest first.
+	ListIdentities(ctx context.Context, userID string) ([]Identity, error)
 }
 
 // serviceAuth is a concrete implementation of the ServiceAuth interface.
@@ -37,6 +91,7 @@ type serviceAuth struct {
 	db           *sql.DB
 	fiberStorage fiber.Storage
 	bcrypt       bcrypt.Service
+	hashKey      []byte // the secret of the keyed hash of API keys, see API_KEY_HASH_SECRET
 }
 
 // NewServiceAuth creates a new instance of the ServiceAuth interface.
@@ -46,6 +101,7 @@ func NewServiceAuth(db *sql.DB, fiberStorage fiber.Storage, bcryptService bcrypt
 		db:           db,
 		fiberStorage: fiberStorage,
 		bcrypt:       bcryptService,
+		hashKey:      []byte(apiKeyHashSecret),
 	}
 }
 
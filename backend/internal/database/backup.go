@@ -259,7 +259,7 @@ func (s *service) backupTableToWriter(tableName string, w io.Writer, batchSize i
 	defer cancel()
 
 	// Start a transaction to ensure data consistency
-	tx, err := s.db.Begin()
+	tx, err := s.sqlDB().Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -282,14 +282,14 @@ func (s *service) backupTableToWriter(tableName string, w io.Writer, batchSize i
 }
 
 // dumpTableSchema writes the CREATE TABLE statement for the specified table to the object.
+//
+// Note: MySQL uses "SHOW CREATE TABLE", while SQLite reads it from sqlite_master (see [Dialect]).
 func (s *service) dumpTableSchema(ctx context.Context, w io.Writer, tableName string) error {
-	query := fmt.Sprintf("SHOW CREATE TABLE `%s`", tableName)
-	row := s.db.QueryRowContext(ctx, query)
-	var table, createTableStmt string
-	if err := row.Scan(&table, &createTableStmt); err != nil {
+	createTableStmt, err := s.dialect.ShowCreateTable(ctx, s.sqlDB(), tableName)
+	if err != nil {
 		return fmt.Errorf("failed to get create table statement: %w", err)
 	}
-	_, err := fmt.Fprintf(w, "%s;\n\n", createTableStmt)
+	_, err = fmt.Fprintf(w, "%s;\n\n", createTableStmt)
 	return err
 }
 
@@ -319,7 +319,7 @@ func (s *service) dumpTableData(ctx context.Context, w io.Writer, tableName stri
 		return fmt.Errorf("batch size must be greater than 0, got %d", batchSize)
 	}
 
-	query := fmt.Sprintf("SELECT * FROM `%s`", tableName)
+	query := fmt.Sprintf("SELECT * FROM %s", s.dialect.QuoteIdent(tableName))
 	rows, err := s.StreamRows(ctx, query)
 	if err != nil {
 		return fmt.Errorf("failed to query table data: %w", err)
@@ -350,11 +350,11 @@ func (s *service) dumpTableData(ctx context.Context, w io.Writer, tableName stri
 			return fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		insertStmt := buildValuesString(values)
+		insertStmt := buildValuesString(s.dialect, values)
 		insertStatements = append(insertStatements, insertStmt)
 
 		if len(insertStatements) >= batchSize {
-			fullInsert := buildInsertStatement(tableName, columns, insertStatements)
+			fullInsert := buildInsertStatement(s.dialect, tableName, columns, insertStatements)
 			if _, err := fmt.Fprint(w, fullInsert); err != nil {
 				return err
 			}
@@ -363,7 +363,7 @@ func (s *service) dumpTableData(ctx context.Context, w io.Writer, tableName stri
 	}
 
 	if len(insertStatements) > 0 {
-		fullInsert := buildInsertStatement(tableName, columns, insertStatements)
+		fullInsert := buildInsertStatement(s.dialect, tableName, columns, insertStatements)
 		if _, err := fmt.Fprint(w, fullInsert); err != nil {
 			return err
 		}
@@ -377,15 +377,15 @@ func (s *service) dumpTableData(ctx context.Context, w io.Writer, tableName stri
 // Note: This differs from MySQL Dumper and PhpMyAdmin Export, both of which use single-row INSERT statements for data.
 // This implementation uses multi-row INSERT statements + Batching, which can improve performance when importing large datasets
 // and help avoid MySQL deadlocks (not due to Go, but inherent to MySQL itself).
-func buildInsertStatement(tableName string, columns []string, values []string) string {
+func buildInsertStatement(d Dialect, tableName string, columns []string, values []string) string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("INSERT INTO `%s` (", tableName))
+	sb.WriteString(fmt.Sprintf("INSERT INTO %s (", d.QuoteIdent(tableName)))
 	// This is now correct and can be imported via phpMyAdmin as well.
 	for i, column := range columns {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(fmt.Sprintf("`%s`", column))
+		sb.WriteString(d.QuoteIdent(column))
 	}
 	sb.WriteString(valuesObject)
 
@@ -395,7 +395,7 @@ func buildInsertStatement(tableName string, columns []string, values []string) s
 }
 
 // buildValuesString constructs the VALUES part of an SQL INSERT statement.
-func buildValuesString(values []any) string {
+func buildValuesString(d Dialect, values []any) string {
 	var sb strings.Builder
 	sb.WriteString("(")
 	for i, val := range values {
@@ -405,13 +405,13 @@ func buildValuesString(values []any) string {
 		if val == nil {
 			sb.WriteString(nullObject)
 		} else if b, ok := val.([]byte); ok {
-			sb.WriteString(fmt.Sprintf("'%s'", escapeString(string(b))))
+			sb.WriteString(fmt.Sprintf("'%s'", d.EscapeString(string(b))))
 		} else {
 			switch v := val.(type) {
 			case int64, float64, bool:
 				sb.WriteString(fmt.Sprintf("%v", v))
 			default:
-				sb.WriteString(fmt.Sprintf("'%s'", escapeString(fmt.Sprintf("%v", v))))
+				sb.WriteString(fmt.Sprintf("'%s'", d.EscapeString(fmt.Sprintf("%v", v))))
 			}
 		}
 	}
@@ -0,0 +1,307 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"math/rand/v2"
+	"reflect"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrNotFound is returned by [GetOrLoad] when the loader reported that the value does not exist
+	// (either by returning ErrNotFound itself or [sql.ErrNoRows]), including when the miss is served from the negative cache.
+	ErrNotFound = errors.New("database: not found")
+
+	// ErrCacheStorageMissing is returned when the cache has no storage configured.
+	ErrCacheStorageMissing = errors.New("database: cache storage is not configured")
+)
+
+// Codec encodes and decodes cached values.
+//
+// Note: The default is sonic, which is the same JSON encoder/decoder used by the Fiber app (see "cmd/server/run.go").
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// sonicCodec is the default [Codec] backed by sonic.
+type sonicCodec struct{}
+
+func (sonicCodec) Marshal(v any) ([]byte, error)      { return sonic.Marshal(v) }
+func (sonicCodec) Unmarshal(data []byte, v any) error { return sonic.Unmarshal(data, v) }
+
+// Scanner is implemented by anything that can delete keys by pattern (e.g., [Service.ScanAndDel]).
+type Scanner interface {
+	ScanAndDel(ctx context.Context, patterns []string) error
+}
+
+// CacheConfig defines the config for the cache-aside helper.
+type CacheConfig struct {
+	// Storage is where the cached values live (e.g., Redis, Cloudflare KV).
+	//
+	// Required.
+	Storage fiber.Storage
+
+	// Scanner is used by InvalidatePattern.
+	//
+	// Optional. Default: nil (InvalidatePattern returns an error).
+	Scanner Scanner
+
+	// Codec encodes and decodes the cached values.
+	//
+	// Optional. Default: sonic.
+	Codec Codec
+
+	// Prefix is prepended to every key.
+	//
+	// Optional. Default: "cache:".
+	Prefix string
+
+	// NegativeTTL is how long a miss (ErrNotFound or sql.ErrNoRows from the loader) is remembered,
+	// so that repeated lookups for something that doesn't exist won't hammer MySQL.
+	// Set it to a negative value to disable negative caching.
+	//
+	// Optional. Default: 1 minute.
+	NegativeTTL time.Duration
+
+	// Jitter is the maximum fraction of the TTL that is randomly added to it (e.g., 0.1 = up to +10%),
+	// which spreads out expirations of keys that were written together.
+	// Set it to a negative value to disable jitter.
+	//
+	// Optional. Default: 0.1.
+	Jitter float64
+}
+
+// CacheConfigDefault is the default config.
+var CacheConfigDefault = CacheConfig{
+	Codec:       sonicCodec{},
+	Prefix:      "cache:",
+	NegativeTTL: time.Minute,
+	Jitter:      0.1,
+}
+
+// Cache implements the cache-aside pattern (read cache -> load from the main database on a miss -> write back to cache).
+//
+// Use it with [GetOrLoad], since Go doesn't allow generic methods.
+type Cache struct {
+	cfg   CacheConfig
+	group singleflight.Group
+}
+
+// Cached value markers, stored as the first byte of every entry.
+const (
+	cacheMarkerValue    byte = 'v'
+	cacheMarkerNegative byte = 'n'
+)
+
+// NewCache creates a new cache-aside helper.
+func NewCache(config ...CacheConfig) *Cache {
+	cfg := CacheConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Codec == nil {
+			cfg.Codec = CacheConfigDefault.Codec
+		}
+		if cfg.Prefix == "" {
+			cfg.Prefix = CacheConfigDefault.Prefix
+		}
+		if cfg.NegativeTTL == 0 {
+			cfg.NegativeTTL = CacheConfigDefault.NegativeTTL
+		}
+		if cfg.Jitter == 0 {
+			cfg.Jitter = CacheConfigDefault.Jitter
+		}
+	}
+	return &Cache{cfg: cfg}
+}
+
+// GetOrLoad returns the cached value for key, or calls loader on a miss and writes the result back with the given TTL.
+//
+// Concurrent misses for the same key are collapsed into a single loader call (stampede protection).
+// When loader returns [ErrNotFound] or [sql.ErrNoRows], the miss is cached for NegativeTTL and ErrNotFound is returned.
+// Other loader errors are returned as-is and nothing is cached.
+//
+// Note: Cache storage errors are logged and treated as a miss, so a Redis outage degrades to reading MySQL directly
+// instead of failing the request.
+//
+// Example Usage:
+//
+//	user, err := database.GetOrLoad(ctx, db.Cache(), "user:"+id, 10*time.Minute, func(ctx context.Context) (User, error) {
+//	    var u User
+//	    err := db.QueryRow(ctx, "SELECT id, name FROM users WHERE id = ?", id).Scan(&u.ID, &u.Name)
+//	    return u, err
+//	})
+func GetOrLoad[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if c == nil || c.cfg.Storage == nil {
+		return zero, ErrCacheStorageMissing
+	}
+
+	fullKey := c.cfg.Prefix + key
+
+	if v, hit, err := cacheGet[T](c, fullKey); hit {
+		return v, err
+	}
+
+	// Note: The loader is shared by every caller waiting on the same key, so it must not be canceled
+	// just because the first caller went away. Each caller still stops waiting when its own context is done.
+	// The calls are grouped by type as well, so callers loading the same key as different types don't share a result.
+	// The type comes from T rather than from the zero value, which is nil for an interface type.
+	typ := reflect.TypeFor[T]()
+	ch := c.group.DoChan(typ.String()+":"+fullKey, func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultCtxTimeout)
+		defer cancel()
+
+		// Check again, another caller may have filled the cache while this one was waiting for the group.
+		if v, hit, err := cacheGet[T](c, fullKey); hit {
+			return v, err
+		}
+
+		v, err := loader(loadCtx)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows) {
+				if c.cfg.NegativeTTL > 0 {
+					c.set(fullKey, []byte{cacheMarkerNegative}, c.cfg.NegativeTTL)
+				}
+				return zero, ErrNotFound
+			}
+			return zero, err
+		}
+
+		data, err := c.cfg.Codec.Marshal(v)
+		if err != nil {
+			return zero, fmt.Errorf("database: failed to encode cache value for key %s: %w", key, err)
+		}
+		c.set(fullKey, append([]byte{cacheMarkerValue}, data...), c.jitter(ttl))
+		return v, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// A nil value of an interface type (e.g., a loader returning a nil io.Reader) loses its type in any.
+		if res.Val == nil {
+			return zero, nil
+		}
+		v, ok := res.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("database: cached value for key %s is %T, not %s", key, res.Val, typ)
+		}
+		return v, nil
+	}
+}
+
+// cacheGet reads and decodes a cached entry. The hit result is false when the caller should load the value.
+func cacheGet[T any](c *Cache, fullKey string) (T, bool, error) {
+	var v T
+	data, err := c.cfg.Storage.Get(fullKey)
+	if err != nil {
+		log.LogErrorf("Failed to get cache key %s: %v", fullKey, err)
+		return v, false, nil
+	}
+	if len(data) == 0 {
+		return v, false, nil
+	}
+
+	switch data[0] {
+	case cacheMarkerNegative:
+		return v, true, ErrNotFound
+	case cacheMarkerValue:
+		err := c.cfg.Codec.Unmarshal(data[1:], &v)
+		if err == nil {
+			return v, true, nil
+		}
+		log.LogErrorf("Failed to decode cache key %s: %v", fullKey, err)
+	}
+
+	// Unknown or corrupted entry, drop it and load again.
+	if err := c.cfg.Storage.Delete(fullKey); err != nil {
+		log.LogErrorf("Failed to delete invalid cache key %s: %v", fullKey, err)
+	}
+	return v, false, nil
+}
+
+// set writes an entry, logging instead of failing since the value was already loaded.
+func (c *Cache) set(fullKey string, data []byte, ttl time.Duration) {
+	if err := c.cfg.Storage.Set(fullKey, data, ttl); err != nil {
+		log.LogErrorf("Failed to set cache key %s: %v", fullKey, err)
+	}
+}
+
+// jitter adds up to Jitter*ttl to the TTL. A zero TTL (no expiration) is left untouched.
+func (c *Cache) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || c.cfg.Jitter <= 0 {
+		return ttl
+	}
+	maxJitter := int64(float64(ttl) * c.cfg.Jitter)
+	if maxJitter <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Int64N(maxJitter))
+}
+
+// Invalidate removes the given keys (without the prefix) from the cache, including negative entries.
+// It should be called after the main database was updated.
+func (c *Cache) Invalidate(_ context.Context, keys ...string) error {
+	if c.cfg.Storage == nil {
+		return ErrCacheStorageMissing
+	}
+	var errs []error
+	for _, key := range keys {
+		if err := c.cfg.Storage.Delete(c.cfg.Prefix + key); err != nil {
+			errs = append(errs, fmt.Errorf("key %s: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InvalidatePattern removes every cached key matching the given patterns (without the prefix, e.g., "user:*")
+// using SCAN + DEL, so it won't block Redis even with millions of keys.
+//
+// Note: The SCAN + DEL goes to Redis directly, so the in-process cache in front of the storage (see [tiered.Storage])
+// is purged as a whole afterwards, since it can't be matched by pattern. The other pods purge theirs on the keyspace
+// notifications of the deleted keys, or on the cache invalidation event of the event bus (see [PurgeL1]).
+func (c *Cache) InvalidatePattern(ctx context.Context, patterns ...string) error {
+	if c.cfg.Scanner == nil {
+		return fmt.Errorf("database: cache has no scanner configured for pattern invalidation")
+	}
+	prefixed := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		prefixed = append(prefixed, c.cfg.Prefix+p)
+	}
+	err := c.cfg.Scanner.ScanAndDel(ctx, prefixed)
+	PurgeL1(c.cfg.Storage)
+	return err
+}
+
+// PurgeL1 clears the in-process cache in front of the storage (see [tiered.Storage.Purge]), if any,
+// without touching the storage behind it.
+//
+// Example Usage:
+//
+//	// Purge L1 on every pod that receives a cache invalidation event.
+//	eventbus.InvalidateCacheHandler(bus, db, func(ctx context.Context, prefix string) {
+//	    database.PurgeL1(db.FiberStorage())
+//	})
+func PurgeL1(storage fiber.Storage) {
+	if l1, ok := storage.(interface{ Purge() }); ok {
+		l1.Purge()
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"h0llyw00dz-template/backend/internal/database/tiered"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mapStorage is a minimal fiber.Storage used for testing the cache-aside helper without Redis.
+type mapStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	ttls map[string]time.Duration
+}
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{data: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (m *mapStorage) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data[key], nil
+}
+
+func (m *mapStorage) Set(key string, val []byte, exp time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = val
+	m.ttls[key] = exp
+	return nil
+}
+
+func (m *mapStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapStorage) Reset() error { return nil }
+func (m *mapStorage) Close() error { return nil }
+
+// ScanAndDel deletes keys matching a simple "prefix*" pattern.
+func (m *mapStorage) ScanAndDel(_ context.Context, patterns []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range patterns {
+		prefix := strings.TrimSuffix(p, "*")
+		for k := range m.data {
+			if strings.HasPrefix(k, prefix) {
+				delete(m.data, k)
+			}
+		}
+	}
+	return nil
+}
+
+type cachedUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGetOrLoad(t *testing.T) {
+	storage := newMapStorage()
+	cache := database.NewCache(database.CacheConfig{Storage: storage, Scanner: storage})
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	loader := func(context.Context) (cachedUser, error) {
+		calls.Add(1)
+		return cachedUser{ID: 1, Name: "gopher"}, nil
+	}
+
+	for range 3 {
+		got, err := database.GetOrLoad(ctx, cache, "user:1", time.Minute, loader)
+		if err != nil {
+			t.Fatalf("GetOrLoad() error = %v", err)
+		}
+		if got.Name != "gopher" {
+			t.Fatalf("GetOrLoad() = %+v, want gopher", got)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+
+	ttl := storage.ttls["cache:user:1"]
+	if ttl < time.Minute || ttl > time.Minute+6*time.Second {
+		t.Errorf("TTL with jitter = %v, want within [1m, 1m6s]", ttl)
+	}
+
+	if err := cache.Invalidate(ctx, "user:1"); err != nil {
+		t.Fatalf("Invalidate() error = %v", err)
+	}
+	if _, err := database.GetOrLoad(ctx, cache, "user:1", time.Minute, loader); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("loader called %d times after Invalidate, want 2", n)
+	}
+
+	if err := cache.InvalidatePattern(ctx, "user:*"); err != nil {
+		t.Fatalf("InvalidatePattern() error = %v", err)
+	}
+	if _, ok := storage.data["cache:user:1"]; ok {
+		t.Errorf("InvalidatePattern() did not remove the key")
+	}
+}
+
+func TestGetOrLoadNegativeCaching(t *testing.T) {
+	tests := []struct {
+		name      string
+		loaderErr error
+		wantErr   error
+		wantCalls int32
+	}{
+		{"sql.ErrNoRows is cached", sql.ErrNoRows, database.ErrNotFound, 1},
+		{"ErrNotFound is cached", database.ErrNotFound, database.ErrNotFound, 1},
+		{"other errors are not cached", errors.New("boom"), nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := database.NewCache(database.CacheConfig{Storage: newMapStorage()})
+			var calls atomic.Int32
+			for range 3 {
+				_, err := database.GetOrLoad(context.Background(), cache, "missing", time.Minute, func(context.Context) (string, error) {
+					calls.Add(1)
+					return "", tt.loaderErr
+				})
+				want := tt.wantErr
+				if want == nil {
+					want = tt.loaderErr
+				}
+				if !errors.Is(err, want) {
+					t.Fatalf("GetOrLoad() error = %v, want %v", err, want)
+				}
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("loader called %d times, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadSingleflight(t *testing.T) {
+	cache := database.NewCache(database.CacheConfig{Storage: newMapStorage()})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := database.GetOrLoad(context.Background(), cache, "answer", time.Minute, loader); err != nil || v != 42 {
+				t.Errorf("GetOrLoad() = %d, %v", v, err)
+			}
+		}()
+	}
+
+	// Give the goroutines a moment to pile up on the same key.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("loader called %d times, want 1", n)
+	}
+}
+
+func TestGetOrLoadSingleflightByType(t *testing.T) {
+	cache := database.NewCache(database.CacheConfig{Storage: newMapStorage()})
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if v, err := database.GetOrLoad(context.Background(), cache, "answer", time.Minute, func(context.Context) (int, error) {
+			<-release
+			return 42, nil
+		}); err != nil || v != 42 {
+			t.Errorf("GetOrLoad[int]() = %d, %v, want 42", v, err)
+		}
+	}()
+
+	// Give the first caller a moment to hold the key.
+	time.Sleep(50 * time.Millisecond)
+
+	// The same key loaded as another type doesn't wait for, nor get, the value of the first caller.
+	v, err := database.GetOrLoad(context.Background(), cache, "answer", time.Minute, func(context.Context) (string, error) {
+		return "forty-two", nil
+	})
+	if err != nil || v != "forty-two" {
+		t.Errorf("GetOrLoad[string]() = %q, %v, want %q", v, err, "forty-two")
+	}
+	close(release)
+	<-done
+}
+
+func TestGetOrLoadNilInterface(t *testing.T) {
+	cache := database.NewCache(database.CacheConfig{Storage: newMapStorage()})
+
+	// A loader of an interface type may return a nil value, which is a value, not a type mismatch.
+	for range 2 {
+		v, err := database.GetOrLoad(context.Background(), cache, "nothing", time.Minute, func(context.Context) (any, error) {
+			return nil, nil
+		})
+		if err != nil || v != nil {
+			t.Errorf("GetOrLoad[any]() = %v, %v, want nil, nil", v, err)
+		}
+	}
+}
+
+func TestInvalidatePatternPurgesL1(t *testing.T) {
+	backend := newMapStorage()
+	l1, err := tiered.New(tiered.Config{Backend: backend, TTL: time.Minute, DisableAdmission: true})
+	if err != nil {
+		t.Fatalf("tiered.New() error = %v", err)
+	}
+	defer l1.Close()
+	cache := database.NewCache(database.CacheConfig{Storage: l1, Scanner: backend})
+
+	var calls atomic.Int32
+	load := func(context.Context) (string, error) {
+		calls.Add(1)
+		return "gopher", nil
+	}
+	for range 2 {
+		if _, err := database.GetOrLoad(context.Background(), cache, "user:1", time.Minute, load); err != nil {
+			t.Fatalf("GetOrLoad() error = %v", err)
+		}
+	}
+
+	// The keys are deleted behind L1, which must not keep serving them.
+	if err := cache.InvalidatePattern(context.Background(), "user:*"); err != nil {
+		t.Fatalf("InvalidatePattern() error = %v", err)
+	}
+	if _, err := database.GetOrLoad(context.Background(), cache, "user:1", time.Minute, load); err != nil {
+		t.Fatalf("GetOrLoad() error = %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("loader calls = %d, want 2", got)
+	}
+}
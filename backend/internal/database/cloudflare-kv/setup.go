@@ -36,7 +36,9 @@ var (
 // Recommended Usage: MYSQL -> Cloudflare-KV
 //
 // Note: This must implement "vice versa" method, for example when the data such as username not stored in cloudflare kv storage
-// then fetch it from Mysql -> stored in this cloudflare kv storage with expiration
+// then fetch it from Mysql -> stored in this cloudflare kv storage with expiration.
+// This is what database.GetOrLoad does; pass this storage to database.NewCache (note that InvalidatePattern
+// is not available for Cloudflare KV since it has no SCAN, so use Invalidate with explicit keys).
 func (config *FiberCloudflareKVClientConfig) InitializeCfkvStorage() (fiber.Storage, error) {
 
 	storage := cloudflarekv.New(cloudflarekv.Config{
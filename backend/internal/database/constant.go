@@ -26,6 +26,28 @@ const (
 const (
 	MySQLConnect = "%s:%s@tcp(%s:%s)/%s"
 	dbMYSQL      = "mysql"
+	dbSQLITE     = "sqlite"
+)
+
+// Supported values for DB_DRIVER and RDB_MODE.
+const (
+	// DriverMySQL uses MySQL (or MariaDB) as the main database. This is the default.
+	DriverMySQL = "mysql"
+
+	// DriverSQLite uses pure-Go SQLite as the main database, which is useful for local development and tests.
+	DriverSQLite = "sqlite"
+
+	// RedisModeStandalone connects to a single Redis server. This is the default.
+	RedisModeStandalone = "standalone"
+
+	// RedisModeSentinel connects to the master monitored by Redis Sentinel and follows it on failover.
+	RedisModeSentinel = "sentinel"
+
+	// RedisModeCluster connects to a Redis Cluster.
+	RedisModeCluster = "cluster"
+
+	// RedisModeMemory runs an in-process Redis stand-in, which is useful for local development and tests.
+	RedisModeMemory = "memory"
 )
 
 // Message constants for Redis-related operations.
@@ -0,0 +1,112 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Dialect abstracts the SQL differences between the supported backends (MySQL and SQLite),
+// mainly for the parts that can't be written in portable SQL (e.g., backups, error codes).
+//
+// Note: Placeholders ("?") are the same for both backends, so regular queries don't need the dialect.
+// Keep queries portable (e.g., avoid "ON DUPLICATE KEY UPDATE") when they should also run on SQLite.
+type Dialect interface {
+	// Name returns the name of the dialect (e.g., "mysql", "sqlite").
+	Name() string
+
+	// QuoteIdent quotes an identifier such as a table or column name.
+	QuoteIdent(name string) string
+
+	// EscapeString escapes a string so it can be used inside a single-quoted SQL literal.
+	EscapeString(value string) string
+
+	// ShowCreateTable returns the CREATE TABLE statement for the table.
+	ShowCreateTable(ctx context.Context, db *sql.DB, table string) (string, error)
+
+	// IsDuplicateEntry reports whether err is a unique or primary key violation.
+	IsDuplicateEntry(err error) bool
+}
+
+var (
+	// MySQLDialect is the dialect for MySQL (and MariaDB).
+	MySQLDialect Dialect = mysqlDialect{}
+
+	// SQLiteDialect is the dialect for SQLite.
+	SQLiteDialect Dialect = sqliteDialect{}
+)
+
+// mysqlDialect implements [Dialect] for MySQL.
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return DriverMySQL }
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (mysqlDialect) EscapeString(value string) string { return escapeString(value) }
+
+func (d mysqlDialect) ShowCreateTable(ctx context.Context, db *sql.DB, table string) (string, error) {
+	var name, createTableStmt string
+	query := fmt.Sprintf("SHOW CREATE TABLE %s", d.QuoteIdent(table))
+	if err := db.QueryRowContext(ctx, query).Scan(&name, &createTableStmt); err != nil {
+		return "", err
+	}
+	return createTableStmt, nil
+}
+
+// IsDuplicateEntry checks for MySQL error number 1062 (ER_DUP_ENTRY).
+func (mysqlDialect) IsDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// sqliteDialect implements [Dialect] for SQLite.
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return DriverSQLite }
+
+func (sqliteDialect) QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// EscapeString only doubles single quotes, since SQLite doesn't treat backslashes as escape characters.
+func (sqliteDialect) EscapeString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
+// ShowCreateTable reads the original CREATE TABLE statement from sqlite_master,
+// since SQLite doesn't have "SHOW CREATE TABLE".
+func (sqliteDialect) ShowCreateTable(ctx context.Context, db *sql.DB, table string) (string, error) {
+	var createTableStmt string
+	const query = "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if err := db.QueryRowContext(ctx, query, table).Scan(&createTableStmt); err != nil {
+		return "", err
+	}
+	return createTableStmt, nil
+}
+
+// IsDuplicateEntry checks for SQLITE_CONSTRAINT_UNIQUE and SQLITE_CONSTRAINT_PRIMARYKEY.
+func (sqliteDialect) IsDuplicateEntry(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return true
+	}
+	return false
+}
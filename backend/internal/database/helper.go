@@ -3,8 +3,9 @@
 // By accessing or using this software, you agree to be bound by the terms
 // of the License Agreement, which you can find at LICENSE files.
 
-// Note: The database package here is not covered by tests and won't have tests implemented for it,
+// Note: The database package here is not covered by tests against MySQL and won't have those tests implemented for it,
 // as it is not worth testing the database that requires authentication. (literally stupid testing that requires authentication unlike mock)
+// Instead, use NewInProcess (SQLite + in-process Redis), which doesn't require any external services.
 
 package database
 
@@ -85,12 +86,9 @@ func convertStringToInterface(strs []string) []any {
 //
 // Note: This function relies on the [github.com/go-sql-driver/mysql] package for the MySQLError type.
 // Additionaly Use [WrapMySQLError] function can be used across the codebase to handle various MySQL error types with custom messages.
+// It also recognizes the SQLite unique/primary key constraint errors (see [Dialect]), so the same code works when DB_DRIVER=sqlite.
 func isDuplicateEntryError(err error) bool {
-	var mysqlErr *mysql.MySQLError
-	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
-		return true
-	}
-	return false
+	return MySQLDialect.IsDuplicateEntry(err) || SQLiteDialect.IsDuplicateEntry(err)
 }
 
 // IsValidTableName checks if the table name is valid to prevent SQL injection.
@@ -0,0 +1,192 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrIdentityLinked is returned when linking an external identity that is already linked to another user.
+var ErrIdentityLinked = errors.New("database: identity already linked to another user")
+
+// UserIdentitiesTable is the name of the table linking the external identities to the users (see [CreateUserIdentitiesTable]).
+const UserIdentitiesTable = "user_identities"
+
+// maxUsernameAttempts is how many usernames are tried when creating a user for an identity, see usernameFor.
+const maxUsernameAttempts = 5
+
+// Identity is a user account at an external identity provider (e.g., Google or GitHub through OAuth2).
+//
+// Note: Subject is the stable ID of the account at the provider (e.g., the "sub" claim of OIDC),
+// unlike the email and the username, which the user can change there.
+type Identity struct {
+	Provider      string    `json:"provider"`
+	Subject       string    `json:"subject"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Username      string    `json:"username,omitempty"` // only used to pick the username of a new user
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// CreateUserIdentitiesTable creates the user identities table if it doesn't exist.
+func CreateUserIdentitiesTable(ctx context.Context, db Service) error {
+	query := `CREATE TABLE IF NOT EXISTS user_identities (
+	provider VARCHAR(64) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY (provider, subject)`
+
+	if db.Dialect().Name() == DriverMySQL {
+		// MySQL has no "CREATE INDEX IF NOT EXISTS", so the index is declared inline.
+		return db.ExecWithoutRow(ctx, query+",\n\tINDEX idx_user_identities_user (user_id)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+
+	if err := db.ExecWithoutRow(ctx, query+"\n)"); err != nil {
+		return err
+	}
+	return db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id)")
+}
+
+// SignInWithIdentity returns the user linked to the identity, linking it first when it's seen for the first time.
+//
+// Note: An identity is only linked to an existing user by email when the provider verified the email,
+// otherwise anyone could sign in as anyone by registering their email at a provider that doesn't verify it.
+func (s *serviceAuth) SignInWithIdentity(ctx context.Context, identity Identity) (User, error) {
+	if identity.Provider == "" || identity.Subject == "" {
+		return User{}, errors.New("database: identity without provider or subject")
+	}
+
+	userID, err := s.identityUserID(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return s.GetUser(ctx, userID)
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return User{}, err
+	}
+
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		return User{}, err
+	}
+	identity.Email = email
+
+	user, _, err := s.queryUser(ctx, "email", email)
+	switch {
+	case err == nil && !identity.EmailVerified:
+		return User{}, ErrUserExists
+	case errors.Is(err, ErrUserNotFound):
+		if user, err = s.createUserForIdentity(ctx, identity); err != nil {
+			return User{}, err
+		}
+	case err != nil:
+		return User{}, err
+	}
+
+	if err := s.LinkIdentity(ctx, user.ID, identity); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// LinkIdentity links the identity to the user. Linking it again to the same user is a no-op.
+func (s *serviceAuth) LinkIdentity(ctx context.Context, userID string, identity Identity) error {
+	const query = "INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?)"
+	_, err := s.db.ExecContext(ctx, query, identity.Provider, identity.Subject, userID, identity.Email, time.Now().UTC().Unix())
+	if err == nil {
+		return nil
+	}
+	if !isDuplicateEntryError(err) {
+		return fmt.Errorf("database: failed to link identity: %w", err)
+	}
+
+	linkedTo, err := s.identityUserID(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return err
+	}
+	if linkedTo != userID {
+		return ErrIdentityLinked
+	}
+	return nil
+}
+
+// ListIdentities returns the identities linked to the user, oldest first.
+func (s *serviceAuth) ListIdentities(ctx context.Context, userID string) ([]Identity, error) {
+	const query = "SELECT provider, subject, email, created_at FROM user_identities WHERE user_id = ? ORDER BY created_at, provider"
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []Identity
+	for rows.Next() {
+		var (
+			identity  Identity
+			createdAt int64
+		)
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.Email, &createdAt); err != nil {
+			return nil, err
+		}
+		identity.CreatedAt = time.Unix(createdAt, 0).UTC()
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// identityUserID returns the ID of the user linked to the identity, or ErrUserNotFound.
+func (s *serviceAuth) identityUserID(ctx context.Context, provider, subject string) (string, error) {
+	var userID string
+	const query = "SELECT user_id FROM user_identities WHERE provider = ? AND subject = ?"
+	err := s.db.QueryRowContext(ctx, query, provider, subject).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return userID, err
+}
+
+// createUserForIdentity creates a user without a password for the identity,
+// with a username derived from the one at the provider, or else from the email.
+func (s *serviceAuth) createUserForIdentity(ctx context.Context, identity Identity) (User, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	for attempt := range maxUsernameAttempts {
+		user, err := s.insertUser(ctx, usernameFor(base, attempt), identity.Email, "")
+		if !errors.Is(err, ErrUserExists) {
+			return user, err
+		}
+		// The email was checked by the caller, so it's most likely the username that is taken.
+	}
+	return User{}, ErrUserExists
+}
+
+// usernameFor turns base into a valid username. After the first attempt, a random suffix is appended,
+// since the username is taken.
+func usernameFor(base string, attempt int) string {
+	username := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return -1
+	}, base)
+
+	if attempt > 0 || len(username) < 3 {
+		username = username[:min(len(username), 23)] + "-" + uuid.NewString()[:8]
+	}
+	return username[:min(len(username), 32)]
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"strings"
+	"testing"
+)
+
+func TestSignInWithIdentity(t *testing.T) {
+	db := newUserService(t)
+	ctx := context.Background()
+	if err := database.CreateUserIdentitiesTable(ctx, db); err != nil {
+		t.Fatalf("CreateUserIdentitiesTable() error = %v", err)
+	}
+	auth := db.Auth()
+
+	taken, _ := auth.CreateUser(ctx, "gopher", "gopher@example.com", "correct horse")
+
+	// The username is taken, so the new user gets a suffixed one.
+	identity := database.Identity{Provider: "github", Subject: "1", Email: "gopher@users.example.com", Username: "gopher"}
+	user, err := auth.SignInWithIdentity(ctx, identity)
+	if err != nil {
+		t.Fatalf("SignInWithIdentity() error = %v", err)
+	}
+	if user.ID == taken.ID || !strings.HasPrefix(user.Username, "gopher-") {
+		t.Errorf("SignInWithIdentity() = %+v, want a new user with a suffixed username", user)
+	}
+
+	// A user created for an identity has no password.
+	if _, err := auth.AuthenticateUser(ctx, user.Username, ""); !errors.Is(err, database.ErrInvalidCredentials) {
+		t.Errorf("AuthenticateUser() without password error = %v, want %v", err, database.ErrInvalidCredentials)
+	}
+
+	// The identity stays linked to its user, even when the email changes at the provider.
+	identity.Email = "renamed@example.com"
+	if again, err := auth.SignInWithIdentity(ctx, identity); err != nil || again.ID != user.ID {
+		t.Errorf("SignInWithIdentity() again = %+v, %v, want %s", again, err, user.ID)
+	}
+
+	if err := auth.LinkIdentity(ctx, taken.ID, identity); !errors.Is(err, database.ErrIdentityLinked) {
+		t.Errorf("LinkIdentity() to another user error = %v, want %v", err, database.ErrIdentityLinked)
+	}
+	if err := auth.LinkIdentity(ctx, user.ID, identity); err != nil {
+		t.Errorf("LinkIdentity() to the same user error = %v", err)
+	}
+
+	if _, err := auth.SignInWithIdentity(ctx, database.Identity{Provider: "github", Subject: "2"}); !errors.Is(err, database.ErrInvalidEmail) {
+		t.Errorf("SignInWithIdentity() without email error = %v, want %v", err, database.ErrInvalidEmail)
+	}
+}
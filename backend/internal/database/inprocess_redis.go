@@ -0,0 +1,42 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	redisStorage "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+// initializeInProcessRedis starts an in-process Redis stand-in and connects both the Redis client
+// and the Fiber storage to it, so the app can boot with zero external services.
+//
+// Note: This speaks the real Redis protocol over a loopback port, so everything that uses the Redis client
+// (e.g., ScanAndDel, pipelines, rate limiting) works the same way. However, it's not a full Redis
+// (e.g., no RedisJSON, limited INFO), and the data is lost on restart, so don't use it in production.
+func initializeInProcessRedis(in *instrumentation) (*miniredis.Miniredis, redis.UniversalClient, fiber.Storage, error) {
+	embedded, err := miniredis.Run()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	redisClient := in.instrumentRedis(newInProcessRedisClient(embedded))
+
+	// Note: The Fiber storage gets its own client, just like with a real Redis, so closing one doesn't affect the other.
+	storage := redisStorage.NewFromConnection(in.instrumentRedis(newInProcessRedisClient(embedded)))
+
+	return embedded, redisClient, storage, nil
+}
+
+// newInProcessRedisClient creates a Redis client connected to the in-process Redis stand-in.
+func newInProcessRedisClient(embedded *miniredis.Miniredis) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:                  embedded.Addr(),
+		PoolSize:              defaultFiberMaxConnections,
+		ContextTimeoutEnabled: true,
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newInProcessService(t *testing.T, path string) database.Service {
+	t.Helper()
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(path)
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestNewInProcess(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"memory", ":memory:"},
+		{"file", filepath.Join(t.TempDir(), "app.db")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newInProcessService(t, tt.path)
+			ctx := context.Background()
+
+			if db.Dialect().Name() != database.DriverSQLite {
+				t.Fatalf("Dialect().Name() = %q, want %q", db.Dialect().Name(), database.DriverSQLite)
+			}
+			if !db.PingDB(ctx) {
+				t.Fatal("PingDB() = false, want true")
+			}
+			if !db.Ready() {
+				t.Fatal("Ready() = false, want true")
+			}
+
+			if err := db.ExecWithoutRow(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT NOT NULL UNIQUE, bio TEXT)"); err != nil {
+				t.Fatalf("CREATE TABLE error = %v", err)
+			}
+			if err := db.ExecWithoutRow(ctx, "INSERT INTO users (username, bio) VALUES (?, ?), (?, ?)",
+				"gopher", "it's a gopher", "ferris", nil); err != nil {
+				t.Fatalf("INSERT error = %v", err)
+			}
+
+			_, err := db.Exec(ctx, "INSERT INTO users (username) VALUES (?)", "gopher")
+			if !db.Dialect().IsDuplicateEntry(err) {
+				t.Errorf("IsDuplicateEntry(%v) = false, want true", err)
+			}
+
+			var id int64
+			if err := db.QueryRow(ctx, "SELECT id FROM users WHERE username = ?", "ferris").Scan(&id); err != nil || id != 2 {
+				t.Errorf("QueryRow() = %d, %v, want 2", id, err)
+			}
+
+			rows, err := db.Query(ctx, "SELECT username FROM users ORDER BY id")
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			var names []string
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				names = append(names, name)
+			}
+			rows.Close()
+			if strings.Join(names, ",") != "gopher,ferris" {
+				t.Errorf("Query() = %v, want [gopher ferris]", names)
+			}
+
+			var buf bytes.Buffer
+			if err := db.BackupTablesConcurrently([]string{"users"}, &buf, 100); err != nil {
+				t.Fatalf("BackupTablesConcurrently() error = %v", err)
+			}
+			backup := buf.String()
+			for _, want := range []string{
+				"CREATE TABLE users",
+				`INSERT INTO "users" ("id", "username", "bio") VALUES`,
+				"'it''s a gopher'",
+				"NULL",
+			} {
+				if !strings.Contains(backup, want) {
+					t.Errorf("backup does not contain %q:\n%s", want, backup)
+				}
+			}
+		})
+	}
+}
+
+func TestNewInProcessRedis(t *testing.T) {
+	db := newInProcessService(t, ":memory:")
+	ctx := context.Background()
+
+	storage := db.FiberStorage()
+	if err := storage.Set("session:1", []byte("gopher"), time.Minute); err != nil {
+		t.Fatalf("FiberStorage().Set() error = %v", err)
+	}
+	if v, err := db.RedisClient().Get(ctx, "session:1").Result(); err != nil || v != "gopher" {
+		t.Errorf("RedisClient().Get() = %q, %v, want gopher (shared with the Fiber storage)", v, err)
+	}
+
+	var calls int
+	loader := func(context.Context) (string, error) {
+		calls++
+		return "", database.ErrNotFound
+	}
+	for range 2 {
+		if _, err := database.GetOrLoad(ctx, db.Cache(), "user:404", time.Minute, loader); !errors.Is(err, database.ErrNotFound) {
+			t.Fatalf("GetOrLoad() error = %v, want ErrNotFound", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loader called %d times, want 1", calls)
+	}
+
+	for i := range 20 {
+		if err := storage.Set(fmt.Sprintf("cache:user:%d", i), []byte("v"), 0); err != nil {
+			t.Fatalf("FiberStorage().Set() error = %v", err)
+		}
+	}
+	if err := db.Cache().InvalidatePattern(ctx, "user:*"); err != nil {
+		t.Fatalf("InvalidatePattern() error = %v", err)
+	}
+	if keys := db.RedisClient().Keys(ctx, "cache:user:*").Val(); len(keys) != 0 {
+		t.Errorf("InvalidatePattern() left %d key(s): %v", len(keys), keys)
+	}
+
+	if err := db.RestartRedisConnection(); err != nil {
+		t.Fatalf("RestartRedisConnection() error = %v", err)
+	}
+	if health := db.Health(""); health["redis_status"] != "up" || health["mysql_status"] != "up" || health["supervisor_ready"] != "true" {
+		t.Errorf("Health() = %v, want redis and sql up", health)
+	}
+}
@@ -0,0 +1,442 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/redis/go-redis/v9"
+	"modernc.org/sqlite"
+)
+
+// Operations used as the "operation" metric label.
+const (
+	opExec          = "exec"
+	opQuery         = "query"
+	opQueryRow      = "query_row"
+	opStreamRows    = "stream_rows"
+	opBeginTx       = "begin_tx"
+	opPrepare       = "prepare"
+	opRedis         = "redis"
+	opRedisPipeline = "redis_pipeline"
+)
+
+// Supported values for DB_SLOW_QUERY_ARGS.
+const (
+	// SlowQueryArgsRedact logs the type and size of every argument, but not its value. This is the default.
+	SlowQueryArgsRedact = "redact"
+
+	// SlowQueryArgsPlain logs the arguments as-is. Don't use it in production, since arguments can be passwords or tokens.
+	SlowQueryArgsPlain = "plain"
+
+	// SlowQueryArgsOff doesn't log the arguments at all.
+	SlowQueryArgsOff = "off"
+)
+
+const (
+	// fingerprintOther is the statement label used once the number of distinct fingerprints hits the limit.
+	fingerprintOther = "other"
+
+	// maxFingerprintLength bounds the length of a statement label.
+	maxFingerprintLength = 160
+
+	// maxLoggedArgs bounds the number of arguments written to the slow-query log.
+	maxLoggedArgs = 10
+)
+
+// InstrumentationConfig defines the settings of the query instrumentation.
+type InstrumentationConfig struct {
+	// SlowQueryThreshold is the duration above which a call is written to the slow-query log. Zero disables the log.
+	SlowQueryThreshold time.Duration
+
+	// SlowQueryArgs controls how the arguments are written to the slow-query log:
+	// SlowQueryArgsRedact (default), SlowQueryArgsPlain, or SlowQueryArgsOff.
+	SlowQueryArgs string
+
+	// MaxFingerprints caps the number of distinct statement labels, so dynamically built queries can't blow up
+	// the cardinality of the metrics. Statements past the cap are labelled "other".
+	MaxFingerprints int
+}
+
+// instrumentation records the duration and errors of every database call as Prometheus metrics,
+// and writes the slow ones to the slow-query log.
+//
+// Note: Statements are labelled by their fingerprint (see [fingerprint]) instead of the raw query,
+// so "SELECT * FROM users WHERE id = 1" and "... id = 2" end up in the same series.
+type instrumentation struct {
+	config       InstrumentationConfig
+	mu           sync.RWMutex
+	fingerprints map[string]string // raw query -> fingerprint
+	distinct     map[string]struct{}
+}
+
+// newInstrumentation creates the instrumentation with the given configuration.
+func newInstrumentation(config InstrumentationConfig) *instrumentation {
+	return &instrumentation{
+		config:       config,
+		fingerprints: make(map[string]string),
+		distinct:     make(map[string]struct{}),
+	}
+}
+
+// observe records a single call. The query is fingerprinted for the statement label.
+func (in *instrumentation) observe(op, query string, args []any, elapsed time.Duration, err error) {
+	in.record(op, in.fingerprint(query), query, args, elapsed, err)
+}
+
+// record records a single call with an already computed statement label.
+func (in *instrumentation) record(op, statement, query string, args []any, elapsed time.Duration, err error) {
+	queryDuration.WithLabelValues(op, statement).Observe(elapsed.Seconds())
+
+	if err != nil {
+		queryErrors.WithLabelValues(op, errorCode(err)).Inc()
+	}
+
+	if in.config.SlowQueryThreshold > 0 && elapsed >= in.config.SlowQueryThreshold {
+		slowQueries.WithLabelValues(op).Inc()
+		log.LogInfof("Slow %s (%s >= %s): %s%s", op, elapsed.Round(time.Microsecond), in.config.SlowQueryThreshold,
+			query, formatArgs(args, in.config.SlowQueryArgs))
+	}
+}
+
+// fingerprint returns the cached fingerprint of the query, or "other" once there are too many distinct fingerprints.
+func (in *instrumentation) fingerprint(query string) string {
+	in.mu.RLock()
+	fp, ok := in.fingerprints[query]
+	in.mu.RUnlock()
+	if ok {
+		return fp
+	}
+
+	fp = fingerprint(query)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if _, known := in.distinct[fp]; !known {
+		if len(in.distinct) >= in.config.MaxFingerprints {
+			return fingerprintOther
+		}
+		in.distinct[fp] = struct{}{}
+	}
+	// Note: Raw queries are only cached while the cache is small, since a query with inlined values
+	// is different every time and would otherwise grow the cache forever.
+	if len(in.fingerprints) < in.config.MaxFingerprints*4 {
+		in.fingerprints[query] = fp
+	}
+	return fp
+}
+
+var (
+	// tupleRe matches a parenthesized list of placeholders, e.g. "(?, ?, ?)".
+	tupleRe = regexp.MustCompile(`\(\?(?: ?, ?\?)+\)`)
+
+	// tuplesRe matches repeated tuples, e.g. "VALUES (?+), (?+), (?+)".
+	tuplesRe = regexp.MustCompile(`(\(\?\+?\))(?: ?, ?\(\?\+?\))+`)
+)
+
+// fingerprint normalizes a SQL statement so that statements that only differ by their values share the same label:
+//
+//   - string and numeric literals become "?"
+//   - comments are removed and whitespace is collapsed
+//   - everything outside quoted identifiers is lowercased
+//   - lists of placeholders become "(?+)", and repeated tuples (e.g., bulk inserts) become a single one
+//
+// For example, "SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'gopher'" becomes
+// "select * from users where id in (?+) and name = ?".
+func fingerprint(query string) string {
+	var (
+		b     strings.Builder
+		space bool
+	)
+	b.Grow(len(query))
+
+	writeSpace := func() {
+		if b.Len() > 0 {
+			space = true
+		}
+	}
+	write := func(s string) {
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			writeSpace()
+
+		case c == '-' && i+1 < len(query) && query[i+1] == '-', c == '#':
+			// Line comment
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			writeSpace()
+
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			// Block comment
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+			writeSpace()
+
+		case c == '\'' || c == '"':
+			// String literal, where the quote is escaped either by a backslash or by doubling it.
+			for i++; i < len(query); i++ {
+				if query[i] == '\\' {
+					i++
+					continue
+				}
+				if query[i] == c {
+					if i+1 < len(query) && query[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+			write("?")
+
+		case c == '`':
+			// Quoted identifier, kept as-is.
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				write(query[i:])
+				i = len(query)
+				break
+			}
+			write(query[i : i+end+2])
+			i += end + 1
+
+		case isDigit(c) && (space || !isIdentByte(prevByte(&b))):
+			// Numeric literal (including hex, decimals, and exponents).
+			for i+1 < len(query) && (isIdentByte(query[i+1]) || query[i+1] == '.') {
+				i++
+			}
+			write("?")
+
+		default:
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			if space && (c == ',' || c == ')') {
+				space = false
+			}
+			write(string(c))
+			if c == '(' {
+				space = false
+				// Skip the spaces right after an opening parenthesis.
+				for i+1 < len(query) && (query[i+1] == ' ' || query[i+1] == '\n' || query[i+1] == '\t') {
+					i++
+				}
+			}
+		}
+	}
+
+	fp := tupleRe.ReplaceAllString(b.String(), "(?+)")
+	fp = tuplesRe.ReplaceAllString(fp, "$1")
+	return truncate(fp, maxFingerprintLength)
+}
+
+// prevByte returns the last byte written to b, or 0 when empty.
+func prevByte(b *strings.Builder) byte {
+	if s := b.String(); len(s) > 0 {
+		return s[len(s)-1]
+	}
+	return 0
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// isIdentByte reports whether c can be part of an unquoted identifier or a numeric literal.
+func isIdentByte(c byte) bool {
+	return isDigit(c) || c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// truncate shortens s to at most n runes, marking the cut with "...".
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-3]) + "..."
+}
+
+// formatArgs formats the arguments for the slow-query log according to the mode.
+func formatArgs(args []any, mode string) string {
+	if len(args) == 0 || mode == SlowQueryArgsOff {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(" args=[")
+	for i, arg := range args {
+		if i == maxLoggedArgs {
+			fmt.Fprintf(&b, ", ... (%d more)", len(args)-maxLoggedArgs)
+			break
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if mode == SlowQueryArgsPlain {
+			fmt.Fprintf(&b, "%v", arg)
+		} else {
+			b.WriteString(redactArg(arg))
+		}
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// redactArg describes an argument without leaking its value. Numbers, booleans, times, and NULL are kept,
+// since they are rarely sensitive and help when reproducing a slow query, while strings and bytes only show their size.
+func redactArg(arg any) string {
+	switch v := arg.(type) {
+	case nil:
+		return nullObject
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case string:
+		return fmt.Sprintf("<redacted string len=%d>", len(v))
+	case []byte:
+		return fmt.Sprintf("<redacted bytes len=%d>", len(v))
+	default:
+		return fmt.Sprintf("<redacted %T>", v)
+	}
+}
+
+// errorCode returns the "code" metric label for an error: the MySQL error number (e.g., "1062"),
+// the SQLite extended code prefixed by "sqlite_", the first word of a Redis error prefixed by "redis_" (e.g., "redis_WRONGTYPE"),
+// or a generic class (timeout, canceled, bad_conn, network, other).
+func errorCode(err error) string {
+	var (
+		mysqlErr  *mysql.MySQLError
+		sqliteErr *sqlite.Error
+		redisErr  redis.Error
+		netErr    net.Error
+	)
+	switch {
+	case errors.As(err, &mysqlErr):
+		return strconv.Itoa(int(mysqlErr.Number))
+	case errors.As(err, &sqliteErr):
+		return "sqlite_" + strconv.Itoa(sqliteErr.Code())
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.Is(err, mysql.ErrInvalidConn):
+		return "bad_conn"
+	case errors.As(err, &redisErr):
+		word, _, _ := strings.Cut(redisErr.Error(), " ")
+		return "redis_" + word
+	case errors.As(err, &netErr):
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// redisHook instruments every Redis command and pipeline sent by a client,
+// which covers the Service methods (e.g., SetKeysAtPipeline) as well as the Fiber storage.
+type redisHook struct {
+	in *instrumentation
+}
+
+var _ redis.Hook = redisHook{}
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		// Note: redis.Nil only means the key doesn't exist, so it's not counted as an error.
+		h.in.record(opRedis, cmd.Name(), cmd.Name(), cmd.Args()[1:], time.Since(start), ignoreRedisNil(err))
+		return err
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		statement := pipelineStatement(cmds)
+		// Note: The arguments (keys and values) are not logged for pipelines, since there can be thousands of them.
+		h.in.record(opRedisPipeline, statement, fmt.Sprintf("%s (%d commands)", statement, len(cmds)), nil, time.Since(start), ignoreRedisNil(err))
+		return err
+	}
+}
+
+// pipelineStatement labels a pipeline by its distinct command names, e.g. "get" or "json.set,expire".
+func pipelineStatement(cmds []redis.Cmder) string {
+	names := make([]string, 0, 2)
+	for _, cmd := range cmds {
+		if name := cmd.Name(); !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return truncate(strings.Join(names, ","), maxFingerprintLength)
+}
+
+// ignoreRedisNil returns nil for [redis.Nil].
+func ignoreRedisNil(err error) error {
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	return err
+}
+
+// instrumentRedis adds the instrumentation hook to a Redis client and returns it.
+func (in *instrumentation) instrumentRedis(client redis.UniversalClient) redis.UniversalClient {
+	client.AddHook(redisHook{in: in})
+	return client
+}
+
+// parseInstrumentationConfig parses the instrumentation configuration from environment variables.
+func parseInstrumentationConfig() (InstrumentationConfig, error) {
+	threshold, err := time.ParseDuration(slowQueryThreshold)
+	if err != nil || threshold < 0 {
+		return InstrumentationConfig{}, fmt.Errorf("invalid slow query threshold value: %q", slowQueryThreshold)
+	}
+
+	switch slowQueryArgs {
+	case SlowQueryArgsRedact, SlowQueryArgsPlain, SlowQueryArgsOff:
+	default:
+		return InstrumentationConfig{}, fmt.Errorf("invalid slow query args value: %q (supported: %q, %q, %q)",
+			slowQueryArgs, SlowQueryArgsRedact, SlowQueryArgsPlain, SlowQueryArgsOff)
+	}
+
+	return InstrumentationConfig{
+		SlowQueryThreshold: threshold,
+		SlowQueryArgs:      slowQueryArgs,
+		MaxFingerprints:    500,
+	}, nil
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM users WHERE id = 1", "select * from users where id = ?"},
+		{"SELECT * FROM users WHERE id = ?", "select * from users where id = ?"},
+		{"select  *\n\tfrom users where name = 'it''s a gopher' and bio = \"x\\\"y\"", "select * from users where name = ? and bio = ?"},
+		{"SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'gopher'", "select * from users where id in (?+) and name = ?"},
+		{"SELECT * FROM users WHERE id IN ( ?,?,? )", "select * from users where id in (?+)"},
+		{"INSERT INTO users (name, bio) VALUES (?, ?), (?, ?), (?, ?)", "insert into users (name, bio) values (?+)"},
+		{"INSERT INTO `Users` (name) VALUES ('a')", "insert into `Users` (name) values (?)"},
+		{"SELECT id FROM t2 -- trailing comment\nWHERE x > 0.5e3 /* inline */ LIMIT 10", "select id from t2 where x > ? limit ?"},
+		{"SELECT 0xFF, col1 FROM t", "select ?, col1 from t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := fingerprint(tt.query); got != tt.want {
+				t.Errorf("fingerprint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := "SELECT " + strings.Repeat("column_name, ", 50) + "id FROM t"
+	if got := fingerprint(long); len(got) != maxFingerprintLength || !strings.HasSuffix(got, "...") {
+		t.Errorf("fingerprint() of a long query = %q (len %d), want it truncated to %d", got, len(got), maxFingerprintLength)
+	}
+}
+
+func TestInstrumentationFingerprintLimit(t *testing.T) {
+	in := newInstrumentation(InstrumentationConfig{MaxFingerprints: 2})
+	for i := range 2 {
+		if got := in.fingerprint(fmt.Sprintf("SELECT * FROM t%d WHERE id = %d", i, i)); got == fingerprintOther {
+			t.Fatalf("fingerprint() = %q before hitting the limit", got)
+		}
+	}
+	if got := in.fingerprint("SELECT * FROM t0 WHERE id = 42"); got != "select * from t0 where id = ?" {
+		t.Errorf("fingerprint() of a known statement = %q, want it kept", got)
+	}
+	if got := in.fingerprint("SELECT * FROM t3"); got != fingerprintOther {
+		t.Errorf("fingerprint() past the limit = %q, want %q", got, fingerprintOther)
+	}
+}
+
+func TestFormatArgs(t *testing.T) {
+	when := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	args := []any{42, "hunter2", []byte("secret"), nil, true, when}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{SlowQueryArgsRedact, " args=[42, <redacted string len=7>, <redacted bytes len=6>, NULL, true, 2025-01-02T03:04:05Z]"},
+		{SlowQueryArgsPlain, " args=[42, hunter2, [115 101 99 114 101 116], <nil>, true, 2025-01-02 03:04:05 +0000 UTC]"},
+		{SlowQueryArgsOff, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			if got := formatArgs(args, tt.mode); got != tt.want {
+				t.Errorf("formatArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	many := make([]any, 15)
+	if got := formatArgs(many, SlowQueryArgsRedact); !strings.HasSuffix(got, ", ... (5 more)]") {
+		t.Errorf("formatArgs() of 15 args = %q, want it capped", got)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"mysql", fmt.Errorf("wrapped: %w", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}), "1062"},
+		{"timeout", context.DeadlineExceeded, "timeout"},
+		{"canceled", context.Canceled, "canceled"},
+		{"bad conn", mysql.ErrInvalidConn, "bad_conn"},
+		{"redis", redis.ErrClosed, "other"},
+		{"other", errors.New("boom"), "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorCode(tt.err); got != tt.want {
+				t.Errorf("errorCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceInstrumentation(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	s := db.(*service)
+	s.instr.config.SlowQueryThreshold = time.Nanosecond // everything is slow
+	ctx := context.Background()
+
+	const (
+		createStmt = "create table gophers (id integer primary key, name text not null unique)"
+		insertStmt = "insert into gophers (name) values (?)"
+		selectStmt = "select id from gophers where name = ?"
+	)
+	before := sampleCount(t, opExec, insertStmt)
+	slowBefore := testutil.ToFloat64(slowQueries.WithLabelValues(opExec))
+	dupBefore := testutil.ToFloat64(queryErrors.WithLabelValues(opExec, "sqlite_2067"))
+
+	if err := db.ExecWithoutRow(ctx, strings.ToUpper(createStmt)); err != nil {
+		t.Fatalf("CREATE TABLE error = %v", err)
+	}
+	for _, name := range []string{"gopher", "ferris", "gopher"} {
+		db.Exec(ctx, "INSERT INTO gophers (name) VALUES (?)", name)
+	}
+	var id int
+	if err := db.QueryRow(ctx, "SELECT id FROM gophers WHERE name = ?", "ferris").Scan(&id); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if err := db.SetKeysAtPipeline(ctx, map[string]any{"a": 1, "b": 2}, time.Minute); err != nil {
+		t.Fatalf("SetKeysAtPipeline() error = %v", err)
+	}
+
+	if got := sampleCount(t, opExec, insertStmt) - before; got != 3 {
+		t.Errorf("samples for %q = %d, want 3", insertStmt, got)
+	}
+	if got := sampleCount(t, opQueryRow, selectStmt); got == 0 {
+		t.Errorf("no samples for %q", selectStmt)
+	}
+	if got := sampleCount(t, opRedisPipeline, "set"); got == 0 {
+		t.Error("no samples for the Redis pipeline")
+	}
+	if got := testutil.ToFloat64(queryErrors.WithLabelValues(opExec, "sqlite_2067")) - dupBefore; got != 1 {
+		t.Errorf("query_errors_total{code=sqlite_2067} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(slowQueries.WithLabelValues(opExec)) - slowBefore; got < 4 {
+		t.Errorf("slow_queries_total{operation=exec} = %v, want at least 4", got)
+	}
+
+	// The pool stats collector exports the primary SQL pool and the Redis pool.
+	collector := newPoolStatsCollector(s)
+	if got := testutil.CollectAndCount(collector, "database_sql_open_connections", "database_redis_pool_total_connections"); got != 2 {
+		t.Errorf("CollectAndCount() = %d, want 2", got)
+	}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP database_sql_max_open_connections Maximum number of open connections to the database.
+# TYPE database_sql_max_open_connections gauge
+database_sql_max_open_connections{db="primary"} 16
+`), "database_sql_max_open_connections"); err != nil {
+		t.Error(err)
+	}
+}
+
+// sampleCount returns the number of observations of the query duration histogram for the labels.
+func sampleCount(t *testing.T, op, statement string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "database_query_duration_seconds" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["operation"] == op && labels["statement"] == statement {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,286 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotAcquired is returned when the lock is held by someone else.
+	ErrLockNotAcquired = errors.New("database: lock not acquired")
+
+	// ErrLockNotHeld is returned when the lock expired or was taken over before it was released or extended.
+	ErrLockNotHeld = errors.New("database: lock not held")
+)
+
+// Lua scripts of the lock.
+//
+// Note: The lock key and its fencing counter share a hash tag, so the acquire script also works on Redis Cluster.
+var (
+	// lockAcquireScript sets the lock if it's free, then returns the next fencing token (0 when the lock is taken).
+	lockAcquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0`)
+
+	// lockReleaseScript deletes the lock only if it's still held with the given value, so an expired holder
+	// can't release a lock acquired by someone else in the meantime.
+	lockReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// lockExtendScript resets the TTL of the lock only if it's still held with the given value.
+	lockExtendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// LockConfig defines the config for the distributed lock.
+type LockConfig struct {
+	// Prefix is prepended to the lock keys.
+	//
+	// Optional. Default: "lock:".
+	Prefix string
+
+	// TTL is how long a lock is held without being extended, which bounds how long a crashed holder blocks the others.
+	//
+	// Optional. Default: 10 seconds.
+	TTL time.Duration
+
+	// RetryInterval is the delay between attempts of Lock while the lock is taken. A random jitter of up to
+	// the same delay is added, so the waiters don't all retry at the same instant.
+	//
+	// Optional. Default: 50 milliseconds.
+	RetryInterval time.Duration
+
+	// DisableAutoExtend disables the extension of held locks every TTL/3.
+	// Without it, a lock held longer than its TTL is silently released.
+	//
+	// Optional. Default: false.
+	DisableAutoExtend bool
+}
+
+// LockConfigDefault is the default config.
+var LockConfigDefault = LockConfig{
+	Prefix:        "lock:",
+	TTL:           10 * time.Second,
+	RetryInterval: 50 * time.Millisecond,
+}
+
+// Locker acquires distributed locks on Redis, so a section runs on a single pod at a time.
+//
+// Example Usage:
+//
+//	locker := database.NewLocker(db.RedisClient())
+//	lock, err := locker.Lock(ctx, "report:daily")
+//	if err != nil {
+//	    return err
+//	}
+//	defer lock.Unlock(context.Background())
+//
+//	// Pass lock.Token() along with the writes, so the storage can reject a stale holder.
+//
+// Note: Like any lock with a TTL, a holder that is paused for longer than the TTL (e.g., a long GC pause or a network
+// partition) can lose the lock without noticing. That's what the fencing tokens are for: they only grow, so a storage
+// that remembers the highest token it has seen can reject the writes of the previous holder.
+type Locker struct {
+	client redis.UniversalClient
+	cfg    LockConfig
+}
+
+// NewLocker creates a new Locker on the Redis client (e.g., [Service.RedisClient]).
+func NewLocker(client redis.UniversalClient, config ...LockConfig) *Locker {
+	cfg := LockConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Prefix == "" {
+			cfg.Prefix = LockConfigDefault.Prefix
+		}
+		if cfg.TTL <= 0 {
+			cfg.TTL = LockConfigDefault.TTL
+		}
+		if cfg.RetryInterval <= 0 {
+			cfg.RetryInterval = LockConfigDefault.RetryInterval
+		}
+	}
+	return &Locker{client: client, cfg: cfg}
+}
+
+// Lock is a held distributed lock.
+type Lock struct {
+	locker *Locker
+	name   string
+	key    string
+	value  string
+	token  int64
+
+	stop     chan struct{}
+	done     chan struct{}
+	lost     chan struct{}
+	lostOnce sync.Once
+	stopOnce sync.Once
+}
+
+// TryLock acquires the lock if it's free, otherwise it returns [ErrLockNotAcquired] right away.
+func (l *Locker) TryLock(ctx context.Context, name string) (*Lock, error) {
+	value, err := lockValue()
+	if err != nil {
+		return nil, err
+	}
+
+	key := l.key(name)
+	token, err := lockAcquireScript.Run(ctx, l.client, []string{key, key + ":fence"}, value, l.cfg.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return nil, fmt.Errorf("database: failed to acquire lock %s: %w", name, err)
+	}
+	if token == 0 {
+		return nil, ErrLockNotAcquired
+	}
+
+	lock := &Lock{
+		locker: l,
+		name:   name,
+		key:    key,
+		value:  value,
+		token:  token,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	if l.cfg.DisableAutoExtend {
+		close(lock.done)
+	} else {
+		go lock.autoExtend()
+	}
+	return lock, nil
+}
+
+// Lock waits until the lock is acquired or the context is done.
+func (l *Locker) Lock(ctx context.Context, name string) (*Lock, error) {
+	for {
+		lock, err := l.TryLock(ctx, name)
+		if !errors.Is(err, ErrLockNotAcquired) {
+			return lock, err
+		}
+
+		delay := l.cfg.RetryInterval + rand.N(l.cfg.RetryInterval)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrLockNotAcquired, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// key returns the Redis key of the lock.
+//
+// Note: The braces are a Redis Cluster hash tag, so the lock and its fencing counter live in the same slot.
+func (l *Locker) key(name string) string {
+	return l.cfg.Prefix + "{" + name + "}"
+}
+
+// Name returns the name of the lock.
+func (lk *Lock) Name() string {
+	return lk.name
+}
+
+// Token returns the fencing token of the lock. Every acquisition of the same name gets a greater token.
+func (lk *Lock) Token() int64 {
+	return lk.token
+}
+
+// Lost is closed when the lock can't be extended anymore (e.g., it expired during a Redis outage),
+// so the holder should stop the work it protects.
+func (lk *Lock) Lost() <-chan struct{} {
+	return lk.lost
+}
+
+// Extend resets the TTL of the lock. It returns [ErrLockNotHeld] when the lock expired or was taken over.
+func (lk *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	ok, err := lockExtendScript.Run(ctx, lk.locker.client, []string{lk.key}, lk.value, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("database: failed to extend lock %s: %w", lk.name, err)
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock stops the auto-extension and releases the lock. It returns [ErrLockNotHeld] when the lock expired
+// or was taken over in the meantime, which means the protected section may have overlapped with another holder.
+func (lk *Lock) Unlock(ctx context.Context) error {
+	lk.stopOnce.Do(func() { close(lk.stop) })
+	<-lk.done
+
+	ok, err := lockReleaseScript.Run(ctx, lk.locker.client, []string{lk.key}, lk.value).Int64()
+	if err != nil {
+		return fmt.Errorf("database: failed to release lock %s: %w", lk.name, err)
+	}
+	if ok == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// autoExtend extends the lock every TTL/3 until it is released or lost.
+//
+// Note: A failed extension is retried on the next tick, so a brief Redis hiccup doesn't lose the lock.
+// The lock is only reported as lost once it is taken over or once it must have expired.
+func (lk *Lock) autoExtend() {
+	defer close(lk.done)
+
+	ttl := lk.locker.cfg.TTL
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	deadline := time.Now().Add(ttl)
+	for {
+		select {
+		case <-lk.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), ttl/3)
+		err := lk.Extend(ctx, ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			deadline = time.Now().Add(ttl)
+		case errors.Is(err, ErrLockNotHeld) || time.Now().After(deadline):
+			log.LogErrorf("Lost lock %s (token %d): %v", lk.name, lk.token, err)
+			lk.lostOnce.Do(func() { close(lk.lost) })
+			return
+		}
+	}
+}
+
+// lockValue returns a random value that identifies the holder of a lock.
+func lockValue() (string, error) {
+	b := make([]byte, 16)
+	if _, err := crand.Read(b); err != nil {
+		return "", fmt.Errorf("database: failed to generate lock value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLocker(t *testing.T, config database.LockConfig) (*database.Locker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return database.NewLocker(client, config), mr
+}
+
+func TestLockerTryLock(t *testing.T) {
+	locker, _ := newTestLocker(t, database.LockConfig{DisableAutoExtend: true})
+	ctx := context.Background()
+
+	first, err := locker.TryLock(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "report"); !errors.Is(err, database.ErrLockNotAcquired) {
+		t.Fatalf("TryLock() of a held lock error = %v, want %v", err, database.ErrLockNotAcquired)
+	}
+
+	// Other names are independent.
+	other, err := locker.TryLock(ctx, "invoice")
+	if err != nil {
+		t.Fatalf("TryLock() of another name error = %v", err)
+	}
+	defer other.Unlock(ctx)
+
+	if err := first.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	second, err := locker.TryLock(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryLock() after Unlock error = %v", err)
+	}
+	defer second.Unlock(ctx)
+
+	if second.Token() <= first.Token() {
+		t.Errorf("fencing token %d is not greater than the previous one %d", second.Token(), first.Token())
+	}
+}
+
+func TestLockerExpiredHolder(t *testing.T) {
+	locker, mr := newTestLocker(t, database.LockConfig{TTL: time.Second, DisableAutoExtend: true})
+	ctx := context.Background()
+
+	stale, err := locker.TryLock(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	mr.FastForward(2 * time.Second)
+
+	current, err := locker.TryLock(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryLock() after expiration error = %v", err)
+	}
+
+	// The stale holder can neither extend nor release the lock of the current holder.
+	if err := stale.Extend(ctx, time.Second); !errors.Is(err, database.ErrLockNotHeld) {
+		t.Errorf("Extend() by the stale holder error = %v, want %v", err, database.ErrLockNotHeld)
+	}
+	if err := stale.Unlock(ctx); !errors.Is(err, database.ErrLockNotHeld) {
+		t.Errorf("Unlock() by the stale holder error = %v, want %v", err, database.ErrLockNotHeld)
+	}
+	if _, err := locker.TryLock(ctx, "report"); !errors.Is(err, database.ErrLockNotAcquired) {
+		t.Errorf("TryLock() error = %v, want the current holder to keep the lock", err)
+	}
+	if err := current.Unlock(ctx); err != nil {
+		t.Errorf("Unlock() by the current holder error = %v", err)
+	}
+}
+
+func TestLockerLockWaits(t *testing.T) {
+	locker, _ := newTestLocker(t, database.LockConfig{RetryInterval: 5 * time.Millisecond})
+	ctx := context.Background()
+
+	held, err := locker.Lock(ctx, "report")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if _, err := locker.Lock(timeoutCtx, "report"); !errors.Is(err, database.ErrLockNotAcquired) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock() of a held lock error = %v, want not acquired after the deadline", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		held.Unlock(ctx)
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	next, err := locker.Lock(waitCtx, "report")
+	if err != nil {
+		t.Fatalf("Lock() error = %v, want it acquired once released", err)
+	}
+	next.Unlock(ctx)
+}
+
+func TestLockerAutoExtend(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	locker, mr := newTestLocker(t, database.LockConfig{TTL: 150 * time.Millisecond})
+	ctx := context.Background()
+
+	lock, err := locker.TryLock(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+
+	// Note: miniredis only expires keys on FastForward, so check the TTL is reset instead of waiting for it to expire.
+	time.Sleep(200 * time.Millisecond)
+	if ttl := mr.TTL("lock:{report}"); ttl <= 0 {
+		t.Fatalf("TTL = %s after %s, want the lock extended", ttl, 200*time.Millisecond)
+	}
+	select {
+	case <-lock.Lost():
+		t.Fatal("Lost() is closed while the lock is held")
+	default:
+	}
+
+	// Taking the lock over is noticed by the next extension.
+	mr.Del("lock:{report}")
+	select {
+	case <-lock.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lost() is not closed after the lock was taken over")
+	}
+	if err := lock.Unlock(ctx); !errors.Is(err, database.ErrLockNotHeld) {
+		t.Errorf("Unlock() of a lost lock error = %v, want %v", err, database.ErrLockNotHeld)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace is the Prometheus namespace of every metric exported by this package.
+const metricsNamespace = "database"
+
+// Note: These are registered on the default registerer, which is the same one used by the Prometheus middleware
+// (see server/k8s/metrics), so they show up on the same metrics endpoint without any extra wiring.
+var (
+	// dependencyUp reports whether a dependency (mysql, redis) answered the last supervisor ping (1) or not (0).
+	dependencyUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "supervisor",
+		Name:      "dependency_up",
+		Help:      "Whether the dependency answered the last supervisor ping (1) or not (0).",
+	}, []string{"dependency"})
+
+	// dependencyReconnects counts the reconnect attempts made by the supervisor while a dependency is degraded.
+	dependencyReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "supervisor",
+		Name:      "reconnect_attempts_total",
+		Help:      "Reconnect attempts made by the supervisor while the dependency is degraded.",
+	}, []string{"dependency", "result"})
+
+	// dependencyTransitions counts the degraded and recovered state changes of a dependency.
+	dependencyTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "supervisor",
+		Name:      "state_transitions_total",
+		Help:      "State changes of the dependency observed by the supervisor.",
+	}, []string{"dependency", "state"})
+
+	// dependencyRestarts counts the connection pool restarts (RestartMySQLConnection, RestartRedisConnection) made by the supervisor.
+	dependencyRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "supervisor",
+		Name:      "restarts_total",
+		Help:      "Connection pool restarts made by the supervisor after repeated reconnect failures.",
+	}, []string{"dependency", "result"})
+
+	// queryDuration records the duration of every database call by operation (e.g., exec, query_row, redis_pipeline)
+	// and by statement fingerprint (see [fingerprint]).
+	queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the database calls by operation and statement fingerprint.",
+		// From 250µs (a Redis GET on the same node) up to ~8s (a heavy report query).
+		Buckets: prometheus.ExponentialBuckets(0.00025, 2, 16),
+	}, []string{"operation", "statement"})
+
+	// queryErrors counts the failed database calls by operation and error code (e.g., the MySQL error number "1062").
+	queryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "query_errors_total",
+		Help:      "Failed database calls by operation and error code (MySQL error number, sqlite_<code>, redis_<prefix>, or a generic class).",
+	}, []string{"operation", "code"})
+
+	// slowQueries counts the calls written to the slow-query log.
+	slowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "slow_queries_total",
+		Help:      "Database calls slower than DB_SLOW_QUERY_THRESHOLD.",
+	}, []string{"operation"})
+)
+
+// poolStatsCollector exports [sql.DBStats] of the primary and the read replicas, and the [redis.PoolStats]
+// of the Redis client as metrics, replacing the strings of the Health map for dashboards and alerts.
+//
+// Note: The stats are read on every scrape, so they always follow the current pools, even after a connection restart.
+// Cumulative stats (e.g., wait count) are counters, so they reset when a pool is restarted, which Prometheus handles fine.
+type poolStatsCollector struct {
+	s *service
+
+	sqlMaxOpen           *prometheus.Desc
+	sqlOpen              *prometheus.Desc
+	sqlInUse             *prometheus.Desc
+	sqlIdle              *prometheus.Desc
+	sqlWaitCount         *prometheus.Desc
+	sqlWaitDuration      *prometheus.Desc
+	sqlMaxIdleClosed     *prometheus.Desc
+	sqlMaxIdleTimeClosed *prometheus.Desc
+	sqlMaxLifetimeClosed *prometheus.Desc
+
+	redisHits       *prometheus.Desc
+	redisMisses     *prometheus.Desc
+	redisTimeouts   *prometheus.Desc
+	redisTotalConns *prometheus.Desc
+	redisIdleConns  *prometheus.Desc
+	redisStaleConns *prometheus.Desc
+}
+
+// newPoolStatsCollector creates the pool stats collector for the service.
+func newPoolStatsCollector(s *service) *poolStatsCollector {
+	sqlDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "sql", name), help, []string{"db"}, nil)
+	}
+	redisDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "redis_pool", name), help, nil, nil)
+	}
+	return &poolStatsCollector{
+		s:                    s,
+		sqlMaxOpen:           sqlDesc("max_open_connections", "Maximum number of open connections to the database."),
+		sqlOpen:              sqlDesc("open_connections", "The number of established connections, both in use and idle."),
+		sqlInUse:             sqlDesc("in_use_connections", "The number of connections currently in use."),
+		sqlIdle:              sqlDesc("idle_connections", "The number of idle connections."),
+		sqlWaitCount:         sqlDesc("wait_count_total", "The total number of connections waited for."),
+		sqlWaitDuration:      sqlDesc("wait_duration_seconds_total", "The total time blocked waiting for a new connection."),
+		sqlMaxIdleClosed:     sqlDesc("max_idle_closed_total", "The total number of connections closed due to SetMaxIdleConns."),
+		sqlMaxIdleTimeClosed: sqlDesc("max_idle_time_closed_total", "The total number of connections closed due to SetConnMaxIdleTime."),
+		sqlMaxLifetimeClosed: sqlDesc("max_lifetime_closed_total", "The total number of connections closed due to SetConnMaxLifetime."),
+		redisHits:            redisDesc("hits_total", "The number of times a free connection was found in the pool."),
+		redisMisses:          redisDesc("misses_total", "The number of times a free connection was not found in the pool."),
+		redisTimeouts:        redisDesc("timeouts_total", "The number of times a wait timeout occurred."),
+		redisTotalConns:      redisDesc("total_connections", "The number of total connections in the pool."),
+		redisIdleConns:       redisDesc("idle_connections", "The number of idle connections in the pool."),
+		redisStaleConns:      redisDesc("stale_connections_total", "The number of stale connections removed from the pool."),
+	}
+}
+
+// Describe implements [prometheus.Collector].
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.sqlMaxOpen, c.sqlOpen, c.sqlInUse, c.sqlIdle, c.sqlWaitCount, c.sqlWaitDuration,
+		c.sqlMaxIdleClosed, c.sqlMaxIdleTimeClosed, c.sqlMaxLifetimeClosed,
+		c.redisHits, c.redisMisses, c.redisTimeouts, c.redisTotalConns, c.redisIdleConns, c.redisStaleConns,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements [prometheus.Collector].
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collectSQL(ch, "primary", c.s.sqlDB().Stats())
+	if c.s.replicas != nil {
+		for _, n := range c.s.replicas.nodes {
+			c.collectSQL(ch, "replica_"+n.name, n.db.Stats())
+		}
+	}
+
+	stats := c.s.redisConn().PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.redisHits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.redisMisses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.redisTimeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.redisTotalConns, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.redisIdleConns, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.redisStaleConns, prometheus.CounterValue, float64(stats.StaleConns))
+}
+
+// collectSQL sends the stats of a single SQL connection pool.
+func (c *poolStatsCollector) collectSQL(ch chan<- prometheus.Metric, db string, stats sql.DBStats) {
+	ch <- prometheus.MustNewConstMetric(c.sqlMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlOpen, prometheus.GaugeValue, float64(stats.OpenConnections), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlInUse, prometheus.GaugeValue, float64(stats.InUse), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlIdle, prometheus.GaugeValue, float64(stats.Idle), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlWaitCount, prometheus.CounterValue, float64(stats.WaitCount), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds(), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlMaxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlMaxIdleTimeClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), db)
+	ch <- prometheus.MustNewConstMetric(c.sqlMaxLifetimeClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), db)
+}
@@ -12,6 +12,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"h0llyw00dz-template/backend/internal/database/tiered"
 	log "h0llyw00dz-template/backend/internal/logger"
 	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/bcrypt"
 	"h0llyw00dz-template/env"
@@ -22,7 +23,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redis/go-redis/v9"
 
 	_ "github.com/go-sql-driver/mysql" // MySQL driver is used for connecting to MySQL databases.
@@ -103,8 +106,18 @@ type Service interface {
 	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 
 	// QueryRow executes a query that is expected to return at most one row and scans that row into the provided destination.
+	//
+	// Note: When read replicas are configured (see DB_REPLICAS), this is served by a healthy replica.
+	// Use [WithPrimary] on the context to force the primary (e.g., for read-your-writes consistency).
 	QueryRow(ctx context.Context, query string, args ...any) *sql.Row
 
+	// Query executes a read-only query that returns rows, typically a SELECT.
+	//
+	// Note: When read replicas are configured (see DB_REPLICAS), this is served by a healthy replica.
+	// Use [WithPrimary] on the context to force the primary (e.g., for read-your-writes consistency).
+	// The caller is responsible for closing the returned rows.
+	Query(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
 	// FiberStorage returns the [fiber.Storage] interface for storage middleware.
 	//
 	// Note: This uses Redis. For other storage options, it is recommended to implement a similar approach.
@@ -157,6 +170,26 @@ type Service interface {
 	// is that it's a better approach for maintaining a large codebase, as it uses the singleton pattern for database management.
 	Auth() ServiceAuth
 
+	// Cache returns the cache-aside helper backed by Redis (read Redis -> fall back to MySQL -> write back to Redis).
+	//
+	// Example Usage:
+	//
+	//	user, err := database.GetOrLoad(ctx, db.Cache(), "user:"+id, 10*time.Minute, loadUserFromMySQL)
+	//
+	// After updating MySQL, call db.Cache().Invalidate(ctx, "user:"+id) or db.Cache().InvalidatePattern(ctx, "user:*").
+	Cache() *Cache
+
+	// Dialect returns the SQL dialect of the main database (MySQL or SQLite, see DB_DRIVER).
+	//
+	// Note: Only needed for SQL that can't be written portably (e.g., quoting identifiers, detecting duplicate entries).
+	Dialect() Dialect
+
+	// RedisClient returns the underlying Redis client, for example to use with the generic RedisJSON helpers
+	// (JSONSetMany, JSONGetMany, JSONMerge, JSONArrAppend), since Go doesn't allow generic methods.
+	//
+	// Note: Don't close the returned client; it is shared across the codebase and closed by [Service.Close].
+	RedisClient() redis.UniversalClient
+
 	// SetKeysAtPipeline efficiently sets multiple key-value pairs in Redis/Valkey with a specified TTL (Time To Live) using pipelining.
 	SetKeysAtPipeline(ctx context.Context, keyValues map[string]any, ttl time.Duration) error
 
@@ -257,8 +290,7 @@ type Service interface {
 	// Note: Ensure your Redis instance has the RedisJSON module enabled.
 	// For more efficiency with simple string values, consider using [GetKeysAtPipeline].
 	//
-	// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-	// This change will elevate it to a top-level function if you know how to handle it.
+	// Deprecated: Use JSONGetMany, which is typed and reports the errors per key.
 	GetKeysJSONAtPipeline(ctx context.Context, objects []any, decoder JSONDecoder, keyFunc KeyFunc, path ...string) ([]any, error)
 
 	// SetKeysJSONAtPipeline stores multiple objects in Redis using JSON.SET with a custom encoder and key extractor.
@@ -267,8 +299,7 @@ type Service interface {
 	// Note: Ensure your Redis instance has the RedisJSON module enabled.
 	// For more efficiency with simple string values, consider using [SetKeysAtPipeline].
 	//
-	// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-	// This change will elevate it to a top-level function if you know how to handle it.
+	// Deprecated: Use JSONSetMany (or JSONMerge), which is typed and supports the TTLs and reports the errors per key.
 	SetKeysJSONAtPipeline(ctx context.Context, objects []any, encoder JSONEncoder, keyFunc KeyFunc, path ...string) error
 
 	// GetRawJSONAtPipeline retrieves multiple JSON objects from Redis without decoding them.
@@ -278,56 +309,91 @@ type Service interface {
 	// Note: Ensure your Redis instance has the RedisJSON module enabled.
 	// For more efficiency with simple string values, consider using [GetKeysAtPipeline].
 	//
-	// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-	// This change will elevate it to a top-level function if you know how to handle it.
+	// Deprecated: Use JSONGetMany with json.RawMessage, which reports the errors per key.
 	GetRawJSONAtPipeline(ctx context.Context, objects []any, keyFunc KeyFunc, path ...string) (map[string][]byte, error)
 
 	// DelKeysJSONAtPipeline removes JSON objects from Redis using the JSON.DEL command.
 	// It utilizes pipelining to efficiently delete multiple keys in a single network call.
 	//
-	// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-	// This change will elevate it to a top-level function if you know how to handle it.
+	// Deprecated: Use JSONDelMany, which reports the errors per key.
 	DelKeysJSONAtPipeline(ctx context.Context, objects []any, keyFunc KeyFunc, path ...string) error
 
 	// StreamRows executes a given query and streams the rows, allowing for efficient iteration over large datasets.
 	//
 	// Note: The caller is responsible for ensuring SQL injection protection when using StreamRows.
 	// This method is suitable for syncing MySQL x Redis if implemented correctly (easy 🤪).
+	// Like QueryRow and Query, it is served by a healthy replica when read replicas are configured.
 	StreamRows(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
+	// Ready reports whether both the SQL database and Redis answered the last ping of the connection supervisor.
+	//
+	// Note: Unlike PingDB, this doesn't hit the network, so it is cheap enough for a readiness probe that is called every few seconds.
+	// While it reports false, the supervisor keeps reconnecting in the background (see DB_SUPERVISOR_INTERVAL).
+	Ready() bool
 }
 
 // service is a concrete implementation of the Service interface.
 type service struct {
-	db          *sql.DB
-	rdb         fiber.Storage
-	redisClient *redis.Client
-	mu          sync.Mutex // a mutex to guard connection restarts or any that needed
-	auth        ServiceAuth
-	bcrypt      *bcrypt.Hash
-	initRedis   *RedisClientConfig
-	initMysql   *MySQLConfig
+	db            *sql.DB
+	dialect       Dialect
+	replicas      *replicaSet // optional read replicas, nil when DB_REPLICAS is not set
+	rdb           fiber.Storage
+	redisClient   redis.UniversalClient
+	embeddedRedis *miniredis.Miniredis // in-process Redis stand-in, nil unless RDB_MODE=memory
+	mu            sync.Mutex           // a mutex to guard connection restarts or any that needed
+	connMu        sync.RWMutex         // guards db, redisClient, and auth, which are swapped by connection restarts
+	supervisor    *supervisor          // pings the connections and reconnects them when they are down
+	instr         *instrumentation     // records the duration and errors of every call, see DB_SLOW_QUERY_THRESHOLD
+	auth          ServiceAuth
+	cache         *Cache
+	bcrypt        bcrypt.Service
+	initRedis     *RedisClientConfig
+	initMysql     *MySQLConfig
+	initSQLite    *SQLiteConfig
 }
 
 // dbConfig holds the environment variables for the database connection.
 //
 // Note: Regarding this Using environment variables in global variables, if you think this high risk you are fucking stupid as developer or security.
 var (
-	dbname               = os.Getenv(env.DBDATABASE)
-	password             = os.Getenv(env.DBPASSWORD)
-	username             = os.Getenv(env.DBUSERNAME)
-	port                 = os.Getenv(env.DBPORT)
-	host                 = os.Getenv(env.DBHOST)
-	redisAddress         = os.Getenv(env.RDBADDRESS)
-	redisPort            = os.Getenv(env.RDBPORT)
-	redisPassword        = os.Getenv(env.RDBPASSWORD)
-	redisDatabase        = os.Getenv(env.RDBDATABASE)
-	redisPoolTimeout     = os.Getenv(env.RDBPOOLTIMEOUT)
-	redisConnMaxIdleTime = os.Getenv(env.RDBMAXCONNLIFEIDLE)
-	redisConnMaxLifetime = os.Getenv(env.RDBMAXCONNLIFETIME)
-	mysqltlsCAs          = os.Getenv(env.MYSQLCERTTLS)
-	redistlsCAs          = os.Getenv(env.REDISCERTTLS)
-	dbInstance           *service
-	initOnce             sync.Once
+	dbname                 = os.Getenv(env.DBDATABASE)
+	password               = os.Getenv(env.DBPASSWORD)
+	username               = os.Getenv(env.DBUSERNAME)
+	port                   = os.Getenv(env.DBPORT)
+	host                   = os.Getenv(env.DBHOST)
+	redisAddress           = os.Getenv(env.RDBADDRESS)
+	redisPort              = os.Getenv(env.RDBPORT)
+	redisPassword          = os.Getenv(env.RDBPASSWORD)
+	redisDatabase          = os.Getenv(env.RDBDATABASE)
+	redisPoolTimeout       = os.Getenv(env.RDBPOOLTIMEOUT)
+	redisConnMaxIdleTime   = os.Getenv(env.RDBMAXCONNLIFEIDLE)
+	redisConnMaxLifetime   = os.Getenv(env.RDBMAXCONNLIFETIME)
+	mysqltlsCAs            = os.Getenv(env.MYSQLCERTTLS)
+	redistlsCAs            = os.Getenv(env.REDISCERTTLS)
+	replicaHosts           = os.Getenv(env.DBREPLICAS)
+	replicaMaxLag          = env.GetEnv(env.DBREPLICAMAXLAG, "10s")
+	replicaCheckInterval   = env.GetEnv(env.DBREPLICACHECKINTERVAL, "5s")
+	dbDriver               = env.GetEnv(env.DBDRIVER, DriverMySQL)
+	sqlitePath             = env.GetEnv(env.DBSQLITEPATH, ":memory:")
+	redisMode              = env.GetEnv(env.RDBMODE, RedisModeStandalone)
+	redisSentinelMaster    = os.Getenv(env.RDBSENTINELMASTER)
+	redisSentinelAddrs     = os.Getenv(env.RDBSENTINELADDRS)
+	redisSentinelPass      = os.Getenv(env.RDBSENTINELPASSWORD)
+	redisClusterAddrs      = os.Getenv(env.RDBCLUSTERADDRS)
+	supervisorInterval     = env.GetEnv(env.DBSUPERVISORINTERVAL, "5s")
+	supervisorMaxBackoff   = env.GetEnv(env.DBSUPERVISORMAXBACKOFF, "30s")
+	supervisorRestartAfter = env.GetEnv(env.DBSUPERVISORRESTARTAFTER, "5")
+	slowQueryThreshold     = env.GetEnv(env.DBSLOWQUERYTHRESHOLD, "200ms")
+	slowQueryArgs          = env.GetEnv(env.DBSLOWQUERYARGS, SlowQueryArgsRedact)
+	storageL1TTL           = os.Getenv(env.STORAGEL1TTL)
+	storageL1MaxEntries    = env.GetEnv(env.STORAGEL1MAXENTRIES, "10000")
+	storageL1Notifications = env.GetEnv(env.STORAGEL1NOTIFICATIONS, "false") == "true"
+	storageEncryptionKeys  = os.Getenv(env.STORAGEENCRYPTIONKEYS)
+	storageEncryptionKeyID = os.Getenv(env.STORAGEENCRYPTIONKEYID)
+	storageAllowPlaintext  = env.GetEnv(env.STORAGEENCRYPTIONALLOWPLAINTEXT, "true") == "true"
+	apiKeyHashSecret       = os.Getenv(env.APIKEYHASHSECRET)
+	dbInstance             *service
+	initOnce               sync.Once
 )
 
 // New creates a new instance of the Service interface.
@@ -344,81 +410,259 @@ var (
 // Note that this calculation is for HPA (stateless) and is based on deployment ratios for scalability. For stateful setups, predictability based on demand is not feasible hahaha.
 //
 // It also improves connection stability for MySQL, reducing occasional drops. For Redis, it enhances latency due to the use of a pool of goroutines.
+//
+// Also note that an unreachable MySQL or Redis doesn't stop the boot anymore. The service starts in degraded mode,
+// [Service.Ready] reports false, and the connection supervisor reconnects in the background until they recover.
+// Configuration errors (e.g., an invalid port or TLS certificate) are still fatal, since retrying won't fix them.
 func New() Service {
 	initOnce.Do(func() {
-		// Initialize the Redis client
-		redisClient, err := initializeRedisClient()
-		if err != nil {
-			// This will catch connection errors such as timeouts and parsing errors from the "strconv" package.
-			log.LogFatal("Failed to initialize Redis client:", err)
+		s := &service{}
+
+		if err := checkAPIKeyHashSecret(apiKeyHashSecret); err != nil {
+			log.LogFatal("Failed to initialize API keys:", err)
 		}
 
-		// Initialize Redis storage for Fiber
-		redisStorage, err := initializeRedisStorage()
+		supervisorConfig, err := parseSupervisorConfig()
 		if err != nil {
-			// This will catch connection errors such as timeouts and parsing errors from the "strconv" package.
-			log.LogFatal("Failed to initialize Redis storage:", err)
+			log.LogFatal("Failed to initialize connection supervisor:", err)
 		}
 
-		// Initialize the MySQL database
-		db, err := initializeMySQLDB()
+		instrumentationConfig, err := parseInstrumentationConfig()
 		if err != nil {
-			// This will not be a connection error, but a DSN parse error or
-			// another initialization error.
-			log.LogFatal("Failed to initialize MySQL database:", err)
+			log.LogFatal("Failed to initialize query instrumentation:", err)
 		}
+		s.instr = newInstrumentation(instrumentationConfig)
 
-		// Initialize bcrypt
-		// Note: This operation should be inexpensive as it uses a pointer,
-		// and the garbage collector will be happy handling memory efficiently. 🤪
-		bchash, err := bcrypt.New()
+		// Initialize Redis
+		//
+		// Note: When RDB_MODE=memory, an in-process Redis stand-in is used instead, so the app can boot with zero external services (e.g., local development, tests).
+		if redisMode == RedisModeMemory {
+			embedded, redisClient, redisStorage, err := initializeInProcessRedis(s.instr)
+			if err != nil {
+				log.LogFatal("Failed to initialize in-process Redis:", err)
+			}
+			s.embeddedRedis, s.redisClient, s.rdb = embedded, redisClient, redisStorage
+		} else {
+			// Initialize the Redis client
+			redisClient, redisConfig, err := initializeRedisClient()
+			if err != nil {
+				// This will catch connection errors such as timeouts and parsing errors from the "strconv" package.
+				log.LogFatal("Failed to initialize Redis client:", err)
+			}
 
-		if err != nil {
+			// Initialize Redis storage for Fiber
+			redisStorage, err := initializeRedisStorage(s.instr)
+			if err != nil {
+				// This will catch connection errors such as timeouts and parsing errors from the "strconv" package.
+				log.LogFatal("Failed to initialize Redis storage:", err)
+			}
+			s.redisClient, s.rdb, s.initRedis = s.instr.instrumentRedis(redisClient), redisStorage, redisConfig
+		}
+
+		// Encrypt the values at rest (optional), then put the optional in-process cache in front,
+		// so the cache hits skip both the network round trip and the decryption.
+		var storageConn redis.UniversalClient
+		if storage, ok := s.rdb.(interface{ Conn() redis.UniversalClient }); ok {
+			storageConn = storage.Conn()
+		}
+		if s.rdb, err = initializeEncryptedStorage(s.rdb); err != nil {
+			log.LogFatal("Failed to initialize encrypted storage:", err)
+		}
+		if s.rdb, err = initializeTieredStorage(s.rdb, storageConn); err != nil {
+			log.LogFatal("Failed to initialize tiered storage:", err)
+		}
+
+		// Initialize the SQL database
+		switch dbDriver {
+		case DriverSQLite:
+			sqliteConfig := newSQLiteConfig()
+			db, err := sqliteConfig.InitializeSQLiteDB()
+			if err != nil {
+				log.LogFatal("Failed to initialize SQLite database:", err)
+			}
+			s.db, s.dialect, s.initSQLite = db, SQLiteDialect, sqliteConfig
+		case DriverMySQL:
+			// Initialize the MySQL database
+			//
+			// Note: This doesn't ping, so a MySQL that is down at boot is picked up by the connection supervisor instead.
+			mysqlConfig := newMySQLConfig()
+			db, err := mysqlConfig.openMySQLDB()
+			if err != nil {
+				// This will not be a connection error, but a DSN parse error or
+				// another initialization error.
+				log.LogFatal("Failed to initialize MySQL database:", err)
+			}
+
+			// Initialize the optional MySQL read replicas.
+			//
+			// Note: Unreachable replicas don't stop the boot; they are just ejected until the health checker sees them again.
+			replicas, err := initializeMySQLReplicas(mysqlConfig)
+			if err != nil {
+				log.LogFatal("Failed to initialize MySQL replicas:", err)
+			}
+			s.db, s.dialect, s.replicas, s.initMysql = db, MySQLDialect, replicas, mysqlConfig
+		default:
+			log.LogFatalf("Unsupported database driver: %q (supported: %q, %q)", dbDriver, DriverMySQL, DriverSQLite)
+		}
+
+		if err := s.initServices(); err != nil {
 			// This will not be a connection error, but a Cost error or
 			// another initialization error.
 			log.LogFatal("Failed to initialize bcrypt:", err)
 		}
 
-		// Create the service instance
-		dbInstance = &service{
-			db:          db,
-			rdb:         redisStorage, // use redisStorage for rate limiting or any other needs in middleware
-			redisClient: redisClient,
-			// Note: This method is safe, even with a large number of service instances (e.g., 1 billion) due to the singleton pattern.
-			// Also, MySQL should be used as the primary database, while Redis should be used for caching.
-			// Here's an example data flow:
-			// 1. For read operations: service -> Redis (if not found in Redis) -> get from main database -> put back in Redis -> repeat.
-			// 2. For insert/update operations: service -> main database -> repeat.
-			// Redis will handle caching for read operations, while write operations will directly interact with the main database.
-			// Also note that these example data flows are highly stable, and the reason for this logic is that traditional SQL databases (e.g., MySQL) have limited open connections,
-			// unlike NoSQL databases (e.g., Redis), which are capable of up to 10K connections with basically no limits.
-			// So Redis is perfect for connection pooling because the most important factor for interacting with it is the connection itself.
-			auth: NewServiceAuth(db, redisStorage, bchash),
-		}
+		// Start the connection supervisor. The first check runs synchronously, so the boot logs tell right away
+		// whether the service starts degraded.
+		s.startSupervisor(supervisorConfig)
+
+		// Export the connection pool stats (MySQL, replicas, Redis) as metrics.
+		//
+		// Note: This is only done for the singleton, since the metrics have no per-instance label.
+		prometheus.MustRegister(newPoolStatsCollector(s))
+
+		dbInstance = s
 	})
 
 	return dbInstance
 }
 
+// NewInProcess creates a new, non-singleton instance of the Service interface backed by pure-Go SQLite
+// and an in-process Redis stand-in, so it doesn't need any external services or credentials.
+//
+// The sqlitePath can be a file path or ":memory:" for a private in-memory database.
+//
+// Note: This is mainly for tests (e.g., handler tests that touch the database) and local development.
+// Unlike [New], it returns an error instead of exiting, and the caller is responsible for calling Close.
+// It also doesn't require API_KEY_HASH_SECRET, so its API keys are only as safe as its throwaway database.
+//
+// Example Usage:
+//
+//	db, err := database.NewInProcess(":memory:")
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	defer db.Close()
+func NewInProcess(sqlitePath string) (Service, error) {
+	s := &service{dialect: SQLiteDialect}
+
+	supervisorConfig, err := parseSupervisorConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	instrumentationConfig, err := parseInstrumentationConfig()
+	if err != nil {
+		return nil, err
+	}
+	s.instr = newInstrumentation(instrumentationConfig)
+
+	s.embeddedRedis, s.redisClient, s.rdb, err = initializeInProcessRedis(s.instr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize in-process Redis: %w", err)
+	}
+
+	s.initSQLite = &SQLiteConfig{Path: sqlitePath}
+	if s.db, err = s.initSQLite.InitializeSQLiteDB(); err != nil {
+		s.redisClient.Close()
+		s.embeddedRedis.Close()
+		return nil, fmt.Errorf("failed to initialize SQLite database: %w", err)
+	}
+
+	if err = s.initServices(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	s.startSupervisor(supervisorConfig)
+
+	return s, nil
+}
+
+// initServices initializes the services that sit on top of the SQL database and Redis (auth, cache).
+func (s *service) initServices() error {
+	// Initialize bcrypt
+	// Note: This operation should be inexpensive as it uses a pointer,
+	// and the garbage collector will be happy handling memory efficiently. 🤪
+	bchash, err := bcrypt.New()
+	if err != nil {
+		return err
+	}
+
+	// Note: This method is safe, even with a large number of service instances (e.g., 1 billion) due to the singleton pattern.
+	// Also, MySQL should be used as the primary database, while Redis should be used for caching.
+	// Here's an example data flow:
+	// 1. For read operations: service -> Redis (if not found in Redis) -> get from main database -> put back in Redis -> repeat (see GetOrLoad).
+	// 2. For insert/update operations: service -> main database -> repeat.
+	// Redis will handle caching for read operations, while write operations will directly interact with the main database.
+	// Also note that these example data flows are highly stable, and the reason for this logic is that traditional SQL databases (e.g., MySQL) have limited open connections,
+	// unlike NoSQL databases (e.g., Redis), which are capable of up to 10K connections with basically no limits.
+	// So Redis is perfect for connection pooling because the most important factor for interacting with it is the connection itself.
+	s.bcrypt = bchash
+	s.auth = NewServiceAuth(s.db, s.rdb, bchash)
+
+	// Initialize the cache-aside helper on top of the same Redis storage.
+	// Pattern invalidation goes through ScanAndDel, which uses the Redis client directly.
+	s.cache = NewCache(CacheConfig{
+		Storage: s.rdb,
+		Scanner: s,
+	})
+
+	return nil
+}
+
 // Close closes the database connection and the Redis client.
 func (s *service) Close() error {
+	// Stop the connection supervisor first, so it doesn't try to reconnect what is being closed
+	if s.supervisor != nil {
+		s.supervisor.close()
+	}
+
+	// Wait for a restart in progress (e.g., one called by hand), then read the connections under connMu,
+	// so the ones it swapped in are the ones closed.
+	//
+	// Note: This is done after stopping the supervisor, since the supervisor restarts under the same lock.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, redisClient := s.sqlDB(), s.redisConn()
+
 	// Close the Redis client connection
-	if err := s.redisClient.Close(); err != nil {
+	if err := redisClient.Close(); err != nil {
 		log.LogErrorf("Error closing Redis client: %v", err)
 		// Don't return yet because we also need to close the SQL database connection.
 	}
 
+	// Stop the keyspace notifications of the in-process cache (if any)
+	if storage, ok := s.rdb.(*tiered.Storage); ok {
+		storage.Close()
+	}
+
+	// Stop the in-process Redis stand-in (if any) once its client is gone
+	if s.embeddedRedis != nil {
+		s.embeddedRedis.Close()
+	}
+
 	// Log information about closing the Redis connection
 	log.LogInfo("Redis connection closed.")
 
+	// Close the read replicas (if any) before the primary
+	if s.replicas != nil {
+		if err := s.replicas.close(); err != nil {
+			log.LogErrorf("Error closing MySQL replicas: %v", err)
+		}
+	}
+
 	// Close the SQL database connection
-	if err := s.db.Close(); err != nil {
+	if err := db.Close(); err != nil {
 		log.LogErrorf("Error closing database connection: %v", err)
 		return err
 	}
 
 	// Log information about closing the database connection
-	log.LogInfof(MsgDBDisconnected, dbname)
+	if s.initSQLite != nil {
+		log.LogInfof(MsgDBDisconnected, s.initSQLite.Path)
+	} else {
+		log.LogInfof(MsgDBDisconnected, dbname)
+	}
 
 	return nil
 }
@@ -443,13 +687,17 @@ func (s *service) Health(filter string) map[string]string {
 		stats = s.checkRedisHealth(ctx, stats)
 	}
 
+	if filter == "" && s.supervisor != nil {
+		stats = s.supervisor.stats(stats)
+	}
+
 	return stats
 }
 
 // checkMySQLHealth checks the health of the MySQL database and adds the relevant statistics to the stats map.
 func (s *service) checkMySQLHealth(ctx context.Context, stats map[string]string) map[string]string {
 	// Ping the MySQL database
-	if err := s.db.PingContext(ctx); err != nil {
+	if err := s.sqlDB().PingContext(ctx); err != nil {
 		// Note: While using `log.Fatal` is an option, it is not recommended for this REST API.
 		// These APIs are designed for large-scale applications with complex infrastructure rather than
 		// small systems reliant on a single database. Using `log.Fatal` can prematurely terminate
@@ -462,7 +710,7 @@ func (s *service) checkMySQLHealth(ctx context.Context, stats map[string]string)
 		stats["mysql_message"] = MsgDBItsHealthy
 
 		// Get MySQL database stats (like open connections, in use, idle, etc.)
-		dbStats := s.db.Stats()
+		dbStats := s.sqlDB().Stats()
 		stats["mysql_open_connections"] = strconv.Itoa(dbStats.OpenConnections)
 		stats["mysql_in_use"] = strconv.Itoa(dbStats.InUse)
 		stats["mysql_idle"] = strconv.Itoa(dbStats.Idle)
@@ -475,6 +723,10 @@ func (s *service) checkMySQLHealth(ctx context.Context, stats map[string]string)
 		stats = s.evaluateMySQLStats(dbStats, stats)
 	}
 
+	if s.replicas != nil {
+		stats = s.replicas.stats(stats)
+	}
+
 	return stats
 }
 
@@ -505,7 +757,7 @@ func (s *service) evaluateMySQLStats(dbStats sql.DBStats, stats map[string]strin
 // checkRedisHealth checks the health of the Redis server and adds the relevant statistics to the stats map.
 func (s *service) checkRedisHealth(ctx context.Context, stats map[string]string) map[string]string {
 	// Ping the Redis server
-	pong, err := s.redisClient.Ping(ctx).Result()
+	pong, err := s.redisConn().Ping(ctx).Result()
 	if err != nil {
 		// Note: While using `log.Fatal` is an option, it is not recommended for this REST API.
 		// These APIs are designed for large-scale applications with complex infrastructure rather than
@@ -520,7 +772,7 @@ func (s *service) checkRedisHealth(ctx context.Context, stats map[string]string)
 		stats["redis_ping_response"] = pong
 
 		// Get Redis server information
-		info, err := s.redisClient.Info(ctx).Result()
+		info, err := s.redisConn().Info(ctx).Result()
 		if err != nil {
 			stats["redis_message"] = fmt.Sprintf(MsgRedisFailedToRetrieveInfo, err)
 		} else {
@@ -534,7 +786,7 @@ func (s *service) checkRedisHealth(ctx context.Context, stats map[string]string)
 			stats["redis_uptime_in_seconds"] = redisInfo["uptime_in_seconds"]
 
 			// Get the pool stats of the Redis client
-			poolStats := s.redisClient.PoolStats()
+			poolStats := s.redisConn().PoolStats()
 
 			// Extract the number of hits (free times) connections in the pool
 			// TODO: Implement a helper function to extract and format numerical values from health stats.
@@ -570,7 +822,7 @@ func (s *service) checkRedisHealth(ctx context.Context, stats map[string]string)
 			stats["redis_max_memory"] = redisInfo["maxmemory"] // Raw max memory in bytes
 
 			// Get the pool size percentage
-			poolSize := s.redisClient.Options().PoolSize
+			poolSize := redisPoolSize(s.redisConn())
 			connectedClients, _ := strconv.Atoi(redisInfo["connected_clients"])
 			poolSizePercentage := float64(connectedClients) / float64(poolSize) * 100
 			stats["redis_pool_size_percentage"] = fmt.Sprintf("%.2f%%", poolSizePercentage)
@@ -580,16 +832,21 @@ func (s *service) checkRedisHealth(ctx context.Context, stats map[string]string)
 		}
 	}
 
+	// For a cluster, the ping above only reached one node, so check every shard and the cluster state as well.
+	if cluster, ok := s.redisConn().(*redis.ClusterClient); ok {
+		stats = checkRedisClusterHealth(ctx, cluster, stats)
+	}
+
 	return stats
 }
 
 // evaluateRedisStats evaluates the Redis server statistics and updates the stats map with the appropriate health message.
 func (s *service) evaluateRedisStats(redisInfo, stats map[string]string) map[string]string {
 	// Retrieve the pool size from the Redis client configuration
-	poolSize := s.redisClient.Options().PoolSize
+	poolSize := redisPoolSize(s.redisConn())
 
 	// Get the pool stats of the Redis client
-	poolStats := s.redisClient.PoolStats()
+	poolStats := s.redisConn().PoolStats()
 
 	// Check the number of connected clients
 	connectedClients, _ := strconv.Atoi(redisInfo["connected_clients"])
@@ -662,7 +919,10 @@ func (s *service) evaluateRedisStats(redisInfo, stats map[string]string) map[str
 
 // Exec executes a SQL query with the provided arguments.
 func (s *service) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return s.db.ExecContext(ctx, query, args...)
+	start := time.Now()
+	result, err := s.sqlDB().ExecContext(ctx, query, args...)
+	s.instr.observe(opExec, query, args, time.Since(start), err)
+	return result, err
 }
 
 // ExecWithoutRow executes a query without returning any rows.
@@ -670,7 +930,7 @@ func (s *service) Exec(ctx context.Context, query string, args ...any) (sql.Resu
 // Note: This method is different from "Exec". Unlike "Exec", it doesn't return "sql.Result".
 // This method is better suited for initializing database schemas or running migrations before the app starts.
 func (s *service) ExecWithoutRow(ctx context.Context, query string, args ...any) error {
-	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+	if _, err := s.Exec(ctx, query, args...); err != nil {
 		log.LogErrorf("Error executing query: %v", err)
 		return err
 	}
@@ -709,13 +969,45 @@ func (s *service) EnsureTransactionClosure(tx *sql.Tx, err *error) {
 }
 
 // BeginTx starts a new transaction.
+//
+// Note: Only starting the transaction is instrumented, since the statements run on the [sql.Tx] directly.
 func (s *service) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
-	return s.db.BeginTx(ctx, opts)
+	start := time.Now()
+	tx, err := s.sqlDB().BeginTx(ctx, opts)
+	s.instr.record(opBeginTx, "begin", "BEGIN", nil, time.Since(start), err)
+	return tx, err
 }
 
 // QueryRow executes a query that is expected to return at most one row.
+//
+// Note: The query runs here, so it is timed here, but [sql.ErrNoRows] only shows up on Scan and isn't counted as an error.
 func (s *service) QueryRow(ctx context.Context, query string, args ...any) *sql.Row {
-	return s.db.QueryRowContext(ctx, query, args...)
+	start := time.Now()
+	row := s.reader(ctx).QueryRowContext(ctx, query, args...)
+	s.instr.observe(opQueryRow, query, args, time.Since(start), row.Err())
+	return row
+}
+
+// Query executes a read-only query that returns rows.
+//
+// Note: Only the time until the first row is available is recorded, not the time spent iterating the rows.
+func (s *service) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := s.reader(ctx).QueryContext(ctx, query, args...)
+	s.instr.observe(opQuery, query, args, time.Since(start), err)
+	return rows, err
+}
+
+// reader returns the connection pool that should serve a read-only query.
+// It is a healthy replica when available, otherwise the primary.
+func (s *service) reader(ctx context.Context) *sql.DB {
+	if isPrimaryForced(ctx) {
+		return s.sqlDB()
+	}
+	if db := s.replicas.pick(); db != nil {
+		return db
+	}
+	return s.sqlDB()
 }
 
 // FiberStorage returns the [fiber.Storage] interface for fiber storage middleware.
@@ -741,58 +1033,11 @@ func (s *service) ScanAndDel(ctx context.Context, patterns []string) error {
 	ctx, cancel := context.WithTimeout(ctx, DefaultCtxTimeout)
 	defer cancel()
 
-	var totalDeleted int
-	// Note: The count is dynamically adjusted based on performance.
-	// This is sufficient, unlike Redis Insight, which can encounter blank screens (on windows hahaha) or OOM errors (on k8s hahaha) with many key-value pairs,
-	// such as millions/billions.
-	baseCount := 1     // Initial count for retrieving keys
-	maxCount := 100000 // Maximum limit for the count; if insufficient, it can be increased to 1 million
-
-	for _, pattern := range patterns {
-		var cursor uint64
-		count := baseCount
-		for {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-				// Continue scanning keys as the context is still valid.
-				// Do not return here.
-			}
-
-			start := time.Now()
-
-			// Use ScanKeys to retrieve keys
-			keys, nextCursor, err := s.ScanKeys(ctx, pattern, cursor, int64(count))
-			if err != nil {
-				return err
-			}
-
-			// Calculate time taken for this iteration
-			duration := time.Since(start)
-
-			// Adjust count based on duration
-			if duration < 50*time.Millisecond && count < maxCount {
-				count *= 2 // Double the count if operation is fast
-			} else if duration > 200*time.Millisecond && count > baseCount {
-				count /= 2 // Halve the count if operation is slow
-			}
-
-			// Delete keys if found
-			if len(keys) > 0 {
-				deleted, err := s.DeleteKeys(ctx, keys, totalDeleted)
-				if err != nil {
-					return err
-				}
-				totalDeleted += deleted
-			}
-
-			cursor = nextCursor
-
-			if cursor == 0 {
-				break
-			}
-		}
+	// Note: In Sentinel mode this runs against the current master, and in Cluster mode it runs against every master concurrently,
+	// since a SCAN cursor is only valid on the node that returned it.
+	totalDeleted, err := scanAndDelete(ctx, s.redisConn(), patterns)
+	if err != nil {
+		return err
 	}
 
 	if totalDeleted > 0 {
@@ -809,16 +1054,17 @@ func (s *service) ScanAndDel(ctx context.Context, patterns []string) error {
 }
 
 // ScanKeys returns a slice of keys for a given pattern starting at the cursor.
+//
+// Note: In Cluster mode, this only scans a single node, because a cursor can't span nodes. Use ScanAndDel to cover every master.
 func (s *service) ScanKeys(ctx context.Context, pattern string, cursor uint64, count int64) ([]string, uint64, error) {
-	return s.redisClient.Scan(ctx, cursor, pattern, count).Result()
+	return s.redisConn().Scan(ctx, cursor, pattern, count).Result()
 }
 
 // DeleteKeys deletes a slice of keys from Redis and returns the updated count.
 func (s *service) DeleteKeys(ctx context.Context, keys []string, totalDeleted int) (int, error) {
-	if _, err := s.redisClient.Del(ctx, keys...).Result(); err != nil {
-		return totalDeleted, err
-	}
-	return totalDeleted + len(keys), nil
+	_, isCluster := s.redisConn().(*redis.ClusterClient)
+	deleted, err := deleteRedisKeys(ctx, s.redisConn(), keys, isCluster)
+	return totalDeleted + deleted, err
 }
 
 // PrepareInsertStatement prepares a SQL insert statement for the transaction.
@@ -849,7 +1095,9 @@ func (s *service) DeleteKeys(ctx context.Context, keys []string, totalDeleted in
 //	    return err
 //	}
 func (s *service) PrepareInsertStatement(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, error) {
+	start := time.Now()
 	stmt, err := tx.PrepareContext(ctx, query)
+	s.instr.observe(opPrepare, query, nil, time.Since(start), err)
 	if err != nil {
 		log.LogErrorf("Error preparing insert statement: %v", err)
 		return nil, err
@@ -858,23 +1106,38 @@ func (s *service) PrepareInsertStatement(ctx context.Context, tx *sql.Tx, query
 }
 
 // RestartRedisConnection safely closes the existing connection to Redis and establishes a new one.
+//
+// Note: The new client is created before the old one is closed, so when that fails, the old client is kept
+// and the callers never see a closed client. This is also called by the connection supervisor.
 func (s *service) RestartRedisConnection() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Close the existing Redis client connection.
-	if err := s.redisClient.Close(); err != nil {
-		log.LogErrorf("Error closing Redis client: %v", err)
-		return err
-	}
-
 	// Reinitialize the Redis client.
-	redisClient, err := s.initRedis.InitializeRedisClient()
-	if err != nil {
-		log.LogErrorf("Error initializing Redis client: %v", err)
-		return err
+	//
+	// Note: The in-process Redis stand-in keeps running, so only the client is recreated.
+	var redisClient redis.UniversalClient
+	if s.embeddedRedis != nil {
+		redisClient = newInProcessRedisClient(s.embeddedRedis)
+	} else {
+		var err error
+		if redisClient, err = s.initRedis.InitializeRedisClient(); err != nil {
+			log.LogErrorf("Error initializing Redis client: %v", err)
+			return err
+		}
 	}
+	s.instr.instrumentRedis(redisClient)
+
+	s.connMu.Lock()
+	old := s.redisClient
 	s.redisClient = redisClient
+	s.connMu.Unlock()
+
+	// Close the old Redis client connection.
+	if err := old.Close(); err != nil {
+		log.LogErrorf("Error closing Redis client: %v", err)
+		// Don't return the error, since the new client is already in place.
+	}
 
 	// Log the reconnection
 	log.LogInfo("Redis connection has been restarted.")
@@ -883,35 +1146,95 @@ func (s *service) RestartRedisConnection() error {
 }
 
 // RestartMySQLConnection safely closes the existing MySQL connection and establishes a new one.
+//
+// Note: The new connection pool is opened (and pinged) before the old one is closed, so when MySQL is still down,
+// the old pool is kept and the callers never see a closed database. The old pool waits for the queries in flight.
+// This is also called by the connection supervisor.
 func (s *service) RestartMySQLConnection() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Close the existing MySQL database connection.
-	if err := s.db.Close(); err != nil {
-		log.LogErrorf("Error closing MySQL database connection: %v", err)
-		return err
-	}
-
 	// Reinitialize the MySQL database connection.
-	var err error
-	s.db, err = s.initMysql.InitializeMySQLDB()
+	//
+	// Note: When DB_DRIVER=sqlite, this reopens the SQLite database instead.
+	var (
+		db  *sql.DB
+		err error
+	)
+	if s.initSQLite != nil {
+		db, err = s.initSQLite.InitializeSQLiteDB()
+	} else {
+		db, err = s.initMysql.InitializeMySQLDB()
+	}
 	if err != nil {
 		log.LogErrorf("Error reinitializing MySQL database connection: %v", err)
 		return err
 	}
 
+	// Swap the pool, and recreate the auth service, which holds the pool as well.
+	s.connMu.Lock()
+	old := s.db
+	s.db = db
+	s.auth = NewServiceAuth(db, s.rdb, s.bcrypt)
+	s.connMu.Unlock()
+
+	// Close the old MySQL database connection.
+	if err := old.Close(); err != nil {
+		log.LogErrorf("Error closing MySQL database connection: %v", err)
+		// Don't return the error, since the new pool is already in place.
+	}
+
 	// Log the reconnection.
 	log.LogInfo("MySQL connection has been restarted.")
 
 	return nil
 }
 
+// sqlDB returns the current connection pool of the main database.
+//
+// Note: Always use this instead of reading s.db directly, since RestartMySQLConnection swaps the pool.
+func (s *service) sqlDB() *sql.DB {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	return s.db
+}
+
+// redisConn returns the current Redis client.
+//
+// Note: Always use this instead of reading s.redisClient directly, since RestartRedisConnection swaps the client.
+func (s *service) redisConn() redis.UniversalClient {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
+	return s.redisClient
+}
+
+// Ready reports whether both the SQL database and Redis answered the last ping of the connection supervisor.
+func (s *service) Ready() bool {
+	return s.supervisor != nil && s.supervisor.ready()
+}
+
 // AuthUser returns the ServiceAuth interface for managing user authentication-related database operations.
 func (s *service) Auth() ServiceAuth {
+	s.connMu.RLock()
+	defer s.connMu.RUnlock()
 	return s.auth
 }
 
+// Dialect returns the SQL dialect of the main database.
+func (s *service) Dialect() Dialect {
+	return s.dialect
+}
+
+// Cache returns the cache-aside helper backed by Redis.
+func (s *service) Cache() *Cache {
+	return s.cache
+}
+
+// RedisClient returns the underlying Redis client.
+func (s *service) RedisClient() redis.UniversalClient {
+	return s.redisConn()
+}
+
 // SetKeysAtPipeline reduces the latency cost associated with round-trip time (RTT) by batching multiple commands (e.g, 1 billion commands that save cost money $$$) into a single network request.
 // This method is particularly useful for bulk-insert scenarios where performance is critical.
 //
@@ -929,6 +1252,9 @@ func (s *service) Auth() ServiceAuth {
 //
 // Note: On my rack-server machine, it has a 0-ms (backend) and 20-ms (frontend for visitor/client in the SEA region) response time because we are neighbors, so ¯\_(ツ)_/¯
 //
+// Also note that in Cluster mode, the pipeline is split by hash slot and sent to the node owning each key (one round-trip per node),
+// which is why every command in it is single-key. Don't replace it with a multi-key command (e.g., MSET), since that fails with CROSSSLOT.
+//
 // Important:
 //   - For better performance, avoid using Redis/Valkey JSON commands.
 //     String commands are sufficient as they are immutable and can easily enhance performance by utilizing other JSON encoders/decoders (Most Important Go, unlike other language) for the value key. so ¯\_(ツ)_/¯
@@ -944,7 +1270,7 @@ func (s *service) SetKeysAtPipeline(ctx context.Context, keyValues map[string]an
 	defer cancel()
 
 	// Initialize a new pipeline.
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Iterate over the provided key-value pairs, queuing up each one in the pipeline.
 	for key, value := range keyValues {
@@ -983,7 +1309,7 @@ func (s *service) GetKeysAtPipeline(ctx context.Context, keys []string) (map[str
 	defer cancel()
 
 	// Initialize a new pipeline
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Create a slice to hold the Redis commands and a map to store the results
 	cmds := make([]*redis.StringCmd, len(keys))
@@ -1055,12 +1381,12 @@ func (s *service) PingDB(ctx context.Context) bool {
 	// as the timeout can be set by the caller.
 
 	// Ping the MySQL database to verify connectivity.
-	if err := s.db.PingContext(ctx); err != nil {
+	if err := s.sqlDB().PingContext(ctx); err != nil {
 		return false
 	}
 
 	// Ping the Redis server to verify connectivity.
-	if err := s.redisClient.Ping(ctx).Err(); err != nil {
+	if err := s.redisConn().Ping(ctx).Err(); err != nil {
 		return false
 	}
 
@@ -1071,7 +1397,9 @@ func (s *service) PingDB(ctx context.Context) bool {
 // StreamRows executes a given query and streams the rows, allowing for efficient iteration over large datasets.
 func (s *service) StreamRows(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
 	// Execute the query with the provided arguments
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	start := time.Now()
+	rows, err := s.reader(ctx).QueryContext(ctx, query, args...)
+	s.instr.observe(opStreamRows, query, args, time.Since(start), err)
 	if err != nil {
 		log.LogErrorf("Failed to stream rows: %v", err)
 		return nil, fmt.Errorf("failed to stream rows: %w", err)
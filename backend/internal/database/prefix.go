@@ -0,0 +1,66 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrSharedStorageReset is returned by PrefixedStorage.Reset, which would wipe the shared storage.
+var ErrSharedStorageReset = errors.New("database: a prefixed storage can't be reset, since its storage is shared")
+
+// PrefixedStorage is a [fiber.Storage] that prefixes its keys, so a middleware (e.g., the sessions) can use
+// a namespace of a shared storage (e.g., [Service.FiberStorage]) without clashing with the other middlewares.
+//
+// Note: Reset always fails with ErrSharedStorageReset, since the Reset of the Redis storage flushes the whole database
+// (FLUSHDB), including the keys of the other middlewares (e.g., the rate limiter, the locks, or the ACME certificates).
+// The keys are meant to expire by their TTL instead. Close does nothing either, since the storage is closed by its owner.
+type PrefixedStorage struct {
+	storage fiber.Storage
+	prefix  string
+}
+
+// NewPrefixedStorage returns a storage prefixing the keys of the storage with the prefix (e.g., "session:").
+func NewPrefixedStorage(storage fiber.Storage, prefix string) *PrefixedStorage {
+	return &PrefixedStorage{storage: storage, prefix: prefix}
+}
+
+// Get gets the value of the key.
+func (s *PrefixedStorage) Get(key string) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	return s.storage.Get(s.prefix + key)
+}
+
+// Set sets the value of the key, expiring after exp (0 means no expiration).
+func (s *PrefixedStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) == 0 || len(val) == 0 {
+		return nil
+	}
+	return s.storage.Set(s.prefix+key, val, exp)
+}
+
+// Delete deletes the key.
+func (s *PrefixedStorage) Delete(key string) error {
+	if len(key) == 0 {
+		return nil
+	}
+	return s.storage.Delete(s.prefix + key)
+}
+
+// Reset returns ErrSharedStorageReset, without touching the storage.
+func (s *PrefixedStorage) Reset() error {
+	return ErrSharedStorageReset
+}
+
+// Close does nothing, since the storage is shared.
+func (s *PrefixedStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisStorage "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPrefixedStorage(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	shared := redisStorage.NewFromConnection(client)
+	sessions := database.NewPrefixedStorage(shared, "session:")
+
+	// A key of another middleware (e.g., the rate limiter) in the shared storage.
+	if err := shared.Set("limiter:1.2.3.4", []byte("7"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if err := sessions.Set("abc", []byte("data"), time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := sessions.Get("abc"); err != nil || string(got) != "data" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "data")
+	}
+	if !mr.Exists("session:abc") {
+		t.Error("Set() didn't prefix the key")
+	}
+	if ttl := mr.TTL("session:abc"); ttl != time.Hour {
+		t.Errorf("TTL = %v, want %v", ttl, time.Hour)
+	}
+
+	// Reset and Close never touch the shared storage.
+	if err := sessions.Reset(); !errors.Is(err, database.ErrSharedStorageReset) {
+		t.Errorf("Reset() error = %v, want %v", err, database.ErrSharedStorageReset)
+	}
+	if err := sessions.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	for _, key := range []string{"limiter:1.2.3.4", "session:abc"} {
+		if !mr.Exists(key) {
+			t.Errorf("key %q was removed from the shared storage", key)
+		}
+	}
+
+	if err := sessions.Delete("abc"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := sessions.Get("abc"); err != nil || got != nil {
+		t.Errorf("Get() after Delete = %q, %v, want nil", got, err)
+	}
+}
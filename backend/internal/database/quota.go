@@ -0,0 +1,380 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// APIKeyUsageTable is the name of the table storing the daily usage of the API keys (see [CreateAPIKeyUsageTable]).
+const APIKeyUsageTable = "api_key_usage"
+
+// quotaIncrScript increments a quota window counter and sets its expiration on the first request of the window.
+var quotaIncrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+// usageConsumeScript takes a usage counter out of the flushing hash, so it's written to the database only once,
+// even by two pods flushing at the same time (e.g., after the lock of a slow flush expired).
+var usageConsumeScript = redis.NewScript(`
+local value = redis.call("HGET", KEYS[1], ARGV[1])
+if value then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return value`)
+
+// QuotaPlan is a quota of requests per window (e.g., 60 per minute, or 10000 per day).
+//
+// Note: The windows are fixed and aligned on the Unix epoch (e.g., a daily window starts at midnight UTC),
+// so every pod agrees on them without coordination.
+type QuotaPlan struct {
+	Limit  int64
+	Window time.Duration
+}
+
+// QuotaConfig defines the config for the API key quotas.
+type QuotaConfig struct {
+	// Plans are the quota plans by name, which API keys refer to (see [WithAPIKeyPlan]).
+	//
+	// Optional. Default: nil (no quota, the usage is still accounted).
+	Plans map[string]QuotaPlan
+
+	// DefaultPlan is the plan of the keys that don't have one, or have one that isn't in Plans.
+	//
+	// Optional. Default: "" (unlimited).
+	DefaultPlan string
+
+	// Prefix is prepended to the Redis keys.
+	//
+	// Optional. Default: "quota:".
+	Prefix string
+
+	// FlushInterval is how often the usage counters are flushed from Redis to the database by Start.
+	//
+	// Optional. Default: 1 minute.
+	FlushInterval time.Duration
+}
+
+// QuotaConfigDefault is the default config.
+var QuotaConfigDefault = QuotaConfig{
+	Prefix:        "quota:",
+	FlushInterval: time.Minute,
+}
+
+// QuotaResult is the outcome of [Quotas.Allow], with what is needed for the X-RateLimit-* headers.
+type QuotaResult struct {
+	Allowed   bool
+	Limit     int64 // 0 when the key has no quota
+	Remaining int64
+	Reset     time.Time // the end of the current window
+}
+
+// APIKeyUsage is the number of requests made with an API key on a day.
+type APIKeyUsage struct {
+	KeyID    string `json:"key_id"`
+	Day      string `json:"day"` // YYYY-MM-DD (UTC)
+	Requests int64  `json:"requests"`
+}
+
+// Quotas enforces the per-key quota plans and accounts the usage of the API keys.
+//
+// Both live in Redis, so they are shared by every pod: the quota windows are plain counters, and the usage is
+// accumulated in a hash that is periodically flushed to the database (see Flush) for billing and analytics.
+//
+// Example Usage:
+//
+//	quotas := database.NewQuotas(db, database.QuotaConfig{
+//	    Plans: map[string]database.QuotaPlan{
+//	        "free": {Limit: 60, Window: time.Minute},
+//	        "pro":  {Limit: 100000, Window: 24 * time.Hour},
+//	    },
+//	    DefaultPlan: "free",
+//	})
+//	quotas.Start()
+//	defer quotas.Close()
+type Quotas struct {
+	db      Service
+	cfg     QuotaConfig
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// NewQuotas creates a new Quotas on the database service.
+func NewQuotas(db Service, config ...QuotaConfig) *Quotas {
+	cfg := QuotaConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Prefix == "" {
+			cfg.Prefix = QuotaConfigDefault.Prefix
+		}
+		if cfg.FlushInterval <= 0 {
+			cfg.FlushInterval = QuotaConfigDefault.FlushInterval
+		}
+	}
+	return &Quotas{
+		db:   db,
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// ParseQuotaPlans parses a comma-separated list of "name=limit/window" plans (e.g., "free=60/1m,pro=100000/24h"),
+// as set in the API_KEY_QUOTA_PLANS environment variable.
+func ParseQuotaPlans(value string) (map[string]QuotaPlan, error) {
+	plans := make(map[string]QuotaPlan)
+	for entry := range strings.SplitSeq(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		limit, window, ok2 := strings.Cut(spec, "/")
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("database: invalid quota plan %q, want name=limit/window", entry)
+		}
+		n, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("database: invalid limit of quota plan %q", entry)
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("database: invalid window of quota plan %q", entry)
+		}
+		plans[name] = QuotaPlan{Limit: n, Window: d}
+	}
+	return plans, nil
+}
+
+// CreateAPIKeyUsageTable creates the API key usage table if it doesn't exist.
+func CreateAPIKeyUsageTable(ctx context.Context, db Service) error {
+	return db.ExecWithoutRow(ctx, `CREATE TABLE IF NOT EXISTS api_key_usage (
+	key_id VARCHAR(32) NOT NULL,
+	day CHAR(10) NOT NULL,
+	requests BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (key_id, day)
+)`)
+}
+
+// plan returns the quota plan of the key, if it has one.
+func (q *Quotas) plan(key APIKey) (QuotaPlan, bool) {
+	if plan, ok := q.cfg.Plans[key.Plan]; ok && plan.Limit > 0 && plan.Window > 0 {
+		return plan, true
+	}
+	plan, ok := q.cfg.Plans[q.cfg.DefaultPlan]
+	return plan, ok && plan.Limit > 0 && plan.Window > 0
+}
+
+// Allow counts a request made with the key against its quota plan, and accounts it in the usage when it's allowed.
+//
+// Note: The rejected requests are not accounted in the usage, since they are not billed.
+func (q *Quotas) Allow(ctx context.Context, key APIKey) (QuotaResult, error) {
+	now := time.Now().UTC()
+	client := q.db.RedisClient()
+	result := QuotaResult{Allowed: true}
+
+	if plan, ok := q.plan(key); ok {
+		start := quotaWindowStart(now, plan.Window)
+		result.Limit = plan.Limit
+		result.Reset = start.Add(plan.Window)
+
+		windowKey := q.cfg.Prefix + "window:" + key.ID + ":" + strconv.FormatInt(start.Unix(), 10)
+		count, err := quotaIncrScript.Run(ctx, client, []string{windowKey}, plan.Window.Milliseconds()).Int64()
+		if err != nil {
+			return result, fmt.Errorf("database: failed to count quota: %w", err)
+		}
+		result.Remaining = max(plan.Limit-count, 0)
+		if count > plan.Limit {
+			result.Allowed = false
+			return result, nil
+		}
+	}
+
+	field := key.ID + "|" + now.Format(time.DateOnly)
+	if err := client.HIncrBy(ctx, q.pendingKey(), field, 1).Err(); err != nil {
+		return result, fmt.Errorf("database: failed to account usage: %w", err)
+	}
+	return result, nil
+}
+
+// quotaWindowStart returns the start of the window containing now, aligned on the Unix epoch.
+//
+// Note: This doesn't use time.Truncate, which aligns on the zero time instead (January 1, year 1),
+// so a window that doesn't divide a day (e.g., a week) wouldn't start where the docs of QuotaPlan say.
+func quotaWindowStart(now time.Time, window time.Duration) time.Time {
+	nanos := now.UnixNano()
+	return time.Unix(0, nanos-nanos%int64(window)).UTC()
+}
+
+// pendingKey is the hash accumulating the usage since the last flush, and flushingKey is the one being flushed.
+//
+// Note: They share a hash tag, since RENAME needs both keys in the same slot on Redis Cluster.
+func (q *Quotas) pendingKey() string  { return q.cfg.Prefix + "{usage}:pending" }
+func (q *Quotas) flushingKey() string { return q.cfg.Prefix + "{usage}:flushing" }
+
+// Flush moves the accumulated usage from Redis to the database.
+//
+// Only one pod flushes at a time (see [Locker]). The pending counters are renamed before being written,
+// so the requests accounted during the flush go to a new hash. A flush interrupted by a crash is resumed by the next one.
+//
+// Each counter is consumed atomically before being written (see usageConsumeScript), so it's never counted twice,
+// even when the lock is lost during a slow flush and another pod takes over. A counter that fails to be written
+// is put back into the pending hash. The trade-off is that a crash between the consume and the write loses that one counter.
+func (q *Quotas) Flush(ctx context.Context) error {
+	client := q.db.RedisClient()
+	lock, err := NewLocker(client, LockConfig{Prefix: q.cfg.Prefix + "lock:"}).TryLock(ctx, "usage-flush")
+	if errors.Is(err, ErrLockNotAcquired) {
+		return nil // another pod is flushing
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	// Resume a flush interrupted by a crash, otherwise take the pending counters.
+	if n, err := client.Exists(ctx, q.flushingKey()).Result(); err != nil {
+		return err
+	} else if n == 0 {
+		if err := client.Rename(ctx, q.pendingKey(), q.flushingKey()).Err(); err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				return nil // nothing to flush
+			}
+			return err
+		}
+	}
+
+	fields, err := client.HKeys(ctx, q.flushingKey()).Result()
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		select {
+		case <-lock.Lost():
+			return ErrLockNotHeld // the remaining counters are flushed by the pod that took over
+		default:
+		}
+
+		value, err := usageConsumeScript.Run(ctx, client, []string{q.flushingKey()}, field).Text()
+		if errors.Is(err, redis.Nil) {
+			continue // already consumed by another flush
+		}
+		if err != nil {
+			return err
+		}
+		keyID, day, ok := strings.Cut(field, "|")
+		requests, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil {
+			log.LogErrorf("Skipping malformed API key usage counter %q: %q", field, value)
+			continue
+		}
+		if err := q.addUsage(ctx, keyID, day, requests); err != nil {
+			// Put the counter back, so it's written by the next flush instead of being lost.
+			if errRestore := client.HIncrBy(context.WithoutCancel(ctx), q.pendingKey(), field, requests).Err(); errRestore != nil {
+				log.LogErrorf("Failed to restore API key usage counter %q (%d requests): %v", field, requests, errRestore)
+			}
+			return err
+		}
+	}
+	return client.Del(ctx, q.flushingKey()).Err()
+}
+
+// addUsage adds requests to the usage of a key on a day.
+//
+// Note: This is an UPDATE falling back to an INSERT instead of an upsert, which is written differently
+// by MySQL ("ON DUPLICATE KEY UPDATE") and SQLite ("ON CONFLICT"). Only one pod flushes at a time, so they don't race.
+func (q *Quotas) addUsage(ctx context.Context, keyID, day string, requests int64) error {
+	const update = "UPDATE api_key_usage SET requests = requests + ? WHERE key_id = ? AND day = ?"
+	result, err := q.db.Exec(ctx, update, requests, keyID, day)
+	if err != nil {
+		return fmt.Errorf("database: failed to flush API key usage: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	const insert = "INSERT INTO api_key_usage (key_id, day, requests) VALUES (?, ?, ?)"
+	if err := q.db.ExecWithoutRow(ctx, insert, keyID, day, requests); err != nil {
+		return fmt.Errorf("database: failed to flush API key usage: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the flushed daily usage of the key since the given day (inclusive), oldest first.
+//
+// Note: The requests accounted since the last flush are not included.
+func (q *Quotas) Usage(ctx context.Context, keyID string, since time.Time) ([]APIKeyUsage, error) {
+	const query = "SELECT key_id, day, requests FROM api_key_usage WHERE key_id = ? AND day >= ? ORDER BY day"
+	rows, err := q.db.Query(ctx, query, keyID, since.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []APIKeyUsage
+	for rows.Next() {
+		var u APIKeyUsage
+		if err := rows.Scan(&u.KeyID, &u.Day, &u.Requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// Start flushes the usage every FlushInterval in the background, until Close is called.
+func (q *Quotas) Start() {
+	if !q.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(q.done)
+		ticker := time.NewTicker(q.cfg.FlushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-q.stop:
+				return
+			case <-ticker.C:
+				if err := q.flushWithTimeout(); err != nil {
+					log.LogErrorf("Failed to flush API key usage: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background flush started by Start, then flushes one last time,
+// so the usage accounted by this pod isn't left waiting for another one.
+func (q *Quotas) Close() error {
+	q.once.Do(func() { close(q.stop) })
+	if q.started.Load() {
+		<-q.done
+	}
+	return q.flushWithTimeout()
+}
+
+// flushWithTimeout flushes with a timeout of one FlushInterval, so a stuck flush doesn't pile up.
+func (q *Quotas) flushWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.cfg.FlushInterval)
+	defer cancel()
+	return q.Flush(ctx)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	"testing"
+	"time"
+)
+
+func TestQuotas(t *testing.T) {
+	db := newAPIKeyService(t)
+	ctx := context.Background()
+	if err := database.CreateAPIKeyUsageTable(ctx, db); err != nil {
+		t.Fatalf("CreateAPIKeyUsageTable() error = %v", err)
+	}
+
+	quotas := database.NewQuotas(db, database.QuotaConfig{
+		Plans: map[string]database.QuotaPlan{
+			"free": {Limit: 2, Window: time.Hour},
+			"pro":  {Limit: 100, Window: time.Hour},
+		},
+		DefaultPlan: "free",
+	})
+
+	free := database.APIKey{ID: "free-key"}
+	pro := database.APIKey{ID: "pro-key", Plan: "pro"}
+
+	for i, want := range []bool{true, true, false, false} {
+		result, err := quotas.Allow(ctx, free)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if result.Allowed != want || result.Limit != 2 {
+			t.Errorf("request %d: Allow() = %+v, want allowed %v with limit 2", i+1, result, want)
+		}
+		if result.Reset.Before(time.Now()) || result.Reset.After(time.Now().Add(time.Hour)) {
+			t.Errorf("request %d: Reset = %v, want within the next hour", i+1, result.Reset)
+		}
+	}
+
+	// Keys don't share their windows, and the plan of the key wins over the default.
+	if result, _ := quotas.Allow(ctx, pro); !result.Allowed || result.Remaining != 99 {
+		t.Errorf("Allow() of the pro key = %+v, want allowed with 99 remaining", result)
+	}
+
+	// Only the allowed requests are accounted, once they are flushed.
+	if usage, _ := quotas.Usage(ctx, free.ID, time.Now()); len(usage) != 0 {
+		t.Errorf("Usage() before Flush = %+v, want none", usage)
+	}
+	if err := quotas.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	quotas.Allow(ctx, pro)
+	if err := quotas.Close(); err != nil { // flushes the last one
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	for key, want := range map[string]int64{free.ID: 2, pro.ID: 2} {
+		usage, err := quotas.Usage(ctx, key, time.Now())
+		if err != nil {
+			t.Fatalf("Usage() error = %v", err)
+		}
+		if len(usage) != 1 || usage[0].Day != today || usage[0].Requests != want {
+			t.Errorf("Usage(%q) = %+v, want %d requests today", key, usage, want)
+		}
+	}
+
+	// Nothing left to flush.
+	if err := quotas.Flush(ctx); err != nil {
+		t.Errorf("Flush() with nothing pending error = %v", err)
+	}
+}
+
+func TestQuotasUnlimited(t *testing.T) {
+	db := newAPIKeyService(t)
+	quotas := database.NewQuotas(db)
+
+	result, err := quotas.Allow(context.Background(), database.APIKey{ID: "any"})
+	if err != nil || !result.Allowed || result.Limit != 0 {
+		t.Errorf("Allow() without plans = %+v, %v, want allowed without limit", result, err)
+	}
+}
+
+func TestQuotasFlushFailure(t *testing.T) {
+	db := newAPIKeyService(t)
+	ctx := context.Background()
+	quotas := database.NewQuotas(db)
+
+	key := database.APIKey{ID: "key"}
+	for range 3 {
+		if _, err := quotas.Allow(ctx, key); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+	}
+
+	// The usage table doesn't exist yet, so the write fails and the counter is put back.
+	if err := quotas.Flush(ctx); err == nil {
+		t.Fatal("Flush() without the usage table error = nil, want an error")
+	}
+	if err := database.CreateAPIKeyUsageTable(ctx, db); err != nil {
+		t.Fatalf("CreateAPIKeyUsageTable() error = %v", err)
+	}
+	if _, err := quotas.Allow(ctx, key); err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	for range 2 {
+		if err := quotas.Flush(ctx); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+
+	usage, err := quotas.Usage(ctx, key.ID, time.Now())
+	if err != nil {
+		t.Fatalf("Usage() error = %v", err)
+	}
+	if len(usage) != 1 || usage[0].Requests != 4 {
+		t.Errorf("Usage() = %+v, want 4 requests counted once", usage)
+	}
+}
+
+func TestParseQuotaPlans(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]database.QuotaPlan
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[string]database.QuotaPlan{}},
+		{
+			name:  "plans",
+			value: "free=60/1m, pro=100000/24h",
+			want: map[string]database.QuotaPlan{
+				"free": {Limit: 60, Window: time.Minute},
+				"pro":  {Limit: 100000, Window: 24 * time.Hour},
+			},
+		},
+		{name: "missing window", value: "free=60", wantErr: true},
+		{name: "invalid limit", value: "free=many/1m", wantErr: true},
+		{name: "invalid window", value: "free=60/0s", wantErr: true},
+		{name: "missing name", value: "=60/1m", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := database.ParseQuotaPlans(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuotaPlans(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseQuotaPlans(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+			for name, plan := range tt.want {
+				if got[name] != plan {
+					t.Errorf("plan %q = %+v, want %+v", name, got[name], plan)
+				}
+			}
+		})
+	}
+}
+
+func TestQuotasWindowAlignedOnUnixEpoch(t *testing.T) {
+	db := newAPIKeyService(t)
+	const week = 7 * 24 * time.Hour
+	quotas := database.NewQuotas(db, database.QuotaConfig{
+		Plans:       map[string]database.QuotaPlan{"weekly": {Limit: 10, Window: week}},
+		DefaultPlan: "weekly",
+	})
+	defer quotas.Close()
+
+	result, err := quotas.Allow(context.Background(), database.APIKey{ID: "weekly-key"})
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	// The zero time of time.Truncate isn't a whole number of weeks before the Unix epoch, so this catches it.
+	if rem := result.Reset.UnixNano() % int64(week); rem != 0 {
+		t.Errorf("Reset = %v, want a multiple of a week since the Unix epoch (off by %v)", result.Reset, time.Duration(rem))
+	}
+}
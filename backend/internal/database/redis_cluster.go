@@ -0,0 +1,217 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrRedisSentinelConfig is returned when Sentinel mode is used without a master name or Sentinel addresses.
+	ErrRedisSentinelConfig = errors.New("database: Redis Sentinel mode requires RDB_SENTINEL_MASTER and RDB_SENTINEL_ADDRS")
+
+	// ErrRedisClusterConfig is returned when Cluster mode is used without seed nodes.
+	ErrRedisClusterConfig = errors.New("database: Redis Cluster mode requires RDB_CLUSTER_ADDRS")
+
+	// ErrRedisUnsupportedMode is returned when RDB_MODE is not one of the supported modes.
+	ErrRedisUnsupportedMode = errors.New("database: unsupported Redis mode")
+)
+
+// splitRedisAddrs splits a comma-separated list of "host:port" addresses, skipping empty entries.
+func splitRedisAddrs(s string) []string {
+	var addrs []string
+	for addr := range strings.SplitSeq(s, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// forEachRedisMaster calls fn for every master node. For a standalone or Sentinel client, fn is called once
+// with the client itself, and for a cluster, fn is called concurrently for each master.
+//
+// Note: This is what makes SCAN work on a cluster, since a SCAN cursor is only valid on the node that returned it.
+func forEachRedisMaster(ctx context.Context, rdb redis.UniversalClient, fn func(ctx context.Context, node redis.Cmdable) error) error {
+	if cluster, ok := rdb.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return fn(ctx, node)
+		})
+	}
+	return fn(ctx, rdb)
+}
+
+// scanAndDelete deletes every key matching the patterns on every master and returns the number of deleted keys.
+//
+// Note: The count is dynamically adjusted based on performance.
+// This is sufficient, unlike Redis Insight, which can encounter blank screens (on windows hahaha) or OOM errors (on k8s hahaha) with many key-value pairs,
+// such as millions/billions.
+func scanAndDelete(ctx context.Context, rdb redis.UniversalClient, patterns []string) (int, error) {
+	_, isCluster := rdb.(*redis.ClusterClient)
+
+	var totalDeleted atomic.Int64
+	for _, pattern := range patterns {
+		err := forEachRedisMaster(ctx, rdb, func(ctx context.Context, node redis.Cmdable) error {
+			deleted, err := scanAndDeleteNode(ctx, node, pattern, isCluster)
+			totalDeleted.Add(int64(deleted))
+			return err
+		})
+		if err != nil {
+			return int(totalDeleted.Load()), err
+		}
+	}
+	return int(totalDeleted.Load()), nil
+}
+
+// scanAndDeleteNode runs the SCAN + DEL loop for a single pattern on a single node.
+//
+// Note: The keys are deleted once the cursor is back to 0, in batches of deleteBatchSize, instead of while scanning.
+// A Redis SCAN may return a key more than once, and a SCAN with offset cursors (e.g., the in-process Redis stand-in)
+// skips keys when the keys before the cursor are deleted, so collecting them first is correct for both.
+// The number of deleted keys is the one reported by DEL, so the duplicates and the keys that expired in the meantime
+// aren't counted.
+func scanAndDeleteNode(ctx context.Context, node redis.Cmdable, pattern string, isCluster bool) (int, error) {
+	baseCount := 1     // Initial count for retrieving keys
+	maxCount := 100000 // Maximum limit for the count; if insufficient, it can be increased to 1 million
+
+	var (
+		cursor uint64
+		count  = baseCount
+		seen   = make(map[string]struct{})
+		keys   []string
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		default:
+			// Continue scanning keys as the context is still valid.
+			// Do not return here.
+		}
+
+		start := time.Now()
+
+		batch, nextCursor, err := node.Scan(ctx, cursor, pattern, int64(count)).Result()
+		if err != nil {
+			return 0, err
+		}
+
+		// Calculate time taken for this iteration
+		duration := time.Since(start)
+
+		// Adjust count based on duration
+		if duration < 50*time.Millisecond && count < maxCount {
+			count *= 2 // Double the count if operation is fast
+		} else if duration > 200*time.Millisecond && count > baseCount {
+			count /= 2 // Halve the count if operation is slow
+		}
+
+		for _, key := range batch {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	var totalDeleted int
+	for batch := range slices.Chunk(keys, deleteBatchSize) {
+		deleted, err := deleteRedisKeys(ctx, node, batch, isCluster)
+		totalDeleted += deleted
+		if err != nil {
+			return totalDeleted, err
+		}
+	}
+	return totalDeleted, nil
+}
+
+// deleteBatchSize is the maximum number of keys deleted by a single DEL (or pipeline in a cluster).
+const deleteBatchSize = 1000
+
+// deleteRedisKeys deletes the keys in a single round-trip, and returns the number of keys that existed.
+//
+// Note: In a cluster, a multi-key DEL fails with CROSSSLOT when the keys hash to different slots
+// (even on the same node), so every key gets its own DEL in a pipeline instead.
+func deleteRedisKeys(ctx context.Context, rdb redis.Cmdable, keys []string, isCluster bool) (int, error) {
+	if !isCluster {
+		deleted, err := rdb.Del(ctx, keys...).Result()
+		return int(deleted), err
+	}
+
+	cmds, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		return nil
+	})
+	var deleted int
+	for _, cmd := range cmds {
+		if del, ok := cmd.(*redis.IntCmd); ok {
+			deleted += int(del.Val())
+		}
+	}
+	return deleted, err
+}
+
+// redisPoolSize returns the configured pool size of the client (per node for a cluster).
+func redisPoolSize(rdb redis.UniversalClient) int {
+	switch c := rdb.(type) {
+	case *redis.Client:
+		return c.Options().PoolSize
+	case *redis.ClusterClient:
+		return c.Options().PoolSize
+	}
+	return defaultFiberMaxConnections
+}
+
+// checkRedisClusterHealth pings every shard and adds the cluster state to the stats map.
+// The Redis status is marked as down when the cluster is not "ok" or a shard doesn't respond.
+func checkRedisClusterHealth(ctx context.Context, cluster *redis.ClusterClient, stats map[string]string) map[string]string {
+	var shards atomic.Int64
+	err := cluster.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+		shards.Add(1)
+		if err := node.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("%s: %w", node.Options().Addr, err)
+		}
+		return nil
+	})
+	stats["redis_cluster_nodes_checked"] = strconv.FormatInt(shards.Load(), 10)
+	if err != nil {
+		stats["redis_status"] = "down"
+		stats["redis_error"] = fmt.Sprintf(ErrDBDown, err)
+		return stats
+	}
+
+	info, err := cluster.ClusterInfo(ctx).Result()
+	if err != nil {
+		stats["redis_message"] = fmt.Sprintf(MsgRedisFailedToRetrieveInfo, err)
+		return stats
+	}
+
+	clusterInfo := parseRedisInfo(info)
+	stats["redis_cluster_state"] = clusterInfo["cluster_state"]
+	stats["redis_cluster_size"] = clusterInfo["cluster_size"]
+	stats["redis_cluster_known_nodes"] = clusterInfo["cluster_known_nodes"]
+	if clusterInfo["cluster_state"] != "ok" {
+		stats["redis_status"] = "down"
+		stats["redis_error"] = fmt.Sprintf(ErrDBDown, "cluster state is "+clusterInfo["cluster_state"])
+	}
+	return stats
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestScanAndDeleteTopologies(t *testing.T) {
+	tests := []struct {
+		name      string
+		newClient func(addr string) redis.UniversalClient
+	}{
+		{"standalone", func(addr string) redis.UniversalClient {
+			return redis.NewClient(&redis.Options{Addr: addr})
+		}},
+		{"cluster", func(addr string) redis.UniversalClient {
+			// Note: miniredis answers CLUSTER SLOTS with a single node owning every slot,
+			// which is enough to exercise the per-master SCAN and per-slot DEL paths.
+			return redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{addr}})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			rdb := tt.newClient(mr.Addr())
+			t.Cleanup(func() { rdb.Close() })
+			ctx := context.Background()
+
+			// Keys that hash to many different slots, written through a pipeline.
+			_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for i := range 50 {
+					pipe.Set(ctx, fmt.Sprintf("user:%d", i), i, 0)
+				}
+				pipe.Set(ctx, "keep:1", "gopher", 0)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Pipelined() error = %v", err)
+			}
+
+			deleted, err := scanAndDelete(ctx, rdb, []string{"user:*"})
+			if err != nil {
+				t.Fatalf("scanAndDelete() error = %v", err)
+			}
+			if deleted != 50 {
+				t.Errorf("scanAndDelete() deleted %d keys, want 50", deleted)
+			}
+			if got := mr.Keys(); !reflect.DeepEqual(got, []string{"keep:1"}) {
+				t.Errorf("remaining keys = %v, want [keep:1]", got)
+			}
+		})
+	}
+}
+
+func TestInitializeRedisClientModes(t *testing.T) {
+	redistlsCAs = testRootCA(t)
+	t.Cleanup(func() { redistlsCAs = "" })
+
+	tests := []struct {
+		name    string
+		config  RedisClientConfig
+		wantErr error
+		want    string
+	}{
+		{"standalone", RedisClientConfig{Address: "localhost", Port: 6379}, nil, "*redis.Client"},
+		{"sentinel", RedisClientConfig{Mode: RedisModeSentinel, MasterName: "mymaster", SentinelAddrs: []string{"localhost:26379"}}, nil, "*redis.Client"},
+		{"sentinel without master", RedisClientConfig{Mode: RedisModeSentinel, SentinelAddrs: []string{"localhost:26379"}}, ErrRedisSentinelConfig, ""},
+		{"cluster", RedisClientConfig{Mode: RedisModeCluster, ClusterAddrs: []string{"localhost:7000", "localhost:7001"}}, nil, "*redis.ClusterClient"},
+		{"cluster without seeds", RedisClientConfig{Mode: RedisModeCluster}, ErrRedisClusterConfig, ""},
+		{"unsupported", RedisClientConfig{Mode: "ring"}, ErrRedisUnsupportedMode, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.config.InitializeRedisClient()
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("InitializeRedisClient() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InitializeRedisClient() error = %v", err)
+			}
+			defer client.Close()
+			if got := fmt.Sprintf("%T", client); got != tt.want {
+				t.Errorf("InitializeRedisClient() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// testRootCA returns a base64-encoded self-signed CA, in the same format as REDIS_CERTS_TLS.
+func testRootCA(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Gopher Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
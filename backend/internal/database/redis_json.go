@@ -19,20 +19,17 @@ import (
 // Note: Currently, this implementation uses the default JSON encoder/decoder from the standard library or other libraries like Sonic.
 // Encoding with indentation (e.g., MarshalIndent) is not supported, as it's generally unnecessary for this use case.
 //
-// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-// This change will elevate it to a top-level function if you know how to handle it.
+// Note: This is also used by the generic [T] helpers through [JSONOptions].
 type JSONEncoder func(v any) ([]byte, error)
 
 // JSONDecoder is a function type for decoding JSON data into an object.
 //
-// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-// This change will elevate it to a top-level function if you know how to handle it.
+// Note: This is also used by the generic [T] helpers through [JSONOptions].
 type JSONDecoder func(data []byte, v any) error
 
 // KeyFunc is a function type for extracting one or more keys from an object.
 //
-// TODO: Switch to generics [T] or implement this specifically for generics [T] ?
-// This change will elevate it to a top-level function if you know how to handle it.
+// Note: For the generic [T] helpers, see [JSONKeyExtractor] instead.
 type KeyFunc func(v any) ([]string, error)
 
 // SetKeysJSONAtPipeline stores multiple objects in Redis using JSON.SET with a custom encoder and key extractor.
@@ -41,7 +38,7 @@ func (s *service) SetKeysJSONAtPipeline(ctx context.Context, objects []any, enco
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Use a context with a timeout to avoid hanging indefinitely
 	//
@@ -85,7 +82,7 @@ func (s *service) GetKeysJSONAtPipeline(ctx context.Context, objects []any, deco
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Use a context with a timeout to avoid hanging indefinitely
 	//
@@ -148,7 +145,7 @@ func (s *service) GetRawJSONAtPipeline(ctx context.Context, objects []any, keyFu
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Use a context with a timeout to avoid hanging indefinitely
 	//
@@ -210,7 +207,7 @@ func (s *service) DelKeysJSONAtPipeline(ctx context.Context, objects []any, keyF
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	pipe := s.redisClient.Pipeline()
+	pipe := s.redisConn().Pipeline()
 
 	// Use a context with a timeout to avoid hanging indefinitely
 	//
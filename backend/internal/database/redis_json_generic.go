@@ -0,0 +1,404 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+//
+// Note: These are the generic [T] versions of the *JSONAtPipeline methods. They are top-level functions
+// because Go doesn't allow generic methods, so pass the Redis client from [Service.RedisClient].
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrJSONKeyNotFound is reported per key when the key (or the path inside it) doesn't exist.
+var ErrJSONKeyNotFound = errors.New("database: JSON key or path not found")
+
+// jsonWriteScript runs the JSON write command ARGV[2] (with the arguments ARGV[3...]) on KEYS[1], then sets its TTL
+// (ARGV[1], in milliseconds) only when the write succeeded, so a failed write doesn't change the TTL of the existing value.
+//
+// Note: A failing redis.call aborts the script, and a nil reply (e.g., a path that doesn't exist) skips the TTL.
+var jsonWriteScript = redis.NewScript(`
+local res = redis.call(ARGV[2], KEYS[1], unpack(ARGV, 3))
+if res then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return res
+`)
+
+// queueJSONWrite queues the JSON write command on the key with the arguments, setting the TTL of the key after it
+// (when ttl is positive) in the same script (see jsonWriteScript).
+func queueJSONWrite(ctx context.Context, pipe redis.Pipeliner, command, key string, ttl time.Duration, args ...any) *redis.Cmd {
+	if ttl <= 0 {
+		return pipe.Do(ctx, append([]any{command, key}, args...)...)
+	}
+	// Note: A TTL under a millisecond would be 0, which deletes the key right away.
+	return jsonWriteScript.Eval(ctx, pipe, []string{key}, append([]any{max(ttl.Milliseconds(), 1), command}, args...)...)
+}
+
+// JSONKeyExtractor extracts the Redis key for a typed object (e.g., "user:" + u.ID).
+type JSONKeyExtractor[T any] func(v T) (string, error)
+
+// JSONOptions configures the generic RedisJSON helpers.
+type JSONOptions struct {
+	// Path is the JSONPath to operate on. Use a nested path (e.g., "$.profile") for partial updates.
+	//
+	// Optional. Default: "$" (root).
+	Path string
+
+	// TTL is applied to every written key. Zero means no expiration.
+	//
+	// Optional. Default: 0.
+	TTL time.Duration
+
+	// TTLFunc returns the TTL for a specific key and takes precedence over TTL.
+	// Returning zero for a key leaves its expiration untouched.
+	//
+	// Optional. Default: nil.
+	TTLFunc func(key string) time.Duration
+
+	// Encoder is used to encode values before writing them.
+	//
+	// Optional. Default: sonic.Marshal.
+	Encoder JSONEncoder
+
+	// Decoder is used to decode values that were read.
+	//
+	// Optional. Default: sonic.Unmarshal.
+	Decoder JSONDecoder
+}
+
+// JSONKeyErrors holds the per-key errors of a pipeline. Keys that are not in the map succeeded.
+//
+// The items whose key couldn't be extracted are reported by their index in the items instead (e.g., "[3]", see [JSONItemKey]).
+//
+// Example Usage:
+//
+//	if err := database.JSONSetMany(ctx, db.RedisClient(), users, userKey); err != nil {
+//	    var keyErrs database.JSONKeyErrors
+//	    if errors.As(err, &keyErrs) {
+//	        for key, err := range keyErrs {
+//	            log.LogErrorf("Failed to set %s: %v", key, err)
+//	        }
+//	    }
+//	}
+type JSONKeyErrors map[string]error
+
+// Error implements the error interface.
+func (e JSONKeyErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "database: %d key(s) failed in pipeline:", len(e))
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s: %v;", k, e[k])
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// JSONItemKey returns the key under which [JSONKeyErrors] reports the item at index i of the items
+// when its key couldn't be extracted (e.g., "[3]").
+func JSONItemKey(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// errOrNil returns nil when there are no per-key errors, so callers can use the usual err != nil check.
+func (e JSONKeyErrors) errOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// jsonOptions returns the first options with defaults applied.
+func jsonOptions(opts []JSONOptions) JSONOptions {
+	var o JSONOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Path == "" {
+		o.Path = "$"
+	}
+	if o.Encoder == nil {
+		o.Encoder = sonic.Marshal
+	}
+	if o.Decoder == nil {
+		o.Decoder = sonic.Unmarshal
+	}
+	return o
+}
+
+// ttlFor returns the TTL to apply to key.
+func (o JSONOptions) ttlFor(key string) time.Duration {
+	if o.TTLFunc != nil {
+		return o.TTLFunc(key)
+	}
+	return o.TTL
+}
+
+// jsonWrite is a pending write for a single key.
+type jsonWrite struct {
+	key  string
+	data string
+}
+
+// encodeJSONWrites encodes the items and extracts their keys.
+// Items that fail are reported in the returned errors and skipped, so they don't stop the other items.
+func encodeJSONWrites[T any](items []T, keyFn JSONKeyExtractor[T], o JSONOptions) ([]jsonWrite, JSONKeyErrors) {
+	writes := make([]jsonWrite, 0, len(items))
+	keyErrs := make(JSONKeyErrors)
+	for i, item := range items {
+		key, err := keyFn(item)
+		if err != nil {
+			keyErrs[JSONItemKey(i)] = fmt.Errorf("failed to get key: %w", err)
+			continue
+		}
+		data, err := o.Encoder(item)
+		if err != nil {
+			keyErrs[key] = fmt.Errorf("failed to encode object: %w", err)
+			continue
+		}
+		writes = append(writes, jsonWrite{key: key, data: string(data)})
+	}
+	return writes, keyErrs
+}
+
+// execJSONWrites runs the writes with the command (e.g., "JSON.SET") in a single pipeline, with their TTL (see queueJSONWrite).
+func execJSONWrites(ctx context.Context, rdb redis.Cmdable, command string, writes []jsonWrite, keyErrs JSONKeyErrors, o JSONOptions) error {
+	if len(writes) == 0 {
+		return keyErrs.errOrNil()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultCtxTimeout)
+	defer cancel()
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.Cmd, len(writes))
+	for i, w := range writes {
+		cmds[i] = queueJSONWrite(ctx, pipe, command, w.key, o.ttlFor(w.key), o.Path, w.data)
+	}
+
+	// Note: Exec returns the first failed command's error, but every command still runs,
+	// so the per-key results below are the source of truth.
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return fmt.Errorf("pipeline execution failed: %w", err)
+	}
+
+	for i, w := range writes {
+		if err := cmds[i].Err(); err != nil {
+			if errors.Is(err, redis.Nil) {
+				err = ErrJSONKeyNotFound
+			}
+			keyErrs[w.key] = err
+		}
+	}
+
+	return keyErrs.errOrNil()
+}
+
+// JSONSetMany stores the items in Redis using JSON.SET in a single pipeline.
+//
+// With a nested Path (e.g., "$.profile"), each item replaces only that part of the existing document (partial update).
+// Note that RedisJSON only allows creating new keys at the root path.
+//
+// It returns [JSONKeyErrors] when some keys failed, while the other keys are still written.
+func JSONSetMany[T any](ctx context.Context, rdb redis.Cmdable, items []T, keyFn JSONKeyExtractor[T], opts ...JSONOptions) error {
+	o := jsonOptions(opts)
+	writes, keyErrs := encodeJSONWrites(items, keyFn, o)
+	return execJSONWrites(ctx, rdb, "JSON.SET", writes, keyErrs, o)
+}
+
+// JSONMerge merges the items into existing documents using JSON.MERGE (RFC 7396 semantics) in a single pipeline.
+// Fields set to null in the item are deleted from the document, and fields that are not present are left untouched.
+//
+// Note: This requires RedisJSON 2.6+ (Redis Stack 7.2+). Use a struct with "omitempty" tags or a map for partial patches.
+//
+// It returns [JSONKeyErrors] when some keys failed, while the other keys are still merged.
+func JSONMerge[T any](ctx context.Context, rdb redis.Cmdable, items []T, keyFn JSONKeyExtractor[T], opts ...JSONOptions) error {
+	o := jsonOptions(opts)
+	writes, keyErrs := encodeJSONWrites(items, keyFn, o)
+	return execJSONWrites(ctx, rdb, "JSON.MERGE", writes, keyErrs, o)
+}
+
+// JSONGetMany retrieves the keys from Redis using JSON.GET in a single pipeline and decodes them into T.
+//
+// With a JSONPath (starting with "$"), the first match is decoded. With a legacy path (e.g., "." or ".profile"),
+// the value is decoded directly.
+//
+// The returned map only contains the keys that were found and decoded. Missing keys are reported
+// as [ErrJSONKeyNotFound] in [JSONKeyErrors], along with any other per-key failure.
+func JSONGetMany[T any](ctx context.Context, rdb redis.Cmdable, keys []string, opts ...JSONOptions) (map[string]T, error) {
+	o := jsonOptions(opts)
+	results := make(map[string]T, len(keys))
+	if len(keys) == 0 {
+		return results, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultCtxTimeout)
+	defer cancel()
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.JSONCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.JSONGet(ctx, key, o.Path)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("pipeline execution failed: %w", err)
+	}
+
+	keyErrs := make(JSONKeyErrors)
+	for i, key := range keys {
+		raw, err := cmds[i].Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				err = ErrJSONKeyNotFound
+			}
+			keyErrs[key] = err
+			continue
+		}
+
+		v, err := decodeJSONValue[T](raw, o)
+		if err != nil {
+			keyErrs[key] = err
+			continue
+		}
+		results[key] = v
+	}
+
+	return results, keyErrs.errOrNil()
+}
+
+// decodeJSONValue decodes a JSON.GET reply, unwrapping the array returned for JSONPath queries.
+func decodeJSONValue[T any](raw string, o JSONOptions) (T, error) {
+	var v T
+	if raw == "" {
+		return v, ErrJSONKeyNotFound
+	}
+
+	if !strings.HasPrefix(o.Path, "$") {
+		if err := o.Decoder([]byte(raw), &v); err != nil {
+			return v, fmt.Errorf("failed to decode object: %w", err)
+		}
+		return v, nil
+	}
+
+	var matches []T
+	if err := o.Decoder([]byte(raw), &matches); err != nil {
+		return v, fmt.Errorf("failed to decode object: %w", err)
+	}
+	if len(matches) == 0 {
+		return v, ErrJSONKeyNotFound
+	}
+	return matches[0], nil
+}
+
+// JSONArrAppend appends the values to the JSON array at path in key using JSON.ARRAPPEND.
+// The values are encoded with the encoder from opts (sonic by default), and the TTL options are applied to the key
+// once the values are appended.
+//
+// It returns the new length of every matching array, with nil entries for the matches that are not arrays.
+// A legacy path (e.g., ".tags") has a single match.
+func JSONArrAppend(ctx context.Context, rdb redis.Cmdable, key, path string, values []any, opts ...JSONOptions) ([]*int64, error) {
+	o := jsonOptions(opts)
+	if path == "" {
+		path = o.Path
+	}
+
+	args := make([]any, 0, len(values)+1)
+	args = append(args, path)
+	for _, value := range values {
+		data, err := o.Encoder(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode object: %w", err)
+		}
+		args = append(args, string(data))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultCtxTimeout)
+	defer cancel()
+
+	pipe := rdb.Pipeline()
+	cmd := queueJSONWrite(ctx, pipe, "JSON.ARRAPPEND", key, o.ttlFor(key), args...)
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("pipeline execution failed: %w", err)
+	}
+
+	reply, err := cmd.Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrJSONKeyNotFound
+		}
+		return nil, err
+	}
+	return jsonArrLengths(reply)
+}
+
+// jsonArrLengths converts the reply of JSON.ARRAPPEND: an array of lengths (nil for non-array matches) for a JSONPath,
+// or a single length for a legacy path.
+func jsonArrLengths(reply any) ([]*int64, error) {
+	switch reply := reply.(type) {
+	case int64:
+		return []*int64{&reply}, nil
+	case []any:
+		lengths := make([]*int64, len(reply))
+		for i, v := range reply {
+			switch v := v.(type) {
+			case int64:
+				lengths[i] = &v
+			case nil:
+			default:
+				return nil, fmt.Errorf("database: unexpected JSON.ARRAPPEND reply %T", v)
+			}
+		}
+		return lengths, nil
+	default:
+		return nil, fmt.Errorf("database: unexpected JSON.ARRAPPEND reply %T", reply)
+	}
+}
+
+// JSONDelMany deletes the keys (or the Path inside them) from Redis using JSON.DEL in a single pipeline.
+// A key that doesn't exist isn't an error.
+//
+// It returns [JSONKeyErrors] when some keys failed, while the other keys are still deleted.
+func JSONDelMany(ctx context.Context, rdb redis.Cmdable, keys []string, opts ...JSONOptions) error {
+	o := jsonOptions(opts)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultCtxTimeout)
+	defer cancel()
+
+	pipe := rdb.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.JSONDel(ctx, key, o.Path)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil && ctx.Err() != nil {
+		return fmt.Errorf("pipeline execution failed: %w", err)
+	}
+
+	keyErrs := make(JSONKeyErrors)
+	for i, key := range keys {
+		if err := cmds[i].Err(); err != nil {
+			keyErrs[key] = err
+		}
+	}
+	return keyErrs.errOrNil()
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newRedisStackClient connects to a local Redis Stack (RedisJSON enabled) instance.
+// The address can be set with REDIS_STACK_ADDR (default: "localhost:6379"), for example:
+//
+//	docker run --rm -p 6379:6379 redis/redis-stack-server:latest
+//
+// The test is skipped when Redis Stack is not available.
+func newRedisStackClient(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_STACK_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := rdb.JSONSet(ctx, "gopher_test:probe", "$", `{}`).Err(); err != nil {
+		rdb.Close()
+		t.Skipf("Redis Stack is not available at %s: %v", addr, err)
+	}
+	rdb.Del(ctx, "gopher_test:probe")
+
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+type jsonUser struct {
+	ID      string      `json:"id"`
+	Name    string      `json:"name"`
+	Profile jsonProfile `json:"profile"`
+	Tags    []string    `json:"tags"`
+}
+
+type jsonProfile struct {
+	Bio  string `json:"bio,omitempty"`
+	Lang string `json:"lang,omitempty"`
+}
+
+func jsonUserKey(u jsonUser) (string, error) {
+	if u.ID == "" {
+		return "", errors.New("missing id")
+	}
+	return "gopher_test:user:" + u.ID, nil
+}
+
+func TestJSONSetManyGetMany(t *testing.T) {
+	rdb := newRedisStackClient(t)
+	ctx := context.Background()
+
+	users := []jsonUser{
+		{ID: "1", Name: "gopher", Profile: jsonProfile{Lang: "en"}, Tags: []string{"go"}},
+		{ID: "2", Name: "ferris", Profile: jsonProfile{Lang: "id"}, Tags: []string{}},
+	}
+	keys := []string{"gopher_test:user:1", "gopher_test:user:2"}
+	t.Cleanup(func() { rdb.Del(context.Background(), append(keys, "gopher_test:user:missing")...) })
+
+	err := database.JSONSetMany(ctx, rdb, users, jsonUserKey, database.JSONOptions{
+		TTLFunc: func(key string) time.Duration {
+			if key == keys[0] {
+				return time.Minute
+			}
+			return 0
+		},
+	})
+	if err != nil {
+		t.Fatalf("JSONSetMany() error = %v", err)
+	}
+
+	if ttl := rdb.TTL(ctx, keys[0]).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL(%s) = %v, want (0, 1m]", keys[0], ttl)
+	}
+	if ttl := rdb.TTL(ctx, keys[1]).Val(); ttl != -1 {
+		t.Errorf("TTL(%s) = %v, want no expiration", keys[1], ttl)
+	}
+
+	got, err := database.JSONGetMany[jsonUser](ctx, rdb, append(keys, "gopher_test:user:missing"))
+	var keyErrs database.JSONKeyErrors
+	if !errors.As(err, &keyErrs) {
+		t.Fatalf("JSONGetMany() error = %v, want JSONKeyErrors", err)
+	}
+	if len(keyErrs) != 1 || !errors.Is(keyErrs["gopher_test:user:missing"], database.ErrJSONKeyNotFound) {
+		t.Errorf("JSONGetMany() key errors = %v, want only the missing key", keyErrs)
+	}
+	if got[keys[0]].Name != "gopher" || got[keys[1]].Profile.Lang != "id" {
+		t.Errorf("JSONGetMany() = %+v", got)
+	}
+
+	// Partial path update and read.
+	if err := database.JSONSetMany(ctx, rdb, []jsonProfile{{Bio: "hello", Lang: "fr"}},
+		func(jsonProfile) (string, error) { return keys[0], nil },
+		database.JSONOptions{Path: "$.profile"}); err != nil {
+		t.Fatalf("JSONSetMany() partial error = %v", err)
+	}
+	profiles, err := database.JSONGetMany[jsonProfile](ctx, rdb, keys[:1], database.JSONOptions{Path: "$.profile"})
+	if err != nil {
+		t.Fatalf("JSONGetMany() partial error = %v", err)
+	}
+	if p := profiles[keys[0]]; p.Bio != "hello" || p.Lang != "fr" {
+		t.Errorf("JSONGetMany() partial = %+v", p)
+	}
+}
+
+func TestJSONMergeAndArrAppend(t *testing.T) {
+	rdb := newRedisStackClient(t)
+	ctx := context.Background()
+
+	key := "gopher_test:user:3"
+	t.Cleanup(func() { rdb.Del(context.Background(), key) })
+
+	if err := database.JSONSetMany(ctx, rdb, []jsonUser{{ID: "3", Name: "gopher", Profile: jsonProfile{Lang: "en"}, Tags: []string{"go"}}}, jsonUserKey); err != nil {
+		t.Fatalf("JSONSetMany() error = %v", err)
+	}
+
+	patch := []map[string]any{{"name": "gopher2"}}
+	if err := database.JSONMerge(ctx, rdb, patch, func(map[string]any) (string, error) { return key, nil }); err != nil {
+		t.Fatalf("JSONMerge() error = %v", err)
+	}
+
+	lengths, err := database.JSONArrAppend(ctx, rdb, key, "$.tags", []any{"redis", "json"})
+	if err != nil {
+		t.Fatalf("JSONArrAppend() error = %v", err)
+	}
+	if len(lengths) != 1 || lengths[0] == nil || *lengths[0] != 3 {
+		t.Errorf("JSONArrAppend() = %v, want [3]", lengths)
+	}
+
+	got, err := database.JSONGetMany[jsonUser](ctx, rdb, []string{key})
+	if err != nil {
+		t.Fatalf("JSONGetMany() error = %v", err)
+	}
+	u := got[key]
+	if u.Name != "gopher2" || u.Profile.Lang != "en" || len(u.Tags) != 3 {
+		t.Errorf("after merge/append = %+v", u)
+	}
+}
+
+func TestJSONSetManyPerKeyErrors(t *testing.T) {
+	rdb := newRedisStackClient(t)
+	ctx := context.Background()
+
+	key := "gopher_test:user:4"
+	t.Cleanup(func() { rdb.Del(context.Background(), key) })
+
+	// A nested path can't create a new key, so this one fails while nothing else is affected.
+	err := database.JSONSetMany(ctx, rdb, []jsonProfile{{Bio: "x"}},
+		func(jsonProfile) (string, error) { return key, nil },
+		database.JSONOptions{Path: "$.profile"})
+
+	var keyErrs database.JSONKeyErrors
+	if !errors.As(err, &keyErrs) || keyErrs[key] == nil {
+		t.Fatalf("JSONSetMany() error = %v, want a per-key error for %s", err, key)
+	}
+
+	// A failed write doesn't touch the TTL of the existing value.
+	if err := rdb.Set(ctx, key, "not JSON", 0).Err(); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	err = database.JSONSetMany(ctx, rdb, []jsonProfile{{Bio: "x"}},
+		func(jsonProfile) (string, error) { return key, nil },
+		database.JSONOptions{Path: "$.profile", TTL: time.Minute})
+	if !errors.As(err, &keyErrs) || keyErrs[key] == nil {
+		t.Fatalf("JSONSetMany() error = %v, want a per-key error for %s", err, key)
+	}
+	if ttl := rdb.TTL(ctx, key).Val(); ttl != -1 {
+		t.Errorf("TTL(%s) = %v after a failed write, want no expiration", key, ttl)
+	}
+}
+
+func TestJSONSetManyKeyErrorsAndDelMany(t *testing.T) {
+	rdb := newRedisStackClient(t)
+	ctx := context.Background()
+
+	key := "gopher_test:user:5"
+	t.Cleanup(func() { rdb.Del(context.Background(), key) })
+
+	// An item without a key is reported by its index, and doesn't stop the other items.
+	err := database.JSONSetMany(ctx, rdb, []jsonUser{{Name: "anonymous"}, {ID: "5", Name: "gopher"}}, jsonUserKey)
+	var keyErrs database.JSONKeyErrors
+	if !errors.As(err, &keyErrs) || len(keyErrs) != 1 || keyErrs[database.JSONItemKey(0)] == nil {
+		t.Fatalf("JSONSetMany() error = %v, want only a per-item error for %s", err, database.JSONItemKey(0))
+	}
+	if n := rdb.Exists(ctx, key).Val(); n != 1 {
+		t.Errorf("Exists(%s) = %d, want 1", key, n)
+	}
+
+	if err := database.JSONDelMany(ctx, rdb, []string{key, "gopher_test:user:missing"}); err != nil {
+		t.Fatalf("JSONDelMany() error = %v", err)
+	}
+	if n := rdb.Exists(ctx, key).Val(); n != 0 {
+		t.Errorf("Exists(%s) = %d after JSONDelMany, want 0", key, n)
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrReplicaNotConfigured is returned when "SHOW REPLICA STATUS" returns no rows,
+	// which means the node is not replicating from anything (e.g., it's actually a primary).
+	ErrReplicaNotConfigured = errors.New("database: node is not configured as a replica")
+
+	// ErrReplicaThreadStopped is returned when the replica IO or SQL thread is not running.
+	ErrReplicaThreadStopped = errors.New("database: replica IO/SQL thread is not running")
+
+	// ErrReplicaLagUnknown is returned when Seconds_Behind_Source is NULL, which usually means replication is broken.
+	ErrReplicaLagUnknown = errors.New("database: replica lag is unknown")
+
+	// ErrReplicaLagging is returned when the replica is too far behind the primary.
+	ErrReplicaLagging = errors.New("database: replica lag exceeds the maximum allowed")
+)
+
+// primaryCtxKey is the context key used to force read operations to the primary.
+type primaryCtxKey struct{}
+
+// WithPrimary returns a copy of ctx that forces read-only calls (QueryRow, Query, StreamRows) to the primary.
+//
+// This is useful for read-your-writes consistency, for example right after an INSERT when the replicas may not
+// have caught up yet.
+//
+// Example Usage:
+//
+//	if _, err := db.Exec(ctx, "INSERT INTO users (name) VALUES (?)", name); err != nil {
+//	    return err
+//	}
+//	row := db.QueryRow(database.WithPrimary(ctx), "SELECT id FROM users WHERE name = ?", name)
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+// isPrimaryForced reports whether the context was marked by [WithPrimary].
+func isPrimaryForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return forced
+}
+
+// replicaNode is a single read replica along with its last known health.
+type replicaNode struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+	lag     atomic.Int64 // last observed lag in seconds, -1 when unknown
+	lastErr atomic.Value // last health check error (string), empty when healthy
+}
+
+// replicaSet load-balances read-only queries across healthy replicas using round-robin.
+//
+// Note: Replicas are ejected automatically when the health checker sees them lagging too much or broken,
+// and they are put back once they recover. When there are no healthy replicas, reads fall back to the primary.
+type replicaSet struct {
+	nodes    []*replicaNode
+	next     atomic.Uint64
+	maxLag   time.Duration
+	interval time.Duration
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// newReplicaSet creates a replica set from already opened connection pools.
+// Every node starts ejected until the first health check marks it healthy.
+func newReplicaSet(dbs map[string]*sql.DB, maxLag, interval time.Duration) *replicaSet {
+	rs := &replicaSet{
+		nodes:    make([]*replicaNode, 0, len(dbs)),
+		maxLag:   maxLag,
+		interval: interval,
+		stop:     make(chan struct{}),
+	}
+	for name, db := range dbs {
+		n := &replicaNode{name: name, db: db}
+		n.lag.Store(-1)
+		n.lastErr.Store("not checked yet")
+		rs.nodes = append(rs.nodes, n)
+	}
+	return rs
+}
+
+// start runs the first health check synchronously, then keeps checking in the background until close is called.
+func (rs *replicaSet) start() {
+	rs.checkAll()
+
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		ticker := time.NewTicker(rs.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rs.stop:
+				return
+			case <-ticker.C:
+				rs.checkAll()
+			}
+		}
+	}()
+}
+
+// pick returns the next healthy replica in round-robin order, or nil when none are healthy.
+func (rs *replicaSet) pick() *sql.DB {
+	if rs == nil || len(rs.nodes) == 0 {
+		return nil
+	}
+
+	total := uint64(len(rs.nodes))
+	start := rs.next.Add(1)
+	for i := range total {
+		n := rs.nodes[(start+i)%total]
+		if n.healthy.Load() {
+			return n.db
+		}
+	}
+	return nil
+}
+
+// checkAll health-checks every replica concurrently.
+func (rs *replicaSet) checkAll() {
+	var wg sync.WaitGroup
+	for _, n := range rs.nodes {
+		wg.Add(1)
+		go func(n *replicaNode) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultPingCtxTimeout)
+			defer cancel()
+			rs.update(n, rs.check(ctx, n))
+		}(n)
+	}
+	wg.Wait()
+}
+
+// update records the check result and logs when a replica is ejected or put back.
+func (rs *replicaSet) update(n *replicaNode, err error) {
+	if err != nil {
+		n.lastErr.Store(err.Error())
+		if n.healthy.Swap(false) {
+			log.LogErrorf("MySQL replica %s ejected: %v", n.name, err)
+		}
+		return
+	}
+
+	n.lastErr.Store("")
+	if !n.healthy.Swap(true) {
+		log.LogInfof("MySQL replica %s is healthy (lag %ds), accepting reads.", n.name, n.lag.Load())
+	}
+}
+
+// check pings the replica and verifies its replication status using "SHOW REPLICA STATUS".
+//
+// Note: This requires MySQL 8.0.22+ (or a recent MariaDB that supports the alias), since older versions only know "SHOW SLAVE STATUS".
+func (rs *replicaSet) check(ctx context.Context, n *replicaNode) error {
+	if err := n.db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	rows, err := n.db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	status, err := scanReplicaStatus(rows)
+	if err != nil {
+		return err
+	}
+
+	if status["Replica_IO_Running"] != "Yes" || status["Replica_SQL_Running"] != "Yes" {
+		n.lag.Store(-1)
+		return ErrReplicaThreadStopped
+	}
+
+	lagStr, ok := status["Seconds_Behind_Source"]
+	if !ok || lagStr == "" {
+		n.lag.Store(-1)
+		return ErrReplicaLagUnknown
+	}
+
+	lag, err := strconv.ParseInt(lagStr, 10, 64)
+	if err != nil {
+		n.lag.Store(-1)
+		return fmt.Errorf("database: invalid Seconds_Behind_Source %q: %w", lagStr, err)
+	}
+	n.lag.Store(lag)
+
+	if time.Duration(lag)*time.Second > rs.maxLag {
+		return fmt.Errorf("%w: %ds > %s", ErrReplicaLagging, lag, rs.maxLag)
+	}
+
+	return nil
+}
+
+// scanReplicaStatus reads the first row of "SHOW REPLICA STATUS" into a map of column name to value.
+// NULL values are stored as empty strings.
+func scanReplicaStatus(rows *sql.Rows) (map[string]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrReplicaNotConfigured
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	scanArgs := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	if err := rows.Scan(scanArgs...); err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]string, len(columns))
+	for i, col := range columns {
+		status[col] = string(values[i])
+	}
+	return status, nil
+}
+
+// stats adds replica health statistics to the stats map.
+func (rs *replicaSet) stats(stats map[string]string) map[string]string {
+	healthy := 0
+	for _, n := range rs.nodes {
+		prefix := "mysql_replica_" + n.name
+		if n.healthy.Load() {
+			healthy++
+			stats[prefix+"_status"] = "up"
+		} else {
+			stats[prefix+"_status"] = "ejected"
+			if msg, _ := n.lastErr.Load().(string); msg != "" {
+				stats[prefix+"_error"] = msg
+			}
+		}
+		if lag := n.lag.Load(); lag >= 0 {
+			stats[prefix+"_lag_seconds"] = strconv.FormatInt(lag, 10)
+		}
+	}
+	stats["mysql_replicas"] = strconv.Itoa(len(rs.nodes))
+	stats["mysql_replicas_healthy"] = strconv.Itoa(healthy)
+	return stats
+}
+
+// close stops the health checker and closes every replica connection pool.
+func (rs *replicaSet) close() error {
+	var errs []error
+	rs.once.Do(func() {
+		close(rs.stop)
+		rs.wg.Wait()
+		for _, n := range rs.nodes {
+			if err := n.db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("replica %s: %w", n.name, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// initializeMySQLReplicas opens the read replicas listed in DB_REPLICAS.
+// It returns nil when no replicas are configured.
+//
+// Each entry is either a "host:port" (or just "host", using DB_PORT), in which case the credentials, database,
+// and TLS settings of the primary are reused, or a full go-sql-driver DSN (anything containing "@"), which is used as-is.
+//
+// Example:
+//
+//	DB_REPLICAS=mysql-replica-0.mysql:3306,mysql-replica-1.mysql:3306
+func initializeMySQLReplicas(primary *MySQLConfig) (*replicaSet, error) {
+	if strings.TrimSpace(replicaHosts) == "" {
+		return nil, nil
+	}
+
+	maxLag, err := time.ParseDuration(replicaMaxLag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MySQL replica max lag value: %v", err)
+	}
+
+	interval, err := time.ParseDuration(replicaCheckInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MySQL replica check interval value: %v", err)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid MySQL replica check interval value: %s", interval)
+	}
+
+	dbs := make(map[string]*sql.DB)
+	for i, entry := range strings.Split(replicaHosts, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		var (
+			db   *sql.DB
+			name = strconv.Itoa(i)
+		)
+		if strings.Contains(entry, "@") {
+			// Full DSN, don't touch it (and don't log it, since it contains credentials), but still give it the pool of the primary.
+			if db, err = sql.Open(dbMYSQL, entry); err == nil {
+				configureMySQLPool(db)
+			}
+		} else {
+			cfg := *primary
+			cfg.Host, cfg.Port = entry, primary.Port
+			if h, p, splitErr := net.SplitHostPort(entry); splitErr == nil {
+				cfg.Host, cfg.Port = h, p
+			}
+			db, err = cfg.openMySQLDB()
+		}
+		if err != nil {
+			for _, opened := range dbs {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("failed to open MySQL replica %s: %w", name, err)
+		}
+		dbs[name] = db
+	}
+
+	if len(dbs) == 0 {
+		return nil, nil
+	}
+
+	rs := newReplicaSet(dbs, maxLag, interval)
+	rs.start()
+	return rs, nil
+}
@@ -0,0 +1,327 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeReplicaDriver is a database/sql driver answering "SHOW REPLICA STATUS" with the status of a fakeReplica,
+// found by the DSN, so the health checks can be tested without MySQL.
+const fakeReplicaDriver = "fake-replica"
+
+var fakeReplicas sync.Map // DSN -> *fakeReplica
+
+func init() {
+	sql.Register(fakeReplicaDriver, fakeDriver{})
+}
+
+// fakeReplica is the replication status of a fake replica. A nil status means "SHOW REPLICA STATUS" returns no rows.
+type fakeReplica struct {
+	mu     sync.Mutex
+	down   bool
+	status []driver.Value // Replica_IO_Running, Replica_SQL_Running, Seconds_Behind_Source (nil is NULL)
+}
+
+// set sets the status of the replica, or takes it down.
+func (r *fakeReplica) set(down bool, status ...driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.down, r.status = down, status
+}
+
+// replicating is the status of a replica that is replicating with the lag.
+func replicating(lag string) []driver.Value {
+	return []driver.Value{"Yes", "Yes", lag}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	r, ok := fakeReplicas.Load(dsn)
+	if !ok {
+		return nil, errors.New("unknown fake replica " + dsn)
+	}
+	return &fakeConn{replica: r.(*fakeReplica)}, nil
+}
+
+type fakeConn struct {
+	replica *fakeReplica
+}
+
+var errReplicaDown = errors.New("connection refused")
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.replica.mu.Lock()
+	defer c.replica.mu.Unlock()
+	if c.replica.down {
+		return errReplicaDown
+	}
+	return nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.replica.mu.Lock()
+	defer c.replica.mu.Unlock()
+	if c.replica.down {
+		return nil, errReplicaDown
+	}
+	if query != "SHOW REPLICA STATUS" {
+		return nil, errors.New("unexpected query " + query)
+	}
+	rows := &fakeRows{columns: []string{"Replica_IO_Running", "Replica_SQL_Running", "Seconds_Behind_Source"}}
+	if c.replica.status != nil {
+		rows.values = [][]driver.Value{c.replica.status}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeReplicaSet returns a replica set of fake replicas named by names, all replicating without lag,
+// and the fake replicas by name. The health checks are only run by the test.
+func newFakeReplicaSet(t *testing.T, maxLag time.Duration, names ...string) (*replicaSet, map[string]*fakeReplica) {
+	t.Helper()
+	dbs := make(map[string]*sql.DB, len(names))
+	replicas := make(map[string]*fakeReplica, len(names))
+	for _, name := range names {
+		dsn := t.Name() + "/" + name
+		replicas[name] = &fakeReplica{status: replicating("0")}
+		fakeReplicas.Store(dsn, replicas[name])
+		t.Cleanup(func() { fakeReplicas.Delete(dsn) })
+
+		db, err := sql.Open(fakeReplicaDriver, dsn)
+		if err != nil {
+			t.Fatalf("sql.Open() error = %v", err)
+		}
+		dbs[name] = db
+	}
+	rs := newReplicaSet(dbs, maxLag, time.Hour)
+	t.Cleanup(func() { rs.close() })
+	return rs, replicas
+}
+
+// nodeOf returns the node of the replica set named name.
+func nodeOf(t *testing.T, rs *replicaSet, name string) *replicaNode {
+	t.Helper()
+	for _, n := range rs.nodes {
+		if n.name == name {
+			return n
+		}
+	}
+	t.Fatalf("no replica named %s", name)
+	return nil
+}
+
+func TestReplicaSetPickRoundRobin(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	rs, _ := newFakeReplicaSet(t, 10*time.Second, "0", "1", "2")
+
+	// Every node starts ejected until the first health check.
+	if db := rs.pick(); db != nil {
+		t.Error("pick() before the first check returned a replica, want nil")
+	}
+	rs.checkAll()
+
+	picked := make(map[*sql.DB]int)
+	var previous *sql.DB
+	for range 6 {
+		db := rs.pick()
+		if db == nil {
+			t.Fatal("pick() = nil with healthy replicas")
+		}
+		if db == previous {
+			t.Error("pick() returned the same replica twice in a row")
+		}
+		previous = db
+		picked[db]++
+	}
+	if len(picked) != 3 {
+		t.Errorf("pick() used %d replicas, want 3", len(picked))
+	}
+	for _, n := range picked {
+		if n != 2 {
+			t.Errorf("pick() counts = %v, want every replica twice", picked)
+			break
+		}
+	}
+
+	var none *replicaSet
+	if db := none.pick(); db != nil {
+		t.Error("pick() of a nil replica set returned a replica, want nil")
+	}
+}
+
+func TestReplicaSetEjectAndRecover(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+
+	tests := []struct {
+		name    string
+		down    bool
+		status  []driver.Value
+		want    error
+		wantLag int64
+	}{
+		{name: "lagging", status: replicating("30"), want: ErrReplicaLagging, wantLag: 30},
+		{name: "unknown lag", status: []driver.Value{"Yes", "Yes", nil}, want: ErrReplicaLagUnknown, wantLag: -1},
+		{name: "IO thread stopped", status: []driver.Value{"No", "Yes", "0"}, want: ErrReplicaThreadStopped, wantLag: -1},
+		{name: "SQL thread stopped", status: []driver.Value{"Yes", "No", "0"}, want: ErrReplicaThreadStopped, wantLag: -1},
+		{name: "not a replica", status: nil, want: ErrReplicaNotConfigured, wantLag: 0},
+		{name: "down", down: true, want: errReplicaDown, wantLag: 0}, // the last known lag is kept
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs, replicas := newFakeReplicaSet(t, 10*time.Second, "0")
+			n := nodeOf(t, rs, "0")
+			rs.checkAll()
+			if !n.healthy.Load() {
+				t.Fatalf("replica not healthy after the first check: %v", n.lastErr.Load())
+			}
+
+			replicas["0"].set(tt.down, tt.status...)
+			ctx := context.Background()
+			err := rs.check(ctx, n)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("check() error = %v, want %v", err, tt.want)
+			}
+			rs.update(n, err)
+			if n.healthy.Load() {
+				t.Error("replica still healthy, want ejected")
+			}
+			if n.lag.Load() != tt.wantLag {
+				t.Errorf("lag = %d, want %d", n.lag.Load(), tt.wantLag)
+			}
+			if db := rs.pick(); db != nil {
+				t.Error("pick() returned the ejected replica")
+			}
+
+			// The replica is put back once it recovers.
+			replicas["0"].set(false, replicating("2")...)
+			rs.checkAll()
+			if !n.healthy.Load() || n.lag.Load() != 2 {
+				t.Errorf("replica healthy = %v with lag %d after recovering, want healthy with lag 2", n.healthy.Load(), n.lag.Load())
+			}
+			if db := rs.pick(); db != n.db {
+				t.Error("pick() didn't return the recovered replica")
+			}
+		})
+	}
+}
+
+func TestReaderFallback(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	primary, err := sql.Open(dbSQLITE, ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer primary.Close()
+
+	rs, replicas := newFakeReplicaSet(t, 10*time.Second, "0", "1")
+	rs.checkAll()
+	s := &service{db: primary, replicas: rs}
+	ctx := context.Background()
+
+	if db := s.reader(ctx); db == primary {
+		t.Error("reader() = primary with healthy replicas, want a replica")
+	}
+	if db := s.reader(WithPrimary(ctx)); db != primary {
+		t.Error("reader(WithPrimary) = a replica, want the primary")
+	}
+
+	// One replica down: the reads go to the other one.
+	replicas["0"].set(true)
+	rs.checkAll()
+	for range 4 {
+		if db := s.reader(ctx); db != nodeOf(t, rs, "1").db {
+			t.Fatal("reader() didn't return the only healthy replica")
+		}
+	}
+
+	// Every replica down: the reads fall back to the primary.
+	replicas["1"].set(true)
+	rs.checkAll()
+	if db := s.reader(ctx); db != primary {
+		t.Error("reader() with every replica down = a replica, want the primary")
+	}
+
+	// Without replicas, the reads go to the primary.
+	if db := (&service{db: primary}).reader(ctx); db != primary {
+		t.Error("reader() without replicas = a replica, want the primary")
+	}
+}
+
+func TestReplicaSetStats(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	rs, replicas := newFakeReplicaSet(t, 10*time.Second, "0", "1")
+	replicas["0"].set(false, replicating("3")...)
+	replicas["1"].set(false, replicating("42")...)
+	rs.checkAll()
+
+	stats := rs.stats(map[string]string{})
+	want := map[string]string{
+		"mysql_replicas":              "2",
+		"mysql_replicas_healthy":      "1",
+		"mysql_replica_0_status":      "up",
+		"mysql_replica_0_lag_seconds": "3",
+		"mysql_replica_1_status":      "ejected",
+		"mysql_replica_1_lag_seconds": "42",
+		"mysql_replica_1_error":       "database: replica lag exceeds the maximum allowed: 42s > 10s",
+	}
+	for key, value := range want {
+		if stats[key] != value {
+			t.Errorf("stats[%q] = %q, want %q", key, stats[key], value)
+		}
+	}
+	if _, ok := stats["mysql_replica_0_error"]; ok {
+		t.Error("stats has an error for the healthy replica")
+	}
+}
+
+func TestInitializeMySQLReplicasPool(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	old := replicaHosts
+	// Note: Nothing listens on port 1, so the replica just stays ejected.
+	replicaHosts = "gopher:secret@tcp(127.0.0.1:1)/gopher"
+	t.Cleanup(func() { replicaHosts = old })
+
+	rs, err := initializeMySQLReplicas(&MySQLConfig{})
+	if err != nil {
+		t.Fatalf("initializeMySQLReplicas() error = %v", err)
+	}
+	defer rs.close()
+
+	// A replica given as a full DSN gets the same pool as the primary (see openMySQLDB).
+	if got := rs.nodes[0].db.Stats().MaxOpenConnections; got != 100 {
+		t.Errorf("MaxOpenConnections = %d, want 100", got)
+	}
+}
@@ -12,9 +12,13 @@ import (
 	"context"
 	"crypto/tls"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
+	"h0llyw00dz-template/backend/internal/database/tiered"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/hybrid"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
@@ -37,6 +41,21 @@ type RedisClientConfig struct {
 	PoolSize              int
 	ConnMaxIdleTime       time.Duration
 	ConnMaxLifetime       time.Duration
+
+	// Mode is the Redis topology: RedisModeStandalone (default), RedisModeSentinel, or RedisModeCluster.
+	Mode string
+
+	// MasterName is the name of the master monitored by Sentinel (Sentinel mode only).
+	MasterName string
+
+	// SentinelAddrs are the "host:port" addresses of the Sentinels (Sentinel mode only).
+	SentinelAddrs []string
+
+	// SentinelPassword is the password for authenticating with the Sentinels, which may differ from Password (Sentinel mode only).
+	SentinelPassword string
+
+	// ClusterAddrs are the "host:port" seed nodes used to discover the cluster (Cluster mode only).
+	ClusterAddrs []string
 }
 
 // FiberRedisClientConfig defines the settings needed for Fiber Redis client initialization.
@@ -70,39 +89,93 @@ type MySQLConfig struct {
 // It will go up and down depending on how the garbage collector is recycling. If immutable is set to false, it can go down to 10MiB or 5MiB when idle.
 var defaultFiberMaxConnections = 5 * runtime.GOMAXPROCS(0)
 
-// InitializeRedisClient initializes and returns a new Redis client.
-func (config *RedisClientConfig) InitializeRedisClient() (*redis.Client, error) {
+// InitializeRedisClient initializes and returns a new Redis client for the configured mode:
+//
+//   - RedisModeStandalone (default): a single node at Address:Port.
+//   - RedisModeSentinel: the master named MasterName, discovered through SentinelAddrs (automatic failover).
+//   - RedisModeCluster: a Redis Cluster discovered from the ClusterAddrs seed nodes.
+//
+// It returns a [redis.UniversalClient], so the callers don't need to care about the topology.
+// Note that SCAN on a cluster only covers one node, so use ScanAndDel, which iterates every master.
+func (config *RedisClientConfig) InitializeRedisClient() (redis.UniversalClient, error) {
 	rootCAs, err := loadRedisRootCA()
 	if err != nil {
 		return nil, err
 	}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", config.Address, config.Port),
-		Password: config.Password,
-		DB:       config.Database,
-		// Note: TLSConfig is optional, but it is recommended for better security, so it's advisable to use it.
-		// Also note that for non-Kubernetes environments, it is recommended to use TLS. For certificates, packages from https://pkg.go.dev/golang.org/x/crypto@v0.24.0/acme or Caddy can be used.
-		// Personally, I don't use this because I am running on Kubernetes with another secure connection method (e.g., bound pods/node ports).
-		// For Mutual TLS or whatever it is, see: https://redis.io/docs/latest/operate/rc/security/database-security/tls-ssl/. However,
-		// the requirement for Mutual TLS or whatever it is depends on how the cloud provider sets it up.
-		// For example, in some cloud providers, Mutual TLS or whatever it is may not be needed, and only the following settings are required.
-		TLSConfig: &tls.Config{
-			// Explicitly set the maximum and minimum TLS versions to 1.3 this server anyways.
-			// However Go's standard TLS 1.3 implementation is broken because it keeps forcing the use of the AES-GCM cipher suite.
-			ClientCAs:  rootCAs,
-			MaxVersion: tls.VersionTLS13,
-			MinVersion: tls.VersionTLS13,
-			// Note: Explicitly setting CurvePreferences is disabled by default to ensure future compatibility with X25519MLKEM768 or SecP256r1MLKEM768.
-		},
-		PoolTimeout:           config.PoolTimeout,           // PoolTimeout should already be a time.Duration
-		PoolSize:              config.PoolSize,              // adding back this for default.
-		ContextTimeoutEnabled: config.ContextTimeoutEnabled, // adding back this for default.
-		ConnMaxIdleTime:       config.ConnMaxIdleTime,       // Required ENV = REDIS_MAXCONN_IDLE_TIME
-		ConnMaxLifetime:       config.ConnMaxLifetime,       // Required ENV = REDIS_MAXCONN_LIFE_TIME
-		MinIdleConns:          config.PoolSize / 4,          // Set minimum idle connections to 25% of the pool size
-	})
-	return client, nil
+	// Note: TLSConfig is optional, but it is recommended for better security, so it's advisable to use it.
+	// Also note that for non-Kubernetes environments, it is recommended to use TLS. For certificates, packages from https://pkg.go.dev/golang.org/x/crypto@v0.24.0/acme or Caddy can be used.
+	// Personally, I don't use this because I am running on Kubernetes with another secure connection method (e.g., bound pods/node ports).
+	// For Mutual TLS or whatever it is, see: https://redis.io/docs/latest/operate/rc/security/database-security/tls-ssl/. However,
+	// the requirement for Mutual TLS or whatever it is depends on how the cloud provider sets it up.
+	// For example, in some cloud providers, Mutual TLS or whatever it is may not be needed, and only the following settings are required.
+	tlsConfig := &tls.Config{
+		// Explicitly set the maximum and minimum TLS versions to 1.3 this server anyways.
+		// However Go's standard TLS 1.3 implementation is broken because it keeps forcing the use of the AES-GCM cipher suite.
+		ClientCAs:  rootCAs,
+		MaxVersion: tls.VersionTLS13,
+		MinVersion: tls.VersionTLS13,
+		// Note: Explicitly setting CurvePreferences is disabled by default to ensure future compatibility with X25519MLKEM768 or SecP256r1MLKEM768.
+	}
+
+	switch config.Mode {
+	case RedisModeSentinel:
+		// Note: The failover client asks the Sentinels for the current master and follows it on failover,
+		// so it's still a plain *redis.Client and everything works the same as standalone.
+		if config.MasterName == "" || len(config.SentinelAddrs) == 0 {
+			return nil, ErrRedisSentinelConfig
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:            config.MasterName,
+			SentinelAddrs:         config.SentinelAddrs,
+			SentinelPassword:      config.SentinelPassword,
+			Password:              config.Password,
+			DB:                    config.Database,
+			TLSConfig:             tlsConfig,
+			PoolTimeout:           config.PoolTimeout,
+			PoolSize:              config.PoolSize,
+			ContextTimeoutEnabled: config.ContextTimeoutEnabled,
+			ConnMaxIdleTime:       config.ConnMaxIdleTime,
+			ConnMaxLifetime:       config.ConnMaxLifetime,
+			MinIdleConns:          config.PoolSize / 4,
+		}), nil
+
+	case RedisModeCluster:
+		// Note: Redis Cluster only has database 0, so RDB_DATABASE is ignored here.
+		// The pool settings are per node (e.g., 3 masters = up to 3 * PoolSize connections).
+		if len(config.ClusterAddrs) == 0 {
+			return nil, ErrRedisClusterConfig
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:                 config.ClusterAddrs,
+			Password:              config.Password,
+			TLSConfig:             tlsConfig,
+			PoolTimeout:           config.PoolTimeout,
+			PoolSize:              config.PoolSize,
+			ContextTimeoutEnabled: config.ContextTimeoutEnabled,
+			ConnMaxIdleTime:       config.ConnMaxIdleTime,
+			ConnMaxLifetime:       config.ConnMaxLifetime,
+			MinIdleConns:          config.PoolSize / 4,
+		}), nil
+
+	case "", RedisModeStandalone:
+		client := redis.NewClient(&redis.Options{
+			Addr:                  fmt.Sprintf("%s:%d", config.Address, config.Port),
+			Password:              config.Password,
+			DB:                    config.Database,
+			TLSConfig:             tlsConfig,
+			PoolTimeout:           config.PoolTimeout,           // PoolTimeout should already be a time.Duration
+			PoolSize:              config.PoolSize,              // adding back this for default.
+			ContextTimeoutEnabled: config.ContextTimeoutEnabled, // adding back this for default.
+			ConnMaxIdleTime:       config.ConnMaxIdleTime,       // Required ENV = REDIS_MAXCONN_IDLE_TIME
+			ConnMaxLifetime:       config.ConnMaxLifetime,       // Required ENV = REDIS_MAXCONN_LIFE_TIME
+			MinIdleConns:          config.PoolSize / 4,          // Set minimum idle connections to 25% of the pool size
+		})
+		return client, nil
+
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrRedisUnsupportedMode, config.Mode)
+	}
 }
 
 // InitializeMySQLDB initializes and returns a new MySQL database client.
@@ -158,6 +231,29 @@ func (config *RedisClientConfig) InitializeRedisClient() (*redis.Client, error)
 // because having too many connections for MySQL can lead to bottlenecks (MySQL bottlenecks). For now, the current setup
 // is sufficient, as Redis will handle most of the connection pooling.
 func (config *MySQLConfig) InitializeMySQLDB() (*sql.DB, error) {
+	db, err := config.openMySQLDB()
+	if err != nil {
+		return nil, err
+	}
+
+	// Set a timeout for the Ping operation.
+	//
+	// TODO: Use an environment variable to customize the timeout (e.g., "10*time.Second").
+	// For now, explicitly setting it to 10 seconds should be sufficient, as it is only used during initialization to avoid runtime confusion.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openMySQLDB opens the MySQL connection pool without pinging it.
+//
+// Note: This is split out from InitializeMySQLDB so that read replicas can be opened even when they are
+// unreachable at boot. A replica that is down will simply stay ejected until the health checker sees it again.
+func (config *MySQLConfig) openMySQLDB() (*sql.DB, error) {
 	rootCAs, err := loadMySQLRootCA()
 	if err != nil {
 		return nil, err
@@ -195,6 +291,13 @@ func (config *MySQLConfig) InitializeMySQLDB() (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	configureMySQLPool(db)
+
+	return db, nil
+}
+
+// configureMySQLPool sets the connection pool parameters of a MySQL database (the primary or a replica).
+func configureMySQLPool(db *sql.DB) {
 	// Set MySQL connection pool parameters.
 	// Note: Implementing statistics similar to those in Redis isn't feasible due to connection limitations.
 	// Even attempting to set it to unlimited will inevitably lead to a bottleneck, regardless of server specs (e.g., even on a high-spec or baremetal server).
@@ -211,18 +314,6 @@ func (config *MySQLConfig) InitializeMySQLDB() (*sql.DB, error) {
 	// as long as the disk/storage for MySQL is not HDD. Additionally, the load balancer must be standalone, meaning it should be dedicated only to MySQL Deployment Unlike Ingress (e.g., Nginx).
 	db.SetMaxOpenConns(100) // Maximum number of open connections
 	db.SetMaxIdleConns(50)  // Maximum number of idle connections
-
-	// Set a timeout for the Ping operation.
-	//
-	// TODO: Use an environment variable to customize the timeout (e.g., "10*time.Second").
-	// For now, explicitly setting it to 10 seconds should be sufficient, as it is only used during initialization to avoid runtime confusion.
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	if err = db.PingContext(ctx); err != nil {
-		return nil, err
-	}
-	return db, nil
 }
 
 // InitializeRedisStorage initializes and returns a new Redis storage instance
@@ -263,15 +354,25 @@ func (config *FiberRedisClientConfig) InitializeRedisStorage() (fiber.Storage, e
 // It handles parsing errors and returns an error if any of the configurations are invalid.
 func parseRedisConfig() (*RedisClientConfig, error) {
 	// Parse the Redis database index from the environment variable.
-	redisDB, err := strconv.Atoi(redisDatabase)
-	if err != nil {
-		return nil, fmt.Errorf("invalid Redis database index: %v", err)
+	//
+	// Note: Redis Cluster only has database 0, so it's not required in Cluster mode.
+	var redisDB int
+	if redisMode != RedisModeCluster || redisDatabase != "" {
+		var err error
+		if redisDB, err = strconv.Atoi(redisDatabase); err != nil {
+			return nil, fmt.Errorf("invalid Redis database index: %v", err)
+		}
 	}
 
 	// Parse Redis port from the environment variable
-	redisPortInt, err := strconv.Atoi(redisPort)
-	if err != nil {
-		return nil, fmt.Errorf("invalid Redis port: %v", err)
+	//
+	// Note: Sentinel and Cluster modes use their own address lists, so the port is only required in standalone mode.
+	var redisPortInt int
+	if redisMode == RedisModeStandalone || redisPort != "" {
+		var err error
+		if redisPortInt, err = strconv.Atoi(redisPort); err != nil {
+			return nil, fmt.Errorf("invalid Redis port: %v", err)
+		}
 	}
 
 	// Parse pool timeout from the environment variable
@@ -303,66 +404,68 @@ func parseRedisConfig() (*RedisClientConfig, error) {
 		ContextTimeoutEnabled: true,
 		ConnMaxIdleTime:       redisConnMaxIdleTime,
 		ConnMaxLifetime:       redisConnMaxLifetime,
+		Mode:                  redisMode,
+		MasterName:            redisSentinelMaster,
+		SentinelAddrs:         splitRedisAddrs(redisSentinelAddrs),
+		SentinelPassword:      redisSentinelPass,
+		ClusterAddrs:          splitRedisAddrs(redisClusterAddrs),
 	}, nil
 }
 
 // initializeRedisClient initializes the Redis client using the provided Redis configuration.
-// It parses the configuration from environment variables and returns a new Redis client instance.
-func initializeRedisClient() (*redis.Client, error) {
+// It parses the configuration from environment variables and returns a new Redis client instance,
+// along with the parsed configuration so the connection can be restarted later.
+func initializeRedisClient() (redis.UniversalClient, *RedisClientConfig, error) {
 	// Parse the Redis configuration from environment variables
 	redisConfig, err := parseRedisConfig()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Initialize and return the Redis client using the provided configuration
-	return redisConfig.InitializeRedisClient()
+	client, err := redisConfig.InitializeRedisClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, redisConfig, nil
 }
 
 // initializeRedisStorage initializes the Redis storage for Fiber using the provided Redis configuration.
 // It parses the configuration from environment variables and returns a new Redis storage instance.
-func initializeRedisStorage() (fiber.Storage, error) {
-	// Parse the Redis database index from the environment variable.
-	redisDB, err := strconv.Atoi(redisDatabase)
+//
+// Note: The storage gets its own [redis.UniversalClient] built from the same configuration, so Fiber middlewares
+// (e.g., rate limiting, sessions) follow failovers and hash slots as well in Sentinel and Cluster modes.
+//
+// Also note that [FiberRedisClientConfig.InitializeRedisStorage] is not used here, because the Fiber storage pings Redis
+// when it is created and panics when that fails, which would kill the boot on a brief Redis outage
+// instead of letting the connection supervisor take care of it.
+//
+// The pool size stays defaultFiberMaxConnections, as with the Fiber storage config before, whatever the pool size
+// of the main client is. The other pool settings (e.g., RDB_POOL_TIMEOUT, REDIS_MAXCONN_LIFE_TIME) now apply to it as well.
+func initializeRedisStorage(in *instrumentation) (fiber.Storage, error) {
+	redisConfig, err := parseRedisConfig()
 	if err != nil {
-		return nil, fmt.Errorf("invalid Redis database index: %v", err)
+		return nil, err
 	}
-
-	// Parse Redis port from the environment variable
-	redisPortInt, err := strconv.Atoi(redisPort)
+	redisConfig.PoolSize = defaultFiberMaxConnections
+	client, err := redisConfig.InitializeRedisClient()
 	if err != nil {
-		return nil, fmt.Errorf("invalid Redis port: %v", err)
-	}
-
-	// Prepare Fiber Redis storage configuration
-	fiberRedisConfig := &FiberRedisClientConfig{
-		Address:  redisAddress,
-		Port:     redisPortInt,
-		Password: redisPassword,
-		Database: redisDB,
-		PoolSize: defaultFiberMaxConnections,
-		// TODO: When ENV (e.g, GO_APP=local) it will set to true.
-		Reset: false,
+		return nil, err
 	}
-
-	// Initialize and return the Redis storage using the provided configuration
-	return fiberRedisConfig.InitializeRedisStorage()
+	return redisStorage.NewFromConnection(in.instrumentRedis(client)), nil
 }
 
-// initializeMySQLDB initializes the MySQL database using the provided MySQL configuration.
-// It prepares the configuration from environment variables and returns a new database connection.
-func initializeMySQLDB() (*sql.DB, error) {
-	// Prepare MySQL configuration
-	mysqlConfig := &MySQLConfig{
+// newMySQLConfig prepares the MySQL configuration from environment variables.
+//
+// Note: The returned configuration is kept in the service, so RestartMySQLConnection can reinitialize the connection later.
+func newMySQLConfig() *MySQLConfig {
+	return &MySQLConfig{
 		Username: username,
 		Password: password,
 		Host:     host,
 		Port:     port,
 		Database: dbname,
 	}
-
-	// Initialize and return the MySQL database connection using the provided configuration
-	return mysqlConfig.InitializeMySQLDB()
 }
 
 // model represents the Bubble Tea model for the spinners.
@@ -433,3 +536,68 @@ func (m model) View() string {
 	}
 	return fmt.Sprintf("\r\n   %s Initializing database%s   %s Progress%s", styledDotSpinner, styledPointsSpinner, styledMeterSpinner, styledPointsSpinner)
 }
+
+// initializeTieredStorage puts an in-process cache (L1) in front of the Redis storage when STORAGE_L1_TTL is set,
+// otherwise it returns the storage as is. The connection of the Fiber Redis storage is used for the keyspace notifications.
+//
+// Note: The L1 only saves the network round trip of the hot keys (e.g., the same cached page or session on every request).
+// Keep its TTL short, since the pods don't see each other's writes before it expires, unless STORAGE_L1_NOTIFICATIONS is enabled.
+func initializeTieredStorage(storage fiber.Storage, conn redis.UniversalClient) (fiber.Storage, error) {
+	if storageL1TTL == "" {
+		return storage, nil
+	}
+
+	ttl, err := time.ParseDuration(storageL1TTL)
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid storage L1 TTL value: %q", storageL1TTL)
+	}
+	maxEntries, err := strconv.Atoi(storageL1MaxEntries)
+	if err != nil || maxEntries <= 0 {
+		return nil, fmt.Errorf("invalid storage L1 max entries value: %q", storageL1MaxEntries)
+	}
+
+	config := tiered.Config{
+		Backend:    storage,
+		Name:       "redis",
+		TTL:        ttl,
+		MaxEntries: maxEntries,
+	}
+	if storageL1Notifications {
+		config.Notifications = conn
+		config.NotificationsDB, _ = strconv.Atoi(redisDatabase)
+	}
+	return tiered.New(config)
+}
+
+// initializeEncryptedStorage seals the values of the Redis storage when STORAGE_ENCRYPTION_KEYS is set,
+// otherwise it returns the storage as is.
+//
+// Note: The existing plaintext values are still readable, so enabling it doesn't log everyone out.
+// They are sealed the next time they are written, and the others expire on their own. Once they have,
+// STORAGE_ENCRYPTION_ALLOW_PLAINTEXT should be set to "false", so a plaintext value written to Redis is rejected.
+func initializeEncryptedStorage(storage fiber.Storage) (fiber.Storage, error) {
+	if storageEncryptionKeys == "" {
+		return storage, nil
+	}
+
+	keys := make(map[string][]byte)
+	for i, entry := range strings.Split(storageEncryptionKeys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			// Note: The entry itself is not in the error, since it may be a key.
+			return nil, fmt.Errorf("invalid storage encryption key entry #%d, want \"id:base64key\"", i+1)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage encryption key %q: %v", id, err)
+		}
+		keys[id] = key
+	}
+
+	return hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:        storage,
+		Keys:           keys,
+		CurrentKeyID:   storageEncryptionKeyID,
+		AllowPlaintext: storageAllowPlaintext,
+	})
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite" // pure-Go SQLite driver (no cgo), registered as "sqlite"
+)
+
+// SQLiteConfig defines the configuration for the SQLite database.
+type SQLiteConfig struct {
+	// Path is the database file, or ":memory:" for an in-memory database.
+	//
+	// Note: Each in-memory database is private to the config that created it and is gone after Close.
+	Path string
+
+	// memoryName is the shared-cache name of the in-memory database, generated on the first initialization.
+	//
+	// Note: Restarting the connection of an in-memory database starts from an empty database,
+	// since the data is dropped together with the last connection.
+	memoryName string
+}
+
+// InitializeSQLiteDB initializes and returns a new SQLite database client.
+//
+// The connection is opened with foreign keys enabled and a busy timeout, and file databases use WAL mode,
+// so that concurrent readers don't block the writer.
+func (config *SQLiteConfig) InitializeSQLiteDB() (*sql.DB, error) {
+	db, err := sql.Open(dbSQLITE, config.dsn())
+	if err != nil {
+		return nil, err
+	}
+
+	if config.isMemory() {
+		// Note: An in-memory database is dropped once its last connection is closed,
+		// so keep the idle connections around forever.
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+		db.SetMaxIdleConns(4)
+	} else {
+		db.SetConnMaxIdleTime(1 * time.Hour)
+		db.SetMaxIdleConns(4)
+	}
+	db.SetMaxOpenConns(16)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// isMemory reports whether the config points to an in-memory database.
+func (config *SQLiteConfig) isMemory() bool {
+	return config.Path == "" || config.Path == ":memory:"
+}
+
+// dsn builds the data source name for the modernc.org/sqlite driver.
+func (config *SQLiteConfig) dsn() string {
+	params := url.Values{}
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "foreign_keys(1)")
+
+	if config.isMemory() {
+		if config.memoryName == "" {
+			config.memoryName = uuid.NewString()
+		}
+		// Note: "cache=shared" lets every connection in the pool see the same in-memory database.
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
+		return fmt.Sprintf("file:%s?%s", config.memoryName, params.Encode())
+	}
+
+	params.Add("_pragma", "journal_mode(WAL)")
+	return fmt.Sprintf("file:%s?%s", config.Path, params.Encode())
+}
+
+// newSQLiteConfig prepares the SQLite configuration from environment variables.
+//
+// Note: The returned configuration is kept in the service, so RestartMySQLConnection can reinitialize the connection later.
+func newSQLiteConfig() *SQLiteConfig {
+	return &SQLiteConfig{Path: sqlitePath}
+}
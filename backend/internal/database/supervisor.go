@@ -0,0 +1,295 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Names of the dependencies watched by the supervisor, also used as the "dependency" metric label.
+const (
+	dependencyMySQL = "mysql"
+	dependencyRedis = "redis"
+)
+
+// SupervisorConfig defines the settings of the connection supervisor.
+type SupervisorConfig struct {
+	// Interval is how often a healthy dependency is pinged.
+	Interval time.Duration
+
+	// MinBackoff is the delay before the first reconnect attempt after a failed ping.
+	// It doubles after every failed attempt, up to MaxBackoff.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between reconnect attempts.
+	MaxBackoff time.Duration
+
+	// RestartAfter is the number of consecutive failed attempts after which the connection pool is restarted
+	// (e.g., RestartMySQLConnection), in case the pool itself is stuck (e.g., stale DNS or TLS state).
+	// Zero disables restarts, so the supervisor only keeps pinging.
+	RestartAfter int
+
+	// PingTimeout bounds every ping.
+	PingTimeout time.Duration
+}
+
+// supervisedDependency is a single dependency along with its last known state.
+//
+// Note: failures and downSince are only touched by the goroutine watching the dependency (or by start before it runs).
+type supervisedDependency struct {
+	name    string
+	ping    func(ctx context.Context) error
+	restart func() error // optional, nil when the pool can't or shouldn't be rebuilt
+	up      atomic.Bool
+	checked atomic.Bool // whether the first check is done, so the boot doesn't log a bogus "recovered"
+	lastErr atomic.Value
+	// failures is the number of consecutive failed pings.
+	failures  int
+	downSince time.Time
+}
+
+// supervisor pings the dependencies on an interval and reconnects them with exponential backoff when they are down.
+//
+// Note: This is what lets the boot continue in degraded mode instead of calling log.Fatal on a brief outage,
+// which would otherwise make Kubernetes restart the pod in a loop. While a dependency is down, [Service.Ready]
+// reports false, so the readiness probe takes the pod out of the load balancer until it recovers.
+type supervisor struct {
+	config SupervisorConfig
+	deps   []*supervisedDependency
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// newSupervisor creates a supervisor for the given dependencies. Every dependency starts as down until its first check.
+func newSupervisor(config SupervisorConfig, deps ...*supervisedDependency) *supervisor {
+	for _, d := range deps {
+		d.lastErr.Store("not checked yet")
+		dependencyUp.WithLabelValues(d.name).Set(0)
+	}
+	return &supervisor{
+		config: config,
+		deps:   deps,
+		stop:   make(chan struct{}),
+	}
+}
+
+// start runs the first check of every dependency synchronously, then keeps watching each of them in the background
+// until close is called.
+func (sv *supervisor) start() {
+	var wg sync.WaitGroup
+	for _, d := range sv.deps {
+		wg.Add(1)
+		go func(d *supervisedDependency) {
+			defer wg.Done()
+			sv.check(d)
+		}(d)
+	}
+	wg.Wait()
+
+	for _, d := range sv.deps {
+		sv.wg.Add(1)
+		go sv.watch(d)
+	}
+}
+
+// watch checks the dependency after every delay until the supervisor is closed.
+func (sv *supervisor) watch(d *supervisedDependency) {
+	defer sv.wg.Done()
+
+	timer := time.NewTimer(sv.nextDelay(d))
+	defer timer.Stop()
+	for {
+		select {
+		case <-sv.stop:
+			return
+		case <-timer.C:
+			sv.check(d)
+			timer.Reset(sv.nextDelay(d))
+		}
+	}
+}
+
+// nextDelay returns the interval for a healthy dependency, otherwise an exponential backoff with jitter.
+//
+// Note: The jitter keeps every pod from reconnecting at the same instant once the dependency comes back.
+func (sv *supervisor) nextDelay(d *supervisedDependency) time.Duration {
+	if d.up.Load() {
+		return sv.config.Interval
+	}
+
+	backoff := sv.config.MinBackoff
+	for i := 1; i < d.failures && backoff < sv.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, sv.config.MaxBackoff)
+
+	// Equal jitter: somewhere between half and the full backoff.
+	half := backoff / 2
+	return half + rand.N(half+1)
+}
+
+// check pings the dependency once, updates its state, and restarts its pool after too many consecutive failures.
+func (sv *supervisor) check(d *supervisedDependency) {
+	degraded := d.checked.Load() && !d.up.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), sv.config.PingTimeout)
+	err := d.ping(ctx)
+	cancel()
+
+	if degraded {
+		dependencyReconnects.WithLabelValues(d.name, result(err)).Inc()
+	}
+
+	if err != nil {
+		sv.markDown(d, err)
+		if d.restart != nil && sv.config.RestartAfter > 0 && d.failures%sv.config.RestartAfter == 0 {
+			sv.restart(d)
+		}
+		return
+	}
+
+	sv.markUp(d)
+}
+
+// markDown records a failed ping and logs when the dependency becomes degraded.
+func (sv *supervisor) markDown(d *supervisedDependency, err error) {
+	d.failures++
+	d.lastErr.Store(err.Error())
+	dependencyUp.WithLabelValues(d.name).Set(0)
+
+	// Note: Swap also covers the first check at boot, where the dependency starts as down.
+	if d.up.Swap(false) || !d.checked.Swap(true) {
+		d.downSince = time.Now()
+		dependencyTransitions.WithLabelValues(d.name, "degraded").Inc()
+		log.LogErrorf("Dependency %s is degraded, reconnecting with backoff (max %s): %v", d.name, sv.config.MaxBackoff, err)
+	}
+}
+
+// markUp records a successful ping and logs when the dependency recovers.
+func (sv *supervisor) markUp(d *supervisedDependency) {
+	failures := d.failures
+	d.failures = 0
+	d.lastErr.Store("")
+	dependencyUp.WithLabelValues(d.name).Set(1)
+
+	wasChecked := d.checked.Swap(true)
+	if !d.up.Swap(true) && wasChecked {
+		dependencyTransitions.WithLabelValues(d.name, "recovered").Inc()
+		log.LogInfof("Dependency %s recovered after %s (%d failed attempts).", d.name, time.Since(d.downSince).Round(time.Millisecond), failures)
+	}
+}
+
+// restart rebuilds the connection pool of the dependency. The next check tells whether it helped.
+func (sv *supervisor) restart(d *supervisedDependency) {
+	if err := d.restart(); err != nil {
+		dependencyRestarts.WithLabelValues(d.name, "failure").Inc()
+		log.LogErrorf("Failed to restart the %s connection after %d failed attempts: %v", d.name, d.failures, err)
+		return
+	}
+	dependencyRestarts.WithLabelValues(d.name, "success").Inc()
+}
+
+// ready reports whether every dependency answered its last ping.
+func (sv *supervisor) ready() bool {
+	for _, d := range sv.deps {
+		if !d.up.Load() {
+			return false
+		}
+	}
+	return true
+}
+
+// stats adds the supervisor state of every dependency to the stats map.
+func (sv *supervisor) stats(stats map[string]string) map[string]string {
+	for _, d := range sv.deps {
+		prefix := d.name + "_supervisor"
+		if d.up.Load() {
+			stats[prefix+"_state"] = "up"
+		} else {
+			stats[prefix+"_state"] = "degraded"
+			if msg, _ := d.lastErr.Load().(string); msg != "" {
+				stats[prefix+"_error"] = msg
+			}
+		}
+	}
+	stats["supervisor_ready"] = strconv.FormatBool(sv.ready())
+	return stats
+}
+
+// close stops watching the dependencies. It is safe to call more than once.
+func (sv *supervisor) close() {
+	sv.once.Do(func() {
+		close(sv.stop)
+		sv.wg.Wait()
+	})
+}
+
+// result returns the "result" metric label for an error.
+func result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// parseSupervisorConfig parses the supervisor configuration from environment variables.
+func parseSupervisorConfig() (SupervisorConfig, error) {
+	interval, err := time.ParseDuration(supervisorInterval)
+	if err != nil || interval <= 0 {
+		return SupervisorConfig{}, fmt.Errorf("invalid supervisor interval value: %q", supervisorInterval)
+	}
+
+	maxBackoff, err := time.ParseDuration(supervisorMaxBackoff)
+	if err != nil || maxBackoff <= 0 {
+		return SupervisorConfig{}, fmt.Errorf("invalid supervisor max backoff value: %q", supervisorMaxBackoff)
+	}
+
+	restartAfter, err := strconv.Atoi(supervisorRestartAfter)
+	if err != nil || restartAfter < 0 {
+		return SupervisorConfig{}, fmt.Errorf("invalid supervisor restart after value: %q", supervisorRestartAfter)
+	}
+
+	return SupervisorConfig{
+		Interval:     interval,
+		MinBackoff:   min(500*time.Millisecond, maxBackoff),
+		MaxBackoff:   maxBackoff,
+		RestartAfter: restartAfter,
+		PingTimeout:  DefaultPingCtxTimeout,
+	}, nil
+}
+
+// startSupervisor starts watching the SQL database and Redis of the service.
+//
+// Note: Only MySQL and the (non-embedded) Redis client get restarted. SQLite and the in-process Redis stand-in are
+// local, so there is no stale connection to get rid of, and restarting an in-memory SQLite would only risk the data.
+func (s *service) startSupervisor(config SupervisorConfig) {
+	mysqlDep := &supervisedDependency{
+		name: dependencyMySQL,
+		ping: func(ctx context.Context) error { return s.sqlDB().PingContext(ctx) },
+	}
+	if s.initMysql != nil {
+		mysqlDep.restart = s.RestartMySQLConnection
+	}
+
+	redisDep := &supervisedDependency{
+		name: dependencyRedis,
+		ping: func(ctx context.Context) error { return s.redisConn().Ping(ctx).Err() },
+	}
+	if s.embeddedRedis == nil {
+		redisDep.restart = s.RestartRedisConnection
+	}
+
+	s.supervisor = newSupervisor(config, mysqlDep, redisDep)
+	s.supervisor.start()
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var errDown = errors.New("connection refused")
+
+func TestSupervisorDegradedBootAndRecovery(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+
+	var (
+		down     atomic.Bool
+		restarts atomic.Int32
+	)
+	down.Store(true)
+
+	dep := &supervisedDependency{
+		name: "gopher-db",
+		ping: func(context.Context) error {
+			if down.Load() {
+				return errDown
+			}
+			return nil
+		},
+		restart: func() error {
+			restarts.Add(1)
+			return nil
+		},
+	}
+	sv := newSupervisor(SupervisorConfig{
+		Interval:     10 * time.Millisecond,
+		MinBackoff:   time.Millisecond,
+		MaxBackoff:   4 * time.Millisecond,
+		RestartAfter: 3,
+		PingTimeout:  time.Second,
+	}, dep)
+	sv.start()
+	defer sv.close()
+
+	// The first check is synchronous, so the boot already knows it's degraded.
+	if sv.ready() {
+		t.Fatal("ready() = true at boot with the dependency down, want false")
+	}
+	if got := testutil.ToFloat64(dependencyUp.WithLabelValues("gopher-db")); got != 0 {
+		t.Errorf("dependency_up = %v, want 0", got)
+	}
+
+	waitFor(t, "the pool to be restarted", func() bool { return restarts.Load() >= 2 })
+
+	down.Store(false)
+	waitFor(t, "the dependency to recover", sv.ready)
+
+	if got := testutil.ToFloat64(dependencyUp.WithLabelValues("gopher-db")); got != 1 {
+		t.Errorf("dependency_up = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(dependencyTransitions.WithLabelValues("gopher-db", "degraded")); got != 1 {
+		t.Errorf("state_transitions_total{state=degraded} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(dependencyTransitions.WithLabelValues("gopher-db", "recovered")); got != 1 {
+		t.Errorf("state_transitions_total{state=recovered} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(dependencyReconnects.WithLabelValues("gopher-db", "success")); got != 1 {
+		t.Errorf("reconnect_attempts_total{result=success} = %v, want 1", got)
+	}
+
+	stats := sv.stats(map[string]string{})
+	if stats["gopher-db_supervisor_state"] != "up" || stats["supervisor_ready"] != "true" {
+		t.Errorf("stats() = %v, want up and ready", stats)
+	}
+
+	// Going down again is reported again.
+	down.Store(true)
+	waitFor(t, "the dependency to be degraded", func() bool { return !sv.ready() })
+	if got := testutil.ToFloat64(dependencyTransitions.WithLabelValues("gopher-db", "degraded")); got != 2 {
+		t.Errorf("state_transitions_total{state=degraded} = %v, want 2", got)
+	}
+}
+
+func TestSupervisorBackoff(t *testing.T) {
+	sv := newSupervisor(SupervisorConfig{
+		Interval:   time.Minute,
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	})
+
+	tests := []struct {
+		name     string
+		up       bool
+		failures int
+		min, max time.Duration
+	}{
+		{"healthy", true, 0, time.Minute, time.Minute},
+		{"first failure", false, 1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"third failure", false, 3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped", false, 30, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &supervisedDependency{failures: tt.failures}
+			d.up.Store(tt.up)
+			for range 100 {
+				if got := sv.nextDelay(d); got < tt.min || got > tt.max {
+					t.Fatalf("nextDelay() = %s, want between %s and %s", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+// waitFor polls cond until it's true or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCloseDuringRestart(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	s := db.(*service)
+
+	// The restarts racing with Close swap the pool under connMu, so Close must read it under the same lock (checked with -race).
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 10 {
+			s.RestartMySQLConnection()
+		}
+	}()
+	time.Sleep(time.Millisecond)
+	s.Close()
+	<-done
+	s.sqlDB().Close()
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tiered
+
+import (
+	"container/list"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// entry is a cached value.
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// shard is a bounded LRU guarded by its own mutex, so the shards don't contend with each other.
+//
+// Note: gen is bumped by every write and invalidation, so a Get that fetched from the backend
+// doesn't cache its value when the key was changed in the meantime (see add).
+type shard struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	lru      *list.List // front is the most recently used
+	sketch   *sketch    // nil when admission is disabled
+	gen      uint64
+}
+
+func newShard(capacity int, admission bool) *shard {
+	s := &shard{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		lru:      list.New(),
+	}
+	if admission {
+		s.sketch = newSketch(capacity)
+	}
+	return s
+}
+
+// get returns the value of a live entry. It also counts the access for the admission policy.
+func (s *shard) get(key string, hash uint64, now time.Time) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sketch != nil {
+		s.sketch.increment(hash)
+	}
+
+	elem, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if now.After(e.expires) {
+		s.removeElement(elem)
+		return nil, false
+	}
+	s.lru.MoveToFront(elem)
+	return e.value, true
+}
+
+// generation returns the current generation, to be passed to add after fetching from the backend.
+func (s *shard) generation() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen
+}
+
+// add caches a value fetched from the backend, unless the key was written or invalidated since gen.
+// It reports whether the value was evicted or rejected to make room.
+func (s *shard) add(key string, hash uint64, value []byte, expires time.Time, gen uint64) (evicted, rejected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.gen != gen {
+		return false, false
+	}
+	return s.insert(key, hash, value, expires)
+}
+
+// set caches a value written through to the backend, replacing any previous one.
+func (s *shard) set(key string, hash uint64, value []byte, expires time.Time) (evicted, rejected bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gen++
+	if s.sketch != nil {
+		s.sketch.increment(hash)
+	}
+	if elem, ok := s.items[key]; ok {
+		s.removeElement(elem)
+	}
+	return s.insert(key, hash, value, expires)
+}
+
+// insert adds the entry, making room when the shard is full. Must be called with the lock held.
+//
+// Note: With admission, the least recently used entry is only evicted for a key that is accessed more often (TinyLFU).
+// This keeps a burst of one-off keys (e.g., a crawler) from flushing the hot keys out of the cache.
+func (s *shard) insert(key string, hash uint64, value []byte, expires time.Time) (evicted, rejected bool) {
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.value, e.expires = value, expires
+		s.lru.MoveToFront(elem)
+		return false, false
+	}
+
+	if s.lru.Len() >= s.capacity {
+		victim := s.lru.Back()
+		victimEntry := victim.Value.(*entry)
+		if s.sketch != nil && time.Now().Before(victimEntry.expires) &&
+			s.sketch.estimate(hash) <= s.sketch.estimate(maphash.String(seed, victimEntry.key)) {
+			return false, true
+		}
+		s.removeElement(victim)
+		evicted = true
+	}
+
+	s.items[key] = s.lru.PushFront(&entry{key: key, value: value, expires: expires})
+	return evicted, false
+}
+
+// remove drops the key and bumps the generation. It reports whether the key was cached.
+func (s *shard) remove(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gen++
+	elem, ok := s.items[key]
+	if ok {
+		s.removeElement(elem)
+	}
+	return ok
+}
+
+// clear drops every entry and bumps the generation.
+func (s *shard) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gen++
+	clear(s.items)
+	s.lru.Init()
+}
+
+// len returns the number of entries, including the expired ones that weren't accessed since.
+func (s *shard) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+// removeElement drops an entry. Must be called with the lock held.
+func (s *shard) removeElement(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.items, elem.Value.(*entry).key)
+}
+
+// sketchDepth is the number of rows of the count-min sketch.
+const sketchDepth = 4
+
+// sketch is a count-min sketch estimating how often keys are accessed, with periodic aging (the "TinyLFU" frequency filter).
+type sketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newSketch creates a sketch sized for a cache of the given capacity.
+//
+// Note: Small caches still get a minimum size, otherwise the collisions between a few hundred keys
+// make every key look as popular as the hot ones.
+func newSketch(capacity int) *sketch {
+	width := 256
+	for width < capacity*4 {
+		width <<= 1
+	}
+	s := &sketch{mask: uint64(width - 1), resetAt: max(capacity*10, width/2)}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index returns the counter of the key in a row, deriving a different hash per row.
+func (s *sketch) index(hash uint64, row int) uint64 {
+	h := hash + uint64(row)*0x9e3779b97f4a7c15
+	h ^= h >> 31
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 29
+	return h & s.mask
+}
+
+// increment counts an access, and halves every counter after enough accesses, so old popularity fades away.
+func (s *sketch) increment(hash uint64) {
+	for row := range s.rows {
+		if i := s.index(hash, row); s.rows[row][i] < 15 {
+			s.rows[row][i]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		for row := range s.rows {
+			for i := range s.rows[row] {
+				s.rows[row][i] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+// estimate returns the estimated access count of the key (an upper bound).
+func (s *sketch) estimate(hash uint64) uint8 {
+	estimate := uint8(15)
+	for row := range s.rows {
+		estimate = min(estimate, s.rows[row][s.index(hash, row)])
+	}
+	return estimate
+}
@@ -0,0 +1,447 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package tiered provides a two-tier [fiber.Storage]: a bounded in-process cache (L1) in front of
+// any backing storage (L2), such as Redis or Cloudflare KV, so hot keys don't pay the network round trip on every hit.
+//
+// Example Usage:
+//
+//	storage, err := tiered.New(tiered.Config{
+//	    Backend:       db.FiberStorage(),
+//	    TTL:           5 * time.Second,
+//	    Notifications: redisStorage.Conn(), // optional, invalidates L1 when another pod writes
+//	})
+//	if err != nil {
+//	    log.LogFatal(err)
+//	}
+//	app.Use(cache.New(cache.Config{Storage: storage}))
+//
+// Note: Without notifications, a pod may serve a value that another pod changed or deleted for up to TTL.
+// Keep the TTL short for data where that matters (e.g., sessions), or enable the notifications.
+package tiered
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"hash/maphash"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrBackendMissing is returned by New when no backing storage is configured.
+var ErrBackendMissing = errors.New("tiered: backend storage is not configured")
+
+// notifyKeyspaceFlags are the keyspace notification classes needed for invalidation:
+// keyspace events (K), generic commands like DEL and EXPIRE (g), string commands ($), expired (x) and evicted (e) keys.
+const notifyKeyspaceFlags = "Kg$xe"
+
+// seed is the hash seed of the shards and of the admission sketches.
+var seed = maphash.MakeSeed()
+
+// Metrics of every tiered storage, labeled by [Config.Name].
+var (
+	l1Requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tiered_storage",
+		Name:      "l1_requests_total",
+		Help:      "Number of L1 lookups by result (hit or miss). The hit ratio is hit / (hit + miss).",
+	}, []string{"storage", "result"})
+
+	l1Evictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tiered_storage",
+		Name:      "l1_evictions_total",
+		Help:      "Number of L1 entries evicted to make room for another one.",
+	}, []string{"storage"})
+
+	l1Rejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tiered_storage",
+		Name:      "l1_rejections_total",
+		Help:      "Number of values not cached in L1 because the admission policy preferred the current entries.",
+	}, []string{"storage"})
+
+	l1Invalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tiered_storage",
+		Name:      "l1_invalidations_total",
+		Help:      "Number of L1 entries invalidated by keyspace notifications.",
+	}, []string{"storage"})
+)
+
+// Config defines the config for the tiered storage.
+type Config struct {
+	// Backend is the backing storage (L2). Every write goes through to it.
+	//
+	// Required.
+	Backend fiber.Storage
+
+	// Name labels the metrics of this storage (e.g., "redis", "cfkv").
+	//
+	// Optional. Default: "default".
+	Name string
+
+	// MaxEntries bounds the number of keys cached in L1.
+	//
+	// Optional. Default: 10000.
+	MaxEntries int
+
+	// MaxValueSize is the size above which values are not cached in L1, so a few big values can't take all the memory.
+	//
+	// Optional. Default: 64 KiB.
+	MaxValueSize int
+
+	// TTL is how long a value is cached in L1. The expiration of the value in the backend is used when it's shorter.
+	//
+	// Optional. Default: 5 seconds.
+	TTL time.Duration
+
+	// Shards is the number of independently locked partitions of L1.
+	//
+	// Optional. Default: 16.
+	Shards int
+
+	// DisableAdmission caches every value (plain LRU) instead of only the ones accessed more often than the entry they'd evict (TinyLFU).
+	//
+	// Optional. Default: false.
+	DisableAdmission bool
+
+	// Notifications is the Redis client to subscribe to keyspace notifications with, so the keys changed, deleted,
+	// expired or evicted in Redis (e.g., by another pod) are invalidated in L1 right away. It must point to the same
+	// Redis as the backend.
+	//
+	// Note: Keyspace notifications are only sent by the node that owns the key, so with Redis Cluster
+	// this only covers the node the subscription lands on, and the other keys rely on the TTL.
+	//
+	// Optional. Default: nil (invalidation by TTL only).
+	Notifications redis.UniversalClient
+
+	// NotificationsDB is the Redis database index of the backend.
+	//
+	// Optional. Default: 0.
+	NotificationsDB int
+
+	// ConfigureNotifications enables the needed keyspace notification classes with CONFIG SET.
+	// Leave it disabled when CONFIG isn't allowed (e.g., most managed Redis), and set notify-keyspace-events there instead.
+	//
+	// Optional. Default: false.
+	ConfigureNotifications bool
+}
+
+// ConfigDefault is the default config.
+var ConfigDefault = Config{
+	Name:         "default",
+	MaxEntries:   10000,
+	MaxValueSize: 64 * 1024,
+	TTL:          5 * time.Second,
+	Shards:       16,
+}
+
+// Stats is a snapshot of the counters of a tiered storage.
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Rejections    uint64
+	Invalidations uint64
+	Entries       int
+}
+
+// HitRatio returns the share of L1 lookups that were hits, between 0 and 1.
+func (s Stats) HitRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
+}
+
+// MissRatio returns the share of L1 lookups that went to the backend, between 0 and 1.
+func (s Stats) MissRatio() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Misses) / float64(total)
+	}
+	return 0
+}
+
+// Storage is a two-tier [fiber.Storage]. Create it with [New].
+type Storage struct {
+	cfg     Config
+	backend fiber.Storage
+	shards  []*shard
+
+	hits, misses, evictions, rejections, invalidations atomic.Uint64
+
+	hitCounter, missCounter prometheus.Counter
+
+	pubsub    *redis.PubSub
+	closing   atomic.Bool
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a new tiered storage.
+func New(config Config) (*Storage, error) {
+	cfg := config
+	if cfg.Backend == nil {
+		return nil, ErrBackendMissing
+	}
+	if cfg.Name == "" {
+		cfg.Name = ConfigDefault.Name
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = ConfigDefault.MaxEntries
+	}
+	if cfg.MaxValueSize <= 0 {
+		cfg.MaxValueSize = ConfigDefault.MaxValueSize
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = ConfigDefault.TTL
+	}
+	if cfg.Shards <= 0 {
+		cfg.Shards = ConfigDefault.Shards
+	}
+	cfg.Shards = min(cfg.Shards, cfg.MaxEntries)
+
+	s := &Storage{
+		cfg:         cfg,
+		backend:     cfg.Backend,
+		shards:      make([]*shard, cfg.Shards),
+		hitCounter:  l1Requests.WithLabelValues(cfg.Name, "hit"),
+		missCounter: l1Requests.WithLabelValues(cfg.Name, "miss"),
+	}
+	capacity := (cfg.MaxEntries + cfg.Shards - 1) / cfg.Shards
+	for i := range s.shards {
+		s.shards[i] = newShard(capacity, !cfg.DisableAdmission)
+	}
+
+	if cfg.Notifications != nil {
+		if err := s.subscribe(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// shard returns the shard of the key along with the hash of the key.
+func (s *Storage) shard(key string) (*shard, uint64) {
+	hash := maphash.String(seed, key)
+	return s.shards[hash%uint64(len(s.shards))], hash
+}
+
+// Get returns the value of the key from L1, or from the backend on a miss. A missing key returns nil without an error.
+func (s *Storage) Get(key string) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+
+	sh, hash := s.shard(key)
+	if value, ok := sh.get(key, hash, time.Now()); ok {
+		s.hits.Add(1)
+		s.hitCounter.Inc()
+		return bytes.Clone(value), nil
+	}
+	s.misses.Add(1)
+	s.missCounter.Inc()
+
+	gen := sh.generation()
+	value, err := s.backend.Get(key)
+	if err != nil || value == nil || len(value) > s.cfg.MaxValueSize {
+		return value, err
+	}
+
+	// Note: The backend doesn't tell the remaining TTL, so L1 keeps the value for at most the L1 TTL.
+	s.record(sh.add(key, hash, bytes.Clone(value), time.Now().Add(s.cfg.TTL), gen))
+	return value, nil
+}
+
+// Set writes the value through to the backend, then caches it in L1.
+func (s *Storage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) == 0 || len(val) == 0 {
+		return nil
+	}
+
+	sh, hash := s.shard(key)
+	if err := s.backend.Set(key, val, exp); err != nil {
+		// The write may or may not have reached the backend, so don't trust L1 for this key anymore.
+		sh.remove(key)
+		return err
+	}
+
+	if len(val) > s.cfg.MaxValueSize {
+		sh.remove(key)
+		return nil
+	}
+	ttl := s.cfg.TTL
+	if exp > 0 {
+		ttl = min(ttl, exp)
+	}
+	s.record(sh.set(key, hash, bytes.Clone(val), time.Now().Add(ttl)))
+	return nil
+}
+
+// Delete deletes the key from the backend and from L1.
+func (s *Storage) Delete(key string) error {
+	if len(key) == 0 {
+		return nil
+	}
+	sh, _ := s.shard(key)
+	err := s.backend.Delete(key)
+	sh.remove(key)
+	return err
+}
+
+// Reset resets the backend and clears L1.
+func (s *Storage) Reset() error {
+	err := s.backend.Reset()
+	s.Purge()
+	return err
+}
+
+// Close stops the keyspace notifications and clears L1.
+//
+// Note: The backend is not closed, since it is usually shared (e.g., [database.Service.FiberStorage]) and closed by its owner.
+func (s *Storage) Close() error {
+	s.closeOnce.Do(func() {
+		if s.pubsub != nil {
+			s.closing.Store(true)
+			s.pubsub.Close()
+			<-s.done
+		}
+		s.Purge()
+	})
+	return nil
+}
+
+// Purge clears L1 only.
+func (s *Storage) Purge() {
+	for _, sh := range s.shards {
+		sh.clear()
+	}
+}
+
+// Backend returns the backing storage.
+func (s *Storage) Backend() fiber.Storage {
+	return s.backend
+}
+
+// Conn returns the Redis client of the backend, or nil when the backend isn't Redis.
+//
+// Note: This lets the middlewares that look for a Redis storage (e.g., the distributed lock of the idempotency middleware)
+// find it through L1, which they'd otherwise miss and quietly fall back to a per-process lock.
+func (s *Storage) Conn() redis.UniversalClient {
+	if backend, ok := s.backend.(interface{ Conn() redis.UniversalClient }); ok {
+		return backend.Conn()
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the counters.
+func (s *Storage) Stats() Stats {
+	entries := 0
+	for _, sh := range s.shards {
+		entries += sh.len()
+	}
+	return Stats{
+		Hits:          s.hits.Load(),
+		Misses:        s.misses.Load(),
+		Evictions:     s.evictions.Load(),
+		Rejections:    s.rejections.Load(),
+		Invalidations: s.invalidations.Load(),
+		Entries:       entries,
+	}
+}
+
+// record counts an eviction or a rejection of the admission policy.
+func (s *Storage) record(evicted, rejected bool) {
+	if evicted {
+		s.evictions.Add(1)
+		l1Evictions.WithLabelValues(s.cfg.Name).Inc()
+	}
+	if rejected {
+		s.rejections.Add(1)
+		l1Rejections.WithLabelValues(s.cfg.Name).Inc()
+	}
+}
+
+// subscribe starts invalidating L1 on keyspace notifications.
+func (s *Storage) subscribe() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := s.cfg.Notifications
+	if s.cfg.ConfigureNotifications {
+		if err := enableKeyspaceNotifications(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	prefix := fmt.Sprintf("__keyspace@%d__:", s.cfg.NotificationsDB)
+	s.pubsub = client.PSubscribe(ctx, prefix+"*")
+	s.done = make(chan struct{})
+	go s.invalidate(prefix)
+	return nil
+}
+
+// invalidate drops the keys named by the keyspace notifications from L1 until the subscription is closed.
+func (s *Storage) invalidate(prefix string) {
+	defer close(s.done)
+
+	ctx := context.Background()
+	for {
+		msg, err := s.pubsub.Receive(ctx)
+		if err != nil {
+			if s.closing.Load() {
+				return
+			}
+			// Note: go-redis reconnects and subscribes again on the next Receive.
+			log.LogErrorf("Tiered storage %s lost its keyspace notifications: %v", s.cfg.Name, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Notifications may have been missed while the subscription was down (also true for the first one,
+			// since nothing is cached yet it's cheap), so nothing in L1 can be trusted anymore.
+			if m.Kind == "psubscribe" {
+				s.Purge()
+			}
+		case *redis.Message:
+			key := strings.TrimPrefix(m.Channel, prefix)
+			sh, _ := s.shard(key)
+			if sh.remove(key) {
+				s.invalidations.Add(1)
+				l1Invalidations.WithLabelValues(s.cfg.Name).Inc()
+			}
+		}
+	}
+}
+
+// enableKeyspaceNotifications adds the keyspace notification classes needed for invalidation
+// to the current notify-keyspace-events, keeping the ones already enabled.
+func enableKeyspaceNotifications(ctx context.Context, client redis.UniversalClient) error {
+	current, err := client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		return fmt.Errorf("tiered: failed to read notify-keyspace-events: %w", err)
+	}
+
+	flags := current["notify-keyspace-events"]
+	for _, flag := range notifyKeyspaceFlags {
+		// Note: "A" is an alias for "g$lshzxetd", which already covers every class needed except "K".
+		if !strings.ContainsRune(flags, flag) && (flag == 'K' || !strings.ContainsRune(flags, 'A')) {
+			flags += string(flag)
+		}
+	}
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", flags).Err(); err != nil {
+		return fmt.Errorf("tiered: failed to enable keyspace notifications: %w", err)
+	}
+	return nil
+}
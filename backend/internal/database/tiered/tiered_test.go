@@ -0,0 +1,247 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tiered_test
+
+import (
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database/tiered"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	redisStorage "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+// countingStorage is a map-backed fiber.Storage that counts the calls to Get.
+type countingStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	gets int
+}
+
+func newCountingStorage() *countingStorage {
+	return &countingStorage{data: make(map[string][]byte)}
+}
+
+func (m *countingStorage) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gets++
+	return m.data[key], nil
+}
+
+func (m *countingStorage) Set(key string, val []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = append([]byte(nil), val...)
+	return nil
+}
+
+func (m *countingStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *countingStorage) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.data)
+	return nil
+}
+
+func (m *countingStorage) Close() error { return nil }
+
+func (m *countingStorage) backendGets() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gets
+}
+
+func TestNewWithoutBackend(t *testing.T) {
+	if _, err := tiered.New(tiered.Config{}); !errors.Is(err, tiered.ErrBackendMissing) {
+		t.Errorf("New() error = %v, want %v", err, tiered.ErrBackendMissing)
+	}
+}
+
+func TestReadAndWriteThrough(t *testing.T) {
+	backend := newCountingStorage()
+	backend.Set("gopher", []byte("blue"), 0)
+	storage, err := tiered.New(tiered.Config{Backend: backend, Name: "test_rw", MaxValueSize: 8})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer storage.Close()
+
+	for range 3 {
+		got, err := storage.Get("gopher")
+		if err != nil || string(got) != "blue" {
+			t.Fatalf("Get() = %q, %v, want blue", got, err)
+		}
+		got[0] = 'X' // callers can't corrupt the cached value
+	}
+	if gets := backend.backendGets(); gets != 1 {
+		t.Errorf("backend Get called %d times, want 1", gets)
+	}
+
+	// Writes go through to the backend and replace the cached value.
+	if err := storage.Set("gopher", []byte("green"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, _ := backend.Get("gopher"); string(got) != "green" {
+		t.Errorf("backend value = %q, want green", got)
+	}
+	if got, _ := storage.Get("gopher"); string(got) != "green" {
+		t.Errorf("Get() after Set = %q, want green", got)
+	}
+
+	// Values above MaxValueSize are only stored in the backend.
+	storage.Set("big", []byte("0123456789"), 0)
+	storage.Get("big")
+	storage.Get("big")
+	if gets := backend.backendGets(); gets != 4 { // 1 + the direct read above + 2 for "big"
+		t.Errorf("backend Get called %d times, want 4", gets)
+	}
+
+	if err := storage.Delete("gopher"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := storage.Get("gopher"); got != nil {
+		t.Errorf("Get() after Delete = %q, want nil", got)
+	}
+
+	stats := storage.Stats()
+	if stats.Hits != 3 || stats.Misses != 4 {
+		t.Errorf("Stats() = %+v, want 3 hits and 4 misses", stats)
+	}
+	if got, want := stats.HitRatio(), 3.0/7; got != want {
+		t.Errorf("HitRatio() = %v, want %v", got, want)
+	}
+	if got := stats.HitRatio() + stats.MissRatio(); got != 1 {
+		t.Errorf("HitRatio() + MissRatio() = %v, want 1", got)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	backend := newCountingStorage()
+	storage, _ := tiered.New(tiered.Config{Backend: backend, Name: "test_ttl", TTL: time.Hour})
+	defer storage.Close()
+
+	// The expiration of the value is used when it's shorter than the L1 TTL.
+	storage.Set("session", []byte("alive"), 20*time.Millisecond)
+	storage.Get("session")
+	if gets := backend.backendGets(); gets != 0 {
+		t.Fatalf("backend Get called %d times before the expiration, want 0", gets)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	storage.Get("session")
+	if gets := backend.backendGets(); gets != 1 {
+		t.Errorf("backend Get called %d times after the expiration, want 1", gets)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	tests := []struct {
+		name          string
+		admission     bool
+		wantHotCached bool
+	}{
+		{"lru", false, false},
+		{"tinylfu", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newCountingStorage()
+			for i := range 100 {
+				backend.Set(fmt.Sprint("key", i), []byte("value"), 0)
+			}
+			storage, _ := tiered.New(tiered.Config{
+				Backend:          backend,
+				Name:             "test_eviction_" + tt.name,
+				MaxEntries:       4,
+				Shards:           1,
+				DisableAdmission: !tt.admission,
+			})
+			defer storage.Close()
+
+			// Four hot keys, read many times.
+			for range 5 {
+				for i := range 4 {
+					storage.Get(fmt.Sprint("key", i))
+				}
+			}
+			// Then a scan of one-off keys.
+			for i := 4; i < 100; i++ {
+				storage.Get(fmt.Sprint("key", i))
+			}
+
+			if entries := storage.Stats().Entries; entries > 4 {
+				t.Errorf("Entries = %d, want at most 4", entries)
+			}
+
+			before := backend.backendGets()
+			for i := range 4 {
+				storage.Get(fmt.Sprint("key", i))
+			}
+			if cached := backend.backendGets() == before; cached != tt.wantHotCached {
+				t.Errorf("hot keys still cached = %v, want %v (stats %+v)", cached, tt.wantHotCached, storage.Stats())
+			}
+		})
+	}
+}
+
+func TestKeyspaceNotifications(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	storage, err := tiered.New(tiered.Config{
+		Backend:       redisStorage.NewFromConnection(client),
+		Name:          "test_notifications",
+		TTL:           time.Hour,
+		Notifications: client,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer storage.Close()
+
+	// Wait for the subscription, since L1 is purged when it's confirmed.
+	for deadline := time.Now().Add(5 * time.Second); mr.PubSubNumPat() == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the keyspace subscription")
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	mr.Set("gopher", "blue")
+	if got, _ := storage.Get("gopher"); string(got) != "blue" {
+		t.Fatalf("Get() = %q, want blue", got)
+	}
+
+	// Another pod changes the key. miniredis doesn't send keyspace notifications, so send the one Redis would.
+	mr.Set("gopher", "green")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mr.Publish("__keyspace@0__:gopher", "set")
+		if got, _ := storage.Get("gopher"); string(got) == "green" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the L1 entry to be invalidated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if storage.Stats().Invalidations == 0 {
+		t.Error("Invalidations = 0, want the notification counted")
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUserExists is returned when registering a username or email that is already taken.
+	ErrUserExists = errors.New("database: username or email already taken")
+
+	// ErrUserNotFound is returned when there is no user with the given ID.
+	ErrUserNotFound = errors.New("database: user not found")
+
+	// ErrInvalidCredentials is returned when the login or the password is wrong.
+	// It's the same error for both, so a failed login doesn't tell which accounts exist.
+	ErrInvalidCredentials = errors.New("database: invalid credentials")
+
+	// ErrInvalidUsername is returned for a username that isn't 3 to 32 letters, digits, dots, dashes or underscores.
+	ErrInvalidUsername = errors.New("database: invalid username")
+
+	// ErrInvalidEmail is returned for an email address that can't be parsed.
+	ErrInvalidEmail = errors.New("database: invalid email")
+
+	// ErrInvalidPassword is returned for a password shorter than 8 or longer than 72 bytes.
+	ErrInvalidPassword = errors.New("database: password must be 8 to 72 bytes")
+)
+
+const (
+	// UsersTable is the name of the table storing the user accounts (see [CreateUsersTable]).
+	UsersTable = "users"
+
+	// minPasswordLength is the minimum length of a password, in bytes.
+	minPasswordLength = 8
+
+	// maxPasswordLength is the maximum length of a password, in bytes.
+	// Note: bcrypt only uses the first 72 bytes (and golang.org/x/crypto/bcrypt refuses longer ones),
+	// so longer passwords are rejected instead of being silently truncated.
+	maxPasswordLength = 72
+)
+
+// usernamePattern is the allowed format of usernames.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,32}$`)
+
+// User is a user account. The password hash is never returned.
+type User struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateUsersTable creates the users table if it doesn't exist.
+//
+// Note: Like the API keys table, the times are stored as Unix seconds, so the schema works on MySQL and SQLite.
+func CreateUsersTable(ctx context.Context, db Service) error {
+	query := `CREATE TABLE IF NOT EXISTS users (
+	id CHAR(36) NOT NULL PRIMARY KEY,
+	username VARCHAR(32) NOT NULL UNIQUE,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password_hash VARCHAR(60) NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`
+	if db.Dialect().Name() == DriverMySQL {
+		query += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	return db.ExecWithoutRow(ctx, query)
+}
+
+// validatePassword checks the length of a password.
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// normalizeEmail validates an email address and returns it in lower case, so the unique constraint is case-insensitive.
+func normalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" || len(addr.Address) > 255 {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+// CreateUser registers a new user with a bcrypt hash of the password.
+func (s *serviceAuth) CreateUser(ctx context.Context, username, email, password string) (User, error) {
+	if !usernamePattern.MatchString(username) {
+		return User{}, ErrInvalidUsername
+	}
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+	if err := validatePassword(password); err != nil {
+		return User{}, err
+	}
+
+	hash, err := s.bcrypt.HashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+
+	return s.insertUser(ctx, username, email, hash)
+}
+
+// insertUser inserts a new user with the given password hash, which is empty for the users
+// that only sign in with an external identity (see SignInWithIdentity).
+func (s *serviceAuth) insertUser(ctx context.Context, username, email, hash string) (User, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	user := User{
+		ID:        uuid.NewString(),
+		Username:  username,
+		Email:     email,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	const query = "INSERT INTO users (id, username, email, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)"
+	if _, err := s.db.ExecContext(ctx, query, user.ID, user.Username, user.Email, hash, now.Unix(), now.Unix()); err != nil {
+		if isDuplicateEntryError(err) {
+			return User{}, ErrUserExists
+		}
+		return User{}, fmt.Errorf("database: failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// AuthenticateUser returns the user whose username or email is login, if the password matches.
+//
+// Note: When there is no such user, a password is still compared against a dummy hash,
+// so the response time doesn't tell whether the account exists.
+func (s *serviceAuth) AuthenticateUser(ctx context.Context, login, password string) (User, error) {
+	column, login := loginColumn(login)
+	user, hash, err := s.queryUser(ctx, column, login)
+	if errors.Is(err, ErrUserNotFound) {
+		s.bcrypt.ComparePassword(password, s.dummyPasswordHash())
+		return User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	// Note: The users without a password (see insertUser) can't sign in with one.
+	if hash == "" || len(password) > maxPasswordLength || !s.bcrypt.ComparePassword(password, hash) {
+		return User{}, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// GetUser returns the user with the given ID.
+func (s *serviceAuth) GetUser(ctx context.Context, id string) (User, error) {
+	user, _, err := s.queryUser(ctx, "id", id)
+	return user, err
+}
+
+// FindUser returns the user whose username or email is login, for the login methods without a password (e.g., WebAuthn).
+func (s *serviceAuth) FindUser(ctx context.Context, login string) (User, error) {
+	column, login := loginColumn(login)
+	user, _, err := s.queryUser(ctx, column, login)
+	return user, err
+}
+
+// loginColumn returns the column of the users table matching the login (either "username" or "email"), and the normalized login.
+func loginColumn(login string) (string, string) {
+	if strings.Contains(login, "@") {
+		return "email", strings.ToLower(strings.TrimSpace(login))
+	}
+	return "username", login
+}
+
+// ChangePassword replaces the password of the user, after checking the current one.
+func (s *serviceAuth) ChangePassword(ctx context.Context, id, currentPassword, newPassword string) error {
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	_, hash, err := s.queryUser(ctx, "id", id)
+	if err != nil {
+		return err
+	}
+	if hash == "" || !s.bcrypt.ComparePassword(currentPassword, hash) {
+		return ErrInvalidCredentials
+	}
+
+	newHash, err := s.bcrypt.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	const query = "UPDATE users SET password_hash = ?, updated_at = ? WHERE id = ?"
+	if _, err := s.db.ExecContext(ctx, query, newHash, time.Now().UTC().Unix(), id); err != nil {
+		return fmt.Errorf("database: failed to change password: %w", err)
+	}
+	return nil
+}
+
+// queryUser returns the user whose column (either "id", "username" or "email") matches the value, with its password hash.
+func (s *serviceAuth) queryUser(ctx context.Context, column, value string) (User, string, error) {
+	var (
+		user                 User
+		hash                 string
+		createdAt, updatedAt int64
+	)
+	query := "SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE " + column + " = ?"
+	err := s.db.QueryRowContext(ctx, query, value).Scan(&user.ID, &user.Username, &user.Email, &hash, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, "", ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, "", err
+	}
+	user.CreatedAt = time.Unix(createdAt, 0).UTC()
+	user.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return user, hash, nil
+}
+
+// dummyHash is a bcrypt hash compared against when there is no such user, see AuthenticateUser.
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash returns dummyHash, computing it with the same cost as the real hashes the first time.
+func (s *serviceAuth) dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = s.bcrypt.HashPassword("not a real password, only burning time")
+	})
+	return dummyHash
+}
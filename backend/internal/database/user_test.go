@@ -0,0 +1,106 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package database_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"strings"
+	"testing"
+)
+
+func newUserService(t *testing.T) database.Service {
+	t.Helper()
+	db := newInProcessService(t, ":memory:")
+	if err := database.CreateUsersTable(context.Background(), db); err != nil {
+		t.Fatalf("CreateUsersTable() error = %v", err)
+	}
+	return db
+}
+
+func TestUserLifecycle(t *testing.T) {
+	db := newUserService(t)
+	auth := db.Auth()
+	ctx := context.Background()
+
+	user, err := auth.CreateUser(ctx, "gopher", "Gopher@Example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.ID == "" || user.Email != "gopher@example.com" {
+		t.Errorf("CreateUser() = %+v, want an ID and a lower case email", user)
+	}
+
+	// The username and the email (whatever its case) are unique.
+	for _, dup := range [][2]string{{"gopher", "other@example.com"}, {"other", "GOPHER@example.com"}} {
+		if _, err := auth.CreateUser(ctx, dup[0], dup[1], "correct horse"); !errors.Is(err, database.ErrUserExists) {
+			t.Errorf("CreateUser(%q, %q) error = %v, want %v", dup[0], dup[1], err, database.ErrUserExists)
+		}
+	}
+
+	for _, login := range []string{"gopher", "GOPHER@example.com"} {
+		got, err := auth.AuthenticateUser(ctx, login, "correct horse")
+		if err != nil || got.ID != user.ID {
+			t.Errorf("AuthenticateUser(%q) = %+v, %v, want %s", login, got, err, user.ID)
+		}
+	}
+	for _, login := range []string{"gopher", "nobody"} {
+		if _, err := auth.AuthenticateUser(ctx, login, "wrong horse"); !errors.Is(err, database.ErrInvalidCredentials) {
+			t.Errorf("AuthenticateUser(%q) with a wrong password error = %v, want %v", login, err, database.ErrInvalidCredentials)
+		}
+	}
+
+	if err := auth.ChangePassword(ctx, user.ID, "wrong horse", "battery staple"); !errors.Is(err, database.ErrInvalidCredentials) {
+		t.Errorf("ChangePassword() with a wrong current password error = %v, want %v", err, database.ErrInvalidCredentials)
+	}
+	if err := auth.ChangePassword(ctx, user.ID, "correct horse", "battery staple"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := auth.AuthenticateUser(ctx, "gopher", "correct horse"); !errors.Is(err, database.ErrInvalidCredentials) {
+		t.Errorf("AuthenticateUser() with the old password error = %v, want %v", err, database.ErrInvalidCredentials)
+	}
+	if _, err := auth.AuthenticateUser(ctx, "gopher", "battery staple"); err != nil {
+		t.Errorf("AuthenticateUser() with the new password error = %v", err)
+	}
+
+	if got, err := auth.GetUser(ctx, user.ID); err != nil || got.Username != "gopher" {
+		t.Errorf("GetUser() = %+v, %v", got, err)
+	}
+	if _, err := auth.GetUser(ctx, "missing"); !errors.Is(err, database.ErrUserNotFound) {
+		t.Errorf("GetUser() of a missing user error = %v, want %v", err, database.ErrUserNotFound)
+	}
+	if got, err := auth.FindUser(ctx, " Gopher@Example.com"); err != nil || got.ID != user.ID {
+		t.Errorf("FindUser() by email = %+v, %v, want %s", got, err, user.ID)
+	}
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	db := newUserService(t)
+
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{"short username", "go", "gopher@example.com", "correct horse", database.ErrInvalidUsername},
+		{"username with spaces", "go pher", "gopher@example.com", "correct horse", database.ErrInvalidUsername},
+		{"invalid email", "gopher", "not an email", "correct horse", database.ErrInvalidEmail},
+		{"email with a name", "gopher", "Gopher <gopher@example.com>", "correct horse", database.ErrInvalidEmail},
+		{"short password", "gopher", "gopher@example.com", "short", database.ErrInvalidPassword},
+		{"long password", "gopher", "gopher@example.com", strings.Repeat("x", 73), database.ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := db.Auth().CreateUser(context.Background(), tt.username, tt.email, tt.password); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateUser() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package eventbus broadcasts events to every pod (replica) through Redis, for example to tell all of them
+// to invalidate a cache prefix or to reload the translations, since with HPA every pod keeps its own in-memory state.
+//
+// Every event is appended to a Redis Stream (one per topic) and announced with Redis Pub/Sub. A pod that gets the
+// announcement reads the stream from the last event it handled, so events are delivered in order and at least once,
+// even when an announcement is lost (e.g., during a reconnect or a failover), since the next announcement,
+// the resubscription, or the periodic catch-up reads the missing ones.
+//
+// Example Usage:
+//
+//	bus := eventbus.New(eventbus.Config{Redis: db})
+//	eventbus.Subscribe(bus, eventbus.TopicInvalidateCache, eventbus.InvalidateCacheHandler(bus, db))
+//	bus.Start()
+//	defer bus.Close()
+//
+//	// On any pod:
+//	eventbus.PublishInvalidateCache(ctx, bus, "cache:user:")
+//
+// Note: This requires Redis 6.2+ (or Valkey) for exclusive stream ranges.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrRedisMissing is returned when the bus has no Redis configured.
+	ErrRedisMissing = errors.New("eventbus: redis is not configured")
+
+	// ErrDecode is returned to the handler loop when the payload of an event can't be decoded. Such events aren't retried.
+	ErrDecode = errors.New("eventbus: failed to decode payload")
+)
+
+// RedisProvider provides the Redis client used by the bus (e.g., [database.Service]).
+//
+// Note: The client is fetched again on every reconnect, so the bus follows a restarted connection
+// (see database.RestartRedisConnection) instead of holding on to a closed client.
+type RedisProvider interface {
+	RedisClient() redis.UniversalClient
+}
+
+// Codec encodes and decodes event payloads.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// sonicCodec is the default [Codec] backed by sonic.
+type sonicCodec struct{}
+
+func (sonicCodec) Marshal(v any) ([]byte, error)      { return sonic.Marshal(v) }
+func (sonicCodec) Unmarshal(data []byte, v any) error { return sonic.Unmarshal(data, v) }
+
+// Config defines the config for the event bus.
+type Config struct {
+	// Redis provides the Redis client.
+	//
+	// Required.
+	Redis RedisProvider
+
+	// Prefix is prepended to the stream keys and channels.
+	//
+	// Optional. Default: "eventbus:".
+	Prefix string
+
+	// Source identifies this pod in the events it publishes.
+	//
+	// Optional. Default: the hostname, which is the pod name on Kubernetes.
+	Source string
+
+	// Codec encodes and decodes the payloads.
+	//
+	// Optional. Default: sonic.
+	Codec Codec
+
+	// MaxLen is the approximate number of events kept in each stream.
+	// A pod that is disconnected for longer than it takes to publish this many events misses the oldest ones.
+	//
+	// Optional. Default: 10000.
+	MaxLen int64
+
+	// MaxAttempts is how many times a handler is called for an event before giving up on it.
+	//
+	// Optional. Default: 3.
+	MaxAttempts int
+
+	// RetryBackoff is the delay before calling a failed handler again. It doubles after every attempt.
+	//
+	// Optional. Default: 100 milliseconds.
+	RetryBackoff time.Duration
+
+	// ReconnectBackoff is the delay before subscribing again after the subscription failed.
+	// It doubles after every failure, up to 30 seconds.
+	//
+	// Optional. Default: 500 milliseconds.
+	ReconnectBackoff time.Duration
+
+	// CatchUpInterval is how often the streams are read even without any announcement, and the subscription is pinged.
+	// This is the upper bound for the delivery delay of an event whose announcement was lost.
+	//
+	// Optional. Default: 30 seconds.
+	CatchUpInterval time.Duration
+}
+
+// ConfigDefault is the default config.
+var ConfigDefault = Config{
+	Prefix:           "eventbus:",
+	Codec:            sonicCodec{},
+	MaxLen:           10000,
+	MaxAttempts:      3,
+	RetryBackoff:     100 * time.Millisecond,
+	ReconnectBackoff: 500 * time.Millisecond,
+	CatchUpInterval:  30 * time.Second,
+}
+
+// maxReconnectBackoff caps the delay between subscription attempts.
+const maxReconnectBackoff = 30 * time.Second
+
+// catchUpBatch is the number of events read from a stream at once.
+const catchUpBatch = 100
+
+// rawEvent is an event as read from the stream, before its payload is decoded.
+type rawEvent struct {
+	id      string
+	topic   string
+	source  string
+	time    time.Time
+	payload []byte
+}
+
+// rawHandler handles a raw event (see [Subscribe], which decodes the payload).
+type rawHandler func(ctx context.Context, event rawEvent) error
+
+// Bus is the event bus of a pod. Create it with [New].
+type Bus struct {
+	cfg Config
+
+	mu       sync.Mutex
+	handlers map[string][]rawHandler // topic -> handlers
+	lastIDs  map[string]string       // topic -> ID of the last handled event, "" until the subscription is confirmed
+	pubsub   *redis.PubSub
+	started  bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a new event bus. Register the handlers with [Subscribe], then call Start.
+func New(config ...Config) *Bus {
+	cfg := ConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Prefix == "" {
+			cfg.Prefix = ConfigDefault.Prefix
+		}
+		if cfg.Codec == nil {
+			cfg.Codec = ConfigDefault.Codec
+		}
+		if cfg.MaxLen <= 0 {
+			cfg.MaxLen = ConfigDefault.MaxLen
+		}
+		if cfg.MaxAttempts <= 0 {
+			cfg.MaxAttempts = ConfigDefault.MaxAttempts
+		}
+		if cfg.RetryBackoff <= 0 {
+			cfg.RetryBackoff = ConfigDefault.RetryBackoff
+		}
+		if cfg.ReconnectBackoff <= 0 {
+			cfg.ReconnectBackoff = ConfigDefault.ReconnectBackoff
+		}
+		if cfg.CatchUpInterval <= 0 {
+			cfg.CatchUpInterval = ConfigDefault.CatchUpInterval
+		}
+	}
+	if cfg.Source == "" {
+		cfg.Source, _ = os.Hostname()
+	}
+
+	return &Bus{
+		cfg:      cfg,
+		handlers: make(map[string][]rawHandler),
+		lastIDs:  make(map[string]string),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Source returns the identifier of this pod in the published events.
+func (b *Bus) Source() string {
+	return b.cfg.Source
+}
+
+// Start subscribes to the topics in the background and keeps the subscription alive until Close is called.
+// Topics subscribed after Start are picked up as well.
+func (b *Bus) Start() error {
+	if b.cfg.Redis == nil {
+		return ErrRedisMissing
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.started {
+		return nil
+	}
+	b.started = true
+	go b.run()
+	return nil
+}
+
+// Close stops the subscription and waits for the handler in progress (if any). It is safe to call more than once.
+func (b *Bus) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stop)
+
+		b.mu.Lock()
+		started, ps := b.started, b.pubsub
+		b.mu.Unlock()
+		if ps != nil {
+			ps.Close()
+		}
+		if started {
+			<-b.done
+		}
+	})
+	return nil
+}
+
+// streamKey returns the Redis Stream key of a topic.
+func (b *Bus) streamKey(topic string) string {
+	return b.cfg.Prefix + "stream:" + topic
+}
+
+// channel returns the Pub/Sub channel of a topic.
+func (b *Bus) channel(topic string) string {
+	return b.cfg.Prefix + "channel:" + topic
+}
+
+// topicOf returns the topic of a Pub/Sub channel.
+func (b *Bus) topicOf(channel string) string {
+	return strings.TrimPrefix(channel, b.cfg.Prefix+"channel:")
+}
+
+// publish appends the event to the stream of the topic and announces it.
+func (b *Bus) publish(ctx context.Context, topic string, payload []byte) (string, error) {
+	if b.cfg.Redis == nil {
+		return "", ErrRedisMissing
+	}
+	rdb := b.cfg.Redis.RedisClient()
+
+	id, err := rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.streamKey(topic),
+		MaxLen: b.cfg.MaxLen,
+		Approx: true,
+		Values: map[string]any{
+			"source":  b.cfg.Source,
+			"time":    time.Now().UnixMilli(),
+			"payload": payload,
+		},
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("eventbus: failed to publish %s: %w", topic, err)
+	}
+
+	// When this pod subscribes to the topic but its subscription isn't confirmed yet (its last ID is still ""),
+	// the first catch-up would start after this event, so this pod would never handle its own event
+	// (e.g., the publisher's ScanAndDel of [InvalidateCacheHandler]). Start the catch-up right before it instead.
+	b.mu.Lock()
+	if last, known := b.lastIDs[topic]; known && last == "" {
+		b.lastIDs[topic] = streamIDBefore(id)
+	}
+	b.mu.Unlock()
+
+	// Note: The announcement only carries the ID, since the subscribers read the events from the stream anyway.
+	if err := rdb.Publish(ctx, b.channel(topic), id).Err(); err != nil {
+		// The event is stored, so the subscribers still get it on their next catch-up.
+		return id, fmt.Errorf("eventbus: event %s stored, but the announcement failed: %w", id, err)
+	}
+	return id, nil
+}
+
+// subscribe registers a handler for the topic, and subscribes to its channel when the bus is already running.
+func (b *Bus) subscribe(topic string, handler rawHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, known := b.handlers[topic]
+	b.handlers[topic] = append(b.handlers[topic], handler)
+	if known {
+		return
+	}
+	b.lastIDs[topic] = ""
+
+	if b.pubsub != nil {
+		if err := b.pubsub.Subscribe(context.Background(), b.channel(topic)); err != nil {
+			// The next reconnect subscribes to every known topic anyway.
+			log.LogErrorf("Event bus failed to subscribe to %s: %v", topic, err)
+		}
+	}
+}
+
+// run keeps a subscription open, reconnecting with backoff when it fails, until the bus is closed.
+func (b *Bus) run() {
+	defer close(b.done)
+
+	backoff := b.cfg.ReconnectBackoff
+	for {
+		ps := b.open()
+		err := b.receive(ps)
+		ps.Close()
+
+		select {
+		case <-b.stop:
+			return
+		default:
+		}
+
+		log.LogErrorf("Event bus subscription lost, reconnecting in %s: %v", backoff, err)
+		select {
+		case <-b.stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// open subscribes to the channels of every known topic with the current Redis client.
+func (b *Bus) open() *redis.PubSub {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	channels := make([]string, 0, len(b.handlers))
+	for topic := range b.handlers {
+		channels = append(channels, b.channel(topic))
+	}
+	b.pubsub = b.cfg.Redis.RedisClient().Subscribe(context.Background(), channels...)
+	return b.pubsub
+}
+
+// receive handles the messages of the subscription until it fails or the bus is closed.
+func (b *Bus) receive(ps *redis.PubSub) error {
+	ctx := context.Background()
+	for {
+		msg, err := ps.ReceiveTimeout(ctx, b.cfg.CatchUpInterval)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				// Quiet period: check that the connection is still alive, and catch up in case an announcement was lost.
+				if err := ps.Ping(ctx); err != nil {
+					return err
+				}
+				b.catchUpAll(ctx)
+				continue
+			}
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// Note: This also arrives after go-redis resubscribes on its own (e.g., after a failover),
+			// which is exactly when announcements may have been lost.
+			if m.Kind == "subscribe" {
+				b.catchUp(ctx, b.topicOf(m.Channel))
+			}
+		case *redis.Message:
+			b.catchUp(ctx, b.topicOf(m.Channel))
+		}
+	}
+}
+
+// catchUpAll reads the new events of every known topic.
+func (b *Bus) catchUpAll(ctx context.Context) {
+	b.mu.Lock()
+	topics := make([]string, 0, len(b.lastIDs))
+	for topic := range b.lastIDs {
+		topics = append(topics, topic)
+	}
+	b.mu.Unlock()
+
+	for _, topic := range topics {
+		b.catchUp(ctx, topic)
+	}
+}
+
+// catchUp reads the events of the topic after the last handled one, in order, and dispatches them.
+//
+// Note: Stream IDs grow with every XADD, so when an announcement arrives, every event before it is already
+// in the stream. That's why reading from the last handled ID can't skip events, even when the announcements
+// of concurrent publishers arrive out of order.
+func (b *Bus) catchUp(ctx context.Context, topic string) {
+	b.mu.Lock()
+	last, known := b.lastIDs[topic]
+	b.mu.Unlock()
+	if !known {
+		return
+	}
+
+	rdb := b.cfg.Redis.RedisClient()
+	stream := b.streamKey(topic)
+
+	// The first time, start from the current end of the stream, so a new pod doesn't replay the history.
+	if last == "" {
+		tail, err := rdb.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+		if err != nil {
+			log.LogErrorf("Event bus failed to read the end of %s: %v", topic, err)
+			return
+		}
+		last = "0-0"
+		if len(tail) > 0 {
+			last = tail[0].ID
+		}
+		b.mu.Lock()
+		started := b.lastIDs[topic] != ""
+		if !started {
+			b.lastIDs[topic] = last
+		}
+		b.mu.Unlock()
+		if !started {
+			return
+		}
+		// A publish of this pod set the starting point meanwhile (see publish), so its event is handled now.
+		b.catchUp(ctx, topic)
+		return
+	}
+
+	for {
+		msgs, err := rdb.XRangeN(ctx, stream, "("+last, "+", catchUpBatch).Result()
+		if err != nil {
+			log.LogErrorf("Event bus failed to read %s: %v", topic, err)
+			return
+		}
+		for _, msg := range msgs {
+			b.dispatch(ctx, parseEvent(topic, msg))
+			last = msg.ID
+			b.setLastID(topic, last)
+		}
+		if len(msgs) < catchUpBatch {
+			return
+		}
+	}
+}
+
+// setLastID records the last handled event of the topic.
+func (b *Bus) setLastID(topic, id string) {
+	b.mu.Lock()
+	b.lastIDs[topic] = id
+	b.mu.Unlock()
+}
+
+// dispatch calls every handler of the topic, retrying a failed handler with backoff up to MaxAttempts.
+func (b *Bus) dispatch(ctx context.Context, event rawEvent) {
+	b.mu.Lock()
+	handlers := b.handlers[event.topic]
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		backoff := b.cfg.RetryBackoff
+		for attempt := 1; ; attempt++ {
+			err := callHandler(ctx, handler, event)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, ErrDecode) || attempt >= b.cfg.MaxAttempts {
+				log.LogErrorf("Event bus gave up on event %s (%s) after %d attempt(s): %v", event.id, event.topic, attempt, err)
+				break
+			}
+			select {
+			case <-b.stop:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+}
+
+// callHandler calls the handler, turning a panic into an error, so a buggy handler can't kill the subscription.
+func callHandler(ctx context.Context, handler rawHandler, event rawEvent) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("eventbus: handler panicked: %v", p)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+// streamIDBefore returns the stream ID right before id, so an exclusive range from it starts with id.
+func streamIDBefore(id string) string {
+	msStr, seqStr, _ := strings.Cut(id, "-")
+	ms, err := strconv.ParseUint(msStr, 10, 64)
+	if err != nil {
+		return "0-0"
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return "0-0"
+	}
+	if seq > 0 {
+		return fmt.Sprintf("%d-%d", ms, seq-1)
+	}
+	if ms == 0 {
+		return "0-0"
+	}
+	return fmt.Sprintf("%d-%d", ms-1, uint64(math.MaxUint64))
+}
+
+// parseEvent converts a stream entry into a raw event.
+func parseEvent(topic string, msg redis.XMessage) rawEvent {
+	event := rawEvent{id: msg.ID, topic: topic}
+	event.source, _ = msg.Values["source"].(string)
+	if payload, ok := msg.Values["payload"].(string); ok {
+		event.payload = []byte(payload)
+	}
+	if ms, err := strconv.ParseInt(fmt.Sprint(msg.Values["time"]), 10, 64); err == nil {
+		event.time = time.UnixMilli(ms)
+	}
+	return event
+}
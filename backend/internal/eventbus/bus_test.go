@@ -0,0 +1,354 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eventbus
+
+import (
+	"context"
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type greeting struct {
+	Message string `json:"message"`
+}
+
+var topicGreeting = NewTopic[greeting]("test.greeting")
+
+// clientProvider implements RedisProvider with a plain client.
+type clientProvider struct{ client redis.UniversalClient }
+
+func (p clientProvider) RedisClient() redis.UniversalClient { return p.client }
+
+// recorder collects the events delivered to a handler.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event[greeting]
+}
+
+func (r *recorder) handle(_ context.Context, event Event[greeting]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recorder) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var messages []string
+	for _, e := range r.events {
+		messages = append(messages, e.Payload.Message)
+	}
+	return messages
+}
+
+func newTestBus(t *testing.T, mr *miniredis.Miniredis, source string) *Bus {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	bus := New(Config{
+		Redis:            clientProvider{client},
+		Source:           source,
+		RetryBackoff:     time.Millisecond,
+		ReconnectBackoff: 10 * time.Millisecond,
+		CatchUpInterval:  time.Second,
+	})
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+// waitSubscribed waits until the subscription of the topic is confirmed, so the events published afterward are delivered.
+func waitSubscribed(t *testing.T, bus *Bus, topic string) {
+	t.Helper()
+	waitFor(t, "the subscription to "+topic, func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return bus.lastIDs[topic] != ""
+	})
+}
+
+// waitFor polls cond until it's true or fails the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPublishSubscribeAcrossPods(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	podA, podB := newTestBus(t, mr, "pod-a"), newTestBus(t, mr, "pod-b")
+	var gotA, gotB recorder
+	Subscribe(podA, topicGreeting, gotA.handle)
+	Subscribe(podB, topicGreeting, gotB.handle)
+	for _, bus := range []*Bus{podA, podB} {
+		if err := bus.Start(); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		waitSubscribed(t, bus, topicGreeting.Name())
+	}
+
+	want := []string{"hello", "from", "pod-a"}
+	for _, msg := range want {
+		if _, err := Publish(ctx, podA, topicGreeting, greeting{msg}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	for name, got := range map[string]*recorder{"pod-a": &gotA, "pod-b": &gotB} {
+		waitFor(t, name+" to get every event", func() bool { return len(got.messages()) == len(want) })
+		if msgs := got.messages(); !slices.Equal(msgs, want) {
+			t.Errorf("%s got %v, want %v", name, msgs, want)
+		}
+		if event := got.events[0]; event.Source != "pod-a" || event.Topic != topicGreeting.Name() || event.ID == "" || event.Time.IsZero() {
+			t.Errorf("%s got event %+v, want its metadata set", name, event)
+		}
+	}
+}
+
+func TestCatchUpAfterLostAnnouncement(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	bus := newTestBus(t, mr, "pod-a")
+	var got recorder
+	Subscribe(bus, topicGreeting, got.handle)
+	bus.Start()
+	waitSubscribed(t, bus, topicGreeting.Name())
+
+	// An event stored without its announcement (e.g., the PUBLISH was lost in a failover)
+	// is delivered along with the next announced one, in order.
+	rdb := bus.cfg.Redis.RedisClient()
+	if err := rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: bus.streamKey(topicGreeting.Name()),
+		Values: map[string]any{"source": "pod-b", "time": time.Now().UnixMilli(), "payload": `{"message":"lost"}`},
+	}).Err(); err != nil {
+		t.Fatalf("XAdd() error = %v", err)
+	}
+	Publish(ctx, bus, topicGreeting, greeting{"announced"})
+
+	waitFor(t, "both events", func() bool { return len(got.messages()) == 2 })
+	if msgs := got.messages(); !slices.Equal(msgs, []string{"lost", "announced"}) {
+		t.Errorf("got %v, want [lost announced]", msgs)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	bus := newTestBus(t, mr, "pod-a")
+	var got recorder
+	Subscribe(bus, topicGreeting, got.handle)
+	bus.Start()
+	waitSubscribed(t, bus, topicGreeting.Name())
+
+	// Note: miniredis keeps its data across a restart, like a Redis with persistence.
+	mr.Close()
+	time.Sleep(50 * time.Millisecond)
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	waitFor(t, "the event after the restart", func() bool {
+		if len(got.messages()) > 0 {
+			return true
+		}
+		// The announcement may be published before the bus has resubscribed, then the resubscription catches up.
+		Publish(ctx, bus, topicGreeting, greeting{"back"})
+		time.Sleep(50 * time.Millisecond)
+		return len(got.messages()) > 0
+	})
+	if msgs := got.messages(); msgs[0] != "back" {
+		t.Errorf("got %v, want it to start with back", msgs)
+	}
+}
+
+func TestHandlerRetry(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	bus := newTestBus(t, mr, "pod-a")
+	var (
+		mu       sync.Mutex
+		attempts = map[string]int{}
+	)
+	Subscribe(bus, topicGreeting, func(_ context.Context, event Event[greeting]) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[event.Payload.Message]++
+		switch event.Payload.Message {
+		case "flaky":
+			if attempts["flaky"] < 2 {
+				return errors.New("try again")
+			}
+		case "broken":
+			return errors.New("always failing")
+		case "panic":
+			panic("gopher panicked")
+		}
+		return nil
+	})
+	var got recorder
+	Subscribe(bus, topicGreeting, got.handle)
+	bus.Start()
+	waitSubscribed(t, bus, topicGreeting.Name())
+
+	for _, msg := range []string{"flaky", "broken", "panic", "done"} {
+		Publish(ctx, bus, topicGreeting, greeting{msg})
+	}
+	// An event whose payload doesn't match the topic is dropped without retrying, and doesn't block the next ones.
+	bus.publish(ctx, topicGreeting.Name(), []byte("not json"))
+	Publish(ctx, bus, topicGreeting, greeting{"after"})
+
+	waitFor(t, "every event", func() bool { return len(got.messages()) == 5 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"flaky": 2, "broken": 3, "panic": 3, "done": 1, "after": 1}
+	for msg, n := range want {
+		if attempts[msg] != n {
+			t.Errorf("attempts[%q] = %d, want %d", msg, attempts[msg], n)
+		}
+	}
+	if msgs := got.messages(); !slices.Equal(msgs, []string{"flaky", "broken", "panic", "done", "after"}) {
+		t.Errorf("second handler got %v, want every decodable event once", msgs)
+	}
+}
+
+// patternScanner records the patterns it is asked to delete.
+type patternScanner struct {
+	mu       sync.Mutex
+	patterns []string
+}
+
+func (s *patternScanner) ScanAndDel(_ context.Context, patterns []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patterns = append(s.patterns, patterns...)
+	return nil
+}
+
+func (s *patternScanner) get() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.patterns)
+}
+
+func TestInvalidateCacheHandler(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	podA, podB := newTestBus(t, mr, "pod-a"), newTestBus(t, mr, "pod-b")
+	var scannerA, scannerB patternScanner
+	var mu sync.Mutex
+	local := map[string][]string{}
+	hook := func(pod string) func(context.Context, string) {
+		return func(_ context.Context, prefix string) {
+			mu.Lock()
+			defer mu.Unlock()
+			local[pod] = append(local[pod], prefix)
+		}
+	}
+	Subscribe(podA, TopicInvalidateCache, InvalidateCacheHandler(podA, &scannerA, hook("pod-a")))
+	Subscribe(podB, TopicInvalidateCache, InvalidateCacheHandler(podB, &scannerB, hook("pod-b")))
+	for _, bus := range []*Bus{podA, podB} {
+		bus.Start()
+		waitSubscribed(t, bus, TopicInvalidateCache.Name())
+	}
+
+	if _, err := PublishInvalidateCache(ctx, podA, "cache:user:", "cache:post:"); err != nil {
+		t.Fatalf("PublishInvalidateCache() error = %v", err)
+	}
+
+	waitFor(t, "the local hooks", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(local["pod-a"]) == 2 && len(local["pod-b"]) == 2
+	})
+	waitFor(t, "the keys to be deleted", func() bool { return len(scannerA.get()) == 2 })
+
+	if got := scannerA.get(); !slices.Equal(got, []string{"cache:user:*", "cache:post:*"}) {
+		t.Errorf("publisher deleted %v, want both prefixes", got)
+	}
+	// Redis is shared, so only the publisher scans it.
+	if got := scannerB.get(); len(got) != 0 {
+		t.Errorf("subscriber deleted %v, want nothing", got)
+	}
+}
+
+func TestInvalidateCacheBeforeSubscribed(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// An older event of another pod, which the publisher must not replay.
+	other := newTestBus(t, mr, "pod-b")
+	if _, err := PublishInvalidateCache(ctx, other, "cache:old:"); err != nil {
+		t.Fatalf("PublishInvalidateCache() error = %v", err)
+	}
+
+	// The event is published before the subscription is confirmed (the bus isn't even started).
+	pod := newTestBus(t, mr, "pod-a")
+	var scanner patternScanner
+	Subscribe(pod, TopicInvalidateCache, InvalidateCacheHandler(pod, &scanner))
+	if _, err := PublishInvalidateCache(ctx, pod, "cache:user:"); err != nil {
+		t.Fatalf("PublishInvalidateCache() error = %v", err)
+	}
+	if err := pod.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	waitFor(t, "the keys to be deleted", func() bool { return len(scanner.get()) > 0 })
+	if got := scanner.get(); !slices.Equal(got, []string{"cache:user:*"}) {
+		t.Errorf("publisher deleted %v, want only its own prefix", got)
+	}
+}
+
+func TestStreamIDBefore(t *testing.T) {
+	tests := []struct {
+		id, want string
+	}{
+		{"1700000000000-5", "1700000000000-4"},
+		{"1700000000000-0", "1699999999999-18446744073709551615"},
+		{"0-0", "0-0"},
+		{"invalid", "0-0"},
+	}
+	for _, tt := range tests {
+		if got := streamIDBefore(tt.id); got != tt.want {
+			t.Errorf("streamIDBefore(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestStartWithoutRedis(t *testing.T) {
+	bus := New()
+	if err := bus.Start(); !errors.Is(err, ErrRedisMissing) {
+		t.Errorf("Start() error = %v, want %v", err, ErrRedisMissing)
+	}
+	if _, err := Publish(context.Background(), bus, topicGreeting, greeting{}); !errors.Is(err, ErrRedisMissing) {
+		t.Errorf("Publish() error = %v, want %v", err, ErrRedisMissing)
+	}
+	bus.Close()
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eventbus
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	translation "h0llyw00dz-template/backend/internal/translate"
+)
+
+// CacheInvalidation is the payload of [TopicInvalidateCache].
+type CacheInvalidation struct {
+	// Prefixes are the key prefixes to invalidate (e.g., "cache:user:").
+	Prefixes []string `json:"prefixes"`
+}
+
+// TranslationsReload is the payload of [TopicReloadTranslations].
+type TranslationsReload struct {
+	// Reason is only logged (e.g., "new release").
+	Reason string `json:"reason,omitempty"`
+}
+
+var (
+	// TopicInvalidateCache asks every pod to invalidate the given key prefixes.
+	TopicInvalidateCache = NewTopic[CacheInvalidation]("cache.invalidate")
+
+	// TopicReloadTranslations asks every pod to reload the translations file.
+	TopicReloadTranslations = NewTopic[TranslationsReload]("translations.reload")
+)
+
+// PublishInvalidateCache asks every pod to invalidate the given key prefixes.
+func PublishInvalidateCache(ctx context.Context, bus *Bus, prefixes ...string) (string, error) {
+	return Publish(ctx, bus, TopicInvalidateCache, CacheInvalidation{Prefixes: prefixes})
+}
+
+// PublishReloadTranslations asks every pod to reload the translations file.
+func PublishReloadTranslations(ctx context.Context, bus *Bus, reason string) (string, error) {
+	return Publish(ctx, bus, TopicReloadTranslations, TranslationsReload{Reason: reason})
+}
+
+// InvalidateCacheHandler returns the handler of [TopicInvalidateCache].
+//
+// The keys matching the prefixes are deleted with scanner (e.g., [database.Service.ScanAndDel]) by the pod that
+// published the event only, since Redis is shared and a single SCAN is enough. The local hooks (e.g., for an
+// in-memory cache) are called on every pod, including the publisher.
+//
+// Note: The publisher handles its own event even when it publishes before its subscription is confirmed,
+// since [Publish] then starts its catch-up at that event instead of at the end of the stream.
+func InvalidateCacheHandler(bus *Bus, scanner database.Scanner, local ...func(ctx context.Context, prefix string)) Handler[CacheInvalidation] {
+	return func(ctx context.Context, event Event[CacheInvalidation]) error {
+		for _, prefix := range event.Payload.Prefixes {
+			for _, hook := range local {
+				hook(ctx, prefix)
+			}
+		}
+
+		if scanner == nil || event.Source != bus.Source() || len(event.Payload.Prefixes) == 0 {
+			return nil
+		}
+
+		patterns := make([]string, 0, len(event.Payload.Prefixes))
+		for _, prefix := range event.Payload.Prefixes {
+			patterns = append(patterns, prefix+"*")
+		}
+		if err := scanner.ScanAndDel(ctx, patterns); err != nil {
+			return err
+		}
+		log.LogInfof("Invalidated cache prefixes %v (event %s).", event.Payload.Prefixes, event.ID)
+		return nil
+	}
+}
+
+// ReloadTranslationsHandler returns the handler of [TopicReloadTranslations], which reloads the translations from filePath.
+//
+// Note: The path is fixed by the pod configuration instead of coming from the event, so whoever can publish to Redis
+// can't make the pods read an arbitrary file.
+func ReloadTranslationsHandler(filePath string) Handler[TranslationsReload] {
+	return func(ctx context.Context, event Event[TranslationsReload]) error {
+		if err := translation.LoadTranslations(filePath); err != nil {
+			return err
+		}
+		log.LogInfof("Reloaded translations from %s (event %s from %s, reason: %q).", filePath, event.ID, event.Source, event.Payload.Reason)
+		return nil
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Topic is a named stream of events whose payload is T.
+//
+// Note: The type parameter lets the compiler catch a publisher and a subscriber disagreeing on the payload,
+// which a bare channel name would only reveal at runtime on another pod.
+type Topic[T any] struct {
+	name string
+}
+
+// NewTopic creates a topic. The name must be the same on every pod (e.g., "cache.invalidate").
+func NewTopic[T any](name string) Topic[T] {
+	return Topic[T]{name: name}
+}
+
+// Name returns the name of the topic.
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+// Event is an event delivered to a [Handler].
+type Event[T any] struct {
+	// ID is the Redis Stream ID of the event, unique and increasing per topic.
+	// Since delivery is at least once, handlers that aren't idempotent can use it to skip duplicates.
+	ID string
+
+	// Topic is the name of the topic.
+	Topic string
+
+	// Source is the pod that published the event (see [Config.Source]).
+	Source string
+
+	// Time is when the event was published.
+	Time time.Time
+
+	// Payload is the decoded payload.
+	Payload T
+}
+
+// Handler handles the events of a topic. A handler that returns an error is called again with backoff,
+// up to [Config.MaxAttempts] times.
+type Handler[T any] func(ctx context.Context, event Event[T]) error
+
+// Publish publishes an event to every pod subscribed to the topic, including this one, and returns its ID.
+//
+// Note: When only the announcement fails, the ID is returned along with the error,
+// since the event is stored and is still delivered by the next catch-up.
+func Publish[T any](ctx context.Context, bus *Bus, topic Topic[T], payload T) (string, error) {
+	data, err := bus.cfg.Codec.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("eventbus: failed to encode %s: %w", topic.name, err)
+	}
+	return bus.publish(ctx, topic.name, data)
+}
+
+// Subscribe registers a handler for the topic on this pod. It can be called before or after [Bus.Start].
+//
+// Note: A pod only receives the events published after its subscription is confirmed by Redis, not the history.
+// Handlers of a pod are called one at a time, in the order of the events.
+func Subscribe[T any](bus *Bus, topic Topic[T], handler Handler[T]) {
+	bus.subscribe(topic.name, func(ctx context.Context, raw rawEvent) error {
+		var payload T
+		if err := bus.cfg.Codec.Unmarshal(raw.payload, &payload); err != nil {
+			return fmt.Errorf("%w: %v", ErrDecode, err)
+		}
+		return handler(ctx, Event[T]{
+			ID:      raw.id,
+			Topic:   raw.topic,
+			Source:  raw.source,
+			Time:    raw.time,
+			Payload: payload,
+		})
+	})
+}
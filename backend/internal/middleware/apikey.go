@@ -0,0 +1,111 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package middleware
+
+import (
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireScopes creates a middleware that only lets through the requests whose API key was granted every scope
+// (see [database.WithAPIKeyScopes]). It must run after the key authentication middleware, which is what
+// the Scopes field of APIRoute and APIGroup does.
+//
+// Example Usage:
+//
+//	APIRoute{
+//	    Path:    "/db",
+//	    Method:  fiber.MethodGet,
+//	    KeyAuth: keyAuth,
+//	    Scopes:  []string{"health:read"},
+//	    Handler: healthz.DBHandler(db),
+//	}
+//
+// Note: A missing scope is answered with 403 and a "WWW-Authenticate" header naming the required scopes (RFC 6750),
+// while a request without an authenticated API key is answered with 401.
+func RequireScopes(scopes ...string) fiber.Handler {
+	challenge := fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(scopes, " "))
+
+	return func(c *fiber.Ctx) error {
+		key, ok := keyauth.APIKeyFromContext(c)
+		if !ok {
+			log.LogUserActivity(c, "Missing API key for a route that requires scopes")
+			return helper.SendErrorResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		for _, scope := range scopes {
+			if !key.HasScope(scope) {
+				log.LogUserActivity(c, fmt.Sprintf("API key %s is missing scope %s", key.ID, scope))
+				c.Set(fiber.HeaderWWWAuthenticate, challenge)
+				return helper.SendErrorResponse(c, fiber.StatusForbidden, "Insufficient scope")
+			}
+		}
+		return c.Next()
+	}
+}
+
+// NewAPIKeyQuotaMiddleware creates a middleware that enforces the quota plan of the API key of the request,
+// and accounts its usage (see [database.Quotas]). Like RequireScopes, it must run after the key authentication middleware.
+//
+// The usual rate limit headers are set on every response: "X-RateLimit-Limit", "X-RateLimit-Remaining" and
+// "X-RateLimit-Reset" (in seconds), plus "Retry-After" when the quota is exceeded.
+//
+// Note: When Redis is unavailable, the request is let through (and logged), since an outage of the quota
+// accounting shouldn't take the whole API down. The global rate limiter still applies.
+func NewAPIKeyQuotaMiddleware(quotas *database.Quotas) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := keyauth.APIKeyFromContext(c)
+		if !ok {
+			return c.Next()
+		}
+
+		result, err := quotas.Allow(c.UserContext(), key)
+		if err != nil {
+			log.LogErrorf("Failed to check the quota of API key %s: %v", key.ID, err)
+			return c.Next()
+		}
+
+		if result.Limit > 0 {
+			resetIn := max(int64(time.Until(result.Reset).Round(time.Second).Seconds()), 0)
+			c.Set(xRateLimitLimit, strconv.FormatInt(result.Limit, 10))
+			c.Set(xRateLimitRemaining, strconv.FormatInt(result.Remaining, 10))
+			c.Set(xRateLimitReset, strconv.FormatInt(resetIn, 10))
+			if !result.Allowed {
+				log.LogUserActivity(c, fmt.Sprintf("API key %s exceeded its quota", key.ID))
+				c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(resetIn, 10))
+				return helper.SendErrorResponse(c, fiber.StatusTooManyRequests, "API key quota exceeded")
+			}
+		}
+		return c.Next()
+	}
+}
+
+// requireScopesOrNil returns RequireScopes for the scopes, or nil when there are none,
+// so it can be passed to useNonNilMiddleware and appendNonNilHandler.
+func requireScopesOrNil(scopes []string) fiber.Handler {
+	if len(scopes) == 0 {
+		return nil
+	}
+	return RequireScopes(scopes...)
+}
+
+// requirePermissionOrNil returns the Require of the authorizer for the permission, or nil when the authorization
+// is disabled (the authorizer is nil), so it can be passed to useNonNilMiddleware and appendNonNilHandler.
+func requirePermissionOrNil(authz *authorization.Authorizer, permission string, resource ...authorization.ResourceFunc) fiber.Handler {
+	if authz == nil {
+		return nil
+	}
+	return authz.Require(permission, resource...)
+}
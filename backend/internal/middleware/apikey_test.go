@@ -0,0 +1,85 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package middleware_test
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRequireScopesAndQuota(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	database.CreateAPIKeysTable(ctx, db)
+	database.CreateAPIKeyUsageTable(ctx, db)
+	reader, _, _ := db.Auth().CreateAPIKey(ctx, "gopher", "reader", time.Hour, database.WithAPIKeyScopes("health:read"))
+	nobody, _, _ := db.Auth().CreateAPIKey(ctx, "gopher", "no scopes", time.Hour)
+
+	quotas := database.NewQuotas(db, database.QuotaConfig{
+		Plans:       map[string]database.QuotaPlan{"free": {Limit: 2, Window: time.Hour}},
+		DefaultPlan: "free",
+	})
+	keyAuth := middleware.NewKeyAuthMiddleware(
+		middleware.WithValidator(keyauth.NewValidator(db)),
+		middleware.WithErrorHandler(keyauth.ErrorKeyAuthHandler),
+	)
+
+	app := fiber.New()
+	ok := func(c *fiber.Ctx) error { return c.SendString("ok") }
+	app.Get("/health", keyAuth, middleware.RequireScopes("health:read"), middleware.NewAPIKeyQuotaMiddleware(quotas), ok)
+	app.Get("/unauthenticated", middleware.RequireScopes("health:read"), ok)
+
+	tests := []struct {
+		name          string
+		path          string
+		key           string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{"granted", "/health", reader, fiber.StatusOK, "1"},
+		{"missing scope", "/health", nobody, fiber.StatusForbidden, ""},
+		{"granted again", "/health", reader, fiber.StatusOK, "0"},
+		{"quota exceeded", "/health", reader, fiber.StatusTooManyRequests, "0"},
+		{"without key auth", "/unauthenticated", reader, fiber.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.key)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := resp.Header.Get("X-RateLimit-Remaining"); got != tt.wantRemaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, tt.wantRemaining)
+			}
+			if tt.wantStatus == fiber.StatusForbidden && resp.Header.Get(fiber.HeaderWWWAuthenticate) == "" {
+				t.Error("missing WWW-Authenticate header on 403")
+			}
+			if tt.wantStatus == fiber.StatusTooManyRequests && resp.Header.Get(fiber.HeaderRetryAfter) == "" {
+				t.Error("missing Retry-After header on 429")
+			}
+		})
+	}
+}
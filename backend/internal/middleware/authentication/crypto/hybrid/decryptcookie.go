@@ -10,6 +10,7 @@ import (
 	"crypto/cipher"
 	"encoding/base64"
 	"encoding/hex"
+	"errors"
 
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -32,36 +33,38 @@ func DecryptCookie(encodedCookie, key string, encoding string) (string, error) {
 		return "", ErrorInvalidCookie
 	}
 
-	decryptFn := func(encryptedCookie []byte) ([]byte, error) {
-		// Extract the nonces and encrypted cookie value
-		if len(encryptedCookie) < 12+chacha20poly1305.NonceSizeX {
-			return nil, ErrorInvalidCookie
-		}
-		aesNonce := encryptedCookie[:12]
-		chachaNonce := encryptedCookie[12 : 12+chacha20poly1305.NonceSizeX]
-		ciphertext := encryptedCookie[12+chacha20poly1305.NonceSizeX:]
-
-		// Decrypt the cookie value using ChaCha20-Poly1305
-		aesCiphertext, err := decryptChaCha20Poly1305(ciphertext, chachaNonce, keyDecoded)
-		if err != nil {
-			return nil, err
+	plaintext, err := open(decodedCookie, keyDecoded, nil)
+	if err != nil {
+		if err == errSealedTooShort {
+			return "", ErrorInvalidCookie
 		}
+		return "", err
+	}
 
-		// Decrypt the AES-GCM ciphertext
-		plaintext, err := decryptAESGCM(aesCiphertext, aesNonce, keyDecoded)
-		if err != nil {
-			return nil, err
-		}
+	return string(plaintext), nil
+}
+
+// errSealedTooShort is returned by open when the value can't even hold the nonces.
+var errSealedTooShort = errors.New("sealed value too short")
 
-		return plaintext, nil
+// open reverses seal. The additional data must be the same as the one given to seal.
+func open(sealed, key, additionalData []byte) ([]byte, error) {
+	// Extract the nonces and encrypted value
+	if len(sealed) < 12+chacha20poly1305.NonceSizeX {
+		return nil, errSealedTooShort
 	}
+	aesNonce := sealed[:12]
+	chachaNonce := sealed[12 : 12+chacha20poly1305.NonceSizeX]
+	ciphertext := sealed[12+chacha20poly1305.NonceSizeX:]
 
-	plaintext, err := decryptFn(decodedCookie)
+	// Decrypt the value using ChaCha20-Poly1305
+	aesCiphertext, err := decryptChaCha20Poly1305(ciphertext, chachaNonce, key, additionalData)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(plaintext), nil
+	// Decrypt the AES-GCM ciphertext
+	return decryptAESGCM(aesCiphertext, aesNonce, key)
 }
 
 // decryptAESGCM decrypts the ciphertext using AES-GCM and returns the plaintext.
@@ -84,13 +87,13 @@ func decryptAESGCM(ciphertext, nonce, key []byte) ([]byte, error) {
 }
 
 // decryptChaCha20Poly1305 decrypts the ciphertext using XChaCha20-Poly1305 and returns the plaintext.
-func decryptChaCha20Poly1305(ciphertext, nonce, key []byte) ([]byte, error) {
+func decryptChaCha20Poly1305(ciphertext, nonce, key, additionalData []byte) ([]byte, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, err
 	}
 
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
@@ -23,27 +23,7 @@ func EncryptCookie(value, key string, encoding string) (string, error) {
 		return "", ErrorInvalidKey
 	}
 
-	encryptFn := func(plaintext []byte) ([]byte, error) {
-		// Encrypt the plaintext using AES-GCM
-		ciphertext, aesNonce, err := encryptAESGCM(plaintext, keyDecoded)
-		if err != nil {
-			return nil, err
-		}
-
-		// Encrypt the AES-GCM ciphertext using ChaCha20-Poly1305
-		encryptedCookie, chachaNonce, err := encryptChaCha20Poly1305(ciphertext, keyDecoded)
-		if err != nil {
-			return nil, err
-		}
-
-		// Combine the nonces and encrypted cookie value
-		// Note: this strong, required 99999999999999 cpu to brute force it.
-		noncesAndCiphertext := append(aesNonce, chachaNonce...)
-		noncesAndCiphertext = append(noncesAndCiphertext, encryptedCookie...)
-		return noncesAndCiphertext, nil
-	}
-
-	encryptedCookie, err := encryptFn([]byte(value))
+	encryptedCookie, err := seal([]byte(value), keyDecoded, nil)
 	if err != nil {
 		return "", err
 	}
@@ -59,6 +39,29 @@ func EncryptCookie(value, key string, encoding string) (string, error) {
 	return encodedCookie, nil
 }
 
+// seal encrypts the plaintext with AES-GCM, then encrypts the result with XChaCha20-Poly1305,
+// and returns the AES-GCM nonce, the XChaCha20-Poly1305 nonce and the ciphertext, in that order.
+// The additional data (optional) is authenticated by the outer layer but not encrypted.
+func seal(plaintext, key, additionalData []byte) ([]byte, error) {
+	// Encrypt the plaintext using AES-GCM
+	ciphertext, aesNonce, err := encryptAESGCM(plaintext, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encrypt the AES-GCM ciphertext using ChaCha20-Poly1305
+	sealed, chachaNonce, err := encryptChaCha20Poly1305(ciphertext, key, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Combine the nonces and encrypted value
+	// Note: this strong, required 99999999999999 cpu to brute force it.
+	noncesAndCiphertext := append(aesNonce, chachaNonce...)
+	noncesAndCiphertext = append(noncesAndCiphertext, sealed...)
+	return noncesAndCiphertext, nil
+}
+
 // encryptAESGCM encrypts the plaintext using AES-GCM and returns the ciphertext and nonce.
 func encryptAESGCM(plaintext, key []byte) ([]byte, []byte, error) {
 	block, err := aes.NewCipher(key)
@@ -80,7 +83,7 @@ func encryptAESGCM(plaintext, key []byte) ([]byte, []byte, error) {
 }
 
 // encryptChaCha20Poly1305 encrypts the plaintext using XChaCha20-Poly1305 and returns the ciphertext and nonce.
-func encryptChaCha20Poly1305(plaintext, key []byte) ([]byte, []byte, error) {
+func encryptChaCha20Poly1305(plaintext, key, additionalData []byte) ([]byte, []byte, error) {
 	aead, err := chacha20poly1305.NewX(key)
 	if err != nil {
 		return nil, nil, err
@@ -91,6 +94,6 @@ func encryptChaCha20Poly1305(plaintext, key []byte) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
-	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext := aead.Seal(nil, nonce, plaintext, additionalData)
 	return ciphertext, nonce, nil
 }
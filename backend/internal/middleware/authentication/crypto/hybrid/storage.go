@@ -0,0 +1,247 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package hybrid
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrorStorageMissing is returned by NewEncryptedStorage when no storage is configured.
+	ErrorStorageMissing = errors.New("hybrid: storage is not configured")
+
+	// ErrorUnknownKeyID is returned when there is no key for the key ID of the configured current key,
+	// or of a stored value (e.g., a key that was removed while values sealed with it still exist).
+	ErrorUnknownKeyID = errors.New("hybrid: unknown key ID")
+
+	// ErrorInvalidValue is returned when a stored value is not a sealed value, or fails authentication
+	// (e.g., it was tampered with, or copied under another storage key).
+	ErrorInvalidValue = errors.New("hybrid: invalid sealed value")
+)
+
+// sealedMagic starts every value written by EncryptedStorage: a zero byte, which JSON, msgpack (of a map or struct)
+// and gob values don't start with, followed by the format version.
+var sealedMagic = []byte{0x00, 0x01}
+
+// KeyWrapper encrypts and decrypts data keys with a key encryption key that never leaves it
+// (e.g., [vault.VClient] with the Vault Transit secrets engine).
+type KeyWrapper interface {
+	Encrypt(ctx context.Context, keyName string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error)
+}
+
+// StorageConfig defines the config for EncryptedStorage.
+type StorageConfig struct {
+	// Storage is the storage the sealed values are written to (e.g., Redis or Cloudflare KV).
+	//
+	// Required.
+	Storage fiber.Storage
+
+	// Keys are the 32-byte data keys by key ID. Every key that may have sealed a value still in the storage
+	// must be kept here, so rotating a key means adding a new one and changing CurrentKeyID, not replacing it.
+	//
+	// Required, unless the keys are given as WrappedKeys.
+	Keys map[string][]byte
+
+	// CurrentKeyID is the ID of the key used to seal new values.
+	//
+	// Required.
+	CurrentKeyID string
+
+	// WrappedKeys are data keys encrypted by KeyWrapper (envelope encryption), by key ID. They are decrypted once by
+	// NewEncryptedStorage and added to Keys, so the plaintext keys never have to be stored in the configuration.
+	// Create them with GenerateWrappedKey.
+	//
+	// Optional. Default: nil.
+	WrappedKeys map[string][]byte
+
+	// KeyWrapper decrypts the WrappedKeys.
+	//
+	// Required with WrappedKeys.
+	KeyWrapper KeyWrapper
+
+	// WrappingKeyName is the name of the key encryption key of KeyWrapper (e.g., the Vault Transit key name).
+	//
+	// Required with WrappedKeys.
+	WrappingKeyName string
+
+	// AllowPlaintext returns the values that aren't sealed as is instead of failing with ErrorInvalidValue,
+	// so encryption can be enabled on a storage that already has values. Disable it once they have expired.
+	//
+	// Optional. Default: false.
+	AllowPlaintext bool
+}
+
+// EncryptedStorage is a [fiber.Storage] that seals values with the hybrid AES-GCM/XChaCha20-Poly1305 scheme
+// before writing them to another storage, so the sessions and cached data are not readable from Redis or Cloudflare KV.
+//
+// Every value is stored as the magic bytes, the key ID, then the sealed value. The storage key and the key ID
+// are authenticated along with the value, so a value can't be moved under another key without failing to open.
+//
+// Example Usage:
+//
+//	storage, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+//	    Storage:      db.FiberStorage(),
+//	    Keys:         map[string][]byte{"2025-01": oldKey, "2025-06": newKey},
+//	    CurrentKeyID: "2025-06",
+//	})
+//
+// Note: The keys are only used for new writes, so the values sealed with an old key are not re-sealed on rotation.
+// They are still opened with their own key until they expire or are overwritten.
+type EncryptedStorage struct {
+	storage        fiber.Storage
+	keys           map[string][]byte
+	currentKeyID   string
+	allowPlaintext bool
+}
+
+// NewEncryptedStorage creates a new EncryptedStorage.
+func NewEncryptedStorage(config StorageConfig) (*EncryptedStorage, error) {
+	if config.Storage == nil {
+		return nil, ErrorStorageMissing
+	}
+
+	keys := make(map[string][]byte, len(config.Keys)+len(config.WrappedKeys))
+	for id, key := range config.Keys {
+		keys[id] = key
+	}
+
+	if len(config.WrappedKeys) > 0 {
+		if config.KeyWrapper == nil || config.WrappingKeyName == "" {
+			return nil, fmt.Errorf("%w: wrapped keys need a key wrapper and a wrapping key name", ErrorInvalidKey)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for id, wrapped := range config.WrappedKeys {
+			key, err := config.KeyWrapper.Decrypt(ctx, config.WrappingKeyName, wrapped)
+			if err != nil {
+				return nil, fmt.Errorf("hybrid: failed to unwrap key %q: %w", id, err)
+			}
+			keys[id] = key
+		}
+	}
+
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("%w: key ID %q must be 1 to 255 bytes", ErrorInvalidKey, id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%w: key %q must be 32 bytes, got %d", ErrorInvalidKey, id, len(key))
+		}
+	}
+	if _, ok := keys[config.CurrentKeyID]; !ok {
+		return nil, fmt.Errorf("%w: current key %q", ErrorUnknownKeyID, config.CurrentKeyID)
+	}
+
+	return &EncryptedStorage{
+		storage:        config.Storage,
+		keys:           keys,
+		currentKeyID:   config.CurrentKeyID,
+		allowPlaintext: config.AllowPlaintext,
+	}, nil
+}
+
+// GenerateWrappedKey generates a new random data key and returns it encrypted by the key wrapper,
+// ready to be added to StorageConfig.WrappedKeys.
+func GenerateWrappedKey(ctx context.Context, wrapper KeyWrapper, keyName string) ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return wrapper.Encrypt(ctx, keyName, key)
+}
+
+// Get returns the opened value of the key, or nil when it doesn't exist.
+func (s *EncryptedStorage) Get(key string) ([]byte, error) {
+	stored, err := s.storage.Get(key)
+	if err != nil || len(stored) == 0 {
+		return stored, err
+	}
+
+	if !bytes.HasPrefix(stored, sealedMagic) {
+		if s.allowPlaintext {
+			return stored, nil
+		}
+		return nil, ErrorInvalidValue
+	}
+
+	rest := stored[len(sealedMagic):]
+	if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+		return nil, ErrorInvalidValue
+	}
+	keyID := string(rest[1 : 1+rest[0]])
+	dataKey, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrorUnknownKeyID, keyID)
+	}
+
+	header := stored[:len(sealedMagic)+1+len(keyID)]
+	plaintext, err := open(stored[len(header):], dataKey, additionalData(header, key))
+	if err != nil {
+		return nil, ErrorInvalidValue
+	}
+	return plaintext, nil
+}
+
+// Set seals the value with the current key and writes it to the storage.
+func (s *EncryptedStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) == 0 || len(val) == 0 {
+		return nil
+	}
+
+	header := make([]byte, 0, len(sealedMagic)+1+len(s.currentKeyID))
+	header = append(header, sealedMagic...)
+	header = append(header, byte(len(s.currentKeyID)))
+	header = append(header, s.currentKeyID...)
+
+	sealed, err := seal(val, s.keys[s.currentKeyID], additionalData(header, key))
+	if err != nil {
+		return err
+	}
+	return s.storage.Set(key, append(header, sealed...), exp)
+}
+
+// Delete deletes the key from the storage.
+func (s *EncryptedStorage) Delete(key string) error {
+	return s.storage.Delete(key)
+}
+
+// Reset resets the storage.
+func (s *EncryptedStorage) Reset() error {
+	return s.storage.Reset()
+}
+
+// Close closes the storage.
+func (s *EncryptedStorage) Close() error {
+	return s.storage.Close()
+}
+
+// Conn returns the Redis client of the storage, or nil when the storage isn't Redis.
+//
+// Note: The values written with it directly aren't sealed, so it's only meant for what doesn't go through the storage
+// (e.g., the distributed lock of the idempotency middleware).
+func (s *EncryptedStorage) Conn() redis.UniversalClient {
+	if storage, ok := s.storage.(interface{ Conn() redis.UniversalClient }); ok {
+		return storage.Conn()
+	}
+	return nil
+}
+
+// additionalData binds a sealed value to its header and storage key.
+func additionalData(header []byte, key string) []byte {
+	aad := make([]byte, 0, len(header)+len(key))
+	aad = append(aad, header...)
+	return append(aad, key...)
+}
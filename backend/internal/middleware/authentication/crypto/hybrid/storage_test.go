@@ -0,0 +1,292 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package hybrid_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/hybrid"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	redisStorage "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+// mapStorage is a minimal in-memory fiber.Storage.
+type mapStorage struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMapStorage() *mapStorage {
+	return &mapStorage{data: make(map[string][]byte)}
+}
+
+func (m *mapStorage) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data[key], nil
+}
+
+func (m *mapStorage) Set(key string, val []byte, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = bytes.Clone(val)
+	return nil
+}
+
+func (m *mapStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *mapStorage) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.data)
+	return nil
+}
+
+func (m *mapStorage) Close() error { return nil }
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptedStorageRoundTrip(t *testing.T) {
+	backend := newMapStorage()
+	storage, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:      backend,
+		Keys:         map[string][]byte{"k1": newKey(t)},
+		CurrentKeyID: "k1",
+	})
+	if err != nil {
+		t.Fatalf("NewEncryptedStorage() error = %v", err)
+	}
+
+	secret := []byte(`{"api_key":"gopher-secret"}`)
+	if err := storage.Set("session:1", secret, time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	stored, _ := backend.Get("session:1")
+	if bytes.Contains(stored, []byte("gopher-secret")) {
+		t.Fatalf("stored value %q contains the plaintext", stored)
+	}
+
+	got, err := storage.Get("session:1")
+	if err != nil || !bytes.Equal(got, secret) {
+		t.Fatalf("Get() = %q, %v, want %q", got, err, secret)
+	}
+	if got, err := storage.Get("missing"); got != nil || err != nil {
+		t.Errorf("Get() of a missing key = %q, %v, want nil, nil", got, err)
+	}
+
+	// A sealed value copied under another key doesn't open, since the key is authenticated.
+	backend.Set("session:2", stored, time.Minute)
+	if _, err := storage.Get("session:2"); !errors.Is(err, hybrid.ErrorInvalidValue) {
+		t.Errorf("Get() of a moved value error = %v, want %v", err, hybrid.ErrorInvalidValue)
+	}
+
+	// A tampered value doesn't open either.
+	tampered := bytes.Clone(stored)
+	tampered[len(tampered)-1] ^= 1
+	backend.Set("session:1", tampered, time.Minute)
+	if _, err := storage.Get("session:1"); !errors.Is(err, hybrid.ErrorInvalidValue) {
+		t.Errorf("Get() of a tampered value error = %v, want %v", err, hybrid.ErrorInvalidValue)
+	}
+}
+
+func TestEncryptedStorageKeyRotation(t *testing.T) {
+	backend := newMapStorage()
+	oldKey, newKeyBytes := newKey(t), newKey(t)
+
+	before, _ := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:      backend,
+		Keys:         map[string][]byte{"2025-01": oldKey},
+		CurrentKeyID: "2025-01",
+	})
+	before.Set("old", []byte("sealed with the old key"), 0)
+
+	after, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:      backend,
+		Keys:         map[string][]byte{"2025-01": oldKey, "2025-06": newKeyBytes},
+		CurrentKeyID: "2025-06",
+	})
+	if err != nil {
+		t.Fatalf("NewEncryptedStorage() error = %v", err)
+	}
+	after.Set("new", []byte("sealed with the new key"), 0)
+
+	for key, want := range map[string]string{"old": "sealed with the old key", "new": "sealed with the new key"} {
+		if got, err := after.Get(key); err != nil || string(got) != want {
+			t.Errorf("Get(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+
+	// Once the old key is dropped, its values can't be opened anymore.
+	dropped, _ := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:      backend,
+		Keys:         map[string][]byte{"2025-06": newKeyBytes},
+		CurrentKeyID: "2025-06",
+	})
+	if _, err := dropped.Get("old"); !errors.Is(err, hybrid.ErrorUnknownKeyID) {
+		t.Errorf("Get() with the old key dropped error = %v, want %v", err, hybrid.ErrorUnknownKeyID)
+	}
+}
+
+func TestEncryptedStoragePlaintextMigration(t *testing.T) {
+	backend := newMapStorage()
+	backend.Set("legacy", []byte(`{"plain":true}`), 0)
+	key := newKey(t)
+
+	for _, allow := range []bool{false, true} {
+		storage, _ := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+			Storage:        backend,
+			Keys:           map[string][]byte{"k1": key},
+			CurrentKeyID:   "k1",
+			AllowPlaintext: allow,
+		})
+		got, err := storage.Get("legacy")
+		if allow && (err != nil || string(got) != `{"plain":true}`) {
+			t.Errorf("Get() with AllowPlaintext = %q, %v, want the plaintext value", got, err)
+		}
+		if !allow && !errors.Is(err, hybrid.ErrorInvalidValue) {
+			t.Errorf("Get() without AllowPlaintext error = %v, want %v", err, hybrid.ErrorInvalidValue)
+		}
+	}
+}
+
+func TestNewEncryptedStorageErrors(t *testing.T) {
+	backend := newMapStorage()
+	key := newKey(t)
+
+	tests := []struct {
+		name   string
+		config hybrid.StorageConfig
+		want   error
+	}{
+		{"no storage", hybrid.StorageConfig{Keys: map[string][]byte{"k1": key}, CurrentKeyID: "k1"}, hybrid.ErrorStorageMissing},
+		{"unknown current key", hybrid.StorageConfig{Storage: backend, Keys: map[string][]byte{"k1": key}, CurrentKeyID: "k2"}, hybrid.ErrorUnknownKeyID},
+		{"short key", hybrid.StorageConfig{Storage: backend, Keys: map[string][]byte{"k1": key[:16]}, CurrentKeyID: "k1"}, hybrid.ErrorInvalidKey},
+		{"wrapped keys without wrapper", hybrid.StorageConfig{Storage: backend, WrappedKeys: map[string][]byte{"k1": key}, CurrentKeyID: "k1"}, hybrid.ErrorInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := hybrid.NewEncryptedStorage(tt.config); !errors.Is(err, tt.want) {
+				t.Errorf("NewEncryptedStorage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// fakeTransit is a KeyWrapper that "wraps" keys by XOR with its own key, like a Vault Transit key that never leaves Vault.
+type fakeTransit struct {
+	kek   []byte
+	calls int
+}
+
+func (f *fakeTransit) Encrypt(_ context.Context, keyName string, plaintext []byte) ([]byte, error) {
+	if keyName != "storage" {
+		return nil, errors.New("unknown transit key")
+	}
+	out := make([]byte, len(plaintext))
+	for i := range plaintext {
+		out[i] = plaintext[i] ^ f.kek[i%len(f.kek)]
+	}
+	return out, nil
+}
+
+func (f *fakeTransit) Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	f.calls++
+	return f.Encrypt(ctx, keyName, ciphertext)
+}
+
+func TestEncryptedStorageEnvelope(t *testing.T) {
+	transit := &fakeTransit{kek: newKey(t)}
+	wrapped, err := hybrid.GenerateWrappedKey(context.Background(), transit, "storage")
+	if err != nil {
+		t.Fatalf("GenerateWrappedKey() error = %v", err)
+	}
+
+	storage, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:         newMapStorage(),
+		WrappedKeys:     map[string][]byte{"vault-1": wrapped},
+		CurrentKeyID:    "vault-1",
+		KeyWrapper:      transit,
+		WrappingKeyName: "storage",
+	})
+	if err != nil {
+		t.Fatalf("NewEncryptedStorage() error = %v", err)
+	}
+	if transit.calls != 1 {
+		t.Errorf("Decrypt called %d times, want the data key unwrapped once", transit.calls)
+	}
+
+	storage.Set("k", []byte("v"), 0)
+	if got, err := storage.Get("k"); err != nil || string(got) != "v" {
+		t.Errorf("Get() = %q, %v, want v", got, err)
+	}
+}
+
+// TestEncryptedStorageBinaryValues checks that binary values (e.g., the gob-encoded data of the session middleware)
+// survive the round trip untouched.
+func TestEncryptedStorageBinaryValues(t *testing.T) {
+	storage, _ := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+		Storage:      newMapStorage(),
+		Keys:         map[string][]byte{"k1": newKey(t)},
+		CurrentKeyID: "k1",
+	})
+	payload := []byte{0x0e, 0xff, 0x81, 0x04, 0x01, 0x02, 0xff, 0x82, 0x00}
+	storage.Set("session", payload, time.Minute)
+	if got, err := storage.Get("session"); err != nil || !bytes.Equal(got, payload) {
+		t.Errorf("Get() = %v, %v, want %v", got, err, payload)
+	}
+}
+
+func TestEncryptedStorageConn(t *testing.T) {
+	key := newKey(t)
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	defer client.Close()
+
+	tests := []struct {
+		name    string
+		backend fiber.Storage
+		want    redis.UniversalClient
+	}{
+		{"redis", redisStorage.NewFromConnection(client), client},
+		{"not redis", newMapStorage(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+				Storage:      tt.backend,
+				Keys:         map[string][]byte{"k1": key},
+				CurrentKeyID: "k1",
+			})
+			if err != nil {
+				t.Fatalf("NewEncryptedStorage() error = %v", err)
+			}
+			if got := storage.Conn(); got != tt.want {
+				t.Errorf("Conn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,623 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"cmp"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/acme"
+)
+
+// Challenge types of the ACME authorizations (RFC 8555, Section 8, and RFC 8737).
+const (
+	// ChallengeHTTP01 proves the control of a host by serving a token on "http://<host>/.well-known/acme-challenge/<token>",
+	// answered by [ACMEManager.HTTPHandler] on the port 80 server.
+	ChallengeHTTP01 = "http-01"
+
+	// ChallengeTLSALPN01 proves the control of a host by serving a self-signed certificate with the "acme-tls/1" protocol
+	// on port 443, answered by [ACMEManager.GetCertificate] on the TLS listener.
+	ChallengeTLSALPN01 = "tls-alpn-01"
+)
+
+// acmeChallengePath is the path prefix of the HTTP-01 challenges.
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+var (
+	// ErrMissingACMEHosts is returned by NewACMEManager when there is no host.
+	ErrMissingACMEHosts = errors.New("crypto/acme: at least one host is required")
+
+	// ErrMissingACMEStorage is returned by NewACMEManager when there is no storage.
+	ErrMissingACMEStorage = errors.New("crypto/acme: storage is required")
+
+	// ErrACMEOrderInProgress is returned by Renew when another replica holds the lock of the order.
+	ErrACMEOrderInProgress = errors.New("crypto/acme: order in progress on another replica")
+
+	// ErrACMENoChallenge is returned when an authorization offers none of the configured challenges.
+	ErrACMENoChallenge = errors.New("crypto/acme: no supported challenge")
+
+	// ErrACMENoCertificate is returned during the handshake when no certificate was issued yet,
+	// or when the server name isn't one of the hosts.
+	ErrACMENoCertificate = errors.New("crypto/acme: no certificate for server name")
+)
+
+// ACMEConfig defines the config for the ACMEManager.
+type ACMEConfig struct {
+	// Hosts are the hosts of the certificate, which is a single certificate with every host as a DNS name.
+	//
+	// Required.
+	Hosts []string
+
+	// Storage stores the account key, the certificate and the pending challenges, shared by the replicas,
+	// so any of them can answer a challenge and serve a certificate ordered by another one.
+	// It should be durable and not shared with other middlewares (see ACMEStorage), since the CAs rate limit the orders.
+	//
+	// Required.
+	Storage fiber.Storage
+
+	// Locker holds a distributed lock during the orders, so only one replica orders at a time.
+	//
+	// Optional. Default: nil (no lock, for a single replica)
+	Locker *database.Locker
+
+	// DirectoryURL is the directory URL of the ACME server.
+	//
+	// Optional. Default: acme.LetsEncryptURL
+	DirectoryURL string
+
+	// Email is the contact of the account, which the CA notifies about expiring certificates and incidents.
+	//
+	// Optional. Default: "" (no contact)
+	Email string
+
+	// Challenges are the challenge types, tried in order.
+	//
+	// Optional. Default: []string{ChallengeTLSALPN01, ChallengeHTTP01}
+	Challenges []string
+
+	// RenewBefore is how long before its expiry the certificate is renewed.
+	//
+	// Optional. Default: 30 * 24 * time.Hour
+	RenewBefore time.Duration
+
+	// CheckInterval is the interval at which the certificate is reloaded from the storage (e.g., renewed by another replica),
+	// and renewed when it's due (see Start).
+	//
+	// Optional. Default: 1 * time.Hour
+	CheckInterval time.Duration
+
+	// RetryInterval is the interval of the checks after a failed order, or while another replica orders.
+	//
+	// Optional. Default: 1 * time.Minute
+	RetryInterval time.Duration
+
+	// OrderTimeout limits each order, from the new order to the download of the certificate.
+	//
+	// Optional. Default: 5 * time.Minute
+	OrderTimeout time.Duration
+
+	// KeyPrefix is prepended to the storage keys.
+	//
+	// Optional. Default: "acme:"
+	KeyPrefix string
+
+	// HTTPClient is the HTTP client of the requests to the ACME server.
+	//
+	// Optional. Default: http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// ACMEManager obtains and renews a certificate from an ACME server (RFC 8555), such as Let's Encrypt,
+// with the HTTP-01 and TLS-ALPN-01 challenges. It's used with [ACMEManager.TLSConfig] on the TLS listener,
+// and [ACMEManager.HTTPHandler] on the port 80 server.
+type ACMEManager struct {
+	cfg   ACMEConfig
+	cert  atomic.Pointer[tls.Certificate]
+	hosts map[string]struct{}
+
+	mu sync.Mutex // serializes the renewals of this replica
+
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// NewACMEManager returns a new ACMEManager with the config, with the certificate of the storage (if any).
+// The certificate is only ordered by Renew or Start, once the listeners can answer the challenges.
+//
+// Example Usage:
+//
+//	certs, err := tls.NewACMEManager(tls.ACMEConfig{
+//	    Hosts:   []string{"example.com", "api.example.com"},
+//	    Storage: tls.NewACMEStorage(db),
+//	    Locker:  database.NewLocker(db.RedisClient()),
+//	    Email:   "admin@example.com",
+//	})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	certs.Start()
+//	defer certs.Close()
+//
+//	ln := tls.NewListener(ln, certs.TLSConfig())
+//	redirect := &http.Server{Addr: ":80", Handler: certs.HTTPHandler(redirectHandler)}
+func NewACMEManager(config ACMEConfig) (*ACMEManager, error) {
+	hosts := make(map[string]struct{}, len(config.Hosts))
+	normalized := make([]string, 0, len(config.Hosts))
+	for _, host := range config.Hosts {
+		if host = normalizeHost(host); host != "" {
+			hosts[host] = struct{}{}
+			normalized = append(normalized, host)
+		}
+	}
+	config.Hosts = normalized
+	if len(config.Hosts) == 0 {
+		return nil, ErrMissingACMEHosts
+	}
+	if config.Storage == nil {
+		return nil, ErrMissingACMEStorage
+	}
+	config.DirectoryURL = cmp.Or(config.DirectoryURL, acme.LetsEncryptURL)
+	if len(config.Challenges) == 0 {
+		config.Challenges = []string{ChallengeTLSALPN01, ChallengeHTTP01}
+	}
+	config.RenewBefore = cmp.Or(config.RenewBefore, 30*24*time.Hour)
+	config.CheckInterval = cmp.Or(config.CheckInterval, time.Hour)
+	config.RetryInterval = cmp.Or(config.RetryInterval, time.Minute)
+	config.OrderTimeout = cmp.Or(config.OrderTimeout, 5*time.Minute)
+	config.KeyPrefix = cmp.Or(config.KeyPrefix, "acme:")
+	config.HTTPClient = cmp.Or(config.HTTPClient, http.DefaultClient)
+
+	m := &ACMEManager{
+		cfg:   config,
+		hosts: hosts,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	// Note: A broken certificate in the storage doesn't fail the boot, since it's ordered again by Renew.
+	if cert, err := m.loadCert(); err != nil {
+		log.LogErrorf("Failed to load the ACME certificate: %v", err)
+	} else if cert != nil {
+		m.setCert(cert)
+	}
+	return m, nil
+}
+
+// TLSConfig returns a TLS config serving the certificate, and answering the TLS-ALPN-01 challenges.
+//
+// Note: The "acme-tls/1" protocol must be in NextProtos, otherwise the handshakes of the challenges fail.
+func (m *ACMEManager) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"http/1.1", acme.ALPNProto},
+	}
+}
+
+// GetCertificate returns the certificate for the hosts, or the certificate of a pending TLS-ALPN-01 challenge
+// when the client only asks for the "acme-tls/1" protocol.
+func (m *ACMEManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	host := normalizeHost(hello.ServerName)
+	if len(hello.SupportedProtos) == 1 && hello.SupportedProtos[0] == acme.ALPNProto {
+		return m.challengeCert(host)
+	}
+
+	cert := m.cert.Load()
+	if cert == nil {
+		return nil, ErrACMENoCertificate
+	}
+	// Note: The clients without a server name (e.g., connecting to an IP address) get the certificate anyway, like with LoadConfig.
+	if _, ok := m.hosts[host]; !ok && host != "" {
+		return nil, fmt.Errorf("%w %q", ErrACMENoCertificate, hello.ServerName)
+	}
+	return cert, nil
+}
+
+// HTTPHandler returns a handler answering the HTTP-01 challenges, and passing the other requests to the fallback
+// (e.g., the redirect to HTTPS). A nil fallback answers them with 404 Not Found.
+func (m *ACMEManager) HTTPHandler(fallback http.Handler) http.Handler {
+	if fallback == nil {
+		fallback = http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.URL.Path, acmeChallengePath)
+		if !ok {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		keyAuth, err := m.cfg.Storage.Get(m.key("http-01:" + token))
+		if err != nil {
+			log.LogErrorf("Failed to get the ACME HTTP-01 challenge: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if keyAuth == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write(keyAuth)
+	})
+}
+
+// Certificate returns the current certificate, or nil when none was issued yet.
+func (m *ACMEManager) Certificate() *tls.Certificate {
+	return m.cert.Load()
+}
+
+// Renew reloads the certificate from the storage, and orders a new one when there is none or it's due for renewal.
+// With a Locker, it returns ErrACMEOrderInProgress when another replica is ordering.
+func (m *ACMEManager) Renew(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.reloadDue() {
+		return nil
+	}
+
+	if m.cfg.Locker != nil {
+		lock, err := m.cfg.Locker.TryLock(ctx, m.key("order:"+m.cfg.Hosts[0]))
+		if errors.Is(err, database.ErrLockNotAcquired) {
+			return ErrACMEOrderInProgress
+		}
+		if err != nil {
+			return fmt.Errorf("crypto/acme: failed to lock the order: %w", err)
+		}
+		defer lock.Unlock(context.Background())
+
+		// Note: Another replica may have renewed it between the check and the lock.
+		if !m.reloadDue() {
+			return nil
+		}
+	}
+
+	// Note: The order holds m.mu, so an order longer than CheckInterval delays the next checks (e.g., the reload of
+	// a certificate renewed by another replica). It's logged as a warning, since it's usually a stuck ACME server,
+	// or an OrderTimeout longer than the CheckInterval.
+	start := time.Now()
+	slow := time.AfterFunc(m.cfg.CheckInterval, func() {
+		log.LogErrorf("Warning: the ACME order of %s is still running after %s, longer than the check interval (%s)",
+			strings.Join(m.cfg.Hosts, ", "), time.Since(start).Round(time.Second), m.cfg.CheckInterval)
+	})
+	defer slow.Stop()
+
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.OrderTimeout)
+	defer cancel()
+	cert, err := m.order(ctx)
+	if err != nil {
+		return err
+	}
+	m.setCert(cert)
+	log.LogInfof("Obtained the certificate of %s from the ACME server, expiring at %s",
+		strings.Join(m.cfg.Hosts, ", "), cert.Leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+// reloadDue reloads the certificate from the storage, and reports whether it must be ordered.
+func (m *ACMEManager) reloadDue() bool {
+	cert, err := m.loadCert()
+	if err != nil {
+		log.LogErrorf("Failed to load the ACME certificate, ordering a new one: %v", err)
+		return true
+	}
+	if cert == nil {
+		return true
+	}
+	m.setCert(cert)
+	return time.Until(cert.Leaf.NotAfter) <= m.cfg.RenewBefore
+}
+
+// setCert serves the certificate, and updates its expiry metric.
+func (m *ACMEManager) setCert(cert *tls.Certificate) {
+	m.cert.Store(cert)
+	certificateExpiry.WithLabelValues(m.key("cert:" + m.cfg.Hosts[0])).Set(float64(cert.Leaf.NotAfter.Unix()))
+}
+
+// order orders a new certificate for the hosts, and stores it.
+func (m *ACMEManager) order(ctx context.Context) (*tls.Certificate, error) {
+	accountKey, err := m.accountKey()
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{Key: accountKey, DirectoryURL: m.cfg.DirectoryURL, HTTPClient: m.cfg.HTTPClient}
+
+	account := &acme.Account{}
+	if m.cfg.Email != "" {
+		account.Contact = []string{"mailto:" + m.cfg.Email}
+	}
+	if _, err := client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("crypto/acme: failed to register the account: %w", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.cfg.Hosts...))
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to create the order: %w", err)
+	}
+	orderURL := order.URI
+	for _, authzURL := range order.AuthzURLs {
+		if err := m.authorize(ctx, client, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	if order, err = client.WaitOrder(ctx, orderURL); err != nil {
+		return nil, fmt.Errorf("crypto/acme: order not ready: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: m.cfg.Hosts}, key)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := finalize(ctx, client, orderURL, order.FinalizeURL, csr)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to finalize the order: %w", err)
+	}
+
+	data, err := encodeKeyPair(chain, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := parseKeyPair(data)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: invalid certificate: %w", err)
+	}
+	if err := m.cfg.Storage.Set(m.key("cert:"+m.cfg.Hosts[0]), data, 0); err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to store the certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// finalize submits the CSR of the order, and downloads the certificate chain once it's issued.
+func finalize(ctx context.Context, client *acme.Client, orderURL, finalizeURL string, csr []byte) ([][]byte, error) {
+	chain, _, err := client.CreateOrderCert(ctx, finalizeURL, csr, true)
+	if err == nil {
+		return chain, nil
+	}
+	// Note: CreateOrderCert waits for an order still processing on the Location of the finalize response,
+	// which some ACME servers (e.g., Pebble) don't send, so the order is then awaited on its own URL.
+	order, waitErr := client.WaitOrder(ctx, orderURL)
+	if waitErr != nil || order.Status != acme.StatusValid {
+		return nil, err
+	}
+	return client.FetchCert(ctx, order.CertURL, true)
+}
+
+// authorize fulfills the authorization with the first configured challenge it offers.
+func (m *ACMEManager) authorize(ctx context.Context, client *acme.Client, authzURL string) error {
+	authz, err := client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("crypto/acme: failed to get the authorization: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, typ := range m.cfg.Challenges {
+		if i := slices.IndexFunc(authz.Challenges, func(c *acme.Challenge) bool { return c.Type == typ }); i >= 0 {
+			chal = authz.Challenges[i]
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("%w for %s", ErrACMENoChallenge, authz.Identifier.Value)
+	}
+
+	key, err := m.prepare(client, chal, authz.Identifier.Value)
+	if err != nil {
+		return err
+	}
+	defer m.cfg.Storage.Delete(key)
+
+	if _, err := client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("crypto/acme: failed to accept the %s challenge of %s: %w", chal.Type, authz.Identifier.Value, err)
+	}
+	if _, err := client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("crypto/acme: %s challenge of %s failed: %w", chal.Type, authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+// prepare stores the response of the challenge, so any replica can answer it, and returns its storage key.
+func (m *ACMEManager) prepare(client *acme.Client, chal *acme.Challenge, host string) (string, error) {
+	var (
+		key  string
+		data []byte
+	)
+	switch chal.Type {
+	case ChallengeHTTP01:
+		keyAuth, err := client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return "", err
+		}
+		key, data = m.key("http-01:"+chal.Token), []byte(keyAuth)
+	case ChallengeTLSALPN01:
+		cert, err := client.TLSALPN01ChallengeCert(chal.Token, host)
+		if err != nil {
+			return "", err
+		}
+		if data, err = encodeKeyPair(cert.Certificate, cert.PrivateKey); err != nil {
+			return "", err
+		}
+		key = m.key("tls-alpn-01:" + host)
+	}
+	if err := m.cfg.Storage.Set(key, data, m.cfg.OrderTimeout); err != nil {
+		return "", fmt.Errorf("crypto/acme: failed to store the %s challenge: %w", chal.Type, err)
+	}
+	return key, nil
+}
+
+// challengeCert returns the certificate of the pending TLS-ALPN-01 challenge of the host.
+func (m *ACMEManager) challengeCert(host string) (*tls.Certificate, error) {
+	data, err := m.cfg.Storage.Get(m.key("tls-alpn-01:" + host))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("crypto/acme: no pending TLS-ALPN-01 challenge for %q", host)
+	}
+	return parseKeyPair(data)
+}
+
+// accountKey returns the key of the account on the ACME server, after generating it when there is none.
+//
+// Note: It's only called during an order, so with the lock held, and the replicas share the same account.
+func (m *ACMEManager) accountKey() (*ecdsa.PrivateKey, error) {
+	sum := sha256.Sum256([]byte(m.cfg.DirectoryURL))
+	storageKey := m.key("account:" + hex.EncodeToString(sum[:8]))
+
+	data, err := m.cfg.Storage.Get(storageKey)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to get the account key: %w", err)
+	}
+	if data != nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("crypto/acme: invalid account key")
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.cfg.Storage.Set(storageKey, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0); err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to store the account key: %w", err)
+	}
+	return key, nil
+}
+
+// loadCert loads the certificate from the storage, or returns nil when there is none.
+func (m *ACMEManager) loadCert() (*tls.Certificate, error) {
+	data, err := m.cfg.Storage.Get(m.key("cert:" + m.cfg.Hosts[0]))
+	if err != nil || data == nil {
+		return nil, err
+	}
+	cert, err := parseKeyPair(data)
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: invalid stored certificate: %w", err)
+	}
+	// Note: A certificate stored before the hosts changed is ordered again.
+	for _, host := range m.cfg.Hosts {
+		if !slices.Contains(cert.Leaf.DNSNames, host) {
+			return nil, nil
+		}
+	}
+	return cert, nil
+}
+
+// key returns the storage key with the prefix.
+func (m *ACMEManager) key(name string) string {
+	return m.cfg.KeyPrefix + name
+}
+
+// Start renews the certificate in the background (see Renew), right away, then every ACMEConfig.CheckInterval
+// (or ACMEConfig.RetryInterval after a failure), until Close is called.
+func (m *ACMEManager) Start() {
+	if !m.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(m.done)
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-timer.C:
+				ctx, cancel := context.WithCancel(context.Background())
+				go func() {
+					select {
+					case <-m.stop:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				err := m.Renew(ctx)
+				cancel()
+
+				switch {
+				case err == nil:
+					timer.Reset(m.cfg.CheckInterval)
+				case errors.Is(err, ErrACMEOrderInProgress):
+					timer.Reset(m.cfg.RetryInterval)
+				default:
+					log.LogErrorf("Failed to renew the ACME certificate: %v", err)
+					timer.Reset(m.cfg.RetryInterval)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background renewal started by Start, and cancels the order in progress (if any).
+func (m *ACMEManager) Close() {
+	m.once.Do(func() { close(m.stop) })
+	if m.started.Load() {
+		<-m.done
+	}
+}
+
+// encodeKeyPair encodes the certificate chain and the private key in PEM.
+func encodeKeyPair(chain [][]byte, key any) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	for _, cert := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+	}
+	return data, nil
+}
+
+// parseKeyPair parses a certificate chain and its private key encoded by encodeKeyPair.
+func parseKeyPair(data []byte) (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	"time"
+)
+
+// ACMETable is the name of the table storing the state of the ACMEManager (see NewACMEStorage).
+const ACMETable = "acme_storage"
+
+// ErrACMEStorageReset is returned by ACMEStorage.Reset, which would delete the account key and the certificates.
+var ErrACMEStorageReset = errors.New("crypto/acme: the storage can't be reset, since it holds the account key and the certificates")
+
+// ACMEStorage is a [fiber.Storage] of the state of the ACMEManager (the account key, the certificates and the pending challenges),
+// in its own table of the SQL database, so it's durable and shared by the replicas without being shared by other middlewares.
+//
+// Note: The Redis storage isn't suitable, since its keys can be evicted (e.g., "maxmemory-policy allkeys-lru"), and its Reset
+// flushes the whole database (FLUSHDB). Losing the account key or the certificate means ordering again, which the CAs rate limit
+// (e.g., 5 duplicate certificates per week on Let's Encrypt). That's why Reset always fails with ErrACMEStorageReset.
+type ACMEStorage struct {
+	db database.Service
+}
+
+// NewACMEStorage returns the storage of the ACMEManager in the ACMETable of the database (see CreateACMETable).
+func NewACMEStorage(db database.Service) *ACMEStorage {
+	return &ACMEStorage{db: db}
+}
+
+// CreateACMETable creates the table of the ACMEStorage if it doesn't exist.
+func CreateACMETable(ctx context.Context, db database.Service) error {
+	query := `CREATE TABLE IF NOT EXISTS acme_storage (
+	name VARCHAR(255) NOT NULL PRIMARY KEY,
+	data BLOB NOT NULL,
+	expires_at BIGINT NOT NULL DEFAULT 0
+)`
+	if db.Dialect().Name() == database.DriverMySQL {
+		query += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	return db.ExecWithoutRow(ctx, query)
+}
+
+// Get gets the value of the key, or nil when it doesn't exist or has expired.
+func (s *ACMEStorage) Get(key string) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	ctx := context.Background()
+	var (
+		data      []byte
+		expiresAt int64
+	)
+	err := s.db.QueryRow(ctx, "SELECT data, expires_at FROM acme_storage WHERE name = ?", key).Scan(&data, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to get %s: %w", key, err)
+	}
+	if expiresAt != 0 && time.Now().UnixMilli() >= expiresAt {
+		// Note: The expired values are the challenges of an interrupted order, removed lazily.
+		s.Delete(key)
+		return nil, nil
+	}
+	return data, nil
+}
+
+// Set sets the value of the key, expiring after exp (0 means no expiration).
+//
+// Note: This is an UPDATE falling back to an INSERT instead of an upsert, which is written differently
+// by MySQL ("ON DUPLICATE KEY UPDATE") and SQLite ("ON CONFLICT"). An INSERT failing on a duplicate key
+// (a concurrent Set, or an UPDATE of MySQL not counting an unchanged row) is done again as an UPDATE.
+func (s *ACMEStorage) Set(key string, val []byte, exp time.Duration) error {
+	if len(key) == 0 || len(val) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	var expiresAt int64
+	if exp > 0 {
+		expiresAt = time.Now().Add(exp).UnixMilli()
+	}
+
+	const update = "UPDATE acme_storage SET data = ?, expires_at = ? WHERE name = ?"
+	result, err := s.db.Exec(ctx, update, val, expiresAt, key)
+	if err != nil {
+		return fmt.Errorf("crypto/acme: failed to set %s: %w", key, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.db.Exec(ctx, "INSERT INTO acme_storage (name, data, expires_at) VALUES (?, ?, ?)", key, val, expiresAt)
+	if err != nil && s.db.Dialect().IsDuplicateEntry(err) {
+		_, err = s.db.Exec(ctx, update, val, expiresAt, key)
+	}
+	if err != nil {
+		return fmt.Errorf("crypto/acme: failed to set %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete deletes the key.
+func (s *ACMEStorage) Delete(key string) error {
+	if len(key) == 0 {
+		return nil
+	}
+	if err := s.db.ExecWithoutRow(context.Background(), "DELETE FROM acme_storage WHERE name = ?", key); err != nil {
+		return fmt.Errorf("crypto/acme: failed to delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// Reset returns ErrACMEStorageReset, without touching the storage.
+func (s *ACMEStorage) Reset() error {
+	return ErrACMEStorageReset
+}
+
+// Close does nothing, since the database is closed by its owner.
+func (s *ACMEStorage) Close() error {
+	return nil
+}
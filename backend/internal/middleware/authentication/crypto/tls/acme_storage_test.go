@@ -0,0 +1,64 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls_test
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestACMEStorage(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	service := newACMEStorage(t)
+	storage := setupTLS.NewACMEStorage(service)
+
+	if err := storage.Set("acme:account", []byte("key"), 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for _, value := range []string{"cert-1", "cert-2", "cert-2"} { // the same value twice is still a successful Set
+		if err := storage.Set("acme:cert", []byte(value), 0); err != nil {
+			t.Fatalf("Set(%q) error = %v", value, err)
+		}
+	}
+	if got, err := storage.Get("acme:cert"); err != nil || string(got) != "cert-2" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "cert-2")
+	}
+
+	// The challenges expire.
+	if err := storage.Set("acme:challenge", []byte("token"), time.Millisecond); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if got, err := storage.Get("acme:challenge"); err != nil || got != nil {
+		t.Errorf("Get() of an expired key = %q, %v, want nil", got, err)
+	}
+
+	// Neither the shared Redis storage nor the storage itself can reset the account key and the certificate.
+	if err := service.FiberStorage().Reset(); err != nil {
+		t.Fatalf("FiberStorage().Reset() error = %v", err)
+	}
+	if err := storage.Reset(); !errors.Is(err, setupTLS.ErrACMEStorageReset) {
+		t.Errorf("Reset() error = %v, want %v", err, setupTLS.ErrACMEStorageReset)
+	}
+	if err := storage.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	for _, key := range []string{"acme:account", "acme:cert"} {
+		if got, err := storage.Get(key); err != nil || got == nil {
+			t.Errorf("Get(%q) after the resets = %q, %v, want the value", key, got, err)
+		}
+	}
+
+	if err := storage.Delete("acme:cert"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, err := storage.Get("acme:cert"); err != nil || got != nil {
+		t.Errorf("Get() after Delete = %q, %v, want nil", got, err)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"io"
+	stdlog "log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/letsencrypt/pebble/v2/ca"
+	"github.com/letsencrypt/pebble/v2/db"
+	"github.com/letsencrypt/pebble/v2/va"
+	"github.com/letsencrypt/pebble/v2/wfe"
+)
+
+// pebble is a local Pebble ACME server, validating the challenges on the HTTP and TLS listeners.
+type pebble struct {
+	directoryURL string
+	client       *http.Client
+	httpLn       net.Listener
+	tlsLn        net.Listener
+}
+
+// newPebble starts a Pebble ACME server issuing certificates valid for the validity period.
+func newPebble(t *testing.T, validity time.Duration) *pebble {
+	t.Helper()
+	t.Setenv("PEBBLE_VA_NOSLEEP", "1")
+	t.Setenv("PEBBLE_WFE_NONCEREJECT", "0")
+
+	// Note: The listeners are on every address, since "localhost" may resolve to "::1" or "127.0.0.1".
+	httpLn, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { httpLn.Close() })
+	tlsLn, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	t.Cleanup(func() { tlsLn.Close() })
+
+	logger := stdlog.New(io.Discard, "", 0)
+	store := db.NewMemoryStore()
+	authority := ca.New(logger, store, "", "ecdsa", 0, 1, map[string]ca.Profile{
+		"default": {Description: "The default profile", ValidityPeriod: uint64(validity.Seconds())},
+	})
+	validator := va.New(logger, httpLn.Addr().(*net.TCPAddr).Port, tlsLn.Addr().(*net.TCPAddr).Port, false, "", store)
+	frontend := wfe.New(logger, store, validator, authority, []string{"pebble.letsencrypt.org"}, false, false, 0, 0)
+
+	srv := httptest.NewTLSServer(frontend.Handler())
+	t.Cleanup(srv.Close)
+	return &pebble{directoryURL: srv.URL + wfe.DirectoryPath, client: srv.Client(), httpLn: httpLn, tlsLn: tlsLn}
+}
+
+// newACMEStorage returns an in-process database with the ACME table, whose storage and locker are shared by the managers like replicas.
+func newACMEStorage(t *testing.T) database.Service {
+	t.Helper()
+	service, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	if err := setupTLS.CreateACMETable(context.Background(), service); err != nil {
+		t.Fatalf("CreateACMETable() error = %v", err)
+	}
+	return service
+}
+
+func TestACMEManagerHTTP01(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	server := newPebble(t, 90*24*time.Hour)
+	service := newACMEStorage(t)
+	locker := database.NewLocker(service.RedisClient())
+
+	config := setupTLS.ACMEConfig{
+		Hosts:        []string{"localhost"},
+		Storage:      setupTLS.NewACMEStorage(service),
+		Locker:       locker,
+		DirectoryURL: server.directoryURL,
+		HTTPClient:   server.client,
+		Challenges:   []string{setupTLS.ChallengeHTTP01},
+		OrderTimeout: 30 * time.Second,
+	}
+	certs, err := setupTLS.NewACMEManager(config)
+	if err != nil {
+		t.Fatalf("NewACMEManager() error = %v", err)
+	}
+	// The redirect server answers the challenges, and passes the other requests through.
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	go http.Serve(server.httpLn, certs.HTTPHandler(redirect))
+
+	if _, err := certs.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"}); !errors.Is(err, setupTLS.ErrACMENoCertificate) {
+		t.Errorf("GetCertificate() before the order error = %v, want %v", err, setupTLS.ErrACMENoCertificate)
+	}
+
+	// The order waits while another replica holds the lock.
+	lock, err := locker.TryLock(context.Background(), "acme:order:localhost")
+	if err != nil {
+		t.Fatalf("TryLock() error = %v", err)
+	}
+	if err := certs.Renew(context.Background()); !errors.Is(err, setupTLS.ErrACMEOrderInProgress) {
+		t.Errorf("Renew() with the lock held error = %v, want %v", err, setupTLS.ErrACMEOrderInProgress)
+	}
+	lock.Unlock(context.Background())
+
+	if err := certs.Renew(context.Background()); err != nil {
+		t.Fatalf("Renew() error = %v", err)
+	}
+	cert, err := certs.GetCertificate(&tls.ClientHelloInfo{ServerName: "LocalHost"})
+	if err != nil || cert.Leaf.DNSNames[0] != "localhost" {
+		t.Fatalf("GetCertificate() = %v, %v, want the certificate of localhost", cert, err)
+	}
+	if _, err := certs.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"}); !errors.Is(err, setupTLS.ErrACMENoCertificate) {
+		t.Errorf("GetCertificate() of another host error = %v, want %v", err, setupTLS.ErrACMENoCertificate)
+	}
+
+	// Another replica serves the stored certificate, and doesn't order until it's due.
+	replica, err := setupTLS.NewACMEManager(config)
+	if err != nil {
+		t.Fatalf("NewACMEManager() of the replica error = %v", err)
+	}
+	if got := replica.Certificate(); got == nil || got.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) != 0 {
+		t.Fatalf("Certificate() of the replica = %v, want the stored certificate", got)
+	}
+	if err := replica.Renew(context.Background()); err != nil {
+		t.Fatalf("Renew() of the replica error = %v", err)
+	}
+	if got := replica.Certificate(); got.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) != 0 {
+		t.Errorf("Renew() of the replica ordered a certificate before it's due")
+	}
+
+	// A certificate within RenewBefore of its expiry is renewed.
+	config.RenewBefore = 100 * 24 * time.Hour
+	renewing, err := setupTLS.NewACMEManager(config)
+	if err != nil {
+		t.Fatalf("NewACMEManager() error = %v", err)
+	}
+	if err := renewing.Renew(context.Background()); err != nil {
+		t.Fatalf("Renew() of a due certificate error = %v", err)
+	}
+	if got := renewing.Certificate(); got.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) == 0 {
+		t.Error("Renew() of a due certificate kept the previous certificate")
+	}
+
+	// The other requests are redirected.
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get("http://" + server.httpLn.Addr().String() + "/.well-known/acme-challenge/unknown")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of an unknown challenge status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	resp, err = client.Get("http://" + server.httpLn.Addr().String() + "/login")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMovedPermanently {
+		t.Errorf("GET /login status = %d, want %d", resp.StatusCode, http.StatusMovedPermanently)
+	}
+}
+
+func TestACMEManagerTLSALPN01(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	server := newPebble(t, 90*24*time.Hour)
+	service := newACMEStorage(t)
+
+	certs, err := setupTLS.NewACMEManager(setupTLS.ACMEConfig{
+		Hosts:         []string{"localhost"},
+		Storage:       setupTLS.NewACMEStorage(service),
+		DirectoryURL:  server.directoryURL,
+		HTTPClient:    server.client,
+		Challenges:    []string{setupTLS.ChallengeTLSALPN01},
+		OrderTimeout:  30 * time.Second,
+		RetryInterval: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewACMEManager() error = %v", err)
+	}
+	ln := tls.NewListener(server.tlsLn, certs.TLSConfig())
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	// The certificate is ordered in the background.
+	certs.Start()
+	defer certs.Close()
+	deadline := time.Now().Add(30 * time.Second)
+	for certs.Certificate() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Certificate() = nil, want the certificate ordered by Start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// The certificate is served to the clients, once the challenge is over.
+	conn, err := tls.Dial("tcp", server.tlsLn.Addr().String(), &tls.Config{ServerName: "localhost", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("tls.Dial() error = %v", err)
+	}
+	defer conn.Close()
+	if got := conn.ConnectionState().PeerCertificates[0]; got.SerialNumber.Cmp(certs.Certificate().Leaf.SerialNumber) != 0 {
+		t.Errorf("served certificate %v, want the ACME certificate", got.SerialNumber)
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"cmp"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrMissingCertificates is returned by NewCertManager when there is no certificate.
+	ErrMissingCertificates = errors.New("crypto/tls: at least one certificate is required")
+
+	// ErrNoCertificate is returned during the handshake, with CertManagerConfig.StrictSNI,
+	// when no certificate is served for the server name of the client.
+	ErrNoCertificate = errors.New("crypto/tls: no certificate for server name")
+)
+
+// CertificateFiles is a certificate (with its chain) and its private key, in PEM files.
+type CertificateFiles struct {
+	// CertFile is the path to the certificate file, with the intermediate certificates after the leaf.
+	//
+	// Required.
+	CertFile string
+
+	// KeyFile is the path to the private key file.
+	//
+	// Required.
+	KeyFile string
+
+	// Hosts are the server names served with this certificate, which are hosts (e.g., "example.com")
+	// or wildcards of one label (e.g., "*.example.com").
+	//
+	// Optional. Default: the DNS names of the certificate
+	Hosts []string
+}
+
+// CertManagerConfig defines the config for the CertManager.
+type CertManagerConfig struct {
+	// Certificates are the served certificates. The first one is the default, served to the clients
+	// without a server name (e.g., connecting to an IP address) or with an unknown one.
+	//
+	// Required.
+	Certificates []CertificateFiles
+
+	// Hosts are the hosts expected to be served (e.g., the hosts of the domain router). A warning is logged
+	// on every reload for the ones that no certificate covers, since they are served the default certificate.
+	//
+	// Optional. Default: nil
+	Hosts []string
+
+	// RefreshInterval is the interval at which the files are checked for changes (see Start),
+	// so a certificate renewed on the disk (e.g., a Kubernetes secret updated by cert-manager) is picked up without a restart.
+	//
+	// Optional. Default: 1 * time.Minute
+	RefreshInterval time.Duration
+
+	// ExpiryWarning is how long before its expiry a certificate is warned about, at most once a day.
+	//
+	// Optional. Default: 14 * 24 * time.Hour
+	ExpiryWarning time.Duration
+
+	// StrictSNI rejects the handshakes with an unknown server name, instead of serving the default certificate.
+	//
+	// Optional. Default: false
+	StrictSNI bool
+}
+
+// CertificateStatus describes a loaded certificate.
+type CertificateStatus struct {
+	// CertFile is the path to the certificate file.
+	CertFile string
+
+	// Hosts are the server names served with the certificate.
+	Hosts []string
+
+	// NotAfter is the expiry of the certificate.
+	NotAfter time.Time
+}
+
+// CertManager serves certificates loaded from files, chosen by the server name (SNI) of the clients,
+// and reloads them when they change. It's used with [tls.Config.GetCertificate].
+type CertManager struct {
+	cfg   CertManagerConfig
+	certs atomic.Pointer[certSet]
+
+	mu     sync.Mutex           // serializes the reloads
+	mtime  map[string]time.Time // of the loaded files
+	warned map[string]time.Time // last expiry warning, by certificate file
+
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// certSet is a set of loaded certificates, replaced as a whole on reload.
+type certSet struct {
+	fallback  *tls.Certificate
+	byHost    map[string][]*tls.Certificate // by lowercase host or wildcard
+	statuses  []CertificateStatus
+	uncovered []string
+}
+
+// NewCertManager returns a new CertManager with the config, after loading the certificates.
+//
+// Example Usage:
+//
+//	certs, err := tls.NewCertManager(tls.CertManagerConfig{
+//	    Certificates: []tls.CertificateFiles{
+//	        {CertFile: "/etc/tls/example/tls.crt", KeyFile: "/etc/tls/example/tls.key"},
+//	        {CertFile: "/etc/tls/api/tls.crt", KeyFile: "/etc/tls/api/tls.key"},
+//	    },
+//	})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	certs.Start()
+//	defer certs.Close()
+//
+//	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+func NewCertManager(config CertManagerConfig) (*CertManager, error) {
+	if len(config.Certificates) == 0 {
+		return nil, ErrMissingCertificates
+	}
+	config.RefreshInterval = cmp.Or(config.RefreshInterval, time.Minute)
+	config.ExpiryWarning = cmp.Or(config.ExpiryWarning, 14*24*time.Hour)
+
+	m := &CertManager{
+		cfg:    config,
+		mtime:  make(map[string]time.Time),
+		warned: make(map[string]time.Time),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload loads the certificate files, and replaces the certificates atomically when every one is valid.
+// When one isn't (e.g., a secret being updated, with a new certificate and the previous key), the previous certificates are kept.
+func (m *CertManager) Reload() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set, mtime, err := m.load()
+	if err != nil {
+		certificateReloads.WithLabelValues("error").Inc()
+		return err
+	}
+	certificateReloads.WithLabelValues("ok").Inc()
+
+	m.certs.Store(set)
+	m.mtime = mtime
+	for _, host := range set.uncovered {
+		log.LogInfof("No certificate covers the host %s, which is served the default certificate", host)
+	}
+	m.checkExpiry(set)
+	return nil
+}
+
+// load loads the certificate files.
+func (m *CertManager) load() (*certSet, map[string]time.Time, error) {
+	set := &certSet{byHost: make(map[string][]*tls.Certificate)}
+	mtime := make(map[string]time.Time, 2*len(m.cfg.Certificates))
+	for _, files := range m.cfg.Certificates {
+		for _, file := range []string{files.CertFile, files.KeyFile} {
+			info, err := os.Stat(file)
+			if err != nil {
+				return nil, nil, fmt.Errorf("crypto/tls: failed to load certificate: %w", err)
+			}
+			mtime[file] = info.ModTime()
+		}
+
+		cert, err := tls.LoadX509KeyPair(files.CertFile, files.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("crypto/tls: failed to load key pair %s: %w", files.CertFile, err)
+		}
+		if cert.Leaf == nil {
+			if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return nil, nil, fmt.Errorf("crypto/tls: failed to parse certificate %s: %w", files.CertFile, err)
+			}
+		}
+
+		hosts := files.Hosts
+		if len(hosts) == 0 {
+			hosts = cert.Leaf.DNSNames
+		}
+		for _, host := range hosts {
+			host = normalizeHost(host)
+			set.byHost[host] = append(set.byHost[host], &cert)
+		}
+		if set.fallback == nil {
+			set.fallback = &cert
+		}
+		set.statuses = append(set.statuses, CertificateStatus{
+			CertFile: files.CertFile,
+			Hosts:    hosts,
+			NotAfter: cert.Leaf.NotAfter,
+		})
+	}
+
+	for _, host := range m.cfg.Hosts {
+		if host = normalizeHost(host); host != "" && len(set.lookup(host)) == 0 {
+			set.uncovered = append(set.uncovered, host)
+		}
+	}
+	return set, mtime, nil
+}
+
+// checkExpiry updates the expiry metrics, and warns about the certificates expiring soon.
+//
+// Note: It must be called with m.mu held.
+func (m *CertManager) checkExpiry(set *certSet) {
+	now := time.Now()
+	for _, status := range set.statuses {
+		certificateExpiry.WithLabelValues(status.CertFile).Set(float64(status.NotAfter.Unix()))
+
+		left := status.NotAfter.Sub(now)
+		if left > m.cfg.ExpiryWarning || now.Sub(m.warned[status.CertFile]) < 24*time.Hour {
+			continue
+		}
+		m.warned[status.CertFile] = now
+		if left <= 0 {
+			log.LogErrorf("The certificate %s expired at %s", status.CertFile, status.NotAfter.Format(time.RFC3339))
+		} else {
+			log.LogErrorf("The certificate %s expires in %s, at %s", status.CertFile, left.Round(time.Minute), status.NotAfter.Format(time.RFC3339))
+		}
+	}
+}
+
+// GetCertificate returns the certificate for the server name of the client: the one of the exact host,
+// then the one of the wildcard of its parent domain, then the default one (unless CertManagerConfig.StrictSNI).
+// When several certificates are served for the host (e.g., an ECDSA and an RSA one), the first one supported by the client is returned.
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := m.certs.Load()
+	if hello.ServerName == "" {
+		return set.fallback, nil
+	}
+
+	certs := set.lookup(normalizeHost(hello.ServerName))
+	if len(certs) == 0 {
+		if m.cfg.StrictSNI {
+			return nil, fmt.Errorf("%w %q", ErrNoCertificate, hello.ServerName)
+		}
+		return set.fallback, nil
+	}
+	for _, cert := range certs {
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return certs[0], nil
+}
+
+// Certificates returns the status of the loaded certificates, in the order of the config.
+func (m *CertManager) Certificates() []CertificateStatus {
+	return m.certs.Load().statuses
+}
+
+// lookup returns the certificates of the host, or of the wildcard of its parent domain.
+func (s *certSet) lookup(host string) []*tls.Certificate {
+	if certs, ok := s.byHost[host]; ok {
+		return certs
+	}
+	// Note: A wildcard only covers one label (RFC 6125, Section 6.4.3), so "*.example.com"
+	// covers "api.example.com", but neither "example.com" nor "v1.api.example.com".
+	if _, parent, ok := strings.Cut(host, "."); ok && strings.Contains(parent, ".") {
+		return s.byHost["*."+parent]
+	}
+	return nil
+}
+
+// Start checks the files for changes every CertManagerConfig.RefreshInterval in the background, until Close is called,
+// and reloads them when one changed. It also updates the expiry metrics and warnings.
+func (m *CertManager) Start() {
+	if !m.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				if !m.changed() {
+					m.mu.Lock()
+					m.checkExpiry(m.certs.Load())
+					m.mu.Unlock()
+					continue
+				}
+				if err := m.Reload(); err != nil {
+					log.LogErrorf("Failed to reload the certificates, keeping the previous ones: %v", err)
+					continue
+				}
+				log.LogInfo("Reloaded the TLS certificates")
+			}
+		}
+	}()
+}
+
+// Close stops the background refresh started by Start.
+func (m *CertManager) Close() {
+	m.once.Do(func() { close(m.stop) })
+	if m.started.Load() {
+		<-m.done
+	}
+}
+
+// changed reports whether a file changed since it was loaded.
+func (m *CertManager) changed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for file, loaded := range m.mtime {
+		info, err := os.Stat(file)
+		// Note: A file being replaced may briefly be missing, which is a change that fails to load (and is retried).
+		if err != nil || !info.ModTime().Equal(loaded) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost lowercases the host, and strips its port and trailing dot (e.g., "API.Example.com.:443" is "api.example.com").
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeServerCert writes a server certificate of the CA for the DNS names, expiring at notAfter,
+// in the "tls.crt" and "tls.key" files of the directory, like a Kubernetes TLS secret.
+func (ca *testCA) writeServerCert(t *testing.T, dir string, notAfter time.Time, dnsNames ...string) setupTLS.CertificateFiles {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	files := setupTLS.CertificateFiles{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	os.MkdirAll(dir, 0o700)
+	// Note: The files get a later modification time on every write, like a secret updated by Kubernetes.
+	mtime := time.Now().Add(time.Duration(ca.serial) * time.Second)
+	for file, block := range map[string]*pem.Block{
+		files.CertFile: {Type: "CERTIFICATE", Bytes: der},
+		files.KeyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+		os.Chtimes(file, mtime, mtime)
+	}
+	return files
+}
+
+// servedName returns the common name of the certificate served for the server name.
+func servedName(t *testing.T, certs *setupTLS.CertManager, serverName string) (string, error) {
+	t.Helper()
+	cert, err := certs.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:        serverName,
+		SupportedVersions: []uint16{tls.VersionTLS13},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+	})
+	if err != nil {
+		return "", err
+	}
+	return cert.Leaf.Subject.CommonName, nil
+}
+
+func TestCertManagerSNI(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	ca := newTestCA(t, "Gopher Testing CA")
+	dir := t.TempDir()
+	expiry := time.Now().Add(90 * 24 * time.Hour)
+	main := ca.writeServerCert(t, filepath.Join(dir, "main"), expiry, "example.com", "www.example.com")
+	api := ca.writeServerCert(t, filepath.Join(dir, "api"), expiry, "*.api.example.com")
+
+	if _, err := setupTLS.NewCertManager(setupTLS.CertManagerConfig{}); !errors.Is(err, setupTLS.ErrMissingCertificates) {
+		t.Errorf("NewCertManager() without certificates error = %v, want %v", err, setupTLS.ErrMissingCertificates)
+	}
+
+	certs, err := setupTLS.NewCertManager(setupTLS.CertManagerConfig{
+		Certificates: []setupTLS.CertificateFiles{main, api},
+		Hosts:        []string{"example.com:8443", "v1.api.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewCertManager() error = %v", err)
+	}
+	strict, err := setupTLS.NewCertManager(setupTLS.CertManagerConfig{
+		Certificates: []setupTLS.CertificateFiles{main, api},
+		StrictSNI:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewCertManager() with StrictSNI error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		serverName string
+		want       string
+		wantStrict error
+	}{
+		{"exact host", "example.com", "example.com", nil},
+		{"case and trailing dot", "WWW.Example.com.", "example.com", nil},
+		{"wildcard", "v1.api.example.com", "*.api.example.com", nil},
+		{"wildcard covers one label", "a.v1.api.example.com", "example.com", setupTLS.ErrNoCertificate},
+		{"wildcard doesn't cover its parent", "api.example.com", "example.com", setupTLS.ErrNoCertificate},
+		{"unknown host", "example.org", "example.com", setupTLS.ErrNoCertificate},
+		{"no server name", "", "example.com", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := servedName(t, certs, tt.serverName)
+			if err != nil || got != tt.want {
+				t.Errorf("GetCertificate(%q) = %q, %v, want %q", tt.serverName, got, err, tt.want)
+			}
+			got, err = servedName(t, strict, tt.serverName)
+			if tt.wantStrict != nil {
+				if !errors.Is(err, tt.wantStrict) {
+					t.Errorf("GetCertificate(%q) with StrictSNI error = %v, want %v", tt.serverName, err, tt.wantStrict)
+				}
+			} else if err != nil || got != tt.want {
+				t.Errorf("GetCertificate(%q) with StrictSNI = %q, %v, want %q", tt.serverName, got, err, tt.want)
+			}
+		})
+	}
+
+	// The certificate is served in a real handshake, and verified by the client for the server name.
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{GetCertificate: certs.GetCertificate})
+	if err != nil {
+		t.Fatalf("tls.Listen() error = %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{ServerName: "v1.api.example.com", RootCAs: pool})
+	if err != nil {
+		t.Fatalf("tls.Dial() error = %v", err)
+	}
+	conn.Close()
+}
+
+func TestCertManagerReload(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	ca := newTestCA(t, "Gopher Testing CA")
+	dir := filepath.Join(t.TempDir(), "main")
+	first := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+	files := ca.writeServerCert(t, dir, first, "example.com")
+
+	certs, err := setupTLS.NewCertManager(setupTLS.CertManagerConfig{
+		Certificates:    []setupTLS.CertificateFiles{files},
+		RefreshInterval: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewCertManager() error = %v", err)
+	}
+	if got := certs.Certificates()[0].NotAfter; !got.Equal(first) {
+		t.Errorf("Certificates()[0].NotAfter = %v, want %v", got, first)
+	}
+
+	// A broken file keeps the previous certificates.
+	os.WriteFile(files.KeyFile, []byte("not a key"), 0o600)
+	if err := certs.Reload(); err == nil {
+		t.Error("Reload() of a broken key error = nil, want an error")
+	}
+	if got, err := servedName(t, certs, "example.com"); err != nil || got != "example.com" {
+		t.Errorf("GetCertificate() after a failed reload = %q, %v, want the previous certificate", got, err)
+	}
+
+	// A renewed certificate is picked up in the background.
+	certs.Start()
+	defer certs.Close()
+	renewed := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	ca.writeServerCert(t, dir, renewed, "example.com")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !certs.Certificates()[0].NotAfter.Equal(renewed) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Certificates()[0].NotAfter = %v, want the renewed %v", certs.Certificates()[0].NotAfter, renewed)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -5,7 +5,8 @@
 
 // Package tls provides functionality for configuring TLS settings in a Go application.
 // It supports loading TLS certificates and keys from environment variables and optionally
-// configuring mutual TLS (mTLS) by loading CA certificates for client verification.
+// configuring mutual TLS (mTLS) by loading CA certificates for client verification,
+// and a middleware mapping the verified client certificates to principals.
 //
 // This package is designed to be used in conjunction with the h0llyw00dz-template environment
 // configuration, which defines the necessary environment variables for TLS setup.
@@ -15,6 +16,12 @@
 //   - env.SERVERKEYTLS: The path to the server's TLS private key file.
 //   - env.SERVERCATLS: The path to the CA certificate file for mTLS.
 //   - env.ENABLEMTLS: A flag to enable mutual TLS (mTLS) if set to "true".
+//   - env.SERVERCRLTLS: The comma-separated paths to the CRL files of the client certificates (see [LoadRevocation]).
+//   - env.SERVEROCSPTLS: The URL of the OCSP responder of the client certificates (see [LoadRevocation]).
+//   - env.SERVERSNICERTSTLS: The comma-separated directories with a "tls.crt" and a "tls.key", served by SNI (see [CertManager]).
+//   - env.ACMEHOSTS: The comma-separated hosts of a certificate obtained from an ACME server, without TLS_CERT_FILE (see [LoadACME]).
+//   - env.ACMEDIRECTORYURL: The directory URL of the ACME server (default: Let's Encrypt).
+//   - env.ACMEEMAIL: The contact email of the ACME account.
 //
 // Usage:
 //
@@ -29,6 +36,76 @@
 //	}
 //	// Use tlsConfig in your server setup
 //
+// Server Certificates:
+//
+// The server certificates are served by a [CertManager], which chooses them by the server name (SNI) of the clients,
+// so every host of the domain router can have its own certificate, with the first one as the default.
+// It checks the files for changes and reloads them atomically, keeping the previous certificates when the new ones are invalid,
+// so a certificate renewed by cert-manager in a Kubernetes secret is served without a restart.
+// It exports the expiry of the certificates as the "tls_certificate_expiry_timestamp_seconds" metric, and logs when one expires soon:
+//
+//	certs, err := tls.NewCertManager(tls.CertManagerConfig{
+//	    Certificates: []tls.CertificateFiles{
+//	        {CertFile: "/etc/tls/example/tls.crt", KeyFile: "/etc/tls/example/tls.key"},
+//	        {CertFile: "/etc/tls/api/tls.crt", KeyFile: "/etc/tls/api/tls.key"},
+//	    },
+//	    Hosts: []string{"example.com", "api.example.com"},
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to load the certificates: %v", err)
+//	}
+//	certs.Start()
+//	defer certs.Close()
+//	tlsConfig := &tls.Config{GetCertificate: certs.GetCertificate}
+//
+// ACME:
+//
+// Without certificate files, an [ACMEManager] obtains the certificate from an ACME server (RFC 8555), such as Let's Encrypt,
+// with the TLS-ALPN-01 challenge on the TLS listener (see [ACMEManager.TLSConfig]) or the HTTP-01 challenge on the port 80 server
+// (see [ACMEManager.HTTPHandler]), and renews it before it expires. The account key, the certificate and the pending challenges
+// are stored in their own table of the database (see [ACMEStorage], which can't be reset), and the orders hold a distributed lock,
+// so the replicas share one certificate and any of them can answer the challenges:
+//
+//	if err := tls.CreateACMETable(ctx, db); err != nil {
+//	    log.Fatalf("Failed to create the ACME table: %v", err)
+//	}
+//	certs, err := tls.NewACMEManager(tls.ACMEConfig{
+//	    Hosts:   []string{"example.com", "api.example.com"},
+//	    Storage: tls.NewACMEStorage(db),
+//	    Locker:  database.NewLocker(db.RedisClient()),
+//	})
+//	if err != nil {
+//	    log.Fatalf("Failed to configure ACME: %v", err)
+//	}
+//	ln := tls.NewListener(ln, certs.TLSConfig())
+//	redirect := &http.Server{Addr: ":80", Handler: certs.HTTPHandler(redirectHandler)}
+//	certs.Start()
+//	defer certs.Close()
+//
+// Note: The ACME server validates the challenges on the ports 80 and 443 of the hosts, so they must reach this server
+// (e.g., a LoadBalancer service passing TLS through), and the port 80 server must be enabled for HTTP-01.
+//
+// Client Certificates:
+//
+// With mTLS, the TLS handshake verifies the chain of the client certificate against the CAs. The [NewClientAuth] middleware
+// then checks its revocation, with a [CRLChecker] (CRL files, reloaded when they change) and an [OCSPChecker] (a local responder),
+// and maps it to a [Principal] with a [MappingPolicy]: by subject common name, URI SAN, SPIFFE ID or SPKI pin.
+// The principal is stored in the context (see [PrincipalFromContext]), and [RequirePrincipals] restricts a route to an allowlist:
+//
+//	crls, responder, err := tls.LoadRevocation()
+//	if err != nil {
+//	    log.Fatalf("Failed to load the revocation checks: %v", err)
+//	}
+//	internal := app.Group("/internal", tls.NewClientAuth(tls.ClientAuthConfig{
+//	    Mapping: tls.MappingPolicy{Modes: []tls.MappingMode{tls.MapSPIFFEID}, TrustDomains: []string{"example.org"}},
+//	    CRL:     crls,
+//	    OCSP:    responder,
+//	}))
+//	internal.Get("/metrics", tls.RequirePrincipals("spiffe://example.org/ns/monitoring/*"), handleMetrics)
+//
+// Note: The middleware reads the client certificate from the connection, so the TLS must be terminated by this server,
+// not by a proxy in front of it.
+//
 // Error Handling:
 //
 // The package defines ErrorMTLS for handling cases where CA certificates cannot be appended
@@ -0,0 +1,36 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace is the Prometheus namespace of every metric exported by this package.
+const metricsNamespace = "tls"
+
+// Note: These are registered on the default registerer, like the metrics of the database package,
+// so they show up on the same metrics endpoint. They are labeled by the certificate file (or the storage key of the
+// ACME certificate), which is stable across the renewals, unlike the serial number.
+var (
+	// certificateExpiry reports the NotAfter of the loaded certificates, as a Unix timestamp, so an alert
+	// can fire on "tls_certificate_expiry_timestamp_seconds - time() < 7 * 86400".
+	certificateExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "certificate",
+		Name:      "expiry_timestamp_seconds",
+		Help:      "Expiry (NotAfter) of the served certificate, as a Unix timestamp.",
+	}, []string{"file"})
+
+	// certificateReloads counts the reloads of the certificates by result ("ok" or "error").
+	certificateReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "certificate",
+		Name:      "reloads_total",
+		Help:      "Reloads of the served certificates by result (ok, or error when the previous ones were kept).",
+	}, []string{"result"})
+)
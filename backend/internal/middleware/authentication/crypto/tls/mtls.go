@@ -0,0 +1,178 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrClientCertificateRequired is returned when the connection has no verified client certificate
+	// (e.g., a plain HTTP connection, or TLS terminated by a proxy in front of the server).
+	ErrClientCertificateRequired = errors.New("crypto/mtls: verified client certificate required")
+
+	// ErrPrincipalNotAllowed is returned by RequirePrincipals when the principal isn't in the allowlist of the route.
+	ErrPrincipalNotAllowed = errors.New("crypto/mtls: principal not allowed")
+)
+
+// principalContextKey is the key of the Principal in the context.
+const principalContextKey = "mtls_principal"
+
+// ClientAuthConfig defines the config for the client certificate middleware.
+type ClientAuthConfig struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// Mapping maps the client certificates to principals.
+	//
+	// Optional. Default: MappingPolicy{} (the SPIFFE ID, then the subject common name)
+	Mapping MappingPolicy
+
+	// CRL checks the client certificates against CRLs.
+	//
+	// Optional. Default: nil (no CRL check)
+	CRL *CRLChecker
+
+	// OCSP checks the client certificates with an OCSP responder, after the CRLs (if any).
+	//
+	// Optional. Default: nil (no OCSP check)
+	OCSP *OCSPChecker
+
+	// Optional lets the requests without a client certificate through, without a principal
+	// (e.g., with tls.VerifyClientCertIfGiven, for the routes that also accept API keys).
+	//
+	// Optional. Default: false
+	Optional bool
+
+	// ErrorHandler is called when a client certificate is rejected.
+	//
+	// Optional. Default: a 401 Unauthorized (or 403 Forbidden when revoked) problem+json response
+	ErrorHandler fiber.ErrorHandler
+}
+
+// NewClientAuth creates a new middleware handler that maps the verified client certificate of the connection to a [Principal],
+// after checking its revocation, and stores it in the context (see PrincipalFromContext).
+//
+// It expects the chain of the certificate to be verified by the TLS handshake (see LoadConfig with ENABLE_MTLS=true),
+// and only reads it from the connection.
+//
+// Example Usage:
+//
+//	crls, err := tls.NewCRLChecker(tls.CRLConfig{Files: []string{"/etc/tls/crl/clients.crl"}})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	crls.Start()
+//	defer crls.Close()
+//
+//	internal := app.Group("/internal", tls.NewClientAuth(tls.ClientAuthConfig{
+//	    Mapping: tls.MappingPolicy{TrustDomains: []string{"example.org"}},
+//	    CRL:     crls,
+//	}))
+//	internal.Post("/payouts", tls.RequirePrincipals("spiffe://example.org/ns/billing/sa/worker"), handlePayouts)
+func NewClientAuth(config ...ClientAuthConfig) fiber.Handler {
+	var cfg ClientAuthConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = defaultClientAuthErrorHandler
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		principal, err := cfg.authenticate(c)
+		if errors.Is(err, ErrClientCertificateRequired) && cfg.Optional {
+			return c.Next()
+		}
+		if err != nil {
+			log.LogUserActivity(c, "Rejected client certificate: "+err.Error())
+			return cfg.ErrorHandler(c, err)
+		}
+
+		c.Locals(principalContextKey, principal)
+		return c.Next()
+	}
+}
+
+// authenticate maps the verified client certificate of the connection to a principal, after checking its revocation.
+func (cfg *ClientAuthConfig) authenticate(c *fiber.Ctx) (Principal, error) {
+	state := c.Context().TLSConnectionState()
+	// Note: Without verified chains, the certificate (if any) wasn't checked against the CAs (e.g., tls.RequireAnyClientCert).
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Principal{}, ErrClientCertificateRequired
+	}
+	chain := state.VerifiedChains[0]
+	cert := chain[0]
+
+	if cfg.CRL != nil {
+		if err := cfg.CRL.Check(cert); err != nil {
+			return Principal{}, err
+		}
+	}
+	if cfg.OCSP != nil {
+		if len(chain) < 2 {
+			return Principal{}, ErrRevocationUnknown
+		}
+		if err := cfg.OCSP.Check(c.UserContext(), cert, chain[1]); err != nil {
+			return Principal{}, err
+		}
+	}
+
+	return cfg.Mapping.Map(cert)
+}
+
+// defaultClientAuthErrorHandler sends a problem+json response, with a detail that doesn't tell which check failed
+// besides the revocation.
+func defaultClientAuthErrorHandler(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrClientCertificateRequired):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Client certificate required")
+	case errors.Is(err, ErrCertificateRevoked):
+		return helper.SendProblemResponse(c, fiber.StatusForbidden, "Client certificate revoked")
+	case errors.Is(err, ErrNoPrincipal), errors.Is(err, ErrRevocationUnknown):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Client certificate not accepted")
+	default:
+		log.LogErrorf("Unexpected error during client certificate authentication: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// PrincipalFromContext returns the principal stored by NewClientAuth.
+func PrincipalFromContext(c *fiber.Ctx) (Principal, bool) {
+	principal, ok := c.Locals(principalContextKey).(Principal)
+	return principal, ok
+}
+
+// RequirePrincipals is a middleware that only lets through the principals of the allowlist, which are principals
+// in the form of [Principal.String] (e.g., "spiffe://example.org/ns/billing/sa/worker", "cn:admin" or "spki:pinned-device"),
+// or prefixes ending with "*" (e.g., "spiffe://example.org/ns/billing/*"). It answers 401 Unauthorized without a principal,
+// and 403 Forbidden for a principal not in the allowlist.
+//
+// Note: It must run after NewClientAuth.
+func RequirePrincipals(allowed ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFromContext(c)
+		if !ok {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Client certificate required")
+		}
+		if !slices.ContainsFunc(allowed, func(pattern string) bool { return matchPrincipal(pattern, principal) }) {
+			log.LogUserActivity(c, "Rejected principal "+principal.String()+": "+ErrPrincipalNotAllowed.Error())
+			return helper.SendProblemResponse(c, fiber.StatusForbidden, "Principal not allowed")
+		}
+		return c.Next()
+	}
+}
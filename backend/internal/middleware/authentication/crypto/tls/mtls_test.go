@@ -0,0 +1,375 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/ocsp"
+)
+
+// testCA is a private CA issuing the server and client certificates.
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, serial: 1}
+}
+
+// issue issues a certificate, for a client or for the server (with the IP SAN 127.0.0.1).
+func (ca *testCA) issue(t *testing.T, cn string, uris ...string) tls.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	for _, u := range uris {
+		parsed, _ := url.Parse(u)
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCRL writes a CRL of the CA revoking the serial numbers, and returns its path.
+func (ca *testCA) writeCRL(t *testing.T, path string, number int64, revoked ...*big.Int) {
+	t.Helper()
+	list := &x509.RevocationList{
+		Number:     big.NewInt(number),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range revoked {
+		list.RevokedCertificateEntries = append(list.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, list, ca.cert, ca.key)
+	if err != nil {
+		t.Fatalf("CreateRevocationList() error = %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	// Note: The file is replaced atomically, like a Kubernetes volume does, with a later modification time.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	mtime := time.Now().Add(time.Duration(number) * time.Second)
+	os.Chtimes(tmp, mtime, mtime)
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+}
+
+// serve serves the app over mTLS, and returns its URL.
+func serve(t *testing.T, ca *testCA, app *fiber.App) string {
+	t.Helper()
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server")},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    pool,
+	})
+	if err != nil {
+		t.Fatalf("tls.Listen() error = %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return "https://" + ln.Addr().String()
+}
+
+// get sends a GET request with the client certificate (if any), and returns the status and the body.
+func get(t *testing.T, ca *testCA, rawURL string, cert *tls.Certificate) (int, string) {
+	t.Helper()
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: pool}
+	if cert != nil {
+		config.Certificates = []tls.Certificate{*cert}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 5 * time.Second}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", rawURL, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestClientAuth(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	ca := newTestCA(t, "Gopher Testing CA")
+	worker := ca.issue(t, "worker", "spiffe://example.org/ns/billing/sa/worker")
+	admin := ca.issue(t, "admin")
+	revoked := ca.issue(t, "revoked")
+	pinned := ca.issue(t, "")
+	impostor := ca.issue(t, "spiffe://example.org/ns/billing/sa/worker")
+
+	crlFile := filepath.Join(t.TempDir(), "clients.crl")
+	ca.writeCRL(t, crlFile, 1, revoked.Leaf.SerialNumber)
+	crls, err := setupTLS.NewCRLChecker(setupTLS.CRLConfig{
+		Files:           []string{crlFile},
+		CAs:             []*x509.Certificate{ca.cert},
+		RefreshInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewCRLChecker() error = %v", err)
+	}
+	crls.Start()
+	t.Cleanup(crls.Close)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Use(setupTLS.NewClientAuth(setupTLS.ClientAuthConfig{
+		Mapping: setupTLS.MappingPolicy{
+			Modes:        []setupTLS.MappingMode{setupTLS.MapSPIFFEID, setupTLS.MapSPKIPin, setupTLS.MapSubjectCN},
+			TrustDomains: []string{"example.org"},
+			Pins:         map[string]string{setupTLS.SPKIPin(pinned.Leaf): "pinned-device"},
+		},
+		CRL: crls,
+	}))
+	app.Get("/whoami", func(c *fiber.Ctx) error {
+		principal, _ := setupTLS.PrincipalFromContext(c)
+		return c.SendString(string(principal.Mode) + ":" + principal.Name)
+	})
+	app.Get("/billing", setupTLS.RequirePrincipals("spiffe://example.org/ns/billing/*"), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	app.Get("/admin", setupTLS.RequirePrincipals("cn:admin"), func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+	base := serve(t, ca, app)
+
+	tests := []struct {
+		name       string
+		path       string
+		cert       *tls.Certificate
+		wantStatus int
+		wantBody   string
+	}{
+		{"SPIFFE ID", "/whoami", &worker, fiber.StatusOK, "spiffe:spiffe://example.org/ns/billing/sa/worker"},
+		{"Subject CN", "/whoami", &admin, fiber.StatusOK, "cn:admin"},
+		{"SPKI pin", "/whoami", &pinned, fiber.StatusOK, "spki:pinned-device"},
+		{"No certificate", "/whoami", nil, fiber.StatusUnauthorized, ""},
+		{"Revoked", "/whoami", &revoked, fiber.StatusForbidden, ""},
+		{"Allowed principal", "/billing", &worker, fiber.StatusOK, "ok"},
+		{"Principal not allowed", "/billing", &admin, fiber.StatusForbidden, ""},
+		{"Subject CN that looks like a SPIFFE ID", "/billing", &impostor, fiber.StatusForbidden, ""},
+		{"Allowed subject CN", "/admin", &admin, fiber.StatusOK, "ok"},
+		{"SPIFFE ID not allowed by subject CN", "/admin", &worker, fiber.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get(t, ca, base+tt.path, tt.cert)
+			if status != tt.wantStatus || (tt.wantBody != "" && body != tt.wantBody) {
+				t.Errorf("GET %s = %d %q, want %d %q", tt.path, status, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+
+	// A renewed CRL is picked up without a restart.
+	ca.writeCRL(t, crlFile, 2, revoked.Leaf.SerialNumber, admin.Leaf.SerialNumber)
+	deadline := time.Now().Add(5 * time.Second)
+	for !errors.Is(crls.Check(admin.Leaf), setupTLS.ErrCertificateRevoked) {
+		if time.Now().After(deadline) {
+			t.Fatal("CRLChecker didn't reload the renewed CRL after 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status, _ := get(t, ca, base+"/whoami", &admin); status != fiber.StatusForbidden {
+		t.Errorf("GET /whoami with a certificate revoked by the renewed CRL = %d, want %d", status, fiber.StatusForbidden)
+	}
+}
+
+func TestCRLChecker(t *testing.T) {
+	ca := newTestCA(t, "Gopher Testing CA")
+	other := newTestCA(t, "Other CA")
+	cert := ca.issue(t, "client")
+
+	dir := t.TempDir()
+	crlFile := filepath.Join(dir, "clients.crl")
+	ca.writeCRL(t, crlFile, 1)
+
+	// A CRL that isn't signed by one of the CAs is rejected.
+	if _, err := setupTLS.NewCRLChecker(setupTLS.CRLConfig{Files: []string{crlFile}, CAs: []*x509.Certificate{other.cert}}); err == nil {
+		t.Error("NewCRLChecker() with a CRL of another CA error = nil, want an error")
+	}
+	if _, err := setupTLS.NewCRLChecker(setupTLS.CRLConfig{}); !errors.Is(err, setupTLS.ErrMissingCRLFiles) {
+		t.Errorf("NewCRLChecker() without files error = %v, want %v", err, setupTLS.ErrMissingCRLFiles)
+	}
+
+	crls, err := setupTLS.NewCRLChecker(setupTLS.CRLConfig{Files: []string{crlFile}, CAs: []*x509.Certificate{ca.cert, other.cert}})
+	if err != nil {
+		t.Fatalf("NewCRLChecker() error = %v", err)
+	}
+	if err := crls.Check(cert.Leaf); err != nil {
+		t.Errorf("Check() of a valid certificate error = %v", err)
+	}
+	// A certificate whose issuer has no CRL can't be checked.
+	if err := crls.Check(other.issue(t, "client").Leaf); !errors.Is(err, setupTLS.ErrRevocationUnknown) {
+		t.Errorf("Check() without a CRL of the issuer error = %v, want %v", err, setupTLS.ErrRevocationUnknown)
+	}
+
+	// A broken file keeps the previous CRLs.
+	os.WriteFile(crlFile, []byte("not a CRL"), 0o600)
+	if err := crls.Reload(); err == nil {
+		t.Error("Reload() of a broken file error = nil, want an error")
+	}
+	if err := crls.Check(cert.Leaf); err != nil {
+		t.Errorf("Check() after a failed reload error = %v, want the previous CRL", err)
+	}
+}
+
+func TestOCSPChecker(t *testing.T) {
+	ca := newTestCA(t, "Gopher Testing CA")
+	good := ca.issue(t, "good")
+	revoked := ca.issue(t, "revoked")
+
+	var requests int
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := io.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		template := ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+		}
+		if req.SerialNumber.Cmp(revoked.Leaf.SerialNumber) == 0 {
+			template.Status = ocsp.Revoked
+			template.RevokedAt = time.Now().Add(-time.Minute)
+		}
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, template, crypto.Signer(ca.key))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	t.Cleanup(responder.Close)
+
+	checker, err := setupTLS.NewOCSPChecker(setupTLS.OCSPConfig{ResponderURL: responder.URL})
+	if err != nil {
+		t.Fatalf("NewOCSPChecker() error = %v", err)
+	}
+	if err := checker.Check(t.Context(), good.Leaf, ca.cert); err != nil {
+		t.Errorf("Check() of a good certificate error = %v", err)
+	}
+	if err := checker.Check(t.Context(), good.Leaf, ca.cert); err != nil || requests != 1 {
+		t.Errorf("Check() again = %v after %d requests, want the cached response", err, requests)
+	}
+	if err := checker.Check(t.Context(), revoked.Leaf, ca.cert); !errors.Is(err, setupTLS.ErrCertificateRevoked) {
+		t.Errorf("Check() of a revoked certificate error = %v, want %v", err, setupTLS.ErrCertificateRevoked)
+	}
+
+	// A responder that can't be reached fails closed, unless FailOpen.
+	down, _ := setupTLS.NewOCSPChecker(setupTLS.OCSPConfig{ResponderURL: "http://127.0.0.1:1"})
+	if err := down.Check(t.Context(), good.Leaf, ca.cert); !errors.Is(err, setupTLS.ErrRevocationUnknown) {
+		t.Errorf("Check() with the responder down error = %v, want %v", err, setupTLS.ErrRevocationUnknown)
+	}
+	down, _ = setupTLS.NewOCSPChecker(setupTLS.OCSPConfig{ResponderURL: "http://127.0.0.1:1", FailOpen: true})
+	if err := down.Check(t.Context(), good.Leaf, ca.cert); err != nil {
+		t.Errorf("Check() with the responder down and FailOpen error = %v, want nil", err)
+	}
+}
+
+func TestMappingPolicy(t *testing.T) {
+	ca := newTestCA(t, "Gopher Testing CA")
+	tests := []struct {
+		name     string
+		policy   setupTLS.MappingPolicy
+		cert     tls.Certificate
+		wantName string
+		wantErr  bool
+	}{
+		{"Default prefers the SPIFFE ID", setupTLS.MappingPolicy{}, ca.issue(t, "svc", "spiffe://example.org/svc"), "spiffe://example.org/svc", false},
+		{"Default falls back to the CN", setupTLS.MappingPolicy{}, ca.issue(t, "svc"), "svc", false},
+		{"Untrusted SPIFFE domain", setupTLS.MappingPolicy{Modes: []setupTLS.MappingMode{setupTLS.MapSPIFFEID}, TrustDomains: []string{"example.org"}},
+			ca.issue(t, "svc", "spiffe://evil.example/svc"), "", true},
+		{"Untrusted SPIFFE domain doesn't fall back to the CN", setupTLS.MappingPolicy{
+			Modes:        []setupTLS.MappingMode{setupTLS.MapSPIFFEID, setupTLS.MapSubjectCN},
+			TrustDomains: []string{"example.org"},
+		}, ca.issue(t, "svc", "spiffe://evil.example/svc"), "", true},
+		{"Default with trust domains requires the SPIFFE ID", setupTLS.MappingPolicy{TrustDomains: []string{"example.org"}},
+			ca.issue(t, "spiffe://example.org/svc"), "", true},
+		{"Several URIs aren't an SVID", setupTLS.MappingPolicy{Modes: []setupTLS.MappingMode{setupTLS.MapSPIFFEID}},
+			ca.issue(t, "svc", "spiffe://example.org/a", "spiffe://example.org/b"), "", true},
+		{"URI SAN", setupTLS.MappingPolicy{Modes: []setupTLS.MappingMode{setupTLS.MapURISAN}},
+			ca.issue(t, "svc", "spiffe://example.org/svc", "https://svc.example.org"), "https://svc.example.org", false},
+		{"Unknown pin", setupTLS.MappingPolicy{Modes: []setupTLS.MappingMode{setupTLS.MapSPKIPin}}, ca.issue(t, "svc"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := tt.policy.Map(tt.cert.Leaf)
+			if (err != nil) != tt.wantErr || principal.Name != tt.wantName {
+				t.Errorf("Map() = %q, %v, want %q (error: %v)", principal.Name, err, tt.wantName, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrNoPrincipal is returned when the client certificate maps to no principal with the mapping policy.
+var ErrNoPrincipal = errors.New("crypto/mtls: client certificate maps to no principal")
+
+// MappingMode is a way of mapping a client certificate to a principal.
+type MappingMode string
+
+const (
+	// MapSubjectCN maps a certificate to its subject common name.
+	MapSubjectCN MappingMode = "cn"
+
+	// MapURISAN maps a certificate to its first URI subject alternative name that isn't a SPIFFE ID.
+	MapURISAN MappingMode = "uri"
+
+	// MapSPIFFEID maps a certificate to its SPIFFE ID (the URI SAN "spiffe://trust-domain/path"),
+	// as issued by SPIRE or a service mesh (e.g., Istio).
+	MapSPIFFEID MappingMode = "spiffe"
+
+	// MapSPKIPin maps a certificate to the name of its pinned public key (see MappingPolicy.Pins).
+	MapSPKIPin MappingMode = "spki"
+)
+
+// MappingPolicy defines how the client certificates are mapped to principals.
+type MappingPolicy struct {
+	// Modes are the mapping modes, tried in order: the first one that maps the certificate wins.
+	//
+	// Note: A certificate with a URI SAN that MapSPIFFEID doesn't map (e.g., a SPIFFE ID of another trust domain)
+	// never falls back to MapSubjectCN, since its common name could be anything the issuer allowed.
+	//
+	// Optional. Default: []MappingMode{MapSPIFFEID, MapSubjectCN}, or []MappingMode{MapSPIFFEID} when TrustDomains is set
+	Modes []MappingMode
+
+	// TrustDomains are the SPIFFE trust domains accepted by MapSPIFFEID (e.g., "example.org").
+	//
+	// Optional. Default: any trust domain
+	TrustDomains []string
+
+	// Pins maps the SPKI pins (the base64 of the SHA-256 of the public key, like in HPKP) to principal names, for MapSPKIPin.
+	//
+	// Optional.
+	Pins map[string]string
+}
+
+// Principal is the identity of a client, mapped from its verified certificate.
+type Principal struct {
+	// Name is the subject common name, the URI, the SPIFFE ID or the name of the pin, depending on Mode.
+	Name string
+
+	// Mode is the mapping mode that mapped the certificate.
+	Mode MappingMode
+
+	// Certificate is the client certificate.
+	Certificate *x509.Certificate
+}
+
+// SPKIPin returns the SPKI pin of the certificate, which is the base64 of the SHA-256 of its public key.
+//
+// The pin of a certificate can be computed with:
+//
+//	openssl x509 -in client.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Map maps the certificate to a principal, with the first mode that maps it, or returns ErrNoPrincipal.
+func (p MappingPolicy) Map(cert *x509.Certificate) (Principal, error) {
+	modes := p.Modes
+	if len(modes) == 0 {
+		modes = []MappingMode{MapSPIFFEID, MapSubjectCN}
+		if len(p.TrustDomains) > 0 {
+			modes = modes[:1]
+		}
+	}
+
+	spiffeRejected := false
+	for _, mode := range modes {
+		if mode == MapSubjectCN && spiffeRejected {
+			continue
+		}
+		if name, ok := p.mapWith(mode, cert); ok {
+			return Principal{Name: name, Mode: mode, Certificate: cert}, nil
+		}
+		if mode == MapSPIFFEID && len(cert.URIs) > 0 {
+			spiffeRejected = true
+		}
+	}
+	return Principal{}, ErrNoPrincipal
+}
+
+// mapWith maps the certificate with the mode.
+func (p MappingPolicy) mapWith(mode MappingMode, cert *x509.Certificate) (string, bool) {
+	switch mode {
+	case MapSubjectCN:
+		return cert.Subject.CommonName, cert.Subject.CommonName != ""
+	case MapURISAN:
+		for _, u := range cert.URIs {
+			if u.Scheme != "spiffe" {
+				return u.String(), true
+			}
+		}
+	case MapSPIFFEID:
+		// Note: A SPIFFE X.509-SVID has exactly one URI SAN (SPIFFE X509-SVID specification, Section 2),
+		// so a certificate with several of them isn't an SVID.
+		if len(cert.URIs) != 1 {
+			return "", false
+		}
+		u := cert.URIs[0]
+		if u.Scheme != "spiffe" || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+			return "", false
+		}
+		if len(p.TrustDomains) > 0 && !slices.Contains(p.TrustDomains, strings.ToLower(u.Host)) {
+			return "", false
+		}
+		return u.String(), true
+	case MapSPKIPin:
+		name, ok := p.Pins[SPKIPin(cert)]
+		return name, ok
+	}
+	return "", false
+}
+
+// String returns the principal as "mode:name" (e.g., "cn:admin" or "spki:pinned-device"), or as the SPIFFE ID itself
+// for MapSPIFFEID, since its scheme already is the name of the mode (e.g., "spiffe://example.org/ns/billing/sa/worker").
+func (p Principal) String() string {
+	if p.Mode == MapSPIFFEID {
+		return p.Name
+	}
+	return string(p.Mode) + ":" + p.Name
+}
+
+// matchPrincipal reports whether the principal matches the pattern, which is either a principal in the form of [Principal.String],
+// or a prefix ending with "*" (e.g., "spiffe://example.org/ns/payments/*").
+//
+// Note: The mode is part of the match, so a subject common name of "spiffe://example.org/ns/payments/worker"
+// doesn't match the SPIFFE ID pattern "spiffe://example.org/ns/payments/*".
+func matchPrincipal(pattern string, principal Principal) bool {
+	name := principal.String()
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(name, prefix)
+	}
+	return pattern == name
+}
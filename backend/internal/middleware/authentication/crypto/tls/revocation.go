@@ -0,0 +1,409 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package tls
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	// ErrCertificateRevoked is returned when the client certificate is revoked, by a CRL or by the OCSP responder.
+	ErrCertificateRevoked = errors.New("crypto/mtls: client certificate revoked")
+
+	// ErrRevocationUnknown is returned when the revocation of the client certificate can't be checked
+	// (e.g., no CRL of its issuer, an expired CRL, or an unreachable OCSP responder).
+	ErrRevocationUnknown = errors.New("crypto/mtls: revocation status of client certificate unknown")
+
+	// ErrMissingCRLFiles is returned by NewCRLChecker when there is no CRL file.
+	ErrMissingCRLFiles = errors.New("crypto/mtls: at least one CRL file is required")
+
+	// ErrMissingOCSPResponder is returned by NewOCSPChecker when there is no responder URL.
+	ErrMissingOCSPResponder = errors.New("crypto/mtls: OCSP responder URL is required")
+)
+
+// CRLConfig defines the config for the CRLChecker.
+type CRLConfig struct {
+	// Files are the CRL files (PEM or DER), one per CA issuing the client certificates.
+	//
+	// Required.
+	Files []string
+
+	// CAs are the CAs whose signature is checked on the CRLs.
+	//
+	// Optional. Default: the CA certificates of TLS_CA_FILE
+	CAs []*x509.Certificate
+
+	// RefreshInterval is the interval at which the files are checked for changes (see Start),
+	// so a CRL renewed on the disk (e.g., a Kubernetes secret or config map) is picked up without a restart.
+	//
+	// Optional. Default: 1 * time.Minute
+	RefreshInterval time.Duration
+
+	// AllowExpired accepts the certificates checked against a CRL past its NextUpdate, instead of returning ErrRevocationUnknown.
+	//
+	// Optional. Default: false
+	AllowExpired bool
+}
+
+// CRLChecker checks the client certificates against CRLs loaded from files, and reloads them when they change.
+//
+// Note: Like the CRL distribution points in browsers, the CRLs aren't fetched from the network. They are expected
+// to be written by a job of the CA (e.g., a CronJob of step-ca or Vault PKI) on a shared volume.
+type CRLChecker struct {
+	cfg  CRLConfig
+	crls atomic.Pointer[map[string]*crl] // by raw issuer
+
+	mu    sync.Mutex           // serializes the reloads
+	mtime map[string]time.Time // of the loaded files
+
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// crl is a loaded CRL.
+type crl struct {
+	revoked    map[string]struct{} // serial numbers, in hex
+	nextUpdate time.Time
+}
+
+// NewCRLChecker returns a new CRLChecker with the config, after loading the CRL files.
+//
+// Example Usage:
+//
+//	crls, err := tls.NewCRLChecker(tls.CRLConfig{Files: []string{"/etc/tls/crl/clients.crl"}})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	crls.Start()
+//	defer crls.Close()
+func NewCRLChecker(config CRLConfig) (*CRLChecker, error) {
+	if len(config.Files) == 0 {
+		return nil, ErrMissingCRLFiles
+	}
+	if len(config.CAs) == 0 {
+		cas, err := loadCACertificates()
+		if err != nil {
+			return nil, err
+		}
+		config.CAs = cas
+	}
+	config.RefreshInterval = cmp.Or(config.RefreshInterval, time.Minute)
+
+	c := &CRLChecker{
+		cfg:   config,
+		mtime: make(map[string]time.Time),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload loads the CRL files, and replaces the CRLs atomically when every one is valid.
+// When one isn't (e.g., a file being written), the previous CRLs are kept.
+func (c *CRLChecker) Reload() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	crls := make(map[string]*crl, len(c.cfg.Files))
+	mtime := make(map[string]time.Time, len(c.cfg.Files))
+	for _, file := range c.cfg.Files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("crypto/mtls: failed to load CRL: %w", err)
+		}
+		issuer, loaded, err := c.load(file)
+		if err != nil {
+			return fmt.Errorf("crypto/mtls: failed to load CRL %s: %w", file, err)
+		}
+		crls[issuer] = loaded
+		mtime[file] = info.ModTime()
+	}
+
+	c.crls.Store(&crls)
+	c.mtime = mtime
+	return nil
+}
+
+// load parses the CRL file, and checks its signature against the CA that issued it.
+func (c *CRLChecker) load(file string) (string, *crl, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return "", nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+		}
+		data = block.Bytes
+	}
+
+	list, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var issuer *x509.Certificate
+	for _, ca := range c.cfg.CAs {
+		if bytes.Equal(ca.RawSubject, list.RawIssuer) {
+			issuer = ca
+			break
+		}
+	}
+	if issuer == nil {
+		return "", nil, errors.New("no CA for the issuer of the CRL")
+	}
+	if err := list.CheckSignatureFrom(issuer); err != nil {
+		return "", nil, err
+	}
+
+	loaded := &crl{
+		revoked:    make(map[string]struct{}, len(list.RevokedCertificateEntries)),
+		nextUpdate: list.NextUpdate,
+	}
+	for _, entry := range list.RevokedCertificateEntries {
+		loaded.revoked[entry.SerialNumber.Text(16)] = struct{}{}
+	}
+	return string(list.RawIssuer), loaded, nil
+}
+
+// Check returns ErrCertificateRevoked when the certificate is in the CRL of its issuer,
+// or ErrRevocationUnknown when its issuer has no CRL, or an expired one (unless CRLConfig.AllowExpired).
+func (c *CRLChecker) Check(cert *x509.Certificate) error {
+	loaded, ok := (*c.crls.Load())[string(cert.RawIssuer)]
+	if !ok {
+		return fmt.Errorf("%w: no CRL of issuer %q", ErrRevocationUnknown, cert.Issuer)
+	}
+	if _, revoked := loaded.revoked[cert.SerialNumber.Text(16)]; revoked {
+		return ErrCertificateRevoked
+	}
+	if !c.cfg.AllowExpired && !loaded.nextUpdate.IsZero() && time.Now().After(loaded.nextUpdate) {
+		return fmt.Errorf("%w: CRL of issuer %q expired", ErrRevocationUnknown, cert.Issuer)
+	}
+	return nil
+}
+
+// Start checks the files for changes every CRLConfig.RefreshInterval in the background, until Close is called,
+// and reloads them when one changed.
+func (c *CRLChecker) Start() {
+	if !c.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				if !c.changed() {
+					continue
+				}
+				if err := c.Reload(); err != nil {
+					log.LogErrorf("Failed to reload the CRLs, keeping the previous ones: %v", err)
+					continue
+				}
+				log.LogInfo("Reloaded the CRLs of the client certificates")
+			}
+		}
+	}()
+}
+
+// Close stops the background refresh started by Start.
+func (c *CRLChecker) Close() {
+	c.once.Do(func() { close(c.stop) })
+	if c.started.Load() {
+		<-c.done
+	}
+}
+
+// changed reports whether a file changed since it was loaded.
+func (c *CRLChecker) changed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, file := range c.cfg.Files {
+		info, err := os.Stat(file)
+		// Note: A file being replaced may briefly be missing, which is a change that fails to load (and is retried).
+		if err != nil || !info.ModTime().Equal(c.mtime[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// OCSPConfig defines the config for the OCSPChecker.
+type OCSPConfig struct {
+	// ResponderURL is the URL of the OCSP responder (e.g., the one of a private CA in the cluster).
+	// It's used for every certificate, whatever its AIA extension says, so the clients can't point to a responder of their own.
+	//
+	// Required.
+	ResponderURL string
+
+	// Timeout limits each request to the responder.
+	//
+	// Optional. Default: 2 * time.Second
+	Timeout time.Duration
+
+	// CacheTTL is how long a response is cached when it has no NextUpdate. Otherwise, it's cached until NextUpdate.
+	//
+	// Optional. Default: 5 * time.Minute
+	CacheTTL time.Duration
+
+	// FailOpen accepts the certificates when the responder can't be reached or answers "unknown",
+	// instead of returning ErrRevocationUnknown.
+	//
+	// Optional. Default: false
+	FailOpen bool
+
+	// Client is the HTTP client of the requests to the responder.
+	//
+	// Optional. Default: http.DefaultClient
+	Client *http.Client
+}
+
+// OCSPChecker checks the client certificates with an OCSP responder, and caches the responses.
+type OCSPChecker struct {
+	cfg OCSPConfig
+
+	mu    sync.Mutex
+	cache map[string]ocspEntry // by raw issuer and serial number
+}
+
+// ocspEntry is a cached OCSP response.
+type ocspEntry struct {
+	status  int
+	expires time.Time
+}
+
+// NewOCSPChecker returns a new OCSPChecker with the config.
+func NewOCSPChecker(config OCSPConfig) (*OCSPChecker, error) {
+	if config.ResponderURL == "" {
+		return nil, ErrMissingOCSPResponder
+	}
+	config.Timeout = cmp.Or(config.Timeout, 2*time.Second)
+	config.CacheTTL = cmp.Or(config.CacheTTL, 5*time.Minute)
+	config.Client = cmp.Or(config.Client, http.DefaultClient)
+	return &OCSPChecker{cfg: config, cache: make(map[string]ocspEntry)}, nil
+}
+
+// Check asks the responder for the status of the certificate, issued by issuer. It returns ErrCertificateRevoked
+// when it's revoked, or ErrRevocationUnknown when the responder can't tell (unless OCSPConfig.FailOpen).
+func (o *OCSPChecker) Check(ctx context.Context, cert, issuer *x509.Certificate) error {
+	key := string(cert.RawIssuer) + "/" + cert.SerialNumber.Text(16)
+	o.mu.Lock()
+	entry, ok := o.cache[key]
+	o.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		resp, err := o.query(ctx, cert, issuer)
+		if err != nil {
+			if o.cfg.FailOpen {
+				log.LogErrorf("OCSP check of the client certificate %s failed, accepting it: %v", cert.SerialNumber.Text(16), err)
+				return nil
+			}
+			return fmt.Errorf("%w: %w", ErrRevocationUnknown, err)
+		}
+
+		entry = ocspEntry{status: resp.Status, expires: time.Now().Add(o.cfg.CacheTTL)}
+		if !resp.NextUpdate.IsZero() {
+			entry.expires = resp.NextUpdate
+		}
+		o.mu.Lock()
+		o.cache[key] = entry
+		o.mu.Unlock()
+	}
+
+	switch entry.status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return ErrCertificateRevoked
+	default:
+		if o.cfg.FailOpen {
+			return nil
+		}
+		return fmt.Errorf("%w: OCSP status unknown", ErrRevocationUnknown)
+	}
+}
+
+// query sends an OCSP request for the certificate, and returns the response, once its signature is checked against the issuer.
+func (o *OCSPChecker) query(ctx context.Context, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	body, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.ResponderURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+
+	resp, err := o.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder answered %s", resp.Status)
+	}
+	der, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(der, cert, issuer)
+}
+
+// loadCACertificates loads the CA certificates of TLS_CA_FILE.
+func loadCACertificates() ([]*x509.Certificate, error) {
+	if caCertFile == "" {
+		return nil, ErrorMTLSCAEmpty
+	}
+	data, err := os.ReadFile(caCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading CA certificates: %w", err)
+	}
+
+	var cas []*x509.Certificate
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		ca, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error loading CA certificates: %w", err)
+		}
+		cas = append(cas, ca)
+	}
+	if len(cas) == 0 {
+		return nil, ErrorMTLS
+	}
+	return cas, nil
+}
@@ -6,12 +6,16 @@
 package tls
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
 	"h0llyw00dz-template/env"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var (
@@ -31,6 +35,28 @@ var (
 
 	// caCertFile holds the path to the CA certificate file.
 	caCertFile = env.GetEnv(env.SERVERCATLS, "")
+
+	// crlFiles holds the comma-separated paths to the CRL files of the client certificates.
+	crlFiles = env.GetEnv(env.SERVERCRLTLS, "")
+
+	// ocspResponder holds the URL of the OCSP responder of the client certificates.
+	ocspResponder = env.GetEnv(env.SERVEROCSPTLS, "")
+
+	// sniCertDirs holds the comma-separated directories of the certificates served by SNI.
+	sniCertDirs = env.GetEnv(env.SERVERSNICERTSTLS, "")
+
+	// acmeHosts holds the comma-separated hosts of the certificate obtained from the ACME server.
+	acmeHosts = env.GetEnv(env.ACMEHOSTS, "")
+
+	// acmeDirectoryURL holds the directory URL of the ACME server.
+	acmeDirectoryURL = env.GetEnv(env.ACMEDIRECTORYURL, "")
+
+	// acmeEmail holds the contact email of the ACME account.
+	acmeEmail = env.GetEnv(env.ACMEEMAIL, "")
+
+	// routerHosts holds the hosts of the domain router (see backend/internal/middleware/routes.go),
+	// which are expected to be covered by a certificate.
+	routerHosts = []string{env.GetEnv(env.DOMAIN, ""), env.GetEnv(env.APISUBDOMAIN, "")}
 )
 
 var (
@@ -40,15 +66,21 @@ var (
 
 // LoadConfig loads TLS configuration based on environment variables.
 //
+// The certificates are served by a [CertManager]: the one of TLS_CERT_FILE and TLS_KEY_FILE by default,
+// and the ones of TLS_SNI_CERT_DIRS by SNI. They are reloaded when their files change, so a renewal doesn't need a restart.
+//
 // TODO: Implement an explicit crash if the HTTPS/TLS certificate is not a wildcard.
 // This ensures effectiveness, especially in quantum advances later.
 func LoadConfig() (*tls.Config, error) {
 	if tlsCertFile != "" && tlsKeyFile != "" {
 		// Note: Fiber uses ECC is significantly faster compared to Nginx uses ECC, which struggles to handle a billion concurrent requests.
-		cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		certs, err := loadCertManager()
 		if err != nil {
-			return nil, fmt.Errorf("failed to load key pair: %w", err)
+			return nil, err
 		}
+		// Note: The certificates are watched as long as the process runs, since the server
+		// only stops on shutdown, so the manager is never closed.
+		certs.Start()
 
 		// Note: For ECC the OCSP, it's optional if explicitly set to TLSv1.3 and used in internal mode.
 		// However, if it's used externally and allows TLSv1.2, then OCSP should be configured, provided that
@@ -68,7 +100,7 @@ func LoadConfig() (*tls.Config, error) {
 		// If your cluster has any Kubernetes network mechanism that doesn't work with these configurations (e.g., nginx.ingress.kubernetes.io/backend-protocol: HTTPS, enable-ocsp),
 		// then there may be an issue with your Kubernetes network configuration.
 		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
+			GetCertificate: certs.GetCertificate,
 		}
 
 		// This boolean is determined by the environment variable ENABLE_MTLS using env.GetEnv, which performs a lookup.
@@ -90,6 +122,24 @@ func LoadConfig() (*tls.Config, error) {
 	return nil, nil
 }
 
+// loadCertManager returns a CertManager serving the certificate of TLS_CERT_FILE and TLS_KEY_FILE by default,
+// and the ones of the directories of TLS_SNI_CERT_DIRS by SNI.
+func loadCertManager() (*CertManager, error) {
+	config := CertManagerConfig{
+		Certificates: []CertificateFiles{{CertFile: tlsCertFile, KeyFile: tlsKeyFile}},
+		Hosts:        routerHosts,
+	}
+	for dir := range strings.SplitSeq(sniCertDirs, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			config.Certificates = append(config.Certificates, CertificateFiles{
+				CertFile: filepath.Join(dir, "tls.crt"),
+				KeyFile:  filepath.Join(dir, "tls.key"),
+			})
+		}
+	}
+	return NewCertManager(config)
+}
+
 // loadCA loads the CA certificates for client verification.
 func loadCA() (*x509.CertPool, error) {
 	if caCertFile == "" {
@@ -107,3 +157,75 @@ func loadCA() (*x509.CertPool, error) {
 
 	return caCertPool, nil
 }
+
+// LoadRevocation returns the revocation checks of the client certificates configured by the environment variables:
+// a CRLChecker of TLS_CRL_FILES, and an OCSPChecker of TLS_OCSP_RESPONDER. Each one is nil when its variable isn't set.
+//
+// The CRLChecker is started, so it must be closed on shutdown.
+//
+// Example Usage:
+//
+//	crls, responder, err := tls.LoadRevocation()
+//	if err != nil {
+//	    log.Fatalf("Failed to load the revocation checks: %v", err)
+//	}
+//	if crls != nil {
+//	    defer crls.Close()
+//	}
+//	app.Use("/internal", tls.NewClientAuth(tls.ClientAuthConfig{CRL: crls, OCSP: responder}))
+func LoadRevocation() (*CRLChecker, *OCSPChecker, error) {
+	var (
+		crls      *CRLChecker
+		responder *OCSPChecker
+		err       error
+	)
+	if crlFiles != "" {
+		var files []string
+		for file := range strings.SplitSeq(crlFiles, ",") {
+			if file = strings.TrimSpace(file); file != "" {
+				files = append(files, file)
+			}
+		}
+		if crls, err = NewCRLChecker(CRLConfig{Files: files}); err != nil {
+			return nil, nil, err
+		}
+		crls.Start()
+	}
+	if ocspResponder != "" {
+		if responder, err = NewOCSPChecker(OCSPConfig{ResponderURL: ocspResponder}); err != nil {
+			if crls != nil {
+				crls.Close()
+			}
+			return nil, nil, err
+		}
+	}
+	return crls, responder, nil
+}
+
+// LoadACME returns an ACMEManager of the hosts of ACME_HOSTS, with ACME_DIRECTORY_URL and ACME_EMAIL,
+// storing its state in the ACMETable of the database (see ACMEStorage) and ordering under the lock of the locker.
+// It's nil when ACME_HOSTS isn't set, or when TLS_CERT_FILE and TLS_KEY_FILE are set (see LoadConfig).
+//
+// The ACMETable is created if it doesn't exist, and the ACMEManager isn't started, since the listeners must answer the challenges first.
+//
+// Example Usage:
+//
+//	certs, err := tls.LoadACME(db, database.NewLocker(db.RedisClient()))
+//	if err != nil {
+//	    log.Fatalf("Failed to load the ACME configuration: %v", err)
+//	}
+func LoadACME(db database.Service, locker *database.Locker) (*ACMEManager, error) {
+	if acmeHosts == "" || (tlsCertFile != "" && tlsKeyFile != "") {
+		return nil, nil
+	}
+	if err := CreateACMETable(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("crypto/acme: failed to create the %s table: %w", ACMETable, err)
+	}
+	return NewACMEManager(ACMEConfig{
+		Hosts:        strings.Split(acmeHosts, ","),
+		Storage:      NewACMEStorage(db),
+		Locker:       locker,
+		DirectoryURL: acmeDirectoryURL,
+		Email:        acmeEmail,
+	})
+}
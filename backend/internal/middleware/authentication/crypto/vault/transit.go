@@ -7,6 +7,7 @@ package vault
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 )
 
@@ -22,7 +23,7 @@ func (v *VClient) Encrypt(ctx context.Context, keyName string, plaintext []byte)
 		"plaintext": plaintext,
 	}
 
-	encryptResp, err := v.client.Logical().Write(v.buildTransitPath("encrypt", keyName), encryptData)
+	encryptResp, err := v.client.Logical().WriteWithContext(ctx, v.buildTransitPath("encrypt", keyName), encryptData)
 	if err != nil {
 		return nil, fmt.Errorf("unable to encrypt data: %w", err)
 	}
@@ -37,19 +38,26 @@ func (v *VClient) Encrypt(ctx context.Context, keyName string, plaintext []byte)
 
 // Decrypt decrypts data using Vault's Transit Secrets Engine.
 func (v *VClient) Decrypt(ctx context.Context, keyName string, ciphertext []byte) ([]byte, error) {
+	// Note: The ciphertext is the "vault:v1:..." string returned by Encrypt, so it must not be sent as []byte (base64).
 	decryptData := map[string]any{
-		"ciphertext": ciphertext,
+		"ciphertext": string(ciphertext),
 	}
 
-	decryptResp, err := v.client.Logical().Write(v.buildTransitPath("decrypt", keyName), decryptData)
+	decryptResp, err := v.client.Logical().WriteWithContext(ctx, v.buildTransitPath("decrypt", keyName), decryptData)
 	if err != nil {
 		return nil, fmt.Errorf("unable to decrypt data: %w", err)
 	}
 
-	plaintext, ok := decryptResp.Data["plaintext"].([]byte)
+	// Note: Vault returns the plaintext base64-encoded, the same way Encrypt sends it (encoding/json encodes []byte as base64).
+	encoded, ok := decryptResp.Data["plaintext"].(string)
 	if !ok {
 		return nil, fmt.Errorf("plaintext not found in response")
 	}
 
+	plaintext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode plaintext: %w", err)
+	}
+
 	return plaintext, nil
 }
@@ -3,50 +3,131 @@
 // By accessing or using this software, you agree to be bound by the terms
 // of the License Agreement, which you can find at LICENSE files.
 
-// Package eth provides a middleware for configuring an Ethereum client in a Fiber application.
+// Package eth provides a middleware for configuring a pool of Ethereum clients in a Fiber application,
+// Sign-In with Ethereum (EIP-4361), and the verification of on-chain payments.
 //
-// The middleware creates an Ethereum client using the provided configuration and stores it in the Fiber context
-// for subsequent use in route handlers. It also handles errors that may occur during the client creation process.
+// The middleware stores a [Pool] of Ethereum clients in the Fiber context for subsequent use in route handlers.
+// The pool is created once, with the middleware, instead of dialing a new client (hence a new TCP/TLS or WebSocket connection)
+// on every request.
 //
 // Configuration:
 //
-// The [Config] struct allows configuring the Ethereum client:
+// The [Config] struct allows configuring the Ethereum clients:
 //
 //	type Config struct {
-//		URL         string
-//		ContextKey  any
-//		ErrorHandler func(c *fiber.Ctx, err error) error
-//		Next         func(*fiber.Ctx) bool
+//		URL                 string
+//		Endpoints           []Endpoint
+//		Pool                *Pool
+//		CallTimeout         time.Duration
+//		HealthCheckInterval time.Duration
+//		ContextKey          any
+//		ErrorHandler        func(c *fiber.Ctx, err error) error
+//		Next                func(*fiber.Ctx) bool
 //	}
 //
 // Configuration:
-//   - URL: The URL of the Ethereum network to connect to (e.g., "https://eth.btz.pm").
-//   - ContextKey: The key used to store the Ethereum client in the Fiber context. This key must be specified when creating the Config struct.
-//   - ErrorHandler: A custom error handler function to handle errors that occur during client creation.
+//   - URL: The URL of the Ethereum network to connect to (e.g., "https://eth.btz.pm"), used when Endpoints is empty.
+//   - Endpoints: The RPC endpoints, by order of preference. The calls fail over to the next healthy one.
+//   - Pool: A pool shared with other parts of the application (e.g., a background job), instead of a new one.
+//   - CallTimeout: The timeout of each call to an endpoint (default: 10 seconds).
+//   - HealthCheckInterval: The interval of the health checks of the endpoints (default: 30 seconds, negative to disable).
+//   - ContextKey: The key used to store the pool in the Fiber context. This key must be specified when creating the Config struct.
+//   - ErrorHandler: A custom error handler function to handle the requests when every endpoint is down.
 //     If not provided, it defaults to using htmx.NewStaticHandleVersionedAPIError.
 //   - Next: A function that determines whether to skip the middleware and proceed to the next middleware or route handler.
 //     It takes a Fiber context as input and returns a boolean value.
 //     If true, the middleware is skipped, and the next middleware or route handler is executed.
 //     If false, the middleware continues its execution.
 //
-// Retrieving the Ethereum Client:
+// Using the Pool:
 //
-// In route handlers, the Ethereum client can be retrieved from the Fiber context using the specified ContextKey:
+// In route handlers, the pool can be retrieved from the Fiber context using the specified ContextKey,
+// and the calls are made with [Pool.Do] or [Call], which pick a healthy endpoint and limit the call with CallTimeout:
 //
-//	client := c.Locals(config.ContextKey).(*ethclient.Client)
+//	pool := c.Locals(config.ContextKey).(*eth.Pool)
+//	balance, err := eth.Call(c.UserContext(), pool, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+//		return client.BalanceAt(ctx, address, nil)
+//	})
 //
-// Make sure to type-assert the retrieved value to [*ethclient.Client].
+// Make sure to type-assert the retrieved value to [*Pool].
+//
+// Health and Failover:
+//
+// The clients are dialed lazily, on the first call to their endpoint, then kept open. The endpoints are checked
+// with eth_blockNumber in the background, and a call that fails because of the endpoint (e.g., a network error, a timeout,
+// an HTTP 5xx or 429) marks it as unhealthy and is retried on the next one. The errors answered by a node
+// (e.g., [ethereum.NotFound] or a reverted call) are returned as is.
+//
+// Metrics:
+//
+// The pools export the Prometheus metrics eth_rpc_calls_total, eth_rpc_call_duration_seconds, eth_rpc_endpoint_up and
+// eth_rpc_head_block, labeled by [Endpoint.Name] (never by URL, since it usually carries an API key).
 //
 // Error Handling:
 //
-// If an error occurs during the Ethereum client creation, the middleware will call the specified ErrorHandler function.
-// A custom error handler can be provided in the [Config] to handle errors according to specific requirements.
+// When every endpoint is known to be down, the middleware calls the specified ErrorHandler function with a 503 [fiber.Error].
 // If no custom error handler is provided, it defaults to using [htmx.NewStaticHandleVersionedAPIError].
 //
 // Cleaning Up:
 //
-// The middleware automatically closes the Ethereum client when the request is finished using defer [client.Close].
-// This ensures proper cleanup of resources.
+// The pool lives as long as the application. A pool created with [NewPool] should be closed with [Pool.Close] on shutdown.
+//
+// Sign-In with Ethereum:
+//
+// The [SIWE] type provides Sign-In with Ethereum (EIP-4361), where the users log in by signing a message with their wallet:
+//
+//  1. The client gets a message to sign from "POST /message" (or a nonce from "GET /nonce" to build the message itself).
+//  2. The wallet signs the message with personal_sign.
+//  3. "POST /verify" checks the domain, the URI, the chain ID, the times and the nonce of the message, then recovers the signer
+//     from the signature (offline, with go-ethereum's crypto package). On success, the session is bound to the address.
+//  4. The routes behind [SIWE.RequireAddress] read the address with [AddressFromContext].
+//
+// Example Usage:
+//
+//	auth, err := eth.NewSIWE(eth.SIWEConfig{
+//		Domain:  "example.com",
+//		Storage: db.FiberStorage(),
+//	})
+//	if err != nil {
+//		// Handle error
+//	}
+//	auth.Mount(v1.Group("/siwe", userSessions), rateLimiter)
+//	v1.Get("/wallet", userSessions, auth.RequireAddress(), handleWallet)
+//
+// Note: The nonces are single-use and expire with [SIWEConfig.NonceTTL]. The smart contract wallets (EIP-1271) aren't supported,
+// since their signatures can't be checked offline.
+//
+// Payments:
+//
+// The [Payments] type verifies that a transaction paid the application before a resource is unlocked:
+//
+//  1. The client sends the payment with its wallet, then submits the transaction hash to "POST /" (see [Payments.Mount]),
+//     with the reference of what it pays for. The price comes from [PaymentsConfig.Price].
+//  2. The transaction is checked right away: the chain ID, the recipient, the amount (the value for Ether, or the Transfer
+//     logs for an ERC-20 token) and the sender, which must be the address signed in with [SIWE.RequireAddress].
+//     A transaction that isn't sent by the payer or to the recipient is rejected without being stored.
+//  3. The payment is stored in the eth_payments table (see [CreatePaymentsTable]), then refreshed by a background poller
+//     (see [Payments.Start]) until it's confirmed, once it reaches [PaymentsConfig.Confirmations], or failed.
+//  4. [PaymentsConfig.OnConfirmed] unlocks the resource, and the client polls "GET /:tx_hash" for the status.
+//
+// Example Usage:
+//
+//	payments, err := eth.NewPayments(eth.PaymentsConfig{
+//		Pool:        pool,
+//		DB:          db,
+//		Recipient:   treasury,
+//		Price:       priceOfOrder,
+//		OnConfirmed: unlockOrder,
+//	})
+//	if err != nil {
+//		// Handle error
+//	}
+//	payments.Start()
+//	defer payments.Close()
+//	payments.Mount(v1.Group("/payments", userSessions, auth.RequireAddress()), rateLimiter)
+//
+// Note: A transaction can only be submitted once, so it can't pay for two references. Only a failed payment
+// (e.g., an insufficient amount for the reference) can be submitted again, which replaces it.
 //
 // Note: Make sure to import the necessary dependencies and update the import paths based on the project structure.
 package eth
@@ -0,0 +1,196 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// Session keys of the signed-in address.
+const (
+	sessionAddress   = "siwe_address"
+	sessionChainID   = "siwe_chain_id"
+	sessionExpiresAt = "siwe_expires_at"
+)
+
+// addressContextKey is the key of the signed-in address in the context.
+const addressContextKey = "siwe_address"
+
+// messageRequest is the body of HandleMessage.
+type messageRequest struct {
+	Address string `json:"address"`
+}
+
+// verifyRequest is the body of HandleVerify. Signature is the hex of the 65 bytes returned by personal_sign.
+type verifyRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+// sessionResponse is the response of HandleVerify and HandleSession.
+type sessionResponse struct {
+	Address string `json:"address"`
+	ChainID int64  `json:"chain_id"`
+}
+
+// HandleNonce issues a nonce, for the clients building the message themselves (e.g., with a SIWE library).
+func (s *SIWE) HandleNonce(c *fiber.Ctx) error {
+	nonce, err := s.Nonce()
+	if err != nil {
+		return s.sendError(c, err)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{"nonce": nonce})
+}
+
+// HandleMessage builds the message to be signed by the address of the body, with a new nonce.
+func (s *SIWE) HandleMessage(c *fiber.Ctx) error {
+	var body messageRequest
+	if err := c.BodyParser(&body); err != nil || !common.IsHexAddress(body.Address) {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid address")
+	}
+
+	m, err := s.Message(common.HexToAddress(body.Address))
+	if err != nil {
+		return s.sendError(c, err)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(fiber.Map{"message": m.String(), "nonce": m.Nonce})
+}
+
+// HandleVerify verifies the signed message of the body, then binds the session to the signer (with a regenerated ID).
+//
+// The session ends with the expiration time of the message, if any, or with the session itself.
+func (s *SIWE) HandleVerify(c *fiber.Ctx) error {
+	sess, ok := c.Locals(users.SessionContextKey).(*session.Session)
+	if !ok {
+		log.LogError("eth: the session middleware is missing")
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	var body verifyRequest
+	if err := c.BodyParser(&body); err != nil {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	signature, err := hexutil.Decode(body.Signature)
+	if err != nil {
+		return s.sendError(c, ErrInvalidSignature)
+	}
+
+	m, err := s.Verify(body.Message, signature)
+	if err != nil {
+		log.LogUserActivity(c, "Failed Sign-In with Ethereum: "+err.Error())
+		return s.sendError(c, err)
+	}
+
+	if err := sess.Regenerate(); err != nil {
+		return s.sendError(c, err)
+	}
+	sess.Set(sessionAddress, m.Address.Hex())
+	sess.Set(sessionChainID, m.ChainID)
+	if !m.ExpirationTime.IsZero() {
+		sess.Set(sessionExpiresAt, m.ExpirationTime.Unix())
+	}
+	if err := sess.Save(); err != nil {
+		return s.sendError(c, err)
+	}
+
+	log.LogUserActivity(c, "Signed in with Ethereum as "+m.Address.Hex())
+	return c.JSON(sessionResponse{Address: m.Address.Hex(), ChainID: m.ChainID})
+}
+
+// HandleSession answers with the signed-in address.
+//
+// Note: It must run after RequireAddress.
+func (s *SIWE) HandleSession(c *fiber.Ctx) error {
+	address, ok := AddressFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+	return c.JSON(sessionResponse{Address: address.Hex(), ChainID: s.cfg.ChainID})
+}
+
+// RequireAddress is a middleware that loads the signed-in address of the session into the context (see AddressFromContext),
+// or answers 401 when there is none, or when it expired.
+func (s *SIWE) RequireAddress() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sess, ok := c.Locals(users.SessionContextKey).(*session.Session)
+		if !ok {
+			log.LogError("eth: the session middleware is missing")
+			return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+		}
+
+		address, _ := sess.Get(sessionAddress).(string)
+		chainID, _ := sess.Get(sessionChainID).(int64)
+		if address == "" || chainID != s.cfg.ChainID {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+		if expiresAt, ok := sess.Get(sessionExpiresAt).(int64); ok && time.Now().Unix() > expiresAt {
+			sess.Destroy()
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Session expired")
+		}
+
+		c.Locals(addressContextKey, common.HexToAddress(address))
+		return c.Next()
+	}
+}
+
+// AddressFromContext returns the address loaded by RequireAddress.
+func AddressFromContext(c *fiber.Ctx) (common.Address, bool) {
+	address, ok := c.Locals(addressContextKey).(common.Address)
+	return address, ok
+}
+
+// Mount registers the routes of Sign-In with Ethereum on the router:
+//
+//   - "GET /nonce" and "POST /message", with the rate limiter (if not nil) in front, since each one stores a nonce.
+//   - "POST /verify", with the rate limiter (if not nil) in front.
+//   - "GET /session", behind RequireAddress.
+//
+// Note: The session middleware of the users must be in front of the router, and users.Logout ends the session.
+func (s *SIWE) Mount(router fiber.Router, rateLimiter fiber.Handler) {
+	limited := func(method, path string, handler fiber.Handler) {
+		if rateLimiter != nil {
+			router.Add(method, path, rateLimiter, handler)
+			return
+		}
+		router.Add(method, path, handler)
+	}
+	limited(fiber.MethodGet, "/nonce", s.HandleNonce)
+	limited(fiber.MethodPost, "/message", s.HandleMessage)
+	limited(fiber.MethodPost, "/verify", s.HandleVerify)
+	router.Get("/session", s.RequireAddress(), s.HandleSession)
+}
+
+// sendError maps the errors of SIWE to problem details.
+func (s *SIWE) sendError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidMessage):
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid message")
+	case errors.Is(err, ErrInvalidSignature):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid signature")
+	case errors.Is(err, ErrDomainMismatch):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Message is for another domain")
+	case errors.Is(err, ErrChainIDMismatch):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Message is for another chain")
+	case errors.Is(err, ErrExpiredMessage):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Message expired or not yet valid")
+	case errors.Is(err, ErrInvalidNonce):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid or used nonce")
+	default:
+		log.LogErrorf("Unexpected error in SIWE handler: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidMessage is returned by ParseMessage when a message doesn't follow EIP-4361.
+var ErrInvalidMessage = errors.New("eth: invalid SIWE message")
+
+// messageHeader ends the first line of a SIWE message, after the domain.
+const messageHeader = " wants you to sign in with your Ethereum account:"
+
+// Message is a Sign-In with Ethereum message (EIP-4361), which the wallets show to the users before signing it.
+type Message struct {
+	// Scheme is the scheme of the origin of the request (e.g., "http" for a local development server).
+	// It's omitted from the message when empty, in which case "https" is assumed.
+	Scheme string
+
+	// Domain is the authority (host and optional port) requesting the signing.
+	Domain string
+
+	// Address is the address of the account signing the message, written with its EIP-55 checksum.
+	Address common.Address
+
+	// Statement is an optional human-readable assertion, which must not contain a newline.
+	Statement string
+
+	// URI is the subject of the signing (e.g., the origin of the application).
+	URI string
+
+	// Version is the version of the message, which must be "1".
+	Version string
+
+	// ChainID is the EIP-155 chain ID the session is bound to.
+	ChainID int64
+
+	// Nonce is a random string of at least 8 alphanumeric characters, issued by the server against replay.
+	Nonce string
+
+	// IssuedAt is when the message was created.
+	IssuedAt time.Time
+
+	// ExpirationTime is when the message expires. It's optional.
+	ExpirationTime time.Time
+
+	// NotBefore is when the message becomes valid. It's optional.
+	NotBefore time.Time
+
+	// RequestID is an optional ID of the request, chosen by the server.
+	RequestID string
+
+	// Resources are optional URIs the user wishes to have resolved as part of the authentication.
+	Resources []string
+}
+
+// String returns the message to sign, in the format of EIP-4361.
+func (m Message) String() string {
+	var b strings.Builder
+	if m.Scheme != "" {
+		b.WriteString(m.Scheme + "://")
+	}
+	b.WriteString(m.Domain + messageHeader + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + strconv.FormatInt(m.ChainID, 10) + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if !m.ExpirationTime.IsZero() {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if !m.NotBefore.IsZero() {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+// ParseMessage parses a SIWE message (EIP-4361), as signed by a wallet.
+//
+// Note: It only checks the syntax of the message. What it says (e.g., its domain or its expiration time)
+// is checked by SIWE.Verify.
+func ParseMessage(s string) (Message, error) {
+	lines := strings.Split(s, "\n")
+	if len(lines) < 9 {
+		return Message{}, fmt.Errorf("%w: too short", ErrInvalidMessage)
+	}
+
+	var m Message
+	origin, ok := strings.CutSuffix(lines[0], messageHeader)
+	if !ok || origin == "" {
+		return Message{}, fmt.Errorf("%w: missing header", ErrInvalidMessage)
+	}
+	if scheme, domain, found := strings.Cut(origin, "://"); found {
+		m.Scheme, m.Domain = scheme, domain
+	} else {
+		m.Domain = origin
+	}
+	if m.Domain == "" || strings.ContainsAny(m.Domain, " /") {
+		return Message{}, fmt.Errorf("%w: invalid domain", ErrInvalidMessage)
+	}
+
+	// Note: The address must have its EIP-55 checksum, which catches the typos of the users and the wallets.
+	if !common.IsHexAddress(lines[1]) || common.HexToAddress(lines[1]).Hex() != lines[1] {
+		return Message{}, fmt.Errorf("%w: invalid address", ErrInvalidMessage)
+	}
+	m.Address = common.HexToAddress(lines[1])
+
+	// Note: The statement is optional, but its empty line isn't.
+	fields := lines[4:]
+	if lines[2] != "" {
+		return Message{}, fmt.Errorf("%w: invalid statement", ErrInvalidMessage)
+	}
+	if lines[3] != "" {
+		if lines[4] != "" {
+			return Message{}, fmt.Errorf("%w: invalid statement", ErrInvalidMessage)
+		}
+		m.Statement, fields = lines[3], lines[5:]
+	}
+
+	// next returns the value of the next field with the tag, if it's there.
+	next := func(tag string) (string, bool) {
+		if len(fields) == 0 {
+			return "", false
+		}
+		value, ok := strings.CutPrefix(fields[0], tag+": ")
+		if !ok {
+			return "", false
+		}
+		fields = fields[1:]
+		return value, true
+	}
+	// nextTime returns the time of the next field with the tag, if it's there.
+	nextTime := func(tag string) (time.Time, bool, error) {
+		value, ok := next(tag)
+		if !ok {
+			return time.Time{}, false, nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: invalid %s", ErrInvalidMessage, tag)
+		}
+		return t, true, nil
+	}
+
+	if m.URI, ok = next("URI"); !ok {
+		return Message{}, fmt.Errorf("%w: missing URI", ErrInvalidMessage)
+	}
+	if u, err := url.Parse(m.URI); err != nil || u.Scheme == "" {
+		return Message{}, fmt.Errorf("%w: invalid URI", ErrInvalidMessage)
+	}
+	if m.Version, ok = next("Version"); !ok || m.Version != "1" {
+		return Message{}, fmt.Errorf("%w: invalid version", ErrInvalidMessage)
+	}
+	chainID, ok := next("Chain ID")
+	if !ok {
+		return Message{}, fmt.Errorf("%w: missing chain ID", ErrInvalidMessage)
+	}
+	var err error
+	if m.ChainID, err = strconv.ParseInt(chainID, 10, 64); err != nil || m.ChainID < 1 {
+		return Message{}, fmt.Errorf("%w: invalid chain ID", ErrInvalidMessage)
+	}
+	if m.Nonce, ok = next("Nonce"); !ok || !isNonce(m.Nonce) {
+		return Message{}, fmt.Errorf("%w: invalid nonce", ErrInvalidMessage)
+	}
+	if m.IssuedAt, ok, err = nextTime("Issued At"); err != nil {
+		return Message{}, err
+	} else if !ok {
+		return Message{}, fmt.Errorf("%w: missing Issued At", ErrInvalidMessage)
+	}
+	if m.ExpirationTime, _, err = nextTime("Expiration Time"); err != nil {
+		return Message{}, err
+	}
+	if m.NotBefore, _, err = nextTime("Not Before"); err != nil {
+		return Message{}, err
+	}
+	m.RequestID, _ = next("Request ID")
+	if len(fields) > 0 && fields[0] == "Resources:" {
+		for _, line := range fields[1:] {
+			resource, ok := strings.CutPrefix(line, "- ")
+			if !ok {
+				return Message{}, fmt.Errorf("%w: invalid resource", ErrInvalidMessage)
+			}
+			m.Resources = append(m.Resources, resource)
+		}
+		fields = nil
+	}
+	if len(fields) > 0 {
+		return Message{}, fmt.Errorf("%w: unexpected %q", ErrInvalidMessage, fields[0])
+	}
+	return m, nil
+}
+
+// isNonce reports whether s is a nonce of at least 8 alphanumeric characters.
+func isNonce(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace is the Prometheus namespace of every metric exported by this package.
+const metricsNamespace = "eth"
+
+// Note: These are registered on the default registerer, like the metrics of the database package,
+// so they show up on the same metrics endpoint. They are labeled by [Endpoint.Name], never by URL,
+// since the URLs of the RPC providers usually carry an API key.
+var (
+	// rpcCalls counts the calls made by the pools by endpoint and result ("ok", "error" or "failover").
+	rpcCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "calls_total",
+		Help:      "Calls to the RPC endpoints by result (ok, error, or failover when the next endpoint was tried).",
+	}, []string{"endpoint", "result"})
+
+	// rpcCallDuration records the duration of the calls by endpoint.
+	rpcCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of the calls to the RPC endpoints.",
+		// From 5ms (a node on the same network) up to ~20s (a slow eth_getLogs).
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 13),
+	}, []string{"endpoint"})
+
+	// endpointUp reports whether an endpoint is healthy (1) or not (0), according to the health checks and the calls.
+	endpointUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "endpoint_up",
+		Help:      "Whether the RPC endpoint is healthy (1) or not (0).",
+	}, []string{"endpoint"})
+
+	// endpointHead reports the latest block number seen by the health checks of an endpoint.
+	endpointHead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "rpc",
+		Name:      "head_block",
+		Help:      "Latest block number of the RPC endpoint, as seen by the last health check.",
+	}, []string{"endpoint"})
+
+	// paymentsResolved counts the payments that became final, by status ("confirmed" or "failed").
+	paymentsResolved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "payments",
+		Name:      "resolved_total",
+		Help:      "Payments that became final, by status (confirmed or failed).",
+	}, []string{"status"})
+)
@@ -7,8 +7,9 @@ package eth
 
 import (
 	htmx "h0llyw00dz-template/frontend/htmx/error_page_handler"
+	"sync"
+	"time"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -16,37 +17,134 @@ import (
 //
 // Note: There no default config.
 type Config struct {
-	URL          string
+	// URL is the URL of the Ethereum network to connect to (e.g., "https://eth.btz.pm"),
+	// used when Endpoints is empty.
+	URL string
+
+	// Endpoints are the RPC endpoints, by order of preference (see PoolConfig.Endpoints).
+	Endpoints []Endpoint
+
+	// Pool is a pool shared with other parts of the application (e.g., a background job).
+	// When set, URL, Endpoints, CallTimeout and HealthCheckInterval are ignored.
+	Pool *Pool
+
+	// CallTimeout limits each call to an endpoint (see PoolConfig.CallTimeout).
+	CallTimeout time.Duration
+
+	// HealthCheckInterval is the interval of the health checks (see PoolConfig.HealthCheckInterval).
+	HealthCheckInterval time.Duration
+
 	ContextKey   any
 	ErrorHandler func(c *fiber.Ctx, err error) error
 	Next         func(*fiber.Ctx) bool
 }
 
-// New is a custom Fiber middleware that configures the Ethereum client
+// Client is the Ethereum middleware with its pool of clients. Create it with [NewClient], and Close it on shutdown.
+type Client struct {
+	config Config
+	pool   *Pool
+	owned  bool // the pool was created from the config, rather than shared with Config.Pool
+}
+
+// NewClient returns the middleware of the config, creating its pool unless Config.Pool is set.
 //
-// Note: It should be fine if the gateway is via Cloudflare. This is how we test for excellent performance - by doing one thing and doing it well.
-func New(config Config) fiber.Handler {
+// Example Usage:
+//
+//	client, err := eth.NewClient(eth.Config{URL: "https://eth.btz.pm", ContextKey: "eth"})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	defer client.Close()
+//
+//	app.Use(client.Handler())
+func NewClient(config Config) (*Client, error) {
+	if config.Pool != nil {
+		return &Client{config: config, pool: config.Pool}, nil
+	}
+
+	endpoints := config.Endpoints
+	if len(endpoints) == 0 && config.URL != "" {
+		endpoints = []Endpoint{{URL: config.URL}}
+	}
+	pool, err := NewPool(PoolConfig{
+		Endpoints:           endpoints,
+		CallTimeout:         config.CallTimeout,
+		HealthCheckInterval: config.HealthCheckInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{config: config, pool: pool, owned: true}, nil
+}
+
+// Pool returns the pool of the client.
+func (c *Client) Pool() *Pool {
+	return c.pool
+}
+
+// Close closes the pool (stopping its health checks), unless it's shared with Config.Pool, which its owner closes.
+func (c *Client) Close() {
+	if c.owned {
+		c.pool.Close()
+	}
+}
+
+// Handler returns the Fiber middleware, which stores the pool in the context, under Config.ContextKey.
+//
+// When every endpoint is known to be down, the requests are answered with a 503 Service Unavailable
+// (by Config.ErrorHandler, if any).
+func (c *Client) Handler() fiber.Handler {
+	config, pool := c.config, c.pool
 	return func(c *fiber.Ctx) error {
 		// Check if the request should be skipped
 		if config.Next != nil && config.Next(c) {
 			return c.Next()
 		}
 
-		// Create a new Ethereum client using the provided URL
-		client, err := ethclient.Dial(config.URL)
-		if err != nil {
+		if pool.Down() {
+			err := fiber.NewError(fiber.StatusServiceUnavailable, ErrNoHealthyEndpoint.Error())
 			if config.ErrorHandler != nil {
 				return config.ErrorHandler(c, err)
 			}
-			return htmx.NewStaticHandleVersionedAPIError(c, fiber.NewError(fiber.StatusInternalServerError, err.Error()))
+			return htmx.NewStaticHandleVersionedAPIError(c, err)
 		}
 
-		// Store the Ethereum client in the Fiber context using the specified context key
-		c.Locals(config.ContextKey, client)
-
-		// Clean up the client when the request is finished
-		defer client.Close()
+		// Store the pool in the Fiber context using the specified context key
+		c.Locals(config.ContextKey, pool)
 
 		return c.Next()
 	}
 }
+
+// New is a custom Fiber middleware that stores a pool of Ethereum clients in the context, under Config.ContextKey.
+//
+// The pool is created once, with the middleware, and shared by all the requests: the clients are dialed lazily,
+// kept open and health-checked, and the calls fail over between the endpoints (see Pool.Do).
+// It panics when the config has no endpoint.
+//
+// The pool created by New is closed when the Fiber app shuts down. Use [NewClient] to close it yourself instead.
+//
+// Note: It should be fine if the gateway is via Cloudflare. This is how we test for excellent performance - by doing one thing and doing it well.
+func New(config Config) fiber.Handler {
+	client, err := NewClient(config)
+	if err != nil {
+		panic(err)
+	}
+	handler := client.Handler()
+	if !client.owned {
+		return handler
+	}
+
+	// Note: The health checks only start with the first call, which is made by a request,
+	// so registering the hook on the first request is early enough.
+	var hookOnce sync.Once
+	return func(c *fiber.Ctx) error {
+		hookOnce.Do(func() {
+			c.App().Hooks().OnShutdown(func() error {
+				client.Close()
+				return nil
+			})
+		})
+		return handler(c)
+	}
+}
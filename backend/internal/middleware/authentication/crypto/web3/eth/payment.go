@@ -0,0 +1,551 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+var (
+	// ErrMissingPool is returned by NewPayments when PaymentsConfig.Pool is nil.
+	ErrMissingPool = errors.New("eth: payments pool is required")
+
+	// ErrMissingDatabase is returned by NewPayments when PaymentsConfig.DB is nil.
+	ErrMissingDatabase = errors.New("eth: payments database is required")
+
+	// ErrMissingRecipient is returned by NewPayments when PaymentsConfig.Recipient is the zero address.
+	ErrMissingRecipient = errors.New("eth: payments recipient is required")
+
+	// ErrInvalidPayment is returned by Submit for a payment without a reference or a positive amount.
+	ErrInvalidPayment = errors.New("eth: invalid payment")
+
+	// ErrPaymentExists is returned by Submit when the transaction was already submitted and its payment didn't fail,
+	// so one transaction can't pay for two references.
+	ErrPaymentExists = errors.New("eth: transaction already submitted")
+
+	// ErrPaymentMismatch is returned by Submit when the transaction isn't sent by the payer or to the recipient.
+	// Such a payment isn't stored, so it can't hold the transaction hash of someone else.
+	ErrPaymentMismatch = errors.New("eth: transaction is not a payment of the payer to the recipient")
+
+	// ErrPaymentNotFound is returned when there is no payment with the given transaction hash.
+	ErrPaymentNotFound = errors.New("eth: payment not found")
+
+	// ErrUnknownReference is returned by PaymentsConfig.Price when there is nothing to pay for the reference.
+	ErrUnknownReference = errors.New("eth: unknown payment reference")
+
+	// ErrNodeChainMismatch is returned when the RPC endpoints are on another chain than PaymentsConfig.ChainID.
+	ErrNodeChainMismatch = errors.New("eth: RPC endpoint is on another chain")
+)
+
+// PaymentsTable is the name of the table storing the payments (see [CreatePaymentsTable]).
+const PaymentsTable = "eth_payments"
+
+// PaymentStatus is the status of a payment.
+type PaymentStatus string
+
+const (
+	// PaymentPending is a payment whose transaction isn't mined yet (or not seen by the node yet).
+	PaymentPending PaymentStatus = "pending"
+
+	// PaymentConfirming is a payment whose transaction is mined and valid, waiting for PaymentsConfig.Confirmations.
+	PaymentConfirming PaymentStatus = "confirming"
+
+	// PaymentConfirmed is a payment whose transaction reached PaymentsConfig.Confirmations. It's final.
+	PaymentConfirmed PaymentStatus = "confirmed"
+
+	// PaymentFailed is a payment whose transaction doesn't pay what was expected (see Payment.Reason),
+	// reverted, or wasn't mined within PaymentsConfig.PendingTimeout. It's final.
+	PaymentFailed PaymentStatus = "failed"
+)
+
+// Final reports whether the status can't change anymore.
+func (s PaymentStatus) Final() bool {
+	return s == PaymentConfirmed || s == PaymentFailed
+}
+
+// Payment is a transaction submitted as the payment of a reference (e.g., an order or a resource to unlock).
+type Payment struct {
+	TxHash    common.Hash
+	Reference string
+	ChainID   int64
+
+	// Token is the ERC-20 contract of the payment, or the zero address for Ether.
+	Token common.Address
+	// Amount is the expected amount, in wei or in the base unit of the token.
+	Amount *big.Int
+	// Payer is the expected sender, or the zero address for anyone.
+	Payer common.Address
+
+	Status PaymentStatus
+	// Reason tells why the payment failed.
+	Reason string
+	// Paid is the amount received by the recipient in the transaction.
+	Paid          *big.Int
+	BlockNumber   uint64
+	BlockHash     common.Hash
+	Confirmations uint64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PaymentRequest is a payment to be verified by Submit.
+type PaymentRequest struct {
+	// TxHash is the hash of the transaction sent by the payer.
+	TxHash common.Hash
+
+	// Reference identifies what is paid for (e.g., an order ID), up to 255 characters.
+	Reference string
+
+	// Token is the ERC-20 contract to be paid with, or the zero address for Ether.
+	Token common.Address
+
+	// Amount is the minimum amount to be received, in wei or in the base unit of the token.
+	Amount *big.Int
+
+	// Payer is the address that must send the payment (e.g., the address of Sign-In with Ethereum),
+	// so nobody can claim the transaction of someone else. The zero address accepts any sender.
+	Payer common.Address
+}
+
+// PaymentsConfig defines the config for the Payments.
+type PaymentsConfig struct {
+	// Pool is the pool of Ethereum clients used to read the transactions (e.g., the one of the middleware).
+	//
+	// Required.
+	Pool *Pool
+
+	// DB stores the payments.
+	//
+	// Required.
+	DB database.Service
+
+	// Recipient is the address that receives the payments.
+	//
+	// Required.
+	Recipient common.Address
+
+	// ChainID is the EIP-155 chain ID of the payments. The transactions for another chain are rejected.
+	//
+	// Optional. Default: 1 (Ethereum mainnet)
+	ChainID int64
+
+	// Confirmations is the number of blocks (including the one of the transaction) after which a payment is final,
+	// so a reorg can't take it back.
+	//
+	// Optional. Default: 12
+	Confirmations uint64
+
+	// PollInterval is the interval at which the background poller (see Start) refreshes the payments that aren't final.
+	//
+	// Optional. Default: 15 * time.Second
+	PollInterval time.Duration
+
+	// PendingTimeout is how long a transaction can stay unmined (or unknown to the node) before the payment fails.
+	//
+	// Optional. Default: 1 * time.Hour
+	PendingTimeout time.Duration
+
+	// BatchSize is the maximum number of payments refreshed by each poll.
+	//
+	// Optional. Default: 100
+	BatchSize int
+
+	// Price returns what must be paid for a reference, for HandleSubmit.
+	// It returns ErrUnknownReference when there is nothing to pay for it.
+	//
+	// Optional. Required by HandleSubmit.
+	Price func(ctx context.Context, reference string) (token common.Address, amount *big.Int, err error)
+
+	// OnConfirmed is called once a payment is confirmed (e.g., to unlock the resource of its reference).
+	// It's called once per payment, by the pod that confirmed it, even when several pods poll the same database.
+	//
+	// Optional.
+	OnConfirmed func(ctx context.Context, payment Payment)
+}
+
+// Payments verifies the payments made on-chain to PaymentsConfig.Recipient, and tracks their confirmations.
+//
+// A submitted transaction is checked right away, then refreshed by the background poller until it's final:
+// confirmed once it reaches PaymentsConfig.Confirmations, or failed.
+type Payments struct {
+	cfg     PaymentsConfig
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	started atomic.Bool
+}
+
+// NewPayments returns a new Payments with the config.
+//
+// Example Usage:
+//
+//	payments, err := eth.NewPayments(eth.PaymentsConfig{
+//	    Pool:      pool,
+//	    DB:        db,
+//	    Recipient: common.HexToAddress("0x..."),
+//	    Price: func(ctx context.Context, reference string) (common.Address, *big.Int, error) {
+//	        return usdc, big.NewInt(5_000_000), nil // 5 USDC
+//	    },
+//	    OnConfirmed: func(ctx context.Context, p eth.Payment) {
+//	        // Unlock p.Reference
+//	    },
+//	})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	payments.Start()
+//	defer payments.Close()
+func NewPayments(config PaymentsConfig) (*Payments, error) {
+	if config.Pool == nil {
+		return nil, ErrMissingPool
+	}
+	if config.DB == nil {
+		return nil, ErrMissingDatabase
+	}
+	if config.Recipient == (common.Address{}) {
+		return nil, ErrMissingRecipient
+	}
+	config.ChainID = cmp.Or(config.ChainID, 1)
+	config.Confirmations = cmp.Or(config.Confirmations, 12)
+	config.PollInterval = cmp.Or(config.PollInterval, 15*time.Second)
+	config.PendingTimeout = cmp.Or(config.PendingTimeout, time.Hour)
+	config.BatchSize = cmp.Or(config.BatchSize, 100)
+
+	return &Payments{
+		cfg:  config,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}, nil
+}
+
+// CreatePaymentsTable creates the payments table if it doesn't exist.
+//
+// Note: The amounts are stored as decimal strings, since they don't fit in a BIGINT (uint256),
+// and the times as Unix seconds, so the schema works on MySQL and SQLite.
+func CreatePaymentsTable(ctx context.Context, db database.Service) error {
+	query := `CREATE TABLE IF NOT EXISTS eth_payments (
+	tx_hash CHAR(66) NOT NULL PRIMARY KEY,
+	reference VARCHAR(255) NOT NULL,
+	chain_id BIGINT NOT NULL,
+	token CHAR(42) NOT NULL,
+	amount VARCHAR(78) NOT NULL,
+	payer CHAR(42) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	paid VARCHAR(78) NOT NULL DEFAULT '0',
+	block_number BIGINT NOT NULL DEFAULT 0,
+	block_hash CHAR(66) NOT NULL DEFAULT '',
+	confirmations BIGINT NOT NULL DEFAULT 0,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL`
+
+	if db.Dialect().Name() == database.DriverMySQL {
+		// MySQL has no "CREATE INDEX IF NOT EXISTS", so the indexes are declared inline.
+		return db.ExecWithoutRow(ctx, query+`,
+	INDEX idx_eth_payments_status (status, updated_at),
+	INDEX idx_eth_payments_reference (reference)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	}
+
+	if err := db.ExecWithoutRow(ctx, query+"\n)"); err != nil {
+		return err
+	}
+	if err := db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_eth_payments_status ON eth_payments (status, updated_at)"); err != nil {
+		return err
+	}
+	return db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_eth_payments_reference ON eth_payments (reference)")
+}
+
+// Submit checks the transaction of the payment right away, then stores the payment.
+//
+// It returns ErrPaymentMismatch, without storing anything, when the transaction isn't sent by the payer or to the recipient.
+// It returns ErrPaymentExists when the transaction was already submitted, unless that payment failed, in which case
+// the new payment replaces it (e.g., the payer submitting the transaction after someone else did with a wrong reference).
+//
+// Note: When the RPC endpoints can't be reached, the payment is returned as pending, and the poller checks it later.
+func (p *Payments) Submit(ctx context.Context, request PaymentRequest) (Payment, error) {
+	if request.TxHash == (common.Hash{}) || request.Reference == "" || len(request.Reference) > 255 ||
+		request.Amount == nil || request.Amount.Sign() <= 0 {
+		return Payment{}, ErrInvalidPayment
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	payment := Payment{
+		TxHash:    request.TxHash,
+		Reference: request.Reference,
+		ChainID:   p.cfg.ChainID,
+		Token:     request.Token,
+		Amount:    new(big.Int).Set(request.Amount),
+		Payer:     request.Payer,
+		Status:    PaymentPending,
+		Paid:      new(big.Int),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, checkErr := p.checkPayment(ctx, payment)
+	if checkErr == nil && result.mismatch {
+		return Payment{}, fmt.Errorf("%w: %s", ErrPaymentMismatch, result.reason)
+	}
+
+	if err := p.store(ctx, payment); err != nil {
+		return Payment{}, err
+	}
+
+	if checkErr != nil {
+		log.LogErrorf("Failed to check the payment %s: %v", payment.TxHash.Hex(), checkErr)
+		return payment, nil
+	}
+	if err := p.apply(ctx, &payment, result); err != nil {
+		log.LogErrorf("Failed to update the payment %s: %v", payment.TxHash.Hex(), err)
+	}
+	return payment, nil
+}
+
+// store inserts the new payment, or replaces the failed payment of the same transaction.
+// It returns ErrPaymentExists when the transaction has a payment that didn't fail.
+func (p *Payments) store(ctx context.Context, payment Payment) error {
+	const insert = "INSERT INTO eth_payments (tx_hash, reference, chain_id, token, amount, payer, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := p.cfg.DB.Exec(ctx, insert, payment.TxHash.Hex(), payment.Reference, payment.ChainID, payment.Token.Hex(),
+		payment.Amount.String(), payment.Payer.Hex(), string(payment.Status), payment.CreatedAt.Unix(), payment.UpdatedAt.Unix())
+	if err == nil {
+		return nil
+	}
+	if !p.cfg.DB.Dialect().IsDuplicateEntry(err) {
+		return fmt.Errorf("eth: failed to store payment: %w", err)
+	}
+
+	// Note: Only a failed payment is replaced, and only once when several submissions race, since the first one
+	// makes it pending again.
+	const replace = "UPDATE eth_payments SET reference = ?, chain_id = ?, token = ?, amount = ?, payer = ?, status = ?, reason = '', paid = '0', block_number = 0, block_hash = '', confirmations = 0, created_at = ?, updated_at = ? WHERE tx_hash = ? AND status = ?"
+	res, err := p.cfg.DB.Exec(ctx, replace, payment.Reference, payment.ChainID, payment.Token.Hex(), payment.Amount.String(),
+		payment.Payer.Hex(), string(payment.Status), payment.CreatedAt.Unix(), payment.UpdatedAt.Unix(), payment.TxHash.Hex(),
+		string(PaymentFailed))
+	if err != nil {
+		return fmt.Errorf("eth: failed to store payment: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("eth: failed to store payment: %w", err)
+	} else if n == 0 {
+		return ErrPaymentExists
+	}
+	return nil
+}
+
+// paymentColumns are the columns scanned by scanPayment.
+const paymentColumns = "tx_hash, reference, chain_id, token, amount, payer, status, reason, paid, block_number, block_hash, confirmations, created_at, updated_at"
+
+// Get returns the payment of the transaction, or ErrPaymentNotFound.
+func (p *Payments) Get(ctx context.Context, txHash common.Hash) (Payment, error) {
+	row := p.cfg.DB.QueryRow(ctx, "SELECT "+paymentColumns+" FROM eth_payments WHERE tx_hash = ?", txHash.Hex())
+	payment, err := scanPayment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Payment{}, ErrPaymentNotFound
+	}
+	return payment, err
+}
+
+// ListByReference returns the payments of the reference, oldest first.
+func (p *Payments) ListByReference(ctx context.Context, reference string) ([]Payment, error) {
+	return p.list(ctx, "SELECT "+paymentColumns+" FROM eth_payments WHERE reference = ? ORDER BY created_at, tx_hash", reference)
+}
+
+// Poll refreshes the payments that aren't final, least recently updated first, up to PaymentsConfig.BatchSize.
+func (p *Payments) Poll(ctx context.Context) error {
+	payments, err := p.list(ctx, "SELECT "+paymentColumns+" FROM eth_payments WHERE status IN (?, ?) ORDER BY updated_at, tx_hash LIMIT ?",
+		string(PaymentPending), string(PaymentConfirming), p.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range payments {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := p.refresh(ctx, &payments[i]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", payments[i].TxHash.Hex(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start polls the payments every PaymentsConfig.PollInterval in the background, until Close is called.
+func (p *Payments) Start() {
+	if !p.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				if err := p.pollWithTimeout(); err != nil {
+					log.LogErrorf("Failed to poll the Ethereum payments: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background poller started by Start.
+//
+// Note: The pool isn't closed, since it's usually shared (e.g., with the middleware).
+func (p *Payments) Close() {
+	p.once.Do(func() { close(p.stop) })
+	if p.started.Load() {
+		<-p.done
+	}
+}
+
+// pollWithTimeout polls with a timeout of one PollInterval, so a stuck poll doesn't pile up,
+// and cancels it when the poller is closed.
+func (p *Payments) pollWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.PollInterval)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return p.Poll(ctx)
+}
+
+// checkPayment checks the transaction of the payment on-chain (see check).
+func (p *Payments) checkPayment(ctx context.Context, payment Payment) (checkResult, error) {
+	return Call(ctx, p.cfg.Pool, func(ctx context.Context, client *ethclient.Client) (checkResult, error) {
+		return p.check(ctx, client, payment)
+	})
+}
+
+// refresh checks the transaction of the payment on-chain, then stores its new state (see apply).
+func (p *Payments) refresh(ctx context.Context, payment *Payment) error {
+	result, err := p.checkPayment(ctx, *payment)
+	if err != nil {
+		return err
+	}
+	return p.apply(ctx, payment, result)
+}
+
+// apply stores the result of the check of the payment, and calls PaymentsConfig.OnConfirmed
+// when it's the one that confirmed the payment.
+func (p *Payments) apply(ctx context.Context, payment *Payment, result checkResult) error {
+	if result.status == PaymentPending && time.Since(payment.CreatedAt) > p.cfg.PendingTimeout {
+		result = checkResult{status: PaymentFailed, reason: "transaction not mined in time"}
+	}
+
+	updated := *payment
+	updated.Status = result.status
+	updated.Reason = result.reason
+	updated.Paid = cmp.Or(result.paid, new(big.Int))
+	updated.BlockNumber = result.blockNumber
+	updated.BlockHash = result.blockHash
+	updated.Confirmations = result.confirmations
+	updated.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	// Note: Only the payments that aren't final are updated, so when several pods refresh the same payment,
+	// a single one confirms it (and calls OnConfirmed).
+	const query = "UPDATE eth_payments SET status = ?, reason = ?, paid = ?, block_number = ?, block_hash = ?, confirmations = ?, updated_at = ? WHERE tx_hash = ? AND status IN (?, ?)"
+	res, err := p.cfg.DB.Exec(ctx, query, string(updated.Status), updated.Reason, updated.Paid.String(), updated.BlockNumber,
+		blockHashColumn(updated.BlockHash), updated.Confirmations, updated.UpdatedAt.Unix(), updated.TxHash.Hex(),
+		string(PaymentPending), string(PaymentConfirming))
+	if err != nil {
+		return fmt.Errorf("eth: failed to update payment: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		// Another pod made the payment final in the meantime.
+		return err
+	}
+	*payment = updated
+
+	if payment.Status.Final() {
+		paymentsResolved.WithLabelValues(string(payment.Status)).Inc()
+		log.LogInfof("Ethereum payment %s of %q is %s %s", payment.TxHash.Hex(), payment.Reference, payment.Status, payment.Reason)
+	}
+	if payment.Status == PaymentConfirmed && p.cfg.OnConfirmed != nil {
+		p.cfg.OnConfirmed(ctx, *payment)
+	}
+	return nil
+}
+
+// list returns the payments of the query.
+func (p *Payments) list(ctx context.Context, query string, args ...any) ([]Payment, error) {
+	rows, err := p.cfg.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// scanPayment scans a row of paymentColumns.
+func scanPayment(row interface{ Scan(dest ...any) error }) (Payment, error) {
+	var (
+		payment                                        Payment
+		txHash, token, amount, payer, status, paid, bh string
+		createdAt, updatedAt                           int64
+	)
+	if err := row.Scan(&txHash, &payment.Reference, &payment.ChainID, &token, &amount, &payer, &status, &payment.Reason,
+		&paid, &payment.BlockNumber, &bh, &payment.Confirmations, &createdAt, &updatedAt); err != nil {
+		return Payment{}, err
+	}
+
+	var ok bool
+	if payment.Amount, ok = new(big.Int).SetString(amount, 10); !ok {
+		return Payment{}, fmt.Errorf("eth: invalid amount %q of payment %s", amount, txHash)
+	}
+	if payment.Paid, ok = new(big.Int).SetString(paid, 10); !ok {
+		return Payment{}, fmt.Errorf("eth: invalid paid amount %q of payment %s", paid, txHash)
+	}
+	payment.TxHash = common.HexToHash(txHash)
+	payment.Token = common.HexToAddress(token)
+	payment.Payer = common.HexToAddress(payer)
+	payment.Status = PaymentStatus(status)
+	if bh != "" {
+		payment.BlockHash = common.HexToHash(bh)
+	}
+	payment.CreatedAt = time.Unix(createdAt, 0).UTC()
+	payment.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return payment, nil
+}
+
+// blockHashColumn returns the block hash as stored, which is empty until the transaction is mined.
+func blockHashColumn(hash common.Hash) string {
+	if hash == (common.Hash{}) {
+		return ""
+	}
+	return hash.Hex()
+}
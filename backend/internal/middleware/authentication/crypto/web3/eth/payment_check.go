@@ -0,0 +1,167 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// transferTopic is the topic of the ERC-20 event Transfer(address indexed from, address indexed to, uint256 value).
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// checkResult is the on-chain state of the transaction of a payment.
+type checkResult struct {
+	status        PaymentStatus
+	reason        string
+	paid          *big.Int
+	blockNumber   uint64
+	blockHash     common.Hash
+	confirmations uint64
+
+	// mismatch is true when the transaction isn't sent by the payer or to the recipient, so it's not this payment at all.
+	mismatch bool
+}
+
+// failed returns the result of a transaction that doesn't pay what was expected.
+func failed(reason string) checkResult {
+	return checkResult{status: PaymentFailed, reason: reason}
+}
+
+// mismatched returns the result of a transaction that isn't sent by the payer or to the recipient.
+func mismatched(reason string) checkResult {
+	return checkResult{status: PaymentFailed, reason: reason, mismatch: true}
+}
+
+// check reads the transaction of the payment, its receipt and the head of the chain, then verifies
+// the chain ID, the recipient, the amount and the payer, and counts the confirmations.
+//
+// Note: The Ether sent by a contract (an internal transaction) isn't seen, since it's neither in the transaction nor in the logs,
+// so the Ether payments must be sent straight to the recipient. The ERC-20 payments may go through a contract (e.g., a router),
+// as long as the token emits a Transfer to the recipient.
+func (p *Payments) check(ctx context.Context, client *ethclient.Client, payment Payment) (checkResult, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return checkResult{}, err
+	}
+	if !chainID.IsInt64() || chainID.Int64() != p.cfg.ChainID {
+		return checkResult{}, fmt.Errorf("%w: %s, want %d", ErrNodeChainMismatch, chainID, p.cfg.ChainID)
+	}
+
+	tx, isPending, err := client.TransactionByHash(ctx, payment.TxHash)
+	if errors.Is(err, ethereum.NotFound) || isTxIndexing(err) || (err == nil && isPending) {
+		return checkResult{status: PaymentPending}, nil
+	}
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	// Note: A transaction without a chain ID (pre EIP-155) can be replayed on any chain, so it's rejected as well.
+	if tx.ChainId().Cmp(big.NewInt(p.cfg.ChainID)) != 0 {
+		return failed("transaction is for another chain"), nil
+	}
+
+	receipt, err := client.TransactionReceipt(ctx, payment.TxHash)
+	if errors.Is(err, ethereum.NotFound) || isTxIndexing(err) {
+		// Mined in a block that was just reorged out, or not indexed yet.
+		return checkResult{status: PaymentPending}, nil
+	}
+	if err != nil {
+		return checkResult{}, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return failed("transaction reverted"), nil
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return failed("invalid transaction signature"), nil
+	}
+
+	var paid *big.Int
+	if payment.Token == (common.Address{}) {
+		if to := tx.To(); to == nil || *to != p.cfg.Recipient {
+			return mismatched("transaction is not sent to the recipient"), nil
+		}
+		if payment.Payer != (common.Address{}) && sender != payment.Payer {
+			return mismatched("transaction is not sent by the payer"), nil
+		}
+		paid = tx.Value()
+	} else {
+		paid = p.transferred(receipt.Logs, payment.Token, payment.Payer)
+		if paid.Sign() == 0 {
+			return mismatched("transaction has no transfer of the token to the recipient"), nil
+		}
+	}
+	if paid.Cmp(payment.Amount) < 0 {
+		return checkResult{status: PaymentFailed, reason: "insufficient amount", paid: paid}, nil
+	}
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return checkResult{}, err
+	}
+	block := receipt.BlockNumber.Uint64()
+	result := checkResult{
+		status:      PaymentConfirming,
+		paid:        paid,
+		blockNumber: block,
+		blockHash:   receipt.BlockHash,
+	}
+	if head >= block {
+		result.confirmations = head - block + 1
+	}
+	if result.confirmations < p.cfg.Confirmations {
+		return result, nil
+	}
+
+	// Note: The receipt may come from a block that was reorged out since, in which case the payment keeps waiting
+	// for the confirmations of the block it's in now.
+	header, err := client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return checkResult{}, err
+	}
+	if header.Hash() != receipt.BlockHash {
+		result.confirmations = 0
+		return result, nil
+	}
+	result.status = PaymentConfirmed
+	return result, nil
+}
+
+// transferred returns the sum of the transfers of the token to the recipient in the logs, from the payer if it's not the zero address.
+func (p *Payments) transferred(logs []*types.Log, token, payer common.Address) *big.Int {
+	total := new(big.Int)
+	for _, l := range logs {
+		// Note: The indexed from and to make 3 topics, which tells the ERC-20 Transfer apart from the ERC-721 one (4 topics).
+		if l.Address != token || l.Removed || len(l.Topics) != 3 || l.Topics[0] != transferTopic || len(l.Data) != 32 {
+			continue
+		}
+		from := common.BytesToAddress(l.Topics[1].Bytes())
+		to := common.BytesToAddress(l.Topics[2].Bytes())
+		if to != p.cfg.Recipient || (payer != (common.Address{}) && from != payer) {
+			continue
+		}
+		total.Add(total, new(big.Int).SetBytes(l.Data))
+	}
+	return total
+}
+
+// isTxIndexing reports whether the node answered that it's still indexing the transactions (e.g., after a restart),
+// in which case a transaction it doesn't know yet may still be there.
+func isTxIndexing(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && rpcErr.Error() == "transaction indexing is in progress"
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gofiber/fiber/v2"
+)
+
+// submitPaymentRequest is the body of HandleSubmit.
+type submitPaymentRequest struct {
+	TxHash    string `json:"tx_hash"`
+	Reference string `json:"reference"`
+}
+
+// paymentResponse is the response of HandleSubmit and HandleGet.
+//
+// Note: The amounts are decimal strings, since they don't fit in a JavaScript number.
+type paymentResponse struct {
+	TxHash        string        `json:"tx_hash"`
+	Reference     string        `json:"reference"`
+	ChainID       int64         `json:"chain_id"`
+	Token         string        `json:"token,omitempty"`
+	Amount        string        `json:"amount"`
+	Paid          string        `json:"paid"`
+	Status        PaymentStatus `json:"status"`
+	Reason        string        `json:"reason,omitempty"`
+	BlockNumber   uint64        `json:"block_number,omitempty"`
+	Confirmations uint64        `json:"confirmations"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// newPaymentResponse returns the response of the payment.
+func newPaymentResponse(payment Payment) paymentResponse {
+	resp := paymentResponse{
+		TxHash:        payment.TxHash.Hex(),
+		Reference:     payment.Reference,
+		ChainID:       payment.ChainID,
+		Amount:        payment.Amount.String(),
+		Paid:          payment.Paid.String(),
+		Status:        payment.Status,
+		Reason:        payment.Reason,
+		BlockNumber:   payment.BlockNumber,
+		Confirmations: payment.Confirmations,
+		CreatedAt:     payment.CreatedAt,
+		UpdatedAt:     payment.UpdatedAt,
+	}
+	if payment.Token != (common.Address{}) {
+		resp.Token = payment.Token.Hex()
+	}
+	return resp
+}
+
+// HandleSubmit submits the transaction of the body as the payment of its reference, for the price returned by PaymentsConfig.Price.
+// It answers 200 OK when the payment is already final, or 202 Accepted while it's waiting to be mined or confirmed,
+// in which case the client polls HandleGet.
+//
+// The route must be behind SIWE.RequireAddress, since the payment must be sent by the signed-in address.
+// Otherwise anyone watching the chain could claim the payments of others for their own references.
+func (p *Payments) HandleSubmit(c *fiber.Ctx) error {
+	if p.cfg.Price == nil {
+		log.LogError("eth: PaymentsConfig.Price is required by HandleSubmit")
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+
+	var body submitPaymentRequest
+	if err := c.BodyParser(&body); err != nil {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	txHash, ok := parseTxHash(body.TxHash)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid transaction hash")
+	}
+
+	payer, ok := AddressFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	token, amount, err := p.cfg.Price(c.UserContext(), body.Reference)
+	if err != nil {
+		return p.sendError(c, err)
+	}
+	request := PaymentRequest{
+		TxHash:    txHash,
+		Reference: body.Reference,
+		Token:     token,
+		Amount:    amount,
+		Payer:     payer,
+	}
+
+	payment, err := p.Submit(c.UserContext(), request)
+	if err != nil {
+		return p.sendError(c, err)
+	}
+
+	log.LogUserActivity(c, "Submitted the Ethereum payment "+payment.TxHash.Hex()+" of "+payment.Reference)
+	status := fiber.StatusAccepted
+	if payment.Status.Final() {
+		status = fiber.StatusOK
+	}
+	return c.Status(status).JSON(newPaymentResponse(payment))
+}
+
+// HandleGet answers with the payment of the transaction hash of the route parameter ":tx_hash".
+func (p *Payments) HandleGet(c *fiber.Ctx) error {
+	txHash, ok := parseTxHash(c.Params("tx_hash"))
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid transaction hash")
+	}
+
+	payment, err := p.Get(c.UserContext(), txHash)
+	if err != nil {
+		return p.sendError(c, err)
+	}
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(newPaymentResponse(payment))
+}
+
+// Mount registers the routes of the payments on the router:
+//
+//   - "POST /", with the rate limiter (if not nil) in front, since each one stores a payment and calls the RPC endpoints.
+//   - "GET /:tx_hash".
+//
+// Note: Put SIWE.RequireAddress in front of the router, since "POST /" requires the signed-in address of the payer.
+func (p *Payments) Mount(router fiber.Router, rateLimiter fiber.Handler) {
+	if rateLimiter != nil {
+		router.Post("/", rateLimiter, p.HandleSubmit)
+	} else {
+		router.Post("/", p.HandleSubmit)
+	}
+	router.Get("/:tx_hash", p.HandleGet)
+}
+
+// parseTxHash parses a transaction hash (0x and 64 hex digits).
+func parseTxHash(s string) (common.Hash, bool) {
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(b), true
+}
+
+// sendError maps the errors of Payments to problem details.
+func (p *Payments) sendError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidPayment):
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid payment")
+	case errors.Is(err, ErrUnknownReference):
+		return helper.SendProblemResponse(c, fiber.StatusNotFound, "Unknown reference")
+	case errors.Is(err, ErrPaymentNotFound):
+		return helper.SendProblemResponse(c, fiber.StatusNotFound, "Payment not found")
+	case errors.Is(err, ErrPaymentExists):
+		return helper.SendProblemResponse(c, fiber.StatusConflict, "Transaction already submitted")
+	case errors.Is(err, ErrPaymentMismatch):
+		return helper.SendProblemResponse(c, fiber.StatusUnprocessableEntity, "Transaction is not a payment of the signed-in address")
+	default:
+		log.LogErrorf("Unexpected error in payments handler: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,431 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/web3/eth"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	recipient = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	// token is a fake ERC-20 that emits Transfer(msg.sender, to, value) for any call with (to, value),
+	// without keeping balances, which is all the payments look at.
+	token = common.HexToAddress("0x00000000000000000000000000000000000000c0")
+	// reverter is a contract that reverts any call.
+	reverter = common.HexToAddress("0x00000000000000000000000000000000000000c1")
+)
+
+// fakeTokenCode is the bytecode of token: MSTORE(0, CALLDATALOAD(32)), LOG3(0, 32, Transfer, CALLER, CALLDATALOAD(0)).
+var fakeTokenCode = append(append(
+	common.FromHex("0x602035600052600035337f"),
+	crypto.Keccak256([]byte("Transfer(address,address,uint256)"))...),
+	common.FromHex("0x60206000a300")...)
+
+// chain is a simulated chain with a funded payer.
+type chain struct {
+	backend *simulated.Backend
+	url     string
+	key     *ecdsa.PrivateKey
+	payer   common.Address
+	nonce   uint64
+}
+
+func newChain(t *testing.T) *chain {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(key.PublicKey)
+	backend, url := newSimulatedNode(t, types.GenesisAlloc{
+		payer:    {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))},
+		token:    {Code: fakeTokenCode, Balance: new(big.Int)},
+		reverter: {Code: common.FromHex("0x60006000fd"), Balance: new(big.Int)},
+	})
+	return &chain{backend: backend, url: url, key: key, payer: payer}
+}
+
+// send sends a transaction from the payer, without mining it.
+func (c *chain) send(t *testing.T, to common.Address, value *big.Int, data []byte) common.Hash {
+	t.Helper()
+	client := c.backend.Client()
+	gasPrice, err := client.SuggestGasPrice(t.Context())
+	if err != nil {
+		t.Fatalf("SuggestGasPrice() error = %v", err)
+	}
+	tx, err := types.SignNewTx(c.key, types.LatestSignerForChainID(big.NewInt(1337)), &types.LegacyTx{
+		Nonce:    c.nonce,
+		To:       &to,
+		Value:    value,
+		Gas:      100_000,
+		GasPrice: gasPrice,
+		Data:     data,
+	})
+	if err != nil {
+		t.Fatalf("SignNewTx() error = %v", err)
+	}
+	if err := client.SendTransaction(t.Context(), tx); err != nil {
+		t.Fatalf("SendTransaction() error = %v", err)
+	}
+	c.nonce++
+	return tx.Hash()
+}
+
+// transfer returns the call data of a transfer of the fake token.
+func transfer(to common.Address, value int64) []byte {
+	return append(common.LeftPadBytes(to.Bytes(), 32), common.LeftPadBytes(big.NewInt(value).Bytes(), 32)...)
+}
+
+// newPayments returns payments on the chain, over an in-process database.
+func newPayments(t *testing.T, c *chain, config eth.PaymentsConfig) *eth.Payments {
+	t.Helper()
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := eth.CreatePaymentsTable(t.Context(), db); err != nil {
+		t.Fatalf("CreatePaymentsTable() error = %v", err)
+	}
+
+	config.Pool = newPool(t, eth.PoolConfig{Endpoints: []eth.Endpoint{{Name: "simulated", URL: c.url}}, HealthCheckInterval: -1})
+	config.DB = db
+	config.Recipient = recipient
+	if config.ChainID == 0 {
+		config.ChainID = 1337
+	}
+	payments, err := eth.NewPayments(config)
+	if err != nil {
+		t.Fatalf("NewPayments() error = %v", err)
+	}
+	t.Cleanup(payments.Close)
+	return payments
+}
+
+func ether(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.Ether))
+}
+
+func TestPaymentsNative(t *testing.T) {
+	c := newChain(t)
+	var confirmed atomic.Int32
+	payments := newPayments(t, c, eth.PaymentsConfig{
+		Confirmations: 3,
+		OnConfirmed: func(ctx context.Context, p eth.Payment) {
+			confirmed.Add(1)
+		},
+	})
+
+	txHash := c.send(t, recipient, ether(1), nil)
+	c.backend.Commit()
+
+	request := eth.PaymentRequest{TxHash: txHash, Reference: "order-1", Amount: ether(1), Payer: c.payer}
+	p, err := payments.Submit(t.Context(), request)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if p.Status != eth.PaymentConfirming || p.Confirmations != 1 || p.Paid.Cmp(ether(1)) != 0 || p.BlockNumber != 1 {
+		t.Fatalf("Submit() = %+v, want confirming with 1 confirmation", p)
+	}
+
+	if _, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-2", Amount: ether(1)}); !errors.Is(err, eth.ErrPaymentExists) {
+		t.Errorf("Submit() of the same transaction error = %v, want %v", err, eth.ErrPaymentExists)
+	}
+
+	c.backend.Commit()
+	c.backend.Commit()
+	for range 2 {
+		if err := payments.Poll(t.Context()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+	}
+
+	got, err := payments.Get(t.Context(), txHash)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Status != eth.PaymentConfirmed || got.Confirmations != 3 || got.Payer != c.payer || got.Reference != "order-1" {
+		t.Errorf("Get() = %+v, want confirmed with 3 confirmations", got)
+	}
+	if n := confirmed.Load(); n != 1 {
+		t.Errorf("OnConfirmed called %d times, want 1", n)
+	}
+
+	list, err := payments.ListByReference(t.Context(), "order-1")
+	if err != nil || len(list) != 1 || list[0].TxHash != txHash {
+		t.Errorf("ListByReference() = %+v, %v, want the payment", list, err)
+	}
+}
+
+func TestPaymentsERC20(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{Confirmations: 1})
+
+	txHash := c.send(t, token, new(big.Int), transfer(recipient, 500))
+	c.backend.Commit()
+
+	p, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-1", Token: token, Amount: big.NewInt(500), Payer: c.payer})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if p.Status != eth.PaymentConfirmed || p.Paid.Int64() != 500 {
+		t.Errorf("Submit() = %+v, want confirmed with 500 paid", p)
+	}
+}
+
+func TestPaymentsFailures(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{Confirmations: 1})
+
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	tests := []struct {
+		name     string
+		tx       common.Hash
+		request  eth.PaymentRequest
+		reason   string
+		mismatch bool // rejected without being stored
+	}{
+		{
+			name:    "Insufficient amount",
+			tx:      c.send(t, recipient, ether(1), nil),
+			request: eth.PaymentRequest{Amount: ether(2)},
+			reason:  "insufficient amount",
+		},
+		{
+			name:     "Wrong recipient",
+			tx:       c.send(t, other, ether(1), nil),
+			request:  eth.PaymentRequest{Amount: ether(1)},
+			reason:   "transaction is not sent to the recipient",
+			mismatch: true,
+		},
+		{
+			name:     "Wrong payer",
+			tx:       c.send(t, recipient, ether(1), nil),
+			request:  eth.PaymentRequest{Amount: ether(1), Payer: other},
+			reason:   "transaction is not sent by the payer",
+			mismatch: true,
+		},
+		{
+			name:     "Wrong token",
+			tx:       c.send(t, token, new(big.Int), transfer(recipient, 500)),
+			request:  eth.PaymentRequest{Amount: big.NewInt(500), Token: other},
+			reason:   "transaction has no transfer of the token to the recipient",
+			mismatch: true,
+		},
+		{
+			name:     "Token to another recipient",
+			tx:       c.send(t, token, new(big.Int), transfer(other, 500)),
+			request:  eth.PaymentRequest{Amount: big.NewInt(500), Token: token},
+			reason:   "transaction has no transfer of the token to the recipient",
+			mismatch: true,
+		},
+		{
+			name:    "Reverted",
+			tx:      c.send(t, reverter, new(big.Int), nil),
+			request: eth.PaymentRequest{Amount: big.NewInt(1), Token: token},
+			reason:  "transaction reverted",
+		},
+	}
+	c.backend.Commit()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.TxHash = tt.tx
+			tt.request.Reference = tt.name
+			p, err := payments.Submit(t.Context(), tt.request)
+			if tt.mismatch {
+				if !errors.Is(err, eth.ErrPaymentMismatch) || !strings.Contains(err.Error(), tt.reason) {
+					t.Errorf("Submit() error = %v, want %v (%s)", err, eth.ErrPaymentMismatch, tt.reason)
+				}
+				if _, err := payments.Get(t.Context(), tt.tx); !errors.Is(err, eth.ErrPaymentNotFound) {
+					t.Errorf("Get() of a rejected payment error = %v, want %v", err, eth.ErrPaymentNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if p.Status != eth.PaymentFailed || p.Reason != tt.reason {
+				t.Errorf("Submit() = %s %q, want %s %q", p.Status, p.Reason, eth.PaymentFailed, tt.reason)
+			}
+		})
+	}
+
+	if _, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: common.Hash{1}, Reference: "free"}); !errors.Is(err, eth.ErrInvalidPayment) {
+		t.Errorf("Submit() without an amount error = %v, want %v", err, eth.ErrInvalidPayment)
+	}
+}
+
+func TestPaymentsReplaceFailed(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{Confirmations: 1})
+
+	txHash := c.send(t, recipient, ether(1), nil)
+	c.backend.Commit()
+
+	// Someone else submits the transaction first, for a reference it doesn't pay.
+	p, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-expensive", Amount: ether(5), Payer: c.payer})
+	if err != nil || p.Status != eth.PaymentFailed {
+		t.Fatalf("Submit() of the wrong reference = %s, %v, want it failed", p.Status, err)
+	}
+
+	// The payer can still submit it for the right one, which replaces the failed payment.
+	p, err = payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-1", Amount: ether(1), Payer: c.payer})
+	if err != nil || p.Status != eth.PaymentConfirmed {
+		t.Fatalf("Submit() after a failed payment = %s, %v, want it confirmed", p.Status, err)
+	}
+	got, err := payments.Get(t.Context(), txHash)
+	if err != nil || got.Reference != "order-1" || got.Status != eth.PaymentConfirmed || got.Reason != "" {
+		t.Errorf("Get() = %+v, %v, want the confirmed payment of order-1", got, err)
+	}
+
+	// A payment that didn't fail can't be replaced.
+	if _, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-2", Amount: ether(1), Payer: c.payer}); !errors.Is(err, eth.ErrPaymentExists) {
+		t.Errorf("Submit() of a confirmed transaction error = %v, want %v", err, eth.ErrPaymentExists)
+	}
+}
+
+func TestPaymentsPending(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{Confirmations: 1, PollInterval: 100 * time.Millisecond})
+
+	txHash := c.send(t, recipient, ether(1), nil)
+	p, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-1", Amount: ether(1)})
+	if err != nil || p.Status != eth.PaymentPending {
+		t.Fatalf("Submit() of an unmined transaction = %s, %v, want %s", p.Status, err, eth.PaymentPending)
+	}
+
+	// The background poller confirms it once mined.
+	payments.Start()
+	c.backend.Commit()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, err = payments.Get(t.Context(), txHash)
+		if err == nil && p.Status == eth.PaymentConfirmed {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Get() = %s, %v after 5s, want %s", p.Status, err, eth.PaymentConfirmed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPaymentsPendingTimeout(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{PendingTimeout: time.Nanosecond})
+
+	p, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: common.Hash{1}, Reference: "order-1", Amount: ether(1)})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if p.Status != eth.PaymentFailed || p.Reason != "transaction not mined in time" {
+		t.Errorf("Submit() of an unknown transaction = %s %q, want it failed", p.Status, p.Reason)
+	}
+}
+
+func TestPaymentsChainMismatch(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{ChainID: 1})
+
+	txHash := c.send(t, recipient, ether(1), nil)
+	c.backend.Commit()
+	p, err := payments.Submit(t.Context(), eth.PaymentRequest{TxHash: txHash, Reference: "order-1", Amount: ether(1)})
+	if err != nil || p.Status != eth.PaymentPending {
+		t.Fatalf("Submit() = %s, %v, want it pending", p.Status, err)
+	}
+	if err := payments.Poll(t.Context()); !errors.Is(err, eth.ErrNodeChainMismatch) {
+		t.Errorf("Poll() error = %v, want %v", err, eth.ErrNodeChainMismatch)
+	}
+}
+
+func TestPaymentsHandlers(t *testing.T) {
+	c := newChain(t)
+	payments := newPayments(t, c, eth.PaymentsConfig{
+		Confirmations: 1,
+		Price: func(ctx context.Context, reference string) (common.Address, *big.Int, error) {
+			if reference != "order-1" {
+				return common.Address{}, nil, eth.ErrUnknownReference
+			}
+			return common.Address{}, ether(1), nil
+		},
+	})
+	app := fiber.New()
+	// Stands in for SIWE.RequireAddress, which loads the signed-in address under "siwe_address" (see AddressFromContext).
+	payments.Mount(app.Group("/payments", func(c *fiber.Ctx) error {
+		if payer := c.Get("X-Payer"); payer != "" {
+			c.Locals("siwe_address", common.HexToAddress(payer))
+		}
+		return c.Next()
+	}), nil)
+
+	txHash := c.send(t, recipient, ether(1), nil)
+	c.backend.Commit()
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	do := func(method, path, body string, payer common.Address) (int, map[string]any) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if payer != (common.Address{}) {
+			req.Header.Set("X-Payer", payer.Hex())
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		defer resp.Body.Close()
+		var got map[string]any
+		json.NewDecoder(resp.Body).Decode(&got)
+		return resp.StatusCode, got
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		payer      common.Address
+		wantStatus int
+	}{
+		{"Unknown reference", http.MethodPost, "/payments", `{"tx_hash":"` + txHash.Hex() + `","reference":"order-2"}`, c.payer, fiber.StatusNotFound},
+		{"Invalid hash", http.MethodPost, "/payments", `{"tx_hash":"0x1234","reference":"order-1"}`, c.payer, fiber.StatusBadRequest},
+		{"Not signed in", http.MethodPost, "/payments", `{"tx_hash":"` + txHash.Hex() + `","reference":"order-1"}`, common.Address{}, fiber.StatusUnauthorized},
+		{"Someone else", http.MethodPost, "/payments", `{"tx_hash":"` + txHash.Hex() + `","reference":"order-1"}`, other, fiber.StatusUnprocessableEntity},
+		{"Submit", http.MethodPost, "/payments", `{"tx_hash":"` + txHash.Hex() + `","reference":"order-1"}`, c.payer, fiber.StatusOK},
+		{"Submit again", http.MethodPost, "/payments", `{"tx_hash":"` + txHash.Hex() + `","reference":"order-1"}`, c.payer, fiber.StatusConflict},
+		{"Get", http.MethodGet, "/payments/" + txHash.Hex(), "", common.Address{}, fiber.StatusOK},
+		{"Get unknown", http.MethodGet, "/payments/" + common.Hash{1}.Hex(), "", common.Address{}, fiber.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := do(tt.method, tt.path, tt.body, tt.payer)
+			if status != tt.wantStatus {
+				t.Fatalf("%s %s status = %d, want %d (%v)", tt.method, tt.path, status, tt.wantStatus, body)
+			}
+			if status == fiber.StatusOK && (body["status"] != string(eth.PaymentConfirmed) || body["amount"] != ether(1).String()) {
+				t.Errorf("%s %s = %v, want the confirmed payment", tt.method, tt.path, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// ErrMissingEndpoints is returned by NewPool when there is no endpoint.
+	ErrMissingEndpoints = errors.New("eth: at least one RPC endpoint is required")
+
+	// ErrNoHealthyEndpoint is returned when every endpoint failed a call.
+	ErrNoHealthyEndpoint = errors.New("eth: no healthy RPC endpoint")
+
+	// ErrPoolClosed is returned by the calls made after Pool.Close.
+	ErrPoolClosed = errors.New("eth: pool is closed")
+)
+
+// Endpoint is an RPC endpoint of an Ethereum node (e.g., a provider such as Infura or Alchemy, or a self-hosted node).
+type Endpoint struct {
+	// Name labels the endpoint in the logs and the metrics.
+	//
+	// Optional. Default: the host of URL
+	Name string
+
+	// URL is the URL of the endpoint ("https://", "wss://" or an IPC path), which may carry an API key.
+	//
+	// Required.
+	URL string
+}
+
+// PoolConfig defines the config for the Pool.
+type PoolConfig struct {
+	// Endpoints are the RPC endpoints, by order of preference. The calls fail over to the next healthy one.
+	//
+	// Required.
+	Endpoints []Endpoint
+
+	// DialTimeout limits the connection to an endpoint (e.g., the WebSocket handshake).
+	//
+	// Optional. Default: 10 * time.Second
+	DialTimeout time.Duration
+
+	// CallTimeout limits each call to an endpoint, so a stuck endpoint fails over instead of hanging the request.
+	//
+	// Optional. Default: 10 * time.Second
+	CallTimeout time.Duration
+
+	// HealthCheckInterval is the interval of the health checks (eth_blockNumber) of the endpoints.
+	// A negative interval disables them, in which case only the failed calls mark the endpoints as unhealthy.
+	//
+	// Optional. Default: 30 * time.Second
+	HealthCheckInterval time.Duration
+}
+
+// Pool is a pool of long-lived Ethereum clients, one per RPC endpoint, shared by all the requests.
+//
+// The clients are dialed lazily (on the first call to their endpoint), then kept open, so the requests don't pay
+// for a new TCP/TLS (or WebSocket) connection. The endpoints are health-checked in the background, and each call
+// fails over to the next healthy endpoint when the current one is down (see Do).
+type Pool struct {
+	cfg       PoolConfig
+	endpoints []*endpoint
+
+	startOnce sync.Once
+	closeOnce sync.Once
+	closed    atomic.Bool
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// endpoint is an endpoint with its client and its health.
+type endpoint struct {
+	Endpoint
+
+	mu     sync.Mutex
+	client *ethclient.Client
+
+	// healthy is true until a call or a health check fails, then until one succeeds.
+	healthy atomic.Bool
+	// checked is true once a health check or a call completed, so the health is known.
+	checked atomic.Bool
+}
+
+// NewPool returns a new Pool with the config. No endpoint is dialed until the first call.
+//
+// Example Usage:
+//
+//	pool, err := eth.NewPool(eth.PoolConfig{
+//	    Endpoints: []eth.Endpoint{
+//	        {Name: "primary", URL: "https://mainnet.infura.io/v3/" + apiKey},
+//	        {Name: "fallback", URL: "https://eth.btz.pm"},
+//	    },
+//	})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	defer pool.Close()
+//
+//	blockNumber, err := eth.Call(ctx, pool, func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+//	    return client.BlockNumber(ctx)
+//	})
+func NewPool(config PoolConfig) (*Pool, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, ErrMissingEndpoints
+	}
+	config.DialTimeout = cmp.Or(config.DialTimeout, 10*time.Second)
+	config.CallTimeout = cmp.Or(config.CallTimeout, 10*time.Second)
+	config.HealthCheckInterval = cmp.Or(config.HealthCheckInterval, 30*time.Second)
+
+	p := &Pool{
+		cfg:  config,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for _, e := range config.Endpoints {
+		if e.URL == "" {
+			return nil, fmt.Errorf("eth: missing URL of endpoint %q", e.Name)
+		}
+		if e.Name == "" {
+			u, err := url.Parse(e.URL)
+			if err != nil || u.Host == "" {
+				return nil, fmt.Errorf("eth: endpoint %q must have a name", e.URL)
+			}
+			e.Name = u.Host
+		}
+		ep := &endpoint{Endpoint: e}
+		ep.healthy.Store(true)
+		p.endpoints = append(p.endpoints, ep)
+	}
+	return p, nil
+}
+
+// Do calls fn with the client of the first healthy endpoint, and a context limited by PoolConfig.CallTimeout.
+// When the endpoint is down (e.g., a network error, a timeout, an HTTP 5xx or 429), it's marked as unhealthy,
+// and fn is called again with the next endpoint. The unhealthy endpoints are tried last.
+//
+// The errors answered by a node (e.g., ethereum.NotFound or a reverted call) are returned as is, without failover.
+//
+// Note: fn may be called several times, so it should only read the chain, or send the same signed transaction
+// (which the nodes deduplicate by its hash).
+func (p *Pool) Do(ctx context.Context, fn func(ctx context.Context, client *ethclient.Client) error) error {
+	if p.closed.Load() {
+		return ErrPoolClosed
+	}
+	p.startOnce.Do(p.start)
+
+	var errs []error
+	for _, e := range p.order() {
+		client, err := e.dial(ctx, p.cfg.DialTimeout)
+		if err != nil {
+			err = e.redact(err)
+			p.markDown(e, err)
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+			continue
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, p.cfg.CallTimeout)
+		start := time.Now()
+		err = e.redact(fn(callCtx, client))
+		cancel()
+		rpcCallDuration.WithLabelValues(e.Name).Observe(time.Since(start).Seconds())
+
+		switch {
+		case err == nil:
+			rpcCalls.WithLabelValues(e.Name, "ok").Inc()
+			p.markUp(e)
+			return nil
+		case ctx.Err() != nil || !isEndpointFailure(err):
+			// Note: The caller gave up, or the node answered, so the endpoint is fine.
+			rpcCalls.WithLabelValues(e.Name, "error").Inc()
+			return err
+		}
+
+		rpcCalls.WithLabelValues(e.Name, "failover").Inc()
+		p.markDown(e, err)
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+	}
+	return fmt.Errorf("%w: %w", ErrNoHealthyEndpoint, errors.Join(errs...))
+}
+
+// Call is Do for a call that returns a value.
+func Call[T any](ctx context.Context, p *Pool, fn func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	var result T
+	err := p.Do(ctx, func(ctx context.Context, client *ethclient.Client) error {
+		var err error
+		result, err = fn(ctx, client)
+		return err
+	})
+	return result, err
+}
+
+// Down reports whether every endpoint is known to be unhealthy.
+func (p *Pool) Down() bool {
+	for _, e := range p.endpoints {
+		if !e.checked.Load() || e.healthy.Load() {
+			return false
+		}
+	}
+	return true
+}
+
+// Close stops the health checks, and closes the clients.
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		p.closed.Store(true)
+		close(p.stop)
+		// Note: The health checks only run once started by a call.
+		p.startOnce.Do(func() { close(p.done) })
+		<-p.done
+
+		for _, e := range p.endpoints {
+			e.mu.Lock()
+			if e.client != nil {
+				e.client.Close()
+				e.client = nil
+			}
+			e.mu.Unlock()
+		}
+	})
+}
+
+// order returns the healthy endpoints, then the unhealthy ones, each by order of preference.
+func (p *Pool) order() []*endpoint {
+	healthy := make([]*endpoint, 0, len(p.endpoints))
+	var unhealthy []*endpoint
+	for _, e := range p.endpoints {
+		if e.healthy.Load() {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// markUp marks the endpoint as healthy.
+func (p *Pool) markUp(e *endpoint) {
+	e.checked.Store(true)
+	if !e.healthy.Swap(true) {
+		log.LogInfof("Ethereum RPC endpoint %s is healthy again", e.Name)
+	}
+	endpointUp.WithLabelValues(e.Name).Set(1)
+}
+
+// markDown marks the endpoint as unhealthy. The error must be redacted (see endpoint.redact).
+func (p *Pool) markDown(e *endpoint, err error) {
+	e.checked.Store(true)
+	if e.healthy.Swap(false) {
+		log.LogErrorf("Ethereum RPC endpoint %s is unhealthy: %v", e.Name, err)
+	}
+	endpointUp.WithLabelValues(e.Name).Set(0)
+}
+
+// start starts the health checks, unless they are disabled.
+func (p *Pool) start() {
+	if p.cfg.HealthCheckInterval < 0 {
+		close(p.done)
+		return
+	}
+	go p.healthChecks()
+}
+
+// healthChecks checks the endpoints right away, then at every interval, until the pool is closed.
+func (p *Pool) healthChecks() {
+	defer close(p.done)
+	ticker := time.NewTicker(p.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		p.checkAll()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll checks every endpoint concurrently, so a stuck one doesn't delay the others.
+func (p *Pool) checkAll() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, e := range p.endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.check(ctx, e)
+		}()
+	}
+	wg.Wait()
+}
+
+// check checks the endpoint with eth_blockNumber.
+func (p *Pool) check(ctx context.Context, e *endpoint) {
+	client, err := e.dial(ctx, p.cfg.DialTimeout)
+	if err == nil {
+		callCtx, cancel := context.WithTimeout(ctx, p.cfg.CallTimeout)
+		var head uint64
+		head, err = client.BlockNumber(callCtx)
+		cancel()
+		if err == nil {
+			endpointHead.WithLabelValues(e.Name).Set(float64(head))
+		}
+	}
+
+	if ctx.Err() != nil {
+		return // The pool is closing.
+	}
+	if err != nil {
+		p.markDown(e, e.redact(err))
+		return
+	}
+	p.markUp(e)
+}
+
+// dial returns the client of the endpoint, dialing it on the first call.
+//
+// Note: The client is kept after a failure, since the RPC client reconnects by itself (WebSocket, IPC),
+// or doesn't keep a connection at all (HTTP, whose transport pools the connections).
+func (e *endpoint) dial(ctx context.Context, timeout time.Duration) (*ethclient.Client, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client, err := ethclient.DialContext(ctx, e.URL)
+	if err != nil {
+		return nil, err
+	}
+	e.client = client
+	return client, nil
+}
+
+// redact returns the error without the URL of the endpoint, which may carry an API key
+// (e.g., the *url.Error of the HTTP transport), so it can be logged and returned to the caller.
+// The URL is replaced by the name of the endpoint, and the errors without it are returned as is.
+func (e *endpoint) redact(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// Note: The *url.Error itself isn't kept in the chain, since it holds the URL as well.
+		msg = strings.ReplaceAll(msg, urlErr.URL, e.Name)
+		return &redactedError{msg: strings.ReplaceAll(msg, e.URL, e.Name), err: urlErr.Err}
+	}
+	if !strings.Contains(msg, e.URL) {
+		return err
+	}
+	return &redactedError{msg: strings.ReplaceAll(msg, e.URL, e.Name), err: err}
+}
+
+// redactedError is an error whose message doesn't include the URL of the endpoint (see endpoint.redact).
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// isEndpointFailure reports whether the error of a call means that the endpoint is down, rather than an answer of the node.
+func isEndpointFailure(err error) bool {
+	if errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 429
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// Note: -32005 is the "limit exceeded" of the providers (EIP-1474), which is worth another endpoint.
+		return rpcErr.ErrorCode() == -32005
+	}
+	return true
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/web3/eth"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	neturl "net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newSimulatedNode starts a simulated chain serving JSON-RPC over HTTP, like a real node, and returns its backend and URL.
+func newSimulatedNode(t *testing.T, alloc types.GenesisAlloc) (*simulated.Backend, string) {
+	t.Helper()
+	// Note: The simulated backend has no way to report the port it listens on, so a free one is picked first.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.HTTPHost = "127.0.0.1"
+		nodeConf.HTTPPort = port
+		nodeConf.HTTPModules = []string{"eth", "net", "web3"}
+	})
+	t.Cleanup(func() { backend.Close() })
+	return backend, fmt.Sprintf("http://127.0.0.1:%d", port)
+}
+
+// deadEndpoint returns the URL of an endpoint that refuses the connections.
+func deadEndpoint(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	l.Close()
+	return "http://" + l.Addr().String()
+}
+
+// newPool returns a pool, closed at the end of the test.
+func newPool(t *testing.T, config eth.PoolConfig) *eth.Pool {
+	t.Helper()
+	log.InitializeLogger("Gopher Testing", "unix")
+	pool, err := eth.NewPool(config)
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func blockNumber(ctx context.Context, client *ethclient.Client) (uint64, error) {
+	return client.BlockNumber(ctx)
+}
+
+func TestPoolFailover(t *testing.T) {
+	backend, url := newSimulatedNode(t, nil)
+	backend.Commit()
+	backend.Commit()
+
+	pool := newPool(t, eth.PoolConfig{
+		Endpoints: []eth.Endpoint{
+			{Name: "dead", URL: deadEndpoint(t)},
+			{Name: "simulated", URL: url},
+		},
+		HealthCheckInterval: -1,
+	})
+
+	got, err := eth.Call(t.Context(), pool, blockNumber)
+	if err != nil || got != 2 {
+		t.Fatalf("Call(BlockNumber) = %d, %v, want 2", got, err)
+	}
+	if pool.Down() {
+		t.Error("Down() = true, want false")
+	}
+
+	// The dead endpoint is now tried last, so the calls go straight to the healthy one.
+	var endpoints []string
+	err = pool.Do(t.Context(), func(ctx context.Context, client *ethclient.Client) error {
+		id, err := client.ChainID(ctx)
+		endpoints = append(endpoints, id.String())
+		return err
+	})
+	if err != nil || len(endpoints) != 1 || endpoints[0] != "1337" {
+		t.Errorf("Do(ChainID) = %v, %v, want a single call on chain 1337", endpoints, err)
+	}
+}
+
+func TestPoolNodeErrors(t *testing.T) {
+	_, url := newSimulatedNode(t, nil)
+	_, other := newSimulatedNode(t, nil)
+	pool := newPool(t, eth.PoolConfig{
+		Endpoints:           []eth.Endpoint{{Name: "first", URL: url}, {Name: "second", URL: other}},
+		HealthCheckInterval: -1,
+	})
+
+	// An answer of the node isn't a failure of the endpoint, so it's not retried.
+	var calls int
+	err := pool.Do(t.Context(), func(ctx context.Context, client *ethclient.Client) error {
+		calls++
+		_, err := client.HeaderByNumber(ctx, big.NewInt(1000))
+		return err
+	})
+	if !errors.Is(err, ethereum.NotFound) || calls != 1 {
+		t.Errorf("Do(HeaderByNumber) = %v after %d calls, want %v after 1 call", err, calls, ethereum.NotFound)
+	}
+}
+
+func TestPoolCallTimeout(t *testing.T) {
+	_, url := newSimulatedNode(t, nil)
+	pool := newPool(t, eth.PoolConfig{
+		Endpoints:           []eth.Endpoint{{Name: "stuck", URL: url}, {Name: "also-stuck", URL: url}},
+		CallTimeout:         50 * time.Millisecond,
+		HealthCheckInterval: -1,
+	})
+
+	var calls atomic.Int32
+	start := time.Now()
+	err := pool.Do(t.Context(), func(ctx context.Context, client *ethclient.Client) error {
+		calls.Add(1)
+		<-ctx.Done() // A stuck endpoint.
+		return ctx.Err()
+	})
+	if !errors.Is(err, eth.ErrNoHealthyEndpoint) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want %v and %v", err, eth.ErrNoHealthyEndpoint, context.DeadlineExceeded)
+	}
+	if calls.Load() != 2 || time.Since(start) > 2*time.Second {
+		t.Errorf("Do() made %d calls in %s, want 2 calls limited by the call timeout", calls.Load(), time.Since(start))
+	}
+	if !pool.Down() {
+		t.Error("Down() = false after every endpoint failed, want true")
+	}
+
+	// A canceled caller isn't a failure of the endpoint.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := pool.Do(ctx, func(ctx context.Context, client *ethclient.Client) error { return ctx.Err() }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() with a canceled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestPoolHealthChecks(t *testing.T) {
+	backend, url := newSimulatedNode(t, nil)
+
+	// A proxy in front of the node, which can be taken down.
+	var down atomic.Bool
+	target, _ := neturl.Parse(url)
+	rpcProxy := httputil.NewSingleHostReverseProxy(target)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		rpcProxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	pool := newPool(t, eth.PoolConfig{
+		Endpoints:           []eth.Endpoint{{Name: "proxied", URL: proxy.URL}},
+		HealthCheckInterval: 20 * time.Millisecond,
+	})
+	if _, err := eth.Call(t.Context(), pool, blockNumber); err != nil {
+		t.Fatalf("Call(BlockNumber) error = %v", err)
+	}
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for pool.Down() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Down() = %v after 5s, want %v", !want, want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	down.Store(true)
+	waitFor(true)
+
+	// The middleware stops the requests while every endpoint is down.
+	app := fiber.New()
+	app.Get("/block", eth.New(eth.Config{Pool: pool, ContextKey: "eth"}), func(c *fiber.Ctx) error {
+		pool := c.Locals("eth").(*eth.Pool)
+		n, err := eth.Call(c.UserContext(), pool, blockNumber)
+		if err != nil {
+			return err
+		}
+		return c.SendString(new(big.Int).SetUint64(n).String())
+	})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/block", nil), -1)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("GET /block while down status = %d, want %d", resp.StatusCode, fiber.StatusServiceUnavailable)
+	}
+
+	down.Store(false)
+	waitFor(false)
+	backend.Commit()
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/block", nil), -1)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || string(body) != "1" {
+		t.Errorf("GET /block = %d %q, want %d %q", resp.StatusCode, body, fiber.StatusOK, "1")
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		endpoints []eth.Endpoint
+		wantErr   bool
+	}{
+		{"No endpoint", nil, true},
+		{"Missing URL", []eth.Endpoint{{Name: "node"}}, true},
+		{"IPC path without a name", []eth.Endpoint{{URL: "/var/run/geth.ipc"}}, true},
+		{"IPC path with a name", []eth.Endpoint{{Name: "local", URL: "/var/run/geth.ipc"}}, false},
+		{"URL", []eth.Endpoint{{URL: "https://eth.btz.pm"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := eth.NewPool(eth.PoolConfig{Endpoints: tt.endpoints})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if pool != nil {
+				pool.Close()
+				if err := pool.Do(t.Context(), func(context.Context, *ethclient.Client) error { return nil }); !errors.Is(err, eth.ErrPoolClosed) {
+					t.Errorf("Do() after Close() error = %v, want %v", err, eth.ErrPoolClosed)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolRedactsURL(t *testing.T) {
+	// The URL carries an API key, like the URLs of the providers (e.g., Infura).
+	dead := deadEndpoint(t)
+	const apiKey = "0123456789abcdef"
+	pool := newPool(t, eth.PoolConfig{
+		Endpoints:           []eth.Endpoint{{URL: dead + "/v3/" + apiKey}},
+		HealthCheckInterval: -1,
+	})
+
+	_, err := eth.Call(t.Context(), pool, blockNumber)
+	if !errors.Is(err, eth.ErrNoHealthyEndpoint) {
+		t.Fatalf("Call(BlockNumber) error = %v, want %v", err, eth.ErrNoHealthyEndpoint)
+	}
+	if strings.Contains(err.Error(), apiKey) {
+		t.Errorf("Call(BlockNumber) error = %q, want it without the API key", err)
+	}
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		t.Errorf("Call(BlockNumber) error wraps %v, want the *url.Error left out", urlErr)
+	}
+	if host := strings.TrimPrefix(dead, "http://"); !strings.Contains(err.Error(), host) {
+		t.Errorf("Call(BlockNumber) error = %q, want it to name the endpoint %s", err, host)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	isClosed := func(pool *eth.Pool) bool {
+		err := pool.Do(t.Context(), func(context.Context, *ethclient.Client) error { return nil })
+		return errors.Is(err, eth.ErrPoolClosed)
+	}
+
+	// The pool created by the client is closed with it.
+	client, err := eth.NewClient(eth.Config{URL: deadEndpoint(t), HealthCheckInterval: -1})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.Close()
+	if !isClosed(client.Pool()) {
+		t.Error("the pool of the client is still open after Close()")
+	}
+
+	// A shared pool is left to its owner.
+	shared := newPool(t, eth.PoolConfig{Endpoints: []eth.Endpoint{{URL: deadEndpoint(t)}}, HealthCheckInterval: -1})
+	client, err = eth.NewClient(eth.Config{Pool: shared})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	client.Close()
+	if isClosed(shared) {
+		t.Error("the shared pool was closed by the client")
+	}
+
+	if _, err := eth.NewClient(eth.Config{}); !errors.Is(err, eth.ErrMissingEndpoints) {
+		t.Errorf("NewClient() without endpoints error = %v, want %v", err, eth.ErrMissingEndpoints)
+	}
+
+	// The pool created by New is closed when the app shuts down.
+	var pool *eth.Pool
+	app := fiber.New()
+	app.Get("/", eth.New(eth.Config{URL: deadEndpoint(t), ContextKey: "eth", HealthCheckInterval: -1}), func(c *fiber.Ctx) error {
+		pool = c.Locals("eth").(*eth.Pool)
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	resp.Body.Close()
+	if err := app.Shutdown(); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if pool == nil || !isClosed(pool) {
+		t.Error("the pool created by New is still open after the app shut down")
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrInvalidSignature is returned when a signature is malformed, or wasn't made by the expected address.
+var ErrInvalidSignature = errors.New("eth: invalid signature")
+
+// RecoverAddress returns the address that signed the message with personal_sign (EIP-191, version 0x45),
+// which is how the wallets sign the SIWE messages.
+//
+// The signature is the 65 bytes [R || S || V], where V is either 0/1 or 27/28 (depending on the wallet).
+//
+// Note: It works offline, so it doesn't support the signatures of the smart contract wallets (EIP-1271),
+// which must be checked by calling the wallet contract.
+func RecoverAddress(message string, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, ErrInvalidSignature
+	}
+
+	// Note: The malleable signatures (an S in the upper half of the curve order) are rejected, like Ethereum does since Homestead.
+	if !crypto.ValidateSignatureValues(sig[crypto.RecoveryIDOffset], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64]), true) {
+		return common.Address{}, ErrInvalidSignature
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, ErrInvalidSignature
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth
+
+import (
+	"cmp"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrMissingDomain is returned by NewSIWE when SIWEConfig.Domain is empty.
+	ErrMissingDomain = errors.New("eth: SIWE domain is required")
+
+	// ErrMissingStorage is returned by NewSIWE when SIWEConfig.Storage is nil.
+	ErrMissingStorage = errors.New("eth: SIWE storage is required")
+
+	// ErrDomainMismatch is returned when a message was made for another domain, scheme or URI (e.g., by a phishing site).
+	ErrDomainMismatch = errors.New("eth: SIWE message is for another domain")
+
+	// ErrChainIDMismatch is returned when a message is for another chain.
+	ErrChainIDMismatch = errors.New("eth: SIWE message is for another chain")
+
+	// ErrExpiredMessage is returned when a message is expired, not yet valid, or issued in the future.
+	ErrExpiredMessage = errors.New("eth: SIWE message expired or not yet valid")
+
+	// ErrInvalidNonce is returned when the nonce of a message wasn't issued by the server, expired, or was already used.
+	ErrInvalidNonce = errors.New("eth: invalid or used SIWE nonce")
+)
+
+// clockSkew is the tolerated difference between the clocks of the wallets and the server.
+const clockSkew = time.Minute
+
+// SIWEConfig defines the config for Sign-In with Ethereum.
+type SIWEConfig struct {
+	// Domain is the authority (host and optional port) of the application, as seen by the browsers (e.g., "example.com").
+	// The messages for another domain are rejected, which is what protects the users against phishing sites.
+	//
+	// Required.
+	Domain string
+
+	// URI is the origin of the application, which the messages must use.
+	//
+	// Optional. Default: "https://" + Domain
+	URI string
+
+	// ChainID is the EIP-155 chain ID the sessions are bound to.
+	//
+	// Optional. Default: 1 (Ethereum mainnet)
+	ChainID int64
+
+	// Statement is the statement of the messages built by the server (see HandleMessage).
+	//
+	// Optional. Default: "Sign in with Ethereum."
+	Statement string
+
+	// Storage stores the nonces until they are used or expired (e.g., database.Service.FiberStorage).
+	//
+	// Required.
+	Storage fiber.Storage
+
+	// NonceTTL is how long a nonce can be used, which is also the expiration time of the messages built by the server.
+	//
+	// Optional. Default: 5 * time.Minute
+	NonceTTL time.Duration
+
+	// KeyPrefix is the prefix of the keys of the nonces in Storage.
+	//
+	// Optional. Default: "siwe:nonce:"
+	KeyPrefix string
+}
+
+// SIWE provides Sign-In with Ethereum (EIP-4361): the users log in by signing a message with their wallet,
+// and their session is bound to the recovered address.
+type SIWE struct {
+	cfg    SIWEConfig
+	scheme string
+	host   string
+}
+
+// NewSIWE returns a new SIWE with the config.
+//
+// Example Usage:
+//
+//	auth, err := eth.NewSIWE(eth.SIWEConfig{
+//	    Domain:  "example.com",
+//	    Storage: db.FiberStorage(),
+//	})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	auth.Mount(v1.Group("/siwe", userSessions), rateLimiter)
+func NewSIWE(config SIWEConfig) (*SIWE, error) {
+	if config.Domain == "" {
+		return nil, ErrMissingDomain
+	}
+	if config.Storage == nil {
+		return nil, ErrMissingStorage
+	}
+	config.URI = cmp.Or(config.URI, "https://"+config.Domain)
+	config.ChainID = cmp.Or(config.ChainID, 1)
+	config.Statement = cmp.Or(config.Statement, "Sign in with Ethereum.")
+	config.NonceTTL = cmp.Or(config.NonceTTL, 5*time.Minute)
+	config.KeyPrefix = cmp.Or(config.KeyPrefix, "siwe:nonce:")
+
+	u, err := url.Parse(config.URI)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("eth: invalid SIWE URI %q", config.URI)
+	}
+	return &SIWE{cfg: config, scheme: u.Scheme, host: u.Host}, nil
+}
+
+// Nonce issues a new nonce, which can be used once within SIWEConfig.NonceTTL.
+func (s *SIWE) Nonce() (string, error) {
+	nonce := rand.Text() // 26 characters of base32, which is alphanumeric.
+	if err := s.cfg.Storage.Set(s.cfg.KeyPrefix+nonce, []byte{1}, s.cfg.NonceTTL); err != nil {
+		return "", fmt.Errorf("eth: failed to store the SIWE nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// Message builds the message to be signed by the address, with a new nonce.
+func (s *SIWE) Message(address common.Address) (Message, error) {
+	nonce, err := s.Nonce()
+	if err != nil {
+		return Message{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	m := Message{
+		Domain:         s.cfg.Domain,
+		Address:        address,
+		Statement:      s.cfg.Statement,
+		URI:            s.cfg.URI,
+		Version:        "1",
+		ChainID:        s.cfg.ChainID,
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: now.Add(s.cfg.NonceTTL),
+	}
+	// Note: The scheme is omitted for https, which is what the wallets assume.
+	if s.scheme != "https" {
+		m.Scheme = s.scheme
+	}
+	return m, nil
+}
+
+// Verify verifies a signed message (EIP-4361, Section "Verifying a signed message"), and consumes its nonce.
+// It returns the parsed message, whose Address is the signer.
+func (s *SIWE) Verify(message string, signature []byte) (Message, error) {
+	m, err := ParseMessage(message)
+	if err != nil {
+		return Message{}, err
+	}
+
+	if m.Domain != s.cfg.Domain || cmp.Or(m.Scheme, "https") != s.scheme {
+		return Message{}, ErrDomainMismatch
+	}
+	if u, err := url.Parse(m.URI); err != nil || u.Scheme != s.scheme || u.Host != s.host {
+		return Message{}, ErrDomainMismatch
+	}
+	if m.ChainID != s.cfg.ChainID {
+		return Message{}, ErrChainIDMismatch
+	}
+
+	now := time.Now()
+	if m.IssuedAt.After(now.Add(clockSkew)) ||
+		(!m.ExpirationTime.IsZero() && now.After(m.ExpirationTime.Add(clockSkew))) ||
+		(!m.NotBefore.IsZero() && now.Add(clockSkew).Before(m.NotBefore)) {
+		return Message{}, ErrExpiredMessage
+	}
+
+	signer, err := RecoverAddress(message, signature)
+	if err != nil {
+		return Message{}, err
+	}
+	if signer != m.Address {
+		return Message{}, ErrInvalidSignature
+	}
+
+	// Note: The nonce is consumed after the signature is checked, so a forged message can't burn the nonce of a user.
+	if err := s.useNonce(m.Nonce); err != nil {
+		return Message{}, err
+	}
+	return m, nil
+}
+
+// useNonce deletes the nonce, or returns ErrInvalidNonce when it's not there.
+//
+// Note: fiber.Storage has no atomic "get and delete", so two requests with the same nonce that arrive at the same time
+// may both get through. Both carry the same signature, hence the same address, so it's at worst a second session
+// of the same user.
+func (s *SIWE) useNonce(nonce string) error {
+	key := s.cfg.KeyPrefix + nonce
+	value, err := s.cfg.Storage.Get(key)
+	if err != nil {
+		return fmt.Errorf("eth: failed to get the SIWE nonce: %w", err)
+	}
+	if value == nil {
+		return ErrInvalidNonce
+	}
+	if err := s.cfg.Storage.Delete(key); err != nil {
+		return fmt.Errorf("eth: failed to delete the SIWE nonce: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package eth_test
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/web3/eth"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// exampleMessage is the example of EIP-4361.
+const exampleMessage = `example.com wants you to sign in with your Ethereum account:
+0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2
+
+I accept the ExampleOrg Terms of Service: https://example.com/tos
+
+URI: https://example.com/login
+Version: 1
+Chain ID: 1
+Nonce: 32891756
+Issued At: 2021-09-30T16:25:24Z
+Resources:
+- ipfs://bafybeiemxf5abjwjbikoz4mc3a3dla6ual3jsgpdr4cjr3oz3evfyavhwq/
+- https://example.com/my-web2-claim.json`
+
+func TestParseMessage(t *testing.T) {
+	m, err := eth.ParseMessage(exampleMessage)
+	if err != nil {
+		t.Fatalf("ParseMessage() error = %v", err)
+	}
+	if m.Domain != "example.com" || m.Address != common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2") ||
+		m.ChainID != 1 || m.Nonce != "32891756" || len(m.Resources) != 2 || !m.IssuedAt.Equal(time.Date(2021, 9, 30, 16, 25, 24, 0, time.UTC)) {
+		t.Errorf("ParseMessage() = %+v", m)
+	}
+	if got := m.String(); got != exampleMessage {
+		t.Errorf("String() =\n%s\nwant\n%s", got, exampleMessage)
+	}
+
+	// Without a statement, with a scheme and the optional fields.
+	m = eth.Message{
+		Scheme:         "http",
+		Domain:         "localhost:8080",
+		Address:        m.Address,
+		URI:            "http://localhost:8080",
+		Version:        "1",
+		ChainID:        11155111,
+		Nonce:          "abcdEFGH1234",
+		IssuedAt:       time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		ExpirationTime: time.Date(2025, 1, 2, 3, 9, 5, 0, time.UTC),
+		NotBefore:      time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC),
+		RequestID:      "req-1",
+	}
+	parsed, err := eth.ParseMessage(m.String())
+	if err != nil {
+		t.Fatalf("ParseMessage(%q) error = %v", m.String(), err)
+	}
+	if parsed.String() != m.String() {
+		t.Errorf("ParseMessage() round trip =\n%s\nwant\n%s", parsed.String(), m.String())
+	}
+
+	invalid := map[string]string{
+		"Lowercase address": strings.Replace(exampleMessage, "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", 1),
+		"Short nonce":       strings.Replace(exampleMessage, "Nonce: 32891756", "Nonce: 1234", 1),
+		"Version 2":         strings.Replace(exampleMessage, "Version: 1", "Version: 2", 1),
+		"Invalid time":      strings.Replace(exampleMessage, "2021-09-30T16:25:24Z", "yesterday", 1),
+		"Missing header":    strings.Replace(exampleMessage, " wants you to sign in", " wants you to log in", 1),
+		"Unexpected field":  strings.Replace(exampleMessage, "Resources:", "Gopher: yes\nResources:", 1),
+	}
+	for name, message := range invalid {
+		if _, err := eth.ParseMessage(message); !errors.Is(err, eth.ErrInvalidMessage) {
+			t.Errorf("ParseMessage() with %s error = %v, want %v", name, err, eth.ErrInvalidMessage)
+		}
+	}
+}
+
+// personalSign signs the message like a wallet does with personal_sign, with V in 27/28.
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) []byte {
+	t.Helper()
+	signature, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("crypto.Sign() error = %v", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature
+}
+
+func TestRecoverAddress(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	signature := personalSign(t, key, exampleMessage)
+
+	if got, err := eth.RecoverAddress(exampleMessage, signature); err != nil || got != want {
+		t.Errorf("RecoverAddress() = %s, %v, want %s", got, err, want)
+	}
+
+	// Some wallets (e.g., hardware wallets) return V in 0/1.
+	raw := append([]byte(nil), signature...)
+	raw[crypto.RecoveryIDOffset] -= 27
+	if got, err := eth.RecoverAddress(exampleMessage, raw); err != nil || got != want {
+		t.Errorf("RecoverAddress() with V in 0/1 = %s, %v, want %s", got, err, want)
+	}
+
+	if got, _ := eth.RecoverAddress(exampleMessage+" ", signature); got == want {
+		t.Error("RecoverAddress() of another message returned the signer")
+	}
+	if _, err := eth.RecoverAddress(exampleMessage, signature[:64]); !errors.Is(err, eth.ErrInvalidSignature) {
+		t.Errorf("RecoverAddress() with a short signature error = %v, want %v", err, eth.ErrInvalidSignature)
+	}
+}
+
+func TestNewSIWE(t *testing.T) {
+	if _, err := eth.NewSIWE(eth.SIWEConfig{}); !errors.Is(err, eth.ErrMissingDomain) {
+		t.Errorf("NewSIWE() error = %v, want %v", err, eth.ErrMissingDomain)
+	}
+	if _, err := eth.NewSIWE(eth.SIWEConfig{Domain: "example.com"}); !errors.Is(err, eth.ErrMissingStorage) {
+		t.Errorf("NewSIWE() error = %v, want %v", err, eth.ErrMissingStorage)
+	}
+}
+
+// newSIWEApp returns an app with the session middleware of the users, and the routes of SIWE under /siwe.
+func newSIWEApp(t *testing.T) (*fiber.App, *eth.SIWE) {
+	t.Helper()
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	auth, err := eth.NewSIWE(eth.SIWEConfig{Domain: "example.com", Storage: db.FiberStorage()})
+	if err != nil {
+		t.Fatalf("NewSIWE() error = %v", err)
+	}
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(users.SessionContextKey, sess)
+		return c.Next()
+	})
+	auth.Mount(app.Group("/siwe"), nil)
+	return app, auth
+}
+
+// do sends a JSON request with the session cookie, and returns the status, the body and the session cookie it sets, if any.
+func do(t *testing.T, app *fiber.App, method, path string, body any, sessionID string) (int, map[string]any, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		b, _ := sonic.Marshal(body)
+		reader = strings.NewReader(string(b))
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if sessionID != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var got map[string]any
+	data, _ := io.ReadAll(resp.Body)
+	sonic.Unmarshal(data, &got)
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			sessionID = cookie.Value
+		}
+	}
+	return resp.StatusCode, got, sessionID
+}
+
+func TestSIWE(t *testing.T) {
+	app, auth := newSIWEApp(t)
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	if status, _, _ := do(t, app, http.MethodGet, "/siwe/session", nil, ""); status != fiber.StatusUnauthorized {
+		t.Errorf("GET /siwe/session without a session status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+
+	status, got, _ := do(t, app, http.MethodPost, "/siwe/message", map[string]string{"address": strings.ToLower(address.Hex())}, "")
+	if status != fiber.StatusOK {
+		t.Fatalf("POST /siwe/message status = %d (%v)", status, got)
+	}
+	message := got["message"].(string)
+	if !strings.Contains(message, address.Hex()) {
+		t.Fatalf("message %q doesn't have the checksummed address %s", message, address.Hex())
+	}
+
+	verify := map[string]string{"message": message, "signature": hexutil.Encode(personalSign(t, key, message))}
+	status, got, sessionID := do(t, app, http.MethodPost, "/siwe/verify", verify, "")
+	if status != fiber.StatusOK || got["address"] != address.Hex() || sessionID == "" {
+		t.Fatalf("POST /siwe/verify = %d, %v, session %q", status, got, sessionID)
+	}
+
+	status, got, _ = do(t, app, http.MethodGet, "/siwe/session", nil, sessionID)
+	if status != fiber.StatusOK || got["address"] != address.Hex() {
+		t.Errorf("GET /siwe/session = %d, %v, want %s", status, got, address.Hex())
+	}
+
+	// The nonce was used.
+	if status, _, _ := do(t, app, http.MethodPost, "/siwe/verify", verify, ""); status != fiber.StatusUnauthorized {
+		t.Errorf("replayed POST /siwe/verify status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+
+	// newMessage returns a message from the server, modified by edit.
+	newMessage := func(edit func(m *eth.Message)) string {
+		m, err := auth.Message(address)
+		if err != nil {
+			t.Fatalf("Message() error = %v", err)
+		}
+		edit(&m)
+		return m.String()
+	}
+	other, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		name    string
+		message string
+		signer  *ecdsa.PrivateKey
+		wantErr error
+	}{
+		{"Another domain", newMessage(func(m *eth.Message) { m.Domain = "evil.example" }), key, eth.ErrDomainMismatch},
+		{"Another scheme", newMessage(func(m *eth.Message) { m.Scheme = "http" }), key, eth.ErrDomainMismatch},
+		{"Another URI", newMessage(func(m *eth.Message) { m.URI = "https://evil.example/login" }), key, eth.ErrDomainMismatch},
+		{"Another chain", newMessage(func(m *eth.Message) { m.ChainID = 137 }), key, eth.ErrChainIDMismatch},
+		{"Expired", newMessage(func(m *eth.Message) { m.ExpirationTime = time.Now().Add(-time.Hour) }), key, eth.ErrExpiredMessage},
+		{"Not yet valid", newMessage(func(m *eth.Message) { m.NotBefore = time.Now().Add(time.Hour) }), key, eth.ErrExpiredMessage},
+		{"Unknown nonce", newMessage(func(m *eth.Message) { m.Nonce = "GopherNonce1234" }), key, eth.ErrInvalidNonce},
+		{"Another signer", newMessage(func(m *eth.Message) {}), other, eth.ErrInvalidSignature},
+		{"Invalid message", "Hello, Gopher!", key, eth.ErrInvalidMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := auth.Verify(tt.message, personalSign(t, tt.signer, tt.message)); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A forged message doesn't burn the nonce of the user.
+	m, _ := auth.Message(address)
+	if _, err := auth.Verify(m.String(), personalSign(t, other, m.String())); !errors.Is(err, eth.ErrInvalidSignature) {
+		t.Fatalf("Verify() by another signer error = %v", err)
+	}
+	if _, err := auth.Verify(m.String(), personalSign(t, key, m.String())); err != nil {
+		t.Errorf("Verify() after a forged message error = %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package webauthn
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrCeremonyNotFound is returned when the ceremony is unknown, expired, already finished or of another kind.
+var ErrCeremonyNotFound = errors.New("webauthn: ceremony not found or expired")
+
+// Kinds of ceremonies.
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// ceremony is what is stored between "begin" and "finish" of a ceremony.
+type ceremony struct {
+	Kind string `json:"kind"`
+	// UserID is the user registering a credential, who must be the one finishing the registration.
+	UserID  string               `json:"user_id,omitempty"`
+	Session webauthn.SessionData `json:"session"`
+}
+
+// saveCeremony stores the ceremony under a random ID, which is sent to the browser in a cookie.
+//
+// Note: The ceremony expires with the cookie, so an abandoned one doesn't stay in the storage.
+func (w *WebAuthn) saveCeremony(c *fiber.Ctx, cer ceremony) error {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	key := base64.RawURLEncoding.EncodeToString(id)
+
+	data, err := json.Marshal(cer)
+	if err != nil {
+		return err
+	}
+	if err := w.cfg.Storage.Set(w.cfg.KeyPrefix+key, data, w.cfg.CeremonyTimeout); err != nil {
+		return err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     w.cfg.CookieName,
+		Value:    key,
+		Expires:  time.Now().Add(w.cfg.CeremonyTimeout),
+		HTTPOnly: true,
+		Secure:   c.Secure(),
+		SameSite: fiber.CookieSameSiteStrictMode,
+	})
+	return nil
+}
+
+// takeCeremony returns the ceremony of the browser, and deletes it, since a challenge is only good once.
+func (w *WebAuthn) takeCeremony(c *fiber.Ctx, kind string) (ceremony, error) {
+	key := c.Cookies(w.cfg.CookieName)
+	c.ClearCookie(w.cfg.CookieName)
+	if key == "" {
+		return ceremony{}, ErrCeremonyNotFound
+	}
+
+	data, err := w.cfg.Storage.Get(w.cfg.KeyPrefix + key)
+	if err != nil {
+		return ceremony{}, err
+	}
+	if data == nil {
+		return ceremony{}, ErrCeremonyNotFound
+	}
+	if err := w.cfg.Storage.Delete(w.cfg.KeyPrefix + key); err != nil {
+		return ceremony{}, err
+	}
+
+	var cer ceremony
+	if err := json.Unmarshal(data, &cer); err != nil || cer.Kind != kind {
+		return ceremony{}, ErrCeremonyNotFound
+	}
+	return cer, nil
+}
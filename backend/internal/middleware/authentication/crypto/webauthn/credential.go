@@ -0,0 +1,171 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package webauthn
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// ErrCredentialNotFound is returned when there is no such credential for the user.
+var ErrCredentialNotFound = errors.New("webauthn: credential not found")
+
+// CredentialsTable is the name of the table storing the WebAuthn credentials (see [CreateCredentialsTable]).
+const CredentialsTable = "webauthn_credentials"
+
+// Credential is a WebAuthn credential (e.g., a passkey or a security key) registered by a user.
+type Credential struct {
+	ID           string    `json:"id"` // base64url, like the credential ID in the browser
+	UserID       string    `json:"user_id"`
+	Name         string    `json:"name"`
+	SignCount    uint32    `json:"sign_count"`
+	CloneWarning bool      `json:"clone_warning"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+
+	// credential is the credential record of the library, with the public key.
+	credential webauthn.Credential
+}
+
+// CreateCredentialsTable creates the WebAuthn credentials table if it doesn't exist.
+//
+// Note: The credential record is stored as JSON (it has the public key, the flags and the attestation),
+// while the sign count and the clone warning have their own columns, since they change on every login.
+func CreateCredentialsTable(ctx context.Context, db database.Service) error {
+	query := `CREATE TABLE IF NOT EXISTS webauthn_credentials (
+	id VARCHAR(512) NOT NULL PRIMARY KEY,
+	user_id CHAR(36) NOT NULL,
+	name VARCHAR(255) NOT NULL DEFAULT '',
+	credential TEXT NOT NULL,
+	sign_count BIGINT NOT NULL DEFAULT 0,
+	clone_warning SMALLINT NOT NULL DEFAULT 0,
+	created_at BIGINT NOT NULL,
+	last_used_at BIGINT NOT NULL DEFAULT 0`
+
+	if db.Dialect().Name() == database.DriverMySQL {
+		// MySQL has no "CREATE INDEX IF NOT EXISTS", so the index is declared inline.
+		return db.ExecWithoutRow(ctx, query+",\n\tINDEX idx_webauthn_credentials_user (user_id)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+
+	if err := db.ExecWithoutRow(ctx, query+"\n)"); err != nil {
+		return err
+	}
+	return db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials (user_id)")
+}
+
+// encodeCredentialID returns the credential ID as stored, and as seen by the browser.
+func encodeCredentialID(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// saveCredential stores a newly registered credential of the user.
+func (w *WebAuthn) saveCredential(ctx context.Context, userID, name string, credential *webauthn.Credential) (Credential, error) {
+	record, err := json.Marshal(credential)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	saved := Credential{
+		ID:         encodeCredentialID(credential.ID),
+		UserID:     userID,
+		Name:       name,
+		SignCount:  credential.Authenticator.SignCount,
+		CreatedAt:  now,
+		credential: *credential,
+	}
+
+	const query = "INSERT INTO webauthn_credentials (id, user_id, name, credential, sign_count, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	if err := w.db.ExecWithoutRow(ctx, query, saved.ID, userID, name, string(record), saved.SignCount, now.Unix()); err != nil {
+		return Credential{}, fmt.Errorf("webauthn: failed to save credential: %w", err)
+	}
+	return saved, nil
+}
+
+// updateCredential stores the sign count, the clone warning and the flags of a credential after a login.
+func (w *WebAuthn) updateCredential(ctx context.Context, credential *webauthn.Credential) error {
+	record, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+
+	cloneWarning := 0
+	if credential.Authenticator.CloneWarning {
+		cloneWarning = 1
+	}
+
+	const query = "UPDATE webauthn_credentials SET credential = ?, sign_count = ?, clone_warning = ?, last_used_at = ? WHERE id = ?"
+	if _, err := w.db.Exec(ctx, query, string(record), credential.Authenticator.SignCount, cloneWarning,
+		time.Now().UTC().Unix(), encodeCredentialID(credential.ID)); err != nil {
+		return fmt.Errorf("webauthn: failed to update credential: %w", err)
+	}
+	return nil
+}
+
+// ListCredentials returns the credentials of the user, oldest first.
+func (w *WebAuthn) ListCredentials(ctx context.Context, userID string) ([]Credential, error) {
+	const query = "SELECT id, user_id, name, credential, sign_count, clone_warning, created_at, last_used_at FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at, id"
+	rows, err := w.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []Credential
+	for rows.Next() {
+		credential, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+// DeleteCredential deletes a credential of the user (e.g., a lost security key).
+func (w *WebAuthn) DeleteCredential(ctx context.Context, userID, id string) error {
+	result, err := w.db.Exec(ctx, "DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// scanCredential scans a row of ListCredentials.
+func scanCredential(rows *sql.Rows) (Credential, error) {
+	var (
+		c                     Credential
+		record                string
+		cloneWarning          int
+		createdAt, lastUsedAt int64
+	)
+	if err := rows.Scan(&c.ID, &c.UserID, &c.Name, &record, &c.SignCount, &cloneWarning, &createdAt, &lastUsedAt); err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal([]byte(record), &c.credential); err != nil {
+		return c, fmt.Errorf("webauthn: corrupted credential %s: %w", c.ID, err)
+	}
+	// The columns are the source of truth, since they are what updateCredential changes.
+	c.credential.Authenticator.SignCount = c.SignCount
+	c.CloneWarning = cloneWarning != 0
+	c.credential.Authenticator.CloneWarning = c.CloneWarning
+	c.CreatedAt = time.Unix(createdAt, 0).UTC()
+	if lastUsedAt > 0 {
+		c.LastUsedAt = time.Unix(lastUsedAt, 0).UTC()
+	}
+	return c, nil
+}
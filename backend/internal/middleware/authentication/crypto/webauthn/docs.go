@@ -35,6 +35,35 @@
 // securely and passed to the corresponding FinishRegistration and FinishLogin functions to complete
 // the WebAuthn flow.
 //
+// # Relying Party
+//
+// The package-level functions above are deprecated in favor of the WebAuthn type created by New, which handles
+// the whole ceremonies over HTTP:
+//
+//   - The session data of a ceremony is stored in a fiber.Storage with a short TTL (see Config.CeremonyTimeout),
+//     under a random key kept in an HttpOnly cookie, and deleted by "finish", so a challenge is only good once.
+//   - The credentials are stored in the database (see CreateCredentialsTable), with their sign count.
+//     A sign count that doesn't increase flags the credential as possibly cloned, which can't be used to log in anymore.
+//   - The login can be usernameless, with a discoverable credential (passkey) identifying the user by its user handle,
+//     which is the user ID.
+//   - A successful login starts the session of the user, like a password login (see users.StartSession).
+//
+// Example Usage:
+//
+//	wa, err := webauthn.New(webauthn.Config{
+//		RelyingParty: &gowebauthn.Config{
+//			RPID:          "example.com",
+//			RPDisplayName: "Gopher",
+//			RPOrigins:     []string{"https://example.com"},
+//		},
+//		Storage: db.FiberStorage(),
+//		DB:      db,
+//	})
+//	if err != nil {
+//		// Handle error
+//	}
+//	wa.Mount(v1.Group("/webauthn", userSessions), users.RequireUser(db), rateLimiter)
+//
 // For more information about WebAuthn and its usage, refer to the GitHub repository at
 // github.com/go-webauthn/webauthn and the WebAuthn specification at https://www.w3.org/TR/webauthn-2/.
 //
@@ -0,0 +1,329 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package webauthn
+
+import (
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"strings"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// maxCredentialNameLength is the maximum length of the name of a credential (e.g., "YubiKey 5C").
+const maxCredentialNameLength = 64
+
+// HandleBeginRegistration begins the registration of a new credential for the logged-in user,
+// answering the options for navigator.credentials.create().
+//
+// Note: It must be behind users.RequireUser. The credentials of the user are excluded, so an authenticator is registered only once,
+// and a discoverable credential (passkey) is preferred, so it can be used for a usernameless login.
+func (w *WebAuthn) HandleBeginRegistration(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	loaded, err := w.loadUser(c.UserContext(), u)
+	if err != nil {
+		return w.internalError(c, "Failed to load the credentials of user "+u.ID, err)
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(loaded.credentials))
+	for _, credential := range loaded.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, sessionData, err := w.web.BeginRegistration(loaded,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+	)
+	if err != nil {
+		return w.internalError(c, "Failed to begin the WebAuthn registration", err)
+	}
+
+	if err := w.saveCeremony(c, ceremony{Kind: ceremonyRegistration, UserID: u.ID, Session: *sessionData}); err != nil {
+		return w.internalError(c, "Failed to save the WebAuthn ceremony", err)
+	}
+	return c.JSON(creation)
+}
+
+// HandleFinishRegistration verifies the response of navigator.credentials.create() and stores the new credential,
+// named by the optional "name" query parameter.
+//
+// Note: It must be behind users.RequireUser, and the user must be the one who began the registration.
+func (w *WebAuthn) HandleFinishRegistration(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	cer, err := w.takeCeremony(c, ceremonyRegistration)
+	if err != nil {
+		return w.sendCeremonyError(c, err)
+	}
+	if cer.UserID != u.ID {
+		log.LogUserActivity(c, "WebAuthn registration finished by another user than "+cer.UserID)
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "The registration expired, begin it again")
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if len(name) > maxCredentialNameLength {
+		return helper.SendProblem(c, helper.ProblemDetails{
+			Status: fiber.StatusUnprocessableEntity,
+			Detail: "Invalid name",
+			Errors: map[string]string{"name": "must be at most 64 bytes"},
+		})
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(c.Body())
+	if err != nil {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid credential")
+	}
+
+	loaded, err := w.loadUser(c.UserContext(), u)
+	if err != nil {
+		return w.internalError(c, "Failed to load the credentials of user "+u.ID, err)
+	}
+
+	credential, err := w.web.CreateCredential(loaded, cer.Session, parsed)
+	if err != nil {
+		log.LogUserActivity(c, "WebAuthn registration of user "+u.ID+" failed: "+protocolDetails(err))
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid credential")
+	}
+
+	saved, err := w.saveCredential(c.UserContext(), u.ID, name, credential)
+	if err != nil {
+		return w.internalError(c, "Failed to save the credential of user "+u.ID, err)
+	}
+	log.LogUserActivity(c, "User "+u.ID+" registered WebAuthn credential "+saved.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(saved)
+}
+
+// HandleBeginLogin begins a login, answering the options for navigator.credentials.get().
+//
+// The body may have the username (or the email) of the user, as {"username": "gopher"}, to allow only their credentials
+// (e.g., security keys which aren't discoverable). Without it, any discoverable credential (passkey) of the relying party
+// can be used, and the user is identified by its user handle (usernameless login).
+//
+// Note: For an unknown user, or a user without credentials, a usernameless login begins, so the response doesn't tell right away
+// whether the account exists. However, the presence of the allowed credentials still does, so prefer the usernameless login.
+func (w *WebAuthn) HandleBeginLogin(c *fiber.Ctx) error {
+	var body struct {
+		Username string `json:"username"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	var (
+		assertion   *protocol.CredentialAssertion
+		sessionData *webauthn.SessionData
+		err         error
+	)
+
+	loaded, err := w.findUser(c, strings.TrimSpace(body.Username))
+	if err != nil {
+		return w.internalError(c, "Failed to look up the user of a WebAuthn login", err)
+	}
+	if loaded != nil && len(loaded.credentials) > 0 {
+		assertion, sessionData, err = w.web.BeginLogin(loaded)
+	} else {
+		assertion, sessionData, err = w.web.BeginDiscoverableLogin()
+	}
+	if err != nil {
+		return w.internalError(c, "Failed to begin the WebAuthn login", err)
+	}
+
+	if err := w.saveCeremony(c, ceremony{Kind: ceremonyLogin, Session: *sessionData}); err != nil {
+		return w.internalError(c, "Failed to save the WebAuthn ceremony", err)
+	}
+	return c.JSON(assertion)
+}
+
+// HandleFinishLogin verifies the response of navigator.credentials.get(), then logs the user in (see [users.StartSession]).
+//
+// The sign count of the credential is stored. When it doesn't increase, the authenticator may have been cloned:
+// the credential is flagged (see Credential.CloneWarning) and can no longer be used to log in, until it's deleted and registered again.
+//
+// Note: It must be combined with Fiber's rate limiter to protect against bots bruteforce attacks, which is what Mount does.
+func (w *WebAuthn) HandleFinishLogin(c *fiber.Ctx) error {
+	sess, ok := c.Locals(users.SessionContextKey).(*session.Session)
+	if !ok {
+		return w.internalError(c, "Failed to finish the WebAuthn login", errors.New("webauthn: the session middleware is missing"))
+	}
+
+	cer, err := w.takeCeremony(c, ceremonyLogin)
+	if err != nil {
+		return w.sendCeremonyError(c, err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(c.Body())
+	if err != nil {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid credential")
+	}
+
+	loaded, credential, err := w.validateLogin(c, cer.Session, parsed)
+	if err != nil {
+		log.LogUserActivity(c, "WebAuthn login failed: "+protocolDetails(err))
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
+	}
+
+	if err := w.updateCredential(c.UserContext(), credential); err != nil {
+		return w.internalError(c, "Failed to update the credential of user "+loaded.ID, err)
+	}
+	if credential.Authenticator.CloneWarning {
+		log.LogUserActivity(c, "WebAuthn credential "+encodeCredentialID(credential.ID)+" of user "+loaded.ID+" may have been cloned")
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "This authenticator may have been cloned, use another one")
+	}
+
+	if err := users.StartSession(sess, loaded.ID); err != nil {
+		return w.internalError(c, "Failed to start the session of user "+loaded.ID, err)
+	}
+	log.LogUserActivity(c, "User "+loaded.ID+" logged in with WebAuthn")
+
+	return c.JSON(loaded.User)
+}
+
+// HandleListCredentials answers the credentials of the logged-in user.
+//
+// Note: It must be behind users.RequireUser.
+func (w *WebAuthn) HandleListCredentials(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	credentials, err := w.ListCredentials(c.UserContext(), u.ID)
+	if err != nil {
+		return w.internalError(c, "Failed to list the credentials of user "+u.ID, err)
+	}
+	if credentials == nil {
+		credentials = []Credential{}
+	}
+	return c.JSON(credentials)
+}
+
+// HandleDeleteCredential deletes the credential of the logged-in user named by the "id" route parameter.
+//
+// Note: It must be behind users.RequireUser.
+func (w *WebAuthn) HandleDeleteCredential(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	id := c.Params("id")
+	if err := w.DeleteCredential(c.UserContext(), u.ID, id); err != nil {
+		if errors.Is(err, ErrCredentialNotFound) {
+			return helper.SendProblemResponse(c, fiber.StatusNotFound, "Credential not found")
+		}
+		return w.internalError(c, "Failed to delete the credential of user "+u.ID, err)
+	}
+	log.LogUserActivity(c, "User "+u.ID+" deleted WebAuthn credential "+id)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Mount registers the routes of the ceremonies and the credentials on the router:
+//
+//   - "POST /register/begin" and "POST /register/finish", behind requireUser.
+//   - "POST /login/begin" and "POST /login/finish", with the rate limiter (if not nil) in front of the latter.
+//   - "GET /credentials" and "DELETE /credentials/:id", behind requireUser.
+//
+// Note: requireUser is usually users.RequireUser, and the session middleware of the users must be in front of the router.
+func (w *WebAuthn) Mount(router fiber.Router, requireUser, rateLimiter fiber.Handler) {
+	router.Post("/register/begin", requireUser, w.HandleBeginRegistration)
+	router.Post("/register/finish", requireUser, w.HandleFinishRegistration)
+
+	router.Post("/login/begin", w.HandleBeginLogin)
+	if rateLimiter != nil {
+		router.Post("/login/finish", rateLimiter, w.HandleFinishLogin)
+	} else {
+		router.Post("/login/finish", w.HandleFinishLogin)
+	}
+
+	router.Get("/credentials", requireUser, w.HandleListCredentials)
+	router.Delete("/credentials/:id", requireUser, w.HandleDeleteCredential)
+}
+
+// findUser returns the user with the username (or the email) and their credentials, or nil when there is no such user.
+func (w *WebAuthn) findUser(c *fiber.Ctx, login string) (*user, error) {
+	if login == "" {
+		return nil, nil
+	}
+
+	u, err := w.db.Auth().FindUser(c.UserContext(), login)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return w.loadUser(c.UserContext(), u)
+}
+
+// validateLogin validates the assertion, for the user who began the login,
+// or for the user of the user handle when the login is usernameless.
+func (w *WebAuthn) validateLogin(c *fiber.Ctx, sessionData webauthn.SessionData, parsed *protocol.ParsedCredentialAssertionData) (*user, *webauthn.Credential, error) {
+	ctx := c.UserContext()
+
+	if len(sessionData.UserID) > 0 {
+		u, err := w.db.Auth().GetUser(ctx, string(sessionData.UserID))
+		if err != nil {
+			return nil, nil, err
+		}
+		loaded, err := w.loadUser(ctx, u)
+		if err != nil {
+			return nil, nil, err
+		}
+		credential, err := w.web.ValidateLogin(loaded, sessionData, parsed)
+		return loaded, credential, err
+	}
+
+	var loaded *user
+	_, credential, err := w.web.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+		u, err := w.db.Auth().GetUser(ctx, string(userHandle))
+		if err != nil {
+			return nil, err
+		}
+		loaded, err = w.loadUser(ctx, u)
+		return loaded, err
+	}, sessionData, parsed)
+	return loaded, credential, err
+}
+
+// sendCeremonyError maps the errors of takeCeremony to problem details.
+func (w *WebAuthn) sendCeremonyError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, ErrCeremonyNotFound) {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "The ceremony expired, begin it again")
+	}
+	return w.internalError(c, "Failed to load the WebAuthn ceremony", err)
+}
+
+// internalError logs the error, then sends a 500 problem.
+func (w *WebAuthn) internalError(c *fiber.Ctx, message string, err error) error {
+	log.LogErrorf("%s: %v", message, err)
+	return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+}
+
+// protocolDetails returns the details of a protocol error, which are more useful in the logs than its message.
+func protocolDetails(err error) string {
+	var perr *protocol.Error
+	if errors.As(err, &perr) {
+		return perr.Details + " " + perr.DevInfo
+	}
+	return err.Error()
+}
@@ -14,6 +14,8 @@ import (
 // It takes a [webauthn.User] and optional [webauthn.LoginOption] as input.
 // It returns a [protocol.CredentialAssertion], [webauthn.SessionData], and an error.
 // The returned session data must be stored securely and passed to the [FinishLogin] function.
+//
+// Deprecated: Use WebAuthn.HandleBeginLogin.
 func BeginLogin(user webauthn.User, opts ...webauthn.LoginOption) (*protocol.CredentialAssertion, *webauthn.SessionData, error) {
 	return web.BeginLogin(user, opts...)
 }
@@ -21,6 +23,8 @@ func BeginLogin(user webauthn.User, opts ...webauthn.LoginOption) (*protocol.Cre
 // FinishLogin completes the WebAuthn login process.
 // It takes a [webauthn.User], the session data obtained from [BeginLogin], and the parsed credential assertion response.
 // It returns a [webauthn.Credential] and an error.
+//
+// Deprecated: Use WebAuthn.HandleFinishLogin.
 func FinishLogin(user webauthn.User, sessionData *webauthn.SessionData, response *protocol.ParsedCredentialAssertionData) (*webauthn.Credential, error) {
 	return web.ValidateLogin(user, *sessionData, response)
 }
@@ -14,6 +14,8 @@ var (
 
 // Init initializes the WebAuthn client with the provided configuration.
 // It returns an error if the initialization fails.
+//
+// Deprecated: Use New, which doesn't rely on a package-level client, and also stores the ceremonies and the credentials.
 func Init(config *webauthn.Config) error {
 	var err error
 	web, err = webauthn.New(config)
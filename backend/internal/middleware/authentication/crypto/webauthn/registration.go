@@ -14,6 +14,8 @@ import (
 // It takes a [webauthn.User] and optional [webauthn.RegistrationOption] as input.
 // It returns a [protocol.CredentialCreation], [webauthn.SessionData], and an error.
 // The returned session data must be stored securely and passed to the [FinishRegistration] function.
+//
+// Deprecated: Use WebAuthn.HandleBeginRegistration.
 func BeginRegistration(user webauthn.User, opts ...webauthn.RegistrationOption) (*protocol.CredentialCreation, *webauthn.SessionData, error) {
 	return web.BeginRegistration(user, opts...)
 }
@@ -21,6 +23,8 @@ func BeginRegistration(user webauthn.User, opts ...webauthn.RegistrationOption)
 // FinishRegistration completes the WebAuthn registration process.
 // It takes a [webauthn.User], the session data obtained from [BeginRegistration], and the parsed credential creation response.
 // It returns a [webauthn.Credential] and an error.
+//
+// Deprecated: Use WebAuthn.HandleFinishRegistration.
 func FinishRegistration(user webauthn.User, sessionData *webauthn.SessionData, response *protocol.ParsedCredentialCreationData) (*webauthn.Credential, error) {
 	return web.CreateCredential(user, *sessionData, response)
 }
@@ -0,0 +1,143 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package webauthn
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrMissingStorage is returned by New when Config.Storage is nil.
+	ErrMissingStorage = errors.New("webauthn: storage is required for the ceremonies")
+
+	// ErrMissingDatabase is returned by New when Config.DB is nil.
+	ErrMissingDatabase = errors.New("webauthn: database is required for the credentials")
+)
+
+// Config defines the config for WebAuthn.
+type Config struct {
+	// RelyingParty is the configuration of the relying party (e.g., RPID, RPDisplayName and RPOrigins).
+	//
+	// Required.
+	RelyingParty *webauthn.Config
+
+	// Storage stores the session data of the ceremonies (e.g., the challenge) between "begin" and "finish".
+	//
+	// Required.
+	Storage fiber.Storage
+
+	// DB stores the credentials (see CreateCredentialsTable) and looks up the users.
+	//
+	// Required.
+	DB database.Service
+
+	// CeremonyTimeout is how long a ceremony can take between "begin" and "finish".
+	//
+	// Optional. Default: 5 * time.Minute
+	CeremonyTimeout time.Duration
+
+	// KeyPrefix is the prefix of the keys of the ceremonies in the storage.
+	//
+	// Optional. Default: "webauthn:"
+	KeyPrefix string
+
+	// CookieName is the name of the cookie binding a ceremony to the browser that began it.
+	//
+	// Optional. Default: "webauthn_ceremony"
+	CookieName string
+}
+
+// ConfigDefault is the default config.
+var ConfigDefault = Config{
+	CeremonyTimeout: 5 * time.Minute,
+	KeyPrefix:       "webauthn:",
+	CookieName:      "webauthn_ceremony",
+}
+
+// WebAuthn is a WebAuthn relying party, with the handlers of the registration and login ceremonies.
+//
+// Unlike the package-level functions (see Init), which only wrap the library, it keeps the session data of the ceremonies,
+// persists the credentials with their sign count, and logs the users in.
+//
+// Example Usage:
+//
+//	wa, err := webauthn.New(webauthn.Config{
+//		RelyingParty: &gowebauthn.Config{
+//			RPID:          "example.com",
+//			RPDisplayName: "Gopher",
+//			RPOrigins:     []string{"https://example.com"},
+//		},
+//		Storage: db.FiberStorage(),
+//		DB:      db,
+//	})
+//	wa.Mount(v1.Group("/webauthn"), users.RequireUser(db), rateLimiter)
+type WebAuthn struct {
+	web *webauthn.WebAuthn
+	cfg Config
+	db  database.Service
+}
+
+// New creates a new WebAuthn relying party.
+func New(config Config) (*WebAuthn, error) {
+	if config.Storage == nil {
+		return nil, ErrMissingStorage
+	}
+	if config.DB == nil {
+		return nil, ErrMissingDatabase
+	}
+
+	web, err := webauthn.New(config.RelyingParty)
+	if err != nil {
+		return nil, err
+	}
+
+	config.CeremonyTimeout = cmp.Or(config.CeremonyTimeout, ConfigDefault.CeremonyTimeout)
+	config.KeyPrefix = cmp.Or(config.KeyPrefix, ConfigDefault.KeyPrefix)
+	config.CookieName = cmp.Or(config.CookieName, ConfigDefault.CookieName)
+
+	return &WebAuthn{web: web, cfg: config, db: config.DB}, nil
+}
+
+// user is a database.User with its credentials, as seen by the library.
+type user struct {
+	database.User
+	credentials []webauthn.Credential
+}
+
+// WebAuthnID returns the user handle, which is the user ID.
+//
+// Note: The user ID is a random UUID, so the user handle doesn't leak anything about the user, as the specification requires.
+func (u *user) WebAuthnID() []byte { return []byte(u.ID) }
+
+// WebAuthnName returns the username.
+func (u *user) WebAuthnName() string { return u.Username }
+
+// WebAuthnDisplayName returns the username, since the users have no display name.
+func (u *user) WebAuthnDisplayName() string { return u.Username }
+
+// WebAuthnCredentials returns the credentials of the user.
+func (u *user) WebAuthnCredentials() []webauthn.Credential { return u.credentials }
+
+// loadUser returns the user with its credentials.
+func (w *WebAuthn) loadUser(ctx context.Context, u database.User) (*user, error) {
+	credentials, err := w.ListCredentials(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := &user{User: u, credentials: make([]webauthn.Credential, 0, len(credentials))}
+	for _, c := range credentials {
+		loaded.credentials = append(loaded.credentials, c.credential)
+	}
+	return loaded, nil
+}
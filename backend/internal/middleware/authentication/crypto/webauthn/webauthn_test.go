@@ -0,0 +1,348 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package webauthn_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/webauthn"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "https://localhost"
+)
+
+// authenticator is a software authenticator with a single ES256 credential, using the "none" attestation.
+type authenticator struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &authenticator{id: id, key: key}
+}
+
+// create answers the options of navigator.credentials.create().
+func (a *authenticator) create(t *testing.T, options []byte) string {
+	t.Helper()
+	var creation struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(options, &creation); err != nil {
+		t.Fatalf("creation options: %v (%s)", err, options)
+	}
+	a.userHandle, _ = base64.RawURLEncoding.DecodeString(creation.PublicKey.User.ID)
+
+	x, y := a.key.PublicKey.X.FillBytes(make([]byte, 32)), a.key.PublicKey.Y.FillBytes(make([]byte, 32))
+	coseKey := cborMap(5,
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(-7), // alg: ES256
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+
+	authData := a.authData(0x41) // UP | AT
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.id)))
+	authData = append(authData, a.id...)
+	authData = append(authData, coseKey...)
+
+	attestation := cborMap(3,
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(0),
+		cborText("authData"), cborBytes(authData),
+	)
+
+	return a.credential(map[string]string{
+		"clientDataJSON":    b64(clientData("webauthn.create", creation.PublicKey.Challenge)),
+		"attestationObject": b64(attestation),
+	})
+}
+
+// get answers the options of navigator.credentials.get().
+func (a *authenticator) get(t *testing.T, options []byte) string {
+	t.Helper()
+	var assertion struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	if err := json.Unmarshal(options, &assertion); err != nil {
+		t.Fatalf("assertion options: %v (%s)", err, options)
+	}
+
+	a.signCount++
+	authData := a.authData(0x05) // UP | UV
+	data := clientData("webauthn.get", assertion.PublicKey.Challenge)
+	hash := sha256.Sum256(data)
+	digest := sha256.Sum256(append(authData, hash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("SignASN1() error = %v", err)
+	}
+
+	return a.credential(map[string]string{
+		"clientDataJSON":    b64(data),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+		"userHandle":        b64(a.userHandle),
+	})
+}
+
+func (a *authenticator) authData(flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	return binary.BigEndian.AppendUint32(append(rpIDHash[:], flags), a.signCount)
+}
+
+func (a *authenticator) credential(response map[string]string) string {
+	body, _ := json.Marshal(map[string]any{
+		"id":       b64(a.id),
+		"rawId":    b64(a.id),
+		"type":     "public-key",
+		"response": response,
+	})
+	return string(body)
+}
+
+func clientData(typ, challenge string) []byte {
+	data, _ := json.Marshal(map[string]any{"type": typ, "challenge": challenge, "origin": testOrigin, "crossOrigin": false})
+	return data
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// cborHead encodes the head of a CBOR data item (RFC 8949), enough for the small values of the tests.
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	}
+}
+
+func cborInt(n int) []byte {
+	if n < 0 {
+		return cborHead(1, -1-n)
+	}
+	return cborHead(0, n)
+}
+
+func cborBytes(b []byte) []byte { return append(cborHead(2, len(b)), b...) }
+
+func cborText(s string) []byte { return append(cborHead(3, len(s)), s...) }
+
+func cborMap(pairs int, items ...[]byte) []byte {
+	out := cborHead(5, pairs)
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// client is a browser keeping the cookies of the app.
+type client struct {
+	t       *testing.T
+	app     *fiber.App
+	cookies map[string]string
+}
+
+func (c *client) do(method, path, body string) (*http.Response, []byte) {
+	c.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	for name, value := range c.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	resp, err := c.app.Test(req)
+	if err != nil {
+		c.t.Fatalf("app.Test() error = %v", err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value == "" || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			delete(c.cookies, cookie.Name)
+			continue
+		}
+		c.cookies[cookie.Name] = cookie.Value
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestCeremonies(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := database.CreateUsersTable(ctx, db); err != nil {
+		t.Fatalf("CreateUsersTable() error = %v", err)
+	}
+	if err := webauthn.CreateCredentialsTable(ctx, db); err != nil {
+		t.Fatalf("CreateCredentialsTable() error = %v", err)
+	}
+	gopher, err := db.Auth().CreateUser(ctx, "gopher", "gopher@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	wa, err := webauthn.New(webauthn.Config{
+		RelyingParty: &gowebauthn.Config{RPID: testRPID, RPDisplayName: "Gopher", RPOrigins: []string{testOrigin}},
+		Storage:      db.FiberStorage(),
+		DB:           db,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(users.SessionContextKey, sess)
+		return c.Next()
+	})
+	app.Post("/login", users.Login(db))
+	app.Get("/me", users.RequireUser(db), users.Me())
+	wa.Mount(app.Group("/webauthn"), users.RequireUser(db), nil)
+
+	key := newAuthenticator(t)
+
+	// Register a passkey while logged in with the password.
+	browser := &client{t: t, app: app, cookies: map[string]string{}}
+	if resp, _ := browser.do(fiber.MethodPost, "/webauthn/register/begin", ""); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("register/begin without login status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+	browser.do(fiber.MethodPost, "/login", `{"login":"gopher","password":"correct horse"}`)
+	resp, options := browser.do(fiber.MethodPost, "/webauthn/register/begin", "")
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("register/begin status = %d (%s)", resp.StatusCode, options)
+	}
+	registration := key.create(t, options)
+	resp, body := browser.do(fiber.MethodPost, "/webauthn/register/finish?name=laptop", registration)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("register/finish status = %d (%s)", resp.StatusCode, body)
+	}
+
+	// The ceremony is only good once.
+	resp, _ = browser.do(fiber.MethodPost, "/webauthn/register/finish", registration)
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("register/finish replayed status = %d, want %d", resp.StatusCode, fiber.StatusBadRequest)
+	}
+
+	credentials, err := wa.ListCredentials(ctx, gopher.ID)
+	if err != nil || len(credentials) != 1 || credentials[0].Name != "laptop" {
+		t.Fatalf("ListCredentials() = %+v, %v, want the laptop credential", credentials, err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+	}{
+		{"usernameless", ""},
+		{"username", `{"username":"gopher"}`},
+		{"email", `{"username":"Gopher@Example.com"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anonymous := &client{t: t, app: app, cookies: map[string]string{}}
+			resp, options := anonymous.do(fiber.MethodPost, "/webauthn/login/begin", tt.username)
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("login/begin status = %d (%s)", resp.StatusCode, options)
+			}
+			resp, body := anonymous.do(fiber.MethodPost, "/webauthn/login/finish", key.get(t, options))
+			if resp.StatusCode != fiber.StatusOK {
+				t.Fatalf("login/finish status = %d (%s)", resp.StatusCode, body)
+			}
+			if resp, body := anonymous.do(fiber.MethodGet, "/me", ""); resp.StatusCode != fiber.StatusOK || !strings.Contains(string(body), gopher.ID) {
+				t.Errorf("/me after login status = %d (%s), want the user", resp.StatusCode, body)
+			}
+		})
+	}
+
+	// A sign count going backward flags the credential, which can't be used anymore.
+	key.signCount = 0
+	anonymous := &client{t: t, app: app, cookies: map[string]string{}}
+	_, options = anonymous.do(fiber.MethodPost, "/webauthn/login/begin", "")
+	if resp, body := anonymous.do(fiber.MethodPost, "/webauthn/login/finish", key.get(t, options)); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("login/finish of a cloned authenticator status = %d (%s), want %d", resp.StatusCode, body, fiber.StatusUnauthorized)
+	}
+	credentials, _ = wa.ListCredentials(ctx, gopher.ID)
+	if len(credentials) != 1 || !credentials[0].CloneWarning || credentials[0].SignCount != 3 {
+		t.Errorf("ListCredentials() after a clone = %+v, want a clone warning with the sign count 3", credentials)
+	}
+
+	key.signCount = 10
+	_, options = anonymous.do(fiber.MethodPost, "/webauthn/login/begin", "")
+	if resp, _ := anonymous.do(fiber.MethodPost, "/webauthn/login/finish", key.get(t, options)); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("login/finish of a flagged credential status = %d, want %d", resp.StatusCode, fiber.StatusUnauthorized)
+	}
+
+	// The user deletes the flagged credential.
+	if resp, body := browser.do(fiber.MethodDelete, "/webauthn/credentials/"+credentials[0].ID, ""); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("DELETE credential status = %d (%s)", resp.StatusCode, body)
+	}
+	if resp, body := browser.do(fiber.MethodGet, "/webauthn/credentials", ""); resp.StatusCode != fiber.StatusOK || string(body) != "[]" {
+		t.Errorf("GET credentials after delete = %d (%s), want []", resp.StatusCode, body)
+	}
+}
+
+func TestNewRequiresStorageAndDatabase(t *testing.T) {
+	rp := &gowebauthn.Config{RPID: testRPID, RPDisplayName: "Gopher", RPOrigins: []string{testOrigin}}
+	if _, err := webauthn.New(webauthn.Config{RelyingParty: rp}); err != webauthn.ErrMissingStorage {
+		t.Errorf("New() without storage error = %v, want %v", err, webauthn.ErrMissingStorage)
+	}
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	if _, err := webauthn.New(webauthn.Config{RelyingParty: rp, Storage: db.FiberStorage()}); err != webauthn.ErrMissingDatabase {
+		t.Errorf("New() without database error = %v, want %v", err, webauthn.ErrMissingDatabase)
+	}
+}
@@ -47,6 +47,9 @@ const (
 // APIKeyData represents the structure of the API key data stored in the cache.
 // It includes the following fields:
 //   - Identifier: The unique identifier associated with the API key.
+//   - Owner: The owner of the API key (e.g., a user ID).
+//   - Scopes: The scopes granted to the API key (e.g., "health:read").
+//   - Plan: The quota plan of the API key.
 //   - APIKey: The actual API key value.
 //   - Status: The status of the API key (e.g., "active", "expired").
 //   - Authorization: The authorization data of the API key.
@@ -55,6 +58,9 @@ const (
 // for relational database (MySQL) marked as TODO.
 type APIKeyData struct {
 	Identifier    string            `json:"identifier,omitempty"`
+	Owner         string            `json:"owner,omitempty"`
+	Scopes        []string          `json:"scopes,omitempty"`
+	Plan          string            `json:"plan,omitempty"`
 	APIKey        string            `json:"apikey"`
 	Status        string            `json:"status"`
 	Authorization AuthorizationData `json:"authorization"`
@@ -0,0 +1,173 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// ErrUnsupportedAlgorithm is returned for an algorithm that isn't supported, or that doesn't match the key.
+	ErrUnsupportedAlgorithm = errors.New("httpsig: unsupported algorithm or key type")
+
+	// ErrInvalidSignature is returned when a signature doesn't verify.
+	ErrInvalidSignature = errors.New("httpsig: invalid signature")
+)
+
+// Algorithms of the HTTP Signature Algorithms registry (RFC 9421, Section 6.2).
+const (
+	AlgorithmECDSAP256SHA256 = "ecdsa-p256-sha256"
+	AlgorithmECDSAP384SHA384 = "ecdsa-p384-sha384"
+	AlgorithmEd25519         = "ed25519"
+	AlgorithmRSAPSSSHA512    = "rsa-pss-sha512"
+	AlgorithmHMACSHA256      = "hmac-sha256"
+)
+
+// algorithmFor returns the algorithm of a public key, a crypto.Signer or an HMAC secret.
+func algorithmFor(key any) (string, error) {
+	if signer, ok := key.(crypto.Signer); ok {
+		key = signer.Public()
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return AlgorithmECDSAP256SHA256, nil
+		case elliptic.P384():
+			return AlgorithmECDSAP384SHA384, nil
+		}
+	case ed25519.PublicKey:
+		return AlgorithmEd25519, nil
+	case *rsa.PublicKey:
+		return AlgorithmRSAPSSSHA512, nil
+	case []byte:
+		return AlgorithmHMACSHA256, nil
+	}
+	return "", ErrUnsupportedAlgorithm
+}
+
+// checkSigningKey checks that the key can sign with the algorithm, without signing (e.g., with an HSM).
+func checkSigningKey(alg string, key any) error {
+	if alg == AlgorithmHMACSHA256 {
+		if secret, ok := key.([]byte); !ok || len(secret) == 0 {
+			return ErrUnsupportedAlgorithm
+		}
+		return nil
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return ErrUnsupportedAlgorithm
+	}
+	if actual, err := algorithmFor(signer); err != nil || actual != alg {
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
+
+// sign signs the signature base with a crypto.Signer (e.g., a private key or an HSM), or an HMAC secret.
+//
+// Note: ECDSA signatures are the concatenation of r and s (RFC 9421, Section 3.3.4), not the ASN.1 DER encoding of crypto.Signer.
+func sign(alg string, key any, base []byte) ([]byte, error) {
+	if alg == AlgorithmHMACSHA256 {
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(base)
+		return mac.Sum(nil), nil
+	}
+
+	if err := checkSigningKey(alg, key); err != nil {
+		return nil, err
+	}
+
+	signer := key.(crypto.Signer)
+	switch alg {
+	case AlgorithmECDSAP256SHA256:
+		digest := sha256.Sum256(base)
+		der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return nil, err
+		}
+		return derToRaw(der, 32)
+	case AlgorithmECDSAP384SHA384:
+		digest := sha512.Sum384(base)
+		der, err := signer.Sign(rand.Reader, digest[:], crypto.SHA384)
+		if err != nil {
+			return nil, err
+		}
+		return derToRaw(der, 48)
+	case AlgorithmEd25519:
+		return signer.Sign(rand.Reader, base, crypto.Hash(0))
+	case AlgorithmRSAPSSSHA512:
+		digest := sha512.Sum512(base)
+		return signer.Sign(rand.Reader, digest[:], &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512})
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// verify verifies a signature of the signature base with a public key, or an HMAC secret.
+func verify(alg string, key any, base, signature []byte) error {
+	if actual, err := algorithmFor(key); err != nil || actual != alg {
+		return ErrUnsupportedAlgorithm
+	}
+
+	var ok bool
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		if alg == AlgorithmECDSAP256SHA256 {
+			digest := sha256.Sum256(base)
+			ok = ecdsa.Verify(k, digest[:], r, s)
+		} else {
+			digest := sha512.Sum384(base)
+			ok = ecdsa.Verify(k, digest[:], r, s)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, base, signature)
+	case *rsa.PublicKey:
+		digest := sha512.Sum512(base)
+		ok = rsa.VerifyPSS(k, crypto.SHA512, digest[:], signature, &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}) == nil
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write(base)
+		ok = hmac.Equal(mac.Sum(nil), signature)
+	}
+
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// derToRaw converts an ASN.1 DER ECDSA signature to the concatenation of r and s, each of size bytes.
+func derToRaw(der []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("httpsig: invalid ECDSA signature: %w", err)
+	}
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrMissingComponent is returned when a covered component isn't in the message (e.g., a missing header).
+var ErrMissingComponent = errors.New("httpsig: missing covered component")
+
+// Derived components (RFC 9421, Section 2.2) supported by the signatures.
+const (
+	ComponentMethod        = "@method"
+	ComponentTargetURI     = "@target-uri"
+	ComponentAuthority     = "@authority"
+	ComponentScheme        = "@scheme"
+	ComponentRequestTarget = "@request-target"
+	ComponentPath          = "@path"
+	ComponentQuery         = "@query"
+)
+
+// message is a request whose components can be signed.
+type message interface {
+	method() string
+	scheme() string
+	authority() string
+	path() string
+	rawQuery() string
+	header(name string) []string
+}
+
+// componentValue returns the value of a component of the message.
+// Header values are trimmed and joined with ", " when the header is repeated (RFC 9421, Section 2.1).
+func componentValue(m message, name string) (string, error) {
+	switch name {
+	case ComponentMethod:
+		return strings.ToUpper(m.method()), nil
+	case ComponentAuthority:
+		return strings.ToLower(m.authority()), nil
+	case ComponentScheme:
+		return strings.ToLower(m.scheme()), nil
+	case ComponentPath:
+		return pathOf(m), nil
+	case ComponentQuery:
+		return "?" + m.rawQuery(), nil
+	case ComponentRequestTarget:
+		return requestTarget(m), nil
+	case ComponentTargetURI:
+		return strings.ToLower(m.scheme()) + "://" + strings.ToLower(m.authority()) + requestTarget(m), nil
+	}
+
+	if strings.HasPrefix(name, "@") || name != strings.ToLower(name) {
+		return "", fmt.Errorf("httpsig: unsupported component %q", name)
+	}
+	values := m.header(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingComponent, name)
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(values, ", "), nil
+}
+
+// pathOf returns the path of the message, which is "/" when empty.
+func pathOf(m message) string {
+	if p := m.path(); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// requestTarget returns the path with the query, if any.
+func requestTarget(m message) string {
+	if q := m.rawQuery(); q != "" {
+		return pathOf(m) + "?" + q
+	}
+	return pathOf(m)
+}
+
+// signatureBase returns the signature base (RFC 9421, Section 2.5) of the covered components with the signature parameters.
+func signatureBase(m message, components []string, params []sfParam) ([]byte, error) {
+	var b strings.Builder
+	for _, name := range components {
+		value, err := componentValue(m, name)
+		if err != nil {
+			return nil, err
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("httpsig: invalid value of component %q", name)
+		}
+		writeString(&b, name)
+		b.WriteString(": ")
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	b.WriteString(`"@signature-params": `)
+	b.WriteString(serializeInnerList(components, params))
+	return []byte(b.String()), nil
+}
+
+// fiberMessage is a request received by Fiber.
+type fiberMessage struct{ c *fiber.Ctx }
+
+func (m fiberMessage) method() string    { return m.c.Method() }
+func (m fiberMessage) scheme() string    { return m.c.Protocol() }
+func (m fiberMessage) authority() string { return string(m.c.Request().Host()) }
+
+// path returns the path as sent by the client, before Fiber normalizes or decodes it.
+func (m fiberMessage) path() string     { return string(m.c.Request().URI().PathOriginal()) }
+func (m fiberMessage) rawQuery() string { return string(m.c.Request().URI().QueryString()) }
+func (m fiberMessage) header(name string) []string {
+	var values []string
+	for _, v := range m.c.Request().Header.PeekAll(name) {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+// httpMessage is a request sent with net/http.
+type httpMessage struct{ r *http.Request }
+
+func (m httpMessage) method() string { return m.r.Method }
+func (m httpMessage) scheme() string { return m.r.URL.Scheme }
+func (m httpMessage) authority() string {
+	if m.r.Host != "" {
+		return m.r.Host
+	}
+	return m.r.URL.Host
+}
+func (m httpMessage) path() string     { return m.r.URL.EscapedPath() }
+func (m httpMessage) rawQuery() string { return m.r.URL.RawQuery }
+func (m httpMessage) header(name string) []string {
+	if name == "host" {
+		return []string{m.authority()}
+	}
+	return append([]string(nil), m.r.Header.Values(name)...)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRFC9421Example checks the signature base and the signature of RFC 9421, Appendix B.2.6 (Ed25519),
+// which were created by another implementation.
+func TestRFC9421Example(t *testing.T) {
+	const (
+		input     = `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`
+		signature = `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`
+		wantBase  = `"date": Tue, 20 Apr 2021 02:07:55 GMT
+"@method": POST
+"@path": /foo
+"@authority": example.com
+"content-type": application/json
+"content-length": 18
+"@signature-params": ("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`
+	)
+
+	req := httptest.NewRequest("POST", "http://example.com/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", "18")
+
+	cfg := configDefault(Config{Keys: StaticKeys{}})
+	parsed, err := cfg.parse(input, signature)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if parsed.keyID != "test-key-ed25519" || parsed.created.Unix() != 1618884473 {
+		t.Errorf("parse() = %+v", parsed)
+	}
+
+	base, err := signatureBase(httpMessage{req}, parsed.components, parsed.params)
+	if err != nil {
+		t.Fatalf("signatureBase() error = %v", err)
+	}
+	if string(base) != wantBase {
+		t.Errorf("signatureBase() =\n%s\nwant\n%s", base, wantBase)
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString("JrQLj5P_89iXES9-vFgrIy29clF9CC_oPPsw3c5D0bs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(AlgorithmEd25519, ed25519.PublicKey(publicKey), base, parsed.signature); err != nil {
+		t.Errorf("verify() error = %v", err)
+	}
+}
+
+func TestVerifyContentDigest(t *testing.T) {
+	body := []byte(`{"hello": "world"}`)
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{"SHA-256", contentDigest(body), false},
+		{"SHA-512 of RFC 9530", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", false},
+		{"SHA-256 of RFC 9530", "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", false},
+		{"Unsupported algorithm only", "md5=:Sd/dVLAcvNLSq16eXua5uQ==:", true},
+		{"Mismatch", "sha-256=:" + base64.StdEncoding.EncodeToString(make([]byte, 32)) + ":", true},
+		{"Malformed", "sha-256=abc", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyContentDigest(tt.header, body); (err != nil) != tt.wantErr {
+				t.Errorf("verifyContentDigest(%q) error = %v, wantErr %v", tt.header, err, tt.wantErr)
+			}
+		})
+	}
+}
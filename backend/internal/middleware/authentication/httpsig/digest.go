@@ -0,0 +1,65 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+// HeaderContentDigest is the header carrying the digest of the body (RFC 9530), which the signatures cover to protect the body.
+const HeaderContentDigest = "Content-Digest"
+
+// ErrDigestMismatch is returned when the Content-Digest header doesn't match the body, or has no supported algorithm.
+var ErrDigestMismatch = errors.New("httpsig: content digest mismatch")
+
+// contentDigest returns the Content-Digest header of a body, using SHA-256.
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
+
+// verifyContentDigest verifies the Content-Digest header against the body.
+// The header may have several digests, of which each "sha-256" and "sha-512" one must match, and at least one must be present.
+func verifyContentDigest(header string, body []byte) error {
+	members, err := parseDictionary(header)
+	if err != nil {
+		return ErrDigestMismatch
+	}
+
+	var checked bool
+	for _, m := range members {
+		want, ok := m.item.value.([]byte)
+		if !ok || m.inner != nil {
+			continue
+		}
+
+		var got []byte
+		switch m.key {
+		case "sha-256":
+			sum := sha256.Sum256(body)
+			got = sum[:]
+		case "sha-512":
+			sum := sha512.Sum512(body)
+			got = sum[:]
+		default:
+			continue // Note: The insecure algorithms (e.g., "md5") are ignored, as in RFC 9530, Section 5.
+		}
+
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			return ErrDigestMismatch
+		}
+		checked = true
+	}
+
+	if !checked {
+		return ErrDigestMismatch
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package httpsig provides HTTP Message Signatures (RFC 9421), for the requests between services
+// (e.g., webhooks, or the partners of the REST APIs) that must prove who sent them and that they weren't modified.
+//
+// Unlike a bearer token (e.g., an API key or a JWT), a signature never leaves the client: the server only knows the public key,
+// and a captured request can't be reused for another method, path or body, nor replayed (see Config.Nonces).
+// This fits the authentication packages here, which don't implement JWT.
+//
+// The package covers both sides:
+//
+//   - Server: the middleware of New verifies the signature of the requests, with the keys looked up by their "keyid"
+//     in a KeyRegistry, the "created" and "expires" parameters, and the body with the Content-Digest header (RFC 9530).
+//
+//   - Client: the Signer signs the outgoing requests, and its Wrap method plugs into server.HTTPRequestMaker.
+//     The keys are crypto.Signer, so the keys of keyidentifier (ECDSA) or an HSM can be used as is.
+//
+// Supported algorithms: ecdsa-p256-sha256, ecdsa-p384-sha384, ed25519, rsa-pss-sha512 and hmac-sha256.
+//
+// Example Usage:
+//
+//	// Server
+//	v1.Post("/webhooks", httpsig.New(httpsig.Config{
+//		Keys:       httpsig.StaticKeys{"partner": {PublicKey: &partnerKey.PublicKey, Subject: "partner"}},
+//		Nonces:     httpsig.NewRedisNonceStore(db.RedisClient(), ""),
+//		Components: []string{"@method", "@authority", "@path", "@query"},
+//	}), func(c *fiber.Ctx) error {
+//		key, _ := httpsig.FromContext(c)
+//		// key.Subject is "partner"
+//	})
+//
+//	// Client
+//	signer, err := httpsig.NewSigner(httpsig.SignerConfig{KeyID: "partner", Key: partnerKey})
+//	if err != nil {
+//		// Handle error
+//	}
+//	resp, err := signer.Wrap(http.DefaultClient.Do)(req)
+//
+// Note: The component parameters (e.g., ";sf", ";bs" or ";req") and the response signatures aren't supported.
+// Also note that "@authority" and "@scheme" depend on the proxy (see Fiber's EnableTrustedProxyCheck),
+// so they should only be required when the server sees the same host and scheme as the clients.
+package httpsig
@@ -0,0 +1,385 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/httpsig"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testKeys are the keys of the clients, with the registry of the server.
+type testKeys struct {
+	ecdsa    *ecdsa.PrivateKey
+	ed25519  ed25519.PrivateKey
+	rsa      *rsa.PrivateKey
+	hmac     []byte
+	registry httpsig.StaticKeys
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("a shared secret of 32 bytes!!!!!")
+
+	return testKeys{
+		ecdsa:   ecKey,
+		ed25519: edKey,
+		rsa:     rsaKey,
+		hmac:    secret,
+		registry: httpsig.StaticKeys{
+			"ecdsa":   {PublicKey: &ecKey.PublicKey, Subject: "ecdsa-client"},
+			"ed25519": {PublicKey: edKey.Public(), Subject: "ed25519-client"},
+			"rsa":     {PublicKey: &rsaKey.PublicKey, Subject: "rsa-client"},
+			"hmac":    {PublicKey: secret, Subject: "hmac-client"},
+		},
+	}
+}
+
+// newApp returns an app whose /v1/hook route is behind the middleware, and responds with the subject of the key.
+func newApp(t *testing.T, config httpsig.Config) *fiber.App {
+	t.Helper()
+	log.InitializeLogger("Gopher Testing", "unix")
+	app := fiber.New()
+	app.All("/v1/hook", httpsig.New(config), func(c *fiber.Ctx) error {
+		key, ok := httpsig.FromContext(c)
+		if !ok {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.SendString(key.Subject)
+	})
+	return app
+}
+
+// newSigner returns a signer, failing the test on error.
+func newSigner(t *testing.T, config httpsig.SignerConfig) *httpsig.Signer {
+	t.Helper()
+	signer, err := httpsig.NewSigner(config)
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	return signer
+}
+
+// send sends the request to the app, returning the status and the body of the response.
+func send(t *testing.T, app *fiber.App, req *http.Request) (int, string) {
+	t.Helper()
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := newApp(t, httpsig.Config{
+		Keys:       keys.registry,
+		Nonces:     httpsig.NewRedisNonceStore(db.RedisClient(), ""),
+		Components: []string{"@method", "@authority", "@path", "@query"},
+	})
+
+	tests := []struct {
+		name   string
+		config httpsig.SignerConfig
+		method string
+		body   string
+		// tamper modifies the request after it's signed.
+		tamper     func(req *http.Request)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "ECDSA with a body",
+			config:     httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method:     http.MethodPost,
+			body:       `{"event":"paid"}`,
+			wantStatus: fiber.StatusOK,
+			wantBody:   "ecdsa-client",
+		},
+		{
+			name:       "Ed25519 without a body",
+			config:     httpsig.SignerConfig{KeyID: "ed25519", Key: keys.ed25519},
+			method:     http.MethodGet,
+			wantStatus: fiber.StatusOK,
+			wantBody:   "ed25519-client",
+		},
+		{
+			name:       "RSA-PSS",
+			config:     httpsig.SignerConfig{KeyID: "rsa", Key: keys.rsa},
+			method:     http.MethodPut,
+			body:       "gopher",
+			wantStatus: fiber.StatusOK,
+			wantBody:   "rsa-client",
+		},
+		{
+			name:       "HMAC",
+			config:     httpsig.SignerConfig{KeyID: "hmac", Key: keys.hmac, TTL: -1},
+			method:     http.MethodDelete,
+			wantStatus: fiber.StatusOK,
+			wantBody:   "hmac-client",
+		},
+		{
+			name:   "Tampered query",
+			config: httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method: http.MethodGet,
+			tamper: func(req *http.Request) {
+				req.URL.RawQuery = "amount=1000"
+				req.RequestURI = req.URL.RequestURI()
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "Tampered body",
+			config: httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method: http.MethodPost,
+			body:   `{"amount":1}`,
+			tamper: func(req *http.Request) {
+				req.Body = io.NopCloser(strings.NewReader(`{"amount":9}`))
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "Tampered method",
+			config: httpsig.SignerConfig{KeyID: "ed25519", Key: keys.ed25519},
+			method: http.MethodGet,
+			tamper: func(req *http.Request) {
+				req.Method = http.MethodDelete
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "Body without a digest",
+			config: httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method: http.MethodGet,
+			tamper: func(req *http.Request) {
+				req.Method = http.MethodPost
+				req.Body = io.NopCloser(strings.NewReader("smuggled"))
+				req.ContentLength = 8
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "Uncovered component",
+			config:     httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa, Components: []string{"@method", "@path"}},
+			method:     http.MethodGet,
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "Unknown key",
+			config:     httpsig.SignerConfig{KeyID: "nobody", Key: keys.ecdsa},
+			method:     http.MethodGet,
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:       "Wrong key",
+			config:     httpsig.SignerConfig{KeyID: "ed25519", Key: keys.ecdsa},
+			method:     http.MethodGet,
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "Missing signature",
+			config: httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method: http.MethodGet,
+			tamper: func(req *http.Request) {
+				req.Header.Del(httpsig.HeaderSignature)
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "Missing nonce",
+			config: httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa},
+			method: http.MethodGet,
+			tamper: func(req *http.Request) {
+				input := req.Header.Get(httpsig.HeaderSignatureInput)
+				start := strings.Index(input, ";nonce=")
+				end := start + 1 + strings.Index(input[start+1:], ";")
+				req.Header.Set(httpsig.HeaderSignatureInput, input[:start]+input[end:])
+			},
+			wantStatus: fiber.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "http://api.example.com/v1/hook?amount=1", body)
+			if err := newSigner(t, tt.config).Sign(req); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			status, got := send(t, app, req)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d (%s), want %d", status, got, tt.wantStatus)
+			}
+			if tt.wantBody != "" && got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	keys := newTestKeys(t)
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := newApp(t, httpsig.Config{
+		Keys:   keys.registry,
+		Nonces: httpsig.NewRedisNonceStore(db.RedisClient(), ""),
+	})
+	signer := newSigner(t, httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa})
+
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/hook", strings.NewReader("pay"))
+	if err := signer.Sign(req); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	replay := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/hook", strings.NewReader("pay"))
+	replay.Header = req.Header.Clone()
+
+	if status, body := send(t, app, req); status != fiber.StatusOK {
+		t.Fatalf("first request status = %d (%s), want %d", status, body, fiber.StatusOK)
+	}
+	if status, _ := send(t, app, replay); status != fiber.StatusUnauthorized {
+		t.Errorf("replayed request status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+
+	// A new signature of the same request has a new nonce.
+	again := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/hook", strings.NewReader("pay"))
+	if err := signer.Sign(again); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if status, body := send(t, app, again); status != fiber.StatusOK {
+		t.Errorf("new signature status = %d (%s), want %d", status, body, fiber.StatusOK)
+	}
+}
+
+func TestExpiredSignature(t *testing.T) {
+	keys := newTestKeys(t)
+	var rejected error
+	app := newApp(t, httpsig.Config{
+		Keys:      keys.registry,
+		MaxAge:    time.Nanosecond,
+		ClockSkew: time.Nanosecond,
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			rejected = err
+			return c.SendStatus(fiber.StatusUnauthorized)
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/v1/hook", nil)
+	if err := newSigner(t, httpsig.SignerConfig{KeyID: "ecdsa", Key: keys.ecdsa}).Sign(req); err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if status, _ := send(t, app, req); status != fiber.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+	if !errors.Is(rejected, httpsig.ErrExpiredSignature) {
+		t.Errorf("error = %v, want %v", rejected, httpsig.ErrExpiredSignature)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	keys := newTestKeys(t)
+	app := newApp(t, httpsig.Config{Keys: keys.registry})
+	signer := newSigner(t, httpsig.SignerConfig{KeyID: "ed25519", Key: keys.ed25519})
+
+	// The wrapped function has the type of server.HTTPRequestMaker.MakeHTTPRequestFunc.
+	var do func(*http.Request) (*http.Response, error) = signer.Wrap(func(req *http.Request) (*http.Response, error) {
+		return app.Test(req, -1)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "http://api.example.com/v1/hook", strings.NewReader("hello"))
+	resp, err := do(req)
+	if err != nil {
+		t.Fatalf("do() error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, fiber.StatusOK)
+	}
+	if req.Header.Get(httpsig.HeaderContentDigest) == "" {
+		t.Error("Content-Digest header is missing")
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	keys := newTestKeys(t)
+
+	tests := []struct {
+		name    string
+		config  httpsig.SignerConfig
+		wantErr error
+	}{
+		{"Missing key ID", httpsig.SignerConfig{Key: keys.ecdsa}, httpsig.ErrMissingKeyID},
+		{"Missing key", httpsig.SignerConfig{KeyID: "gopher"}, httpsig.ErrUnsupportedAlgorithm},
+		{"Empty secret", httpsig.SignerConfig{KeyID: "gopher", Key: []byte{}}, httpsig.ErrUnsupportedAlgorithm},
+		{"Mismatched algorithm", httpsig.SignerConfig{KeyID: "gopher", Key: keys.ecdsa, Algorithm: httpsig.AlgorithmEd25519}, httpsig.ErrUnsupportedAlgorithm},
+		{"Public key", httpsig.SignerConfig{KeyID: "gopher", Key: &keys.ecdsa.PublicKey}, httpsig.ErrUnsupportedAlgorithm},
+		{"Valid", httpsig.SignerConfig{KeyID: "gopher", Key: keys.ecdsa}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := httpsig.NewSigner(tt.config); !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewSigner() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewWithoutKeys(t *testing.T) {
+	defer func() {
+		if r := recover(); r != httpsig.ErrMissingKeys {
+			t.Errorf("New() panic = %v, want %v", r, httpsig.ErrMissingKeys)
+		}
+	}()
+	httpsig.New(httpsig.Config{})
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnknownKey is returned by a KeyRegistry when there is no key with the ID.
+var ErrUnknownKey = errors.New("httpsig: unknown key")
+
+// Key is a key of the registry that verifies the signatures.
+type Key struct {
+	// ID is the key ID sent by the clients in the "keyid" parameter.
+	ID string
+
+	// PublicKey is the public key (*ecdsa.PublicKey, ed25519.PublicKey or *rsa.PublicKey),
+	// or the shared secret ([]byte) of hmac-sha256.
+	PublicKey any
+
+	// Algorithm is the algorithm of the key. It is inferred from PublicKey when empty.
+	//
+	// Note: The algorithm is bound to the key, so a client can't pick another one with the "alg" parameter (RFC 9421, Section 7.3.6).
+	Algorithm string
+
+	// Subject is the identity of the client holding the key (e.g., a service name or a user ID),
+	// which the handlers can read with FromContext.
+	Subject string
+}
+
+// KeyRegistry looks up the keys by their ID (e.g., from a configuration, a database or a KMS).
+type KeyRegistry interface {
+	// Key returns the key with the ID, or ErrUnknownKey.
+	Key(ctx context.Context, keyID string) (Key, error)
+}
+
+// StaticKeys is a KeyRegistry of keys known at startup, indexed by their ID.
+type StaticKeys map[string]Key
+
+// Key returns the key with the ID, or ErrUnknownKey.
+func (k StaticKeys) Key(_ context.Context, keyID string) (Key, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return Key{}, ErrUnknownKey
+	}
+	key.ID = keyID
+	return key, nil
+}
+
+// algorithm returns the algorithm of the key.
+func (k Key) algorithm() (string, error) {
+	if k.Algorithm != "" {
+		return k.Algorithm, nil
+	}
+	return algorithmFor(k.PublicKey)
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Headers carrying the signatures (RFC 9421, Section 4).
+const (
+	HeaderSignature      = "Signature"
+	HeaderSignatureInput = "Signature-Input"
+)
+
+// keyContextKey is the key of the verified Key in the context.
+const keyContextKey = "httpsig_key"
+
+var (
+	// ErrMissingKeys is returned (as a panic) by New when Config.Keys is nil.
+	ErrMissingKeys = errors.New("httpsig: key registry is required")
+
+	// ErrMissingSignature is returned when the request has no signature with the expected label.
+	ErrMissingSignature = errors.New("httpsig: missing signature")
+
+	// ErrMalformedSignature is returned when the Signature or Signature-Input headers are malformed, or miss a required parameter.
+	ErrMalformedSignature = errors.New("httpsig: malformed signature")
+
+	// ErrUncoveredComponent is returned when a signature doesn't cover a required component.
+	ErrUncoveredComponent = errors.New("httpsig: required component is not covered")
+
+	// ErrExpiredSignature is returned when a signature is expired, too old, or created in the future.
+	ErrExpiredSignature = errors.New("httpsig: signature expired")
+
+	// ErrReplayedSignature is returned when the nonce of a signature was already used.
+	ErrReplayedSignature = errors.New("httpsig: signature replayed")
+)
+
+// Config defines the config for the signature verification middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// Keys looks up the keys by the "keyid" parameter of the signatures.
+	//
+	// Required.
+	Keys KeyRegistry
+
+	// Nonces records the nonces of the verified signatures, against replay.
+	// When set, the signatures must have a "nonce" parameter.
+	//
+	// Optional. Default: nil (no replay prevention, besides MaxAge)
+	//
+	// Note: It's strongly recommended for the requests that aren't idempotent (e.g., a payment).
+	Nonces NonceStore
+
+	// Components are the components the signatures must cover, besides "content-digest" when the request has a body.
+	// The clients may cover more components.
+	//
+	// Optional. Default: []string{"@method", "@path"}
+	Components []string
+
+	// Label is the label of the signature to verify, when a request has several signatures.
+	//
+	// Optional. Default: "" (the first signature)
+	Label string
+
+	// MaxAge is the maximum age of a signature, according to its "created" parameter, which is required.
+	// A signature is also rejected after its "expires" parameter, if any.
+	//
+	// Optional. Default: 5 * time.Minute
+	MaxAge time.Duration
+
+	// ClockSkew is the tolerated difference between the clocks of the clients and the server.
+	//
+	// Optional. Default: 30 * time.Second
+	ClockSkew time.Duration
+
+	// ErrorHandler is called when a signature is rejected.
+	//
+	// Optional. Default: a 401 Unauthorized problem+json response
+	ErrorHandler fiber.ErrorHandler
+}
+
+// ConfigDefault is the default config.
+var ConfigDefault = Config{
+	Components: []string{ComponentMethod, ComponentPath},
+	MaxAge:     5 * time.Minute,
+	ClockSkew:  30 * time.Second,
+}
+
+// New creates a new middleware handler that verifies the HTTP Message Signatures (RFC 9421) of the requests.
+// It panics with ErrMissingKeys when Config.Keys is nil.
+//
+// A request must be signed with a known key, cover the required components (see Config.Components),
+// and cover a "content-digest" header matching its body, if any. The verified key is stored in the context (see FromContext).
+//
+// Example Usage:
+//
+//	v1.Post("/webhooks", httpsig.New(httpsig.Config{
+//	    Keys:   httpsig.StaticKeys{"partner": {PublicKey: partnerKey, Subject: "partner"}},
+//	    Nonces: httpsig.NewRedisNonceStore(db.RedisClient(), ""),
+//	}), handleWebhook)
+func New(config ...Config) fiber.Handler {
+	cfg := configDefault(config...)
+	if cfg.Keys == nil {
+		panic(ErrMissingKeys)
+	}
+
+	return func(c *fiber.Ctx) error {
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		key, err := cfg.verify(c)
+		if err != nil {
+			log.LogUserActivity(c, "Rejected HTTP message signature: "+err.Error())
+			return cfg.ErrorHandler(c, err)
+		}
+
+		c.Locals(keyContextKey, key)
+		return c.Next()
+	}
+}
+
+// configDefault returns the config with the defaults.
+func configDefault(config ...Config) Config {
+	cfg := ConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	if len(cfg.Components) == 0 {
+		cfg.Components = ConfigDefault.Components
+	}
+	cfg.MaxAge = cmp.Or(cfg.MaxAge, ConfigDefault.MaxAge)
+	cfg.ClockSkew = cmp.Or(cfg.ClockSkew, ConfigDefault.ClockSkew)
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = defaultErrorHandler
+	}
+	return cfg
+}
+
+// defaultErrorHandler sends a 401 Unauthorized problem+json response, with a detail that doesn't help forging a signature.
+func defaultErrorHandler(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrMissingSignature):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Signature required")
+	case errors.Is(err, ErrExpiredSignature):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Signature expired")
+	case errors.Is(err, ErrReplayedSignature):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Signature already used")
+	case errors.Is(err, ErrDigestMismatch):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Content digest mismatch")
+	case errors.Is(err, ErrMalformedSignature), errors.Is(err, ErrUncoveredComponent), errors.Is(err, ErrMissingComponent),
+		errors.Is(err, ErrUnknownKey), errors.Is(err, ErrUnsupportedAlgorithm), errors.Is(err, ErrInvalidSignature):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid signature")
+	default:
+		log.LogErrorf("Unexpected error during HTTP message signature verification: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// FromContext returns the key that verified the signature of the request.
+func FromContext(c *fiber.Ctx) (Key, bool) {
+	key, ok := c.Locals(keyContextKey).(Key)
+	return key, ok
+}
+
+// signatureInput is a parsed signature with its parameters.
+type signatureInput struct {
+	components []string
+	params     []sfParam
+	signature  []byte
+	keyID      string
+	alg        string
+	created    time.Time
+	expires    time.Time
+	nonce      string
+}
+
+// verify verifies the signature of the request, returning its key.
+func (cfg *Config) verify(c *fiber.Ctx) (Key, error) {
+	input, err := cfg.parse(c.Get(HeaderSignatureInput), c.Get(HeaderSignature))
+	if err != nil {
+		return Key{}, err
+	}
+
+	required := cfg.Components
+	body := c.Request().Body()
+	if len(body) > 0 {
+		required = append(slices.Clip(required), "content-digest")
+	}
+	for _, name := range required {
+		if !slices.Contains(input.components, name) {
+			return Key{}, fmt.Errorf("%w: %s", ErrUncoveredComponent, name)
+		}
+	}
+
+	now := time.Now()
+	if input.created.After(now.Add(cfg.ClockSkew)) || now.Sub(input.created) > cfg.MaxAge+cfg.ClockSkew {
+		return Key{}, ErrExpiredSignature
+	}
+	if !input.expires.IsZero() && now.After(input.expires.Add(cfg.ClockSkew)) {
+		return Key{}, ErrExpiredSignature
+	}
+	if cfg.Nonces != nil && input.nonce == "" {
+		return Key{}, fmt.Errorf("%w: missing nonce", ErrMalformedSignature)
+	}
+
+	key, err := cfg.Keys.Key(c.UserContext(), input.keyID)
+	if err != nil {
+		return Key{}, err
+	}
+	key.ID = input.keyID
+	alg, err := key.algorithm()
+	if err != nil {
+		return Key{}, err
+	}
+	if input.alg != "" && input.alg != alg {
+		return Key{}, ErrUnsupportedAlgorithm
+	}
+
+	base, err := signatureBase(fiberMessage{c}, input.components, input.params)
+	if err != nil {
+		return Key{}, err
+	}
+	if err := verify(alg, key.PublicKey, base, input.signature); err != nil {
+		return Key{}, err
+	}
+
+	// Note: The digest is checked after the signature, so the body is only hashed for the genuine requests.
+	if slices.Contains(input.components, "content-digest") {
+		if err := verifyContentDigest(c.Get(HeaderContentDigest), body); err != nil {
+			return Key{}, err
+		}
+	}
+
+	// Note: The nonce is only recorded for the genuine signatures, so a forged request can't burn the nonce of another one.
+	if cfg.Nonces != nil {
+		ttl := cfg.MaxAge + 2*cfg.ClockSkew - now.Sub(input.created)
+		if !input.expires.IsZero() {
+			ttl = min(ttl, input.expires.Sub(now)+cfg.ClockSkew)
+		}
+		fresh, err := cfg.Nonces.Use(c.UserContext(), input.keyID, input.nonce, max(ttl, time.Second))
+		if err != nil {
+			return Key{}, err
+		}
+		if !fresh {
+			return Key{}, ErrReplayedSignature
+		}
+	}
+
+	return key, nil
+}
+
+// parse parses the signature with the label of the config from the Signature-Input and Signature headers.
+func (cfg *Config) parse(inputHeader, signatureHeader string) (signatureInput, error) {
+	if inputHeader == "" || signatureHeader == "" {
+		return signatureInput{}, ErrMissingSignature
+	}
+	inputs, err := parseDictionary(inputHeader)
+	if err != nil {
+		return signatureInput{}, ErrMalformedSignature
+	}
+	signatures, err := parseDictionary(signatureHeader)
+	if err != nil {
+		return signatureInput{}, ErrMalformedSignature
+	}
+
+	i := 0
+	if cfg.Label != "" {
+		i = slices.IndexFunc(inputs, func(m sfMember) bool { return m.key == cfg.Label })
+	}
+	if i < 0 || len(inputs) == 0 {
+		return signatureInput{}, ErrMissingSignature
+	}
+	member := inputs[i]
+	j := slices.IndexFunc(signatures, func(m sfMember) bool { return m.key == member.key })
+	if j < 0 || member.inner == nil {
+		return signatureInput{}, ErrMalformedSignature
+	}
+
+	input := signatureInput{params: member.params}
+	if input.signature, _ = signatures[j].item.value.([]byte); input.signature == nil {
+		return signatureInput{}, ErrMalformedSignature
+	}
+	for _, item := range member.inner {
+		name, ok := item.value.(string)
+		// Note: The parameters of the components (e.g., ";sf" or ";req") aren't supported.
+		if !ok || len(item.params) > 0 || slices.Contains(input.components, name) {
+			return signatureInput{}, ErrMalformedSignature
+		}
+		input.components = append(input.components, name)
+	}
+
+	var ok bool
+	if v, found := param(member.params, "keyid"); !found {
+		return signatureInput{}, fmt.Errorf("%w: missing keyid", ErrMalformedSignature)
+	} else if input.keyID, ok = v.(string); !ok {
+		return signatureInput{}, ErrMalformedSignature
+	}
+	if v, found := param(member.params, "alg"); found {
+		if input.alg, ok = v.(string); !ok {
+			return signatureInput{}, ErrMalformedSignature
+		}
+	}
+	if v, found := param(member.params, "nonce"); found {
+		if input.nonce, ok = v.(string); !ok {
+			return signatureInput{}, ErrMalformedSignature
+		}
+	}
+	v, found := param(member.params, "created")
+	created, ok := v.(int64)
+	if !found || !ok {
+		return signatureInput{}, fmt.Errorf("%w: missing created", ErrMalformedSignature)
+	}
+	input.created = time.Unix(created, 0)
+	if v, found := param(member.params, "expires"); found {
+		expires, ok := v.(int64)
+		if !ok {
+			return signatureInput{}, ErrMalformedSignature
+		}
+		input.expires = time.Unix(expires, 0)
+	}
+	return input, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore records the nonces of the verified signatures, so a signed request can't be replayed.
+type NonceStore interface {
+	// Use records the nonce of a key until the ttl expires.
+	// It reports false when the nonce was already used.
+	Use(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error)
+}
+
+// RedisNonceStore is a NonceStore in Redis, which is shared by all the instances of the server.
+type RedisNonceStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// NewRedisNonceStore returns a NonceStore in Redis (e.g., the client of database.Service.RedisClient),
+// whose keys start with the prefix ("httpsig:nonce:" by default).
+func NewRedisNonceStore(client redis.UniversalClient, prefix string) *RedisNonceStore {
+	if prefix == "" {
+		prefix = "httpsig:nonce:"
+	}
+	return &RedisNonceStore{client: client, prefix: prefix}
+}
+
+// Use records the nonce of a key until the ttl expires, reporting false when it was already used.
+//
+// Note: SETNX is atomic, so only one of the concurrent requests with the same nonce gets through.
+func (s *RedisNonceStore) Use(ctx context.Context, keyID, nonce string, ttl time.Duration) (bool, error) {
+	fresh, err := s.client.SetNX(ctx, s.prefix+keyID+":"+nonce, 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("httpsig: failed to record the nonce: %w", err)
+	}
+	return fresh, nil
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrMissingKeyID is returned by NewSigner when SignerConfig.KeyID is empty.
+var ErrMissingKeyID = errors.New("httpsig: key ID is required")
+
+// SignerConfig defines the config for the Signer.
+type SignerConfig struct {
+	// KeyID is the ID of the key, known by the server (see KeyRegistry).
+	//
+	// Required.
+	KeyID string
+
+	// Key is a crypto.Signer (e.g., an *ecdsa.PrivateKey, an ed25519.PrivateKey or an HSM),
+	// or the shared secret ([]byte) of hmac-sha256.
+	//
+	// Required.
+	Key any
+
+	// Algorithm is the algorithm of the key.
+	//
+	// Optional. Default: inferred from Key
+	Algorithm string
+
+	// Components are the components to sign. A "content-digest" header is added and signed when a request has a body.
+	//
+	// Optional. Default: []string{"@method", "@authority", "@path", "@query"}
+	Components []string
+
+	// Label is the label of the signature.
+	//
+	// Optional. Default: "sig1"
+	Label string
+
+	// TTL sets the "expires" parameter of the signatures. A negative TTL omits it.
+	//
+	// Optional. Default: 5 * time.Minute
+	TTL time.Duration
+}
+
+// Signer signs the outgoing requests with HTTP Message Signatures (RFC 9421), which the middleware of New verifies.
+type Signer struct {
+	cfg SignerConfig
+}
+
+// NewSigner returns a Signer with the config.
+//
+// Example Usage:
+//
+//	signer, err := httpsig.NewSigner(httpsig.SignerConfig{KeyID: "gopher", Key: privateKey})
+//	if err != nil {
+//	    // Handle error
+//	}
+//	maker := &server.HTTPRequestMaker{MakeHTTPRequestFunc: signer.Wrap(http.DefaultClient.Do)}
+func NewSigner(config SignerConfig) (*Signer, error) {
+	if config.KeyID == "" {
+		return nil, ErrMissingKeyID
+	}
+	if config.Algorithm == "" {
+		alg, err := algorithmFor(config.Key)
+		if err != nil {
+			return nil, err
+		}
+		config.Algorithm = alg
+	}
+	if len(config.Components) == 0 {
+		config.Components = []string{ComponentMethod, ComponentAuthority, ComponentPath, ComponentQuery}
+	}
+	config.Label = cmp.Or(config.Label, "sig1")
+	config.TTL = cmp.Or(config.TTL, 5*time.Minute)
+
+	if err := checkSigningKey(config.Algorithm, config.Key); err != nil {
+		return nil, err
+	}
+	return &Signer{cfg: config}, nil
+}
+
+// Sign signs the request, setting its Signature-Input and Signature headers,
+// and its Content-Digest header when it has a body.
+//
+// Note: The body is read to compute the digest, then restored, so the request can still be sent (and retried with GetBody).
+func (s *Signer) Sign(req *http.Request) error {
+	components := s.cfg.Components
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		req.ContentLength = int64(len(body))
+
+		if len(body) > 0 {
+			req.Header.Set(HeaderContentDigest, contentDigest(body))
+			if !slices.Contains(components, "content-digest") {
+				components = append(slices.Clip(components), "content-digest")
+			}
+		}
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	now := time.Now()
+	params := []sfParam{{key: "created", value: now.Unix()}}
+	if s.cfg.TTL > 0 {
+		params = append(params, sfParam{key: "expires", value: now.Add(s.cfg.TTL).Unix()})
+	}
+	params = append(params,
+		sfParam{key: "nonce", value: base64.RawURLEncoding.EncodeToString(nonce)},
+		sfParam{key: "keyid", value: s.cfg.KeyID},
+		sfParam{key: "alg", value: s.cfg.Algorithm},
+	)
+
+	base, err := signatureBase(httpMessage{req}, components, params)
+	if err != nil {
+		return err
+	}
+	signature, err := sign(s.cfg.Algorithm, s.cfg.Key, base)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(HeaderSignatureInput, s.cfg.Label+"="+serializeInnerList(components, params))
+	req.Header.Set(HeaderSignature, s.cfg.Label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+	return nil
+}
+
+// Wrap returns a function that signs the requests before sending them with next (e.g., http.DefaultClient.Do),
+// ready for server.HTTPRequestMaker.MakeHTTPRequestFunc.
+func (s *Signer) Wrap(next func(*http.Request) (*http.Response, error)) func(*http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		if err := s.Sign(req); err != nil {
+			return nil, err
+		}
+		return next(req)
+	}
+}
@@ -0,0 +1,362 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// errStructuredField is returned when a header isn't a valid structured field (RFC 8941).
+var errStructuredField = errors.New("httpsig: invalid structured field")
+
+// This file implements the subset of Structured Field Values (RFC 8941) used by the signatures:
+// dictionaries whose members are byte sequences (Signature), or inner lists of strings with parameters (Signature-Input),
+// and whose parameters are integers, strings, tokens or booleans.
+
+// sfToken is a token item, which is serialized without quotes.
+type sfToken string
+
+// sfParam is a parameter of an item or an inner list. Its value is an int64, a string, an sfToken, a []byte or a bool.
+type sfParam struct {
+	key   string
+	value any
+}
+
+// sfItem is an item with its parameters.
+type sfItem struct {
+	value  any
+	params []sfParam
+}
+
+// sfMember is a member of a dictionary, either an item or an inner list (when inner is not nil).
+type sfMember struct {
+	key   string
+	item  sfItem
+	inner []sfItem
+	// params are the parameters of the inner list.
+	params []sfParam
+}
+
+// param returns the value of a parameter.
+func param(params []sfParam, key string) (any, bool) {
+	for _, p := range params {
+		if p.key == key {
+			return p.value, true
+		}
+	}
+	return nil, false
+}
+
+// sfParser parses a structured field.
+type sfParser struct {
+	s   string
+	pos int
+}
+
+// parseDictionary parses a dictionary (RFC 8941, Section 4.2.2).
+func parseDictionary(s string) ([]sfMember, error) {
+	p := &sfParser{s: s}
+	p.skipSP()
+
+	var members []sfMember
+	for !p.done() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		member := sfMember{key: key}
+		if p.peek() == '=' {
+			p.pos++
+			if p.peek() == '(' {
+				if member.inner, member.params, err = p.parseInnerList(); err != nil {
+					return nil, err
+				}
+			} else if member.item, err = p.parseItem(); err != nil {
+				return nil, err
+			}
+		} else {
+			member.item.value = true
+			if member.item.params, err = p.parseParams(); err != nil {
+				return nil, err
+			}
+		}
+
+		// A duplicate key overrides the previous value.
+		members = slices.DeleteFunc(members, func(m sfMember) bool { return m.key == key })
+		members = append(members, member)
+
+		p.skipOWS()
+		if p.done() {
+			break
+		}
+		if p.peek() != ',' {
+			return nil, errStructuredField
+		}
+		p.pos++
+		p.skipOWS()
+		if p.done() {
+			return nil, errStructuredField // trailing comma
+		}
+	}
+	return members, nil
+}
+
+func (p *sfParser) done() bool { return p.pos >= len(p.s) }
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *sfParser) skipSP() {
+	for !p.done() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for !p.done() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// parseKey parses a key: lcalpha or "*", followed by lcalpha, DIGIT, "_", "-", "." or "*".
+func (p *sfParser) parseKey() (string, error) {
+	start := p.pos
+	if c := p.peek(); !(c >= 'a' && c <= 'z') && c != '*' {
+		return "", errStructuredField
+	}
+	for !p.done() {
+		c := p.s[p.pos]
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && c != '_' && c != '-' && c != '.' && c != '*' {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+// parseInnerList parses an inner list with its parameters.
+func (p *sfParser) parseInnerList() ([]sfItem, []sfParam, error) {
+	p.pos++ // "("
+	items := []sfItem{}
+	for !p.done() {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+			return items, params, err
+		}
+
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, nil, errStructuredField
+		}
+	}
+	return nil, nil, errStructuredField
+}
+
+// parseItem parses a bare item with its parameters.
+func (p *sfParser) parseItem() (sfItem, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return sfItem{}, err
+	}
+	params, err := p.parseParams()
+	return sfItem{value: value, params: params}, err
+}
+
+// parseParams parses the parameters.
+func (p *sfParser) parseParams() ([]sfParam, error) {
+	var params []sfParam
+	for p.peek() == ';' {
+		p.pos++
+		p.skipSP()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var value any = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+
+		// A duplicate key overrides the previous value.
+		params = slices.DeleteFunc(params, func(p sfParam) bool { return p.key == key })
+		params = append(params, sfParam{key: key, value: value})
+	}
+	return params, nil
+}
+
+// parseBareItem parses an integer, a string, a token, a byte sequence or a boolean.
+// Decimals aren't supported, since no signature parameter uses them.
+func (p *sfParser) parseBareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseInteger()
+	case c == '"':
+		return p.parseString()
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		p.pos++
+		switch p.peek() {
+		case '0':
+			p.pos++
+			return false, nil
+		case '1':
+			p.pos++
+			return true, nil
+		}
+		return nil, errStructuredField
+	case c == '*' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		start := p.pos
+		for !p.done() && isTokenChar(p.s[p.pos]) {
+			p.pos++
+		}
+		return sfToken(p.s[start:p.pos]), nil
+	default:
+		return nil, errStructuredField
+	}
+}
+
+func (p *sfParser) parseInteger() (int64, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.done() && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.peek() == '.' {
+		return 0, errStructuredField
+	}
+	digits := p.s[start:p.pos]
+	if len(strings.TrimPrefix(digits, "-")) == 0 || len(strings.TrimPrefix(digits, "-")) > 15 {
+		return 0, errStructuredField
+	}
+	return strconv.ParseInt(digits, 10, 64)
+}
+
+func (p *sfParser) parseString() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if p.done() || (p.s[p.pos] != '"' && p.s[p.pos] != '\\') {
+				return "", errStructuredField
+			}
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", errStructuredField
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errStructuredField
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	p.pos++ // opening colon
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end < 0 {
+		return nil, errStructuredField
+	}
+	encoded := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Note: RFC 8941 asks the parsers to accept a missing padding.
+		if decoded, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return nil, errStructuredField
+		}
+	}
+	return decoded, nil
+}
+
+// isTokenChar reports whether c can be in a token (tchar, ":" or "/").
+func isTokenChar(c byte) bool {
+	if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
+
+// serializeInnerList serializes an inner list of strings with its parameters, which is the value of "@signature-params".
+func serializeInnerList(items []string, params []sfParam) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, item := range items {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		writeString(&b, item)
+	}
+	b.WriteByte(')')
+	writeParams(&b, params)
+	return b.String()
+}
+
+// writeParams serializes parameters.
+func writeParams(b *strings.Builder, params []sfParam) {
+	for _, p := range params {
+		b.WriteByte(';')
+		b.WriteString(p.key)
+		switch v := p.value.(type) {
+		case bool:
+			if !v {
+				b.WriteString("=?0")
+			}
+		case int64:
+			b.WriteByte('=')
+			b.WriteString(strconv.FormatInt(v, 10))
+		case string:
+			b.WriteByte('=')
+			writeString(b, v)
+		case sfToken:
+			b.WriteByte('=')
+			b.WriteString(string(v))
+		case []byte:
+			b.WriteString("=:")
+			b.WriteString(base64.StdEncoding.EncodeToString(v))
+			b.WriteByte(':')
+		}
+	}
+}
+
+// writeString serializes a string, escaping the quotes and the backslashes.
+func writeString(b *strings.Builder, s string) {
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('"')
+}
@@ -11,8 +11,13 @@ const (
 	sessionKey       = "session"
 	apiKey           = "api_key"
 	keyAuthRequestID = "requestid"
+	apiKeyInfoKey    = "api_key_info"
 )
 
 const (
 	defaultExpryContextKey = time.Second * 2
+
+	// sessionRecheckInterval is how long a key validated in a session is accepted from the session alone,
+	// which is also how long a revoked key can keep working in a session that already used it.
+	sessionRecheckInterval = time.Minute
 )
@@ -6,6 +6,7 @@
 package keyauth
 
 import (
+	"errors"
 	"h0llyw00dz-template/backend/internal/database"
 	log "h0llyw00dz-template/backend/internal/logger"
 	"h0llyw00dz-template/backend/internal/middleware/authentication/helper"
@@ -17,21 +18,105 @@ import (
 )
 
 // ValidatorKeyAuthHandler is a custom validator for the key authentication middleware.
-// It checks if the provided API key is valid and active by querying the Redis cache and the database.
+// It checks if the provided API key is valid and active by querying the session, the Redis cache and the database, in that order:
 //
-// TODO: Finishing this, however performance kinda slow due it's mysql performance issue unlike Redis/Valkey, unless implementing own TLS/Cryptographic Protocol (e.g, TLS 1.4, etc)
+//  1. Session (for browser): a key validated less than sessionRecheckInterval ago in the same session is accepted as is.
+//  2. Redis (for non-browser): see [database.ServiceAuth.ValidateAPIKey], which caches the keys it validated.
+//  3. Database (MySQL): the keyed hash of the key is looked up, then cached in Redis and saved in the session -> repeat.
+//
+// It returns [database.ErrInvalidAPIKey] or [database.ErrExpiredAPIKey], so the matching paths of ErrorKeyAuthHandler fire.
+// On success, the key metadata is stored in the context (see APIKeyFromContext).
+//
+// Note: Won't implement JWT and their base standards, because it's easy to lead to high vulnerability.
+// Also, the cryptography world is not small enough to rely solely on JWT. 🤪
+// So, any package for "authentication" here will be covered with another crypto instead of JWT and their base standards.
 func ValidatorKeyAuthHandler(c *fiber.Ctx, key string, db database.Service) (bool, error) {
 	// Log the authentication attempt.
 	log.LogUserActivity(c, "Attempted Authentication")
 
-	// TODO: Implement the "vice versa" method for Redis (for non-browser) & Redis (for browser, aka session storage) -> database -> repeat.
-	// Note: Won't implement JWT and their base standards, because it's easy to lead to high vulnerability.
-	// Also, the cryptography world is not small enough to rely solely on JWT. 🤪
-	// So, any package for "authentication" here will be covered with another crypto instead of JWT and their base standards.
+	sess, _ := c.Locals(sessionKey).(*session.Session)
+	if sess != nil {
+		if info, ok, err := apiKeyFromSession(sess, key); ok {
+			if err != nil {
+				return false, err
+			}
+			c.Locals(apiKeyInfoKey, info)
+			return true, nil
+		}
+	}
+
+	info, err := db.Auth().ValidateAPIKey(c.UserContext(), key)
+	if err != nil {
+		if sess != nil && errors.Is(err, database.ErrExpiredAPIKey) {
+			saveAPIKeyInSession(sess, database.APIKey{}, key, true)
+		}
+		return false, err
+	}
 
+	if sess != nil {
+		saveAPIKeyInSession(sess, info, key, false)
+	}
+	c.Locals(apiKeyInfoKey, info)
 	return true, nil
 }
 
+// NewValidator returns ValidatorKeyAuthHandler bound to the database service, ready for [keyauth.Config.Validator].
+//
+// Example Usage:
+//
+//	middleware.NewKeyAuthMiddleware(
+//	    middleware.WithValidator(keyauth.NewValidator(db)),
+//	    middleware.WithErrorHandler(keyauth.ErrorKeyAuthHandler),
+//	    middleware.WithSuccessHandler(keyauth.SuccessKeyAuthHandler),
+//	)
+func NewValidator(db database.Service) func(*fiber.Ctx, string) (bool, error) {
+	return func(c *fiber.Ctx, key string) (bool, error) {
+		return ValidatorKeyAuthHandler(c, key, db)
+	}
+}
+
+// APIKeyFromContext returns the metadata of the API key validated by ValidatorKeyAuthHandler for this request.
+//
+// Note: When the key was accepted from the session, only the ID, owner, scopes, plan and expiration time are known.
+func APIKeyFromContext(c *fiber.Ctx) (database.APIKey, bool) {
+	info, ok := c.Locals(apiKeyInfoKey).(database.APIKey)
+	return info, ok
+}
+
+// apiKeyFromSession returns the API key metadata saved in the session, if it's for the same key and was validated recently.
+// The boolean reports whether the session answered, in which case the error is ErrExpiredAPIKey for an expired key.
+func apiKeyFromSession(sess *session.Session, key string) (database.APIKey, bool, error) {
+	_, valid, expired := isAPIKeyValidInSession(sess, key)
+	if !valid {
+		return database.APIKey{}, false, nil
+	}
+	if expired {
+		return database.APIKey{}, true, database.ErrExpiredAPIKey
+	}
+
+	var data helper.APIKeyData
+	if err := sonic.Unmarshal(sess.Get(apiKey).([]byte), &data); err != nil {
+		return database.APIKey{}, false, nil
+	}
+
+	now := time.Now().UTC()
+	if now.Sub(data.Authorization.AuthTime) >= sessionRecheckInterval {
+		// Too old, so a revocation since then must be seen. Ask Redis/the database again.
+		return database.APIKey{}, false, nil
+	}
+	info := database.APIKey{
+		ID:        data.Identifier,
+		Owner:     data.Owner,
+		Scopes:    data.Scopes,
+		Plan:      data.Plan,
+		ExpiresAt: data.Authorization.ExpiredTime,
+	}
+	if info.Expired(now) {
+		return database.APIKey{}, true, database.ErrExpiredAPIKey
+	}
+	return info, true, nil
+}
+
 // isAPIKeyValidInSession checks if the API key is valid and not expired in the session.
 // It returns one string value (UUID) and two boolean values: isAPIKeyValid and expired.
 func isAPIKeyValidInSession(sess *session.Session, key string) (string, bool, bool) {
@@ -63,7 +148,7 @@ func isAPIKeyValidInSession(sess *session.Session, key string) (string, bool, bo
 //
 // Note: The session data is now stored as JSON when viewing in Redis, Valkey Insight, or Commander panel.
 // Additionally, it is possible to implement an encryption/decryption mechanism for the JSON values, as Go, unlike other languages, allows for this functionality + 100% secure.
-func saveAPIKeyInSession(sess *session.Session, key string, expired bool, expirationDate time.Time) {
+func saveAPIKeyInSession(sess *session.Session, info database.APIKey, key string, expired bool) {
 	if expired {
 		data := helper.APIKeyData{
 			APIKey: key,
@@ -84,18 +169,26 @@ func saveAPIKeyInSession(sess *session.Session, key string, expired bool, expira
 
 		sess.Set(apiKey, jsonData)
 		sess.SetExpiry(defaultExpryContextKey)
+		if err := sess.Save(); err != nil {
+			log.LogErrorf("Failed to save session: %v", err)
+		}
+		return
 	}
 
 	data := helper.APIKeyData{
-		APIKey: key,
-		Status: helper.APIKeyActive.String(),
+		Identifier: info.ID,
+		Owner:      info.Owner,
+		Scopes:     info.Scopes,
+		Plan:       info.Plan,
+		APIKey:     key,
+		Status:     helper.APIKeyActive.String(),
 		Authorization: helper.AuthorizationData{
 			// Time Server not client
 			AuthTime: time.Now().UTC(),
 			// Note: This expiration time is retrieved from the relational database (MySQL).
 			// The performance speed might be somewhat slow (taking an average of 1s response time in the frontend) during the first query due to the relational database (always slow).
 			// However, when it hits Redis and is released into cookies with encryption, the speed can be faster (possibly 0ms ~ 1ms response time).
-			ExpiredTime: expirationDate,
+			ExpiredTime: info.ExpiresAt,
 		},
 	}
 
@@ -0,0 +1,94 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package keyauth_test
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	fiberkeyauth "github.com/gofiber/fiber/v2/middleware/keyauth"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+func TestValidatorKeyAuthHandler(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if err := database.CreateAPIKeysTable(ctx, db); err != nil {
+		t.Fatalf("CreateAPIKeysTable() error = %v", err)
+	}
+	key, info, _ := db.Auth().CreateAPIKey(ctx, "gopher", "test", time.Hour)
+	revokedKey, revoked, _ := db.Auth().CreateAPIKey(ctx, "gopher", "revoked", time.Hour)
+	db.Auth().RevokeAPIKey(ctx, revoked.ID)
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals("session", sess)
+		return c.Next()
+	})
+	app.Use(fiberkeyauth.New(fiberkeyauth.Config{
+		Validator:    keyauth.NewValidator(db),
+		ErrorHandler: keyauth.ErrorKeyAuthHandler,
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		got, ok := keyauth.APIKeyFromContext(c)
+		if !ok {
+			return fiber.ErrInternalServerError
+		}
+		return c.SendString(got.ID)
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{"valid", key, fiber.StatusOK},
+		{"valid again", key, fiber.StatusOK},
+		{"unknown", "sk-unknown-key-of-a-gopher", fiber.StatusUnauthorized},
+		{"revoked", revokedKey, fiber.StatusUnauthorized},
+		{"missing", "", fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+			if tt.key != "" {
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tt.key)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == fiber.StatusOK {
+				body := make([]byte, len(info.ID))
+				resp.Body.Read(body)
+				if string(body) != info.ID {
+					t.Errorf("APIKeyFromContext() ID = %q, want %q", body, info.ID)
+				}
+			}
+		})
+	}
+}
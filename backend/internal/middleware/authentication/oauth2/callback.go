@@ -7,90 +7,131 @@ package oauth2
 
 import (
 	"context"
-	"fmt"
+	"crypto/subtle"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
 	"h0llyw00dz-template/backend/pkg/restapis/helper"
-	"net/http"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"golang.org/x/oauth2"
 )
 
-// HandleCallback handles the callback request from Google after the user has authenticated.
-// It retrieves the authorization code from the query parameters, exchanges it for an access token,
-// and then uses the access token to retrieve the user's information from the Google API.
+// HandleCallback handles the callback request from the provider after the user has authenticated.
+// It checks the state, exchanges the authorization code for a token (with the PKCE verifier), retrieves the user information
+// (verifying the ID token and its nonce for the OpenID Connect providers), then logs the user in:
 //
-// TODO: This still needs improvement and must be combined with Fiber's rate limiter to protect against bots bruteforce attacks.
+//   - When the user is already logged in, the account of the provider is linked to theirs.
+//   - Otherwise, the user linked to the account of the provider is logged in, and created on the first login
+//     (see [database.ServiceAuth.SignInWithIdentity]).
+//
+// Like a password login, the session ID is regenerated (see [users.StartSession]).
+//
+// Note: It must be combined with Fiber's rate limiter to protect against bots bruteforce attacks, which is what Registry.Mount does.
 func (m *Manager) HandleCallback(c *fiber.Ctx) error {
-	// Get the context from the [*fiber.Ctx]
-	ctx, cancel := context.WithCancel(c.Context())
-	defer cancel()
-
-	code := c.Query("code")
-	// Note: In the Google OAuth2 mechanism, it always returns the same value, which is useful for protecting against CSRF attacks.
-	// However, for other providers that I'm not familiar with, this code must be refactored later.
-	state := c.Query("state")
-
 	// Get the session from the store
-	sess, err := m.store.Get(c)
+	sess, err := m.session(c)
 	if err != nil {
 		return err
 	}
 
+	state, _ := sess.Get(sessionState).(string)
+	verifier, _ := sess.Get(sessionVerifier).(string)
+	nonce, _ := sess.Get(sessionNonce).(string)
+	provider, _ := sess.Get(sessionProvider).(string)
+
+	// The values of the login are only good once, whatever happens next.
+	sess.Delete(sessionState)
+	sess.Delete(sessionVerifier)
+	sess.Delete(sessionNonce)
+	sess.Delete(sessionProvider)
+
+	if reason := c.Query("error"); reason != "" {
+		return m.fail(c, sess, fiber.StatusUnauthorized, "The provider denied the authorization: "+reason)
+	}
+
 	// Verify the state parameter
 	//
 	// Note: This already uses Fiber's session middleware mechanism, so the session is not using OAuth2's built-in mechanisms such as token, JWT, JWS, etc.
 	// This approach is better oauth2 custom and considered safer.
-	storedState := sess.Get("oauth2_state")
-
-	// Just in case it keeps getting an "Invalid state parameter" error, it's because storedState is of type interface{}/any.
-	// Explicitly converting it to a string using .(string) is a better approach because the "state" is a string,
-	// and it's effective in protecting against CSRF attacks 🤪.
-	if state != storedState.(string) {
-		// Useful protect against bots bruteforce attacks.
-		sess.Destroy()
-		return helper.SendErrorResponse(c, http.StatusBadRequest, "Invalid state parameter")
+	if state == "" || provider != m.name || subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(state)) != 1 {
+		log.LogUserActivity(c, "Invalid OAuth2 state parameter")
+		return m.fail(c, sess, fiber.StatusBadRequest, "Invalid state parameter")
 	}
 
-	token, err := m.config.Exchange(ctx, code)
+	code := c.Query("code")
+	if code == "" {
+		return m.fail(c, sess, fiber.StatusBadRequest, "Missing authorization code")
+	}
+
+	ctx := context.WithValue(c.UserContext(), oauth2.HTTPClient, m.client)
+	token, err := m.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		sess.Destroy()
-		return helper.SendErrorResponse(c, http.StatusBadRequest, err.Error())
+		log.LogErrorf("Failed to exchange the OAuth2 code of %s: %v", m.name, err)
+		return m.fail(c, sess, fiber.StatusBadRequest, "Failed to exchange the authorization code")
 	}
 
-	client := m.config.Client(ctx, token)
-	// TODO: This still needs improvement because Google has many types of OAuth2 (e.g., for desktop, which has been used to implement OAuth2-CLI before, and for web)
-	userInfo, err := m.getUserInfo(c, client)
+	info, err := m.getUserInfo(ctx, token, nonce)
 	if err != nil {
-		sess.Destroy()
-		return helper.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+		log.LogErrorf("Failed to get the user information of %s: %v", m.name, err)
+		if errors.Is(err, ErrInvalidIDToken) || errors.Is(err, ErrMissingSubject) {
+			return m.fail(c, sess, fiber.StatusUnauthorized, "Invalid user information from the provider")
+		}
+		return m.fail(c, sess, fiber.StatusBadGateway, "Failed to get the user information from the provider")
 	}
 
-	// Access user information
-	//
-	// TODO: This still needs improvement.
-	// This should connect to the database and verify the user. If the user is found in the database, they are authorized for signin. However, the signup process is different.
-	email := userInfo["email"].(string)
-	name := userInfo["name"].(string)
+	user, err := m.signIn(ctx, sess, info)
+	if err != nil {
+		return m.sendSignInError(c, sess, err)
+	}
 
-	// Store the user information in the session
-	//
-	// TODO: This should be Set after verifying the user in the database for later identification in the final router
-	sess.Set("oauth2_authorized", fiber.Map{
-		"id":    state, // Changed from "state" to "id" for later identification
-		"email": email,
-		"name":  name,
-	})
+	if err := users.StartSession(sess, user.ID); err != nil {
+		log.LogErrorf("Failed to start the session of user %s: %v", user.ID, err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Failed to start the session")
+	}
+	log.LogUserActivity(c, "User "+user.ID+" logged in with "+m.name)
 
-	if err := sess.Save(); err != nil {
-		sess.Destroy()
-		return helper.SendErrorResponse(c, http.StatusInternalServerError, err.Error())
+	if m.successRedirect != "" {
+		return c.Redirect(m.successRedirect, fiber.StatusSeeOther)
 	}
+	return c.JSON(user)
+}
 
-	// Perform further actions with the user information
-	//
-	// TODO: Remove this later when it is fully improved.
-	fmt.Printf("User logged in: Email: %s, Name: %s\n", email, name)
+// signIn links the identity to the logged-in user, if any, otherwise returns the user linked to it.
+func (m *Manager) signIn(ctx context.Context, sess *session.Session, info UserInfo) (database.User, error) {
+	identity := info.Identity()
+
+	if userID := users.SessionUserID(sess); userID != "" {
+		if err := m.db.LinkIdentity(ctx, userID, identity); err != nil {
+			return database.User{}, err
+		}
+		return m.db.GetUser(ctx, userID)
+	}
+	return m.db.SignInWithIdentity(ctx, identity)
+}
 
-	// TODO: This still needs improvement.
-	// This should redirect to the final router, which connects to everything, including a frontend website.
-	return c.SendString("User logged in successfully")
+// sendSignInError maps the errors of signIn to problem details.
+func (m *Manager) sendSignInError(c *fiber.Ctx, sess *session.Session, err error) error {
+	switch {
+	case errors.Is(err, database.ErrIdentityLinked):
+		return m.fail(c, sess, fiber.StatusConflict, "This account is already linked to another user")
+	case errors.Is(err, database.ErrUserExists):
+		// Note: The email isn't verified by the provider, so the account can't be linked automatically.
+		return m.fail(c, sess, fiber.StatusConflict, "A user with this email already exists, log in to link this account")
+	case errors.Is(err, database.ErrInvalidEmail):
+		return m.fail(c, sess, fiber.StatusUnprocessableEntity, "The provider didn't share a valid email")
+	default:
+		log.LogErrorf("Failed to sign in with %s: %v", m.name, err)
+		return m.fail(c, sess, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// fail saves the session (without the values of the login), then sends the problem.
+func (m *Manager) fail(c *fiber.Ctx, sess *session.Session, status int, detail string) error {
+	if err := sess.Save(); err != nil {
+		log.LogErrorf("Failed to save session: %v", err)
+	}
+	return helper.SendProblemResponse(c, status, detail)
 }
@@ -0,0 +1,15 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package oauth2
+
+// The session keys of the values that live from HandleLogin to HandleCallback.
+// They are deleted by the callback whatever its outcome, so each login can only be completed once.
+const (
+	sessionState    = "oauth2_state"
+	sessionVerifier = "oauth2_verifier"
+	sessionNonce    = "oauth2_nonce"
+	sessionProvider = "oauth2_provider"
+)
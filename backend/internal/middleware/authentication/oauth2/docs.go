@@ -35,8 +35,10 @@
 // production-ready. Developers are encouraged to review and enhance the package based on their specific security
 // requirements, performance considerations, and best practices for OAuth2 implementation.
 //
-// The package currently supports Google as an OAuth2 provider, but adding support for other providers is planned for
-// future iterations. Contributions and feedback from the community are welcome to help improve and expand the
+// The package currently supports Google, GitHub, GitLab, and any OpenID Connect provider through discovery
+// (see ProviderOIDC), served together by a Registry. Every login uses PKCE and a state checked by the callback, plus a nonce
+// for the OpenID Connect providers, whose ID tokens are verified. The users are linked to the accounts of the providers
+// through [database.ServiceAuth]. Contributions and feedback from the community are welcome to help improve and expand the
 // functionality of this OAuth2 package.
 //
 // When using this package in a production environment, it is crucial to thoroughly test and validate the
@@ -7,40 +7,72 @@ package oauth2
 
 import (
 	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/rand"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+	"golang.org/x/oauth2"
 )
 
 // HandleLogin handles the login request.
-// It generates the authorization URL and redirects the user to the Google login page.
+// It generates the authorization URL and redirects the user to the login page of the provider.
+//
+// The state (against CSRF), the PKCE verifier (RFC 7636, against stolen authorization codes) and, for the
+// OpenID Connect providers, the nonce (against replayed ID tokens) are stored in the session, and checked by HandleCallback.
 //
 // Note: This can be used not only for sign-in but also for sign-in and sign-up, as OAuth2 can leverage user information such as name and email.
-// The user information can be combined with multiple databases (recommended as part of a microservice architecture).
-// For example, the session storage can use Redis, while the actual user data (e.g., name, email) can be stored and inserted into MySQL.
+// When the user is already logged in, the account of the provider is linked to theirs instead (see HandleCallback).
 func (m *Manager) HandleLogin(c *fiber.Ctx) error {
 	// Note: This safe against CSRF Attacks 🤪
-	state, err := rand.GenerateFixedUUID(
-		rand.UUIDFormat{
-			RemoveHyphens: true,
-		},
-	)
+	state, err := randomToken()
 	if err != nil {
 		return err
 	}
+	verifier := oauth2.GenerateVerifier()
 
 	// Get the session from the store
-	sess, err := m.store.Get(c)
+	sess, err := m.session(c)
 	if err != nil {
 		return err
 	}
 
-	// Store the state in the session
-	sess.Set("oauth2_state", state)
+	sess.Set(sessionState, state)
+	sess.Set(sessionVerifier, verifier)
+	sess.Set(sessionProvider, m.name)
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if m.provider.verifier != nil {
+		nonce, err := randomToken()
+		if err != nil {
+			return err
+		}
+		sess.Set(sessionNonce, nonce)
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+
 	if err := sess.Save(); err != nil {
 		return err
 	}
 
-	authURL := m.config.AuthCodeURL(state)
+	authURL := m.config.AuthCodeURL(state, opts...)
 	return c.Redirect(authURL, http.StatusTemporaryRedirect)
 }
+
+// session returns the session of the request: the one of the session middleware when it runs before the handlers,
+// otherwise the one of the store of the Manager.
+func (m *Manager) session(c *fiber.Ctx) (*session.Session, error) {
+	if sess, ok := c.Locals(users.SessionContextKey).(*session.Session); ok {
+		return sess, nil
+	}
+	return m.store.Get(c)
+}
+
+// randomToken returns a random value for the state and the nonce.
+func randomToken() (string, error) {
+	return rand.GenerateFixedUUID(
+		rand.UUIDFormat{
+			RemoveHyphens: true,
+		},
+	)
+}
@@ -6,11 +6,16 @@
 package oauth2
 
 import (
-	"fmt"
+	"cmp"
+	"context"
 	"h0llyw00dz-template/backend/internal/database"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2/middleware/session"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
 	"golang.org/x/oauth2/google"
 )
 
@@ -18,31 +23,110 @@ const (
 	// ProviderGoogle represents the identifier for the Google OAuth2 provider.
 	// It is used to specify the provider when configuring the OAuth2 Manager.
 	ProviderGoogle = "Google"
+
+	// ProviderGitHub represents the identifier for the GitHub OAuth2 provider (github.com, or GitHub Enterprise Server with BaseURL).
+	ProviderGitHub = "GitHub"
+
+	// ProviderGitLab represents the identifier for the GitLab OAuth2 provider (gitlab.com, or a self-managed instance with BaseURL).
+	ProviderGitLab = "GitLab"
+
+	// ProviderOIDC represents any OpenID Connect provider (e.g., Keycloak, Auth0, Okta),
+	// whose endpoints are found by discovery from its issuer (see Config.BaseURL).
+	ProviderOIDC = "OIDC"
 )
 
-// googleScopes defines the scopes required for Google OAuth2 authentication.
-var googleScopes = []string{
-	"https://www.googleapis.com/auth/userinfo.email",
-	"https://www.googleapis.com/auth/userinfo.profile",
-}
+const (
+	// googleIssuer is the issuer of the Google ID tokens, which also appears without the scheme in older ones.
+	googleIssuer = "https://accounts.google.com"
+
+	// googleUserInfoURL is the OpenID Connect userinfo endpoint of Google.
+	googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+	// googleJWKSURI is where the signing keys of the Google ID tokens are published.
+	googleJWKSURI = "https://www.googleapis.com/oauth2/v3/certs"
+
+	// githubAPIURL is the REST API of github.com. GitHub Enterprise Server serves it under "/api/v3" instead.
+	githubAPIURL = "https://api.github.com"
+
+	// gitlabURL is the default GitLab instance.
+	gitlabURL = "https://gitlab.com"
+
+	// defaultHTTPTimeout is the timeout of the default HTTP client used to talk to the providers.
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+var (
+	// oidcScopes are the scopes requested from the OpenID Connect providers (including Google and GitLab).
+	oidcScopes = []string{"openid", "email", "profile"}
+
+	// githubScopes are the scopes requested from GitHub, which has no OpenID Connect.
+	// "user:email" is what allows reading the private and the verified emails.
+	githubScopes = []string{"read:user", "user:email"}
+)
 
 // Config represents the configuration for the OAuth2 Manager.
 type Config struct {
-	Provider      string
-	ClientID      string
-	ClientSecret  string
-	RedirectURL   string
+	Provider     string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered at the provider, where HandleCallback is served
+	// (e.g., "https://example.com/v1/auth/google/callback" with Registry.Mount).
+	RedirectURL string
+
+	// Name identifies the provider in the routes of the Registry and in the linked identities,
+	// so it must not change once users linked their accounts.
+	//
+	// Optional. Default: the lower case Provider (e.g., "google"). Required to use more than one OIDC provider.
+	Name string
+
+	// Scopes replaces the default scopes of the provider.
+	//
+	// Optional. Default: "openid email profile" for the OpenID Connect providers, "read:user user:email" for GitHub.
+	Scopes []string
+
+	// BaseURL is the issuer URL of a ProviderOIDC, whose endpoints are found by discovery (required),
+	// or the URL of a self-managed GitLab or GitHub Enterprise Server instance (optional).
+	BaseURL string
+
+	// SuccessRedirect is where the browser is redirected once logged in.
+	//
+	// Optional. Default: "" (the user is answered as JSON).
+	SuccessRedirect string
+
+	// HTTPClient is the client used to talk to the provider (discovery, token exchange, userinfo and signing keys).
+	//
+	// Optional. Default: a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+
+	// SessionConfig is the config of the session store used when the session middleware doesn't run before the handlers.
+	// It should be the same as the one of the session middleware (e.g., same storage and cookie), so the logins are shared.
 	SessionConfig session.Config
+
 	// Note: The DB field cannot be nil.
 	DB database.ServiceAuth
 }
 
+// provider is what the Manager needs to know about a provider.
+type provider struct {
+	endpoint    oauth2.Endpoint
+	scopes      []string
+	userInfoURL string
+	apiURL      string // GitHub only
+
+	// verifier is set for the OpenID Connect providers, whose ID token is verified (including the nonce).
+	verifier *idTokenVerifier
+}
+
 // Manager represents an OAuth2 manager that handles the OAuth2 flow.
 // It contains the OAuth2 configuration required for authentication.
 type Manager struct {
-	config *oauth2.Config
-	store  *session.Store
-	db     database.ServiceAuth
+	name            string
+	config          *oauth2.Config
+	provider        *provider
+	client          *http.Client
+	store           *session.Store
+	db              database.ServiceAuth
+	successRedirect string
 }
 
 // New creates a new instance of the OAuth2 Manager.
@@ -60,39 +144,123 @@ type Manager struct {
 //		ClientSecret:  "your-client-secret",
 //		RedirectURL:   "your-redirect-url",
 //		SessionConfig: sessionConfig,
-//		DB:            dbService.AuthUser(),
+//		DB:            dbService.Auth(),
 //	}
 //
 //	// Create an instance of the OAuth2 manager
-//	manager := oauth2.New(cfg)
-func New(cfg Config) *Manager {
+//	manager, err := oauth2.New(cfg)
+//
+// Note: For ProviderOIDC, the discovery document is fetched here, so it fails when the provider is unreachable.
+// It returns ErrorsUnsupportedProviderEndpoint for an unknown provider.
+func New(cfg Config) (*Manager, error) {
 	// Check if the DB field in the Config struct is nil
 	if cfg.DB == nil {
 		panic("better oauth2: DB field in the Config struct cannot be nil")
 	}
 
-	var config *oauth2.Config
+	client := cfg.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
 
-	switch cfg.Provider {
-	// TODO: This still needs improvement because Google has many types of OAuth2 (e.g., for desktop, which has been used to implement OAuth2-CLI before, and for web)
-	case ProviderGoogle:
-		config = &oauth2.Config{
+	p, err := newProvider(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Scopes) > 0 {
+		p.scopes = cfg.Scopes
+	}
+
+	return &Manager{
+		name: cmp.Or(cfg.Name, strings.ToLower(cfg.Provider)),
+		config: &oauth2.Config{
 			ClientID:     cfg.ClientID,
 			ClientSecret: cfg.ClientSecret,
 			RedirectURL:  cfg.RedirectURL,
-			Scopes:       googleScopes,
-			Endpoint:     google.Endpoint,
+			Scopes:       p.scopes,
+			Endpoint:     p.endpoint,
+		},
+		provider:        p,
+		client:          client,
+		store:           session.New(cfg.SessionConfig),
+		db:              cfg.DB,
+		successRedirect: cfg.SuccessRedirect,
+	}, nil
+}
+
+// Name returns the name of the provider, as used in the routes of the Registry and in the linked identities.
+func (m *Manager) Name() string {
+	return m.name
+}
+
+// newProvider returns the endpoints and scopes of the provider.
+func newProvider(cfg Config, client *http.Client) (*provider, error) {
+	baseURL := strings.TrimSuffix(cfg.BaseURL, "/")
+
+	switch cfg.Provider {
+	case ProviderGoogle:
+		p := newOIDCProvider(cfg, client, oidcMetadata{
+			Issuer:           googleIssuer,
+			UserInfoEndpoint: googleUserInfoURL,
+			JWKSURI:          googleJWKSURI,
+		})
+		p.endpoint = google.Endpoint
+		p.verifier.issuers = append(p.verifier.issuers, strings.TrimPrefix(googleIssuer, "https://"))
+		return p, nil
+
+	case ProviderGitLab:
+		// Note: GitLab is an OpenID Connect provider, but its endpoints are well known, so there is no discovery.
+		baseURL = cmp.Or(baseURL, gitlabURL)
+		return newOIDCProvider(cfg, client, oidcMetadata{
+			Issuer:                baseURL,
+			AuthorizationEndpoint: baseURL + "/oauth/authorize",
+			TokenEndpoint:         baseURL + "/oauth/token",
+			UserInfoEndpoint:      baseURL + "/oauth/userinfo",
+			JWKSURI:               baseURL + "/oauth/discovery/keys",
+		}), nil
+
+	case ProviderGitHub:
+		p := &provider{endpoint: github.Endpoint, scopes: githubScopes, apiURL: githubAPIURL}
+		if baseURL != "" {
+			p.endpoint = oauth2.Endpoint{
+				AuthURL:  baseURL + "/login/oauth/authorize",
+				TokenURL: baseURL + "/login/oauth/access_token",
+			}
+			p.apiURL = baseURL + "/api/v3"
+		}
+		return p, nil
+
+	case ProviderOIDC:
+		if baseURL == "" {
+			return nil, ErrInvalidDiscovery
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultHTTPTimeout)
+		defer cancel()
+		metadata, err := discover(ctx, client, baseURL)
+		if err != nil {
+			return nil, err
 		}
+		return newOIDCProvider(cfg, client, metadata), nil
+
 	default:
-		panic(fmt.Sprintf("unsupported provider: %s", cfg.Provider))
+		return nil, ErrorsUnsupportedProviderEndpoint
 	}
+}
 
-	store := session.New(cfg.SessionConfig)
-
-	return &Manager{
-		config: config,
-		store:  store,
-		// TODO: The DB field will be used to verify the user in the database after the token is exchanged, as mentioned earlier in callback.go.
-		db: cfg.DB,
+// newOIDCProvider returns an OpenID Connect provider from its metadata.
+func newOIDCProvider(cfg Config, client *http.Client, metadata oidcMetadata) *provider {
+	return &provider{
+		endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+		scopes:      oidcScopes,
+		userInfoURL: metadata.UserInfoEndpoint,
+		verifier: &idTokenVerifier{
+			issuers:  []string{metadata.Issuer},
+			clientID: cfg.ClientID,
+			jwksURI:  metadata.JWKSURI,
+			client:   client,
+		},
 	}
 }
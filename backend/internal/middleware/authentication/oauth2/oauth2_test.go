@@ -0,0 +1,431 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/oauth2"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// mockUser is the user the mock provider authenticates.
+type mockUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// mockGrant is what the mock provider remembers about an authorization code.
+type mockGrant struct {
+	challenge string
+	nonce     string
+	user      mockUser
+}
+
+// mockIdP is a local OpenID Connect provider, which also serves the GitHub endpoints (like GitHub Enterprise Server).
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	grants    map[string]mockGrant
+	tokens    map[string]mockUser
+	badNonce  bool // sign the ID tokens with a wrong nonce
+	exchanges int
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, grants: map[string]mockGrant{}, tokens: map[string]mockUser{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"userinfo_endpoint":      idp.URL + "/userinfo",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"}}})
+	})
+	mux.HandleFunc("POST /token", idp.handleToken)
+	mux.HandleFunc("POST /login/oauth/access_token", idp.handleToken)
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		user, ok := idp.bearer(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]any{"sub": user.Subject, "email": user.Email, "email_verified": user.EmailVerified, "preferred_username": user.Username})
+	})
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		user, ok := idp.bearer(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, map[string]any{"id": 4242, "login": user.Username, "email": nil})
+	})
+	mux.HandleFunc("GET /api/v3/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		user, ok := idp.bearer(r)
+		if !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, []map[string]any{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": user.Email, "primary": true, "verified": user.EmailVerified},
+		})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// authorize is what the login page of the provider does: it returns an authorization code for the user,
+// bound to the PKCE challenge and the nonce of the authorization request.
+func (idp *mockIdP) authorize(authURL string, user mockUser) (code, state string) {
+	query := must(url.Parse(authURL)).Query()
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code = "code-" + query.Get("state")
+	idp.grants[code] = mockGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce"), user: user}
+	return code, query.Get("state")
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.exchanges++
+
+	grant, ok := idp.grants[r.FormValue("code")]
+	delete(idp.grants, r.FormValue("code"))
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	nonce := grant.nonce
+	if idp.badNonce {
+		nonce = "replayed"
+	}
+	signer := must(jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: idp.key}, (&jose.SignerOptions{}).WithHeader("kid", "test")))
+	idToken := must(jwt.Signed(signer).Claims(jwt.Claims{
+		Issuer:   idp.URL,
+		Subject:  grant.user.Subject,
+		Audience: jwt.Audience{"client"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+	}).Claims(struct {
+		Nonce string `json:"nonce"`
+	}{nonce}).Serialize())
+
+	accessToken := "token-" + r.FormValue("code")
+	idp.tokens[accessToken] = grant.user
+	writeJSON(w, map[string]any{"access_token": accessToken, "token_type": "Bearer", "expires_in": 3600, "id_token": idToken})
+}
+
+func (idp *mockIdP) bearer(r *http.Request) (mockUser, bool) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	user, ok := idp.tokens[r.Header.Get("Authorization")[len("Bearer "):]]
+	return user, ok
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// testApp serves the registry behind a session middleware, with "/me" to check who is logged in.
+type testApp struct {
+	t   *testing.T
+	app *fiber.App
+}
+
+// get sends a GET request with the session cookie, and returns the response and the session cookie it sets, or the same one.
+func (a testApp) get(target, sessionID string) (*http.Response, string) {
+	a.t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	if sessionID != "" {
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+	}
+	resp, err := a.app.Test(req)
+	if err != nil {
+		a.t.Fatalf("app.Test() error = %v", err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "session_id" {
+			return resp, cookie.Value
+		}
+	}
+	return resp, sessionID
+}
+
+// login goes through the login and the callback of the provider as the user, and returns the callback response and the session.
+func (a testApp) login(idp *mockIdP, provider string, user mockUser, sessionID string) (*http.Response, string) {
+	a.t.Helper()
+	resp, sessionID := a.get("/auth/"+provider+"/login", sessionID)
+	if resp.StatusCode != fiber.StatusTemporaryRedirect {
+		a.t.Fatalf("GET /auth/%s/login: expected status code %d, got %d", provider, fiber.StatusTemporaryRedirect, resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if query := must(url.Parse(location)).Query(); query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		a.t.Errorf("authorization URL %s has no PKCE challenge", location)
+	}
+
+	code, state := idp.authorize(location, user)
+	return a.get("/auth/"+provider+"/callback?code="+code+"&state="+state, sessionID)
+}
+
+func TestOAuth2Login(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for _, create := range []func(context.Context, database.Service) error{database.CreateUsersTable, database.CreateUserIdentitiesTable} {
+		if err := create(ctx, db); err != nil {
+			t.Fatalf("create table error = %v", err)
+		}
+	}
+	gopher, _ := db.Auth().CreateUser(ctx, "gopher", "gopher@example.com", "correct horse")
+
+	idp := newMockIdP(t)
+	oidc, err := oauth2.New(oauth2.Config{
+		Provider:     oauth2.ProviderOIDC,
+		Name:         "mock",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/auth/mock/callback",
+		BaseURL:      idp.URL,
+		DB:           db.Auth(),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	github, err := oauth2.New(oauth2.Config{
+		Provider:     oauth2.ProviderGitHub,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/auth/github/callback",
+		BaseURL:      idp.URL,
+		DB:           db.Auth(),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	registry := oauth2.NewRegistry(oidc, github)
+	if got := registry.Providers(); len(got) != 2 || got[0] != "github" || got[1] != "mock" {
+		t.Errorf("Providers() = %v, want [github mock]", got)
+	}
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(users.SessionContextKey, sess)
+		return c.Next()
+	})
+	var limited int
+	registry.Mount(app.Group("/auth"), func(c *fiber.Ctx) error {
+		limited++
+		return c.Next()
+	})
+	app.Get("/me", users.RequireUser(db), users.Me())
+	a := testApp{t: t, app: app}
+
+	me := func(sessionID string) database.User {
+		t.Helper()
+		resp, _ := a.get("/me", sessionID)
+		var user database.User
+		if resp.StatusCode == fiber.StatusOK {
+			json.NewDecoder(resp.Body).Decode(&user)
+		}
+		return user
+	}
+
+	alice := mockUser{Subject: "alice-sub", Email: "Alice@Example.com", EmailVerified: true, Username: "alice"}
+
+	t.Run("sign up then sign in", func(t *testing.T) {
+		resp, sess := a.login(idp, "mock", alice, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("callback: expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+		first := me(sess)
+		if first.ID == "" || first.Username != "alice" || first.Email != "alice@example.com" {
+			t.Fatalf("GET /me = %+v, want the new user alice", first)
+		}
+
+		_, sess = a.login(idp, "mock", alice, "")
+		if again := me(sess); again.ID != first.ID {
+			t.Errorf("second login = %s, want the same user %s", again.ID, first.ID)
+		}
+	})
+
+	t.Run("verified email links to the existing user", func(t *testing.T) {
+		resp, sess := a.login(idp, "github", mockUser{Email: "gopher@example.com", EmailVerified: true, Username: "gopher"}, "")
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("callback: expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+		if got := me(sess); got.ID != gopher.ID {
+			t.Errorf("GET /me = %+v, want %s", got, gopher.ID)
+		}
+		identities, _ := db.Auth().ListIdentities(ctx, gopher.ID)
+		if len(identities) != 1 || identities[0].Provider != "github" || identities[0].Subject != "4242" {
+			t.Errorf("ListIdentities() = %+v, want the GitHub account 4242", identities)
+		}
+	})
+
+	t.Run("unverified email doesn't link", func(t *testing.T) {
+		mallory := mockUser{Subject: "mallory", Email: "gopher@example.com", Username: "mallory"}
+		if resp, _ := a.login(idp, "mock", mallory, ""); resp.StatusCode != fiber.StatusConflict {
+			t.Errorf("callback: expected status code %d, got %d", fiber.StatusConflict, resp.StatusCode)
+		}
+	})
+
+	t.Run("logged-in user links another account", func(t *testing.T) {
+		_, sess := a.login(idp, "github", mockUser{Email: "gopher@example.com", EmailVerified: true}, "")
+		bob := mockUser{Subject: "gopher-at-mock", Email: "someone-else@example.com", Username: "whatever"}
+		resp, sess := a.login(idp, "mock", bob, sess)
+		if resp.StatusCode != fiber.StatusOK || me(sess).ID != gopher.ID {
+			t.Fatalf("callback: got status code %d and user %+v, want %s", resp.StatusCode, me(sess), gopher.ID)
+		}
+		if identities, _ := db.Auth().ListIdentities(ctx, gopher.ID); len(identities) != 2 {
+			t.Errorf("ListIdentities() = %+v, want 2 identities", identities)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		resp, sess := a.get("/auth/mock/login", "")
+		code, _ := idp.authorize(resp.Header.Get("Location"), alice)
+		exchanges := idp.exchanges
+		if resp, _ := a.get("/auth/mock/callback?code="+code+"&state=forged", sess); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("callback: expected status code %d, got %d", fiber.StatusBadRequest, resp.StatusCode)
+		}
+		if idp.exchanges != exchanges {
+			t.Error("the code was exchanged despite the invalid state")
+		}
+	})
+
+	t.Run("state of another provider", func(t *testing.T) {
+		resp, sess := a.get("/auth/mock/login", "")
+		code, state := idp.authorize(resp.Header.Get("Location"), alice)
+		if resp, _ := a.get("/auth/github/callback?code="+code+"&state="+state, sess); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("callback: expected status code %d, got %d", fiber.StatusBadRequest, resp.StatusCode)
+		}
+	})
+
+	t.Run("callback replay", func(t *testing.T) {
+		resp, sess := a.get("/auth/mock/login", "")
+		code, state := idp.authorize(resp.Header.Get("Location"), alice)
+		target := "/auth/mock/callback?code=" + code + "&state=" + state
+		if resp, _ := a.get(target, sess); resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("callback: expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+		if resp, _ := a.get(target, sess); resp.StatusCode != fiber.StatusBadRequest {
+			t.Errorf("replayed callback: expected status code %d, got %d", fiber.StatusBadRequest, resp.StatusCode)
+		}
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		idp.badNonce = true
+		defer func() { idp.badNonce = false }()
+		if resp, _ := a.login(idp, "mock", alice, ""); resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("callback: expected status code %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		_, sess := a.get("/auth/mock/login", "")
+		if resp, _ := a.get("/auth/mock/callback?error=access_denied", sess); resp.StatusCode != fiber.StatusUnauthorized {
+			t.Errorf("callback: expected status code %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		if resp, _ := a.get("/auth/facebook/login", ""); resp.StatusCode != fiber.StatusNotFound {
+			t.Errorf("login: expected status code %d, got %d", fiber.StatusNotFound, resp.StatusCode)
+		}
+	})
+
+	if limited == 0 {
+		t.Error("the rate limiter wasn't called for the callbacks")
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	// An issuer that claims to be another one is rejected.
+	liar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"issuer": "https://accounts.example.com", "authorization_endpoint": "a", "token_endpoint": "t", "jwks_uri": "j"})
+	}))
+	defer liar.Close()
+
+	tests := []struct {
+		name string
+		cfg  oauth2.Config
+	}{
+		{"unsupported provider", oauth2.Config{Provider: "Facebook"}},
+		{"OIDC without issuer", oauth2.Config{Provider: oauth2.ProviderOIDC}},
+		{"issuer mismatch", oauth2.Config{Provider: oauth2.ProviderOIDC, BaseURL: liar.URL}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.DB = db.Auth()
+			if _, err := oauth2.New(tt.cfg); err == nil {
+				t.Error("New() error = nil, want an error")
+			}
+		})
+	}
+}
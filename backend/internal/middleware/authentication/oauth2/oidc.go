@@ -0,0 +1,197 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package oauth2
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+var (
+	// ErrInvalidIDToken is returned when the ID token of an OpenID Connect provider is missing, or fails the verification
+	// (e.g., wrong signature, issuer, audience or nonce, or expired).
+	ErrInvalidIDToken = errors.New("better oauth2: invalid ID token")
+
+	// ErrInvalidDiscovery is returned when the OpenID Connect discovery document is unusable, or there is no issuer to discover.
+	ErrInvalidDiscovery = errors.New("better oauth2: invalid OpenID Connect discovery document")
+)
+
+const (
+	// discoveryPath is appended to the issuer URL to find its discovery document (OpenID Connect Discovery 1.0).
+	discoveryPath = "/.well-known/openid-configuration"
+
+	// idTokenLeeway is the clock skew allowed when checking the times of an ID token.
+	idTokenLeeway = time.Minute
+
+	// jwksRefreshInterval is the minimum time between two fetches of the signing keys of a provider,
+	// so ID tokens with unknown key IDs can't be used to hammer it.
+	jwksRefreshInterval = time.Minute
+)
+
+// idTokenAlgorithms are the accepted signature algorithms of the ID tokens.
+//
+// Note: "none" and the HMAC algorithms are deliberately not in the list, since the keys come from the provider.
+var idTokenAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// oidcMetadata is the part of an OpenID Connect discovery document that is used.
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims are the standard claims of the user, found in the ID token and in the userinfo response.
+type oidcClaims struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	EmailVerified     any    `json:"email_verified"` // a bool, but some providers send a string
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Nickname          string `json:"nickname"`
+	Picture           string `json:"picture"`
+}
+
+// emailVerified returns the email_verified claim, whether it was sent as a bool or a string.
+func (c oidcClaims) emailVerified() bool {
+	switch v := c.EmailVerified.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// discover fetches the discovery document of the issuer.
+func discover(ctx context.Context, client *http.Client, issuer string) (oidcMetadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	var metadata oidcMetadata
+	if err := getJSON(ctx, client, issuer+discoveryPath, "", &metadata); err != nil {
+		return metadata, fmt.Errorf("%w: %v", ErrInvalidDiscovery, err)
+	}
+
+	// The issuer must be the one that was asked for, otherwise a provider could issue tokens in the name of another (OpenID Connect Discovery 1.0, section 4.3).
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return metadata, fmt.Errorf("%w: issuer %q doesn't match %q", ErrInvalidDiscovery, metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return metadata, fmt.Errorf("%w: missing endpoints", ErrInvalidDiscovery)
+	}
+	return metadata, nil
+}
+
+// idTokenVerifier verifies the ID tokens of an OpenID Connect provider against its signing keys,
+// which are fetched on first use and again when a token is signed by an unknown key (e.g., after a key rotation).
+type idTokenVerifier struct {
+	issuers  []string
+	clientID string
+	jwksURI  string
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// verify checks the signature and the claims of the ID token, including the nonce sent in the authorization request,
+// and returns the claims of the user.
+func (v *idTokenVerifier) verify(ctx context.Context, raw, nonce string) (oidcClaims, error) {
+	var user oidcClaims
+	if raw == "" {
+		return user, fmt.Errorf("%w: missing", ErrInvalidIDToken)
+	}
+
+	token, err := jwt.ParseSigned(raw, idTokenAlgorithms)
+	if err != nil {
+		return user, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	key, err := v.key(ctx, token.Headers[0].KeyID)
+	if err != nil {
+		return user, err
+	}
+
+	var (
+		claims jwt.Claims
+		extra  struct {
+			Nonce string `json:"nonce"`
+		}
+	)
+	if err := token.Claims(key, &claims, &extra, &user); err != nil {
+		return user, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if !slices.Contains(v.issuers, claims.Issuer) {
+		return user, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Expiry == nil || claims.Subject == "" {
+		return user, fmt.Errorf("%w: missing expiry or subject", ErrInvalidIDToken)
+	}
+	if err := claims.ValidateWithLeeway(jwt.Expected{
+		AnyAudience: jwt.Audience{v.clientID},
+		Time:        time.Now(),
+	}, idTokenLeeway); err != nil {
+		return user, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(extra.Nonce), []byte(nonce)) != 1 {
+		return user, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return user, nil
+}
+
+// key returns the signing key with the given ID, fetching the keys again when it's unknown.
+func (v *idTokenVerifier) key(ctx context.Context, kid string) (jose.JSONWebKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(v.fetchedAt) < jwksRefreshInterval {
+		return jose.JSONWebKey{}, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := getJSON(ctx, v.client, v.jwksURI, "", &keys); err != nil {
+		return jose.JSONWebKey{}, fmt.Errorf("better oauth2: failed to fetch the signing keys: %w", err)
+	}
+	v.keys, v.fetchedAt = keys, time.Now()
+
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	return jose.JSONWebKey{}, fmt.Errorf("%w: unknown key %q", ErrInvalidIDToken, kid)
+}
+
+// lookup returns the public signing key with the given ID, or the only key when the token doesn't name one.
+func (v *idTokenVerifier) lookup(kid string) (jose.JSONWebKey, bool) {
+	keys := v.keys.Keys
+	if kid != "" {
+		keys = v.keys.Key(kid)
+	}
+	if len(keys) != 1 || !keys[0].Valid() || !keys[0].IsPublic() || (keys[0].Use != "" && keys[0].Use != "sig") {
+		return jose.JSONWebKey{}, false
+	}
+	return keys[0], true
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package oauth2
+
+import (
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Registry is a set of OAuth2 managers served under the same routes, one per provider (e.g., "/google/login" and "/github/login").
+//
+// Example Usage:
+//
+//	google, _ := oauth2.New(oauth2.Config{Provider: oauth2.ProviderGoogle, RedirectURL: "https://example.com/v1/auth/google/callback", ...})
+//	github, _ := oauth2.New(oauth2.Config{Provider: oauth2.ProviderGitHub, RedirectURL: "https://example.com/v1/auth/github/callback", ...})
+//
+//	registry := oauth2.NewRegistry(google, github)
+//	registry.Mount(v1.Group("/auth"), rateLimiter)
+type Registry struct {
+	managers map[string]*Manager
+}
+
+// NewRegistry creates a new Registry of the managers.
+//
+// Note: The names of the managers must be unique (see Config.Name), otherwise the last one wins.
+func NewRegistry(managers ...*Manager) *Registry {
+	r := &Registry{managers: make(map[string]*Manager, len(managers))}
+	for _, m := range managers {
+		r.managers[strings.ToLower(m.Name())] = m
+	}
+	return r
+}
+
+// Get returns the manager of the provider with the given name.
+func (r *Registry) Get(name string) (*Manager, bool) {
+	m, ok := r.managers[strings.ToLower(name)]
+	return m, ok
+}
+
+// Providers returns the names of the providers, sorted.
+func (r *Registry) Providers() []string {
+	return slices.Sorted(maps.Keys(r.managers))
+}
+
+// HandleLogin is Manager.HandleLogin for the provider named by the "provider" route parameter.
+func (r *Registry) HandleLogin(c *fiber.Ctx) error {
+	m, ok := r.Get(c.Params("provider"))
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusNotFound, "Unknown provider")
+	}
+	return m.HandleLogin(c)
+}
+
+// HandleCallback is Manager.HandleCallback for the provider named by the "provider" route parameter.
+func (r *Registry) HandleCallback(c *fiber.Ctx) error {
+	m, ok := r.Get(c.Params("provider"))
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusNotFound, "Unknown provider")
+	}
+	return m.HandleCallback(c)
+}
+
+// Mount registers "GET /:provider/login" and "GET /:provider/callback" on the router,
+// with the rate limiter (if not nil) in front of the callback.
+//
+// Note: The RedirectURL of each manager must point to its callback route.
+func (r *Registry) Mount(router fiber.Router, rateLimiter fiber.Handler) {
+	router.Get("/:provider/login", r.HandleLogin)
+	if rateLimiter != nil {
+		router.Get("/:provider/callback", rateLimiter, r.HandleCallback)
+		return
+	}
+	router.Get("/:provider/callback", r.HandleCallback)
+}
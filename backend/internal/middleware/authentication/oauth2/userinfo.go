@@ -6,40 +6,163 @@
 package oauth2
 
 import (
+	"cmp"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
 	"h0llyw00dz-template/backend/pkg/gc"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/gofiber/fiber/v2"
-	"golang.org/x/oauth2/google"
-)
-
-const (
-	googleUserInfoURL = "https://www.googleapis.com/oauth2/v2/userinfo"
+	"golang.org/x/oauth2"
 )
 
 var (
 	// ErrorsUnsupportedProviderEndpoint is returned when the OAuth2 provider's endpoint is not supported by the Manager.
 	// It indicates that the Manager does not have the necessary configuration or implementation to handle the specified provider's endpoint.
 	ErrorsUnsupportedProviderEndpoint = errors.New("better oauth2: unsupported provider endpoint")
+
+	// ErrMissingSubject is returned when the provider didn't return the ID of the user.
+	ErrMissingSubject = errors.New("better oauth2: missing user ID in the user information")
 )
 
+// UserInfo is the user information of the provider, normalized to the OpenID Connect claims whatever the provider.
+type UserInfo struct {
+	Provider      string `json:"provider"`
+	Subject       string `json:"sub"` // the stable ID of the user at the provider
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Username      string `json:"preferred_username"`
+	Picture       string `json:"picture"`
+}
+
+// Identity returns the user information as the identity linked to the users (see [database.ServiceAuth]).
+func (u UserInfo) Identity() database.Identity {
+	return database.Identity{
+		Provider:      u.Provider,
+		Subject:       u.Subject,
+		Email:         strings.ToLower(u.Email),
+		EmailVerified: u.EmailVerified,
+		Username:      u.Username,
+	}
+}
+
+// githubUser is the response of the GitHub "/user" API.
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// githubEmail is an element of the response of the GitHub "/user/emails" API.
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
 // getUserInfo retrieves the user information from the OAuth2 provider's API.
-// It takes an OAuth2 client and a Fiber context as parameters and returns a map of user information.
-func (m *Manager) getUserInfo(c *fiber.Ctx, client *http.Client) (map[string]any, error) {
-	var userInfoURL string
+// For the OpenID Connect providers, the ID token is verified first, including the nonce sent with the authorization request.
+func (m *Manager) getUserInfo(ctx context.Context, token *oauth2.Token, nonce string) (UserInfo, error) {
+	var (
+		info UserInfo
+		err  error
+	)
+	if m.provider.verifier != nil {
+		info, err = m.getOIDCUserInfo(ctx, token, nonce)
+	} else {
+		info, err = m.getGitHubUserInfo(ctx, token)
+	}
+	if err != nil {
+		return info, err
+	}
+	if info.Subject == "" {
+		return info, ErrMissingSubject
+	}
+	info.Provider = m.name
+	return info, nil
+}
 
-	// TODO: This still needs improvement because Google has many types of OAuth2 (e.g., for desktop, which has been used to implement OAuth2-CLI before, and for web)
-	switch m.config.Endpoint {
-	case google.Endpoint:
-		userInfoURL = googleUserInfoURL
-	default:
-		return nil, ErrorsUnsupportedProviderEndpoint
+// getOIDCUserInfo verifies the ID token, then completes its claims with the userinfo endpoint, if there is one.
+func (m *Manager) getOIDCUserInfo(ctx context.Context, token *oauth2.Token, nonce string) (UserInfo, error) {
+	rawIDToken, _ := token.Extra("id_token").(string)
+	claims, err := m.provider.verifier.verify(ctx, rawIDToken, nonce)
+	if err != nil {
+		return UserInfo{}, err
 	}
 
-	resp, err := client.Get(userInfoURL)
+	if m.provider.userInfoURL != "" {
+		var fetched oidcClaims
+		if err := getJSON(ctx, m.client, m.provider.userInfoURL, token.AccessToken, &fetched); err != nil {
+			return UserInfo{}, err
+		}
+		// The userinfo response must be about the user of the ID token (OpenID Connect Core 1.0, section 5.3.2).
+		if fetched.Subject != claims.Subject {
+			return UserInfo{}, fmt.Errorf("%w: userinfo subject doesn't match", ErrInvalidIDToken)
+		}
+		claims = fetched
+	}
+
+	return UserInfo{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.emailVerified(),
+		Name:          claims.Name,
+		Username:      cmp.Or(claims.PreferredUsername, claims.Nickname),
+		Picture:       claims.Picture,
+	}, nil
+}
+
+// getGitHubUserInfo retrieves the user, then its primary email, since the public email of the profile may be empty or unverified.
+func (m *Manager) getGitHubUserInfo(ctx context.Context, token *oauth2.Token) (UserInfo, error) {
+	var user githubUser
+	if err := getJSON(ctx, m.client, m.provider.apiURL+"/user", token.AccessToken, &user); err != nil {
+		return UserInfo{}, err
+	}
+
+	info := UserInfo{
+		Name:     user.Name,
+		Username: user.Login,
+		Picture:  user.AvatarURL,
+		Email:    user.Email,
+	}
+	if user.ID != 0 {
+		info.Subject = strconv.FormatInt(user.ID, 10)
+	}
+
+	var emails []githubEmail
+	if err := getJSON(ctx, m.client, m.provider.apiURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return UserInfo{}, err
+	}
+	for _, email := range emails {
+		if email.Primary {
+			info.Email, info.EmailVerified = email.Email, email.Verified
+			break
+		}
+	}
+	return info, nil
+}
+
+// getJSON sends a GET request (with the access token, if any) and decodes the JSON response.
+func getJSON(ctx context.Context, client *http.Client, url, accessToken string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -53,15 +176,10 @@ func (m *Manager) getUserInfo(c *fiber.Ctx, client *http.Client) (map[string]any
 
 	// Read the response body into the buffer
 	if _, err := buf.ReadFrom(resp.Body); err != nil {
-		return nil, err
+		return err
 	}
-
-	// TODO: This still needs improvement.
-	var userInfo map[string]any
-	// Use the decoder from the Fiber app configuration
-	if err := c.App().Config().JSONDecoder(buf.Bytes(), &userInfo); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("better oauth2: GET %s: %s", url, resp.Status)
 	}
-
-	return userInfo, nil
+	return json.Unmarshal(buf.Bytes(), v)
 }
@@ -0,0 +1,39 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package totp provides the TOTP (RFC 6238) second factor of the users, as used by the authenticator apps
+// (e.g., Google Authenticator, Aegis, 1Password).
+//
+// The Manager covers the whole lifecycle:
+//
+//  1. Enrollment: a secret is generated and shown with its otpauth:// URI (as a QR code), then confirmed with a code,
+//     which enables it and generates the recovery codes.
+//
+//  2. Verification: a code is accepted within a clock-skew window (see Config.Skew), and only once, since the last used
+//     time step of each user is kept in Redis. Any code of an earlier (or the same) time step is then rejected, which is
+//     what RFC 6238, Section 5.2 recommends against replays.
+//
+//  3. Recovery codes: single-use codes, stored as bcrypt hashes, for the users who lost their authenticator.
+//
+//  4. Step-up: the RequireStepUp middleware requires a recent proof of the second factor on the selected routes,
+//     either from the session (see HandleVerify) or from a code in a header (e.g., for API keys).
+//
+// Example Usage:
+//
+//	manager, err := totp.New(totp.Config{
+//		Issuer:        "Gopher",
+//		DB:            db,
+//		EncryptionKey: key, // 32 bytes
+//	})
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	manager.Mount(v1.Group("/2fa", userSessions), users.RequireUser(db), rateLimiter)
+//	v1.Delete("/account", userSessions, users.RequireUser(db), manager.RequireStepUp(), deleteAccount)
+//
+// Note: The secrets are encrypted in the database when Config.EncryptionKey is set, which is strongly recommended.
+// Also note that the tables must be created first (see CreateTOTPTable and CreateRecoveryCodesTable).
+package totp
@@ -0,0 +1,185 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// codeRequest is the body of the requests proving the second factor, with either a code or a recovery code.
+type codeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// HandleEnroll generates a new secret for the logged-in user, answering it with its otpauth:// URI (to show as a QR code).
+//
+// Note: It must be behind users.RequireUser. The TOTP isn't enabled until it's confirmed with a code (see HandleConfirm).
+func (m *Manager) HandleEnroll(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	enrollment, err := m.Enroll(c.UserContext(), u)
+	if err != nil {
+		return m.sendError(c, err)
+	}
+	return c.JSON(enrollment)
+}
+
+// HandleConfirm enables the pending TOTP of the logged-in user with a code, answering the recovery codes.
+// Since the user has just proved the second factor, the session is stepped up as well.
+//
+// Note: It must be behind users.RequireUser.
+func (m *Manager) HandleConfirm(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	var body codeRequest
+	if err := c.BodyParser(&body); err != nil {
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	codes, err := m.Confirm(c.UserContext(), u.ID, body.Code)
+	if err != nil {
+		return m.sendError(c, err)
+	}
+	log.LogUserActivity(c, "User "+u.ID+" enabled TOTP")
+
+	if err := m.stepUp(c, u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// HandleVerify steps up the session of the logged-in user with a code or a recovery code (see RequireStepUp).
+//
+// Note: It must be behind users.RequireUser, and combined with Fiber's rate limiter to protect against bots bruteforce attacks,
+// which is what Mount does.
+func (m *Manager) HandleVerify(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	if err := m.verifyRequest(c, u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	if err := m.stepUp(c, u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// HandleRegenerateRecoveryCodes replaces the recovery codes of the logged-in user, answering the new ones.
+// It requires a code or a recovery code, like HandleVerify.
+//
+// Note: It must be behind users.RequireUser.
+func (m *Manager) HandleRegenerateRecoveryCodes(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	if err := m.verifyRequest(c, u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	codes, err := m.RegenerateRecoveryCodes(c.UserContext(), u.ID)
+	if err != nil {
+		return m.sendError(c, err)
+	}
+	log.LogUserActivity(c, "User "+u.ID+" regenerated the TOTP recovery codes")
+
+	return c.JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// HandleDisable removes the TOTP of the logged-in user. It requires a code or a recovery code, like HandleVerify.
+//
+// Note: It must be behind users.RequireUser.
+func (m *Manager) HandleDisable(c *fiber.Ctx) error {
+	u, ok := users.UserFromContext(c)
+	if !ok {
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+	}
+
+	if err := m.verifyRequest(c, u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	if err := m.Disable(c.UserContext(), u.ID); err != nil {
+		return m.sendError(c, err)
+	}
+	log.LogUserActivity(c, "User "+u.ID+" disabled TOTP")
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Mount registers the routes of the second factor on the router, all behind requireUser:
+//
+//   - "POST /enroll"
+//   - "POST /confirm", "POST /verify", "POST /recovery-codes" and "POST /disable", with the rate limiter (if not nil) in front.
+//
+// Note: requireUser is usually users.RequireUser, and the session middleware of the users must be in front of the router.
+func (m *Manager) Mount(router fiber.Router, requireUser, rateLimiter fiber.Handler) {
+	router.Post("/enroll", requireUser, m.HandleEnroll)
+
+	limited := func(path string, handler fiber.Handler) {
+		if rateLimiter != nil {
+			router.Post(path, requireUser, rateLimiter, handler)
+			return
+		}
+		router.Post(path, requireUser, handler)
+	}
+	limited("/confirm", m.HandleConfirm)
+	limited("/verify", m.HandleVerify)
+	limited("/recovery-codes", m.HandleRegenerateRecoveryCodes)
+	limited("/disable", m.HandleDisable)
+}
+
+// verifyRequest checks the code, or the recovery code, of the body.
+func (m *Manager) verifyRequest(c *fiber.Ctx, userID string) error {
+	var body codeRequest
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.ErrBadRequest
+	}
+
+	var err error
+	if body.RecoveryCode != "" {
+		if err = m.UseRecoveryCode(c.UserContext(), userID, body.RecoveryCode); err == nil {
+			log.LogUserActivity(c, "User "+userID+" used a TOTP recovery code")
+		}
+	} else {
+		err = m.Verify(c.UserContext(), userID, body.Code)
+	}
+	if errors.Is(err, ErrInvalidCode) {
+		log.LogUserActivity(c, "Invalid TOTP code for user "+userID)
+	}
+	return err
+}
+
+// sendError maps the errors of the manager to problem details.
+func (m *Manager) sendError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, fiber.ErrBadRequest):
+		return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	case errors.Is(err, ErrInvalidCode):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid code")
+	case errors.Is(err, ErrNotEnrolled):
+		return helper.SendProblemResponse(c, fiber.StatusConflict, "Two-factor authentication is not enabled")
+	case errors.Is(err, ErrAlreadyEnrolled):
+		return helper.SendProblemResponse(c, fiber.StatusConflict, "Two-factor authentication is already enabled")
+	default:
+		log.LogErrorf("Unexpected error in TOTP handler: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp
+
+import (
+	"cmp"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/bcrypt"
+	"time"
+)
+
+var (
+	// ErrMissingIssuer is returned by New when Config.Issuer is empty.
+	ErrMissingIssuer = errors.New("totp: issuer is required")
+
+	// ErrMissingDatabase is returned by New when Config.DB is nil.
+	ErrMissingDatabase = errors.New("totp: database is required")
+
+	// ErrInvalidEncryptionKey is returned by New when Config.EncryptionKey is not a 32-byte key.
+	ErrInvalidEncryptionKey = errors.New("totp: encryption key must be 32 bytes")
+)
+
+// Config defines the config for the TOTP manager.
+type Config struct {
+	// Issuer is the name of the service shown by the authenticator apps (e.g., "Gopher").
+	//
+	// Required.
+	Issuer string
+
+	// DB stores the secrets and the recovery codes (see CreateTOTPTable and CreateRecoveryCodesTable),
+	// and its Redis client stores the last used time steps (replay prevention).
+	//
+	// Required.
+	DB database.Service
+
+	// EncryptionKey is a 32-byte key encrypting the secrets in the database with AES-256-GCM.
+	//
+	// Optional. Default: nil (the secrets are stored as is)
+	//
+	// Note: It's strongly recommended, since a leaked secret is a second factor that can be generated forever.
+	// Changing the key makes the stored secrets unreadable, so the users must enroll again.
+	EncryptionKey []byte
+
+	// Digits is the number of digits of the codes, either 6 or 8.
+	//
+	// Optional. Default: 6
+	Digits int
+
+	// Period is how long a code is valid.
+	//
+	// Optional. Default: 30 * time.Second
+	Period time.Duration
+
+	// Skew is the number of periods accepted before and after the current one, for the clocks that drift.
+	//
+	// Optional. Default: 1
+	//
+	// Note: A negative value accepts only the current period.
+	Skew int
+
+	// RecoveryCodes is the number of recovery codes generated for a user.
+	//
+	// Optional. Default: 10
+	RecoveryCodes int
+
+	// StepUpMaxAge is how long a verification of the second factor lasts in the session (see RequireStepUp).
+	//
+	// Optional. Default: 10 * time.Minute
+	StepUpMaxAge time.Duration
+
+	// AllowUnenrolled lets the users without TOTP through RequireStepUp, instead of requiring them to enroll first.
+	//
+	// Optional. Default: false
+	AllowUnenrolled bool
+
+	// KeyPrefix is the prefix of the keys in Redis.
+	//
+	// Optional. Default: "totp:"
+	KeyPrefix string
+
+	// Bcrypt hashes the recovery codes.
+	//
+	// Optional. Default: bcrypt with the default cost
+	Bcrypt bcrypt.Service
+}
+
+// ConfigDefault is the default config.
+var ConfigDefault = Config{
+	Digits:        6,
+	Period:        30 * time.Second,
+	Skew:          1,
+	RecoveryCodes: 10,
+	StepUpMaxAge:  10 * time.Minute,
+	KeyPrefix:     "totp:",
+}
+
+// Manager manages the TOTP second factor of the users: the enrollment, the verification of the codes,
+// the recovery codes, and the step-up of the sessions.
+type Manager struct {
+	cfg  Config
+	db   database.Service
+	aead cipher.AEAD
+}
+
+// New creates a new TOTP manager.
+func New(config Config) (*Manager, error) {
+	if config.Issuer == "" {
+		return nil, ErrMissingIssuer
+	}
+	if config.DB == nil {
+		return nil, ErrMissingDatabase
+	}
+
+	config.Digits = cmp.Or(config.Digits, ConfigDefault.Digits)
+	if config.Digits != 6 && config.Digits != 8 {
+		return nil, ErrInvalidDigits
+	}
+	config.Period = cmp.Or(config.Period.Truncate(time.Second), ConfigDefault.Period)
+	if config.Skew == 0 {
+		config.Skew = ConfigDefault.Skew
+	}
+	config.Skew = max(config.Skew, 0)
+	config.RecoveryCodes = cmp.Or(config.RecoveryCodes, ConfigDefault.RecoveryCodes)
+	config.StepUpMaxAge = cmp.Or(config.StepUpMaxAge, ConfigDefault.StepUpMaxAge)
+	config.KeyPrefix = cmp.Or(config.KeyPrefix, ConfigDefault.KeyPrefix)
+	if config.Bcrypt == nil {
+		config.Bcrypt, _ = bcrypt.New()
+	}
+
+	m := &Manager{cfg: config, db: config.DB}
+	if config.EncryptionKey != nil {
+		if len(config.EncryptionKey) != 32 {
+			return nil, ErrInvalidEncryptionKey
+		}
+		block, err := aes.NewCipher(config.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		if m.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Code returns the current code of the secret, with the digits and the period of the manager (e.g., for tests or a CLI).
+func (m *Manager) Code(secret string, t time.Time) (string, error) {
+	return GenerateCode(secret, t, m.cfg.Digits, m.cfg.Period)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp
+
+import (
+	"errors"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// HeaderCode is the header carrying a code for RequireStepUp, for the clients without a session (e.g., API keys).
+const HeaderCode = "X-TOTP-Code"
+
+// Session keys of the step-up.
+const (
+	sessionStepUpUser = "totp_user"
+	sessionStepUpAt   = "totp_verified_at"
+)
+
+// RequireStepUp is a middleware that requires a recent proof of the second factor:
+//
+//   - For a logged-in user (see users.RequireUser), the session must have been stepped up by HandleVerify (or HandleConfirm)
+//     within Config.StepUpMaxAge.
+//   - For an API key (see keyauth.APIKeyFromContext), whose owner is the user ID, each request must carry a code in HeaderCode.
+//
+// A logged-in user may also send a code in HeaderCode, which is only good for the request.
+// The users without TOTP are rejected, unless Config.AllowUnenrolled is set.
+//
+// Example Usage:
+//
+//	v1.Delete("/account", users.RequireUser(db), manager.RequireStepUp(), deleteAccount)
+//
+// Note: It must be behind users.RequireUser or keyauth, and combined with Fiber's rate limiter when codes are sent in HeaderCode.
+func (m *Manager) RequireStepUp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, sess := m.principal(c)
+		if userID == "" {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		enabled, err := m.Enabled(c.UserContext(), userID)
+		if err != nil {
+			return m.sendError(c, err)
+		}
+		if !enabled {
+			if m.cfg.AllowUnenrolled {
+				return c.Next()
+			}
+			return helper.SendProblemResponse(c, fiber.StatusForbidden, "Two-factor authentication must be enabled")
+		}
+
+		if sess != nil && m.steppedUp(sess, userID) {
+			return c.Next()
+		}
+
+		if code := c.Get(HeaderCode); code != "" {
+			if err := m.Verify(c.UserContext(), userID, code); err != nil {
+				log.LogUserActivity(c, "Invalid TOTP code in step-up for user "+userID)
+				return m.sendError(c, err)
+			}
+			return c.Next()
+		}
+
+		return helper.SendProblemResponse(c, fiber.StatusForbidden, "Two-factor authentication required")
+	}
+}
+
+// principal returns the ID of the user of the request, with their session if they are logged in.
+func (m *Manager) principal(c *fiber.Ctx) (string, *session.Session) {
+	if u, ok := users.UserFromContext(c); ok {
+		sess, _ := c.Locals(users.SessionContextKey).(*session.Session)
+		return u.ID, sess
+	}
+	if key, ok := keyauth.APIKeyFromContext(c); ok {
+		return key.Owner, nil
+	}
+	return "", nil
+}
+
+// steppedUp reports whether the session was stepped up by the user within Config.StepUpMaxAge.
+//
+// Note: The user is checked, since the values of the session are kept when another user logs in with it (see users.StartSession).
+func (m *Manager) steppedUp(sess *session.Session, userID string) bool {
+	user, _ := sess.Get(sessionStepUpUser).(string)
+	at, _ := sess.Get(sessionStepUpAt).(int64)
+	return user == userID && at > 0 && time.Since(time.Unix(at, 0)) <= m.cfg.StepUpMaxAge
+}
+
+// stepUp records the proof of the second factor in the session of the user, and saves it.
+func (m *Manager) stepUp(c *fiber.Ctx, userID string) error {
+	sess, ok := c.Locals(users.SessionContextKey).(*session.Session)
+	if !ok {
+		return errors.New("totp: the session middleware is missing")
+	}
+
+	sess.Set(sessionStepUpUser, userID)
+	sess.Set(sessionStepUpAt, time.Now().Unix())
+	return sess.Save()
+}
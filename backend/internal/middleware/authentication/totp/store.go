@@ -0,0 +1,365 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrNotEnrolled is returned when the user has no TOTP, or hasn't confirmed it yet.
+	ErrNotEnrolled = errors.New("totp: not enrolled")
+
+	// ErrAlreadyEnrolled is returned by Enroll when the user already has a confirmed TOTP.
+	ErrAlreadyEnrolled = errors.New("totp: already enrolled")
+
+	// ErrInvalidCode is returned when a code is wrong, expired, or was already used.
+	ErrInvalidCode = errors.New("totp: invalid code")
+)
+
+const (
+	// TOTPTable is the name of the table storing the TOTP secrets of the users (see [CreateTOTPTable]).
+	TOTPTable = "user_totp"
+
+	// RecoveryCodesTable is the name of the table storing the recovery codes of the users (see [CreateRecoveryCodesTable]).
+	RecoveryCodesTable = "user_recovery_codes"
+)
+
+// sealedPrefix starts the secrets encrypted with Config.EncryptionKey.
+const sealedPrefix = "v1:"
+
+// recoveryAlphabet is the alphabet of the recovery codes, without the characters that look alike (0/o, 1/l/i).
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// replayScript stores the time step of a code, unless the same or a later one was already used (RFC 6238, Section 5.2).
+var replayScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[1]) or "-1")
+if tonumber(ARGV[1]) <= last then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// Enrollment is a secret to add to an authenticator app, which must be confirmed with a code (see Manager.Confirm).
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// CreateTOTPTable creates the table of the TOTP secrets if it doesn't exist.
+//
+// Note: A secret with enabled_at = 0 is pending, until the user confirms it with a code.
+func CreateTOTPTable(ctx context.Context, db database.Service) error {
+	query := `CREATE TABLE IF NOT EXISTS user_totp (
+	user_id CHAR(36) NOT NULL PRIMARY KEY,
+	secret VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	enabled_at BIGINT NOT NULL DEFAULT 0
+)`
+	if db.Dialect().Name() == database.DriverMySQL {
+		query += " ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+	}
+	return db.ExecWithoutRow(ctx, query)
+}
+
+// CreateRecoveryCodesTable creates the table of the recovery codes if it doesn't exist.
+func CreateRecoveryCodesTable(ctx context.Context, db database.Service) error {
+	query := `CREATE TABLE IF NOT EXISTS user_recovery_codes (
+	id CHAR(36) NOT NULL PRIMARY KEY,
+	user_id CHAR(36) NOT NULL,
+	code_hash VARCHAR(255) NOT NULL,
+	created_at BIGINT NOT NULL,
+	used_at BIGINT NOT NULL DEFAULT 0`
+
+	if db.Dialect().Name() == database.DriverMySQL {
+		// MySQL has no "CREATE INDEX IF NOT EXISTS", so the index is declared inline.
+		return db.ExecWithoutRow(ctx, query+",\n\tINDEX idx_user_recovery_codes_user (user_id)\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+
+	if err := db.ExecWithoutRow(ctx, query+"\n)"); err != nil {
+		return err
+	}
+	return db.ExecWithoutRow(ctx, "CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes (user_id)")
+}
+
+// Enroll generates a new secret for the user, replacing a pending one.
+// It returns ErrAlreadyEnrolled when the user has a confirmed TOTP, which must be disabled first.
+func (m *Manager) Enroll(ctx context.Context, user database.User) (Enrollment, error) {
+	if _, enabled, err := m.secret(ctx, user.ID); err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return Enrollment{}, err
+	} else if enabled {
+		return Enrollment{}, ErrAlreadyEnrolled
+	}
+
+	secret, err := GenerateSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+	sealed, err := m.seal(user.ID, secret)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if _, err := m.db.Exec(ctx, "DELETE FROM user_totp WHERE user_id = ? AND enabled_at = 0", user.ID); err != nil {
+		return Enrollment{}, err
+	}
+	const query = "INSERT INTO user_totp (user_id, secret, created_at) VALUES (?, ?, ?)"
+	if err := m.db.ExecWithoutRow(ctx, query, user.ID, sealed, time.Now().UTC().Unix()); err != nil {
+		// Note: Another request enrolled the user in the meantime.
+		return Enrollment{}, fmt.Errorf("totp: failed to enroll: %w", err)
+	}
+
+	return Enrollment{
+		Secret: secret,
+		URI:    URI(m.cfg.Issuer, user.Username, secret, m.cfg.Digits, m.cfg.Period),
+	}, nil
+}
+
+// Confirm enables the pending TOTP of the user when the code is valid, and returns their recovery codes.
+func (m *Manager) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	secret, enabled, err := m.secret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrAlreadyEnrolled
+	}
+	if err := m.check(ctx, userID, secret, code); err != nil {
+		return nil, err
+	}
+
+	result, err := m.db.Exec(ctx, "UPDATE user_totp SET enabled_at = ? WHERE user_id = ? AND enabled_at = 0", time.Now().UTC().Unix(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, ErrAlreadyEnrolled
+	}
+	return m.RegenerateRecoveryCodes(ctx, userID)
+}
+
+// Enabled reports whether the user has a confirmed TOTP.
+func (m *Manager) Enabled(ctx context.Context, userID string) (bool, error) {
+	_, enabled, err := m.secret(ctx, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// Verify checks a code of the confirmed TOTP of the user.
+// A code is accepted once, and so are the codes of the time steps before it, since the last used step is kept in Redis.
+func (m *Manager) Verify(ctx context.Context, userID, code string) error {
+	secret, enabled, err := m.secret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNotEnrolled
+	}
+	return m.check(ctx, userID, secret, code)
+}
+
+// check validates the code against the secret, then records its time step.
+func (m *Manager) check(ctx context.Context, userID, secret, code string) error {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validate(key, strings.TrimSpace(code), time.Now(), m.cfg.Digits, m.cfg.Period, m.cfg.Skew)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	// The step must be remembered as long as a code of it can be accepted, which is the whole window.
+	ttl := time.Duration(2*m.cfg.Skew+2) * m.cfg.Period
+	fresh, err := replayScript.Run(ctx, m.db.RedisClient(), []string{m.cfg.KeyPrefix + "last:" + userID}, step, ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("totp: failed to record the time step: %w", err)
+	}
+	if fresh == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, returning the new ones.
+//
+// Note: The codes are only stored as bcrypt hashes, so they can't be shown again.
+func (m *Manager) RegenerateRecoveryCodes(ctx context.Context, userID string) (codes []string, err error) {
+	codes = make([]string, m.cfg.RecoveryCodes)
+	hashes := make([]string, m.cfg.RecoveryCodes)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		if hashes[i], err = m.cfg.Bcrypt.HashPassword(normalizeRecoveryCode(codes[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer m.db.EnsureTransactionClosure(tx, &err)
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Unix()
+	for _, hash := range hashes {
+		const query = "INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at) VALUES (?, ?, ?, ?)"
+		if _, err = tx.ExecContext(ctx, query, uuid.NewString(), userID, hash, now); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode consumes an unused recovery code of the user.
+func (m *Manager) UseRecoveryCode(ctx context.Context, userID, code string) error {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return ErrInvalidCode
+	}
+
+	rows, err := m.db.Query(ctx, "SELECT id, code_hash FROM user_recovery_codes WHERE user_id = ? AND used_at = 0", userID)
+	if err != nil {
+		return err
+	}
+	var id string
+	for rows.Next() {
+		var candidate, hash string
+		if err := rows.Scan(&candidate, &hash); err != nil {
+			rows.Close()
+			return err
+		}
+		if id == "" && m.cfg.Bcrypt.ComparePassword(code, hash) {
+			id = candidate
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if id == "" {
+		return ErrInvalidCode
+	}
+
+	// Note: The condition on used_at makes a code usable once, even by concurrent requests.
+	result, err := m.db.Exec(ctx, "UPDATE user_recovery_codes SET used_at = ? WHERE id = ? AND used_at = 0", time.Now().UTC().Unix(), id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// RemainingRecoveryCodes returns the number of unused recovery codes of the user.
+func (m *Manager) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := m.db.QueryRow(ctx, "SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = ? AND used_at = 0", userID).Scan(&n)
+	return n, err
+}
+
+// Disable removes the TOTP and the recovery codes of the user.
+func (m *Manager) Disable(ctx context.Context, userID string) error {
+	if _, err := m.db.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	if _, err := m.db.Exec(ctx, "DELETE FROM user_totp WHERE user_id = ?", userID); err != nil {
+		return err
+	}
+	return m.db.RedisClient().Del(ctx, m.cfg.KeyPrefix+"last:"+userID).Err()
+}
+
+// secret returns the secret of the user, and whether it's confirmed.
+func (m *Manager) secret(ctx context.Context, userID string) (string, bool, error) {
+	var (
+		sealed    string
+		enabledAt int64
+	)
+	err := m.db.QueryRow(ctx, "SELECT secret, enabled_at FROM user_totp WHERE user_id = ?", userID).Scan(&sealed, &enabledAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, ErrNotEnrolled
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	secret, err := m.open(userID, sealed)
+	return secret, enabledAt > 0, err
+}
+
+// seal encrypts the secret with the encryption key, if any, bound to the user ID,
+// so a secret can't be copied to another user in the database.
+func (m *Manager) seal(userID, secret string) (string, error) {
+	if m.aead == nil {
+		return secret, nil
+	}
+	nonce := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(m.aead.Seal(nonce, nonce, []byte(secret), []byte(userID))), nil
+}
+
+// open decrypts a secret sealed by seal.
+func (m *Manager) open(userID, sealed string) (string, error) {
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		if m.aead != nil {
+			return "", fmt.Errorf("totp: the secret of user %s isn't encrypted", userID)
+		}
+		return sealed, nil
+	}
+	if m.aead == nil {
+		return "", fmt.Errorf("totp: the secret of user %s is encrypted, but there is no encryption key", userID)
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || len(data) < m.aead.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+	secret, err := m.aead.Open(nil, data[:m.aead.NonceSize()], data[m.aead.NonceSize():], []byte(userID))
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	return string(secret), nil
+}
+
+// generateRecoveryCode returns a random recovery code, as "xxxxx-xxxxx".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		// Note: The modulo bias over 31 characters is negligible for codes that are bcrypt-hashed and single-use.
+		b[i] = recoveryAlphabet[int(b[i])%len(recoveryAlphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// normalizeRecoveryCode removes the dashes and the spaces, and lowercases the code, as users may type it.
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSecret is returned when a secret is not valid base32.
+	ErrInvalidSecret = errors.New("totp: invalid secret")
+
+	// ErrInvalidDigits is returned when the number of digits is neither 6 nor 8.
+	ErrInvalidDigits = errors.New("totp: digits must be 6 or 8")
+)
+
+// secretSize is the size of the generated secrets, 160 bits as RFC 4226 recommends for HMAC-SHA1.
+const secretSize = 20
+
+// encoding is the base32 encoding of the secrets, without padding, like the authenticator apps expect.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret, encoded in base32.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// decodeSecret decodes a base32 secret, ignoring the case, the spaces and the padding that users may type.
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(strings.TrimRight(secret, "="), " ", ""))
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+// GenerateCode returns the code of the secret at the time (RFC 6238), with the number of digits and the period.
+//
+// Note: The algorithm is HMAC-SHA1, which is the only one all the authenticator apps support.
+func GenerateCode(secret string, t time.Time, digits int, period time.Duration) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	if digits != 6 && digits != 8 {
+		return "", ErrInvalidDigits
+	}
+	return hotp(key, step(t, period), digits), nil
+}
+
+// step returns the time step (the counter of RFC 4226) of the time.
+func step(t time.Time, period time.Duration) int64 {
+	return t.Unix() / int64(period/time.Second)
+}
+
+// hotp returns the HOTP value of the counter (RFC 4226).
+func hotp(key []byte, counter int64, digits int) string {
+	mac := hmac.New(sha1.New, key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(counter)))
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1_000_000)
+	if digits == 8 {
+		mod = 100_000_000
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// validate returns the time step matching the code, within skew steps before or after the time.
+func validate(key []byte, code string, t time.Time, digits int, period time.Duration, skew int) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+
+	current := step(t, period)
+	for i := -skew; i <= skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, current+int64(i), digits)), []byte(code)) == 1 {
+			return current + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth:// URI of the secret, which authenticator apps import from a QR code.
+//
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func URI(issuer, account, secret string, digits int, period time.Duration) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(int64(period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package totp_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/bcrypt"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/totp"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// TestGenerateCode checks the test vectors of RFC 6238, Appendix B (SHA1, 8 digits, 30 seconds).
+func TestGenerateCode(t *testing.T) {
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := totp.GenerateCode(secret, time.Unix(tt.unix, 0), 8, 30*time.Second)
+		if err != nil || got != tt.want {
+			t.Errorf("GenerateCode(%d) = %q, %v, want %q", tt.unix, got, err, tt.want)
+		}
+	}
+
+	if _, err := totp.GenerateCode("not base32!", time.Now(), 6, 30*time.Second); !errors.Is(err, totp.ErrInvalidSecret) {
+		t.Errorf("GenerateCode() with an invalid secret error = %v, want %v", err, totp.ErrInvalidSecret)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Gopher Corp", "gopher@example.com", "JBSWY3DPEHPK3PXP", 6, 30*time.Second)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", uri, err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Gopher Corp:gopher@example.com" {
+		t.Errorf("URI() = %q, want otpauth://totp/Gopher%%20Corp:gopher@example.com", uri)
+	}
+	query := u.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Gopher Corp" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("URI() query = %v", query)
+	}
+}
+
+// newManager returns a manager over an in-process database, with a user.
+func newManager(t *testing.T, config totp.Config) (*totp.Manager, database.Service, database.User) {
+	t.Helper()
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	for _, create := range []func(context.Context, database.Service) error{
+		database.CreateUsersTable, totp.CreateTOTPTable, totp.CreateRecoveryCodesTable,
+	} {
+		if err := create(ctx, db); err != nil {
+			t.Fatalf("create table error = %v", err)
+		}
+	}
+	user, err := db.Auth().CreateUser(ctx, "gopher", "gopher@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	config.Issuer = "Gopher"
+	config.DB = db
+	config.Bcrypt, _ = bcrypt.New(4) // the minimum cost, to keep the tests fast
+	m, err := totp.New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return m, db, user
+}
+
+func TestManagerLifecycle(t *testing.T) {
+	m, db, user := newManager(t, totp.Config{EncryptionKey: make([]byte, 32)})
+	ctx := context.Background()
+
+	enrollment, err := m.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	if !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("Enroll() URI = %q, want the secret", enrollment.URI)
+	}
+
+	// The secret is encrypted in the database.
+	var stored string
+	db.QueryRow(ctx, "SELECT secret FROM user_totp WHERE user_id = ?", user.ID).Scan(&stored)
+	if stored == enrollment.Secret || !strings.HasPrefix(stored, "v1:") {
+		t.Errorf("stored secret = %q, want it encrypted", stored)
+	}
+
+	if err := m.Verify(ctx, user.ID, "000000"); !errors.Is(err, totp.ErrNotEnrolled) {
+		t.Errorf("Verify() before confirmation error = %v, want %v", err, totp.ErrNotEnrolled)
+	}
+
+	// A code of the previous period is still accepted (skew), and confirms the enrollment.
+	previous, _ := m.Code(enrollment.Secret, time.Now().Add(-30*time.Second))
+	codes, err := m.Confirm(ctx, user.ID, previous)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if len(codes) != 10 {
+		t.Errorf("Confirm() recovery codes = %d, want 10", len(codes))
+	}
+	if _, err := m.Enroll(ctx, user); !errors.Is(err, totp.ErrAlreadyEnrolled) {
+		t.Errorf("Enroll() when enabled error = %v, want %v", err, totp.ErrAlreadyEnrolled)
+	}
+
+	// The current code is accepted once, and so is nothing older than it.
+	current, _ := m.Code(enrollment.Secret, time.Now())
+	if err := m.Verify(ctx, user.ID, current); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := m.Verify(ctx, user.ID, current); !errors.Is(err, totp.ErrInvalidCode) {
+		t.Errorf("Verify() replayed error = %v, want %v", err, totp.ErrInvalidCode)
+	}
+	if err := m.Verify(ctx, user.ID, previous); !errors.Is(err, totp.ErrInvalidCode) {
+		t.Errorf("Verify() of an older step error = %v, want %v", err, totp.ErrInvalidCode)
+	}
+	tooOld, _ := m.Code(enrollment.Secret, time.Now().Add(-2*time.Minute))
+	if err := m.Verify(ctx, user.ID, tooOld); !errors.Is(err, totp.ErrInvalidCode) {
+		t.Errorf("Verify() outside the skew error = %v, want %v", err, totp.ErrInvalidCode)
+	}
+
+	// A recovery code is single-use, whatever the case and the dashes.
+	if err := m.UseRecoveryCode(ctx, user.ID, strings.ToUpper(codes[0])); err != nil {
+		t.Fatalf("UseRecoveryCode() error = %v", err)
+	}
+	if err := m.UseRecoveryCode(ctx, user.ID, codes[0]); !errors.Is(err, totp.ErrInvalidCode) {
+		t.Errorf("UseRecoveryCode() again error = %v, want %v", err, totp.ErrInvalidCode)
+	}
+	if n, err := m.RemainingRecoveryCodes(ctx, user.ID); err != nil || n != 9 {
+		t.Errorf("RemainingRecoveryCodes() = %d, %v, want 9", n, err)
+	}
+
+	if err := m.Disable(ctx, user.ID); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	if enabled, err := m.Enabled(ctx, user.ID); err != nil || enabled {
+		t.Errorf("Enabled() after Disable() = %v, %v, want false", enabled, err)
+	}
+	if err := m.UseRecoveryCode(ctx, user.ID, codes[1]); !errors.Is(err, totp.ErrInvalidCode) {
+		t.Errorf("UseRecoveryCode() after Disable() error = %v, want %v", err, totp.ErrInvalidCode)
+	}
+}
+
+func TestNew(t *testing.T) {
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		config  totp.Config
+		wantErr error
+	}{
+		{"missing issuer", totp.Config{DB: db}, totp.ErrMissingIssuer},
+		{"missing database", totp.Config{Issuer: "Gopher"}, totp.ErrMissingDatabase},
+		{"invalid digits", totp.Config{Issuer: "Gopher", DB: db, Digits: 7}, totp.ErrInvalidDigits},
+		{"invalid key", totp.Config{Issuer: "Gopher", DB: db, EncryptionKey: []byte("short")}, totp.ErrInvalidEncryptionKey},
+		{"valid", totp.Config{Issuer: "Gopher", DB: db}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := totp.New(tt.config); !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequireStepUp(t *testing.T) {
+	m, db, user := newManager(t, totp.Config{})
+	ctx := context.Background()
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(users.SessionContextKey, sess)
+		return c.Next()
+	})
+	app.Post("/login", users.Login(db))
+	m.Mount(app.Group("/2fa"), users.RequireUser(db), nil)
+	app.Delete("/account", users.RequireUser(db), m.RequireStepUp(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	var sessionID string
+	do := func(method, path, body string, header ...string) int {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		if sessionID != "" {
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == "session_id" {
+				sessionID = cookie.Value
+			}
+		}
+		return resp.StatusCode
+	}
+
+	if status := do(fiber.MethodPost, "/login", `{"login":"gopher","password":"correct horse"}`); status != fiber.StatusOK {
+		t.Fatalf("login status = %d", status)
+	}
+	if status := do(fiber.MethodDelete, "/account", ""); status != fiber.StatusForbidden {
+		t.Errorf("step-up without TOTP status = %d, want %d", status, fiber.StatusForbidden)
+	}
+
+	enrollment, err := m.Enroll(ctx, user)
+	if err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	code, _ := m.Code(enrollment.Secret, time.Now().Add(-30*time.Second))
+	codes, err := m.Confirm(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+
+	if status := do(fiber.MethodDelete, "/account", ""); status != fiber.StatusForbidden {
+		t.Errorf("step-up before verification status = %d, want %d", status, fiber.StatusForbidden)
+	}
+	if status := do(fiber.MethodDelete, "/account", "", totp.HeaderCode, "123456"); status != fiber.StatusUnauthorized {
+		t.Errorf("step-up with a wrong code status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+	if status := do(fiber.MethodPost, "/2fa/verify", `{"recovery_code":"`+codes[0]+`"}`); status != fiber.StatusNoContent {
+		t.Fatalf("verify with a recovery code status = %d, want %d", status, fiber.StatusNoContent)
+	}
+	if status := do(fiber.MethodDelete, "/account", ""); status != fiber.StatusNoContent {
+		t.Errorf("step-up after verification status = %d, want %d", status, fiber.StatusNoContent)
+	}
+	if status := do(fiber.MethodPost, "/2fa/verify", `{"recovery_code":"`+codes[0]+`"}`); status != fiber.StatusUnauthorized {
+		t.Errorf("verify with a used recovery code status = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package authorization_test
+
+import (
+	"context"
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
+	"io"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// testPolicy is the policy of the tests, in YAML.
+const testPolicy = `
+roles:
+  - name: viewer
+    rules:
+      - permissions: ["invoices:read"]
+        conditions: {tenant: true}
+  - name: accountant
+    inherits: [viewer]
+    rules:
+      - permissions: ["invoices:*"]
+        conditions: {owner: true}
+      - effect: deny
+        permissions: ["invoices:delete"]
+  - name: night-shift
+    rules:
+      - permissions: ["reports:run"]
+        conditions:
+          time_window: {days: [fri], start: "22:00", end: "06:00"}
+  - name: admin
+    rules:
+      - permissions: ["*"]
+        conditions: {ip_ranges: ["10.0.0.0/8", "192.0.2.1"]}
+bindings:
+  - {subject: alice, roles: [accountant], tenant: acme}
+  - {subject: bob, roles: [viewer], tenant: globex}
+  - {subject: carol, roles: [night-shift]}
+  - {subject: root, roles: [admin]}
+`
+
+// newAuthorizer returns an Authorizer of the test policy.
+func newAuthorizer(t *testing.T, config authorization.Config) *authorization.Authorizer {
+	t.Helper()
+	policy, err := authorization.ParsePolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+	config.Loader = authorization.Static(policy)
+	authz, err := authorization.New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return authz
+}
+
+func TestDecide(t *testing.T) {
+	authz := newAuthorizer(t, authorization.Config{})
+	invoice := authorization.Resource{Type: "invoice", ID: "1", Owner: "alice", Tenant: "acme"}
+	// 2025-01-03 is a Friday.
+	friday := time.Date(2025, 1, 3, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		request authorization.Request
+		want    bool
+		role    string
+	}{
+		{"own invoice", authorization.Request{Subject: authorization.Subject{ID: "alice"}, Permission: "invoices:update", Resource: invoice}, true, "accountant"},
+		{"not the owner", authorization.Request{Subject: authorization.Subject{ID: "alice"}, Permission: "invoices:update", Resource: authorization.Resource{Owner: "bob", Tenant: "acme"}}, false, ""},
+		{"inherited tenant rule", authorization.Request{Subject: authorization.Subject{ID: "alice"}, Permission: "invoices:read", Resource: authorization.Resource{Owner: "bob", Tenant: "acme"}}, true, "viewer"},
+		{"deny overrides allow", authorization.Request{Subject: authorization.Subject{ID: "alice"}, Permission: "invoices:delete", Resource: invoice}, false, "accountant"},
+		{"other tenant", authorization.Request{Subject: authorization.Subject{ID: "bob"}, Permission: "invoices:read", Resource: invoice}, false, ""},
+		{"unbound subject", authorization.Request{Subject: authorization.Subject{ID: "mallory"}, Permission: "invoices:read", Resource: invoice}, false, ""},
+		{"roles of the subject", authorization.Request{Subject: authorization.Subject{ID: "svc", Roles: []string{"viewer"}, Tenant: "acme"}, Permission: "invoices:read", Resource: invoice}, true, "viewer"},
+		{"unknown role", authorization.Request{Subject: authorization.Subject{ID: "svc", Roles: []string{"ghost"}}, Permission: "invoices:read", Resource: invoice}, false, ""},
+		{"IP in range", authorization.Request{Subject: authorization.Subject{ID: "root"}, Permission: "users:delete", IP: netip.MustParseAddr("10.1.2.3")}, true, "admin"},
+		{"IPv4-mapped IP", authorization.Request{Subject: authorization.Subject{ID: "root"}, Permission: "users:delete", IP: netip.MustParseAddr("::ffff:192.0.2.1")}, true, "admin"},
+		{"IP out of range", authorization.Request{Subject: authorization.Subject{ID: "root"}, Permission: "users:delete", IP: netip.MustParseAddr("203.0.113.1")}, false, ""},
+		{"no IP", authorization.Request{Subject: authorization.Subject{ID: "root"}, Permission: "users:delete"}, false, ""},
+		{"within the window", authorization.Request{Subject: authorization.Subject{ID: "carol"}, Permission: "reports:run", Time: friday}, true, "night-shift"},
+		{"after midnight", authorization.Request{Subject: authorization.Subject{ID: "carol"}, Permission: "reports:run", Time: friday.Add(6 * time.Hour)}, true, "night-shift"},
+		{"end of the window", authorization.Request{Subject: authorization.Subject{ID: "carol"}, Permission: "reports:run", Time: friday.Add(7 * time.Hour)}, false, ""},
+		{"before the window", authorization.Request{Subject: authorization.Subject{ID: "carol"}, Permission: "reports:run", Time: friday.Add(-2 * time.Hour)}, false, ""},
+		{"other day", authorization.Request{Subject: authorization.Subject{ID: "carol"}, Permission: "reports:run", Time: friday.Add(24 * time.Hour)}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := authz.Decide(tt.request)
+			if got.Allowed != tt.want || got.Role != tt.role {
+				t.Errorf("Decide() = %+v, want allowed %v by role %q", got, tt.want, tt.role)
+			}
+		})
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   error
+	}{
+		{"unknown inherited role", `roles: [{name: a, inherits: [b], rules: []}]`, authorization.ErrUnknownRole},
+		{"unknown bound role", `{roles: [{name: a}], bindings: [{subject: s, roles: [b]}]}`, authorization.ErrUnknownRole},
+		{"inheritance cycle", `roles: [{name: a, inherits: [b]}, {name: b, inherits: [a]}]`, authorization.ErrRoleCycle},
+		{"duplicate role", `roles: [{name: a}, {name: a}]`, authorization.ErrInvalidPolicy},
+		{"unknown effect", `roles: [{name: a, rules: [{effect: maybe, permissions: ["*"]}]}]`, authorization.ErrInvalidPolicy},
+		{"no permission", `roles: [{name: a, rules: [{}]}]`, authorization.ErrInvalidPolicy},
+		{"invalid IP range", `roles: [{name: a, rules: [{permissions: ["*"], conditions: {ip_ranges: ["10.0.0.0/33"]}}]}]`, authorization.ErrInvalidPolicy},
+		{"invalid time", `roles: [{name: a, rules: [{permissions: ["*"], conditions: {time_window: {start: "9am", end: "17:00"}}}]}]`, authorization.ErrInvalidPolicy},
+		{"invalid day", `roles: [{name: a, rules: [{permissions: ["*"], conditions: {time_window: {days: [someday], start: "09:00", end: "17:00"}}}]}]`, authorization.ErrInvalidPolicy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := authorization.ParsePolicy([]byte(tt.policy))
+			if err != nil {
+				t.Fatalf("ParsePolicy() error = %v", err)
+			}
+			if _, err := authorization.New(authorization.Config{Loader: authorization.Static(policy)}); !errors.Is(err, tt.want) {
+				t.Errorf("New() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := authorization.New(authorization.Config{}); !errors.Is(err, authorization.ErrMissingLoader) {
+		t.Errorf("New() without a loader error = %v, want %v", err, authorization.ErrMissingLoader)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	dir := t.TempDir()
+	files := map[string]string{
+		"effect.yaml":     `roles: [{name: a, rules: [{efect: deny, permissions: ["*"]}]}]`,
+		"conditions.yaml": `roles: [{name: a, rules: [{permissions: ["*"], condition: {owner: true}}]}]`,
+		"condition.yaml":  `roles: [{name: a, rules: [{permissions: ["*"], conditions: {ownr: true}}]}]`,
+		"effect.json":     `{"roles": [{"name": "a", "rules": [{"Efect": "deny", "permissions": ["*"]}]}]}`,
+		"conditions.json": `{"roles": [{"name": "a", "rules": [{"permissions": ["*"], "conditons": {"owner": true}}]}]}`,
+	}
+
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	authorization.CreateRolesTable(ctx, db)
+	authorization.CreateBindingsTable(ctx, db)
+	query := `INSERT INTO authorization_roles (name, inherits, rules) VALUES ('a', '', '[{"permissions": ["*"], "efect": "deny"}]')`
+	if err := db.ExecWithoutRow(ctx, query); err != nil {
+		t.Fatalf("ExecWithoutRow() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		loader authorization.Loader
+	}{
+		{"database", authorization.FromDatabase(db)},
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(data), 0o600)
+		tests = append(tests, struct {
+			name   string
+			loader authorization.Loader
+		}{name, authorization.FromFile(path)})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A misspelled key must fail the load, instead of turning a deny into an allow or dropping a condition.
+			if _, err := authorization.New(authorization.Config{Loader: tt.loader}); !errors.Is(err, authorization.ErrInvalidPolicy) {
+				t.Errorf("New() error = %v, want %v", err, authorization.ErrInvalidPolicy)
+			}
+		})
+	}
+}
+
+func TestLoaders(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(yamlFile, []byte(testPolicy), 0o600)
+	jsonFile := filepath.Join(dir, "policy.json")
+	os.WriteFile(jsonFile, []byte(`{"roles": [{"name": "viewer", "rules": [{"permissions": ["invoices:read"]}]}],
+		"bindings": [{"subject": "alice", "roles": ["viewer"]}]}`), 0o600)
+
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	for _, create := range []func(context.Context, database.Service) error{authorization.CreateRolesTable, authorization.CreateBindingsTable} {
+		if err := create(ctx, db); err != nil {
+			t.Fatalf("create table error = %v", err)
+		}
+	}
+	for _, query := range []string{
+		`INSERT INTO authorization_roles (name, inherits, rules) VALUES ('reader', '', '[{"permissions": ["invoices:read"]}]')`,
+		`INSERT INTO authorization_roles (name, inherits, rules) VALUES ('viewer', 'reader', '[]')`,
+		`INSERT INTO authorization_bindings (subject, role, tenant) VALUES ('alice', 'viewer', 'acme')`,
+	} {
+		if err := db.ExecWithoutRow(ctx, query); err != nil {
+			t.Fatalf("ExecWithoutRow() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		loader authorization.Loader
+	}{
+		{"YAML file", authorization.FromFile(yamlFile)},
+		{"JSON file", authorization.FromFile(jsonFile)},
+		{"database", authorization.FromDatabase(db)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authz, err := authorization.New(authorization.Config{Loader: tt.loader})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			// Note: The tenant condition of the YAML policy holds, since alice is bound to the tenant of the resource.
+			request := authorization.Request{
+				Subject:    authorization.Subject{ID: "alice"},
+				Permission: "invoices:read",
+				Resource:   authorization.Resource{Tenant: "acme"},
+			}
+			if got := authz.Decide(request); !got.Allowed {
+				t.Errorf("Decide() = %+v, want allowed", got)
+			}
+		})
+	}
+
+	if _, err := authorization.New(authorization.Config{Loader: authorization.FromFile(filepath.Join(dir, "missing.yaml"))}); err == nil {
+		t.Error("New() of a missing file error = nil, want an error")
+	}
+}
+
+func TestDecisionCache(t *testing.T) {
+	authz := newAuthorizer(t, authorization.Config{})
+	request := authorization.Request{Subject: authorization.Subject{ID: "mallory"}, Permission: "invoices:read"}
+	if got := authz.Decide(request); got.Allowed {
+		t.Fatalf("Decide() = %+v, want denied", got)
+	}
+
+	// The cached decisions are dropped with the policy.
+	if err := authz.SetPolicy(authorization.Policy{
+		Roles:    []authorization.Role{{Name: "reader", Rules: []authorization.Rule{{Permissions: []string{"invoices:read"}}}}},
+		Bindings: []authorization.Binding{{Subject: "mallory", Roles: []string{"reader"}}},
+	}); err != nil {
+		t.Fatalf("SetPolicy() error = %v", err)
+	}
+	if got := authz.Decide(request); !got.Allowed {
+		t.Errorf("Decide() after SetPolicy = %+v, want allowed", got)
+	}
+
+	// An invalid policy keeps the previous one.
+	if err := authz.SetPolicy(authorization.Policy{Bindings: []authorization.Binding{{Subject: "mallory", Roles: []string{"ghost"}}}}); err == nil {
+		t.Error("SetPolicy() of an invalid policy error = nil, want an error")
+	}
+	if got := authz.Decide(request); !got.Allowed {
+		t.Errorf("Decide() after an invalid SetPolicy = %+v, want allowed", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	authz := newAuthorizer(t, authorization.Config{
+		// The subject is given by a header in the tests, like an authentication middleware would.
+		Subject: func(c *fiber.Ctx) (authorization.Subject, bool) {
+			id := c.Get("X-Subject")
+			return authorization.Subject{ID: id}, id != ""
+		},
+	})
+	loadInvoice := func(c *fiber.Ctx) (authorization.Resource, error) {
+		switch c.Params("id") {
+		case "1":
+			return authorization.Resource{Type: "invoice", ID: "1", Owner: "alice", Tenant: "acme"}, nil
+		case "broken":
+			return authorization.Resource{}, errors.New("database is down")
+		default:
+			return authorization.Resource{}, fiber.ErrNotFound
+		}
+	}
+
+	app := fiber.New()
+	app.Put("/invoices/:id", authz.Require("invoices:update", loadInvoice), func(c *fiber.Ctx) error {
+		subject, _ := authorization.SubjectFromContext(c)
+		return c.SendString(subject.ID)
+	})
+
+	tests := []struct {
+		name    string
+		subject string
+		id      string
+		want    int
+	}{
+		{"owner", "alice", "1", fiber.StatusOK},
+		{"not the owner", "bob", "1", fiber.StatusForbidden},
+		{"no subject", "", "1", fiber.StatusUnauthorized},
+		{"unknown resource", "alice", "2", fiber.StatusNotFound},
+		{"resource error", "alice", "broken", fiber.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPut, "/invoices/"+tt.id, nil)
+			if tt.subject != "" {
+				req.Header.Set("X-Subject", tt.subject)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("app.Test() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultSubjectOfClientCertificate(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		// Note: This is what setupTLS.NewClientAuth stores for a client certificate mapped by its subject common name.
+		c.Locals("mtls_principal", setupTLS.Principal{Name: "alice", Mode: setupTLS.MapSubjectCN})
+		subject, ok := authorization.DefaultSubject(c)
+		if !ok {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+		return c.SendString(subject.ID)
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("app.Test() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	// The certificate doesn't share the namespace of the users, so it doesn't get the roles of the user "alice".
+	if got, want := string(body), "mtls:cn:alice"; got != want {
+		t.Errorf("DefaultSubject().ID = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package authorization
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrMissingLoader is returned by New when Config.Loader is nil.
+var ErrMissingLoader = errors.New("authorization: policy loader is required")
+
+// Config defines the config for the Authorizer.
+type Config struct {
+	// Loader loads the policy (see FromFile, FromDatabase and Static).
+	//
+	// Required.
+	Loader Loader
+
+	// Subject returns the authenticated subject of a request, or false when there is none.
+	//
+	// Optional. Default: DefaultSubject
+	Subject func(c *fiber.Ctx) (Subject, bool)
+
+	// CacheTTL is how long a decision is cached. A negative value disables the cache.
+	//
+	// Optional. Default: 1 * time.Minute
+	//
+	// Note: The decisions of the rules with a time window aren't cached, and the cache is cleared when the policy changes.
+	CacheTTL time.Duration
+
+	// CacheSize is the maximum number of cached decisions.
+	//
+	// Optional. Default: 10000
+	CacheSize int
+
+	// RefreshInterval is the interval of the reloads of the policy by Start.
+	//
+	// Optional. Default: 0 (no background reload)
+	RefreshInterval time.Duration
+}
+
+// Subject is the authenticated subject of a request (e.g., a user, the owner of an API key, or a client certificate).
+type Subject struct {
+	// ID is the ID of the subject, which the bindings refer to, and which owns the resources.
+	ID string
+
+	// Roles are roles the subject has besides its bindings (e.g., from its API key).
+	Roles []string
+
+	// Tenant is the tenant of the subject, when it isn't bound by the policy.
+	Tenant string
+}
+
+// Resource is the resource a permission is checked on. The zero value is a resource without attributes,
+// which never satisfies the Owner or Tenant conditions.
+type Resource struct {
+	Type   string
+	ID     string
+	Owner  string
+	Tenant string
+}
+
+// Request is an authorization request.
+type Request struct {
+	Subject    Subject
+	Permission string
+	Resource   Resource
+
+	// IP is the client IP, for the IPRanges conditions.
+	IP netip.Addr
+
+	// Time is the time of the request, for the TimeWindow conditions.
+	//
+	// Optional. Default: time.Now()
+	Time time.Time
+}
+
+// Decision is the result of an authorization request.
+type Decision struct {
+	Allowed bool
+
+	// Role is the role whose rule allowed (or denied) the request, or "" when no rule matched.
+	Role string
+
+	// Reason tells why the request was allowed or denied, for the audit log.
+	Reason string
+}
+
+// Authorizer decides whether the subjects have the permissions, according to a policy of roles (RBAC)
+// and attribute conditions (ABAC).
+type Authorizer struct {
+	cfg    Config
+	policy atomic.Pointer[compiledPolicy]
+
+	mu    sync.Mutex
+	cache map[string]cachedDecision
+
+	started atomic.Bool
+	once    sync.Once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// cachedDecision is a decision in the cache.
+type cachedDecision struct {
+	decision Decision
+	expires  time.Time
+}
+
+// New creates an Authorizer, loading its policy.
+func New(config Config) (*Authorizer, error) {
+	if config.Loader == nil {
+		return nil, ErrMissingLoader
+	}
+	if config.Subject == nil {
+		config.Subject = DefaultSubject
+	}
+	config.CacheTTL = cmp.Or(config.CacheTTL, time.Minute)
+	config.CacheSize = cmp.Or(config.CacheSize, 10000)
+
+	a := &Authorizer{
+		cfg:   config,
+		cache: make(map[string]cachedDecision),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := a.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload loads the policy again. On error, the previous policy is kept.
+func (a *Authorizer) Reload(ctx context.Context) error {
+	policy, err := a.cfg.Loader(ctx)
+	if err != nil {
+		return err
+	}
+	return a.SetPolicy(policy)
+}
+
+// SetPolicy replaces the policy, and clears the cached decisions. On error, the previous policy is kept.
+func (a *Authorizer) SetPolicy(policy Policy) error {
+	compiled, err := policy.compile()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policy.Store(compiled)
+	clear(a.cache)
+	return nil
+}
+
+// Decide decides whether the subject has the permission on the resource.
+//
+// The roles of the subject are its own roles and the roles of its binding. A request is allowed when a rule of these
+// roles (or of the roles they inherit) allows the permission with all its conditions holding, and no such rule denies it.
+// Unknown roles are ignored.
+func (a *Authorizer) Decide(request Request) Decision {
+	if request.Time.IsZero() {
+		request.Time = time.Now()
+	}
+	policy := a.policy.Load()
+	binding := policy.bindings[request.Subject.ID]
+	if request.Subject.Tenant == "" {
+		request.Subject.Tenant = binding.Tenant
+	}
+	roles := slices.Concat(request.Subject.Roles, binding.Roles)
+
+	key := cacheKey(request, roles)
+	if decision, ok := a.cached(key, policy); ok {
+		return decision
+	}
+
+	decision, timed := decide(policy, request, roles)
+	if !timed {
+		a.store(key, policy, decision)
+	}
+	return decision
+}
+
+// decide evaluates the rules of the roles. It also reports whether a matching rule had a time window,
+// in which case the decision can't be cached.
+func decide(policy *compiledPolicy, request Request, roles []string) (decision Decision, timed bool) {
+	decision.Reason = fmt.Sprintf("no role grants %q", request.Permission)
+	for _, role := range roles {
+		for _, rule := range policy.roles[role] {
+			if !slices.ContainsFunc(rule.permissions, func(pattern string) bool {
+				return matchPermission(pattern, request.Permission)
+			}) {
+				continue
+			}
+			timed = timed || rule.window != nil
+			if !rule.holds(request) {
+				continue
+			}
+			if rule.deny {
+				// Note: A deny overrides any allow, so there is no need to look further.
+				return Decision{Role: rule.role, Reason: fmt.Sprintf("%q denied by role %q", request.Permission, rule.role)}, timed
+			}
+			if !decision.Allowed {
+				decision = Decision{Allowed: true, Role: rule.role, Reason: fmt.Sprintf("%q granted by role %q", request.Permission, rule.role)}
+			}
+		}
+	}
+	return decision, timed
+}
+
+// holds reports whether the conditions of the rule hold for the request.
+func (r compiledRule) holds(request Request) bool {
+	if r.owner && (request.Subject.ID == "" || request.Resource.Owner != request.Subject.ID) {
+		return false
+	}
+	if r.tenant && (request.Subject.Tenant == "" || request.Resource.Tenant != request.Subject.Tenant) {
+		return false
+	}
+	if len(r.ipRanges) > 0 {
+		ip := request.IP.Unmap()
+		if !ip.IsValid() || !slices.ContainsFunc(r.ipRanges, func(prefix netip.Prefix) bool { return prefix.Contains(ip) }) {
+			return false
+		}
+	}
+	return r.window == nil || r.window.contains(request.Time)
+}
+
+// cacheKey returns the key of the decision of the request in the cache.
+func cacheKey(request Request, roles []string) string {
+	return strings.Join([]string{
+		request.Subject.ID, strings.Join(roles, ","), request.Subject.Tenant, request.Permission,
+		request.Resource.Type, request.Resource.ID, request.Resource.Owner, request.Resource.Tenant, request.IP.String(),
+	}, "\x00")
+}
+
+// cached returns the cached decision of the key, if it was made with the policy and isn't expired.
+func (a *Authorizer) cached(key string, policy *compiledPolicy) (Decision, bool) {
+	if a.cfg.CacheTTL < 0 {
+		return Decision{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.cache[key]
+	if !ok || a.policy.Load() != policy || time.Now().After(entry.expires) {
+		return Decision{}, false
+	}
+	return entry.decision, true
+}
+
+// store caches the decision of the key, unless the policy changed meanwhile.
+func (a *Authorizer) store(key string, policy *compiledPolicy, decision Decision) {
+	if a.cfg.CacheTTL < 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.policy.Load() != policy {
+		return
+	}
+	now := time.Now()
+	if len(a.cache) >= a.cfg.CacheSize {
+		maps.DeleteFunc(a.cache, func(_ string, entry cachedDecision) bool { return now.After(entry.expires) })
+		if len(a.cache) >= a.cfg.CacheSize {
+			// Note: The cache is only a shortcut, so dropping it is cheaper than tracking the least recently used decisions.
+			clear(a.cache)
+		}
+	}
+	a.cache[key] = cachedDecision{decision: decision, expires: now.Add(a.cfg.CacheTTL)}
+}
+
+// Start reloads the policy every Config.RefreshInterval in the background, until Close.
+// It does nothing without a RefreshInterval.
+func (a *Authorizer) Start() {
+	if a.cfg.RefreshInterval <= 0 || !a.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(a.done)
+		ticker := time.NewTicker(a.cfg.RefreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				if err := a.Reload(context.Background()); err != nil {
+					log.LogErrorf("Failed to reload the authorization policy, keeping the previous one: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the background reload started by Start.
+func (a *Authorizer) Close() {
+	a.once.Do(func() { close(a.stop) })
+	if a.started.Load() {
+		<-a.done
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package authorization provides a policy-based authorization layer, on top of the authentication middlewares
+// (e.g., keyauth, the user sessions of OAuth2, or the client certificates), so the handlers don't check permissions by hand.
+//
+// A Policy has roles (RBAC), whose rules allow or deny permissions like "invoices:read", optionally under attribute
+// conditions (ABAC): the subject owns the resource, the resource belongs to the tenant of the subject, the client IP is in
+// a range, or the request is made within a time window. A role can inherit the rules of other roles, and a deny overrides
+// any allow. The subjects are bound to roles by the bindings of the policy.
+//
+// The policy is loaded from a YAML or JSON file (see FromFile), the database (see FromDatabase), or code (see Static),
+// and can be reloaded in the background (see Config.RefreshInterval). The decisions are cached for Config.CacheTTL.
+//
+// Example policy (YAML):
+//
+//	roles:
+//	  - name: viewer
+//	    rules:
+//	      - permissions: ["invoices:read"]
+//	        conditions: {tenant: true}
+//	  - name: accountant
+//	    inherits: [viewer]
+//	    rules:
+//	      - permissions: ["invoices:*"]
+//	        conditions:
+//	          owner: true
+//	          time_window: {days: [mon, tue, wed, thu, fri], start: "08:00", end: "18:00", location: Asia/Jakarta}
+//	      - effect: deny
+//	        permissions: ["invoices:delete"]
+//	        conditions: {ip_ranges: ["0.0.0.0/0", "::/0"]}
+//	  - name: admin
+//	    rules:
+//	      - permissions: ["*"]
+//	        conditions: {ip_ranges: ["10.0.0.0/8"]}
+//	bindings:
+//	  - {subject: "user-1", roles: [accountant], tenant: "acme"}
+//	  - {subject: "mtls:spiffe://example.org/ns/billing/sa/worker", roles: [accountant], tenant: "acme"}
+//
+// Example Usage:
+//
+//	authz, err := authorization.New(authorization.Config{
+//		Loader:          authorization.FromDatabase(db),
+//		RefreshInterval: time.Minute,
+//	})
+//	if err != nil {
+//		// Handle error
+//	}
+//	authz.Start()
+//	defer authz.Close()
+//
+//	loadInvoice := func(c *fiber.Ctx) (authorization.Resource, error) {
+//		invoice, err := invoices.Get(c.UserContext(), c.Params("id"))
+//		if err != nil {
+//			return authorization.Resource{}, fiber.ErrNotFound
+//		}
+//		return authorization.Resource{Type: "invoice", ID: invoice.ID, Owner: invoice.UserID, Tenant: invoice.Tenant}, nil
+//	}
+//
+//	middleware.APIRoute{
+//		Path:          "/invoices/:id",
+//		Method:        fiber.MethodDelete,
+//		Handler:       deleteInvoice,
+//		KeyAuth:       keyAuth,
+//		Authorization: authz.Require("invoices:delete", loadInvoice),
+//	}
+//
+// The denials are logged in the audit log with LogUserActivity, with the subject, the resource and the reason.
+//
+// Note: The subject is found by DefaultSubject, unless Config.Subject is set (e.g., to give roles to the scopes of
+// the API keys). Make sure the authentication middleware runs before Require, which answers 401 Unauthorized without a subject.
+package authorization
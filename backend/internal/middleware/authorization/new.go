@@ -0,0 +1,113 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package authorization
+
+import (
+	"errors"
+	"fmt"
+	log "h0llyw00dz-template/backend/internal/logger"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"net/netip"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// subjectContextKey is the key of the authorized Subject in the context.
+const subjectContextKey = "authorization_subject"
+
+// ResourceFunc returns the resource of a request (e.g., a document loaded by the ID in the path),
+// for the Owner and Tenant conditions. A *fiber.Error is answered with its code and message.
+type ResourceFunc func(c *fiber.Ctx) (Resource, error)
+
+// Require is a middleware that only lets through the subjects having the permission, on the resource of the request
+// when a ResourceFunc is given. It answers 401 Unauthorized without a subject, and 403 Forbidden when the permission
+// is denied, which is logged in the audit log.
+//
+// It's meant for the Authorization field of the routes (see APIRoute and APIGroup), after the authentication.
+//
+// Example Usage:
+//
+//	{
+//	    Path:          "/invoices/:id",
+//	    Method:        fiber.MethodGet,
+//	    Handler:       getInvoice,
+//	    KeyAuth:       keyAuth,
+//	    Authorization: authz.Require("invoices:read", loadInvoice),
+//	}
+func (a *Authorizer) Require(permission string, resource ...ResourceFunc) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		subject, ok := a.cfg.Subject(c)
+		if !ok {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		var target Resource
+		if len(resource) > 0 {
+			var err error
+			if target, err = resource[0](c); err != nil {
+				var e *fiber.Error
+				if errors.As(err, &e) {
+					return helper.SendProblemResponse(c, e.Code, e.Message)
+				}
+				log.LogErrorf("Failed to load the resource of %q: %v", permission, err)
+				return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+			}
+		}
+
+		// Note: An unparsable IP is the zero netip.Addr, which never satisfies the IPRanges conditions.
+		ip, _ := netip.ParseAddr(c.IP())
+		decision := a.Decide(Request{
+			Subject:    subject,
+			Permission: permission,
+			Resource:   target,
+			IP:         ip,
+		})
+		if !decision.Allowed {
+			log.LogUserActivity(c, fmt.Sprintf("Authorization denied for %q on %s: %s", subject.ID, describe(target), decision.Reason))
+			return helper.SendProblemResponse(c, fiber.StatusForbidden, "Permission denied")
+		}
+
+		c.Locals(subjectContextKey, subject)
+		return c.Next()
+	}
+}
+
+// describe returns the resource as "type/id" for the audit log.
+func describe(resource Resource) string {
+	if resource.Type == "" && resource.ID == "" {
+		return "the route"
+	}
+	return resource.Type + "/" + resource.ID
+}
+
+// MTLSSubjectPrefix prefixes the IDs of the subjects authenticated by a client certificate (e.g., "mtls:cn:admin"
+// or "mtls:spiffe://example.org/ns/billing/sa/worker"), so a certificate can't be bound to the roles of a user of the same ID.
+const MTLSSubjectPrefix = "mtls:"
+
+// DefaultSubject returns the subject authenticated by the user sessions (see users.RequireUser), an API key
+// (its owner, see keyauth.APIKeyFromContext), or a client certificate (its principal prefixed by [MTLSSubjectPrefix],
+// see setupTLS.PrincipalFromContext), in that order.
+func DefaultSubject(c *fiber.Ctx) (Subject, bool) {
+	if user, ok := users.UserFromContext(c); ok {
+		return Subject{ID: user.ID}, true
+	}
+	if key, ok := keyauth.APIKeyFromContext(c); ok && key.Owner != "" {
+		return Subject{ID: key.Owner}, true
+	}
+	if principal, ok := setupTLS.PrincipalFromContext(c); ok {
+		return Subject{ID: MTLSSubjectPrefix + principal.String()}, true
+	}
+	return Subject{}, false
+}
+
+// SubjectFromContext returns the subject authorized by Require.
+func SubjectFromContext(c *fiber.Ctx) (Subject, bool) {
+	subject, ok := c.Locals(subjectContextKey).(Subject)
+	return subject, ok
+}
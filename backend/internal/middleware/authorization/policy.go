@@ -0,0 +1,379 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package authorization
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownRole is returned when a role inherits from, or a binding refers to, a role that isn't defined.
+	ErrUnknownRole = errors.New("authorization: unknown role")
+
+	// ErrRoleCycle is returned when roles inherit from each other.
+	ErrRoleCycle = errors.New("authorization: role inheritance cycle")
+
+	// ErrInvalidPolicy is returned when a policy can't be parsed, or has an invalid rule.
+	ErrInvalidPolicy = errors.New("authorization: invalid policy")
+)
+
+// Effects of the rules.
+const (
+	// EffectAllow grants the permissions of the rule.
+	EffectAllow = "allow"
+
+	// EffectDeny denies the permissions of the rule, whatever the other rules allow.
+	EffectDeny = "deny"
+)
+
+// Policy is a set of roles, and the bindings of the subjects to them.
+type Policy struct {
+	// Roles are the roles, with their rules.
+	Roles []Role `json:"roles" yaml:"roles"`
+
+	// Bindings bind the subjects to roles.
+	Bindings []Binding `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+}
+
+// Role is a named set of rules, which can inherit the rules of other roles.
+type Role struct {
+	// Name is the name of the role (e.g., "billing-admin").
+	Name string `json:"name" yaml:"name"`
+
+	// Inherits are the roles whose rules are also the rules of this role (e.g., "admin" inherits "editor").
+	Inherits []string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+
+	// Rules are the rules of the role.
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule allows (or denies) permissions, when its conditions hold.
+type Rule struct {
+	// Effect is EffectAllow or EffectDeny. A deny overrides any allow.
+	//
+	// Optional. Default: EffectAllow
+	Effect string `json:"effect,omitempty" yaml:"effect,omitempty"`
+
+	// Permissions are the permissions of the rule, like "invoices:read", "invoices:*" (every "invoices:" permission) or "*".
+	Permissions []string `json:"permissions" yaml:"permissions"`
+
+	// Conditions are the attribute conditions of the rule, which must all hold.
+	Conditions Conditions `json:"conditions,omitzero" yaml:"conditions,omitempty"`
+}
+
+// Conditions are the attribute conditions of a rule (ABAC). The zero value always holds.
+type Conditions struct {
+	// Owner requires the subject to own the resource.
+	Owner bool `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// Tenant requires the resource to belong to the tenant of the subject.
+	Tenant bool `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+
+	// IPRanges requires the client IP to be in one of the ranges, which are CIDRs or IPs (e.g., "10.0.0.0/8").
+	IPRanges []string `json:"ip_ranges,omitempty" yaml:"ip_ranges,omitempty"`
+
+	// TimeWindow requires the request to be made within the time window.
+	TimeWindow *TimeWindow `json:"time_window,omitempty" yaml:"time_window,omitempty"`
+}
+
+// TimeWindow is a daily time window, such as the office hours.
+type TimeWindow struct {
+	// Days are the days of the window, as "mon", "tue", "wed", "thu", "fri", "sat" or "sun".
+	//
+	// Optional. Default: every day
+	Days []string `json:"days,omitempty" yaml:"days,omitempty"`
+
+	// Start is the start of the window, as "15:04". A Start after End is a window over midnight (e.g., "22:00" to "06:00"),
+	// which belongs to the day it starts.
+	Start string `json:"start" yaml:"start"`
+
+	// End is the end of the window, as "15:04", excluded.
+	End string `json:"end" yaml:"end"`
+
+	// Location is the time zone of the window (e.g., "Asia/Jakarta").
+	//
+	// Optional. Default: "UTC"
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// Binding binds a subject to roles.
+type Binding struct {
+	// Subject is the ID of the subject (e.g., the ID of a user, see Subject.ID).
+	Subject string `json:"subject" yaml:"subject"`
+
+	// Roles are the roles of the subject.
+	Roles []string `json:"roles" yaml:"roles"`
+
+	// Tenant is the tenant of the subject, for the Tenant conditions.
+	//
+	// Optional.
+	Tenant string `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+}
+
+// ParsePolicy parses a policy in JSON or YAML (which JSON is a subset of).
+func ParsePolicy(data []byte) (Policy, error) {
+	var policy Policy
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return Policy{}, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	return policy, nil
+}
+
+// LoadFile loads a policy from a JSON (".json") or YAML file.
+func LoadFile(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("authorization: failed to load policy: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var policy Policy
+		if err := decodeJSON(data, &policy); err != nil {
+			return Policy{}, fmt.Errorf("%w: %s: %v", ErrInvalidPolicy, path, err)
+		}
+		return policy, nil
+	}
+	return ParsePolicy(data)
+}
+
+// decodeJSON decodes the JSON data into v, rejecting the unknown fields.
+//
+// Note: The policies are decoded strictly, since a misspelled field (e.g., "efect": "deny") would be silently dropped,
+// turning a deny into an allow, or a condition into none, which widens the access instead of failing.
+func decodeJSON(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("unexpected data after the policy")
+	}
+	return nil
+}
+
+// compiledPolicy is a validated policy, with the inherited rules flattened into each role.
+type compiledPolicy struct {
+	roles    map[string][]compiledRule
+	bindings map[string]Binding
+}
+
+// compiledRule is a validated rule.
+type compiledRule struct {
+	role        string // the role that defines the rule, which may be inherited
+	deny        bool
+	permissions []string
+	owner       bool
+	tenant      bool
+	ipRanges    []netip.Prefix
+	window      *compiledWindow
+}
+
+// compiledWindow is a validated time window.
+type compiledWindow struct {
+	days       [7]bool // by time.Weekday; all false means every day
+	start, end int     // minutes since midnight
+	location   *time.Location
+}
+
+// weekdays maps the day names of the time windows to their weekday.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// compile validates the policy, and flattens the inheritance of the roles.
+func (p Policy) compile() (*compiledPolicy, error) {
+	defined := make(map[string]Role, len(p.Roles))
+	for _, role := range p.Roles {
+		if role.Name == "" {
+			return nil, fmt.Errorf("%w: role without a name", ErrInvalidPolicy)
+		}
+		if _, ok := defined[role.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate role %q", ErrInvalidPolicy, role.Name)
+		}
+		defined[role.Name] = role
+	}
+
+	compiled := &compiledPolicy{
+		roles:    make(map[string][]compiledRule, len(p.Roles)),
+		bindings: make(map[string]Binding, len(p.Bindings)),
+	}
+	for _, role := range p.Roles {
+		rules, err := flatten(defined, role.Name, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		compiled.roles[role.Name] = rules
+	}
+
+	for _, binding := range p.Bindings {
+		for _, role := range binding.Roles {
+			if _, ok := defined[role]; !ok {
+				return nil, fmt.Errorf("%w %q in the binding of %q", ErrUnknownRole, role, binding.Subject)
+			}
+		}
+		// Note: Several bindings of a subject (e.g., one row per role in the database) are merged.
+		merged := compiled.bindings[binding.Subject]
+		merged.Subject = binding.Subject
+		merged.Roles = append(merged.Roles, binding.Roles...)
+		if merged.Tenant == "" {
+			merged.Tenant = binding.Tenant
+		}
+		compiled.bindings[binding.Subject] = merged
+	}
+	return compiled, nil
+}
+
+// flatten returns the rules of the role, followed by the ones it inherits.
+func flatten(defined map[string]Role, name string, visiting map[string]bool) ([]compiledRule, error) {
+	role, ok := defined[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownRole, name)
+	}
+	if visiting[name] {
+		return nil, fmt.Errorf("%w through %q", ErrRoleCycle, name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	rules := make([]compiledRule, 0, len(role.Rules))
+	for i, rule := range role.Rules {
+		compiled, err := rule.compile()
+		if err != nil {
+			return nil, fmt.Errorf("%w: rule %d of role %q: %v", ErrInvalidPolicy, i, name, err)
+		}
+		compiled.role = name
+		rules = append(rules, compiled)
+	}
+	for _, parent := range role.Inherits {
+		inherited, err := flatten(defined, parent, visiting)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, inherited...)
+	}
+	return rules, nil
+}
+
+// compile validates the rule.
+func (r Rule) compile() (compiledRule, error) {
+	compiled := compiledRule{
+		permissions: r.Permissions,
+		owner:       r.Conditions.Owner,
+		tenant:      r.Conditions.Tenant,
+	}
+	switch r.Effect {
+	case "", EffectAllow:
+	case EffectDeny:
+		compiled.deny = true
+	default:
+		return compiledRule{}, fmt.Errorf("unknown effect %q", r.Effect)
+	}
+	if len(r.Permissions) == 0 {
+		return compiledRule{}, errors.New("no permission")
+	}
+
+	for _, ipRange := range r.Conditions.IPRanges {
+		prefix, err := netip.ParsePrefix(ipRange)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(ipRange)
+			if addrErr != nil {
+				return compiledRule{}, fmt.Errorf("invalid IP range %q", ipRange)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		compiled.ipRanges = append(compiled.ipRanges, prefix.Masked())
+	}
+
+	if window := r.Conditions.TimeWindow; window != nil {
+		var err error
+		if compiled.window, err = window.compile(); err != nil {
+			return compiledRule{}, err
+		}
+	}
+	return compiled, nil
+}
+
+// compile validates the time window.
+func (w TimeWindow) compile() (*compiledWindow, error) {
+	compiled := &compiledWindow{location: time.UTC}
+	for _, day := range w.Days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", day)
+		}
+		compiled.days[weekday] = true
+	}
+
+	var err error
+	if compiled.start, err = parseClock(w.Start); err != nil {
+		return nil, err
+	}
+	if compiled.end, err = parseClock(w.End); err != nil {
+		return nil, err
+	}
+	if compiled.start == compiled.end {
+		return nil, errors.New("empty time window")
+	}
+	if w.Location != "" {
+		if compiled.location, err = time.LoadLocation(w.Location); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+// parseClock parses a time of the day as "15:04", into minutes since midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether the time is within the window.
+func (w *compiledWindow) contains(t time.Time) bool {
+	t = t.In(w.location)
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	if w.start > w.end {
+		// Note: The hours after midnight belong to the window of the day before.
+		if minute < w.end {
+			day = (day + 6) % 7
+		} else if minute < w.start {
+			return false
+		}
+	} else if minute < w.start || minute >= w.end {
+		return false
+	}
+	return w.days == [7]bool{} || w.days[day]
+}
+
+// matchPermission reports whether the permission is granted by the pattern, which is either a permission,
+// a prefix ending with ":*" (e.g., "invoices:*"), or "*".
+func matchPermission(pattern, permission string) bool {
+	if pattern == "*" || pattern == permission {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, ":") {
+		return strings.HasPrefix(permission, prefix)
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+	"h0llyw00dz-template/backend/internal/database"
+	"strings"
+)
+
+// Tables of the policy loaded by FromDatabase (see CreateRolesTable and CreateBindingsTable).
+const (
+	// RolesTable is the name of the table storing the roles.
+	RolesTable = "authorization_roles"
+
+	// BindingsTable is the name of the table storing the bindings of the subjects to the roles.
+	BindingsTable = "authorization_bindings"
+)
+
+// Loader loads the policy, on New and on every Authorizer.Reload.
+type Loader func(ctx context.Context) (Policy, error)
+
+// Static returns a loader of a policy built in code.
+func Static(policy Policy) Loader {
+	return func(context.Context) (Policy, error) {
+		return policy, nil
+	}
+}
+
+// FromFile returns a loader of a policy file, in JSON (".json") or YAML (see LoadFile).
+// The file is read again on every reload, so it can be updated (e.g., as a Kubernetes ConfigMap).
+func FromFile(path string) Loader {
+	return func(context.Context) (Policy, error) {
+		return LoadFile(path)
+	}
+}
+
+// FromDatabase returns a loader of the policy stored in the RolesTable and BindingsTable tables.
+//
+// A role is a row with its name, the comma-separated roles it inherits, and its rules as a JSON array (see Rule).
+// A binding is a row per subject and role, with the tenant of the subject.
+func FromDatabase(db database.Service) Loader {
+	return func(ctx context.Context) (Policy, error) {
+		var policy Policy
+		rows, err := db.Query(ctx, "SELECT name, inherits, rules FROM authorization_roles ORDER BY name")
+		if err != nil {
+			return Policy{}, fmt.Errorf("authorization: failed to load roles: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				role           Role
+				inherits, data string
+			)
+			if err := rows.Scan(&role.Name, &inherits, &data); err != nil {
+				return Policy{}, fmt.Errorf("authorization: failed to load roles: %w", err)
+			}
+			if inherits != "" {
+				for parent := range strings.SplitSeq(inherits, ",") {
+					role.Inherits = append(role.Inherits, strings.TrimSpace(parent))
+				}
+			}
+			if err := decodeJSON([]byte(data), &role.Rules); err != nil {
+				return Policy{}, fmt.Errorf("%w: rules of role %q: %v", ErrInvalidPolicy, role.Name, err)
+			}
+			policy.Roles = append(policy.Roles, role)
+		}
+		if err := rows.Err(); err != nil {
+			return Policy{}, fmt.Errorf("authorization: failed to load roles: %w", err)
+		}
+
+		bindings, err := db.Query(ctx, "SELECT subject, role, tenant FROM authorization_bindings ORDER BY subject, role")
+		if err != nil {
+			return Policy{}, fmt.Errorf("authorization: failed to load bindings: %w", err)
+		}
+		defer bindings.Close()
+		for bindings.Next() {
+			var (
+				binding Binding
+				role    string
+			)
+			if err := bindings.Scan(&binding.Subject, &role, &binding.Tenant); err != nil {
+				return Policy{}, fmt.Errorf("authorization: failed to load bindings: %w", err)
+			}
+			binding.Roles = []string{role}
+			policy.Bindings = append(policy.Bindings, binding)
+		}
+		if err := bindings.Err(); err != nil {
+			return Policy{}, fmt.Errorf("authorization: failed to load bindings: %w", err)
+		}
+		return policy, nil
+	}
+}
+
+// CreateRolesTable creates the table of the roles loaded by FromDatabase if it doesn't exist.
+func CreateRolesTable(ctx context.Context, db database.Service) error {
+	return createTable(ctx, db, `CREATE TABLE IF NOT EXISTS authorization_roles (
+	name VARCHAR(64) NOT NULL PRIMARY KEY,
+	inherits VARCHAR(1024) NOT NULL DEFAULT '',
+	rules TEXT NOT NULL`)
+}
+
+// CreateBindingsTable creates the table of the bindings loaded by FromDatabase if it doesn't exist.
+func CreateBindingsTable(ctx context.Context, db database.Service) error {
+	return createTable(ctx, db, `CREATE TABLE IF NOT EXISTS authorization_bindings (
+	subject VARCHAR(255) NOT NULL,
+	role VARCHAR(64) NOT NULL,
+	tenant VARCHAR(64) NOT NULL DEFAULT '',
+	PRIMARY KEY (subject, role)`)
+}
+
+// createTable closes the CREATE TABLE query, with the table options of MySQL.
+func createTable(ctx context.Context, db database.Service, query string) error {
+	if db.Dialect().Name() == database.DriverMySQL {
+		return db.ExecWithoutRow(ctx, query+"\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4")
+	}
+	return db.ExecWithoutRow(ctx, query+"\n)")
+}
@@ -28,6 +28,13 @@ const (
 	maxExpirationRESTAPIsRateLimiter = 1 * time.Minute
 )
 
+// Rate limit headers set by NewAPIKeyQuotaMiddleware (the same ones as the Fiber limiter middleware).
+const (
+	xRateLimitLimit     = "X-RateLimit-Limit"
+	xRateLimitRemaining = "X-RateLimit-Remaining"
+	xRateLimitReset     = "X-RateLimit-Reset"
+)
+
 const (
 	loggerFormat = "${time} [${blue}${appName}${reset}] [${green}INFO${reset}] | " +
 		"[${cyan}${proxy}${reset} - ${protocol} - ${hostName} - ${status}] | ${latency} - ${unixTime} | " +
@@ -0,0 +1,89 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package middleware
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/middleware/idempotency"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultIdempotencyLockTimeout bounds how long a request waits for another request with the same idempotency key.
+const defaultIdempotencyLockTimeout = 30 * time.Second
+
+// RedisIdempotencyLocker implements [idempotency.Locker] with a distributed lock, so requests that share
+// an idempotency key are serialized across every pod instead of only within a pod (like [idempotency.MemoryLock]).
+type RedisIdempotencyLocker struct {
+	locker  *database.Locker
+	timeout time.Duration
+
+	mu    sync.Mutex
+	locks map[string]*database.Lock
+}
+
+// NewRedisIdempotencyLocker creates a new idempotency locker on the distributed locker.
+// A request waits up to timeout (default 30 seconds) for the lock before it fails.
+func NewRedisIdempotencyLocker(locker *database.Locker, timeout ...time.Duration) *RedisIdempotencyLocker {
+	l := &RedisIdempotencyLocker{
+		locker:  locker,
+		timeout: defaultIdempotencyLockTimeout,
+		locks:   make(map[string]*database.Lock),
+	}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		l.timeout = timeout[0]
+	}
+	return l
+}
+
+// Lock waits until the lock of the idempotency key is acquired.
+func (l *RedisIdempotencyLocker) Lock(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+
+	lock, err := l.locker.Lock(ctx, "idempotency:"+key)
+	if err != nil {
+		return err
+	}
+
+	// Note: Only one request can hold the lock of a key at a time, so the entry can't be overwritten here.
+	l.mu.Lock()
+	l.locks[key] = lock
+	l.mu.Unlock()
+	return nil
+}
+
+// Unlock releases the lock of the idempotency key.
+func (l *RedisIdempotencyLocker) Unlock(key string) error {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	delete(l.locks, key)
+	l.mu.Unlock()
+	if !ok {
+		// Same as idempotency.MemoryLock: unlocking an unknown key is a no-op.
+		return nil
+	}
+	return lock.Unlock(context.Background())
+}
+
+// defaultIdempotencyLock returns a distributed locker when the idempotency storage is Redis, otherwise nil,
+// which leaves Fiber's in-memory locker as the default.
+func defaultIdempotencyLock(config idempotency.Config) idempotency.Locker {
+	// Note: This matches redisStorage.Storage (see Conn), without relying on how the storage was created.
+	// The wrappers of a storage (e.g., tiered.Storage or hybrid.EncryptedStorage) forward it, or return nil when they don't wrap Redis.
+	storage, ok := config.Storage.(interface{ Conn() redis.UniversalClient })
+	if !ok {
+		return nil
+	}
+	client := storage.Conn()
+	if client == nil {
+		return nil
+	}
+	return NewRedisIdempotencyLocker(database.NewLocker(client))
+}
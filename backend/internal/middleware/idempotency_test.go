@@ -0,0 +1,113 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package middleware_test
+
+import (
+	"bytes"
+	"h0llyw00dz-template/backend/internal/database/tiered"
+	"h0llyw00dz-template/backend/internal/middleware"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/hybrid"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gofiber/fiber/v2"
+	redisStorage "github.com/gofiber/storage/redis/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestIdempotencyAcrossPods checks that the same idempotency key sent concurrently to two pods
+// runs the handler once, since the Redis storage makes the middleware use a distributed lock.
+// The lock must also be found through the wrappers of the Redis storage, like the tiered storage (L1) and the encryption at rest.
+func TestIdempotencyAcrossPods(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage func(t *testing.T, client *redis.Client) fiber.Storage
+	}{
+		{"redis", func(t *testing.T, client *redis.Client) fiber.Storage {
+			return redisStorage.NewFromConnection(client)
+		}},
+		{"tiered", func(t *testing.T, client *redis.Client) fiber.Storage {
+			storage, err := tiered.New(tiered.Config{Backend: redisStorage.NewFromConnection(client), Name: "idempotency_test"})
+			if err != nil {
+				t.Fatalf("tiered.New() error = %v", err)
+			}
+			t.Cleanup(func() { storage.Close() })
+			return storage
+		}},
+		{"encrypted", func(t *testing.T, client *redis.Client) fiber.Storage {
+			storage, err := hybrid.NewEncryptedStorage(hybrid.StorageConfig{
+				Storage:      redisStorage.NewFromConnection(client),
+				Keys:         map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)},
+				CurrentKeyID: "k1",
+			})
+			if err != nil {
+				t.Fatalf("NewEncryptedStorage() error = %v", err)
+			}
+			return storage
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testIdempotencyAcrossPods(t, tt.storage)
+		})
+	}
+}
+
+func testIdempotencyAcrossPods(t *testing.T, newStorage func(t *testing.T, client *redis.Client) fiber.Storage) {
+	mr := miniredis.RunT(t)
+
+	var calls atomic.Int32
+	newPod := func() *fiber.App {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		app := fiber.New()
+		app.Use(middleware.NewIdempotencyMiddleware(
+			middleware.WithIdempotencyStorage(newStorage(t, client)),
+			middleware.WithIdempotencyLifetime(time.Minute),
+		))
+		app.Post("/payments", func(c *fiber.Ctx) error {
+			calls.Add(1)
+			time.Sleep(50 * time.Millisecond) // keep the lock held while the other pod waits
+			return c.SendString("paid")
+		})
+		return app
+	}
+	pods := []*fiber.App{newPod(), newPod()}
+
+	var wg sync.WaitGroup
+	bodies := make([]string, len(pods))
+	for i, app := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(fiber.MethodPost, "/payments", nil)
+			req.Header.Set("X-Idempotency-Key", "00000000-0000-0000-0000-000000000001")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Errorf("Test() error = %v", err)
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			bodies[i] = string(body)
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("handler called %d times, want 1", got)
+	}
+	for i, body := range bodies {
+		if body != "paid" {
+			t.Errorf("pod %d response = %q, want %q", i, body, "paid")
+		}
+	}
+}
@@ -9,9 +9,14 @@ import (
 	"h0llyw00dz-template/backend/internal/database"
 	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/hybrid"
 	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/keyidentifier"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
+	"h0llyw00dz-template/backend/pkg/restapis/apikeys"
 	healthz "h0llyw00dz-template/backend/pkg/restapis/server/health"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
 	htmx "h0llyw00dz-template/frontend/htmx/error_page_handler"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/encryptcookie"
@@ -20,24 +25,34 @@ import (
 
 // APIRoute represents a single API route, containing the path, HTTP method,
 // handler function, and an optional rate limiter.
+//
+// Scopes are the API key scopes required by the route (see RequireScopes), and Quota enforces the quota plan
+// of the API key (see NewAPIKeyQuotaMiddleware). Both run right after KeyAuth, which they need.
+// Authorization runs after them, to check the permission of the authenticated subject (see authorization.Authorizer.Require).
 type APIRoute struct {
 	Path                      string
 	Method                    string
 	Handler                   fiber.Handler
 	RateLimiter               fiber.Handler
 	KeyAuth                   fiber.Handler
+	Scopes                    []string
+	Quota                     fiber.Handler
+	Authorization             fiber.Handler
 	RequestID                 fiber.Handler
 	EncryptedCookieMiddleware fiber.Handler
 	CompressJSON              fiber.Handler
 }
 
 // APIGroup represents a group of API routes under a common prefix.
-// It also allows for a group-wide rate limiter.
+// It also allows for a group-wide rate limiter, and group-wide API key scopes, quota and authorization (like APIRoute).
 type APIGroup struct {
 	Prefix                    string
 	Routes                    []APIRoute
 	RateLimiter               fiber.Handler
 	KeyAuth                   fiber.Handler
+	Scopes                    []string
+	Quota                     fiber.Handler
+	Authorization             fiber.Handler
 	RequestID                 fiber.Handler
 	EncryptedCookieMiddleware fiber.Handler
 	CompressJSON              fiber.Handler
@@ -68,7 +83,9 @@ type APIConfig struct {
 //
 //	api: The Fiber router to register the routes on.
 //	db: The database service to be used by the API handlers.
-func registerRESTAPIsRoutes(api fiber.Router, db database.Service) {
+//	quotas: The quota plans and usage of the API keys (see NewAPIKeyQuotaMiddleware).
+//	authz: The authorizer of the routes requiring a permission, or nil when the authorization is disabled.
+func registerRESTAPIsRoutes(api fiber.Router, db database.Service, quotas *database.Quotas, authz *authorization.Authorizer) {
 	// Note: This is just an example that can be integrated with other Fiber middleware.
 	// If needed to store it in storage, use a prefix for group keys and call "GetKeyFunc".
 	genReqID := keyidentifier.New(keyidentifier.Config{
@@ -204,6 +221,43 @@ func registerRESTAPIsRoutes(api fiber.Router, db database.Service) {
 	//    because Heroku stores metrics in memory (actually the same as this Prometheus Middleware), unlike on Kubernetes.
 	server.Get("/metrics", rateLimiterRESTAPIs)
 
+	// Register the user account routes ('/v1/users' prefix).
+	// Note: The sessions are stored in the same storage as the rate limiter, so they are shared by every pod (see NewSessionMiddleware).
+	// They have their own key prefix, and no cleanup, since it would reset the whole shared storage (they expire by their TTL).
+	// The register and login routes are rate limited, since they are where passwords are guessed.
+	// Reading the profile requires the "users:read" permission when the authorization is enabled (see AUTHORIZATION_POLICY_FILE).
+	userSessions := NewSessionMiddleware(
+		WithSessionStorage(database.NewPrefixedStorage(gopherStorage, "session:")),
+		time.Duration(0),
+		WithSessionCookieHTTPOnly(true),
+		WithSessionCookieSameSite(fiber.CookieSameSiteLaxMode),
+	)
+	accounts := v1.Group("/users", userSessions)
+	accounts.Post("/register", rateLimiterRESTAPIs, users.Register(db))
+	accounts.Post("/login", rateLimiterRESTAPIs, users.Login(db))
+	accounts.Post("/logout", users.Logout())
+	accounts.Get("/me", appendNonNilHandler(nil, users.RequireUser(db), requirePermissionOrNil(authz, "users:read"), users.Me())...)
+	accounts.Post("/password", rateLimiterRESTAPIs, users.RequireUser(db), users.ChangePassword(db))
+
+	// Register the API key routes ('/v1/keys' prefix).
+	// Note: The requests are counted against the quota plan of the API key (see API_KEY_QUOTA_PLANS), and accounted in its usage.
+	registerGroup(v1, APIGroup{
+		Prefix:      "/keys",
+		RateLimiter: rateLimiterRESTAPIs,
+		KeyAuth: NewKeyAuthMiddleware(
+			WithValidator(keyauth.NewValidator(db)),
+			WithErrorHandler(keyauth.ErrorKeyAuthHandler),
+		),
+		Quota: NewAPIKeyQuotaMiddleware(quotas),
+		Routes: []APIRoute{
+			{
+				Path:    "/usage",
+				Method:  fiber.MethodGet,
+				Handler: apikeys.Usage(quotas),
+			},
+		},
+	})
+
 	// Register server APIs routes
 	// Custom error handling for versioned APIs
 	api.Use(htmx.NewErrorHandler)
@@ -231,6 +285,12 @@ func serverAPIs(v1 fiber.Router, db database.Service, rateLimiterRESTAPIs fiber.
 		{ // Note: Example https://localhost:8080/v1/server/health/db
 			Prefix:      "/server/health",
 			RateLimiter: rateLimiterRESTAPIs, // This is an optional example.
+			// Note: This is an optional example as well. Only the API keys granted "health:read" (or "health:*") can access this group.
+			KeyAuth: NewKeyAuthMiddleware(
+				WithValidator(keyauth.NewValidator(db)),
+				WithErrorHandler(keyauth.ErrorKeyAuthHandler),
+			),
+			Scopes: []string{"health:read"},
 			Routes: []APIRoute{
 				{
 					Path: "/db",
@@ -274,6 +334,9 @@ func registerGroupMiddlewares(g fiber.Router, group APIGroup) {
 		g,
 		group.RateLimiter,
 		group.KeyAuth,
+		requireScopesOrNil(group.Scopes),
+		group.Quota,
+		group.Authorization,
 		group.RequestID,
 		group.EncryptedCookieMiddleware,
 		group.CompressJSON,
@@ -296,7 +359,7 @@ func registerRoute(g fiber.Router, route APIRoute) {
 
 // getRouteHandlers returns the handlers for an API route.
 func getRouteHandlers(route APIRoute) []fiber.Handler {
-	handlers := make([]fiber.Handler, 0, 5)
+	handlers := make([]fiber.Handler, 0, 8)
 
 	// Note: This approach uses a "higher-order function" called appendNonNilHandler.
 	// Also Note that Higher-order functions are powerful especially for "Cryptography Technique" and can handle multiple functions as arguments.
@@ -306,6 +369,9 @@ func getRouteHandlers(route APIRoute) []fiber.Handler {
 		handlers,
 		route.RateLimiter,
 		route.KeyAuth,
+		requireScopesOrNil(route.Scopes),
+		route.Quota,
+		route.Authorization,
 		route.RequestID,
 		route.EncryptedCookieMiddleware,
 	)
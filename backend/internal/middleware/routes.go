@@ -180,8 +180,18 @@ func registerRouteConfigMiddleware(app *fiber.App, db database.Service) {
 		WithLoggerFormat(loggerFormat),
 		WithLoggerTimeFormat(loggerFormatTime),
 	)
+	// Liveness and readiness probes for Kubernetes (GET /livez and GET /readyz).
+	//
+	// Note: Liveness only tells that the process is serving, so a database outage doesn't make Kubernetes restart the pod
+	// (which wouldn't help anyway). Readiness follows the connection supervisor, so the pod is taken out of the
+	// load balancer while MySQL or Redis is degraded and put back once they recover.
+	// It goes first, so the probes aren't logged or cached.
+	healthzCheck := NewHealthZCheck(
+		WithReadinessProbe(func(*fiber.Ctx) bool { return db.Ready() }),
+	)
+
 	// Apply the recover middleware
-	app.Use(httpLogg, xRequestID, etagMiddleware, cspMiddleware, cacheMiddleware, htmx.NewErrorHandler, recoverMiddleware)
+	app.Use(healthzCheck, httpLogg, xRequestID, etagMiddleware, cspMiddleware, cacheMiddleware, htmx.NewErrorHandler, recoverMiddleware)
 }
 
 // registerRootRouter sets up the root router for the application.
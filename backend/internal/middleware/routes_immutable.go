@@ -14,6 +14,7 @@ import (
 	"h0llyw00dz-template/backend/pkg/network/cidr"
 
 	"h0llyw00dz-template/backend/internal/database"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
 	"h0llyw00dz-template/backend/internal/middleware/router/domain"
 	"h0llyw00dz-template/env"
 
@@ -27,7 +28,7 @@ import (
 // Note: There are now 3 routers: restapis, frontend, and wildcard handler (503) (wildcard handler (503) known as root).
 // They operate independently. Also note that as the codebase grows, the routing structure
 // may become a binary tree (see https://en.wikipedia.org/wiki/Binary_tree), which is considered one of the best art in Go programming.
-func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Service) {
+func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Service, quotas *database.Quotas, authz *authorization.Authorizer) {
 	// Validate and parse trusted proxies
 	trustedProxies, err := cidr.ValidateAndParseIPs(env.TRUSTEDPROXIES, "0.0.0.0/0")
 	if err != nil {
@@ -85,7 +86,7 @@ func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Ser
 		// it can result in a highly stable and scalable system for large-scale deployments (as demonstrated through extensive testing with multiple nodes until stability was consistently achieved).
 		BodyLimit: sizeBodyLimit,
 	})
-	registerRESTAPIsRoutes(api, db)
+	registerRESTAPIsRoutes(api, db, quotas, authz)
 	hosts[apiSubdomain] = api
 
 	// Frontend domain
@@ -11,6 +11,7 @@ package middleware
 import (
 	"h0llyw00dz-template/backend/internal/database"
 	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
 	"h0llyw00dz-template/backend/internal/middleware/router/domain"
 	"h0llyw00dz-template/backend/pkg/convert"
 	"h0llyw00dz-template/backend/pkg/network/cidr"
@@ -26,7 +27,7 @@ import (
 // Note: There are now 3 routers: restapis, frontend, and wildcard handler (503) (wildcard handler (503) known as root).
 // They operate independently. Also note that as the codebase grows, the routing structure
 // may become a binary tree (see https://en.wikipedia.org/wiki/Binary_tree), which is considered one of the best art in Go programming.
-func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Service) {
+func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Service, quotas *database.Quotas, authz *authorization.Authorizer) {
 	// Validate and parse trusted proxies
 	trustedProxies, err := cidr.ValidateAndParseIPs(env.TRUSTEDPROXIES, "0.0.0.0/0")
 	if err != nil {
@@ -81,7 +82,7 @@ func RegisterRoutes(app *fiber.App, appName, monitorPath string, db database.Ser
 		// it can result in a highly stable and scalable system for large-scale deployments (as demonstrated through extensive testing with multiple nodes until stability was consistently achieved).
 		BodyLimit: sizeBodyLimit,
 	})
-	registerRESTAPIsRoutes(api, db)
+	registerRESTAPIsRoutes(api, db, quotas, authz)
 	hosts[apiSubdomain] = api
 
 	// Frontend domain
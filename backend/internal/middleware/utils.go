@@ -435,7 +435,12 @@ func NewSessionMiddleware(options ...any) fiber.Handler {
 	store := session.New(config)
 
 	// Start the cleanup goroutine for expired sessions.
-	go CleanupExpiredSessions(store, cleanupInterval)
+	//
+	// Note: A non-positive cleanup interval disables it. It must be disabled for a shared storage (e.g., Redis),
+	// since the cleanup resets the whole storage, and the sessions there expire by their TTL anyway.
+	if cleanupInterval > 0 {
+		go CleanupExpiredSessions(store, cleanupInterval)
+	}
 
 	// Return the session middleware function.
 	return func(c *fiber.Ctx) error {
@@ -556,6 +561,8 @@ func NewSwaggerMiddleware(options ...any) fiber.Handler {
 //
 // Note: This can improve latency for ingress on an HPA with many pods (e.g., 50+ pods). However, use it carefully as it is similar to cookies.
 // For storage, using Redis is recommended. Ensure the validation header is accurate (e.g., from ingress NGINX not actual client), as it can become overpowered (OP) reduce latency.
+// When the storage is Redis and no locker is set (see WithIdempotencyLock), the keys are locked with a distributed lock (see RedisIdempotencyLocker),
+// since Fiber's default in-memory locker only serializes the requests within a single pod.
 func NewIdempotencyMiddleware(options ...any) fiber.Handler {
 	// Create a new idempotency middleware configuration.
 	config := idempotency.Config{}
@@ -567,6 +574,10 @@ func NewIdempotencyMiddleware(options ...any) fiber.Handler {
 		}
 	}
 
+	if config.Lock == nil {
+		config.Lock = defaultIdempotencyLock(config)
+	}
+
 	// Create the idempotency middleware with the configured options.
 	idempotencyMiddleware := idempotency.New(config)
 
@@ -7,9 +7,15 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"h0llyw00dz-template/backend/internal/database"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/web3/eth"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/crypto/webauthn"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/totp"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
 	"net/http"
 	"time"
 
@@ -56,8 +62,74 @@ import (
 func InitializeTables(db database.Service) error {
 	// Note: This approach provides a more flexible and scalable way to initialize database tables compared to using an ORM system.
 	// It allows for easy initialization or migration of tables, and can handle a large number of database schemas (e.g, 1 billion database schemas 🔥) without limitations.
-	return createTables(db)
+	return createTables(db,
+		createTable(database.APIKeysTable, createAPIKeysTable),
+		createTable(database.APIKeyUsageTable, createAPIKeyUsageTable),
+		createTable(database.UsersTable, createUsersTable),
+		createTable(database.UserIdentitiesTable, createUserIdentitiesTable),
+		createTable(webauthn.CredentialsTable, createWebAuthnCredentialsTable),
+		createTable(totp.TOTPTable, createTOTPTable),
+		createTable(totp.RecoveryCodesTable, createRecoveryCodesTable),
+		createTable(eth.PaymentsTable, createEthPaymentsTable),
+		createTable(authorization.RolesTable, createAuthorizationRolesTable),
+		createTable(authorization.BindingsTable, createAuthorizationBindingsTable),
+		createTable(setupTLS.ACMETable, createACMETable),
+	)
+}
+
+// createAPIKeysTable creates the table of the API keys managed by [database.ServiceAuth] if it doesn't exist.
+func createAPIKeysTable(db database.Service) error {
+	return database.CreateAPIKeysTable(context.Background(), db)
+}
+
+// createAPIKeyUsageTable creates the table of the daily API key usage flushed by [database.Quotas] if it doesn't exist.
+func createAPIKeyUsageTable(db database.Service) error {
+	return database.CreateAPIKeyUsageTable(context.Background(), db)
+}
+
+// createUsersTable creates the table of the user accounts managed by [database.ServiceAuth] if it doesn't exist.
+func createUsersTable(db database.Service) error {
+	return database.CreateUsersTable(context.Background(), db)
+}
+
+// createUserIdentitiesTable creates the table linking the external identities (e.g., OAuth2) to the users if it doesn't exist.
+func createUserIdentitiesTable(db database.Service) error {
+	return database.CreateUserIdentitiesTable(context.Background(), db)
+}
+
+// createWebAuthnCredentialsTable creates the table of the WebAuthn credentials (e.g., passkeys) of the users if it doesn't exist.
+func createWebAuthnCredentialsTable(db database.Service) error {
+	return webauthn.CreateCredentialsTable(context.Background(), db)
+}
+
+// createTOTPTable creates the table of the TOTP secrets of the users if it doesn't exist.
+func createTOTPTable(db database.Service) error {
+	return totp.CreateTOTPTable(context.Background(), db)
+}
+
+// createRecoveryCodesTable creates the table of the TOTP recovery codes of the users if it doesn't exist.
+func createRecoveryCodesTable(db database.Service) error {
+	return totp.CreateRecoveryCodesTable(context.Background(), db)
+}
+
+// createEthPaymentsTable creates the table of the on-chain payments verified by [eth.Payments] if it doesn't exist.
+func createEthPaymentsTable(db database.Service) error {
+	return eth.CreatePaymentsTable(context.Background(), db)
+}
+
+// createAuthorizationRolesTable creates the table of the roles loaded by [authorization.FromDatabase] if it doesn't exist.
+func createAuthorizationRolesTable(db database.Service) error {
+	return authorization.CreateRolesTable(context.Background(), db)
+}
+
+// createAuthorizationBindingsTable creates the table binding the subjects to the roles if it doesn't exist.
+func createAuthorizationBindingsTable(db database.Service) error {
+	return authorization.CreateBindingsTable(context.Background(), db)
+}
 
+// createACMETable creates the table of the state of the ACME certificates (see [setupTLS.ACMEStorage]) if it doesn't exist.
+func createACMETable(db database.Service) error {
+	return setupTLS.CreateACMETable(context.Background(), db)
 }
 
 // createTable is a higher-order function that creates a table in the database.
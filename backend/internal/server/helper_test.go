@@ -0,0 +1,38 @@
+// Copyright (c) 2024 H0llyW00dz All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/server"
+)
+
+func TestInitializeTables(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	// Twice, since the tables are created on every boot.
+	for range 2 {
+		if err := server.InitializeTables(db); err != nil {
+			t.Fatalf("InitializeTables() error = %v", err)
+		}
+	}
+
+	for _, table := range []string{database.UsersTable, database.APIKeysTable, database.APIKeyUsageTable} {
+		if err := db.ExecWithoutRow(context.Background(), "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+}
@@ -20,8 +20,12 @@ import (
 	"time"
 
 	"h0llyw00dz-template/backend/internal/database"
+	"h0llyw00dz-template/backend/internal/eventbus"
 	log "h0llyw00dz-template/backend/internal/logger"
 	"h0llyw00dz-template/backend/internal/middleware"
+	setupTLS "h0llyw00dz-template/backend/internal/middleware/authentication/crypto/tls"
+	"h0llyw00dz-template/backend/internal/middleware/authorization"
+	translation "h0llyw00dz-template/backend/internal/translate"
 	"h0llyw00dz-template/env"
 
 	"github.com/gofiber/fiber/v2"
@@ -37,6 +41,20 @@ var (
 	// This is controlled by the DISABLE_PORT_HTTPINSECURE environment variable.
 	// If set to "true", the server will not set up an HTTP listener on port 80 otherwise it will listening (e.g., keep empty the env).
 	disableHTTPInsecure = env.GetEnv(env.DISABLEDEFAULTPORTHTTP, "") == "true"
+
+	// translationsFile is the translations JSON file, loaded at boot and reloaded through the event bus.
+	// It is set using the TRANSLATIONS_FILE environment variable.
+	translationsFile = os.Getenv(env.TRANSLATIONSFILE)
+
+	// apiKeyQuotaPlans are the quota plans of the API keys, and apiKeyDefaultPlan is the plan of the keys without one.
+	// They are set using the API_KEY_QUOTA_PLANS and API_KEY_DEFAULT_PLAN environment variables.
+	apiKeyQuotaPlans  = os.Getenv(env.APIKEYQUOTAPLANS)
+	apiKeyDefaultPlan = os.Getenv(env.APIKEYDEFAULTPLAN)
+
+	// authorizationPolicyFile is the policy file of the authorization layer, reloaded every authorizationRefreshInterval.
+	// They are set using the AUTHORIZATION_POLICY_FILE and AUTHORIZATION_REFRESH_INTERVAL environment variables.
+	authorizationPolicyFile      = os.Getenv(env.AUTHORIZATIONPOLICYFILE)
+	authorizationRefreshInterval = env.GetEnv(env.AUTHORIZATIONREFRESHINTERVAL, "1m")
 )
 
 // Server defines the interface for a server that can be started, shut down, and clean up its database.
@@ -53,24 +71,115 @@ type Server interface {
 type FiberServer struct {
 	App        *fiber.App
 	db         database.Service
+	events     *eventbus.Bus
+	quotas     *database.Quotas
+	authz      *authorization.Authorizer
 	httpServer *http.Server
+	acme       *setupTLS.ACMEManager
 }
 
 // NewFiberServer returns a new FiberServer with the given Fiber app, application name, and monitor path.
-// It also initializes the database and registers routes.
+// It also initializes the database, creates its tables and registers routes.
+//
+// Note: The tables are created before the routes are registered, since the routes (e.g., /v1/users and /v1/keys/usage)
+// query them right away. Failing to create them fails the boot, like the ACME table in [FiberServer.LoadACME].
 func NewFiberServer(app *fiber.App, appName, monitorPath string) *FiberServer {
 	// Note: The database.New() function and the database.service function that takes the database as a parameter are safe from multiple calls (e.g., 10,000 calls from different parts of the codebase)
 	// because they follow the singleton pattern. Without the singleton pattern, it would be unsafe
 	// as it would create multiple database connections, leading to potential resource exhaustion.
 	db := database.New()
+	if err := InitializeTables(db); err != nil {
+		log.LogFatal(err)
+	}
 	s := &FiberServer{
-		App: app,
-		db:  db,
+		App:    app,
+		db:     db,
+		events: newEventBus(db),
+		quotas: newQuotas(db),
+		authz:  newAuthorizer(),
 	}
-	middleware.RegisterRoutes(app, appName, monitorPath, db)
+	middleware.RegisterRoutes(app, appName, monitorPath, db, s.quotas, s.authz)
 	return s
 }
 
+// newQuotas creates the quotas of the API keys, with the plans of API_KEY_QUOTA_PLANS.
+//
+// Note: Invalid plans fail the boot, since they would silently leave the API keys without their quota.
+func newQuotas(db database.Service) *database.Quotas {
+	plans, err := database.ParseQuotaPlans(apiKeyQuotaPlans)
+	if err != nil {
+		log.LogFatal(err)
+	}
+	return database.NewQuotas(db, database.QuotaConfig{
+		Plans:       plans,
+		DefaultPlan: apiKeyDefaultPlan,
+	})
+}
+
+// newAuthorizer creates the authorizer of the policy file of AUTHORIZATION_POLICY_FILE, or returns nil when it isn't set.
+//
+// Note: An invalid policy fails the boot, since the routes requiring a permission would otherwise be left open.
+func newAuthorizer() *authorization.Authorizer {
+	if authorizationPolicyFile == "" {
+		return nil
+	}
+	refreshInterval, err := time.ParseDuration(authorizationRefreshInterval)
+	if err != nil {
+		log.LogFatalf("Invalid %s: %v", env.AUTHORIZATIONREFRESHINTERVAL, err)
+	}
+	authz, err := authorization.New(authorization.Config{
+		Loader:          authorization.FromFile(authorizationPolicyFile),
+		RefreshInterval: refreshInterval,
+	})
+	if err != nil {
+		log.LogFatal(err)
+	}
+	return authz
+}
+
+// newEventBus creates the cross-pod event bus with the built-in handlers, and starts it.
+//
+// Note: The bus reconnects on its own, so a Redis outage at boot only delays the events instead of failing the boot.
+func newEventBus(db database.Service) *eventbus.Bus {
+	events := eventbus.New(eventbus.Config{Redis: db})
+	// Note: Every pod purges its in-process cache (if any), since the keys deleted by the publisher
+	// are only invalidated there by the keyspace notifications when they're enabled.
+	eventbus.Subscribe(events, eventbus.TopicInvalidateCache, eventbus.InvalidateCacheHandler(events, db, func(context.Context, string) {
+		database.PurgeL1(db.FiberStorage())
+	}))
+
+	if translationsFile != "" {
+		if err := translation.LoadTranslations(translationsFile); err != nil {
+			log.LogErrorf("Error loading translations from %s: %v", translationsFile, err)
+		}
+		eventbus.Subscribe(events, eventbus.TopicReloadTranslations, eventbus.ReloadTranslationsHandler(translationsFile))
+	}
+
+	if err := events.Start(); err != nil {
+		log.LogErrorf("Error starting the event bus: %v", err)
+	}
+	return events
+}
+
+// Events returns the cross-pod event bus (e.g., to publish a cache invalidation to every pod).
+func (s *FiberServer) Events() *eventbus.Bus {
+	return s.events
+}
+
+// LoadACME returns the TLS config of the certificate obtained from the ACME server of ACME_HOSTS (see [setupTLS.LoadACME]),
+// stored in its own table of the database and ordered under a distributed lock, so the replicas share it. It's nil when ACME isn't configured.
+//
+// The certificate is ordered once the server is started, with the TLS-ALPN-01 challenge on the TLS listener,
+// or the HTTP-01 challenge on the port 80 server, and renewed before it expires until the shutdown.
+func (s *FiberServer) LoadACME() (*tls.Config, error) {
+	certs, err := setupTLS.LoadACME(s.db, database.NewLocker(s.db.RedisClient()))
+	if err != nil || certs == nil {
+		return nil, err
+	}
+	s.acme = certs
+	return certs.TLSConfig(), nil
+}
+
 // Start runs the Fiber server in a separate goroutine to listen for incoming requests.
 func (s *FiberServer) Start(addr, monitorPath string, tlsConfig *tls.Config, streamListener net.Listener) {
 	// Important: Do not modify the current implementation of the HTTPS/TLS mechanism (e.g., by removing the tlsHandler struct).
@@ -138,28 +247,33 @@ func (s *FiberServer) Start(addr, monitorPath string, tlsConfig *tls.Config, str
 		// TODO: Improve this that can be customize
 		go func() {
 			httpAddr := ":80" // Listen on port 80 for HTTP
+			var redirect http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				httpsPort := strings.Split(addr, ":")[1]
+				portPart := ""
+				if httpsPort != "443" {
+					portPart = ":" + httpsPort
+				}
+				target := httpsURI + r.Host + portPart + r.URL.RequestURI()
+
+				// Extract the host from the API subdomain
+				apiHost := strings.Split(apiSubdomain, ":")[0]
+
+				// Check if the request is for the API subdomain
+				if apiHost != "" && strings.HasPrefix(r.Host, apiHost) {
+					// Note: Use a 308 redirect for REST APIs to preserve the HTTP method and body.
+					// A 301 redirect is better for SEO, as it is well-recognized by search engines for business with google, bing, other search engine hahaha.
+					http.Redirect(w, r, target, http.StatusPermanentRedirect) // 308 redirect for API
+				} else {
+					http.Redirect(w, r, target, http.StatusMovedPermanently) // 301 redirect for others
+				}
+			})
+			// Note: The HTTP-01 challenges of the ACME server are answered here, before the redirect.
+			if s.acme != nil {
+				redirect = s.acme.HTTPHandler(redirect)
+			}
 			s.httpServer = &http.Server{
-				Addr: httpAddr,
-				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					httpsPort := strings.Split(addr, ":")[1]
-					portPart := ""
-					if httpsPort != "443" {
-						portPart = ":" + httpsPort
-					}
-					target := httpsURI + r.Host + portPart + r.URL.RequestURI()
-
-					// Extract the host from the API subdomain
-					apiHost := strings.Split(apiSubdomain, ":")[0]
-
-					// Check if the request is for the API subdomain
-					if apiHost != "" && strings.HasPrefix(r.Host, apiHost) {
-						// Note: Use a 308 redirect for REST APIs to preserve the HTTP method and body.
-						// A 301 redirect is better for SEO, as it is well-recognized by search engines for business with google, bing, other search engine hahaha.
-						http.Redirect(w, r, target, http.StatusPermanentRedirect) // 308 redirect for API
-					} else {
-						http.Redirect(w, r, target, http.StatusMovedPermanently) // 301 redirect for others
-					}
-				}),
+				Addr:    httpAddr,
+				Handler: redirect,
 			}
 			if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 				log.LogFatalf("Error starting HTTP redirect server: %v", err)
@@ -167,6 +281,20 @@ func (s *FiberServer) Start(addr, monitorPath string, tlsConfig *tls.Config, str
 		}()
 	}
 
+	// Flush the usage of the API keys to the database in the background, until CleanupDB.
+	if s.quotas != nil {
+		s.quotas.Start()
+	}
+
+	// Reload the authorization policy in the background, until the shutdown.
+	if s.authz != nil {
+		s.authz.Start()
+	}
+
+	// Order (or renew) the ACME certificate, now that the listeners can answer the challenges.
+	if s.acme != nil {
+		s.acme.Start()
+	}
 }
 
 // Shutdown gracefully stops the Fiber server using the provided timeout and context for the HTTP insecure server.
@@ -176,6 +304,14 @@ func (s *FiberServer) Start(addr, monitorPath string, tlsConfig *tls.Config, str
 // Memory leaks could occur if the network in the ingress becomes unstable, such as bottlenecks in ingress-nginx caused by complex configurations.
 // For bandwidth considerations, consider hosting in a cost-effective cloud environment (e.g., DOKS).
 func (s *FiberServer) Shutdown(ctx context.Context, shutdownTimeout time.Duration) error {
+	// The ACME renewal stops first, since it uses the storage closed by CleanupDB.
+	if s.acme != nil {
+		s.acme.Close()
+	}
+	// The reload of the authorization policy stops as well, since it's only needed while serving.
+	if s.authz != nil {
+		s.authz.Close()
+	}
 	// http server (insecure) it will be first
 	if s.httpServer != nil {
 		if err := s.httpServer.Shutdown(ctx); err != nil {
@@ -194,6 +330,18 @@ func (s *FiberServer) Shutdown(ctx context.Context, shutdownTimeout time.Duratio
 func (s *FiberServer) CleanupDB() error {
 	var err error
 
+	// The usage of the API keys is flushed one last time first, since it needs both Redis and the database.
+	if s.quotas != nil {
+		if errFlush := s.quotas.Close(); errFlush != nil {
+			log.LogErrorf("Error flushing the API key usage: %v", errFlush)
+		}
+	}
+
+	// The event bus goes next, since its subscription uses the Redis client.
+	if s.events != nil {
+		s.events.Close()
+	}
+
 	// If the database service is present, close it which will close both the SQL db and Redis connections
 	if s.db != nil {
 		// Use the common Go idiom style for concise error checking.
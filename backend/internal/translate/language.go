@@ -8,6 +8,7 @@ package translation
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/bytedance/sonic"
 )
@@ -17,6 +18,9 @@ import (
 // Note: There is a performance cost to using this translation mechanism, as it can grow easily if there is a lot of data (approximately 1MB or more).
 var Translations map[string]map[string]string
 
+// translationsMu guards Translations, since it can be reloaded at runtime (e.g., by the event bus) while being read.
+var translationsMu sync.RWMutex
+
 // LoadTranslations loads translations from a single JSON file.
 func LoadTranslations(filePath string) error {
 	// Note: This method is better because on Windows, long paths are not allowed by default, unlike on Unix/Linux.
@@ -27,17 +31,26 @@ func LoadTranslations(filePath string) error {
 		return err
 	}
 
-	err = sonic.Unmarshal(content, &Translations)
+	// Note: Decoding into a new map keeps the current translations intact when the file is invalid,
+	// and keeps readers from seeing a half-decoded map during a reload.
+	var loaded map[string]map[string]string
+	err = sonic.Unmarshal(content, &loaded)
 	if err != nil {
 		return err
 	}
 
+	translationsMu.Lock()
+	Translations = loaded
+	translationsMu.Unlock()
+
 	return nil
 }
 
 // Translate returns the translated string for the given key and language.
 func Translate(lang, key string, args ...any) string {
+	translationsMu.RLock()
 	translation, exists := Translations[lang][key]
+	translationsMu.RUnlock()
 	if !exists {
 		return key // Fallback to the key itself if translation does not exist
 	}
@@ -0,0 +1,70 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package apikeys provides the REST API handlers of the API keys, for the key authenticating the request.
+//
+// The handlers rely on the key authentication middleware (see middleware.NewKeyAuthMiddleware), which must run before them.
+//
+// Errors are sent as problem details (RFC 7807, see [helper.SendProblem]).
+package apikeys
+
+import (
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// defaultUsageDays is the number of days of usage answered by Usage without the "days" query.
+	defaultUsageDays = 30
+
+	// maxUsageDays is the maximum of the "days" query of Usage.
+	maxUsageDays = 366
+)
+
+// usageResponse is the body answered by Usage.
+type usageResponse struct {
+	KeyID string                 `json:"key_id"`
+	Plan  string                 `json:"plan,omitempty"`
+	Usage []database.APIKeyUsage `json:"usage"`
+}
+
+// Usage answers the daily usage of the API key of the request over the last "days" days (default: 30, max: 366),
+// oldest first.
+//
+// Note: The usage is the one flushed to the database (see [database.Quotas.Flush]), so the last requests may be missing.
+func Usage(quotas *database.Quotas) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, ok := keyauth.APIKeyFromContext(c)
+		if !ok {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		days := defaultUsageDays
+		if value := c.Query("days"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxUsageDays {
+				return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid days, want 1 to "+strconv.Itoa(maxUsageDays))
+			}
+			days = n
+		}
+
+		since := time.Now().UTC().AddDate(0, 0, 1-days)
+		usage, err := quotas.Usage(c.UserContext(), key.ID, since)
+		if err != nil {
+			log.LogErrorf("Failed to load the usage of API key %s: %v", key.ID, err)
+			return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Failed to load the usage")
+		}
+		if usage == nil {
+			usage = []database.APIKeyUsage{}
+		}
+		return c.JSON(usageResponse{KeyID: key.ID, Plan: key.Plan, Usage: usage})
+	}
+}
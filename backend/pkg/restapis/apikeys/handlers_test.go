@@ -0,0 +1,84 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package apikeys_test
+
+import (
+	"context"
+	"encoding/json"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/internal/middleware"
+	"h0llyw00dz-template/backend/internal/middleware/authentication/keyauth"
+	"h0llyw00dz-template/backend/pkg/restapis/apikeys"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUsage(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	database.CreateAPIKeysTable(ctx, db)
+	database.CreateAPIKeyUsageTable(ctx, db)
+	key, _, err := db.Auth().CreateAPIKey(ctx, "gopher", "usage", time.Hour)
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	quotas := database.NewQuotas(db)
+	app := fiber.New()
+	keyAuth := middleware.NewKeyAuthMiddleware(
+		middleware.WithValidator(keyauth.NewValidator(db)),
+		middleware.WithErrorHandler(keyauth.ErrorKeyAuthHandler),
+	)
+	app.Get("/usage", keyAuth, middleware.NewAPIKeyQuotaMiddleware(quotas), apikeys.Usage(quotas))
+	app.Get("/unauthenticated", apikeys.Usage(quotas))
+
+	// do sends a request with the API key, and decodes the usage it answers.
+	do := func(path string) (int, []database.APIKeyUsage) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Usage []database.APIKeyUsage `json:"usage"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Usage
+	}
+
+	if status, usage := do("/usage"); status != fiber.StatusOK || len(usage) != 0 {
+		t.Errorf("GET /usage before Flush = %d %+v, want 200 without usage", status, usage)
+	}
+	if err := quotas.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	// The request reading the usage is accounted as well, before it's answered.
+	if status, usage := do("/usage?days=1"); status != fiber.StatusOK || len(usage) != 1 || usage[0].Requests != 1 {
+		t.Errorf("GET /usage after Flush = %d %+v, want 200 with 1 request", status, usage)
+	}
+
+	for _, path := range []string{"/usage?days=0", "/usage?days=367", "/usage?days=many"} {
+		if status, _ := do(path); status != fiber.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", path, status, fiber.StatusBadRequest)
+		}
+	}
+	if status, _ := do("/unauthenticated"); status != fiber.StatusUnauthorized {
+		t.Errorf("GET /unauthenticated = %d, want %d", status, fiber.StatusUnauthorized)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"h0llyw00dz-template/backend/pkg/mime"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 )
 
 // ErrorResponse represents the structure of an error response.
@@ -34,3 +35,45 @@ func SendErrorResponse(c *fiber.Ctx, statusCode int, errorMessage string) error
 		Error: errorMessage,
 	}, mime.ApplicationProblemJSON)
 }
+
+// ProblemDetails represents a problem details object (RFC 7807).
+//
+// Note: Unlike ErrorResponse, the fields follow the standard, so generic clients can understand them.
+// Errors lists the invalid fields of a request, by name (an extension member).
+type ProblemDetails struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// SendProblemResponse sends a problem details response (RFC 7807) with the specified status code and detail.
+// The title is the standard status text, the instance is the request path, and the type is "about:blank".
+//
+// Example Usage:
+//
+//	return helper.SendProblemResponse(c, fiber.StatusConflict, "Username or email already taken")
+func SendProblemResponse(c *fiber.Ctx, statusCode int, detail string) error {
+	return SendProblem(c, ProblemDetails{Status: statusCode, Detail: detail})
+}
+
+// SendProblem sends a problem details response (RFC 7807), filling in the type, title and instance when they are empty.
+func SendProblem(c *fiber.Ctx, problem ProblemDetails) error {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = utils.StatusMessage(problem.Status)
+	}
+	if problem.Instance == "" {
+		problem.Instance = c.Path()
+	}
+
+	contentType := mime.ApplicationProblemJSON
+	if !mime.IsASCII(problem.Detail) {
+		contentType = mime.ApplicationProblemJSONCharsetUTF8
+	}
+	return c.Status(problem.Status).JSON(problem, contentType)
+}
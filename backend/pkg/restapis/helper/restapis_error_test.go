@@ -393,3 +393,52 @@ func TestSendErrorResponse_InternalServerError_NonASCII(t *testing.T) {
 		t.Errorf("Expected error message '%s', got '%s'", expectedErrorMessage, errorResponse.Error)
 	}
 }
+
+func TestSendProblemResponse(t *testing.T) {
+	app := fiber.New()
+	app.Post("/gopher/register", func(c *fiber.Ctx) error {
+		return helper.SendProblem(c, helper.ProblemDetails{
+			Status: fiber.StatusUnprocessableEntity,
+			Detail: "Invalid request",
+			Errors: map[string]string{"email": "invalid email"},
+		})
+	})
+	app.Get("/gopher/conflict", func(c *fiber.Ctx) error {
+		return helper.SendProblemResponse(c, fiber.StatusConflict, "Gopher sudah ada 🐹")
+	})
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantTitle       string
+	}{
+		{"with errors", fiber.MethodPost, "/gopher/register", fiber.StatusUnprocessableEntity, mime.ApplicationProblemJSON, "Unprocessable Entity"},
+		{"non-ASCII detail", fiber.MethodGet, "/gopher/conflict", fiber.StatusConflict, mime.ApplicationProblemJSONCharsetUTF8, "Conflict"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(tt.method, tt.path, nil))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != tt.wantContentType {
+				t.Errorf("Expected Content-Type '%s', got '%s'", tt.wantContentType, contentType)
+			}
+
+			var problem helper.ProblemDetails
+			if err := sonic.ConfigFastest.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("Failed to parse response body: %v", err)
+			}
+			if problem.Type != "about:blank" || problem.Title != tt.wantTitle || problem.Status != tt.wantStatus || problem.Instance != tt.path {
+				t.Errorf("Unexpected problem details: %+v", problem)
+			}
+		})
+	}
+}
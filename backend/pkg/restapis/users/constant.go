@@ -0,0 +1,17 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package users
+
+const (
+	// SessionContextKey is the context key of the session set by the session middleware (see middleware.NewSessionMiddleware).
+	SessionContextKey = "session"
+
+	// sessionUserID is the session key holding the ID of the logged-in user.
+	sessionUserID = "user_id"
+
+	// userContextKey is the context key of the user loaded by RequireUser.
+	userContextKey = "user"
+)
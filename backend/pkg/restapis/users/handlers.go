@@ -0,0 +1,251 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+// Package users provides the REST API handlers of the user accounts: register, login, logout and change-password.
+//
+// The handlers rely on the session middleware (see middleware.NewSessionMiddleware), which must run before them
+// and store the session under SessionContextKey. A login regenerates the session ID, so a session ID known
+// before the login (e.g., planted by an attacker, aka session fixation) is useless afterwards.
+//
+// Errors are sent as problem details (RFC 7807, see [helper.SendProblem]).
+package users
+
+import (
+	"errors"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/helper"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+// registerRequest is the body of Register.
+type registerRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// loginRequest is the body of Login. Login is either the username or the email.
+type loginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// changePasswordRequest is the body of ChangePassword.
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// Register creates a new user account, and answers 201 with the user.
+//
+// Note: It doesn't log the user in, so a registration can't be used to skip what the login does (e.g., rate limiting, MFA).
+func Register(db database.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req registerRequest
+		if err := c.BodyParser(&req); err != nil {
+			return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		user, err := db.Auth().CreateUser(c.UserContext(), req.Username, req.Email, req.Password)
+		if err != nil {
+			return sendUserError(c, err)
+		}
+
+		log.LogUserActivity(c, "Registered user "+user.ID)
+		return c.Status(fiber.StatusCreated).JSON(user)
+	}
+}
+
+// Login checks the credentials, then stores the user in a new session (with a regenerated ID), and answers with the user.
+func Login(db database.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sess, ok := sessionFrom(c)
+		if !ok {
+			return sendMissingSession(c)
+		}
+
+		var req loginRequest
+		if err := c.BodyParser(&req); err != nil {
+			return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		user, err := db.Auth().AuthenticateUser(c.UserContext(), req.Login, req.Password)
+		if err != nil {
+			log.LogUserActivity(c, "Failed login attempt")
+			return sendUserError(c, err)
+		}
+
+		if err := StartSession(sess, user.ID); err != nil {
+			log.LogErrorf("Failed to start the session of user %s: %v", user.ID, err)
+			return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Failed to start the session")
+		}
+
+		log.LogUserActivity(c, "User "+user.ID+" logged in")
+		return c.JSON(user)
+	}
+}
+
+// Logout destroys the session, and answers 204.
+func Logout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sess, ok := sessionFrom(c)
+		if !ok {
+			return sendMissingSession(c)
+		}
+
+		if err := sess.Destroy(); err != nil {
+			log.LogErrorf("Failed to destroy session: %v", err)
+			return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Failed to log out")
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// ChangePassword replaces the password of the logged-in user after checking the current one, and answers 204.
+// The session ID is regenerated as well, since the privileges of the old one changed.
+//
+// Note: It must run after RequireUser.
+func ChangePassword(db database.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := UserFromContext(c)
+		if !ok {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+		sess, ok := sessionFrom(c)
+		if !ok {
+			return sendMissingSession(c)
+		}
+
+		var req changePasswordRequest
+		if err := c.BodyParser(&req); err != nil {
+			return helper.SendProblemResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+
+		if err := db.Auth().ChangePassword(c.UserContext(), user.ID, req.CurrentPassword, req.NewPassword); err != nil {
+			if errors.Is(err, database.ErrInvalidCredentials) {
+				// Not a 401: the session is fine, only the current password is wrong.
+				return helper.SendProblemResponse(c, fiber.StatusForbidden, "Current password is incorrect")
+			}
+			return sendUserError(c, err)
+		}
+
+		if err := StartSession(sess, user.ID); err != nil {
+			log.LogErrorf("Failed to regenerate the session of user %s: %v", user.ID, err)
+		}
+		log.LogUserActivity(c, "User "+user.ID+" changed password")
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+// Me answers with the logged-in user.
+//
+// Note: It must run after RequireUser.
+func Me() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := UserFromContext(c)
+		if !ok {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+		return c.JSON(user)
+	}
+}
+
+// RequireUser is a middleware that loads the logged-in user of the session into the context (see UserFromContext),
+// or answers 401 when there is none.
+func RequireUser(db database.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		sess, ok := sessionFrom(c)
+		if !ok {
+			return sendMissingSession(c)
+		}
+
+		id := SessionUserID(sess)
+		if id == "" {
+			return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+		}
+
+		user, err := db.Auth().GetUser(c.UserContext(), id)
+		if err != nil {
+			if errors.Is(err, database.ErrUserNotFound) {
+				// The account is gone, so is the session.
+				sess.Destroy()
+				return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Authentication required")
+			}
+			log.LogErrorf("Failed to load user %s: %v", id, err)
+			return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+		}
+
+		c.Locals(userContextKey, user)
+		return c.Next()
+	}
+}
+
+// UserFromContext returns the user loaded by RequireUser.
+func UserFromContext(c *fiber.Ctx) (database.User, bool) {
+	user, ok := c.Locals(userContextKey).(database.User)
+	return user, ok
+}
+
+// SessionUserID returns the ID of the user logged in the session, or "" when there is none.
+func SessionUserID(sess *session.Session) string {
+	id, _ := sess.Get(sessionUserID).(string)
+	return id
+}
+
+// StartSession regenerates the session ID, then stores the user ID in the session and saves it.
+// It's also what other login methods (e.g., OAuth2, WebAuthn) should use once they have identified the user.
+//
+// Note: The session can't be used after this, since Save releases it.
+func StartSession(sess *session.Session, userID string) error {
+	if err := sess.Regenerate(); err != nil {
+		return err
+	}
+	sess.Set(sessionUserID, userID)
+	return sess.Save()
+}
+
+// sessionFrom returns the session of the request set by the session middleware,
+// or false when the middleware is missing (see sendMissingSession).
+func sessionFrom(c *fiber.Ctx) (*session.Session, bool) {
+	sess, ok := c.Locals(SessionContextKey).(*session.Session)
+	return sess, ok && sess != nil
+}
+
+// sendMissingSession answers 500 when the session middleware is missing, which is a setup error.
+func sendMissingSession(c *fiber.Ctx) error {
+	log.LogError("users: the session middleware is missing")
+	return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+}
+
+// sendUserError maps the errors of the user store to problem details.
+func sendUserError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, database.ErrUserExists):
+		return helper.SendProblemResponse(c, fiber.StatusConflict, "Username or email already taken")
+	case errors.Is(err, database.ErrInvalidCredentials):
+		return helper.SendProblemResponse(c, fiber.StatusUnauthorized, "Invalid credentials")
+	case errors.Is(err, database.ErrInvalidUsername):
+		return sendInvalidField(c, "username", "must be 3 to 32 letters, digits, dots, dashes or underscores")
+	case errors.Is(err, database.ErrInvalidEmail):
+		return sendInvalidField(c, "email", "must be a valid email address")
+	case errors.Is(err, database.ErrInvalidPassword):
+		return sendInvalidField(c, "password", "must be 8 to 72 bytes")
+	default:
+		log.LogErrorf("Unexpected error in users handler: %v", err)
+		return helper.SendProblemResponse(c, fiber.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// sendInvalidField sends a 422 problem naming the invalid field.
+func sendInvalidField(c *fiber.Ctx, field, reason string) error {
+	return helper.SendProblem(c, helper.ProblemDetails{
+		Status: fiber.StatusUnprocessableEntity,
+		Detail: "Invalid " + field,
+		Errors: map[string]string{field: reason},
+	})
+}
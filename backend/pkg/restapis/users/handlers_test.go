@@ -0,0 +1,170 @@
+// Copyright (c) 2025 H0llyW00dzZ All rights reserved.
+//
+// By accessing or using this software, you agree to be bound by the terms
+// of the License Agreement, which you can find at LICENSE files.
+
+package users_test
+
+import (
+	"context"
+	"h0llyw00dz-template/backend/internal/database"
+	log "h0llyw00dz-template/backend/internal/logger"
+	"h0llyw00dz-template/backend/pkg/restapis/users"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
+)
+
+func TestUserHandlers(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+	if err := database.CreateUsersTable(context.Background(), db); err != nil {
+		t.Fatalf("CreateUsersTable() error = %v", err)
+	}
+
+	store := session.New(session.Config{Storage: db.FiberStorage()})
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(users.SessionContextKey, sess)
+		return c.Next()
+	})
+	app.Get("/visit", func(c *fiber.Ctx) error { // saves an anonymous session, like a pre-login flow would
+		sess := c.Locals(users.SessionContextKey).(*session.Session)
+		sess.Set("visited", true)
+		return sess.Save()
+	})
+	app.Post("/register", users.Register(db))
+	app.Post("/login", users.Login(db))
+	app.Post("/logout", users.Logout())
+	app.Get("/me", users.RequireUser(db), users.Me())
+	app.Post("/password", users.RequireUser(db), users.ChangePassword(db))
+
+	// do sends a JSON request with the session cookie, and returns the response with the session cookie it sets, if any.
+	do := func(method, path, body, sessionID string) (*http.Response, string) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if sessionID != "" {
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: sessionID})
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("app.Test() error = %v", err)
+		}
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == "session_id" {
+				return resp, cookie.Value
+			}
+		}
+		return resp, ""
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		body            string
+		wantStatus      int
+		wantContentType string
+	}{
+		{"register", "/register", `{"username":"gopher","email":"gopher@example.com","password":"correct horse"}`, fiber.StatusCreated, fiber.MIMEApplicationJSON},
+		{"register taken", "/register", `{"username":"gopher","email":"other@example.com","password":"correct horse"}`, fiber.StatusConflict, "application/problem+json"},
+		{"register invalid", "/register", `{"username":"gopher2","email":"gopher2@example.com","password":"short"}`, fiber.StatusUnprocessableEntity, "application/problem+json"},
+		{"register malformed", "/register", `{`, fiber.StatusBadRequest, "application/problem+json"},
+		{"login wrong password", "/login", `{"login":"gopher","password":"wrong horse"}`, fiber.StatusUnauthorized, "application/problem+json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := do(fiber.MethodPost, tt.path, tt.body, "")
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(contentType, tt.wantContentType) {
+				t.Errorf("Expected Content-Type '%s', got '%s'", tt.wantContentType, contentType)
+			}
+		})
+	}
+
+	// A session ID known before the login is not the one of the logged-in session.
+	_, anonymous := do(fiber.MethodGet, "/visit", "", "")
+	if anonymous == "" {
+		t.Fatal("GET /visit didn't set a session cookie")
+	}
+	if resp, _ := do(fiber.MethodGet, "/me", "", anonymous); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("GET /me without login: expected status code %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+	resp, loggedIn := do(fiber.MethodPost, "/login", `{"login":"gopher@example.com","password":"correct horse"}`, anonymous)
+	if resp.StatusCode != fiber.StatusOK || loggedIn == "" || loggedIn == anonymous {
+		t.Fatalf("POST /login: got status code %d and session %q, want 200 with a new session", resp.StatusCode, loggedIn)
+	}
+	if resp, _ := do(fiber.MethodGet, "/me", "", anonymous); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("GET /me with the pre-login session: expected status code %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+	if resp, _ := do(fiber.MethodGet, "/me", "", loggedIn); resp.StatusCode != fiber.StatusOK {
+		t.Errorf("GET /me: expected status code %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	// Changing the password rotates the session as well.
+	if resp, _ := do(fiber.MethodPost, "/password", `{"current_password":"wrong horse","new_password":"battery staple"}`, loggedIn); resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("POST /password with a wrong current password: expected status code %d, got %d", fiber.StatusForbidden, resp.StatusCode)
+	}
+	resp, rotated := do(fiber.MethodPost, "/password", `{"current_password":"correct horse","new_password":"battery staple"}`, loggedIn)
+	if resp.StatusCode != fiber.StatusNoContent || rotated == "" || rotated == loggedIn {
+		t.Fatalf("POST /password: got status code %d and session %q, want 204 with a new session", resp.StatusCode, rotated)
+	}
+
+	if resp, _ := do(fiber.MethodPost, "/logout", "", rotated); resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("POST /logout: expected status code %d, got %d", fiber.StatusNoContent, resp.StatusCode)
+	}
+	if resp, _ := do(fiber.MethodGet, "/me", "", rotated); resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("GET /me after logout: expected status code %d, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestMissingSessionMiddleware(t *testing.T) {
+	log.InitializeLogger("Gopher Testing", "unix")
+	db, err := database.NewInProcess(":memory:")
+	if err != nil {
+		t.Fatalf("NewInProcess() error = %v", err)
+	}
+	defer db.Close()
+
+	// Without the session middleware, the handlers answer 500 instead of using a nil session.
+	app := fiber.New()
+	app.Post("/login", users.Login(db))
+	app.Post("/logout", users.Logout())
+	app.Get("/me", users.RequireUser(db), users.Me())
+	app.Post("/password", func(c *fiber.Ctx) error {
+		c.Locals("user", database.User{ID: "gopher"})
+		return c.Next()
+	}, users.ChangePassword(db))
+
+	for _, path := range []string{"/login", "/logout", "/me", "/password"} {
+		method := http.MethodPost
+		if path == "/me" {
+			method = http.MethodGet
+		}
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"login":"gopher","password":"password"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != fiber.StatusInternalServerError {
+			t.Errorf("%s %s status = %d, want %d", method, path, resp.StatusCode, fiber.StatusInternalServerError)
+		}
+	}
+}
@@ -29,6 +29,13 @@ const (
 	//   - "default": Default timestamp format (e.g., 2024/04/17 15:04:05).
 )
 
+// Translations Configuration
+const (
+	// TRANSLATIONSFILE is the JSON file of the translations. When set, it is loaded at boot and reloaded on every pod
+	// when a "translations.reload" event is published on the event bus.
+	TRANSLATIONSFILE = "TRANSLATIONS_FILE" // The translations JSON file (default: "", translations disabled).
+)
+
 // App/Server Timeout Configuration
 const (
 	READTIMEOUT     = "READ_TIMEOUT"     // The maximum duration for reading the entire request, including the body (default: "5s").
@@ -54,6 +61,28 @@ const (
 	DBDATABASE = "DB_DATABASE" // The name of the MySQL database to connect to (required).
 	DBUSERNAME = "DB_USERNAME" // The username for authenticating with the MySQL database (required).
 	DBPASSWORD = "DB_PASSWORD" // The password for authenticating with the MySQL database (required).
+	// DBREPLICAS is a comma-separated list of read replicas (optional).
+	// Each entry is either "host:port", which reuses the credentials, database and TLS settings of the primary,
+	// or a full DSN (e.g., "user:pass@tcp(host:3306)/db?tls=custom") for replicas that need different settings.
+	// When set, read-only calls (QueryRow, Query, StreamRows) are load-balanced across healthy replicas.
+	DBREPLICAS             = "DB_REPLICAS"
+	DBREPLICAMAXLAG        = "DB_REPLICA_MAX_LAG"        // The maximum replication lag before a replica is ejected (default: "10s").
+	DBREPLICACHECKINTERVAL = "DB_REPLICA_CHECK_INTERVAL" // How often the replicas are health-checked (default: "5s").
+	// DBDRIVER selects the SQL backend: "mysql" (default) or "sqlite" (pure Go, no external services).
+	// When set to "sqlite", the MySQL settings above are ignored.
+	DBDRIVER     = "DB_DRIVER"
+	DBSQLITEPATH = "DB_SQLITE_PATH" // The SQLite database file, or ":memory:" for an in-memory database (default: ":memory:").
+	// DBSUPERVISORINTERVAL is how often the connection supervisor pings MySQL (or SQLite) and Redis (default: "5s").
+	// When a ping fails, the service reports not ready and reconnects with exponential backoff until it recovers.
+	DBSUPERVISORINTERVAL     = "DB_SUPERVISOR_INTERVAL"
+	DBSUPERVISORMAXBACKOFF   = "DB_SUPERVISOR_MAX_BACKOFF"   // The maximum delay between reconnect attempts (default: "30s").
+	DBSUPERVISORRESTARTAFTER = "DB_SUPERVISOR_RESTART_AFTER" // Failed reconnect attempts before the connection pool is restarted, "0" to never restart (default: "5").
+	// DBSLOWQUERYTHRESHOLD is the duration above which a MySQL (or SQLite) or Redis call is written to the slow-query log,
+	// "0" to disable the log (default: "200ms"). Every call is still recorded in the query duration metrics.
+	DBSLOWQUERYTHRESHOLD = "DB_SLOW_QUERY_THRESHOLD"
+	// DBSLOWQUERYARGS controls how the query arguments are written to the slow-query log: "redact" (default),
+	// which only keeps numbers, booleans, times, and the size of strings, "plain" (local development only), or "off".
+	DBSLOWQUERYARGS = "DB_SLOW_QUERY_ARGS"
 )
 
 // Redis Database Configuration
@@ -65,6 +94,33 @@ const (
 	RDBPOOLTIMEOUT     = "RDB_POOL_TIMEOUT"        // The maximum amount of time to wait for a connection from the Redis connection pool (required).
 	RDBMAXCONNLIFEIDLE = "REDIS_MAXCONN_IDLE_TIME" // The maximum amount of time a Redis connection can remain idle in the connection pool (required).
 	RDBMAXCONNLIFETIME = "REDIS_MAXCONN_LIFE_TIME" // The maximum lifetime of a Redis connection in the connection pool (required).
+	// RDBMODE selects how Redis is reached: "standalone" (default), "sentinel", "cluster",
+	// or "memory" (an in-process Redis stand-in, no external services).
+	// When set to "memory", the Redis settings above are ignored and the data is lost on restart.
+	// When set to "sentinel" or "cluster", RDB_ADDRESS and RDB_PORT are ignored (RDB_PASSWORD is still used for the data nodes).
+	RDBMODE             = "RDB_MODE"
+	RDBSENTINELMASTER   = "RDB_SENTINEL_MASTER"   // The name of the master monitored by Sentinel (required in sentinel mode).
+	RDBSENTINELADDRS    = "RDB_SENTINEL_ADDRS"    // A comma-separated list of Sentinel "host:port" addresses (required in sentinel mode).
+	RDBSENTINELPASSWORD = "RDB_SENTINEL_PASSWORD" // The password for authenticating with the Sentinels (optional).
+	RDBCLUSTERADDRS     = "RDB_CLUSTER_ADDRS"     // A comma-separated list of cluster seed nodes "host:port" (required in cluster mode).
+	// STORAGEL1TTL enables an in-process cache (L1) in front of the Redis storage used by the Fiber middleware
+	// (cache, session, limiter, CSRF), and sets how long a value is kept in it (e.g., "2s"). Empty disables it (default: "").
+	// Without STORAGE_L1_NOTIFICATIONS, a pod may serve a value changed by another pod for up to this duration.
+	STORAGEL1TTL        = "STORAGE_L1_TTL"
+	STORAGEL1MAXENTRIES = "STORAGE_L1_MAX_ENTRIES" // The maximum number of keys in the in-process cache (default: "10000").
+	// STORAGEL1NOTIFICATIONS invalidates the in-process cache on Redis keyspace notifications, when set to "true" (default: "false").
+	// Redis must have notify-keyspace-events including "Kg$xe" (or "KA"), since it's not changed by the application.
+	STORAGEL1NOTIFICATIONS = "STORAGE_L1_NOTIFICATIONS"
+	// STORAGEENCRYPTIONKEYS enables the encryption at rest of the values of the Redis storage used by the Fiber middleware
+	// (sessions, API key data, cached responses). It is a comma-separated list of "id:key" entries, where the key is
+	// a base64-encoded 32-byte key (e.g., "2025-06:+Nhv3..."). Empty disables it (default: "").
+	// To rotate the key, add a new entry and point STORAGE_ENCRYPTION_KEY_ID to it, then remove the old entry
+	// once the values sealed with it have expired.
+	STORAGEENCRYPTIONKEYS  = "STORAGE_ENCRYPTION_KEYS"
+	STORAGEENCRYPTIONKEYID = "STORAGE_ENCRYPTION_KEY_ID" // The ID of the key used to seal new values (required with STORAGE_ENCRYPTION_KEYS).
+	// STORAGEENCRYPTIONALLOWPLAINTEXT accepts the values that aren't sealed yet, so enabling the encryption doesn't log
+	// everyone out (default: "true"). Set it to "false" once the plaintext values have expired, so they are rejected.
+	STORAGEENCRYPTIONALLOWPLAINTEXT = "STORAGE_ENCRYPTION_ALLOW_PLAINTEXT"
 )
 
 // TLS Configuration
@@ -87,6 +143,20 @@ const (
 	// Note: This for mTLS (Optional)
 	SERVERCATLS = "TLS_CA_FILE"
 	ENABLEMTLS  = "ENABLE_MTLS"
+	// SERVERCRLTLS is a comma-separated list of CRL files (PEM or DER) checked against the client certificates, reloaded when they change.
+	SERVERCRLTLS = "TLS_CRL_FILES"
+	// SERVEROCSPTLS is the URL of a local OCSP responder (e.g., of the private CA) asked about the client certificates.
+	SERVEROCSPTLS = "TLS_OCSP_RESPONDER"
+	// SERVERSNICERTSTLS is a comma-separated list of directories with a "tls.crt" and a "tls.key" (e.g., Kubernetes TLS secrets mounted as volumes),
+	// served by SNI in addition to TLS_CERT_FILE and TLS_KEY_FILE, and reloaded when they change.
+	SERVERSNICERTSTLS = "TLS_SNI_CERT_DIRS"
+	// ACMEHOSTS is a comma-separated list of hosts of a certificate obtained from an ACME server (e.g., Let's Encrypt),
+	// used when TLS_CERT_FILE and TLS_KEY_FILE aren't set.
+	ACMEHOSTS = "ACME_HOSTS"
+	// ACMEDIRECTORYURL is the directory URL of the ACME server (default: the production directory of Let's Encrypt).
+	ACMEDIRECTORYURL = "ACME_DIRECTORY_URL"
+	// ACMEEMAIL is the contact email of the ACME account.
+	ACMEEMAIL = "ACME_EMAIL"
 )
 
 // Site Middleware Configuration (Optional since it boilerplate and must rewrite a "DomainRouter" in RegisterRoutes (see backend/internal/middleware/routes.go))
@@ -102,3 +172,26 @@ const (
 	CFKVACCID       = "CF_KV_ACC_ID"       // The Cloudflare Account ID.
 	CFKVNAMESPACEID = "CF_KV_NAMESPACE_ID" // The Cloudflare NameSpace ID.
 )
+
+// Authentication Configuration
+const (
+	// APIKEYHASHSECRET is the secret of the keyed hash (HMAC-SHA256) of the API keys stored in the database,
+	// so a leaked api_keys table can't be used to check guessed keys without it as well.
+	// It is required and must be at least 32 bytes (e.g., "openssl rand -hex 32"), otherwise the boot fails.
+	// Note: Changing it invalidates every existing API key, so treat it like an encryption key.
+	APIKEYHASHSECRET = "API_KEY_HASH_SECRET"
+	// APIKEYQUOTAPLANS is a comma-separated list of the quota plans of the API keys, as "name=limit/window" entries
+	// (e.g., "free=60/1m,pro=100000/24h"). Empty only accounts the usage, without any quota (default: "").
+	APIKEYQUOTAPLANS  = "API_KEY_QUOTA_PLANS"
+	APIKEYDEFAULTPLAN = "API_KEY_DEFAULT_PLAN" // The plan of the API keys without one (default: "", unlimited).
+)
+
+// Authorization Configuration
+const (
+	// AUTHORIZATIONPOLICYFILE is the policy file (YAML, or JSON with a ".json" extension) of the authorization layer,
+	// checked by the routes that require a permission (e.g., "users:read" for GET /v1/users/me). Empty disables it (default: "").
+	// Note: Once it's set, the subjects must be bound to a role granting these permissions, or they are denied.
+	AUTHORIZATIONPOLICYFILE = "AUTHORIZATION_POLICY_FILE"
+	// AUTHORIZATIONREFRESHINTERVAL is how often the policy file is reloaded, "0" to never reload it (default: "1m").
+	AUTHORIZATIONREFRESHINTERVAL = "AUTHORIZATION_REFRESH_INTERVAL"
+)